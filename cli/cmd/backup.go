@@ -2,10 +2,13 @@ package cmd
 
 import (
 	"fmt"
+	"os"
+	"strings"
 	"sync"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/cli/internal/output"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
 	"github.com/spf13/cobra"
 )
 
@@ -23,7 +26,45 @@ var backupService = func() func() (backup.BackupService, error) {
 				return
 			}
 
-			instance = backup.NewFileBasedBackupService(config, logger)
+			instance = backup.NewFileBasedBackupService(config, documents.NewSQLiteDocumentBlobReferenceRepositoryFactory(), logger)
+		})
+		return instance, initErr
+	}
+}()
+
+// backupMonitor returns a singleton instance of the BackupMonitor
+var backupMonitor = func() func() (backup.BackupMonitor, error) {
+	var instance backup.BackupMonitor
+	var once sync.Once
+	var initErr error
+
+	return func() (backup.BackupMonitor, error) {
+		once.Do(func() {
+			config, err := appConfig()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			backupSvc, err := backupService()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			db, err := database()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			runRepo, err := backup.NewSQLiteBackupRunRepository(db)
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			instance = backup.NewDefaultBackupMonitor(backupSvc, runRepo, config, logger)
 		})
 		return instance, initErr
 	}
@@ -60,12 +101,58 @@ var backupCreateCmd = &cobra.Command{
 			"filename":   backupInfo.Filename,
 			"size_bytes": backupInfo.SizeBytes,
 			"path":       backupInfo.FilePath,
+			"encrypted":  backupInfo.Encrypted,
 		})
 
-		return nil
+		if noUpload, _ := cmd.Flags().GetBool("no-upload"); noUpload {
+			return nil
+		}
+		return uploadBackup(backupSvc, backupInfo.Filename)
 	},
 }
 
+// backupUploadCmd represents the command to upload a backup to the off-site targets
+var backupUploadCmd = &cobra.Command{
+	Use:   "upload <filename>",
+	Short: "Upload a backup to the off-site targets",
+	Long: `Uploads a backup and its manifest to all targets configured in FF_BACKUP_TARGETS
+and applies the retention of each target.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		backupSvc, err := backupService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize backup service: %w", err)
+		}
+
+		return uploadBackup(backupSvc, args[0])
+	},
+}
+
+// uploadBackup uploads a backup to the configured targets and prints the result of each target
+func uploadBackup(backupSvc backup.BackupService, filename string) error {
+	results, err := backupSvc.UploadBackup(filename)
+	if err != nil {
+		return fmt.Errorf("failed to upload backup: %w", err)
+	}
+
+	failed := 0
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			output.PrintError("Upload to "+result.Target+" failed", result.Err)
+			continue
+		}
+		output.PrintSuccess("Backup uploaded to "+result.Target, map[string]interface{}{
+			"deleted_old_backups": result.Deleted,
+		})
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("upload failed for %d of %d targets", failed, len(results))
+	}
+	return nil
+}
+
 // backupListCmd represents the command to list all backups
 var backupListCmd = &cobra.Command{
 	Use:   "list",
@@ -126,28 +213,222 @@ var backupDeleteCmd = &cobra.Command{
 var backupCleanupCmd = &cobra.Command{
 	Use:   "cleanup",
 	Short: "Clean up old backups",
-	Long:  `Removes old backup files according to the MaxGenerations configuration.`,
+	Long: `Removes the backups that no retention rule keeps.
+
+A backup is kept if it is one of the newest FF_BACKUP_MAX_GENERATIONS backups or the
+newest backup of one of the last FF_BACKUP_KEEP_DAILY days, FF_BACKUP_KEEP_WEEKLY weeks,
+FF_BACKUP_KEEP_MONTHLY months or FF_BACKUP_KEEP_YEARLY years with backups.
+Manual backups are always kept unless --include-manual is given or
+FF_BACKUP_PRUNE_MANUAL is true.
+
+Use --dry-run to see which backups would be deleted and why, and --verbose to
+list the kept backups as well.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		includeManual, _ := cmd.Flags().GetBool("include-manual")
+
 		backupSvc, err := backupService()
 		if err != nil {
 			return fmt.Errorf("failed to initialize backup service: %w", err)
 		}
 
-		if err := backupSvc.CleanupOldBackups(); err != nil {
+		decisions, err := backupSvc.CleanupOldBackups(backup.CleanupOptions{
+			DryRun:        dryRun,
+			IncludeManual: includeManual,
+		})
+		if err != nil {
 			return fmt.Errorf("failed to cleanup backups: %w", err)
 		}
 
-		output.PrintSuccess("Backup cleanup completed", nil)
+		if len(decisions) == 0 {
+			fmt.Println("No backups found")
+			return nil
+		}
+
+		output.NewFormatter(verbose).PrintRetentionDecisions(decisions, dryRun)
+
+		for _, decision := range decisions {
+			if decision.Err != nil {
+				return fmt.Errorf("failed to delete some backups")
+			}
+		}
+		if !dryRun {
+			output.PrintSuccess("Backup cleanup completed", nil)
+		}
 
 		return nil
 	},
 }
 
+// backupStatusCmd represents the command to show the status of automatic backups
+var backupStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the status of automatic backups",
+	Long: `Shows the schedule, the next due backup and the latest runs of the backup worker
+in the web UI, including their outcome, duration, size and error.
+
+The command exits with an error if the latest run failed or had problems, or if a
+backup is overdue, so it can be used in monitoring scripts.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		limit, _ := cmd.Flags().GetInt("limit")
+
+		monitor, err := backupMonitor()
+		if err != nil {
+			return fmt.Errorf("failed to initialize backup monitor: %w", err)
+		}
+
+		status, err := monitor.GetStatus(limit)
+		if err != nil {
+			return fmt.Errorf("failed to get backup status: %w", err)
+		}
+
+		output.NewFormatter(verbose).PrintBackupStatus(status)
+
+		if !status.Healthy() {
+			return fmt.Errorf("automatic backups are unhealthy")
+		}
+		return nil
+	},
+}
+
+// backupRestoreCmd represents the command to restore a backup
+var backupRestoreCmd = &cobra.Command{
+	Use:   "restore <filename>",
+	Short: "Restore the database from a backup",
+	Long: `Replaces the database with the content of a backup file.
+
+Encrypted backups are decrypted with the configured passphrase or key file
+(FF_BACKUP_PASSPHRASE / FF_BACKUP_KEY_FILE) unless --passphrase-file or --key-file
+is given. The restored database is checked with PRAGMA integrity_check before it
+atomically replaces the current database, which is kept as a .pre-restore copy.
+
+Stop the web UI before restoring. The command refuses to run while the web UI is
+running unless --force is given.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filename := args[0]
+		force, _ := cmd.Flags().GetBool("force")
+		passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+		keyFile, _ := cmd.Flags().GetString("key-file")
+
+		options := backup.RestoreOptions{
+			Force:   force,
+			KeyFile: keyFile,
+		}
+		if passphraseFile != "" {
+			data, err := os.ReadFile(passphraseFile)
+			if err != nil {
+				return fmt.Errorf("failed to read passphrase file: %w", err)
+			}
+			options.Passphrase = strings.TrimRight(string(data), "\r\n")
+		}
+
+		backupSvc, err := backupService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize backup service: %w", err)
+		}
+
+		restoreInfo, err := backupSvc.RestoreBackup(filename, options)
+		if err != nil {
+			return fmt.Errorf("failed to restore backup: %w", err)
+		}
+
+		if restoreInfo.ServerPid != 0 {
+			output.PrintWarning(fmt.Sprintf("The web UI (pid %d) is still running. Restart it to use the restored database.", restoreInfo.ServerPid))
+		}
+
+		output.PrintSuccess("Backup restored successfully", map[string]interface{}{
+			"filename":          restoreInfo.Filename,
+			"database":          restoreInfo.DatabasePath,
+			"previous_database": restoreInfo.PreviousDatabasePath,
+		})
+
+		return nil
+	},
+}
+
+// backupVerifyCmd represents the command to verify backups
+var backupVerifyCmd = &cobra.Command{
+	Use:   "verify [filename]",
+	Short: "Verify backups against their manifests",
+	Long: `Verifies a backup, or all backups with --all, against the manifest written when
+the backup was created. The file is re-hashed, opened read-only, checked with
+PRAGMA integrity_check and its row counts are compared with the manifest.
+Encrypted backups are decrypted with the configured passphrase or key file.
+
+The result is recorded in the manifest and shown by "backup list".`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		all, _ := cmd.Flags().GetBool("all")
+		if all == (len(args) == 1) {
+			return fmt.Errorf("specify either a backup filename or --all")
+		}
+
+		backupSvc, err := backupService()
+		if err != nil {
+			return fmt.Errorf("failed to initialize backup service: %w", err)
+		}
+
+		filenames := args
+		if all {
+			backups, err := backupSvc.ListBackups()
+			if err != nil {
+				return fmt.Errorf("failed to list backups: %w", err)
+			}
+			filenames = make([]string, 0, len(backups))
+			for _, backupInfo := range backups {
+				filenames = append(filenames, backupInfo.Filename)
+			}
+		}
+
+		if len(filenames) == 0 {
+			fmt.Println("No backups found")
+			return nil
+		}
+
+		verifications := make([]*backup.BackupVerification, 0, len(filenames))
+		failed := 0
+		for _, filename := range filenames {
+			verification, err := backupSvc.VerifyBackup(filename)
+			if err != nil {
+				return fmt.Errorf("failed to verify backup %s: %w", filename, err)
+			}
+			if !verification.Ok() {
+				failed++
+			}
+			verifications = append(verifications, verification)
+		}
+
+		formatter := output.NewFormatter(verbose)
+		formatter.PrintBackupVerifications(verifications)
+
+		if failed > 0 {
+			return fmt.Errorf("%d of %d backups failed verification", failed, len(verifications))
+		}
+		return nil
+	},
+}
+
 func init() {
+	backupCreateCmd.Flags().Bool("no-upload", false, "do not upload the backup to the off-site targets")
+	backupVerifyCmd.Flags().Bool("all", false, "verify all backups")
+	backupStatusCmd.Flags().Int("limit", 10, "number of runs to show")
+	backupCleanupCmd.Flags().Bool("dry-run", false, "show which backups would be deleted without deleting them")
+	backupCleanupCmd.Flags().Bool("include-manual", false, "apply the retention rules to manual backups too")
+
+	backupRestoreCmd.Flags().Bool("force", false, "restore even if the web UI is running")
+	backupRestoreCmd.Flags().String("passphrase-file", "", "file containing the passphrase of an encrypted backup")
+	backupRestoreCmd.Flags().String("key-file", "", "key file of an encrypted backup")
+	backupRestoreCmd.MarkFlagsMutuallyExclusive("passphrase-file", "key-file")
+
 	backupCmd.AddCommand(backupCreateCmd)
 	backupCmd.AddCommand(backupListCmd)
 	backupCmd.AddCommand(backupDeleteCmd)
 	backupCmd.AddCommand(backupCleanupCmd)
+	backupCmd.AddCommand(backupStatusCmd)
+	backupCmd.AddCommand(backupRestoreCmd)
+	backupCmd.AddCommand(backupVerifyCmd)
+	backupCmd.AddCommand(backupUploadCmd)
 
 	rootCmd.AddCommand(backupCmd)
 }
@@ -0,0 +1,328 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/cli/internal/output"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	"github.com/spf13/cobra"
+)
+
+// migrationRunner returns a singleton instance of the schema MigrationRunner
+var migrationRunner = func() func() (ccc.MigrationRunner, error) {
+	var instance ccc.MigrationRunner
+	var once sync.Once
+	var initErr error
+
+	return func() (ccc.MigrationRunner, error) {
+		once.Do(func() {
+			db, err := database()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			instance = schema.NewMigrationRunner(db, logger)
+		})
+		return instance, initErr
+	}
+}()
+
+// blobStore returns a singleton instance of the configured blob store, which is nil if file contents are stored in the database
+var blobStore = func() func() (blobstore.BlobStore, error) {
+	var instance blobstore.BlobStore
+	var once sync.Once
+	var initErr error
+
+	return func() (blobstore.BlobStore, error) {
+		once.Do(func() {
+			config, err := appConfig()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			instance, initErr = blobstore.NewBlobStoreFromConfig(config.BlobStore)
+		})
+		return instance, initErr
+	}
+}()
+
+// requireBlobStore returns the configured blob store and fails if file contents are stored in the database
+func requireBlobStore() (blobstore.BlobStore, error) {
+	store, err := blobStore()
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize blob store: %w", err)
+	}
+	if store == nil {
+		return nil, fmt.Errorf("no blob store is configured, set %s to %s or %s", ccc.EnvBlobStore, blobstore.StoreTypeFileSystem, blobstore.StoreTypeS3)
+	}
+	return store, nil
+}
+
+// dbCmd represents the db command group
+var dbCmd = &cobra.Command{
+	Use:   "db",
+	Short: "Database management commands",
+	Long: `Commands for managing the FrozenFortress database.
+
+Commands in this group do not apply pending schema migrations automatically.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// dbMigrateCmd represents the migrate command group
+var dbMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Schema migration commands",
+	Long:  `Commands for inspecting, applying and reverting versioned schema migrations.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// dbMigrateStatusCmd represents the command to show the migration status
+var dbMigrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the schema migration status",
+	Long:  `Lists all schema migrations and whether they have been applied to the database.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := migrationRunner()
+		if err != nil {
+			return fmt.Errorf("failed to initialize migration runner: %w", err)
+		}
+
+		statuses, err := runner.Status()
+		if err != nil {
+			return err
+		}
+
+		formatter := output.NewFormatter(verbose)
+		formatter.PrintMigrations(statuses)
+
+		return nil
+	},
+}
+
+// dbMigrateUpCmd represents the command to apply pending migrations
+var dbMigrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply all pending schema migrations",
+	Long:  `Applies all pending schema migrations in ascending version order. Each migration runs in its own transaction.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		runner, err := migrationRunner()
+		if err != nil {
+			return fmt.Errorf("failed to initialize migration runner: %w", err)
+		}
+
+		applied, err := runner.Up()
+		if err != nil {
+			return err
+		}
+
+		if len(applied) == 0 {
+			output.PrintSuccess("Database schema is already up to date", nil)
+			return nil
+		}
+
+		output.PrintSuccess("Schema migrations applied", map[string]any{
+			"count":   len(applied),
+			"version": applied[len(applied)-1].Version,
+		})
+
+		return nil
+	},
+}
+
+// dbMigrateDownCmd represents the command to revert applied migrations
+var dbMigrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the most recent schema migrations",
+	Long: `Reverts the most recently applied schema migrations in descending version order.
+
+WARNING: Reverting a migration may drop tables or columns and the data they contain.
+Create a backup before reverting migrations.
+
+Examples:
+  ffcli db migrate down
+  ffcli db migrate down --steps 2`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		steps, _ := cmd.Flags().GetInt("steps")
+
+		runner, err := migrationRunner()
+		if err != nil {
+			return fmt.Errorf("failed to initialize migration runner: %w", err)
+		}
+
+		reverted, err := runner.Down(steps)
+		if err != nil {
+			return err
+		}
+
+		if len(reverted) == 0 {
+			output.PrintSuccess("No applied migrations to revert", nil)
+			return nil
+		}
+
+		output.PrintSuccess("Schema migrations reverted", map[string]any{
+			"count":   len(reverted),
+			"version": reverted[len(reverted)-1].Version,
+		})
+
+		return nil
+	},
+}
+
+// dbBlobsCmd represents the blobs command group
+var dbBlobsCmd = &cobra.Command{
+	Use:   "blobs",
+	Short: "Blob store commands",
+	Long: `Commands for the blob store holding the encrypted contents and previews of document files.
+
+The blob store is configured with FF_BLOB_STORE. Run ffcli db migrate up before using these commands.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// dbBlobsMigrateCmd represents the command to move file contents from the database to the blob store
+var dbBlobsMigrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Move file contents from the database to the blob store",
+	Long: `Moves the encrypted contents and previews of document files that are still stored in the
+database to the configured blob store. The migration can be interrupted and run again,
+and the web UI may keep running while it is in progress.
+
+The database file only shrinks after a VACUUM, which --vacuum runs after the migration.
+
+Examples:
+  ffcli db blobs migrate
+  ffcli db blobs migrate --vacuum`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		vacuum, _ := cmd.Flags().GetBool("vacuum")
+
+		store, err := requireBlobStore()
+		if err != nil {
+			return err
+		}
+		db, err := database()
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+
+		migrator := documents.NewDefaultDocumentBlobMigrator(db, store, logger)
+		summary, err := migrator.MigrateToBlobStore(context.Background())
+		if err != nil {
+			return err
+		}
+
+		if vacuum {
+			if _, err := db.Exec("VACUUM"); err != nil {
+				return fmt.Errorf("failed to vacuum database: %w", err)
+			}
+		}
+
+		output.PrintSuccess("File contents moved to blob store", map[string]any{
+			"store":    store.Name(),
+			"files":    summary.Files,
+			"previews": summary.Previews,
+			"bytes":    summary.Bytes,
+			"skipped":  summary.Skipped,
+		})
+		if summary.Skipped > 0 {
+			output.PrintWarning("Some files were modified during the migration, run the command again to move them")
+		}
+
+		return nil
+	},
+}
+
+// dbBlobsGcCmd represents the command to delete unreferenced blobs
+var dbBlobsGcCmd = &cobra.Command{
+	Use:   "gc",
+	Short: "Delete blobs that are no longer referenced",
+	Long: `Deletes the blobs of deleted or replaced files from the blob store. Blobs stored within the
+last 24 hours are kept, so that uploads in progress are not affected, as are blobs referenced by
+the backups in the backup directory. Nothing is deleted if the references of a backup cannot be
+read, e.g. because an encrypted backup needs a passphrase that is no longer configured.
+
+Examples:
+  ffcli db blobs gc --dry-run
+  ffcli db blobs gc`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+		store, err := requireBlobStore()
+		if err != nil {
+			return err
+		}
+		db, err := database()
+		if err != nil {
+			return fmt.Errorf("failed to open database: %w", err)
+		}
+
+		backupSvc, err := backupService()
+		if err != nil {
+			return err
+		}
+
+		// Blobs referenced only by backups are kept, so that restoring a backup does not leave files without content
+		sources := []blobstore.BlobReferenceSource{documents.NewSQLiteDocumentBlobReferenceRepository(db), backupSvc}
+		garbageCollector := blobstore.NewDefaultBlobGarbageCollector(store, sources, logger)
+		storedBefore := time.Now().Add(-blobstore.GarbageCollectionGracePeriod)
+		summary, err := garbageCollector.CollectGarbage(context.Background(), storedBefore, dryRun)
+		if err != nil {
+			return err
+		}
+
+		message := "Unreferenced blobs deleted"
+		if dryRun {
+			message = "Dry run, no blobs were deleted"
+		}
+		output.PrintSuccess(message, map[string]any{
+			"store":         store.Name(),
+			"scanned":       summary.Scanned,
+			"unreferenced":  summary.Unreferenced,
+			"deleted":       summary.Deleted,
+			"deleted_bytes": summary.DeletedBytes,
+		})
+
+		return nil
+	},
+}
+
+// isDbCommand reports whether the given command belongs to the db command group
+func isDbCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == dbCmd {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	dbMigrateDownCmd.Flags().Int("steps", 1, "number of migrations to revert")
+
+	dbMigrateCmd.AddCommand(dbMigrateStatusCmd)
+	dbMigrateCmd.AddCommand(dbMigrateUpCmd)
+	dbMigrateCmd.AddCommand(dbMigrateDownCmd)
+
+	dbBlobsMigrateCmd.Flags().Bool("vacuum", false, "vacuum the database after the migration to shrink the database file")
+	dbBlobsGcCmd.Flags().Bool("dry-run", false, "only count the unreferenced blobs without deleting them")
+
+	dbBlobsCmd.AddCommand(dbBlobsMigrateCmd)
+	dbBlobsCmd.AddCommand(dbBlobsGcCmd)
+
+	dbCmd.AddCommand(dbMigrateCmd)
+	dbCmd.AddCommand(dbBlobsCmd)
+
+	rootCmd.AddCommand(dbCmd)
+}
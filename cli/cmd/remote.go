@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/client"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+	"github.com/spf13/cobra"
+)
+
+const (
+	// envRemoteUrl holds the URL of the server used by the remote commands, unless --server is given
+	envRemoteUrl = "FF_REMOTE_URL"
+	// envApiToken holds the personal API token used by the remote commands
+	envApiToken = "FF_API_TOKEN"
+)
+
+// remoteClient creates an API client for the server given by --server or FF_REMOTE_URL
+func remoteClient(cmd *cobra.Command) (*client.Client, error) {
+	serverUrl, _ := cmd.Flags().GetString("server")
+	if serverUrl == "" {
+		serverUrl = os.Getenv(envRemoteUrl)
+	}
+	if serverUrl == "" {
+		return nil, ccc.NewInvalidInputErrorWithMessage("server", "no server URL", fmt.Sprintf("Set the URL of the server with --server or %s.", envRemoteUrl))
+	}
+
+	token := os.Getenv(envApiToken)
+	if token == "" {
+		return nil, ccc.NewInvalidInputErrorWithMessage("token", "no API token", fmt.Sprintf("Set a personal API token in %s.", envApiToken))
+	}
+
+	return client.NewClient(serverUrl, token, nil), nil
+}
+
+// remoteError converts an error of the API client to an ApiError, so it is reported like errors of local commands
+func remoteError(err error) error {
+	var clientErr *client.Error
+	if !errors.As(err, &clientErr) {
+		return err
+	}
+	userMessage := clientErr.Message
+	if clientErr.Cause != nil {
+		userMessage = fmt.Sprintf("%s: %v", clientErr.Message, clientErr.Cause)
+	}
+	return &ccc.ApiError{
+		StatusCode:       clientErr.StatusCode,
+		Code:             ccc.ErrorCode(clientErr.Code),
+		UserMessage:      userMessage,
+		TechnicalMessage: clientErr.Error(),
+		Cause:            clientErr.Cause,
+	}
+}
+
+// isRemoteCommand reports whether cmd talks to a server instead of opening the database
+func isRemoteCommand(cmd *cobra.Command) bool {
+	for c := cmd; c != nil; c = c.Parent() {
+		if c == remoteCmd {
+			return true
+		}
+	}
+	return false
+}
+
+// remoteCmd represents the base command for operations against a remote server
+var remoteCmd = &cobra.Command{
+	Use:   "remote",
+	Short: "Access a remote server with a personal API token",
+	Long: `Commands that talk to the REST API of a Frozen Fortress server instead of opening the database, so they work from any machine. The server is given by --server or FF_REMOTE_URL and the personal API token, created on the account page, by FF_API_TOKEN. The token determines the user and needs the secrets:read scope.
+
+Examples:
+  export FF_API_TOKEN=ffpat_...
+  ffcli remote secret get github --server https://vault.example.com`,
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// remoteSecretCmd represents the base command for secret operations against a remote server
+var remoteSecretCmd = &cobra.Command{
+	Use:   "secret",
+	Short: "Read secrets from a remote server",
+	Run: func(cmd *cobra.Command, args []string) {
+		cmd.Help()
+	},
+}
+
+// remoteSecretListCmd represents the command to list the secrets of the token's user
+var remoteSecretListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the secrets of the token's user (names only)",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiClient, err := remoteClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		options := client.SecretListOptions{Page: 1, PageSize: 100, SortBy: "Name", SortAsc: true}
+		var secretList []string
+		for {
+			response, err := apiClient.GetSecrets(context.Background(), options)
+			if err != nil {
+				return remoteError(err)
+			}
+			for _, secret := range response.Secrets {
+				secretList = append(secretList, fmt.Sprintf("- %s (%s)", secret.Name, secrets.SecretType(secret.Type).DisplayName()))
+			}
+			if options.Page >= response.TotalPages {
+				break
+			}
+			options.Page++
+		}
+
+		if len(secretList) == 0 {
+			fmt.Println("No secrets found.")
+			return nil
+		}
+		for _, line := range secretList {
+			fmt.Println(line)
+		}
+		return nil
+	},
+}
+
+// remoteSecretGetCmd represents the command to get a secret from a remote server
+var remoteSecretGetCmd = &cobra.Command{
+	Use:   "get <secret_name>",
+	Short: "Get a specific secret's fields",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiClient, err := remoteClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		secret, err := apiClient.GetSecretByName(context.Background(), args[0])
+		if err != nil {
+			return remoteError(err)
+		}
+
+		fmt.Printf("  Name: %s\n", secret.Name)
+		fmt.Printf("  Type: %s\n", secrets.SecretType(secret.Type).DisplayName())
+		for _, field := range secret.Fields {
+			fmt.Printf("  %s: %s\n", field.Name, field.Value)
+		}
+		if secret.ExpiresAt != nil {
+			fmt.Printf("  Expires: %s\n", secret.ExpiresAt.Format("2006-01-02"))
+		}
+		if secret.RotationDueAt != nil {
+			fmt.Printf("  Rotate every: %d days (next rotation due %s)\n", secret.RotateEvery, secret.RotationDueAt.Format("2006-01-02"))
+		}
+		return nil
+	},
+}
+
+// remoteSecretTotpCmd represents the command to print the current one-time password of a secret on a remote server
+var remoteSecretTotpCmd = &cobra.Command{
+	Use:   "totp <secret_name>",
+	Short: "Print the current one-time password of a secret",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		apiClient, err := remoteClient(cmd)
+		if err != nil {
+			return err
+		}
+
+		secret, err := apiClient.GetSecretByName(context.Background(), args[0])
+		if err != nil {
+			return remoteError(err)
+		}
+		code, err := apiClient.GetTotpCode(context.Background(), secret.Id)
+		if err != nil {
+			return remoteError(err)
+		}
+
+		fmt.Printf("%s (valid for %d more seconds)\n", code.Code, code.SecondsRemaining)
+		return nil
+	},
+}
+
+func init() {
+	remoteCmd.PersistentFlags().String("server", "", "URL of the server, e.g. https://vault.example.com (default $"+envRemoteUrl+")")
+
+	remoteSecretCmd.AddCommand(remoteSecretListCmd)
+	remoteSecretCmd.AddCommand(remoteSecretGetCmd)
+	remoteSecretCmd.AddCommand(remoteSecretTotpCmd)
+	remoteCmd.AddCommand(remoteSecretCmd)
+
+	rootCmd.AddCommand(remoteCmd)
+}
@@ -88,6 +88,12 @@ and does not require authentication as it assumes privileged access.`,
 			fmt.Printf("Using configuration: %s\\n", cfg.String())
 		}
 
+		// Remote commands talk to a server and never open the database
+		if isRemoteCommand(cmd) {
+			logger = ccc.CreateLogger(cfg)
+			return nil
+		}
+
 		// Initialize database (this will be cached via singleton)
 		_, err = database()
 		if err != nil {
@@ -97,9 +103,23 @@ and does not require authentication as it assumes privileged access.`,
 		// Initialize logger
 		logger = ccc.CreateLogger(cfg)
 
+		// Apply pending schema migrations, unless the command manages migrations explicitly
+		if !isDbCommand(cmd) {
+			runner, err := migrationRunner()
+			if err != nil {
+				return fmt.Errorf("failed to initialize migration runner: %w", err)
+			}
+			if _, err := runner.Up(); err != nil {
+				return err
+			}
+		}
+
 		return nil
 	},
 	PersistentPostRunE: func(cmd *cobra.Command, args []string) error {
+		if isRemoteCommand(cmd) {
+			return nil
+		}
 		// Clean up database connection
 		return cleanupResources()
 	},
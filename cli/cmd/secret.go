@@ -8,10 +8,12 @@ import (
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
 	"github.com/spf13/cobra"
 	"golang.org/x/term"
@@ -82,15 +84,43 @@ var signInHandler = func() func() (auth.SignInHandler, error) {
 				return
 			}
 
+			credentialRepo, err := auth.NewSQLiteWebAuthnCredentialRepository(db)
+			if err != nil {
+				initErr = err
+				return
+			}
+
 			config := ccc.LoadConfigFromEnv()
 
 			encServiceInstance := encryptionService()
 
+			secretMgr, err := secretManager()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			blobs, err := blobStore()
+			if err != nil {
+				initErr = err
+				return
+			}
+			tagMgr := documents.NewDefaultTagManager(documents.NewDocumentUnitOfWorkFactory(db, blobs), ccc.NewUuidGenerator(), logger)
+
+			signInListeners := []auth.SignInListener{
+				secrets.NewNameIndexSignInListener(secretMgr, encServiceInstance, logger),
+				documents.NewTagNameSignInListener(tagMgr, encServiceInstance, logger),
+			}
+
 			instance = auth.NewDefaultSignInHandler(
 				userRepo,
 				signInHistoryRepo,
 				secService,
 				encServiceInstance,
+				auth.NewDefaultTotpService(),
+				credentialRepo,
+				auth.NewDefaultWebAuthnService(encServiceInstance, logger),
+				signInListeners,
 				config,
 				logger,
 			)
@@ -121,7 +151,13 @@ var secretManager = func() func() (secrets.SecretManager, error) {
 				return
 			}
 
-			instance = secrets.NewDefaultSecretManager(repo, idGen, userRepo, logger)
+			cfg, err := appConfig()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			instance = secrets.NewDefaultSecretManager(repo, idGen, userRepo, cfg.SecretHistoryLimit, logger)
 		})
 		return instance, initErr
 	}
@@ -181,13 +217,51 @@ func authenticateUser(userDto auth.UserDto, password string) error {
 		return fmt.Errorf("authentication failed: %w", err)
 	}
 
+	// Users with two-factor authentication have to provide a code as well
+	if result.RequiresTotp {
+		code, err := promptForTotpCode()
+		if err != nil {
+			return err
+		}
+
+		result, err = handler.HandleTotpSignIn(auth.TotpSignInRequest{
+			UserId:            result.User.Id,
+			Mek:               result.Mek,
+			Code:              code,
+			ChallengeIssuedAt: time.Now(),
+		}, context)
+		if err != nil {
+			return fmt.Errorf("authentication failed: %w", err)
+		}
+	}
+
 	if !result.Success {
 		return fmt.Errorf("authentication failed: %s", result.ErrorMessage)
 	}
 
+	for _, notice := range result.Notices {
+		fmt.Println("Note: " + notice)
+	}
+
 	return nil
 }
 
+// promptForTotpCode prompts the user for the code of the authenticator app
+func promptForTotpCode() (string, error) {
+	fmt.Print("Enter authentication code: ")
+	reader := bufio.NewReader(os.Stdin)
+	code, err := reader.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("authentication code input failed: %w", err)
+	}
+
+	code = strings.TrimSpace(code)
+	if code == "" {
+		return "", fmt.Errorf("authentication code cannot be empty")
+	}
+	return code, nil
+}
+
 // prepareSecretOperation encapsulates common steps for secret operations.
 func prepareSecretOperation(userIdentifier string) (auth.UserDto, dataprotection.DataProtector, secrets.SecretManager, error) {
 	// 1. Resolve user identifier
@@ -232,16 +306,132 @@ var secretCmd = &cobra.Command{
 	},
 }
 
+// secretFieldsFromFlags parses the --field flags of a command in the given order. Fields named like a
+// default field of the secret type are concealed like it, others only if they are listed in --conceal.
+func secretFieldsFromFlags(cmd *cobra.Command, secretType secrets.SecretType) ([]secrets.SecretField, error) {
+	fieldFlags, _ := cmd.Flags().GetStringArray("field")
+	concealFlags, _ := cmd.Flags().GetStringArray("conceal")
+
+	concealed := make(map[string]bool)
+	for _, field := range secretType.DefaultFields() {
+		concealed[strings.ToLower(field.Name)] = field.Concealed
+	}
+	for _, name := range concealFlags {
+		concealed[strings.ToLower(strings.TrimSpace(name))] = true
+	}
+
+	fields := make([]secrets.SecretField, 0, len(fieldFlags))
+	for _, flag := range fieldFlags {
+		name, value, ok := strings.Cut(flag, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid field %q, expected name=value", flag)
+		}
+		name = strings.TrimSpace(name)
+		fields = append(fields, secrets.SecretField{Name: name, Value: value, Concealed: concealed[strings.ToLower(name)]})
+	}
+	return withTotpFlag(cmd, fields), nil
+}
+
+// withTotpFlag replaces the one-time password field of the given fields with the seed of the --totp flag, if set
+func withTotpFlag(cmd *cobra.Command, fields []secrets.SecretField) []secrets.SecretField {
+	seed, _ := cmd.Flags().GetString("totp")
+	if seed == "" {
+		return fields
+	}
+
+	totp := secrets.SecretField{Name: secrets.TotpFieldName, Value: seed, Concealed: true, Kind: secrets.FieldKindTotp}
+	result := make([]secrets.SecretField, 0, len(fields)+1)
+	for _, field := range fields {
+		if field.Kind == secrets.FieldKindTotp {
+			totp.Name = field.Name
+			continue
+		}
+		result = append(result, field)
+	}
+	return append(result, totp)
+}
+
+// secretScheduleFromFlags applies the --expires and --rotate-every flags of a command to the given expiry
+// date and rotation interval. --expires takes a date as YYYY-MM-DD or "never".
+func secretScheduleFromFlags(cmd *cobra.Command, expiresAt *time.Time, rotateEvery int) (*time.Time, int, error) {
+	if cmd.Flags().Changed("expires") {
+		value, _ := cmd.Flags().GetString("expires")
+		if value == "never" {
+			expiresAt = nil
+		} else {
+			parsed, err := time.Parse("2006-01-02", value)
+			if err != nil {
+				return nil, 0, fmt.Errorf("invalid expiry date %q, expected YYYY-MM-DD or never", value)
+			}
+			expiresAt = &parsed
+		}
+	}
+	if cmd.Flags().Changed("rotate-every") {
+		rotateEvery, _ = cmd.Flags().GetInt("rotate-every")
+	}
+	return expiresAt, rotateEvery, nil
+}
+
+// secretScheduleFlagsChanged reports whether the expiry date or rotation interval is set on the command line
+func secretScheduleFlagsChanged(cmd *cobra.Command) bool {
+	return cmd.Flags().Changed("expires") || cmd.Flags().Changed("rotate-every")
+}
+
 // secretAddCmd represents the command to add a new secret
 var secretAddCmd = &cobra.Command{
-	Use:   "add <user_identifier> <secret_name> <secret_value>",
+	Use:   "add <user_identifier> <secret_name> [secret_value]",
 	Short: "Add a new secret for a user. Requires user authentication.",
-	Long:  `Adds a new secret for the specified user. You need to provide the user's identifier (username or ID), the secret name, and the secret value as arguments. This command requires user authentication.`,
-	Args:  cobra.ExactArgs(3), // Expect exactly 3 arguments
+	Long: `Adds a new secret for the specified user. You need to provide the user's identifier (username or ID), the secret name, and either the secret value or the fields of the secret. This command requires user authentication.
+
+The type (login, api_key, note, card, ssh_key or generic) defaults to generic. A secret value is stored in the first concealed field of the type. Fields are given as name=value in the order they should be shown; fields named like a default field of the type are concealed like it, others can be concealed with --conceal. --totp adds a one-time password field holding an otpauth:// URI or base32 seed, whose codes 'ffcli secret totp' prints.
+
+--expires sets the date the credential expires on and --rotate-every the number of days after which its value should be changed. The web UI reminds of both ahead of time.
+
+Examples:
+  ffcli secret add john.doe "Database" "s3cret"
+  ffcli secret add john.doe "GitHub" --type login --field Username=octo --field Password=hunter2 --field URL=https://github.com
+  ffcli secret add john.doe "AWS root" --type login --field Username=root --field Password=s3cret --totp JBSWY3DPEHPK3PXP
+  ffcli secret add john.doe "Stripe" --type api_key --field "Key ID=pk_live" --field Secret=sk_live --field Webhook=whsec --conceal Webhook
+  ffcli secret add john.doe "VPN certificate" "s3cret" --expires 2026-03-31 --rotate-every 90`,
+	Args: cobra.RangeArgs(2, 3),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		userIdentifier := args[0]
 		secretName := args[1]
-		secretValue := args[2]
+		var secretValue string
+		if len(args) > 2 {
+			secretValue = args[2]
+		}
+
+		typeName, _ := cmd.Flags().GetString("type")
+		secretType, err := secrets.ParseSecretType(typeName)
+		if err != nil {
+			return err
+		}
+		fields, err := secretFieldsFromFlags(cmd, secretType)
+		if err != nil {
+			return err
+		}
+		fieldFlags, _ := cmd.Flags().GetStringArray("field")
+		if secretValue != "" && len(fieldFlags) > 0 || secretValue == "" && len(fields) == 0 {
+			return fmt.Errorf("provide either a secret value or --field flags")
+		}
+		if secretValue != "" && len(fields) > 0 {
+			// The value goes into the first concealed default field, next to the one-time password
+			fields = secretType.DefaultFields()
+			for i := range fields {
+				if fields[i].Concealed {
+					fields[i].Value = secretValue
+					break
+				}
+			}
+			fields = withTotpFlag(cmd, fields)
+			secretValue = ""
+		}
+
+		expiresAt, rotateEvery, err := secretScheduleFromFlags(cmd, nil, 0)
+		if err != nil {
+			return err
+		}
 
 		userDto, dataProtector, secretManager, err := prepareSecretOperation(userIdentifier)
 		if err != nil {
@@ -252,6 +442,10 @@ var secretAddCmd = &cobra.Command{
 		request := secrets.UpsertSecretRequest{
 			SecretName:  secretName,
 			SecretValue: secretValue,
+			SecretType:  secretType,
+			Fields:      fields,
+			ExpiresAt:   expiresAt,
+			RotateEvery: rotateEvery,
 		}
 
 		createResp, err := secretManager.CreateSecret(userDto.Id, request, dataProtector)
@@ -259,21 +453,42 @@ var secretAddCmd = &cobra.Command{
 			return fmt.Errorf("failed to create secret: %w", err)
 		}
 
-		fmt.Printf("Secret '%s' created successfully for user '%s' (ID: %s). Secret ID: %s\\n", secretName, userDto.UserName, userDto.Id, createResp.SecretId)
+		fmt.Printf("Secret '%s' created successfully for user '%s' (ID: %s). Secret ID: %s\n", secretName, userDto.UserName, userDto.Id, createResp.SecretId)
 		return nil
 	},
 }
 
 // secretEditCmd represents the command to edit an existing secret
 var secretEditCmd = &cobra.Command{
-	Use:   "edit <user_identifier> <secret_name> <new_secret_value>",
-	Short: "Edit an existing secret's value. Requires user authentication.",
-	Long:  `Updates the value of an existing secret for the specified user. The secret is identified by its current name. This command requires user authentication.`,
-	Args:  cobra.ExactArgs(3),
+	Use:   "edit <user_identifier> <secret_name> [new_secret_value]",
+	Short: "Edit an existing secret's value or fields. Requires user authentication.",
+	Long: `Updates an existing secret for the specified user. The secret is identified by its current name. This command requires user authentication.
+
+A new secret value replaces the primary value of the secret, e.g. the password of a login, and keeps its other fields. --field flags replace all fields of the secret, --type changes its type and --totp sets the seed of its one-time password field. --expires changes the expiry date, "never" removes it, and --rotate-every the rotation interval in days, 0 turns it off.
+
+Examples:
+  ffcli secret edit john.doe "GitHub" "new password"
+  ffcli secret edit john.doe "GitHub" --field Username=octo --field Password=hunter3
+  ffcli secret edit john.doe "Database" --type login
+  ffcli secret edit john.doe "VPN certificate" --expires 2027-03-31`,
+	Args: cobra.RangeArgs(2, 3),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		userIdentifier := args[0]
 		secretName := args[1]
-		newSecretValue := args[2]
+		var newSecretValue string
+		if len(args) > 2 {
+			newSecretValue = args[2]
+		}
+		typeName, _ := cmd.Flags().GetString("type")
+		fieldFlags, _ := cmd.Flags().GetStringArray("field")
+		totpSeed, _ := cmd.Flags().GetString("totp")
+		scheduleChanged := secretScheduleFlagsChanged(cmd)
+		if newSecretValue != "" && (typeName != "" || len(fieldFlags) > 0 || totpSeed != "" || scheduleChanged) {
+			return fmt.Errorf("a new secret value cannot be combined with --type, --field, --totp, --expires or --rotate-every")
+		}
+		if newSecretValue == "" && typeName == "" && len(fieldFlags) == 0 && totpSeed == "" && !scheduleChanged {
+			return fmt.Errorf("provide a new secret value, --type, --field, --totp, --expires or --rotate-every")
+		}
 
 		userDto, dataProtector, secretManager, err := prepareSecretOperation(userIdentifier)
 		if err != nil {
@@ -288,11 +503,30 @@ var secretEditCmd = &cobra.Command{
 		}
 		secretIdToUpdate := secretDto.Id
 
-		// Prepare request and update secret
+		// Prepare request and update secret, keeping the original name
 		updateRequest := secrets.UpsertSecretRequest{
-			SecretName:  secretDto.Name, // Keep the original name
+			SecretName:  secretDto.Name,
 			SecretValue: newSecretValue,
 		}
+		if newSecretValue == "" {
+			updateRequest.SecretType = secretDto.Type
+			if typeName != "" {
+				if updateRequest.SecretType, err = secrets.ParseSecretType(typeName); err != nil {
+					return err
+				}
+			}
+			updateRequest.Fields = withTotpFlag(cmd, secretDto.Fields)
+			if len(fieldFlags) > 0 {
+				if updateRequest.Fields, err = secretFieldsFromFlags(cmd, updateRequest.SecretType); err != nil {
+					return err
+				}
+			}
+			// Updates with fields replace the expiry date and rotation interval as well
+			updateRequest.ExpiresAt, updateRequest.RotateEvery, err = secretScheduleFromFlags(cmd, secretDto.ExpiresAt, secretDto.RotateEvery)
+			if err != nil {
+				return err
+			}
+		}
 
 		success, err := secretManager.UpdateSecret(userDto.Id, secretIdToUpdate, updateRequest, dataProtector)
 		if err != nil {
@@ -304,7 +538,7 @@ var secretEditCmd = &cobra.Command{
 			return fmt.Errorf("update operation for secret \"%s\" reported no success but no error either", secretName)
 		}
 
-		fmt.Printf("Secret '%s' updated successfully for user '%s'.\\n", secretName, userDto.UserName)
+		fmt.Printf("Secret '%s' updated successfully for user '%s'.\n", secretName, userDto.UserName)
 		return nil
 	},
 }
@@ -347,7 +581,7 @@ var secretRenameCmd = &cobra.Command{
 			return fmt.Errorf("rename operation for secret '%s' to '%s' reported no success but no error either", oldSecretName, newSecretName)
 		}
 
-		fmt.Printf("Secret '%s' successfully renamed to '%s' for user '%s'.\\n", oldSecretName, newSecretName, userDto.UserName)
+		fmt.Printf("Secret '%s' successfully renamed to '%s' for user '%s'.\n", oldSecretName, newSecretName, userDto.UserName)
 		return nil
 	},
 }
@@ -356,7 +590,7 @@ var secretRenameCmd = &cobra.Command{
 var secretListCmd = &cobra.Command{
 	Use:   "list <user_identifier>",
 	Short: "List a user's secrets (names only). Requires user authentication.",
-	Long:  `Lists the names of all secrets belonging to the specified user. This command requires user authentication.`,
+	Long:  `Lists the names of all secrets belonging to the specified user, together with their expiry and rotation dates. --expiring-within only lists secrets that expire or are due for rotation within the given number of days, soonest first. This command requires user authentication.`,
 	Args:  cobra.ExactArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		userIdentifier := args[0]
@@ -373,6 +607,10 @@ var secretListCmd = &cobra.Command{
 			SortBy:   "Name",
 			SortAsc:  true,
 		}
+		if expiringWithin, _ := cmd.Flags().GetInt("expiring-within"); expiringWithin > 0 {
+			getSecretsRequest.ExpiringWithinDays = expiringWithin
+			getSecretsRequest.SortBy = "ExpiresAt"
+		}
 
 		paginatedResponse, err := secretManager.GetSecrets(userDto.Id, getSecretsRequest, dataProtector)
 		if err != nil {
@@ -380,24 +618,39 @@ var secretListCmd = &cobra.Command{
 		}
 
 		if len(paginatedResponse.Secrets) == 0 {
-			fmt.Printf("No secrets found for user '%s'.\\n", userDto.UserName)
+			fmt.Printf("No secrets found for user '%s'.\n", userDto.UserName)
 			return nil
 		}
 
-		fmt.Printf("Secrets for user '%s':\\n", userDto.UserName)
+		fmt.Printf("Secrets for user '%s':\n", userDto.UserName)
 		for _, secret := range paginatedResponse.Secrets {
-			fmt.Printf("- %s\\n", secret.Name)
+			fmt.Printf("- %s (%s)%s\n", secret.Name, secret.Type.DisplayName(), secretScheduleSummary(secret))
 		}
 
 		return nil
 	},
 }
 
+// secretScheduleSummary describes the expiry and rotation dates of a secret for the list, empty if it has none
+func secretScheduleSummary(secret *secrets.SecretDto) string {
+	var parts []string
+	if secret.ExpiresAt != nil {
+		parts = append(parts, "expires "+secret.ExpiresAt.Format("2006-01-02"))
+	}
+	if secret.RotationDueAt != nil {
+		parts = append(parts, "rotate by "+secret.RotationDueAt.Format("2006-01-02"))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return " - " + strings.Join(parts, ", ")
+}
+
 // secretGetCmd represents the command to get a specific secret's value
 var secretGetCmd = &cobra.Command{
 	Use:   "get <user_identifier> <secret_name>",
-	Short: "Get a specific secret's value. Requires user authentication.",
-	Long:  `Retrieves and displays the name and value of a specific secret for the specified user. This command requires user authentication.`,
+	Short: "Get a specific secret's fields. Requires user authentication.",
+	Long:  `Retrieves and displays the name, type and fields of a specific secret for the specified user. Concealed fields are displayed as well. This command requires user authentication.`,
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		userIdentifier := args[0]
@@ -415,10 +668,107 @@ var secretGetCmd = &cobra.Command{
 			return err // This might be a ccc.ResourceNotFoundError if secret doesn't exist
 		}
 
-		fmt.Printf("Secret Details for user '%s':\\n", userDto.UserName)
-		fmt.Printf("  Name:  %s\\n", secretDto.Name)
-		fmt.Printf("  Value: %s\\n", secretDto.Value)
+		fmt.Printf("Secret Details for user '%s':\n", userDto.UserName)
+		fmt.Printf("  Name: %s\n", secretDto.Name)
+		fmt.Printf("  Type: %s\n", secretDto.Type.DisplayName())
+		for _, field := range secretDto.Fields {
+			fmt.Printf("  %s: %s\n", field.Name, field.Value)
+		}
+		if secretDto.ExpiresAt != nil {
+			fmt.Printf("  Expires: %s\n", secretDto.ExpiresAt.Format("2006-01-02"))
+		}
+		if secretDto.RotationDueAt != nil {
+			fmt.Printf("  Rotate every: %d days (next rotation due %s)\n", secretDto.RotateEvery, secretDto.RotationDueAt.Format("2006-01-02"))
+		}
+
+		return nil
+	},
+}
+
+// secretTotpCmd represents the command to print the current one-time password of a secret
+var secretTotpCmd = &cobra.Command{
+	Use:   "totp <user_identifier> <secret_name>",
+	Short: "Print the current one-time password of a secret. Requires user authentication.",
+	Long: `Generates the current code from the one-time password field of a secret, e.g. one added with --totp, and prints it together with the seconds until the next code. This command requires user authentication.
+
+Examples:
+  ffcli secret totp john.doe "AWS root"`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		userDto, dataProtector, secretManager, err := prepareSecretOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		secretDto, err := secretManager.GetSecretByName(userDto.Id, args[1], dataProtector)
+		if err != nil {
+			return err
+		}
+		code, err := secretManager.GetTotpCode(userDto.Id, secretDto.Id, dataProtector)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%s (valid for %d more seconds)\n", code.Code, code.SecondsRemaining)
+		return nil
+	},
+}
+
+// secretHistoryCmd represents the command to list and restore the prior versions of a secret
+var secretHistoryCmd = &cobra.Command{
+	Use:   "history <user_identifier> <secret_name>",
+	Short: "List the prior versions of a secret. Requires user authentication.",
+	Long: `Lists the prior versions of a secret, newest first, which are recorded whenever the secret is updated. The number of versions kept per secret is set by FF_SECRET_HISTORY_LIMIT. Use --show-values to print the fields of each version and --restore to replace the secret with one of them; the replaced state becomes a version itself. This command requires user authentication.
+
+Examples:
+  ffcli secret history john.doe "AWS root"
+  ffcli secret history john.doe "AWS root" --show-values
+  ffcli secret history john.doe "AWS root" --restore 2`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		showValues, _ := cmd.Flags().GetBool("show-values")
+		restore, _ := cmd.Flags().GetInt("restore")
+
+		userDto, dataProtector, secretManager, err := prepareSecretOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		secretDto, err := secretManager.GetSecretByName(userDto.Id, args[1], dataProtector)
+		if err != nil {
+			return err
+		}
+		history, err := secretManager.GetSecretHistory(userDto.Id, secretDto.Id, dataProtector)
+		if err != nil {
+			return err
+		}
+
+		if cmd.Flags().Changed("restore") {
+			if restore < 1 || restore > len(history) {
+				return fmt.Errorf("secret '%s' has %d prior versions, cannot restore version %d", secretDto.Name, len(history), restore)
+			}
+			version := history[restore-1]
+			if _, err := secretManager.RestoreSecretVersion(userDto.Id, secretDto.Id, version.Id, dataProtector); err != nil {
+				return fmt.Errorf("failed to restore version %d of secret '%s': %w", restore, secretDto.Name, err)
+			}
+			fmt.Printf("Secret '%s' restored to version %d from %s.\n", secretDto.Name, restore, version.ModifiedAt)
+			return nil
+		}
 
+		if len(history) == 0 {
+			fmt.Printf("Secret '%s' has no prior versions.\n", secretDto.Name)
+			return nil
+		}
+
+		fmt.Printf("Prior versions of secret '%s' (newest first):\n", secretDto.Name)
+		for i, version := range history {
+			fmt.Printf("%d. %s - %s (%s), replaced %s\n", i+1, version.ModifiedAt, version.Name, version.Type.DisplayName(), version.ReplacedAt)
+			if showValues {
+				for _, field := range version.Fields {
+					fmt.Printf("     %s: %s\n", field.Name, field.Value)
+				}
+			}
+		}
 		return nil
 	},
 }
@@ -426,8 +776,8 @@ var secretGetCmd = &cobra.Command{
 // secretDeleteCmd represents the command to delete a secret
 var secretDeleteCmd = &cobra.Command{
 	Use:   "delete <user_identifier> <secret_name>",
-	Short: "Delete a specific secret. Requires user authentication.",
-	Long:  `Deletes a specific secret for the specified user. The secret is identified by its name. This command requires user authentication.`,
+	Short: "Move a specific secret to the trash. Requires user authentication.",
+	Long:  `Moves a specific secret of the specified user to the trash, from where it can be restored in the web UI until it is purged. The secret is identified by its name. This command requires user authentication.`,
 	Args:  cobra.ExactArgs(2),
 	RunE: func(cmd *cobra.Command, args []string) error {
 		userIdentifier := args[0]
@@ -459,7 +809,7 @@ var secretDeleteCmd = &cobra.Command{
 			return fmt.Errorf("delete operation for secret '%s' reported no success but no error either", secretName)
 		}
 
-		fmt.Printf("Secret '%s' deleted successfully for user '%s'.\\n", secretName, userDto.UserName)
+		fmt.Printf("Secret '%s' moved to the trash for user '%s'.\n", secretName, userDto.UserName)
 		return nil
 	},
 }
@@ -481,11 +831,26 @@ func createDataProtector(userId, password string) (dataprotection.DataProtector,
 }
 
 func init() {
+	for _, command := range []*cobra.Command{secretAddCmd, secretEditCmd} {
+		command.Flags().StringP("type", "t", "", "secret type: login, api_key, note, card, ssh_key or generic")
+		command.Flags().StringArray("field", nil, "field of the secret as name=value, can be repeated")
+		command.Flags().StringArray("conceal", nil, "name of a field to conceal, can be repeated")
+		command.Flags().String("totp", "", "otpauth:// URI or base32 seed of a one-time password field")
+		command.Flags().String("expires", "", "date the secret expires on as YYYY-MM-DD, or never")
+		command.Flags().Int("rotate-every", 0, "number of days after which the value should be rotated, 0 = never")
+	}
+	secretListCmd.Flags().Int("expiring-within", 0, "only list secrets that expire or are due for rotation within this many days")
+
+	secretHistoryCmd.Flags().BoolP("show-values", "s", false, "print the fields of each version")
+	secretHistoryCmd.Flags().Int("restore", 0, "number of the version to restore, as listed")
+
 	secretCmd.AddCommand(secretAddCmd)
 	secretCmd.AddCommand(secretEditCmd)
 	secretCmd.AddCommand(secretRenameCmd)
 	secretCmd.AddCommand(secretListCmd)
 	secretCmd.AddCommand(secretGetCmd)
+	secretCmd.AddCommand(secretTotpCmd)
+	secretCmd.AddCommand(secretHistoryCmd)
 	secretCmd.AddCommand(secretDeleteCmd)
 
 	rootCmd.AddCommand(secretCmd)
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sync"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/cli/internal/output"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secretimport"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+	"github.com/spf13/cobra"
+)
+
+// secretImporter returns a singleton instance of the SecretImporter
+var secretImporter = func() func() (secretimport.SecretImporter, error) {
+	var instance secretimport.SecretImporter
+	var once sync.Once
+	var initErr error
+
+	return func() (secretimport.SecretImporter, error) {
+		once.Do(func() {
+			secretMgr, err := secretManager()
+			if err != nil {
+				initErr = err
+				return
+			}
+			instance = secretimport.NewDefaultSecretImporter(secretMgr, logger)
+		})
+		return instance, initErr
+	}
+}()
+
+// secretImportCmd represents the command to import secrets from another password manager
+var secretImportCmd = &cobra.Command{
+	Use:   "import <user_identifier> <file>",
+	Short: "Import secrets from a Bitwarden, KeePass or 1Password export. Requires user authentication.",
+	Long: `Creates a secret for each entry of an export file of another password manager.
+Supported formats are:
+
+  bitwarden-json   unencrypted JSON export of Bitwarden
+  bitwarden-csv    CSV export of Bitwarden
+  keepass-xml      "KeePass XML (2.x)" export of KeePass or KeePassXC
+  1password-csv    CSV export of 1Password
+
+KeePass databases (.kdbx) have to be exported to XML first.
+
+The value of a secret is the password of the entry, or its notes if it has no
+password. Usernames, URLs and the notes of entries with a password are not
+imported. Secrets are stored in batches of 100, each in one transaction; if the
+import fails, the batches stored up to that point are kept.
+
+--on-conflict decides what happens if a secret with the same name exists:
+  rename     import it as "<name> (imported)" (default)
+  skip       keep the existing secret
+  overwrite  replace the value of the existing secret
+
+Examples:
+  ffcli secret import john.doe bitwarden_export.json --format bitwarden-json
+  ffcli secret import john.doe keepass.xml --format keepass-xml --on-conflict skip`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		formatName, _ := cmd.Flags().GetString("format")
+		onConflict, _ := cmd.Flags().GetString("on-conflict")
+
+		// Validate the options before asking for the password
+		format, err := secretimport.ParseFormat(formatName)
+		if err != nil {
+			return err
+		}
+		policy, err := secrets.ParseConflictPolicy(onConflict)
+		if err != nil {
+			return err
+		}
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to open export file: %w", err)
+		}
+		defer file.Close()
+
+		userDto, dataProtector, _, err := prepareSecretOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		importer, err := secretImporter()
+		if err != nil {
+			return fmt.Errorf("failed to initialize secret importer: %w", err)
+		}
+
+		report, err := importer.ImportSecrets(userDto.Id, secretimport.ImportRequest{
+			Format:     format,
+			Source:     file,
+			OnConflict: policy,
+		}, dataProtector)
+		if report != nil {
+			for _, renamed := range report.Renamed {
+				output.PrintWarning(fmt.Sprintf("Secret '%s' already exists and was imported as '%s'", renamed.OriginalName, renamed.Name))
+			}
+			for _, skipped := range report.Skipped {
+				output.PrintWarning(fmt.Sprintf("Skipped '%s': %s", skipped.Name, skipped.Reason))
+			}
+		}
+		if err != nil {
+			if report != nil && report.Created+report.Overwritten > 0 {
+				output.PrintWarning(fmt.Sprintf("The import stopped after %d secrets were created and %d overwritten",
+					report.Created, report.Overwritten))
+			}
+			return err
+		}
+
+		output.PrintSuccess("Secrets imported successfully", map[string]interface{}{
+			"user":        userDto.UserName,
+			"format":      report.Format.DisplayName(),
+			"entries":     report.Entries,
+			"created":     report.Created,
+			"overwritten": report.Overwritten,
+			"renamed":     len(report.Renamed),
+			"skipped":     len(report.Skipped),
+		})
+		return nil
+	},
+}
+
+func init() {
+	secretImportCmd.Flags().StringP("format", "f", "", "format of the export file: bitwarden-json, bitwarden-csv, keepass-xml or 1password-csv")
+	secretImportCmd.Flags().String("on-conflict", string(secrets.ConflictRename), "how to handle secrets whose name exists: rename, skip or overwrite")
+	secretImportCmd.MarkFlagRequired("format")
+
+	secretCmd.AddCommand(secretImportCmd)
+}
@@ -11,6 +11,7 @@ import (
 	"slices"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/cli/internal/output"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/spf13/cobra"
 )
@@ -168,6 +169,119 @@ func runSetup(readOnly bool) error {
 			Type:         "string",
 			Validation:   validateLogLevel,
 		},
+		{
+			EnvVar:       ccc.EnvHealthToken,
+			Description:  "Bearer token for the admin health endpoint (leave empty to disable it)",
+			CurrentValue: currentConfig.HealthToken,
+			DefaultValue: defaultConfig.HealthToken,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvSecretHistoryLimit,
+			Description:  "Number of prior versions kept per secret (0 = history disabled)",
+			CurrentValue: strconv.Itoa(currentConfig.SecretHistoryLimit),
+			DefaultValue: strconv.Itoa(defaultConfig.SecretHistoryLimit),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvTrashRetentionDays,
+			Description:  "Days deleted items stay in the trash before they are purged (0 = never purged)",
+			CurrentValue: strconv.Itoa(currentConfig.TrashRetentionDays),
+			DefaultValue: strconv.Itoa(defaultConfig.TrashRetentionDays),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvReminderLeadDays,
+			Description:  "Days before an expiry or rotation date users are reminded of it",
+			CurrentValue: strconv.Itoa(currentConfig.ReminderLeadDays),
+			DefaultValue: strconv.Itoa(defaultConfig.ReminderLeadDays),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvSMTPHost,
+			Description:  "Host of the SMTP relay for reminder mails (leave empty to disable mails)",
+			CurrentValue: currentConfig.SMTP.Host,
+			DefaultValue: defaultConfig.SMTP.Host,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvSMTPPort,
+			Description:  "Port of the SMTP relay",
+			CurrentValue: strconv.Itoa(currentConfig.SMTP.Port),
+			DefaultValue: strconv.Itoa(defaultConfig.SMTP.Port),
+			Type:         "int",
+			Validation:   validatePort,
+		},
+		{
+			EnvVar:       ccc.EnvSMTPUsername,
+			Description:  "User name for the SMTP relay (leave empty for no authentication)",
+			CurrentValue: currentConfig.SMTP.Username,
+			DefaultValue: defaultConfig.SMTP.Username,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvSMTPPassword,
+			Description:  "Password for the SMTP relay",
+			CurrentValue: currentConfig.SMTP.Password,
+			DefaultValue: defaultConfig.SMTP.Password,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvSMTPFrom,
+			Description:  "Sender address of reminder mails",
+			CurrentValue: currentConfig.SMTP.From,
+			DefaultValue: defaultConfig.SMTP.From,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvSMTPSecurity,
+			Description:  "SMTP connection security (starttls, tls or none for a local mail catcher)",
+			CurrentValue: currentConfig.SMTP.Security,
+			DefaultValue: defaultConfig.SMTP.Security,
+			Type:         "string",
+			Validation:   validateSMTPSecurity,
+		},
+		{
+			EnvVar:       ccc.EnvBlobStore,
+			Description:  "Where document file contents are stored (database, filesystem or s3)",
+			CurrentValue: currentConfig.BlobStore.Type,
+			DefaultValue: defaultConfig.BlobStore.Type,
+			Type:         "string",
+			Validation:   validateBlobStore,
+		},
+		{
+			EnvVar:       ccc.EnvBlobStoreDirectory,
+			Description:  "Directory of the filesystem blob store",
+			CurrentValue: currentConfig.BlobStore.Directory,
+			DefaultValue: defaultConfig.BlobStore.Directory,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvBlobStoreS3Url,
+			Description:  "Bucket URL of the s3 blob store (s3://ACCESS_KEY:SECRET_KEY@host/bucket)",
+			CurrentValue: currentConfig.BlobStore.S3Url,
+			DefaultValue: defaultConfig.BlobStore.S3Url,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvBlobGCIntervalHours,
+			Description:  "Hours between deletions of unreferenced blobs (0 = only with ffcli db blobs gc)",
+			CurrentValue: strconv.Itoa(currentConfig.BlobStore.GCIntervalHours),
+			DefaultValue: strconv.Itoa(defaultConfig.BlobStore.GCIntervalHours),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvMaxUploadSizeMB,
+			Description:  "Maximum size of an uploaded document file in MB (at most 30 without a blob store)",
+			CurrentValue: strconv.Itoa(currentConfig.MaxUploadSizeMB),
+			DefaultValue: strconv.Itoa(defaultConfig.MaxUploadSizeMB),
+			Type:         "int",
+			Validation:   validatePositiveInt,
+		},
 		{
 			EnvVar:       ccc.EnvBackupEnabled,
 			Description:  "Enable automatic backups (true/false)",
@@ -178,12 +292,20 @@ func runSetup(readOnly bool) error {
 		},
 		{
 			EnvVar:       ccc.EnvBackupIntervalDays,
-			Description:  "Backup interval in days (0 = disabled)",
+			Description:  "Backup interval in days (0 = disabled), used if no schedule is set",
 			CurrentValue: strconv.Itoa(currentConfig.Backup.IntervalDays),
 			DefaultValue: strconv.Itoa(defaultConfig.Backup.IntervalDays),
 			Type:         "int",
 			Validation:   validateNonNegativeInt,
 		},
+		{
+			EnvVar:       ccc.EnvBackupSchedule,
+			Description:  "Backup schedule as cron expression, @daily or time of day like 03:00 (leave empty to use the interval)",
+			CurrentValue: currentConfig.Backup.Schedule,
+			DefaultValue: defaultConfig.Backup.Schedule,
+			Type:         "string",
+			Validation:   validateBackupSchedule,
+		},
 		{
 			EnvVar:       ccc.EnvBackupDirectory,
 			Description:  "Directory where backup files are stored",
@@ -193,11 +315,51 @@ func runSetup(readOnly bool) error {
 		},
 		{
 			EnvVar:       ccc.EnvBackupMaxGenerations,
-			Description:  "Maximum number of backup files to keep",
+			Description:  "Number of newest backups to keep (0 = rely on the daily/weekly/monthly/yearly rules)",
 			CurrentValue: strconv.Itoa(currentConfig.Backup.MaxGenerations),
 			DefaultValue: strconv.Itoa(defaultConfig.Backup.MaxGenerations),
 			Type:         "int",
-			Validation:   validatePositiveInt,
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvBackupKeepDaily,
+			Description:  "Keep the newest backup of each of the last N days (0 = disabled)",
+			CurrentValue: strconv.Itoa(currentConfig.Backup.KeepDaily),
+			DefaultValue: strconv.Itoa(defaultConfig.Backup.KeepDaily),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvBackupKeepWeekly,
+			Description:  "Keep the newest backup of each of the last N weeks (0 = disabled)",
+			CurrentValue: strconv.Itoa(currentConfig.Backup.KeepWeekly),
+			DefaultValue: strconv.Itoa(defaultConfig.Backup.KeepWeekly),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvBackupKeepMonthly,
+			Description:  "Keep the newest backup of each of the last N months (0 = disabled)",
+			CurrentValue: strconv.Itoa(currentConfig.Backup.KeepMonthly),
+			DefaultValue: strconv.Itoa(defaultConfig.Backup.KeepMonthly),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvBackupKeepYearly,
+			Description:  "Keep the newest backup of each of the last N years (0 = disabled)",
+			CurrentValue: strconv.Itoa(currentConfig.Backup.KeepYearly),
+			DefaultValue: strconv.Itoa(defaultConfig.Backup.KeepYearly),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvBackupPruneManual,
+			Description:  "Apply the retention rules to manual backups too (true/false)",
+			CurrentValue: strconv.FormatBool(currentConfig.Backup.PruneManual),
+			DefaultValue: strconv.FormatBool(defaultConfig.Backup.PruneManual),
+			Type:         "bool",
+			Validation:   validateBool,
 		},
 		{
 			EnvVar:       ccc.EnvOcrEnabled,
@@ -254,7 +416,7 @@ func runSetup(readOnly bool) error {
 		},
 		{
 			EnvVar:       ccc.EnvOCRMaxAttempts,
-			Description:  "Maximum async OCR attempts per upload",
+			Description:  "Maximum OCR attempts per queued file",
 			CurrentValue: strconv.Itoa(currentConfig.OCR.MaxAttempts),
 			DefaultValue: strconv.Itoa(defaultConfig.OCR.MaxAttempts),
 			Type:         "int",
@@ -284,6 +446,44 @@ func runSetup(readOnly bool) error {
 			Type:         "int",
 			Validation:   validatePositiveInt,
 		},
+		{
+			EnvVar:       ccc.EnvOCRWorkers,
+			Description:  "Number of background OCR workers",
+			CurrentValue: strconv.Itoa(currentConfig.OCR.Workers),
+			DefaultValue: strconv.Itoa(defaultConfig.OCR.Workers),
+			Type:         "int",
+			Validation:   validatePositiveInt,
+		},
+		{
+			EnvVar:       ccc.EnvPDFRendererPath,
+			Description:  "Path of the pdftoppm executable rendering PDF pages (none = no PDF previews and no OCR of scanned pages)",
+			CurrentValue: currentConfig.PDF.RendererPath,
+			DefaultValue: defaultConfig.PDF.RendererPath,
+			Type:         "string",
+		},
+		{
+			EnvVar:       ccc.EnvPDFRenderDPI,
+			Description:  "Resolution in DPI scanned PDF pages are rendered at for OCR",
+			CurrentValue: strconv.Itoa(currentConfig.PDF.RenderDPI),
+			DefaultValue: strconv.Itoa(defaultConfig.PDF.RenderDPI),
+			Type:         "int",
+			Validation:   validatePositiveInt,
+		},
+		{
+			EnvVar:       ccc.EnvPDFMaxOCRPages,
+			Description:  "Maximum number of scanned pages per PDF or TIFF file sent to OCR",
+			CurrentValue: strconv.Itoa(currentConfig.PDF.MaxOCRPages),
+			DefaultValue: strconv.Itoa(defaultConfig.PDF.MaxOCRPages),
+			Type:         "int",
+			Validation:   validateNonNegativeInt,
+		},
+		{
+			EnvVar:       ccc.EnvImageConverterPath,
+			Description:  "Path of the ImageMagick executable converting HEIC images (none = no previews and no OCR of HEIC images)",
+			CurrentValue: currentConfig.ImageConverterPath,
+			DefaultValue: defaultConfig.ImageConverterPath,
+			Type:         "string",
+		},
 	}
 
 	// Collect user input for each configuration item or just display them
@@ -549,6 +749,13 @@ func validateLogLevel(value string) (string, error) {
 	return "", fmt.Errorf("must be one of: %s", strings.Join(validLevels, ", "))
 }
 
+func validateBackupSchedule(value string) (string, error) {
+	if _, err := backup.ParseCronSchedule(value); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(value), nil
+}
+
 func validateRedisNetwork(value string) (string, error) {
 	if value == "" {
 		return "", fmt.Errorf("value cannot be empty")
@@ -609,6 +816,24 @@ func validateOCRLanguages(value string) (string, error) {
 	return strings.Join(validatedLanguages, ","), nil
 }
 
+func validateSMTPSecurity(value string) (string, error) {
+	security := strings.ToLower(strings.TrimSpace(value))
+	validModes := []string{"starttls", "tls", "none"}
+	if slices.Contains(validModes, security) {
+		return security, nil
+	}
+	return "", fmt.Errorf("invalid SMTP security: %s (valid: %s)", value, strings.Join(validModes, ", "))
+}
+
+func validateBlobStore(value string) (string, error) {
+	storeType := strings.ToLower(strings.TrimSpace(value))
+	validTypes := []string{"database", "filesystem", "s3"}
+	if slices.Contains(validTypes, storeType) {
+		return storeType, nil
+	}
+	return "", fmt.Errorf("invalid blob store: %s (valid: %s)", value, strings.Join(validTypes, ", "))
+}
+
 func validateOCRProvider(value string) (string, error) {
 	provider := strings.ToLower(strings.TrimSpace(value))
 	validProviders := []string{"ollama-tesseract", "ollama", "tesseract", "nop"}
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"github.com/Yeti47/frozenfortress/frozenfortress/cli/internal/output"
+	"github.com/spf13/cobra"
+)
+
+// twoFactorCmd represents the 2fa command group
+var twoFactorCmd = &cobra.Command{
+	Use:   "2fa",
+	Short: "Two-factor authentication commands",
+	Long:  `Commands for managing the two-factor authentication (TOTP) of users.`,
+}
+
+// twoFactorResetCmd represents the 2fa reset command
+var twoFactorResetCmd = &cobra.Command{
+	Use:   "reset <username_or_id>",
+	Short: "Reset two-factor authentication for a user",
+	Long: `Disable two-factor authentication for a user by username or user ID.
+
+Use this if a user has lost access to their authenticator app.
+The user can sign in with their password afterwards and set up
+two-factor authentication again on the account page.
+
+Examples:
+  frozen-fortress user 2fa reset john.doe
+  frozen-fortress user 2fa reset 12345`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		identifier := args[0]
+
+		// Resolve user identifier to get user info
+		user, err := resolveUserIdentifier(identifier)
+		if err != nil {
+			return err
+		}
+
+		userMgr, err := userManager()
+		if err != nil {
+			return err
+		}
+
+		reset, err := userMgr.ResetTotp(user.Id)
+		if err != nil {
+			return err
+		}
+
+		if !reset {
+			output.PrintInfo("Two-factor authentication is not enabled for user " + user.UserName)
+			return nil
+		}
+
+		output.PrintSuccess("Two-factor authentication reset successfully", map[string]interface{}{
+			"userId":   user.Id,
+			"username": user.UserName,
+		})
+
+		return nil
+	},
+}
+
+func init() {
+	twoFactorCmd.AddCommand(twoFactorResetCmd)
+	userCmd.AddCommand(twoFactorCmd)
+}
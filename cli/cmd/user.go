@@ -97,6 +97,7 @@ var userManager = func() func() (auth.UserManager, error) {
 				userIdGenerator,
 				encServiceInstance,
 				secServiceInstance,
+				auth.NewDefaultTotpService(),
 				logger,
 			)
 		})
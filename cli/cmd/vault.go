@@ -0,0 +1,253 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/cli/internal/output"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/vaultarchive"
+	"github.com/spf13/cobra"
+	"golang.org/x/term"
+)
+
+// vaultArchiveManager returns a singleton instance of the VaultArchiveManager
+var vaultArchiveManager = func() func() (vaultarchive.VaultArchiveManager, error) {
+	var instance vaultarchive.VaultArchiveManager
+	var once sync.Once
+	var initErr error
+
+	return func() (vaultarchive.VaultArchiveManager, error) {
+		once.Do(func() {
+			config, err := appConfig()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			db, err := database()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			secretMgr, err := secretManager()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			blobs, err := blobStore()
+			if err != nil {
+				initErr = err
+				return
+			}
+
+			encService := encryptionService()
+			idGenerator := ccc.NewUuidGenerator()
+			uowFactory := documents.NewDocumentUnitOfWorkFactory(db, blobs)
+
+			// Text extraction of imported files is queued for the OCR workers of the web UI,
+			// so the CLI only needs the processors to create previews
+			processorFactory := documents.NewDocumentFileProcessorFactoryForConfig(config, documents.NewNopOCRService(config.OCR, logger), logger)
+			fileCreator := documents.NewDefaultDocumentFileCreator(idGenerator, processorFactory, config.MaxUploadSize(), logger)
+			ocrJobKeyProvider := auth.NewConfigOCRJobKeyProvider(config, encService)
+			ocrDispatcherFactory := documents.NewDefaultOCRDispatcherFactory(idGenerator, encService, ocrJobKeyProvider, nil, logger)
+
+			documentMgr := documents.NewDefaultDocumentManager(uowFactory, idGenerator, fileCreator, ocrDispatcherFactory, logger,
+				documents.NewDefaultDocumentSorter[*documents.DocumentDetails]())
+			documentFileMgr := documents.NewDefaultDocumentFileManager(uowFactory, fileCreator, ocrDispatcherFactory, logger)
+			tagMgr := documents.NewDefaultTagManager(uowFactory, idGenerator, logger)
+			noteMgr := documents.NewDefaultNoteManager(uowFactory, idGenerator, logger)
+
+			instance = vaultarchive.NewDefaultVaultArchiveManager(secretMgr, documentMgr, documentFileMgr, tagMgr, noteMgr, logger)
+		})
+		return instance, initErr
+	}
+}()
+
+// readArchivePassphrase reads the archive passphrase from the given file or prompts for it.
+// When confirm is set, the passphrase has to be entered twice.
+func readArchivePassphrase(passphraseFile string, confirm bool) (string, error) {
+	if passphraseFile != "" {
+		data, err := os.ReadFile(passphraseFile)
+		if err != nil {
+			return "", fmt.Errorf("failed to read passphrase file: %w", err)
+		}
+		return strings.TrimRight(string(data), "\r\n"), nil
+	}
+
+	if !term.IsTerminal(int(syscall.Stdin)) {
+		return "", fmt.Errorf("cannot prompt for the archive passphrase without a terminal; use --passphrase-file")
+	}
+
+	fmt.Print("Enter archive passphrase: ")
+	passphrase, err := term.ReadPassword(int(syscall.Stdin))
+	fmt.Println()
+	if err != nil {
+		return "", fmt.Errorf("passphrase input failed: %w", err)
+	}
+
+	if confirm {
+		fmt.Print("Repeat archive passphrase: ")
+		repeated, err := term.ReadPassword(int(syscall.Stdin))
+		fmt.Println()
+		if err != nil {
+			return "", fmt.Errorf("passphrase input failed: %w", err)
+		}
+		if string(repeated) != string(passphrase) {
+			return "", fmt.Errorf("passphrases do not match")
+		}
+	}
+
+	return string(passphrase), nil
+}
+
+// userExportCmd represents the command to export a user's vault
+var userExportCmd = &cobra.Command{
+	Use:   "export <username_or_id>",
+	Short: "Export a user's vault to an encrypted archive. Requires user authentication.",
+	Long: `Writes the secrets, tags, documents with their files and notes of a user to a
+single archive. The data is decrypted with the user's password and encrypted again
+with an archive passphrase of at least 12 characters, which is prompted for unless
+--passphrase-file is given. The archive can be imported with 'ffcli user import'
+or on the account page, also on another instance.
+
+Examples:
+  ffcli user export john.doe
+  ffcli user export john.doe --output john.ffvault`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		outputPath, _ := cmd.Flags().GetString("output")
+		passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+
+		userDto, dataProtector, _, err := prepareSecretOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		passphrase, err := readArchivePassphrase(passphraseFile, true)
+		if err != nil {
+			return err
+		}
+
+		manager, err := vaultArchiveManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize vault archive manager: %w", err)
+		}
+
+		if outputPath == "" {
+			outputPath = fmt.Sprintf("%s-%s.ffvault", userDto.UserName, time.Now().Format("20060102-150405"))
+		}
+		file, err := os.OpenFile(outputPath, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		if err != nil {
+			return fmt.Errorf("failed to create archive file: %w", err)
+		}
+
+		summary, err := manager.ExportVault(context.Background(), userDto.Id, passphrase, dataProtector, file)
+		if err == nil {
+			err = file.Sync()
+		}
+		if closeErr := file.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			// An incomplete archive cannot be imported
+			os.Remove(outputPath)
+			return err
+		}
+
+		output.PrintSuccess("Vault exported successfully", map[string]interface{}{
+			"file":      outputPath,
+			"secrets":   summary.Secrets,
+			"tags":      summary.Tags,
+			"documents": summary.Documents,
+			"files":     summary.Files,
+			"notes":     summary.Notes,
+		})
+		return nil
+	},
+}
+
+// userImportCmd represents the command to import a vault archive
+var userImportCmd = &cobra.Command{
+	Use:   "import <username_or_id> <archive>",
+	Short: "Import a vault archive into a user's account. Requires user authentication.",
+	Long: `Recreates the content of a vault archive created by 'ffcli user export' or the
+account page for a user, who does not have to be the one who exported it.
+
+All items get new IDs. Tags are merged with existing tags of the same name, and
+secrets whose name is taken are imported with an " (imported)" suffix. Creation
+dates are not preserved, and the text of imported files is extracted again by the
+web UI. If the import fails, the items imported up to that point are kept.
+
+Examples:
+  ffcli user import jane.doe john.ffvault
+  ffcli user import jane.doe john.ffvault --passphrase-file passphrase.txt`,
+	Args: cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		passphraseFile, _ := cmd.Flags().GetString("passphrase-file")
+
+		file, err := os.Open(args[1])
+		if err != nil {
+			return fmt.Errorf("failed to open archive: %w", err)
+		}
+		defer file.Close()
+
+		userDto, dataProtector, _, err := prepareSecretOperation(args[0])
+		if err != nil {
+			return err
+		}
+
+		passphrase, err := readArchivePassphrase(passphraseFile, false)
+		if err != nil {
+			return err
+		}
+
+		manager, err := vaultArchiveManager()
+		if err != nil {
+			return fmt.Errorf("failed to initialize vault archive manager: %w", err)
+		}
+
+		summary, err := manager.ImportVault(context.Background(), userDto.Id, passphrase, dataProtector, file)
+		if summary != nil {
+			for oldName, newName := range summary.RenamedSecrets {
+				output.PrintWarning(fmt.Sprintf("Secret '%s' already exists and was imported as '%s'", oldName, newName))
+			}
+		}
+		if err != nil {
+			if summary != nil && summary.Secrets+summary.Tags+summary.Documents > 0 {
+				output.PrintWarning(fmt.Sprintf("The import stopped after %d secrets, %d tags, %d documents, %d files and %d notes were imported",
+					summary.Secrets, summary.Tags, summary.Documents, summary.Files, summary.Notes))
+			}
+			return err
+		}
+
+		output.PrintSuccess("Vault imported successfully", map[string]interface{}{
+			"user":        userDto.UserName,
+			"secrets":     summary.Secrets,
+			"tags":        summary.Tags,
+			"merged tags": summary.MergedTags,
+			"documents":   summary.Documents,
+			"files":       summary.Files,
+			"notes":       summary.Notes,
+		})
+		return nil
+	},
+}
+
+func init() {
+	userExportCmd.Flags().StringP("output", "o", "", "path of the archive (default <username>-<timestamp>.ffvault)")
+	userExportCmd.Flags().String("passphrase-file", "", "file containing the archive passphrase")
+	userImportCmd.Flags().String("passphrase-file", "", "file containing the archive passphrase")
+
+	userCmd.AddCommand(userExportCmd)
+	userCmd.AddCommand(userImportCmd)
+}
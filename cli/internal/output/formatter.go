@@ -3,10 +3,14 @@ package output
 import (
 	"fmt"
 	"os"
+	"strconv"
+	"strings"
 	"text/tabwriter"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
 // Formatter handles output formatting for the CLI
@@ -55,6 +59,7 @@ func (f *Formatter) PrintUser(user *auth.UserDto) {
 	fmt.Fprintf(w, "Username:\t%s\n", user.UserName)
 	fmt.Fprintf(w, "Active:\t%t\n", user.IsActive)
 	fmt.Fprintf(w, "Locked:\t%t\n", user.IsLocked)
+	fmt.Fprintf(w, "2FA:\t%t\n", user.TotpEnabled)
 	fmt.Fprintf(w, "Created:\t%s\n", user.CreatedAt)
 	fmt.Fprintf(w, "Modified:\t%s\n", user.ModifiedAt)
 	w.Flush()
@@ -68,8 +73,8 @@ func (f *Formatter) PrintUsers(users []auth.UserDto) {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "ID\tUSERNAME\tACTIVE\tLOCKED\tCREATED\n")
-	fmt.Fprintf(w, "--\t--------\t------\t------\t-------\n")
+	fmt.Fprintf(w, "ID\tUSERNAME\tACTIVE\tLOCKED\t2FA\tCREATED\n")
+	fmt.Fprintf(w, "--\t--------\t------\t------\t---\t-------\n")
 
 	for _, user := range users {
 		activeStatus := "No"
@@ -82,11 +87,17 @@ func (f *Formatter) PrintUsers(users []auth.UserDto) {
 			lockedStatus = "Yes"
 		}
 
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n",
+		totpStatus := "No"
+		if user.TotpEnabled {
+			totpStatus = "Yes"
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
 			user.Id,
 			user.UserName,
 			activeStatus,
 			lockedStatus,
+			totpStatus,
 			user.CreatedAt,
 		)
 	}
@@ -102,21 +113,191 @@ func (f *Formatter) PrintBackups(backups []*backup.BackupInfo) {
 	}
 
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintf(w, "FILENAME\tTRIGGER\tSIZE (BYTES)\tCREATED\n")
-	fmt.Fprintf(w, "--------\t-------\t------------\t-------\n")
+	fmt.Fprintf(w, "FILENAME\tTRIGGER\tENCRYPTED\tSIZE (BYTES)\tCREATED\tVERIFIED\n")
+	fmt.Fprintf(w, "--------\t-------\t---------\t------------\t-------\t--------\n")
 
 	for _, backup := range backups {
-		fmt.Fprintf(w, "%s\t%s\t%d\t%s\n",
+		fmt.Fprintf(w, "%s\t%s\t%t\t%d\t%s\t%s\n",
 			backup.Filename,
 			backup.Trigger.String(),
+			backup.Encrypted,
 			backup.SizeBytes,
 			backup.CreatedAt.Format("2006-01-02 15:04:05"),
+			backup.VerificationStatus(),
 		)
 	}
 
 	w.Flush()
 }
 
+// PrintBackupVerifications prints the results of backup verifications in a table format
+func (f *Formatter) PrintBackupVerifications(verifications []*backup.BackupVerification) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "FILENAME\tRESULT\tPROBLEMS\n")
+	fmt.Fprintf(w, "--------\t------\t--------\n")
+
+	for _, verification := range verifications {
+		result := "ok"
+		if !verification.Ok() {
+			result = "failed"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n",
+			verification.Filename,
+			result,
+			strings.Join(verification.Problems, "; "),
+		)
+	}
+
+	w.Flush()
+}
+
+// PrintRetentionDecisions prints the backups cleanup deletes and why. In verbose mode the kept
+// backups are listed as well.
+func (f *Formatter) PrintRetentionDecisions(decisions []*backup.RetentionDecision, dryRun bool) {
+	deleteAction := "delete"
+	if dryRun {
+		deleteAction = "would delete"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "FILENAME\tCREATED\tACTION\tREASON\n")
+	fmt.Fprintf(w, "--------\t-------\t------\t------\n")
+
+	kept, deleted, failed := 0, 0, 0
+	for _, decision := range decisions {
+		action := deleteAction
+		reasons := strings.Join(decision.Reasons, "; ")
+		switch {
+		case decision.Keep:
+			kept++
+			action = "keep"
+		case decision.Err != nil:
+			failed++
+			action = "failed"
+			reasons = decision.Err.Error()
+		default:
+			deleted++
+		}
+		if decision.Keep && !f.verbose {
+			continue
+		}
+
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n",
+			decision.Backup.Filename,
+			decision.Backup.CreatedAt.Format("2006-01-02 15:04:05"),
+			action,
+			reasons,
+		)
+	}
+	w.Flush()
+
+	summary := fmt.Sprintf("\n%d backups kept, %d deleted", kept, deleted)
+	if dryRun {
+		summary = fmt.Sprintf("\n%d backups kept, %d would be deleted", kept, deleted)
+	}
+	if failed > 0 {
+		summary += fmt.Sprintf(", %d failed", failed)
+	}
+	fmt.Println(summary)
+}
+
+// PrintBackupStatus prints the status of automatic backups followed by the latest runs
+func (f *Formatter) PrintBackupStatus(status *backup.BackupStatus) {
+	enabled := "disabled"
+	if status.Enabled {
+		enabled = "enabled"
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Automatic backups:\t%s\n", enabled)
+	if status.Schedule != "" {
+		fmt.Fprintf(w, "Schedule:\t%s\n", status.Schedule)
+	}
+	if status.NextRunAt != nil {
+		fmt.Fprintf(w, "Next backup:\t%s\n", status.NextRunAt.Local().Format("2006-01-02 15:04:05"))
+	}
+	if status.LastSuccess != nil {
+		fmt.Fprintf(w, "Last success:\t%s\n", status.LastSuccess.StartedAt.Local().Format("2006-01-02 15:04:05"))
+	} else {
+		fmt.Fprintf(w, "Last success:\tnever\n")
+	}
+	fmt.Fprintf(w, "Consecutive failures:\t%d\n", status.ConsecutiveFailures)
+	w.Flush()
+
+	for _, problem := range status.Problems {
+		f.PrintWarning(problem)
+	}
+
+	if len(status.Runs) == 0 {
+		fmt.Println("\nNo backup runs recorded")
+		return
+	}
+
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "STARTED\tSTATUS\tDURATION\tSIZE (BYTES)\tFILENAME\tERROR\n")
+	fmt.Fprintf(w, "-------\t------\t--------\t------------\t--------\t-----\n")
+	for _, run := range status.Runs {
+		duration := "-"
+		if run.FinishedAt != nil {
+			duration = run.Duration().Round(time.Second).String()
+		}
+		size := "-"
+		if run.Filename != "" {
+			size = strconv.FormatInt(run.SizeBytes, 10)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			run.StartedAt.Local().Format("2006-01-02 15:04:05"),
+			run.Status,
+			duration,
+			size,
+			run.Filename,
+			run.Error,
+		)
+	}
+	w.Flush()
+}
+
+// PrintMigrations prints the status of schema migrations in a table format
+func (f *Formatter) PrintMigrations(migrations []ccc.MigrationStatus) {
+	if len(migrations) == 0 {
+		fmt.Println("No migrations found")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	if f.verbose {
+		fmt.Fprintf(w, "VERSION\tNAME\tSTATUS\tAPPLIED\tCHECKSUM\n")
+		fmt.Fprintf(w, "-------\t----\t------\t-------\t--------\n")
+	} else {
+		fmt.Fprintf(w, "VERSION\tNAME\tSTATUS\tAPPLIED\n")
+		fmt.Fprintf(w, "-------\t----\t------\t-------\n")
+	}
+
+	for _, migration := range migrations {
+		status := "Pending"
+		appliedAt := "-"
+		if migration.Applied {
+			status = "Applied"
+			appliedAt = migration.AppliedAt.Format("2006-01-02 15:04:05")
+		}
+		if migration.ChecksumMismatch {
+			status = "Modified"
+		}
+		if migration.Unknown {
+			status = "Unknown"
+		}
+
+		if f.verbose {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", migration.Version, migration.Name, status, appliedAt, migration.Checksum)
+		} else {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", migration.Version, migration.Name, status, appliedAt)
+		}
+	}
+
+	w.Flush()
+}
+
 // Package-level convenience functions for easier usage in commands
 var defaultFormatter = NewFormatter(false)
 
@@ -0,0 +1,182 @@
+// Package client is a typed Go client for the REST API of a Frozen Fortress server.
+// It exchanges the structs of core/apicontracts, which is its only dependency, so tools can
+// work against a remote server instead of the SQLite database without pulling in the services.
+// Failed requests are returned as *Error with the code reported by the server.
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+)
+
+// Client talks to the REST API of a Frozen Fortress server with a personal access token
+type Client struct {
+	baseUrl    string
+	token      string
+	httpClient *http.Client
+}
+
+// NewClient creates a new client for the server at baseUrl, e.g. https://vault.example.com.
+// If httpClient is nil, http.DefaultClient is used.
+func NewClient(baseUrl string, token string, httpClient *http.Client) *Client {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	return &Client{
+		baseUrl:    strings.TrimSuffix(baseUrl, "/") + apicontracts.BasePath,
+		token:      token,
+		httpClient: httpClient,
+	}
+}
+
+// Me returns the user and scopes of the token used by the client
+func (c *Client) Me(ctx context.Context) (apicontracts.PrincipalDto, error) {
+	var principal apicontracts.PrincipalDto
+	err := c.doJson(ctx, http.MethodGet, "/me", nil, nil, &principal)
+	return principal, err
+}
+
+// Error is returned for failed requests
+type Error struct {
+	StatusCode int    // HTTP status code of the response, 0 if no response was received
+	Code       string // Error code reported by the server, see the ErrorCode constants of apicontracts
+	Message    string // Message that is safe to display
+	Operation  string // Method and path of the request
+	Cause      error  // Underlying error, if the request could not be sent or decoded
+}
+
+func (e *Error) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %v", e.Operation, e.Message, e.Cause)
+	}
+	return fmt.Sprintf("%s: %s (%s)", e.Operation, e.Message, e.Code)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Cause
+}
+
+// IsErrorCode reports whether err is an *Error with the given code
+func IsErrorCode(err error, code string) bool {
+	var clientErr *Error
+	return errors.As(err, &clientErr) && clientErr.Code == code
+}
+
+// IsNotFound reports whether err is an *Error for a resource that does not exist
+func IsNotFound(err error) bool {
+	return IsErrorCode(err, apicontracts.ErrorCodeNotFound)
+}
+
+// statusErrorCodes maps HTTP status codes to error codes for responses without an error body
+var statusErrorCodes = map[int]string{
+	http.StatusBadRequest:   apicontracts.ErrorCodeInvalidInput,
+	http.StatusUnauthorized: apicontracts.ErrorCodeUnauthorized,
+	http.StatusForbidden:    apicontracts.ErrorCodeForbidden,
+	http.StatusNotFound:     apicontracts.ErrorCodeNotFound,
+	http.StatusConflict:     apicontracts.ErrorCodeAlreadyExists,
+}
+
+// internalError creates an error for a request that failed on the side of the client
+func internalError(operation, message string, cause error) *Error {
+	return &Error{Code: apicontracts.ErrorCodeInternalError, Message: message, Operation: operation, Cause: cause}
+}
+
+// doJson sends a request with an optional JSON body and decodes the JSON response into result, unless it is nil
+func (c *Client) doJson(ctx context.Context, method, path string, query url.Values, body any, result any) error {
+	var reader io.Reader
+	contentType := ""
+	if body != nil {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			return internalError(method+" "+path, "failed to encode request body", err)
+		}
+		reader = bytes.NewReader(payload)
+		contentType = "application/json"
+	}
+
+	response, err := c.do(ctx, method, path, query, reader, contentType)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if result == nil {
+		return nil
+	}
+	return decodeJson(response, result)
+}
+
+// decodeJson decodes the JSON body of a successful response
+func decodeJson(response *http.Response, result any) error {
+	if err := json.NewDecoder(response.Body).Decode(result); err != nil {
+		return internalError(response.Request.Method+" "+response.Request.URL.Path, "failed to decode response", err)
+	}
+	return nil
+}
+
+// do sends an authenticated request and converts error responses to ApiErrors.
+// The caller has to close the body of a successful response.
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body io.Reader, contentType string) (*http.Response, error) {
+	requestUrl := c.baseUrl + path
+	if len(query) > 0 {
+		requestUrl += "?" + query.Encode()
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl, body)
+	if err != nil {
+		return nil, internalError(method+" "+path, "failed to create request", err)
+	}
+	request.Header.Set("Authorization", "Bearer "+c.token)
+	request.Header.Set("Accept", "application/json")
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, &Error{Code: apicontracts.ErrorCodeOperationFailed, Message: "could not reach the server", Operation: method + " " + path, Cause: err}
+	}
+
+	if response.StatusCode >= 200 && response.StatusCode < 300 {
+		return response, nil
+	}
+
+	defer response.Body.Close()
+	return nil, decodeError(response, method+" "+path)
+}
+
+// decodeError converts an error response of the server to an *Error
+func decodeError(response *http.Response, operation string) *Error {
+	clientErr := &Error{
+		StatusCode: response.StatusCode,
+		Code:       apicontracts.ErrorCodeOperationFailed,
+		Message:    http.StatusText(response.StatusCode),
+		Operation:  operation,
+	}
+	if code, found := statusErrorCodes[response.StatusCode]; found {
+		clientErr.Code = code
+	}
+
+	var errorResponse apicontracts.ErrorResponse
+	if err := json.NewDecoder(io.LimitReader(response.Body, 64*1024)).Decode(&errorResponse); err == nil && errorResponse.Error.Code != "" {
+		clientErr.Code = errorResponse.Error.Code
+		clientErr.Message = errorResponse.Error.Message
+	}
+
+	return clientErr
+}
+
+// escape escapes a value for use as a path segment
+func escape(value string) string {
+	return url.PathEscape(value)
+}
@@ -0,0 +1,108 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+)
+
+const testToken = "ffpat_test.secret"
+
+func newTestServer(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+testToken {
+			t.Errorf("expected bearer token, got %q", r.Header.Get("Authorization"))
+		}
+		handler(w, r)
+	}))
+	t.Cleanup(server.Close)
+
+	return NewClient(server.URL+"/", testToken, server.Client())
+}
+
+func TestClientMapsResponsesAndErrors(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.EscapedPath() {
+		case apicontracts.BasePath + "/secrets/by-name/mail%2Fwork":
+			json.NewEncoder(w).Encode(apicontracts.SecretDto{Id: "s1", Name: "mail/work", Value: "hunter2"})
+		case apicontracts.BasePath + "/secrets/missing":
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(apicontracts.ErrorResponse{Error: apicontracts.ErrorDto{
+				Code:    apicontracts.ErrorCodeNotFound,
+				Message: "Secret not found",
+			}})
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	})
+
+	secret, err := client.GetSecretByName(context.Background(), "mail/work")
+	if err != nil {
+		t.Fatalf("GetSecretByName failed: %v", err)
+	}
+	if secret.Id != "s1" || secret.Value != "hunter2" {
+		t.Errorf("unexpected secret %+v", secret)
+	}
+
+	_, err = client.GetSecret(context.Background(), "missing")
+	if !IsNotFound(err) {
+		t.Errorf("expected a not found error, got %v", err)
+	}
+	var clientErr *Error
+	if !errors.As(err, &clientErr) || clientErr.Message != "Secret not found" {
+		t.Errorf("expected the message of the server, got %v", err)
+	}
+
+	err = client.DeleteTag(context.Background(), "other")
+	if !errors.As(err, &clientErr) || clientErr.StatusCode != http.StatusBadGateway {
+		t.Errorf("expected an error for a response without body, got %v", err)
+	}
+}
+
+func TestClientUploadsFilesWithContentType(t *testing.T) {
+	client := newTestServer(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != apicontracts.BasePath+"/documents/d1/files" {
+			t.Errorf("unexpected request %s %s", r.Method, r.URL.Path)
+		}
+
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			t.Errorf("expected a file in the form: %v", err)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		content, _ := io.ReadAll(file)
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(apicontracts.DocumentFileDto{
+			Id:          "f1",
+			DocumentId:  "d1",
+			FileName:    header.Filename,
+			ContentType: header.Header.Get("Content-Type"),
+			FileSize:    int64(len(content)),
+		})
+	})
+
+	file, err := client.AddDocumentFile(context.Background(), "d1", FileUpload{
+		FileName:    `scan "1".pdf`,
+		ContentType: "application/pdf",
+		Content:     strings.NewReader("%PDF-1.7"),
+	})
+	if err != nil {
+		t.Fatalf("AddDocumentFile failed: %v", err)
+	}
+	if file.FileName != `scan "1".pdf` || file.ContentType != "application/pdf" || file.FileSize != 8 {
+		t.Errorf("unexpected file %+v", file)
+	}
+}
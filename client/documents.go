@@ -0,0 +1,234 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+)
+
+// DocumentListOptions searches, filters, sorts and pages the documents returned by GetDocumentList
+type DocumentListOptions struct {
+	SearchTerm    string
+	DeepSearch    bool // Also search the extracted text of files and the notes
+	TagIds        []string
+	Issuer        string
+	DateFrom      *time.Time
+	DateTo        *time.Time
+	IssueDateFrom *time.Time
+	IssueDateTo   *time.Time
+	Page          int
+	PageSize      int
+	SortBy        string // title, createdAt, modifiedAt, issueDate or, for searches, relevance
+	SortAsc       bool
+}
+
+// FileUpload is a file added to a document. The content is streamed to the server.
+type FileUpload struct {
+	FileName    string
+	ContentType string
+	Content     io.Reader
+}
+
+// GetDocumentList returns a page of documents. If options.SearchTerm is set, the documents are searched.
+func (c *Client) GetDocumentList(ctx context.Context, options DocumentListOptions) (*apicontracts.DocumentListResponse, error) {
+	query := url.Values{}
+	if options.SearchTerm != "" {
+		query.Set("search", options.SearchTerm)
+		query.Set("deepSearch", strconv.FormatBool(options.DeepSearch))
+	}
+	if len(options.TagIds) > 0 {
+		query.Set("tagIds", strings.Join(options.TagIds, ","))
+	}
+	if options.Issuer != "" {
+		query.Set("issuer", options.Issuer)
+	}
+	for name, date := range map[string]*time.Time{
+		"dateFrom":      options.DateFrom,
+		"dateTo":        options.DateTo,
+		"issueDateFrom": options.IssueDateFrom,
+		"issueDateTo":   options.IssueDateTo,
+	} {
+		if date != nil {
+			query.Set(name, date.Format(apicontracts.IssueDateLayout))
+		}
+	}
+	if options.Page > 0 {
+		query.Set("page", strconv.Itoa(options.Page))
+	}
+	if options.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(options.PageSize))
+	}
+	if options.SortBy != "" {
+		query.Set("sortBy", options.SortBy)
+		query.Set("sortAsc", strconv.FormatBool(options.SortAsc))
+	}
+
+	var response apicontracts.DocumentListResponse
+	if err := c.doJson(ctx, http.MethodGet, "/documents", query, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetDocument returns a document by its ID
+func (c *Client) GetDocument(ctx context.Context, documentId string) (*apicontracts.DocumentDto, error) {
+	var document apicontracts.DocumentDto
+	if err := c.doJson(ctx, http.MethodGet, "/documents/"+escape(documentId), nil, nil, &document); err != nil {
+		return nil, err
+	}
+	return &document, nil
+}
+
+// CreateDocument creates a new document, uploads the given files to it and returns its ID.
+// If an upload fails, the document is kept and the error is returned together with the document ID.
+func (c *Client) CreateDocument(ctx context.Context, request apicontracts.UpsertDocumentRequest, files ...FileUpload) (string, error) {
+	var response apicontracts.IdResponse
+	if err := c.doJson(ctx, http.MethodPost, "/documents", nil, request, &response); err != nil {
+		return "", err
+	}
+
+	for _, file := range files {
+		if _, err := c.AddDocumentFile(ctx, response.Id, file); err != nil {
+			return response.Id, err
+		}
+	}
+	return response.Id, nil
+}
+
+// UpdateDocument replaces the metadata and tags of a document
+func (c *Client) UpdateDocument(ctx context.Context, documentId string, request apicontracts.UpsertDocumentRequest) error {
+	return c.doJson(ctx, http.MethodPut, "/documents/"+escape(documentId), nil, request, nil)
+}
+
+// DeleteDocument deletes a document with all its files and notes
+func (c *Client) DeleteDocument(ctx context.Context, documentId string) error {
+	return c.doJson(ctx, http.MethodDelete, "/documents/"+escape(documentId), nil, nil, nil)
+}
+
+// GetDocumentFiles returns the files of a document without their content
+func (c *Client) GetDocumentFiles(ctx context.Context, documentId string) ([]apicontracts.DocumentFileDto, error) {
+	var files []apicontracts.DocumentFileDto
+	if err := c.doJson(ctx, http.MethodGet, "/documents/"+escape(documentId)+"/files", nil, nil, &files); err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// GetDocumentFile returns a file without its content
+func (c *Client) GetDocumentFile(ctx context.Context, documentId, fileId string) (*apicontracts.DocumentFileDto, error) {
+	path := "/documents/" + escape(documentId) + "/files/" + escape(fileId)
+
+	var file apicontracts.DocumentFileDto
+	if err := c.doJson(ctx, http.MethodGet, path, nil, nil, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// OpenDocumentFile returns a file along with its decrypted content, which is downloaded while it is read.
+// The content has to be closed by the caller.
+func (c *Client) OpenDocumentFile(ctx context.Context, documentId, fileId string) (*apicontracts.DocumentFileDto, io.ReadCloser, error) {
+	file, err := c.GetDocumentFile(ctx, documentId, fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	path := "/documents/" + escape(documentId) + "/files/" + escape(fileId) + "/content"
+	response, err := c.do(ctx, http.MethodGet, path, nil, nil, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	return file, response.Body, nil
+}
+
+// AddDocumentFile uploads a file to a document, streaming its content to the server
+func (c *Client) AddDocumentFile(ctx context.Context, documentId string, upload FileUpload) (*apicontracts.DocumentFileDto, error) {
+	path := "/documents/" + escape(documentId) + "/files"
+
+	body, bodyWriter := io.Pipe()
+	writer := multipart.NewWriter(bodyWriter)
+	go func() {
+		bodyWriter.CloseWithError(writeFilePart(writer, upload.FileName, upload.ContentType, upload.Content))
+	}()
+
+	response, err := c.do(ctx, http.MethodPost, path, nil, body, writer.FormDataContentType())
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	var file apicontracts.DocumentFileDto
+	if err := decodeJson(response, &file); err != nil {
+		return nil, err
+	}
+	return &file, nil
+}
+
+// writeFilePart writes a multipart body with the content in the form field "file"
+func writeFilePart(writer *multipart.Writer, fileName, contentType string, content io.Reader) error {
+	// CreateFormFile would always declare application/octet-stream, but the server needs the actual content type
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="file"; filename="%s"`, quoteEscaper.Replace(fileName)))
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("failed to create multipart body: %w", err)
+	}
+	if _, err := io.Copy(part, content); err != nil {
+		return fmt.Errorf("failed to write multipart body: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("failed to finish multipart body: %w", err)
+	}
+	return nil
+}
+
+// DeleteDocumentFile removes a file from a document
+func (c *Client) DeleteDocumentFile(ctx context.Context, documentId, fileId string) error {
+	return c.doJson(ctx, http.MethodDelete, "/documents/"+escape(documentId)+"/files/"+escape(fileId), nil, nil, nil)
+}
+
+// ReprocessFile queues the text extraction of a file again
+func (c *Client) ReprocessFile(ctx context.Context, documentId, fileId string) error {
+	return c.doJson(ctx, http.MethodPost, "/documents/"+escape(documentId)+"/files/"+escape(fileId)+"/ocr", nil, nil, nil)
+}
+
+// GetDocumentNotes returns the notes of a document
+func (c *Client) GetDocumentNotes(ctx context.Context, documentId string) ([]apicontracts.NoteDto, error) {
+	var notes []apicontracts.NoteDto
+	if err := c.doJson(ctx, http.MethodGet, "/documents/"+escape(documentId)+"/notes", nil, nil, &notes); err != nil {
+		return nil, err
+	}
+	return notes, nil
+}
+
+// CreateNote adds a note to a document and returns its ID. The user of the request is determined by the token.
+func (c *Client) CreateNote(ctx context.Context, documentId string, request apicontracts.UpsertNoteRequest) (string, error) {
+	var response apicontracts.IdResponse
+	if err := c.doJson(ctx, http.MethodPost, "/documents/"+escape(documentId)+"/notes", nil, request, &response); err != nil {
+		return "", err
+	}
+	return response.Id, nil
+}
+
+// UpdateNote replaces the content of a note. The user of the request is determined by the token.
+func (c *Client) UpdateNote(ctx context.Context, noteId string, request apicontracts.UpsertNoteRequest) error {
+	return c.doJson(ctx, http.MethodPut, "/notes/"+escape(noteId), nil, request, nil)
+}
+
+// DeleteNote deletes a note
+func (c *Client) DeleteNote(ctx context.Context, noteId string) error {
+	return c.doJson(ctx, http.MethodDelete, "/notes/"+escape(noteId), nil, nil, nil)
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
@@ -0,0 +1,93 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+)
+
+// SecretListOptions filters, sorts and pages the secrets returned by GetSecrets
+type SecretListOptions struct {
+	Name               string // Only secrets whose name contains this text
+	Page               int
+	PageSize           int
+	SortBy             string // Name, CreatedAt, ModifiedAt or ExpiresAt
+	SortAsc            bool
+	ExpiringWithinDays int // Only secrets that expire or are due for rotation within this many days
+}
+
+// GetSecrets returns a page of secrets, optionally filtered by name
+func (c *Client) GetSecrets(ctx context.Context, options SecretListOptions) (*apicontracts.SecretListResponse, error) {
+	query := url.Values{}
+	if options.Name != "" {
+		query.Set("name", options.Name)
+	}
+	if options.Page > 0 {
+		query.Set("page", strconv.Itoa(options.Page))
+	}
+	if options.PageSize > 0 {
+		query.Set("pageSize", strconv.Itoa(options.PageSize))
+	}
+	if options.SortBy != "" {
+		query.Set("sortBy", options.SortBy)
+	}
+	query.Set("sortAsc", strconv.FormatBool(options.SortAsc))
+	if options.ExpiringWithinDays > 0 {
+		query.Set("expiringWithinDays", strconv.Itoa(options.ExpiringWithinDays))
+	}
+
+	var response apicontracts.SecretListResponse
+	if err := c.doJson(ctx, http.MethodGet, "/secrets", query, nil, &response); err != nil {
+		return nil, err
+	}
+	return &response, nil
+}
+
+// GetSecret returns a secret by its ID
+func (c *Client) GetSecret(ctx context.Context, secretId string) (*apicontracts.SecretDto, error) {
+	var secret apicontracts.SecretDto
+	if err := c.doJson(ctx, http.MethodGet, "/secrets/"+escape(secretId), nil, nil, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// GetSecretByName returns a secret by its exact name
+func (c *Client) GetSecretByName(ctx context.Context, secretName string) (*apicontracts.SecretDto, error) {
+	var secret apicontracts.SecretDto
+	if err := c.doJson(ctx, http.MethodGet, "/secrets/by-name/"+escape(secretName), nil, nil, &secret); err != nil {
+		return nil, err
+	}
+	return &secret, nil
+}
+
+// CreateSecret creates a new secret and returns its ID
+func (c *Client) CreateSecret(ctx context.Context, request apicontracts.UpsertSecretRequest) (string, error) {
+	var response apicontracts.IdResponse
+	if err := c.doJson(ctx, http.MethodPost, "/secrets", nil, request, &response); err != nil {
+		return "", err
+	}
+	return response.Id, nil
+}
+
+// UpdateSecret replaces the name and value or fields of a secret
+func (c *Client) UpdateSecret(ctx context.Context, secretId string, request apicontracts.UpsertSecretRequest) error {
+	return c.doJson(ctx, http.MethodPut, "/secrets/"+escape(secretId), nil, request, nil)
+}
+
+// GetTotpCode returns the current one-time password of a secret
+func (c *Client) GetTotpCode(ctx context.Context, secretId string) (*apicontracts.TotpCodeDto, error) {
+	var code apicontracts.TotpCodeDto
+	if err := c.doJson(ctx, http.MethodGet, "/secrets/"+escape(secretId)+"/totp", nil, nil, &code); err != nil {
+		return nil, err
+	}
+	return &code, nil
+}
+
+// DeleteSecret deletes a secret
+func (c *Client) DeleteSecret(ctx context.Context, secretId string) error {
+	return c.doJson(ctx, http.MethodDelete, "/secrets/"+escape(secretId), nil, nil, nil)
+}
@@ -0,0 +1,45 @@
+package client
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+)
+
+// GetUserTags returns all tags of the user
+func (c *Client) GetUserTags(ctx context.Context) ([]apicontracts.TagDto, error) {
+	var tags []apicontracts.TagDto
+	if err := c.doJson(ctx, http.MethodGet, "/tags", nil, nil, &tags); err != nil {
+		return nil, err
+	}
+	return tags, nil
+}
+
+// GetTag returns a tag by its ID
+func (c *Client) GetTag(ctx context.Context, tagId string) (*apicontracts.TagDto, error) {
+	var tag apicontracts.TagDto
+	if err := c.doJson(ctx, http.MethodGet, "/tags/"+escape(tagId), nil, nil, &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// CreateTag creates a new tag
+func (c *Client) CreateTag(ctx context.Context, request apicontracts.UpsertTagRequest) (*apicontracts.TagDto, error) {
+	var tag apicontracts.TagDto
+	if err := c.doJson(ctx, http.MethodPost, "/tags", nil, request, &tag); err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+// UpdateTag renames or recolors a tag
+func (c *Client) UpdateTag(ctx context.Context, tagId string, request apicontracts.UpsertTagRequest) error {
+	return c.doJson(ctx, http.MethodPut, "/tags/"+escape(tagId), nil, request, nil)
+}
+
+// DeleteTag deletes a tag and removes it from all documents
+func (c *Client) DeleteTag(ctx context.Context, tagId string) error {
+	return c.doJson(ctx, http.MethodDelete, "/tags/"+escape(tagId), nil, nil, nil)
+}
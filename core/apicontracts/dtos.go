@@ -0,0 +1,222 @@
+// Package apicontracts defines the JSON wire format of the REST API.
+// It is shared by the web server and the Go client and deliberately has no dependencies on the services.
+package apicontracts
+
+import "time"
+
+const (
+	// BasePath is the path prefix of version 1 of the REST API
+	BasePath = "/api/v1"
+
+	// IssueDateLayout is the format used for issue dates and date filters
+	IssueDateLayout = "2006-01-02"
+)
+
+// PrincipalDto describes the user and scopes of the token used for a request
+type PrincipalDto struct {
+	UserId   string   `json:"userId"`
+	UserName string   `json:"userName"`
+	TokenId  string   `json:"tokenId"`
+	Scopes   []string `json:"scopes"`
+}
+
+// Codes of ErrorDto, which are the codes of the errors returned by the services
+const (
+	ErrorCodeNotFound         = "NOT_FOUND"
+	ErrorCodeAlreadyExists    = "ALREADY_EXISTS"
+	ErrorCodeInvalidInput     = "INVALID_INPUT"
+	ErrorCodeValidationFailed = "VALIDATION_FAILED"
+	ErrorCodeUnauthorized     = "UNAUTHORIZED"
+	ErrorCodeForbidden        = "FORBIDDEN"
+	ErrorCodeInternalError    = "INTERNAL_ERROR"
+	ErrorCodeOperationFailed  = "OPERATION_FAILED"
+	ErrorCodeConflict         = "CONFLICT"
+)
+
+// ErrorResponse is returned for every failed request
+type ErrorResponse struct {
+	Error ErrorDto `json:"error"`
+}
+
+// ErrorDto holds the error code of the underlying ApiError and a message that is safe to display
+type ErrorDto struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// PageDto contains the pagination information of a list response
+type PageDto struct {
+	Page       int `json:"page"`
+	PageSize   int `json:"pageSize"`
+	TotalCount int `json:"totalCount"`
+	TotalPages int `json:"totalPages"`
+}
+
+// IdResponse is returned when a resource has been created
+type IdResponse struct {
+	Id string `json:"id"`
+}
+
+// Secrets
+
+// Types of SecretDto
+const (
+	SecretTypeGeneric = "generic"
+	SecretTypeLogin   = "login"
+	SecretTypeApiKey  = "api_key"
+	SecretTypeNote    = "note"
+	SecretTypeCard    = "card"
+	SecretTypeSshKey  = "ssh_key"
+)
+
+// FieldKindTotp is the kind of fields holding the seed of one-time passwords
+const FieldKindTotp = "totp"
+
+// SecretDto holds a secret with its fields. Value is the primary value, e.g. the password of a login.
+type SecretDto struct {
+	Id         string           `json:"id"`
+	Name       string           `json:"name"`
+	Type       string           `json:"type"`
+	Value      string           `json:"value"`
+	Fields     []SecretFieldDto `json:"fields"`
+	Totp       *TotpCodeDto     `json:"totp,omitempty"`
+	CreatedAt  string           `json:"createdAt"`
+	ModifiedAt string           `json:"modifiedAt"`
+	// ExpiresAt is when the credential held by the secret expires, nil if it does not expire
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	// RotateEvery is the number of days after which the value should be rotated, 0 = no rotation
+	RotateEvery   int        `json:"rotateEvery,omitempty"`
+	RotationDueAt *time.Time `json:"rotationDueAt,omitempty"`
+}
+
+// SecretFieldDto is a field of a secret. Fields of kind "totp" hold an otpauth:// URI or base32 seed.
+type SecretFieldDto struct {
+	Name      string `json:"name"`
+	Value     string `json:"value"`
+	Concealed bool   `json:"concealed"`
+	Kind      string `json:"kind,omitempty"`
+}
+
+// TotpCodeDto is the current one-time password of a secret
+type TotpCodeDto struct {
+	FieldName        string `json:"fieldName"`
+	Code             string `json:"code"`
+	Period           int    `json:"period"`
+	SecondsRemaining int    `json:"secondsRemaining"`
+}
+
+type SecretListResponse struct {
+	PageDto
+	Secrets []SecretDto `json:"secrets"`
+}
+
+// UpsertSecretRequest creates or updates a secret. Without fields, the value is stored in the first
+// concealed field of the type, or replaces the primary value of an existing secret.
+type UpsertSecretRequest struct {
+	Name   string           `json:"name"`
+	Value  string           `json:"value,omitempty"`
+	Type   string           `json:"type,omitempty"`
+	Fields []SecretFieldDto `json:"fields,omitempty"`
+	// ExpiresAt and RotateEvery are kept when a secret is updated with neither fields nor schedule
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	RotateEvery int        `json:"rotateEvery,omitempty"`
+}
+
+// Documents
+
+// OCR statuses of DocumentFileDto
+const (
+	OcrStatusProcessing = "processing"
+	OcrStatusCompleted  = "completed"
+	OcrStatusFailed     = "failed"
+	OcrStatusSkipped    = "skipped"
+)
+
+type TagDto struct {
+	Id         string    `json:"id"`
+	Name       string    `json:"name"`
+	Color      string    `json:"color"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type DocumentDto struct {
+	Id          string    `json:"id"`
+	Title       string    `json:"title"`
+	Description string    `json:"description"`
+	Issuer      string    `json:"issuer"`
+	IssueDate   string    `json:"issueDate,omitempty"`
+	FileCount   int       `json:"fileCount"`
+	Tags        []TagDto  `json:"tags"`
+	CreatedAt   time.Time `json:"createdAt"`
+	ModifiedAt  time.Time `json:"modifiedAt"`
+}
+
+type DocumentListItemDto struct {
+	DocumentDto
+	HighlightedText string   `json:"highlightedText,omitempty"`
+	MatchTypes      []string `json:"matchTypes,omitempty"`
+}
+
+type DocumentListResponse struct {
+	PageDto
+	Documents []DocumentListItemDto `json:"documents"`
+}
+
+type UpsertDocumentRequest struct {
+	Title       string   `json:"title"`
+	Description string   `json:"description"`
+	Issuer      string   `json:"issuer"`
+	IssueDate   string   `json:"issueDate"`
+	TagIds      []string `json:"tagIds"`
+}
+
+type DocumentFileDto struct {
+	Id            string    `json:"id"`
+	DocumentId    string    `json:"documentId"`
+	FileName      string    `json:"fileName"`
+	ContentType   string    `json:"contentType"`
+	FileSize      int64     `json:"fileSize"`
+	PageCount     int       `json:"pageCount"`
+	ExtractedText string    `json:"extractedText,omitempty"`
+	Confidence    float32   `json:"confidence"`
+	OcrStatus     string    `json:"ocrStatus"`
+	OcrError      string    `json:"ocrError,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+	ModifiedAt    time.Time `json:"modifiedAt"`
+}
+
+type CreateUploadRequest struct {
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType,omitempty"` // Derived from the file name if empty
+	Size        int64  `json:"size"`
+}
+
+type UploadSessionDto struct {
+	Id           string    `json:"id"`
+	DocumentId   string    `json:"documentId"`
+	FileName     string    `json:"fileName"`
+	ContentType  string    `json:"contentType"`
+	Size         int64     `json:"size"`
+	Offset       int64     `json:"offset"`
+	MaxChunkSize int64     `json:"maxChunkSize"`
+	CreatedAt    time.Time `json:"createdAt"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+type UpsertTagRequest struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type NoteDto struct {
+	Id         string    `json:"id"`
+	DocumentId string    `json:"documentId"`
+	Content    string    `json:"content"`
+	CreatedAt  time.Time `json:"createdAt"`
+	ModifiedAt time.Time `json:"modifiedAt"`
+}
+
+type UpsertNoteRequest struct {
+	Content string `json:"content"`
+}
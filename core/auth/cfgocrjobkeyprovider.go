@@ -0,0 +1,31 @@
+package auth
+
+import (
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+// ConfigOCRJobKeyProvider provides the key wrapping the MEK of queued OCR jobs using AppConfig and file-based key
+// persistence. The key is independent of the session keys, so that the web UI and the CLI can share it without
+// sharing the key protecting the sessions.
+type ConfigOCRJobKeyProvider struct {
+	config            ccc.AppConfig
+	encryptionService encryption.EncryptionService
+}
+
+// NewConfigOCRJobKeyProvider creates a new ConfigOCRJobKeyProvider.
+func NewConfigOCRJobKeyProvider(config ccc.AppConfig, encryptionService encryption.EncryptionService) *ConfigOCRJobKeyProvider {
+	return &ConfigOCRJobKeyProvider{
+		config:            config,
+		encryptionService: encryptionService,
+	}
+}
+
+// GetOCRJobKey retrieves the OCR job key, creating and persisting it if necessary.
+func (p *ConfigOCRJobKeyProvider) GetOCRJobKey() ([]byte, error) {
+	keyString, err := getOrCreateKey(p.encryptionService, p.config.OcrJobKey, "ocr_job_key", p.config.KeyDir)
+	if err != nil {
+		return nil, err
+	}
+	return p.encryptionService.ConvertStringToKey(keyString)
+}
@@ -24,7 +24,7 @@ func NewConfigSessionKeyProvider(config ccc.AppConfig, encryptionService encrypt
 
 // GetSigningKey retrieves the signing key, creating and persisting it if necessary.
 func (p *ConfigSessionKeyProvider) GetSigningKey() ([]byte, error) {
-	keyString, err := p.getOrCreateKey(p.config.SigningKey, "signing_key", p.config.KeyDir)
+	keyString, err := getOrCreateKey(p.encryptionService, p.config.SigningKey, "signing_key", p.config.KeyDir)
 	if err != nil {
 		return nil, err
 	}
@@ -33,7 +33,7 @@ func (p *ConfigSessionKeyProvider) GetSigningKey() ([]byte, error) {
 
 // GetEncryptionKey retrieves the encryption key, creating and persisting it if necessary.
 func (p *ConfigSessionKeyProvider) GetEncryptionKey() ([]byte, error) {
-	keyString, err := p.getOrCreateKey(p.config.EncryptionKey, "encryption_key", p.config.KeyDir)
+	keyString, err := getOrCreateKey(p.encryptionService, p.config.EncryptionKey, "encryption_key", p.config.KeyDir)
 	if err != nil {
 		return nil, err
 	}
@@ -42,7 +42,7 @@ func (p *ConfigSessionKeyProvider) GetEncryptionKey() ([]byte, error) {
 
 // getOrCreateKey reads a key from config, or creates a new one if it doesn't exist.
 // If a key doesn't exist, it generates a new one and persists it to a secure file.
-func (p *ConfigSessionKeyProvider) getOrCreateKey(configKey, keyFileName, customKeyDir string) (string, error) {
+func getOrCreateKey(encryptionService encryption.EncryptionService, configKey, keyFileName, customKeyDir string) (string, error) {
 	// Check if the config already has the key
 	if configKey != "" {
 		return configKey, nil
@@ -58,7 +58,7 @@ func (p *ConfigSessionKeyProvider) getOrCreateKey(configKey, keyFileName, custom
 	}
 
 	// Generate a new secure key
-	key, err := p.encryptionService.GenerateKey()
+	key, err := encryptionService.GenerateKey()
 	if err != nil {
 		return "", ccc.NewInternalError("failed to generate key", err)
 	}
@@ -1,5 +1,10 @@
 package auth
 
+import (
+	"slices"
+	"time"
+)
+
 type CreateUserRequest struct {
 	UserName string
 	Password string
@@ -17,12 +22,13 @@ type ChangePasswordRequest struct {
 }
 
 type UserDto struct {
-	Id         string
-	UserName   string
-	IsActive   bool
-	IsLocked   bool
-	CreatedAt  string
-	ModifiedAt string
+	Id          string
+	UserName    string
+	IsActive    bool
+	IsLocked    bool
+	TotpEnabled bool
+	CreatedAt   string
+	ModifiedAt  string
 }
 
 type SignInRequest struct {
@@ -31,18 +37,29 @@ type SignInRequest struct {
 }
 
 type SignInResponse struct {
-	Success bool
-	User    UserDto
-	Error   string // empty if no error
+	Success      bool
+	RequiresTotp bool // true if the password was accepted and a TOTP code is required to complete the sign-in
+	User         UserDto
+	Error        string // empty if no error
 }
 
 type SignInResult struct {
 	Success      bool
-	User         *User  // nil if sign-in failed
-	Mek          string // empty if sign-in failed
+	RequiresTotp bool     // true if the password was accepted but the sign-in must be completed with HandleTotpSignIn
+	User         *User    // nil if sign-in failed
+	Mek          string   // empty if sign-in failed
+	Notices      []string // notices of the sign-in listeners for the user
 	ErrorMessage string
 }
 
+// TotpSignInRequest is the second step of a password sign-in for users with TOTP enabled
+type TotpSignInRequest struct {
+	UserId            string
+	Mek               string // MEK uncovered by the password step, used to decrypt the TOTP seed
+	Code              string
+	ChallengeIssuedAt time.Time // time the password step succeeded
+}
+
 type SignInContext struct {
 	ClientType ClientType
 	IPAddress  string // IP address of the client making the request (if applicable)
@@ -69,6 +86,27 @@ type GenerateRecoveryCodeResponse struct {
 	Generated    string // timestamp when generated
 }
 
+type BeginTotpEnrollmentRequest struct {
+	UserId   string
+	Password string
+}
+
+type BeginTotpEnrollmentResponse struct {
+	Secret string // base32 encoded seed for manual entry
+	Uri    string // otpauth:// URI for authenticator apps
+}
+
+type ConfirmTotpEnrollmentRequest struct {
+	UserId   string
+	Password string
+	Code     string
+}
+
+type DisableTotpRequest struct {
+	UserId   string
+	Password string
+}
+
 type RecoverySignInRequest struct {
 	UserName     string
 	RecoveryCode string
@@ -86,6 +124,151 @@ type RecoverySignInResult struct {
 	Success         bool
 	User            *User
 	Mek             string
-	NewRecoveryCode string // The new recovery code generated after successful recovery
+	NewRecoveryCode string   // The new recovery code generated after successful recovery
+	Notices         []string // notices of the sign-in listeners for the user
 	ErrorMessage    string
 }
+
+// WebAuthnRelyingParty identifies the site passkeys are bound to
+type WebAuthnRelyingParty struct {
+	Id      string   // RP ID, i.e. the domain of the site
+	Name    string   // name shown by the browser
+	Origins []string // origins allowed to perform WebAuthn ceremonies
+}
+
+// PasskeyDto is the user facing representation of a registered passkey
+type PasskeyDto struct {
+	Id         string
+	Name       string
+	CreatedAt  string
+	LastUsedAt string // empty if the passkey was never used
+}
+
+// PasskeyCredentialDescriptor references a credential in creation and request options
+type PasskeyCredentialDescriptor struct {
+	Type       string   `json:"type"`
+	Id         string   `json:"id"` // base64url
+	Transports []string `json:"transports,omitempty"`
+}
+
+// PasskeyCredentialParameter is a supported public key algorithm
+type PasskeyCredentialParameter struct {
+	Type string `json:"type"`
+	Alg  int    `json:"alg"`
+}
+
+// PasskeyRelyingPartyEntity is the rp member of the creation options
+type PasskeyRelyingPartyEntity struct {
+	Id   string `json:"id"`
+	Name string `json:"name"`
+}
+
+// PasskeyUserEntity is the user member of the creation options
+type PasskeyUserEntity struct {
+	Id          string `json:"id"` // base64url encoded user handle
+	Name        string `json:"name"`
+	DisplayName string `json:"displayName"`
+}
+
+// PasskeyAuthenticatorSelection is the authenticatorSelection member of the creation options
+type PasskeyAuthenticatorSelection struct {
+	ResidentKey      string `json:"residentKey"`
+	UserVerification string `json:"userVerification"`
+}
+
+// PasskeyCreationOptions mirrors PublicKeyCredentialCreationOptions with binary values encoded as base64url.
+// PrfSalt has to be passed to the prf extension by the browser.
+type PasskeyCreationOptions struct {
+	Challenge              string                        `json:"challenge"`
+	RelyingParty           PasskeyRelyingPartyEntity     `json:"rp"`
+	User                   PasskeyUserEntity             `json:"user"`
+	PubKeyCredParams       []PasskeyCredentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int                           `json:"timeout"`
+	ExcludeCredentials     []PasskeyCredentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection PasskeyAuthenticatorSelection `json:"authenticatorSelection"`
+	Attestation            string                        `json:"attestation"`
+	PrfSalt                string                        `json:"prfSalt"`
+}
+
+// PasskeyRequestOptions mirrors PublicKeyCredentialRequestOptions with binary values encoded as base64url.
+// PrfSalt has to be passed to the prf extension by the browser.
+type PasskeyRequestOptions struct {
+	Challenge        string                        `json:"challenge"`
+	RelyingPartyId   string                        `json:"rpId"`
+	Timeout          int                           `json:"timeout"`
+	AllowCredentials []PasskeyCredentialDescriptor `json:"allowCredentials"`
+	UserVerification string                        `json:"userVerification"`
+	PrfSalt          string                        `json:"prfSalt"`
+}
+
+// PasskeyRegistrationResponse is the result of navigator.credentials.create() with binary values encoded as base64url
+type PasskeyRegistrationResponse struct {
+	Id                string   `json:"id"`
+	ClientDataJSON    string   `json:"clientDataJSON"`
+	AttestationObject string   `json:"attestationObject"`
+	Transports        []string `json:"transports"`
+	PrfOutput         string   `json:"prfOutput"` // first PRF result, either from the creation or a subsequent assertion
+}
+
+// PasskeyAssertionResponse is the result of navigator.credentials.get() with binary values encoded as base64url
+type PasskeyAssertionResponse struct {
+	Id                string `json:"id"`
+	ClientDataJSON    string `json:"clientDataJSON"`
+	AuthenticatorData string `json:"authenticatorData"`
+	Signature         string `json:"signature"`
+	UserHandle        string `json:"userHandle"`
+	PrfOutput         string `json:"prfOutput"` // first PRF result
+}
+
+type FinishPasskeyRegistrationRequest struct {
+	UserId       string
+	Mek          string // MEK of the signed in user, wrapped for the new passkey
+	Name         string
+	Challenge    string // challenge of the creation options
+	RelyingParty WebAuthnRelyingParty
+	Response     PasskeyRegistrationResponse
+}
+
+type PasskeySignInRequest struct {
+	Challenge    string // challenge of the request options
+	RelyingParty WebAuthnRelyingParty
+	Response     PasskeyAssertionResponse
+}
+
+type CreateApiTokenRequest struct {
+	UserId        string
+	Mek           string // MEK of the signed in user, wrapped for the new token
+	Name          string
+	Scopes        []ApiTokenScope
+	ExpiresInDays int
+}
+
+type CreateApiTokenResponse struct {
+	Token    string // the token to present as bearer token; it is only returned once
+	ApiToken ApiTokenDto
+}
+
+// ApiTokenDto is the user facing representation of a personal access token
+type ApiTokenDto struct {
+	Id         string
+	Name       string
+	Scopes     []string
+	ExpiresAt  string
+	CreatedAt  string
+	LastUsedAt string // empty if the token was never used
+	IsExpired  bool
+}
+
+// ApiPrincipal is the user authenticated by a personal access token
+type ApiPrincipal struct {
+	TokenId  string
+	UserId   string
+	UserName string
+	Scopes   []ApiTokenScope
+	Mek      string // MEK unwrapped with the token secret; only valid for the current request
+}
+
+// HasScope reports whether the token was granted the given scope
+func (p ApiPrincipal) HasScope(scope ApiTokenScope) bool {
+	return slices.Contains(p.Scopes, scope)
+}
@@ -0,0 +1,349 @@
+package auth
+
+import (
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+const (
+	// apiTokenPrefix makes tokens recognizable, e.g. for secret scanners. Tokens look like ffpat_<id>.<secret>
+	apiTokenPrefix       = "ffpat_"
+	apiTokenSecretLength = 32
+
+	defaultApiTokenLifetimeDays = 30
+	maxApiTokenLifetimeDays     = 365
+	maxApiTokenNameLength       = 64
+
+	// apiTokenLastUsedResolution limits how often the last use of a token is written to the database
+	apiTokenLastUsedResolution = time.Minute
+
+	apiTokenMekWrapDomain = "frozenfortress-api-token-mek-v1"
+)
+
+// DefaultApiTokenManager implements ApiTokenManager.
+// Token secrets carry 256 bits of entropy, so a plain SHA-256 hash suffices for verification
+// and no password hashing is needed on every API request.
+type DefaultApiTokenManager struct {
+	userRepository    UserRepository
+	tokenRepository   ApiTokenRepository
+	idGenerator       ApiTokenIdGenerator
+	encryptionService encryption.EncryptionService
+	logger            ccc.Logger
+}
+
+// NewDefaultApiTokenManager creates a new DefaultApiTokenManager instance
+func NewDefaultApiTokenManager(
+	userRepository UserRepository,
+	tokenRepository ApiTokenRepository,
+	idGenerator ApiTokenIdGenerator,
+	encryptionService encryption.EncryptionService,
+	logger ccc.Logger) *DefaultApiTokenManager {
+
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &DefaultApiTokenManager{
+		userRepository:    userRepository,
+		tokenRepository:   tokenRepository,
+		idGenerator:       idGenerator,
+		encryptionService: encryptionService,
+		logger:            logger,
+	}
+}
+
+// CreateToken creates a new token holding a wrapped copy of the MEK
+func (m *DefaultApiTokenManager) CreateToken(request CreateApiTokenRequest) (CreateApiTokenResponse, error) {
+	m.logger.Info("Creating API token", "user_id", request.UserId)
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		return CreateApiTokenResponse{}, ccc.NewInvalidInputErrorWithMessage("name", "cannot be empty", "Please enter a name for the token.")
+	}
+	if len(name) > maxApiTokenNameLength {
+		return CreateApiTokenResponse{}, ccc.NewInvalidInputErrorWithMessage(
+			"name",
+			"too long",
+			"The name of the token must not be longer than 64 characters.",
+		)
+	}
+
+	if len(request.Scopes) == 0 {
+		return CreateApiTokenResponse{}, ccc.NewInvalidInputErrorWithMessage("scopes", "cannot be empty", "Please select at least one scope.")
+	}
+	scopes := make([]string, 0, len(request.Scopes))
+	for _, scope := range request.Scopes {
+		if !slices.Contains(ApiTokenScopes, scope) {
+			return CreateApiTokenResponse{}, ccc.NewInvalidInputErrorWithMessage("scopes", "unknown scope "+string(scope), "Unknown scope: "+string(scope))
+		}
+		if !slices.Contains(scopes, string(scope)) {
+			scopes = append(scopes, string(scope))
+		}
+	}
+
+	lifetimeDays := request.ExpiresInDays
+	if lifetimeDays == 0 {
+		lifetimeDays = defaultApiTokenLifetimeDays
+	}
+	if lifetimeDays < 1 || lifetimeDays > maxApiTokenLifetimeDays {
+		return CreateApiTokenResponse{}, ccc.NewInvalidInputErrorWithMessage(
+			"expiration",
+			"out of range",
+			"Tokens must expire within 1 to 365 days.",
+		)
+	}
+
+	if request.Mek == "" {
+		m.logger.Warn("API token creation failed: no MEK available", "user_id", request.UserId)
+		return CreateApiTokenResponse{}, ccc.NewUnauthorizedError("Your session has expired. Please sign in again.")
+	}
+
+	user, err := m.findUser(request.UserId)
+	if err != nil {
+		return CreateApiTokenResponse{}, err
+	}
+
+	secret := make([]byte, apiTokenSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		m.logger.Error("Failed to generate API token secret", "user_id", user.Id, "error", err)
+		return CreateApiTokenResponse{}, ccc.NewInternalError("generate API token secret", err)
+	}
+
+	wrappingKey, err := m.deriveMekWrappingKey(secret)
+	if err != nil {
+		m.logger.Error("Failed to derive API token wrapping key", "user_id", user.Id, "error", err)
+		return CreateApiTokenResponse{}, ccc.NewInternalError("derive API token wrapping key", err)
+	}
+
+	wrappedMek, err := m.encryptionService.Encrypt(request.Mek, wrappingKey)
+	if err != nil {
+		m.logger.Error("Failed to wrap MEK for API token", "user_id", user.Id, "error", err)
+		return CreateApiTokenResponse{}, ccc.NewInternalError("wrap MEK for API token", err)
+	}
+
+	now := time.Now().UTC()
+	token := &ApiToken{
+		Id:         m.idGenerator.GenerateId(),
+		UserId:     user.Id,
+		Name:       name,
+		SecretHash: hashApiTokenSecret(secret),
+		Scopes:     strings.Join(scopes, ","),
+		WrappedMek: wrappedMek,
+		ExpiresAt:  now.AddDate(0, 0, lifetimeDays),
+		CreatedAt:  now,
+	}
+
+	if err := m.tokenRepository.Add(token); err != nil {
+		m.logger.Error("Failed to store API token", "user_id", user.Id, "error", err)
+		return CreateApiTokenResponse{}, ccc.NewDatabaseError("add API token", err)
+	}
+
+	m.logger.Info("API token created successfully", "user_id", user.Id, "token_id", token.Id, "scopes", token.Scopes, "expires_at", token.ExpiresAt)
+
+	return CreateApiTokenResponse{
+		Token:    apiTokenPrefix + token.Id + "." + base64.RawURLEncoding.EncodeToString(secret),
+		ApiToken: toApiTokenDto(token),
+	}, nil
+}
+
+// GetTokens returns the tokens of a user
+func (m *DefaultApiTokenManager) GetTokens(userId string) ([]ApiTokenDto, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+
+	tokens, err := m.tokenRepository.FindByUserId(userId)
+	if err != nil {
+		m.logger.Error("Failed to find API tokens of user", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find API tokens by user ID", err)
+	}
+
+	dtos := make([]ApiTokenDto, 0, len(tokens))
+	for _, token := range tokens {
+		dtos = append(dtos, toApiTokenDto(token))
+	}
+	return dtos, nil
+}
+
+// RevokeToken deletes a token of a user
+func (m *DefaultApiTokenManager) RevokeToken(userId string, tokenId string) error {
+	m.logger.Info("Revoking API token", "user_id", userId, "token_id", tokenId)
+
+	if userId == "" {
+		return ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+	if tokenId == "" {
+		return ccc.NewInvalidInputError("token ID", "cannot be empty")
+	}
+
+	token, err := m.tokenRepository.FindById(tokenId)
+	if err != nil {
+		m.logger.Error("Failed to find API token", "user_id", userId, "token_id", tokenId, "error", err)
+		return ccc.NewDatabaseError("find API token by ID", err)
+	}
+
+	// Tokens of other users are reported as not found
+	if token == nil || token.UserId != userId {
+		m.logger.Warn("API token not found for revocation", "user_id", userId, "token_id", tokenId)
+		return ccc.NewResourceNotFoundError(tokenId, "API token")
+	}
+
+	if err := m.tokenRepository.Remove(tokenId); err != nil {
+		m.logger.Error("Failed to delete API token", "user_id", userId, "token_id", tokenId, "error", err)
+		return ccc.NewDatabaseError("delete API token", err)
+	}
+
+	m.logger.Info("API token revoked successfully", "user_id", userId, "token_id", tokenId)
+	return nil
+}
+
+// Authenticate verifies a token and unwraps the MEK
+func (m *DefaultApiTokenManager) Authenticate(rawToken string) (ApiPrincipal, error) {
+	tokenId, secret, ok := parseApiToken(rawToken)
+	if !ok {
+		m.logger.Debug("Rejected malformed API token")
+		return ApiPrincipal{}, ccc.NewUnauthorizedError("malformed API token")
+	}
+
+	token, err := m.tokenRepository.FindById(tokenId)
+	if err != nil {
+		m.logger.Error("Failed to find API token", "token_id", tokenId, "error", err)
+		return ApiPrincipal{}, ccc.NewDatabaseError("find API token by ID", err)
+	}
+	if token == nil {
+		m.logger.Warn("Rejected unknown API token", "token_id", tokenId)
+		return ApiPrincipal{}, ccc.NewUnauthorizedError("unknown API token")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashApiTokenSecret(secret)), []byte(token.SecretHash)) != 1 {
+		m.logger.Warn("Rejected API token with invalid secret", "token_id", tokenId, "user_id", token.UserId)
+		return ApiPrincipal{}, ccc.NewUnauthorizedError("invalid API token secret")
+	}
+
+	now := time.Now().UTC()
+	if !now.Before(token.ExpiresAt) {
+		m.logger.Info("Rejected expired API token", "token_id", tokenId, "user_id", token.UserId)
+		return ApiPrincipal{}, ccc.NewUnauthorizedError("API token expired")
+	}
+
+	user, err := m.findUser(token.UserId)
+	if err != nil {
+		if ccc.IsNotFound(err) {
+			return ApiPrincipal{}, ccc.NewUnauthorizedError("user of API token not found")
+		}
+		return ApiPrincipal{}, err
+	}
+	if !user.IsActive || user.IsLocked {
+		m.logger.Warn("Rejected API token of inactive or locked user", "token_id", tokenId, "user_id", user.Id)
+		return ApiPrincipal{}, ccc.NewForbiddenError("user is inactive or locked")
+	}
+
+	wrappingKey, err := m.deriveMekWrappingKey(secret)
+	if err != nil {
+		m.logger.Error("Failed to derive API token wrapping key", "token_id", tokenId, "error", err)
+		return ApiPrincipal{}, ccc.NewInternalError("derive API token wrapping key", err)
+	}
+
+	mek, err := m.encryptionService.Decrypt(token.WrappedMek, wrappingKey)
+	if err != nil || mek == "" {
+		m.logger.Error("Failed to unwrap MEK of API token", "token_id", tokenId, "user_id", user.Id, "error", err)
+		return ApiPrincipal{}, ccc.NewUnauthorizedError("API token could not unlock the MEK")
+	}
+
+	if token.LastUsedAt == nil || now.Sub(*token.LastUsedAt) >= apiTokenLastUsedResolution {
+		if err := m.tokenRepository.UpdateLastUsedAt(token.Id, now); err != nil {
+			m.logger.Warn("Failed to record API token use", "token_id", tokenId, "error", err)
+		}
+	}
+
+	scopes := make([]ApiTokenScope, 0)
+	for _, scope := range strings.Split(token.Scopes, ",") {
+		if scope != "" {
+			scopes = append(scopes, ApiTokenScope(scope))
+		}
+	}
+
+	return ApiPrincipal{
+		TokenId:  token.Id,
+		UserId:   user.Id,
+		UserName: user.UserName,
+		Scopes:   scopes,
+		Mek:      mek,
+	}, nil
+}
+
+func (m *DefaultApiTokenManager) findUser(userId string) (*User, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+
+	user, err := m.userRepository.FindById(userId)
+	if err != nil {
+		m.logger.Error("Failed to find user", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find user by ID", err)
+	}
+	if user == nil {
+		m.logger.Warn("User not found", "user_id", userId)
+		return nil, ccc.NewResourceNotFoundError(userId, "User")
+	}
+	return user, nil
+}
+
+// deriveMekWrappingKey derives the key wrapping the MEK from the token secret
+func (m *DefaultApiTokenManager) deriveMekWrappingKey(secret []byte) (string, error) {
+	key, err := hkdf.Key(sha256.New, secret, nil, apiTokenMekWrapDomain, 32)
+	if err != nil {
+		return "", err
+	}
+	return m.encryptionService.ConvertKeyToString(key)
+}
+
+// parseApiToken splits a token into its ID and secret
+func parseApiToken(rawToken string) (tokenId string, secret []byte, ok bool) {
+	rest, found := strings.CutPrefix(strings.TrimSpace(rawToken), apiTokenPrefix)
+	if !found {
+		return "", nil, false
+	}
+
+	tokenId, encodedSecret, found := strings.Cut(rest, ".")
+	if !found || tokenId == "" {
+		return "", nil, false
+	}
+
+	secret, err := base64.RawURLEncoding.DecodeString(encodedSecret)
+	if err != nil || len(secret) != apiTokenSecretLength {
+		return "", nil, false
+	}
+
+	return tokenId, secret, true
+}
+
+func hashApiTokenSecret(secret []byte) string {
+	hash := sha256.Sum256(secret)
+	return hex.EncodeToString(hash[:])
+}
+
+func toApiTokenDto(token *ApiToken) ApiTokenDto {
+	dto := ApiTokenDto{
+		Id:        token.Id,
+		Name:      token.Name,
+		Scopes:    strings.Split(token.Scopes, ","),
+		ExpiresAt: token.ExpiresAt.Format(time.RFC3339),
+		CreatedAt: token.CreatedAt.Format(time.RFC3339),
+		IsExpired: !time.Now().Before(token.ExpiresAt),
+	}
+	if token.LastUsedAt != nil {
+		dto.LastUsedAt = token.LastUsedAt.Format(time.RFC3339)
+	}
+	return dto
+}
@@ -0,0 +1,125 @@
+package auth
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newApiTokenTestManager(t *testing.T) (*DefaultApiTokenManager, *SQLiteUserRepository, *SQLiteApiTokenRepository) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	userRepo, _ := NewSQLiteUserRepository(db)
+	tokenRepo, _ := NewSQLiteApiTokenRepository(db)
+	manager := NewDefaultApiTokenManager(userRepo, tokenRepo, ccc.NewUuidGenerator(), encryption.NewDefaultEncryptionService(), nil)
+
+	return manager, userRepo, tokenRepo
+}
+
+func addApiTokenTestUser(t *testing.T, userRepo *SQLiteUserRepository) *User {
+	t.Helper()
+
+	now := time.Now().UTC()
+	user := &User{Id: "user-1", UserName: "alice", IsActive: true, CreatedAt: now, ModifiedAt: now}
+	if _, err := userRepo.Add(user); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	return user
+}
+
+func TestApiTokenManagerAuthenticatesAndUnwrapsMek(t *testing.T) {
+	manager, userRepo, _ := newApiTokenTestManager(t)
+	user := addApiTokenTestUser(t, userRepo)
+
+	mek, _ := encryption.NewDefaultEncryptionService().GenerateKey()
+	response, err := manager.CreateToken(CreateApiTokenRequest{
+		UserId: user.Id,
+		Mek:    mek,
+		Name:   "backup script",
+		Scopes: []ApiTokenScope{ApiTokenScopeSecretsRead},
+	})
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+	if !strings.HasPrefix(response.Token, apiTokenPrefix) {
+		t.Errorf("expected token to start with %s, got %s", apiTokenPrefix, response.Token)
+	}
+
+	principal, err := manager.Authenticate(response.Token)
+	if err != nil {
+		t.Fatalf("Authenticate failed: %v", err)
+	}
+	if principal.UserId != user.Id || principal.Mek != mek {
+		t.Errorf("unexpected principal %+v", principal)
+	}
+	if !principal.HasScope(ApiTokenScopeSecretsRead) || principal.HasScope(ApiTokenScopeSecretsWrite) {
+		t.Errorf("unexpected scopes %v", principal.Scopes)
+	}
+
+	tokens, _ := manager.GetTokens(user.Id)
+	if len(tokens) != 1 || tokens[0].LastUsedAt == "" {
+		t.Errorf("expected the token to be listed with its last use, got %+v", tokens)
+	}
+}
+
+func TestApiTokenManagerRejectsInvalidTokens(t *testing.T) {
+	manager, userRepo, tokenRepo := newApiTokenTestManager(t)
+	user := addApiTokenTestUser(t, userRepo)
+
+	mek, _ := encryption.NewDefaultEncryptionService().GenerateKey()
+	response, err := manager.CreateToken(CreateApiTokenRequest{
+		UserId: user.Id,
+		Mek:    mek,
+		Name:   "ci",
+		Scopes: []ApiTokenScope{ApiTokenScopeDocumentsRead},
+	})
+	if err != nil {
+		t.Fatalf("CreateToken failed: %v", err)
+	}
+
+	tampered := []byte(response.Token)
+	tampered[len(tampered)-5] ^= 0x01
+
+	for name, token := range map[string]string{
+		"malformed":      "not-a-token",
+		"unknown":        apiTokenPrefix + "unknown." + strings.SplitN(response.Token, ".", 2)[1],
+		"invalid secret": string(tampered),
+	} {
+		if _, err := manager.Authenticate(token); !ccc.IsErrorCode(err, ccc.ErrCodeUnauthorized) {
+			t.Errorf("%s: expected an unauthorized error, got %v", name, err)
+		}
+	}
+
+	// Expired tokens are rejected even with a valid secret
+	token, _ := tokenRepo.FindById(response.ApiToken.Id)
+	if _, err := tokenRepo.db.Exec(`UPDATE ApiToken SET ExpiresAt = ? WHERE Id = ?`, ccc.FormatSQLiteTimestamp(time.Now().UTC().Add(-time.Minute)), token.Id); err != nil {
+		t.Fatalf("failed to expire token: %v", err)
+	}
+	if _, err := manager.Authenticate(response.Token); !ccc.IsErrorCode(err, ccc.ErrCodeUnauthorized) {
+		t.Errorf("expected an expired token to be rejected, got %v", err)
+	}
+
+	if err := manager.RevokeToken("someone-else", response.ApiToken.Id); !ccc.IsNotFound(err) {
+		t.Errorf("expected revoking a token of another user to fail, got %v", err)
+	}
+	if err := manager.RevokeToken(user.Id, response.ApiToken.Id); err != nil {
+		t.Errorf("RevokeToken failed: %v", err)
+	}
+}
@@ -0,0 +1,279 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+// maxPasskeyNameLength limits the length of user chosen passkey names
+const maxPasskeyNameLength = 64
+
+// DefaultPasskeyManager implements PasskeyManager.
+// Every passkey stores its own envelope of the user's MEK, wrapped with a key derived from the
+// credential's PRF output, so that a passkey sign-in can unlock the vault without the password.
+type DefaultPasskeyManager struct {
+	userRepository       UserRepository
+	credentialRepository WebAuthnCredentialRepository
+	idGenerator          WebAuthnCredentialIdGenerator
+	webAuthnService      WebAuthnService
+	encryptionService    encryption.EncryptionService
+	logger               ccc.Logger
+}
+
+// NewDefaultPasskeyManager creates a new DefaultPasskeyManager instance
+func NewDefaultPasskeyManager(
+	userRepository UserRepository,
+	credentialRepository WebAuthnCredentialRepository,
+	idGenerator WebAuthnCredentialIdGenerator,
+	webAuthnService WebAuthnService,
+	encryptionService encryption.EncryptionService,
+	logger ccc.Logger) *DefaultPasskeyManager {
+
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &DefaultPasskeyManager{
+		userRepository:       userRepository,
+		credentialRepository: credentialRepository,
+		idGenerator:          idGenerator,
+		webAuthnService:      webAuthnService,
+		encryptionService:    encryptionService,
+		logger:               logger,
+	}
+}
+
+// BeginRegistration creates the options for registering a new passkey
+func (m *DefaultPasskeyManager) BeginRegistration(userId string, rp WebAuthnRelyingParty) (PasskeyCreationOptions, error) {
+	m.logger.Info("Beginning passkey registration", "user_id", userId, "rp_id", rp.Id)
+
+	user, err := m.findUser(userId)
+	if err != nil {
+		return PasskeyCreationOptions{}, err
+	}
+
+	challenge, err := m.webAuthnService.NewChallenge()
+	if err != nil {
+		m.logger.Error("Failed to generate WebAuthn challenge", "user_id", userId, "error", err)
+		return PasskeyCreationOptions{}, ccc.NewInternalError("generate WebAuthn challenge", err)
+	}
+
+	existing, err := m.credentialRepository.FindByUserId(userId)
+	if err != nil {
+		m.logger.Error("Failed to find passkeys of user", "user_id", userId, "error", err)
+		return PasskeyCreationOptions{}, ccc.NewDatabaseError("find passkeys by user ID", err)
+	}
+
+	// Prevent registering the same authenticator twice
+	excludeCredentials := make([]PasskeyCredentialDescriptor, 0, len(existing))
+	for _, credential := range existing {
+		excludeCredentials = append(excludeCredentials, credentialDescriptor(credential))
+	}
+
+	params := make([]PasskeyCredentialParameter, 0, len(SupportedWebAuthnAlgorithms))
+	for _, alg := range SupportedWebAuthnAlgorithms {
+		params = append(params, PasskeyCredentialParameter{Type: "public-key", Alg: alg})
+	}
+
+	return PasskeyCreationOptions{
+		Challenge: challenge,
+		RelyingParty: PasskeyRelyingPartyEntity{
+			Id:   rp.Id,
+			Name: rp.Name,
+		},
+		User: PasskeyUserEntity{
+			Id:          base64.RawURLEncoding.EncodeToString([]byte(user.Id)),
+			Name:        user.UserName,
+			DisplayName: user.UserName,
+		},
+		PubKeyCredParams:   params,
+		Timeout:            webAuthnTimeoutMillis,
+		ExcludeCredentials: excludeCredentials,
+		AuthenticatorSelection: PasskeyAuthenticatorSelection{
+			ResidentKey:      "required",
+			UserVerification: "required",
+		},
+		Attestation: "none",
+		PrfSalt:     m.webAuthnService.PrfSalt(),
+	}, nil
+}
+
+// FinishRegistration verifies the registration response and stores the passkey with its MEK envelope
+func (m *DefaultPasskeyManager) FinishRegistration(request FinishPasskeyRegistrationRequest) (PasskeyDto, error) {
+	m.logger.Info("Finishing passkey registration", "user_id", request.UserId, "rp_id", request.RelyingParty.Id)
+
+	name := strings.TrimSpace(request.Name)
+	if name == "" {
+		name = "Passkey"
+	}
+	if len(name) > maxPasskeyNameLength {
+		return PasskeyDto{}, ccc.NewInvalidInputErrorWithMessage(
+			"name",
+			"too long",
+			"The name of the passkey must not be longer than 64 characters.",
+		)
+	}
+
+	if request.Mek == "" {
+		m.logger.Warn("Passkey registration failed: no MEK available", "user_id", request.UserId)
+		return PasskeyDto{}, ccc.NewUnauthorizedError("Your session has expired. Please sign in again.")
+	}
+
+	if request.Challenge == "" {
+		m.logger.Warn("Passkey registration failed: no pending challenge", "user_id", request.UserId)
+		return PasskeyDto{}, ccc.NewInvalidInputErrorWithMessage("challenge", "missing", "The registration has expired. Please try again.")
+	}
+
+	user, err := m.findUser(request.UserId)
+	if err != nil {
+		return PasskeyDto{}, err
+	}
+
+	credential, err := m.webAuthnService.VerifyRegistration(request.RelyingParty, request.Challenge, request.Response)
+	if err != nil {
+		m.logger.Warn("Passkey registration response could not be verified", "user_id", user.Id, "error", err)
+		return PasskeyDto{}, ccc.NewInvalidInputErrorWithMessage("passkey", err.Error(), "The passkey could not be verified.")
+	}
+
+	existing, err := m.credentialRepository.FindByCredentialId(credential.CredentialId)
+	if err != nil {
+		m.logger.Error("Failed to look up passkey", "user_id", user.Id, "error", err)
+		return PasskeyDto{}, ccc.NewDatabaseError("find passkey by credential ID", err)
+	}
+	if existing != nil {
+		m.logger.Warn("Passkey registration failed: credential already registered", "user_id", user.Id)
+		return PasskeyDto{}, ccc.NewResourceAlreadyExistsError(credential.CredentialId, "Passkey")
+	}
+
+	// Without the PRF extension the passkey could not unlock the MEK
+	if request.Response.PrfOutput == "" {
+		m.logger.Warn("Passkey registration failed: PRF extension not supported", "user_id", user.Id)
+		return PasskeyDto{}, ccc.NewInvalidInputErrorWithMessage(
+			"passkey",
+			"PRF extension not supported",
+			"This passkey does not support the PRF extension, which is required to unlock your vault. Please use another authenticator or browser.",
+		)
+	}
+
+	wrappingKey, err := m.webAuthnService.DeriveMekWrappingKey(request.Response.PrfOutput)
+	if err != nil {
+		m.logger.Warn("Passkey registration failed: invalid PRF output", "user_id", user.Id, "error", err)
+		return PasskeyDto{}, ccc.NewInvalidInputErrorWithMessage("passkey", err.Error(), "The passkey returned an invalid PRF result.")
+	}
+
+	wrappedMek, err := m.encryptionService.Encrypt(request.Mek, wrappingKey)
+	if err != nil {
+		m.logger.Error("Failed to wrap MEK for passkey", "user_id", user.Id, "error", err)
+		return PasskeyDto{}, ccc.NewInternalError("wrap MEK for passkey", err)
+	}
+
+	credential.Id = m.idGenerator.GenerateId()
+	credential.UserId = user.Id
+	credential.Name = name
+	credential.WrappedMek = wrappedMek
+	credential.CreatedAt = time.Now()
+
+	if err := m.credentialRepository.Add(credential); err != nil {
+		m.logger.Error("Failed to store passkey", "user_id", user.Id, "error", err)
+		return PasskeyDto{}, ccc.NewDatabaseError("add passkey", err)
+	}
+
+	m.logger.Info("Passkey registered successfully", "user_id", user.Id, "username", user.UserName, "passkey_id", credential.Id)
+	return toPasskeyDto(credential), nil
+}
+
+// GetPasskeys returns the passkeys of a user
+func (m *DefaultPasskeyManager) GetPasskeys(userId string) ([]PasskeyDto, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+
+	credentials, err := m.credentialRepository.FindByUserId(userId)
+	if err != nil {
+		m.logger.Error("Failed to find passkeys of user", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find passkeys by user ID", err)
+	}
+
+	passkeys := make([]PasskeyDto, 0, len(credentials))
+	for _, credential := range credentials {
+		passkeys = append(passkeys, toPasskeyDto(credential))
+	}
+	return passkeys, nil
+}
+
+// DeletePasskey removes a passkey of a user
+func (m *DefaultPasskeyManager) DeletePasskey(userId string, passkeyId string) error {
+	m.logger.Info("Deleting passkey", "user_id", userId, "passkey_id", passkeyId)
+
+	if userId == "" {
+		return ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+	if passkeyId == "" {
+		return ccc.NewInvalidInputError("passkey ID", "cannot be empty")
+	}
+
+	credential, err := m.credentialRepository.FindById(passkeyId)
+	if err != nil {
+		m.logger.Error("Failed to find passkey", "user_id", userId, "passkey_id", passkeyId, "error", err)
+		return ccc.NewDatabaseError("find passkey by ID", err)
+	}
+
+	// Passkeys of other users are reported as not found
+	if credential == nil || credential.UserId != userId {
+		m.logger.Warn("Passkey not found for deletion", "user_id", userId, "passkey_id", passkeyId)
+		return ccc.NewResourceNotFoundError(passkeyId, "Passkey")
+	}
+
+	if err := m.credentialRepository.Remove(passkeyId); err != nil {
+		m.logger.Error("Failed to delete passkey", "user_id", userId, "passkey_id", passkeyId, "error", err)
+		return ccc.NewDatabaseError("delete passkey", err)
+	}
+
+	m.logger.Info("Passkey deleted successfully", "user_id", userId, "passkey_id", passkeyId)
+	return nil
+}
+
+func (m *DefaultPasskeyManager) findUser(userId string) (*User, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+
+	user, err := m.userRepository.FindById(userId)
+	if err != nil {
+		m.logger.Error("Failed to find user", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find user by ID", err)
+	}
+	if user == nil {
+		m.logger.Warn("User not found", "user_id", userId)
+		return nil, ccc.NewResourceNotFoundError(userId, "User")
+	}
+	return user, nil
+}
+
+// credentialDescriptor creates the descriptor referencing a stored credential
+func credentialDescriptor(credential *WebAuthnCredential) PasskeyCredentialDescriptor {
+	descriptor := PasskeyCredentialDescriptor{
+		Type: "public-key",
+		Id:   credential.CredentialId,
+	}
+	if credential.Transports != "" {
+		descriptor.Transports = strings.Split(credential.Transports, ",")
+	}
+	return descriptor
+}
+
+func toPasskeyDto(credential *WebAuthnCredential) PasskeyDto {
+	dto := PasskeyDto{
+		Id:        credential.Id,
+		Name:      credential.Name,
+		CreatedAt: credential.CreatedAt.Format(time.RFC3339),
+	}
+	if credential.LastUsedAt != nil {
+		dto.LastUsedAt = credential.LastUsedAt.Format(time.RFC3339)
+	}
+	return dto
+}
@@ -14,16 +14,27 @@ type DefaultSignInHandler struct {
 	signInHistoryRepository SignInHistoryItemRepository
 	securityService         SecurityService
 	encryptionService       encryption.EncryptionService
+	totpService             TotpService
+	credentialRepository    WebAuthnCredentialRepository
+	webAuthnService         WebAuthnService
+	signInListeners         []SignInListener
 	config                  ccc.AppConfig
 	logger                  ccc.Logger
 }
 
+// totpChallengeLifetime is the time within which the TOTP step must follow a successful password step
+const totpChallengeLifetime = 5 * time.Minute
+
 // NewDefaultSignInHandler creates a new DefaultSignInHandler with all dependencies injected
 func NewDefaultSignInHandler(
 	userRepo UserRepository,
 	signInHistoryRepo SignInHistoryItemRepository,
 	securityService SecurityService,
 	encryptionService encryption.EncryptionService,
+	totpService TotpService,
+	credentialRepository WebAuthnCredentialRepository,
+	webAuthnService WebAuthnService,
+	signInListeners []SignInListener,
 	config ccc.AppConfig,
 	logger ccc.Logger) *DefaultSignInHandler {
 
@@ -36,11 +47,24 @@ func NewDefaultSignInHandler(
 		signInHistoryRepository: signInHistoryRepo,
 		securityService:         securityService,
 		encryptionService:       encryptionService,
+		totpService:             totpService,
+		credentialRepository:    credentialRepository,
+		webAuthnService:         webAuthnService,
+		signInListeners:         signInListeners,
 		config:                  config,
 		logger:                  logger,
 	}
 }
 
+// notifySignedIn tells the sign-in listeners about a successful sign-in and collects their notices
+func (h *DefaultSignInHandler) notifySignedIn(user *User, mek string) []string {
+	var notices []string
+	for _, listener := range h.signInListeners {
+		notices = append(notices, listener.UserSignedIn(user.Id, mek)...)
+	}
+	return notices
+}
+
 // Helper function to create sign-in history item
 func (h *DefaultSignInHandler) createHistoryItem(userName, userId string, context SignInContext, signInMethod SignInMethod) *SignInHistoryItem {
 	return &SignInHistoryItem{
@@ -231,6 +255,18 @@ func (h *DefaultSignInHandler) HandleSignIn(request SignInRequest, context SignI
 
 	h.logger.Debug("MEK uncovered successfully", "username", request.UserName, "user_id", user.Id)
 
+	// Users with TOTP enabled must complete the sign-in with a valid code.
+	// The attempt is recorded once the second step has been completed or denied.
+	if user.TotpEnabled {
+		h.logger.Info("Password accepted, TOTP code required", "username", request.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+		return SignInResult{
+			Success:      false,
+			RequiresTotp: true,
+			User:         user,
+			Mek:          mek,
+		}, nil
+	}
+
 	// Log successful sign-in
 	h.logSuccessfulAttempt(historyItem)
 
@@ -240,6 +276,277 @@ func (h *DefaultSignInHandler) HandleSignIn(request SignInRequest, context SignI
 		Success: true,
 		User:    user,
 		Mek:     mek,
+		Notices: h.notifySignedIn(user, mek),
+	}, nil
+}
+
+// HandleTotpSignIn completes a password sign-in by verifying the user's TOTP code.
+// Invalid codes count as failed sign-in attempts, so they are subject to the same account lockout as invalid passwords.
+// If the result is unsuccessful but still requires TOTP, the caller may retry with another code.
+func (h *DefaultSignInHandler) HandleTotpSignIn(request TotpSignInRequest, context SignInContext) (SignInResult, error) {
+	h.logger.Info("Processing TOTP sign-in step", "user_id", request.UserId, "ip_address", context.IPAddress, "client_type", context.ClientType)
+
+	if request.UserId == "" || request.Mek == "" {
+		h.logger.Warn("TOTP sign-in failed: no pending sign-in", "ip_address", context.IPAddress)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Sign-in expired, please sign in again",
+		}, nil
+	}
+
+	user, err := h.userRepository.FindById(request.UserId)
+	if err != nil {
+		h.logger.Error("Failed to find user for TOTP sign-in", "user_id", request.UserId, "error", err)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Internal error",
+		}, ccc.NewDatabaseError("find user by ID", err)
+	}
+	if user == nil || user.Id == "" {
+		h.logger.Warn("TOTP sign-in failed: user not found", "user_id", request.UserId, "ip_address", context.IPAddress)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Sign-in expired, please sign in again",
+		}, nil
+	}
+
+	historyItem := h.createHistoryItem(user.UserName, user.Id, context, SignInMethodTotp)
+
+	// The account may have been locked or deactivated since the password step
+	valid, denialReason := h.validateUserStatus(user, context)
+	if !valid {
+		historyItem.DenialReason = denialReason
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Invalid credentials",
+		}, nil
+	}
+
+	if time.Since(request.ChallengeIssuedAt) > totpChallengeLifetime {
+		h.logger.Warn("TOTP sign-in failed: challenge expired", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+		historyItem.DenialReason = DenialReasonTotpChallengeExpired
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Sign-in expired, please sign in again",
+		}, nil
+	}
+
+	if !user.TotpEnabled || user.TotpSecret == "" {
+		// TOTP was reset between both steps, the password step has to be repeated
+		h.logger.Warn("TOTP sign-in failed: TOTP is no longer enabled", "username", user.UserName, "user_id", user.Id)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Sign-in expired, please sign in again",
+		}, nil
+	}
+
+	secret, err := h.encryptionService.Decrypt(user.TotpSecret, request.Mek)
+	if err != nil {
+		h.logger.Error("Failed to decrypt TOTP secret", "username", user.UserName, "user_id", user.Id, "error", err)
+		historyItem.DenialReason = "Internal error"
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Internal error",
+		}, ccc.NewInternalError("failed to decrypt TOTP secret", err)
+	}
+
+	step, codeValid, err := h.totpService.ValidateCode(secret, request.Code, time.Now())
+	if err != nil {
+		h.logger.Error("TOTP validation failed", "username", user.UserName, "user_id", user.Id, "error", err)
+		historyItem.DenialReason = "Internal error"
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Internal error",
+		}, ccc.NewInternalError("failed to validate TOTP code", err)
+	}
+
+	if !codeValid {
+		h.logger.Warn("TOTP sign-in failed: invalid code", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+		h.handleFailedAttempt(user, historyItem, DenialReasonInvalidTotpCode, context, "totp")
+		return SignInResult{
+			Success:      false,
+			RequiresTotp: true,
+			ErrorMessage: "Invalid code",
+		}, nil
+	}
+
+	if step <= user.TotpLastUsedStep {
+		h.logger.Warn("TOTP sign-in failed: code already used", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+		h.handleFailedAttempt(user, historyItem, DenialReasonTotpCodeReused, context, "totp")
+		return SignInResult{
+			Success:      false,
+			RequiresTotp: true,
+			ErrorMessage: "Code already used, please wait for the next code",
+		}, nil
+	}
+
+	// Remember the time step so that the same code cannot be replayed
+	user.TotpLastUsedStep = step
+	if _, err := h.userRepository.Update(user); err != nil {
+		h.logger.Error("Failed to store last used TOTP step", "username", user.UserName, "user_id", user.Id, "error", err)
+		historyItem.DenialReason = "Internal error"
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Internal error",
+		}, ccc.NewDatabaseError("update user", err)
+	}
+
+	h.logSuccessfulAttempt(historyItem)
+
+	h.logger.Info("TOTP sign-in successful", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress, "client_type", context.ClientType)
+
+	return SignInResult{
+		Success: true,
+		User:    user,
+		Mek:     request.Mek,
+		Notices: h.notifySignedIn(user, request.Mek),
+	}, nil
+}
+
+// BeginPasskeySignIn creates the options for a usernameless passkey sign-in.
+// The caller is responsible for remembering the challenge until the assertion is received.
+func (h *DefaultSignInHandler) BeginPasskeySignIn(rp WebAuthnRelyingParty) (PasskeyRequestOptions, error) {
+	challenge, err := h.webAuthnService.NewChallenge()
+	if err != nil {
+		h.logger.Error("Failed to generate WebAuthn challenge", "error", err)
+		return PasskeyRequestOptions{}, ccc.NewInternalError("generate WebAuthn challenge", err)
+	}
+
+	return PasskeyRequestOptions{
+		Challenge:        challenge,
+		RelyingPartyId:   rp.Id,
+		Timeout:          webAuthnTimeoutMillis,
+		AllowCredentials: []PasskeyCredentialDescriptor{}, // discoverable credentials only
+		UserVerification: "required",
+		PrfSalt:          h.webAuthnService.PrfSalt(),
+	}, nil
+}
+
+// HandlePasskeySignIn verifies a passkey assertion and unwraps the MEK with the PRF output of the passkey.
+// Passkeys require user verification, so no TOTP code is requested in addition.
+// Invalid assertions count as failed sign-in attempts of the passkey's owner.
+func (h *DefaultSignInHandler) HandlePasskeySignIn(request PasskeySignInRequest, context SignInContext) (SignInResult, error) {
+	h.logger.Info("Processing passkey sign-in", "ip_address", context.IPAddress, "client_type", context.ClientType)
+
+	invalidResult := SignInResult{
+		Success:      false,
+		ErrorMessage: "Invalid passkey",
+	}
+
+	if request.Challenge == "" {
+		h.logger.Warn("Passkey sign-in failed: no pending challenge", "ip_address", context.IPAddress)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Sign-in expired, please try again",
+		}, nil
+	}
+
+	credentialId, err := decodeBase64Url(request.Response.Id)
+	if err != nil || len(credentialId) == 0 {
+		h.logger.Warn("Passkey sign-in failed: malformed credential ID", "ip_address", context.IPAddress)
+		return invalidResult, nil
+	}
+
+	credential, err := h.credentialRepository.FindByCredentialId(request.Response.Id)
+	if err != nil {
+		h.logger.Error("Failed to find passkey", "ip_address", context.IPAddress, "error", err)
+		return SignInResult{Success: false, ErrorMessage: "Internal error"}, ccc.NewDatabaseError("find passkey by credential ID", err)
+	}
+	if credential == nil {
+		h.logger.Warn("Passkey sign-in failed: unknown passkey", "ip_address", context.IPAddress)
+		historyItem := h.createHistoryItem("", "", context, SignInMethodPasskey)
+		historyItem.DenialReason = DenialReasonUnknownPasskey
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return invalidResult, nil
+	}
+
+	user, err := h.userRepository.FindById(credential.UserId)
+	if err != nil {
+		h.logger.Error("Failed to find user of passkey", "user_id", credential.UserId, "error", err)
+		return SignInResult{Success: false, ErrorMessage: "Internal error"}, ccc.NewDatabaseError("find user by ID", err)
+	}
+	if user == nil || user.Id == "" {
+		h.logger.Warn("Passkey sign-in failed: user of passkey not found", "user_id", credential.UserId, "ip_address", context.IPAddress)
+		historyItem := h.createHistoryItem("", credential.UserId, context, SignInMethodPasskey)
+		historyItem.DenialReason = "User not found"
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return invalidResult, nil
+	}
+
+	historyItem := h.createHistoryItem(user.UserName, user.Id, context, SignInMethodPasskey)
+
+	valid, denialReason := h.validateUserStatus(user, context)
+	if !valid {
+		historyItem.DenialReason = denialReason
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return invalidResult, nil
+	}
+
+	signCount, err := h.webAuthnService.VerifyAssertion(request.RelyingParty, request.Challenge, credential, request.Response)
+	if err != nil {
+		h.logger.Warn("Passkey sign-in failed: invalid assertion", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress, "error", err)
+		h.handleFailedAttempt(user, historyItem, DenialReasonInvalidPasskey, context, "passkey")
+		return invalidResult, nil
+	}
+
+	// The user handle of a discoverable credential must belong to the credential's owner
+	if request.Response.UserHandle != "" {
+		userHandle, err := decodeBase64Url(request.Response.UserHandle)
+		if err != nil || string(userHandle) != user.Id {
+			h.logger.Warn("Passkey sign-in failed: user handle mismatch", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+			h.handleFailedAttempt(user, historyItem, DenialReasonInvalidPasskey, context, "passkey")
+			return invalidResult, nil
+		}
+	}
+
+	if request.Response.PrfOutput == "" {
+		h.logger.Warn("Passkey sign-in failed: no PRF output", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+		historyItem.DenialReason = DenialReasonPasskeyUnlockFailed
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{
+			Success:      false,
+			ErrorMessage: "Your browser did not provide the PRF result of the passkey required to unlock your vault",
+		}, nil
+	}
+
+	wrappingKey, err := h.webAuthnService.DeriveMekWrappingKey(request.Response.PrfOutput)
+	if err != nil {
+		h.logger.Warn("Passkey sign-in failed: invalid PRF output", "username", user.UserName, "user_id", user.Id, "error", err)
+		h.handleFailedAttempt(user, historyItem, DenialReasonPasskeyUnlockFailed, context, "passkey")
+		return invalidResult, nil
+	}
+
+	mek, err := h.encryptionService.Decrypt(credential.WrappedMek, wrappingKey)
+	if err != nil {
+		h.logger.Warn("Passkey sign-in failed: MEK could not be unwrapped", "username", user.UserName, "user_id", user.Id, "ip_address", context.IPAddress)
+		h.handleFailedAttempt(user, historyItem, DenialReasonPasskeyUnlockFailed, context, "passkey")
+		return invalidResult, nil
+	}
+
+	now := time.Now()
+	credential.SignCount = signCount
+	credential.LastUsedAt = &now
+	if err := h.credentialRepository.Update(credential); err != nil {
+		h.logger.Error("Failed to update passkey after sign-in", "username", user.UserName, "user_id", user.Id, "error", err)
+		historyItem.DenialReason = "Internal error"
+		_ = h.signInHistoryRepository.Add(historyItem)
+		return SignInResult{Success: false, ErrorMessage: "Internal error"}, ccc.NewDatabaseError("update passkey", err)
+	}
+
+	h.logSuccessfulAttempt(historyItem)
+
+	h.logger.Info("Passkey sign-in successful", "username", user.UserName, "user_id", user.Id, "passkey_id", credential.Id, "ip_address", context.IPAddress)
+
+	return SignInResult{
+		Success: true,
+		User:    user,
+		Mek:     mek,
+		Notices: h.notifySignedIn(user, mek),
 	}, nil
 }
 
@@ -411,5 +718,6 @@ func (h *DefaultSignInHandler) HandleRecoverySignIn(request RecoverySignInReques
 		User:            user,
 		Mek:             plainMek,
 		NewRecoveryCode: newRecoveryCode,
+		Notices:         h.notifySignedIn(user, plainMek),
 	}, nil
 }
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test vectors for HMAC-SHA1, truncated to 6 digits
+func TestTotpServiceMatchesRFC6238Vectors(t *testing.T) {
+	service := NewDefaultTotpService()
+	secret := base32.StdEncoding.EncodeToString([]byte("12345678901234567890"))
+
+	vectors := []struct {
+		unix int64
+		code string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1111111111, "050471"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+		{20000000000, "353130"},
+	}
+
+	for _, v := range vectors {
+		code, err := service.GenerateCode(secret, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("GenerateCode failed: %v", err)
+		}
+		if code != v.code {
+			t.Errorf("expected code %s at %d, got %s", v.code, v.unix, code)
+		}
+	}
+}
+
+func TestTotpServiceValidateCodeAcceptsAdjacentStepsOnly(t *testing.T) {
+	service := NewDefaultTotpService()
+
+	secret, err := service.GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret failed: %v", err)
+	}
+
+	now := time.Unix(1700000000, 0)
+
+	previous, _ := service.GenerateCode(secret, now.Add(-totpPeriod*time.Second))
+	step, valid, err := service.ValidateCode(secret, previous, now)
+	if err != nil || !valid {
+		t.Fatalf("expected code of the previous step to be valid (err: %v)", err)
+	}
+	if step != totpStep(now)-1 {
+		t.Fatalf("expected matched step %d, got %d", totpStep(now)-1, step)
+	}
+
+	stale, _ := service.GenerateCode(secret, now.Add(-3*totpPeriod*time.Second))
+	if _, valid, _ := service.ValidateCode(secret, stale, now); valid {
+		t.Fatal("expected code of an old step to be rejected")
+	}
+}
@@ -33,10 +33,14 @@ type DefaultUserManager struct {
 	userIdGenerator   UserIdGenerator
 	encryptionService encryption.EncryptionService
 	securityService   SecurityService
+	totpService       TotpService
 	logger            ccc.Logger
 }
 
-func NewDefaultUserManager(userRepository UserRepository, userIdGenerator UserIdGenerator, encryptionService encryption.EncryptionService, securityService SecurityService, logger ccc.Logger) *DefaultUserManager {
+// totpIssuer is the issuer shown by authenticator apps for enrolled accounts
+const totpIssuer = "Frozen Fortress"
+
+func NewDefaultUserManager(userRepository UserRepository, userIdGenerator UserIdGenerator, encryptionService encryption.EncryptionService, securityService SecurityService, totpService TotpService, logger ccc.Logger) *DefaultUserManager {
 	if logger == nil {
 		logger = ccc.NopLogger
 	}
@@ -46,6 +50,7 @@ func NewDefaultUserManager(userRepository UserRepository, userIdGenerator UserId
 		userIdGenerator:   userIdGenerator,
 		encryptionService: encryptionService,
 		securityService:   securityService,
+		totpService:       totpService,
 		logger:            logger,
 	}
 }
@@ -198,12 +203,13 @@ func (manager *DefaultUserManager) GetUserById(userId string) (UserDto, error) {
 	manager.logger.Debug("User retrieved successfully by ID", "user_id", userId, "username", user.UserName)
 
 	userDto := UserDto{
-		Id:         user.Id,
-		UserName:   user.UserName,
-		IsActive:   user.IsActive,
-		IsLocked:   user.IsLocked,
-		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
-		ModifiedAt: user.ModifiedAt.Format(time.RFC3339),
+		Id:          user.Id,
+		UserName:    user.UserName,
+		IsActive:    user.IsActive,
+		IsLocked:    user.IsLocked,
+		TotpEnabled: user.TotpEnabled,
+		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
+		ModifiedAt:  user.ModifiedAt.Format(time.RFC3339),
 	}
 
 	return userDto, nil
@@ -231,12 +237,13 @@ func (manager *DefaultUserManager) GetUserByUserName(userName string) (UserDto,
 	manager.logger.Debug("User retrieved successfully by username", "user_id", user.Id, "username", userName)
 
 	userDto := UserDto{
-		Id:         user.Id,
-		UserName:   user.UserName,
-		IsActive:   user.IsActive,
-		IsLocked:   user.IsLocked,
-		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
-		ModifiedAt: user.ModifiedAt.Format(time.RFC3339),
+		Id:          user.Id,
+		UserName:    user.UserName,
+		IsActive:    user.IsActive,
+		IsLocked:    user.IsLocked,
+		TotpEnabled: user.TotpEnabled,
+		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
+		ModifiedAt:  user.ModifiedAt.Format(time.RFC3339),
 	}
 
 	return userDto, nil
@@ -258,12 +265,13 @@ func (manager *DefaultUserManager) GetAllUsers() ([]UserDto, error) {
 
 	for i, user := range users {
 		userDtos[i] = UserDto{
-			Id:         user.Id,
-			UserName:   user.UserName,
-			IsActive:   user.IsActive,
-			IsLocked:   user.IsLocked,
-			CreatedAt:  user.CreatedAt.Format(time.RFC3339),
-			ModifiedAt: user.ModifiedAt.Format(time.RFC3339),
+			Id:          user.Id,
+			UserName:    user.UserName,
+			IsActive:    user.IsActive,
+			IsLocked:    user.IsLocked,
+			TotpEnabled: user.TotpEnabled,
+			CreatedAt:   user.CreatedAt.Format(time.RFC3339),
+			ModifiedAt:  user.ModifiedAt.Format(time.RFC3339),
 		}
 	}
 
@@ -718,3 +726,225 @@ func (manager *DefaultUserManager) GenerateRecoveryCode(request GenerateRecovery
 		Generated:    user.RecoveryGenerated.Format(time.RFC3339),
 	}, nil
 }
+
+// BeginTotpEnrollment generates a new TOTP secret for a user.
+// The secret is stored encrypted with the user's MEK, but TOTP is only enabled once the enrollment has been confirmed.
+func (manager *DefaultUserManager) BeginTotpEnrollment(request BeginTotpEnrollmentRequest) (BeginTotpEnrollmentResponse, error) {
+	manager.logger.Info("Beginning TOTP enrollment", "user_id", request.UserId)
+
+	user, plainMek, err := manager.uncoverMekForTotp(request.UserId, request.Password)
+	if err != nil {
+		return BeginTotpEnrollmentResponse{}, err
+	}
+
+	if user.TotpEnabled {
+		manager.logger.Warn("TOTP enrollment failed: TOTP already enabled", "user_id", request.UserId, "username", user.UserName)
+		return BeginTotpEnrollmentResponse{}, ccc.NewInvalidInputErrorWithMessage(
+			"TOTP",
+			"is already enabled",
+			"Two-factor authentication is already enabled. Disable it first to enroll a new authenticator.",
+		)
+	}
+
+	secret, err := manager.totpService.GenerateSecret()
+	if err != nil {
+		manager.logger.Error("Failed to generate TOTP secret", "user_id", request.UserId, "username", user.UserName, "error", err)
+		return BeginTotpEnrollmentResponse{}, ccc.NewInternalError("generate TOTP secret", err)
+	}
+
+	encryptedSecret, err := manager.encryptionService.Encrypt(secret, plainMek)
+	if err != nil {
+		manager.logger.Error("Failed to encrypt TOTP secret", "user_id", request.UserId, "username", user.UserName, "error", err)
+		return BeginTotpEnrollmentResponse{}, ccc.NewInternalError("encrypt TOTP secret", err)
+	}
+
+	user.TotpSecret = encryptedSecret
+	user.TotpEnabled = false
+	user.TotpLastUsedStep = 0
+	user.ModifiedAt = time.Now()
+
+	if err := manager.updateTotpUser(user, "save TOTP secret"); err != nil {
+		return BeginTotpEnrollmentResponse{}, err
+	}
+
+	manager.logger.Info("TOTP enrollment started", "user_id", request.UserId, "username", user.UserName)
+	return BeginTotpEnrollmentResponse{
+		Secret: secret,
+		Uri:    manager.totpService.BuildUri(totpIssuer, user.UserName, secret),
+	}, nil
+}
+
+// ConfirmTotpEnrollment enables TOTP for a user once a valid code for the pending secret was provided.
+func (manager *DefaultUserManager) ConfirmTotpEnrollment(request ConfirmTotpEnrollmentRequest) error {
+	manager.logger.Info("Confirming TOTP enrollment", "user_id", request.UserId)
+
+	if request.Code == "" {
+		manager.logger.Warn("TOTP enrollment confirmation failed: empty code", "user_id", request.UserId)
+		return ccc.NewInvalidInputError("code", "cannot be empty")
+	}
+
+	user, plainMek, err := manager.uncoverMekForTotp(request.UserId, request.Password)
+	if err != nil {
+		return err
+	}
+
+	if user.TotpEnabled {
+		manager.logger.Warn("TOTP enrollment confirmation failed: TOTP already enabled", "user_id", request.UserId, "username", user.UserName)
+		return ccc.NewInvalidInputErrorWithMessage("TOTP", "is already enabled", "Two-factor authentication is already enabled.")
+	}
+
+	if user.TotpSecret == "" {
+		manager.logger.Warn("TOTP enrollment confirmation failed: no pending enrollment", "user_id", request.UserId, "username", user.UserName)
+		return ccc.NewInvalidInputErrorWithMessage("TOTP", "no pending enrollment", "Please start the enrollment first.")
+	}
+
+	secret, err := manager.encryptionService.Decrypt(user.TotpSecret, plainMek)
+	if err != nil {
+		manager.logger.Error("Failed to decrypt TOTP secret", "user_id", request.UserId, "username", user.UserName, "error", err)
+		return ccc.NewInternalError("decrypt TOTP secret", err)
+	}
+
+	step, valid, err := manager.totpService.ValidateCode(secret, request.Code, time.Now())
+	if err != nil {
+		manager.logger.Error("Failed to validate TOTP code", "user_id", request.UserId, "username", user.UserName, "error", err)
+		return ccc.NewInternalError("validate TOTP code", err)
+	}
+
+	if !valid {
+		manager.logger.Warn("TOTP enrollment confirmation failed: invalid code", "user_id", request.UserId, "username", user.UserName)
+		return ccc.NewInvalidInputErrorWithMessage("code", "is invalid", "The code is invalid. Make sure the time on your device is correct.")
+	}
+
+	user.TotpEnabled = true
+	user.TotpLastUsedStep = step
+	user.ModifiedAt = time.Now()
+
+	if err := manager.updateTotpUser(user, "enable TOTP"); err != nil {
+		return err
+	}
+
+	manager.logger.Info("TOTP enabled", "user_id", request.UserId, "username", user.UserName)
+	return nil
+}
+
+// DisableTotp disables TOTP for a user after verifying the user's password.
+func (manager *DefaultUserManager) DisableTotp(request DisableTotpRequest) error {
+	manager.logger.Info("Disabling TOTP", "user_id", request.UserId)
+
+	user, _, err := manager.uncoverMekForTotp(request.UserId, request.Password)
+	if err != nil {
+		return err
+	}
+
+	clearTotp(user)
+
+	if err := manager.updateTotpUser(user, "disable TOTP"); err != nil {
+		return err
+	}
+
+	manager.logger.Info("TOTP disabled", "user_id", request.UserId, "username", user.UserName)
+	return nil
+}
+
+// ResetTotp disables TOTP for a user without verifying the password.
+// This is meant for administrators, e.g. if a user has lost access to the authenticator.
+func (manager *DefaultUserManager) ResetTotp(id string) (bool, error) {
+	manager.logger.Info("Resetting TOTP via user manager", "user_id", id)
+
+	if id == "" {
+		manager.logger.Warn("TOTP reset failed: empty user ID")
+		return false, ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+
+	user, err := manager.userRepository.FindById(id)
+	if err != nil {
+		manager.logger.Error("Failed to find user for TOTP reset", "user_id", id, "error", err)
+		return false, ccc.NewDatabaseError("find user by ID", err)
+	}
+
+	if user == nil {
+		manager.logger.Warn("User not found for TOTP reset", "user_id", id)
+		return false, ccc.NewResourceNotFoundError(id, "User")
+	}
+
+	if !user.TotpEnabled && user.TotpSecret == "" {
+		manager.logger.Debug("TOTP not enabled, nothing to reset", "user_id", id, "username", user.UserName)
+		return false, nil
+	}
+
+	clearTotp(user)
+
+	if err := manager.updateTotpUser(user, "reset TOTP"); err != nil {
+		return false, err
+	}
+
+	manager.logger.Info("TOTP reset successfully", "user_id", id, "username", user.UserName)
+	return true, nil
+}
+
+// uncoverMekForTotp loads the user, verifies the password and uncovers the MEK that protects the TOTP secret
+func (manager *DefaultUserManager) uncoverMekForTotp(userId string, password string) (*User, string, error) {
+	if userId == "" {
+		manager.logger.Warn("TOTP operation failed: empty user ID")
+		return nil, "", ccc.NewInvalidInputError("user ID", "cannot be empty")
+	}
+
+	if password == "" {
+		manager.logger.Warn("TOTP operation failed: empty password", "user_id", userId)
+		return nil, "", ccc.NewInvalidInputError("password", "cannot be empty")
+	}
+
+	user, err := manager.userRepository.FindById(userId)
+	if err != nil {
+		manager.logger.Error("Failed to find user for TOTP operation", "user_id", userId, "error", err)
+		return nil, "", ccc.NewDatabaseError("find user by ID", err)
+	}
+
+	if user == nil {
+		manager.logger.Warn("User not found for TOTP operation", "user_id", userId)
+		return nil, "", ccc.NewResourceNotFoundError(userId, "User")
+	}
+
+	passwordValid, err := manager.securityService.VerifyUserPassword(*user, password)
+	if err != nil {
+		manager.logger.Error("Password verification failed during TOTP operation", "user_id", userId, "username", user.UserName, "error", err)
+		return nil, "", err
+	}
+
+	if !passwordValid {
+		manager.logger.Warn("Invalid password provided for TOTP operation", "user_id", userId, "username", user.UserName)
+		return nil, "", ccc.NewUnauthorizedError("Invalid password")
+	}
+
+	plainMek, err := manager.securityService.UncoverMek(*user, password)
+	if err != nil {
+		manager.logger.Error("Failed to uncover MEK for TOTP operation", "user_id", userId, "username", user.UserName, "error", err)
+		return nil, "", ccc.NewInternalError("uncover MEK", err)
+	}
+
+	return user, plainMek, nil
+}
+
+// updateTotpUser persists the TOTP state of a user
+func (manager *DefaultUserManager) updateTotpUser(user *User, operation string) error {
+	success, err := manager.userRepository.Update(user)
+	if err != nil {
+		manager.logger.Error("Failed to update user", "operation", operation, "user_id", user.Id, "username", user.UserName, "error", err)
+		return ccc.NewDatabaseError("update user", err)
+	}
+
+	if !success {
+		manager.logger.Warn("User update returned false", "operation", operation, "user_id", user.Id, "username", user.UserName)
+		return ccc.NewOperationFailedError(operation, "update operation returned false")
+	}
+
+	return nil
+}
+
+// clearTotp removes the TOTP secret and disables TOTP
+func clearTotp(user *User) {
+	user.TotpSecret = ""
+	user.TotpEnabled = false
+	user.TotpLastUsedStep = 0
+	user.ModifiedAt = time.Now()
+}
@@ -0,0 +1,390 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+const (
+	webAuthnChallengeLength = 32
+	webAuthnTimeoutMillis   = 5 * 60 * 1000
+	webAuthnPrfOutputLength = 32
+
+	// COSE algorithm identifiers supported for passkeys
+	coseAlgES256 = -7
+	coseAlgEdDSA = -8
+	coseAlgRS256 = -257
+
+	// Authenticator data flags
+	authDataFlagUserPresent   = 0x01
+	authDataFlagUserVerified  = 0x04
+	authDataFlagAttestedCreds = 0x40
+
+	// The PRF output is expanded with HKDF, so that the raw output is never used as a key directly
+	webAuthnPrfSaltInput  = "Frozen Fortress passkey MEK envelope"
+	webAuthnMekWrapDomain = "frozenfortress-passkey-mek-v1"
+)
+
+// SupportedWebAuthnAlgorithms lists the COSE algorithms accepted for new passkeys in order of preference
+var SupportedWebAuthnAlgorithms = []int{coseAlgES256, coseAlgEdDSA, coseAlgRS256}
+
+// DefaultWebAuthnService verifies WebAuthn responses as specified in WebAuthn Level 2.
+// Attestation statements are not verified, since passkeys are not restricted to specific authenticator models.
+// User verification is required for registration and sign-in, as a passkey replaces the password.
+type DefaultWebAuthnService struct {
+	encryptionService encryption.EncryptionService
+	logger            ccc.Logger
+}
+
+// NewDefaultWebAuthnService creates a new DefaultWebAuthnService instance
+func NewDefaultWebAuthnService(encryptionService encryption.EncryptionService, logger ccc.Logger) *DefaultWebAuthnService {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &DefaultWebAuthnService{
+		encryptionService: encryptionService,
+		logger:            logger,
+	}
+}
+
+// webAuthnClientData is the relevant part of the collected client data
+type webAuthnClientData struct {
+	Type      string `json:"type"`
+	Challenge string `json:"challenge"`
+	Origin    string `json:"origin"`
+}
+
+// webAuthnAuthenticatorData is the parsed authenticator data
+type webAuthnAuthenticatorData struct {
+	RpIdHash     []byte
+	Flags        byte
+	SignCount    uint32
+	CredentialId []byte // only present in registration responses
+	PublicKey    []byte // COSE key, only present in registration responses
+}
+
+// NewChallenge generates a random base64url encoded challenge
+func (s *DefaultWebAuthnService) NewChallenge() (string, error) {
+	challenge := make([]byte, webAuthnChallengeLength)
+	if _, err := rand.Read(challenge); err != nil {
+		return "", fmt.Errorf("failed to generate challenge: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(challenge), nil
+}
+
+// VerifyRegistration verifies a registration response and returns the attested credential
+func (s *DefaultWebAuthnService) VerifyRegistration(rp WebAuthnRelyingParty, challenge string, response PasskeyRegistrationResponse) (*WebAuthnCredential, error) {
+	if _, err := s.verifyClientData(rp, challenge, "webauthn.create", response.ClientDataJSON); err != nil {
+		return nil, err
+	}
+
+	attestationObjectBytes, err := decodeBase64Url(response.AttestationObject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object encoding: %w", err)
+	}
+
+	decoded, _, err := decodeCbor(attestationObjectBytes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation object: %w", err)
+	}
+	attestationObject, ok := decoded.(map[any]any)
+	if !ok {
+		return nil, errors.New("invalid attestation object: not a map")
+	}
+	rawAuthData, ok := attestationObject["authData"].([]byte)
+	if !ok {
+		return nil, errors.New("invalid attestation object: missing authenticator data")
+	}
+
+	authData, err := parseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyAuthenticatorData(rp, authData); err != nil {
+		return nil, err
+	}
+	if authData.CredentialId == nil {
+		return nil, errors.New("authenticator data contains no attested credential")
+	}
+
+	credentialId, err := decodeBase64Url(response.Id)
+	if err != nil || !bytes.Equal(credentialId, authData.CredentialId) {
+		return nil, errors.New("credential ID does not match the attested credential")
+	}
+
+	alg, _, err := parseCosePublicKey(authData.PublicKey)
+	if err != nil {
+		return nil, err
+	}
+	if !slices.Contains(SupportedWebAuthnAlgorithms, alg) {
+		return nil, fmt.Errorf("unsupported public key algorithm %d", alg)
+	}
+
+	return &WebAuthnCredential{
+		CredentialId: base64.RawURLEncoding.EncodeToString(authData.CredentialId),
+		PublicKey:    authData.PublicKey,
+		SignCount:    authData.SignCount,
+		Transports:   strings.Join(response.Transports, ","),
+	}, nil
+}
+
+// VerifyAssertion verifies an assertion signed by the given credential and returns the new sign count
+func (s *DefaultWebAuthnService) VerifyAssertion(rp WebAuthnRelyingParty, challenge string, credential *WebAuthnCredential, response PasskeyAssertionResponse) (uint32, error) {
+	if credential == nil {
+		return 0, errors.New("no credential")
+	}
+
+	credentialId, err := decodeBase64Url(response.Id)
+	if err != nil || base64.RawURLEncoding.EncodeToString(credentialId) != credential.CredentialId {
+		return 0, errors.New("assertion was created by another credential")
+	}
+
+	clientDataJSON, err := s.verifyClientData(rp, challenge, "webauthn.get", response.ClientDataJSON)
+	if err != nil {
+		return 0, err
+	}
+
+	rawAuthData, err := decodeBase64Url(response.AuthenticatorData)
+	if err != nil {
+		return 0, fmt.Errorf("invalid authenticator data encoding: %w", err)
+	}
+	authData, err := parseWebAuthnAuthenticatorData(rawAuthData)
+	if err != nil {
+		return 0, err
+	}
+	if err := verifyAuthenticatorData(rp, authData); err != nil {
+		return 0, err
+	}
+
+	signature, err := decodeBase64Url(response.Signature)
+	if err != nil {
+		return 0, fmt.Errorf("invalid signature encoding: %w", err)
+	}
+
+	clientDataHash := sha256.Sum256(clientDataJSON)
+	signedData := append(append([]byte(nil), rawAuthData...), clientDataHash[:]...)
+
+	if err := verifyCoseSignature(credential.PublicKey, signedData, signature); err != nil {
+		return 0, err
+	}
+
+	// A counter that does not increase indicates a cloned authenticator.
+	// Authenticators without a counter always report zero.
+	if (authData.SignCount != 0 || credential.SignCount != 0) && authData.SignCount <= credential.SignCount {
+		return 0, fmt.Errorf("signature counter did not increase (stored %d, received %d)", credential.SignCount, authData.SignCount)
+	}
+
+	return authData.SignCount, nil
+}
+
+// PrfSalt returns the base64url encoded salt evaluated by the PRF extension.
+// The salt is the same for all credentials, which allows usernameless sign-ins.
+func (s *DefaultWebAuthnService) PrfSalt() string {
+	salt := sha256.Sum256([]byte(webAuthnPrfSaltInput))
+	return base64.RawURLEncoding.EncodeToString(salt[:])
+}
+
+// DeriveMekWrappingKey derives the key wrapping the MEK from a base64url encoded PRF output
+func (s *DefaultWebAuthnService) DeriveMekWrappingKey(prfOutput string) (string, error) {
+	secret, err := decodeBase64Url(prfOutput)
+	if err != nil {
+		return "", fmt.Errorf("invalid PRF output encoding: %w", err)
+	}
+	if len(secret) != webAuthnPrfOutputLength {
+		return "", fmt.Errorf("invalid PRF output length %d", len(secret))
+	}
+
+	key, err := hkdf.Key(sha256.New, secret, nil, webAuthnMekWrapDomain, 32)
+	if err != nil {
+		return "", fmt.Errorf("failed to derive MEK wrapping key: %w", err)
+	}
+
+	return s.encryptionService.ConvertKeyToString(key)
+}
+
+// verifyClientData checks type, challenge and origin of the client data and returns the raw JSON
+func (s *DefaultWebAuthnService) verifyClientData(rp WebAuthnRelyingParty, challenge string, expectedType string, encodedClientData string) ([]byte, error) {
+	clientDataJSON, err := decodeBase64Url(encodedClientData)
+	if err != nil {
+		return nil, fmt.Errorf("invalid client data encoding: %w", err)
+	}
+
+	var clientData webAuthnClientData
+	if err := json.Unmarshal(clientDataJSON, &clientData); err != nil {
+		return nil, fmt.Errorf("invalid client data: %w", err)
+	}
+
+	if clientData.Type != expectedType {
+		return nil, fmt.Errorf("unexpected client data type %q", clientData.Type)
+	}
+
+	expectedChallenge, err := decodeBase64Url(challenge)
+	if err != nil || len(expectedChallenge) == 0 {
+		return nil, errors.New("no valid challenge")
+	}
+	receivedChallenge, err := decodeBase64Url(clientData.Challenge)
+	if err != nil || subtle.ConstantTimeCompare(expectedChallenge, receivedChallenge) != 1 {
+		return nil, errors.New("challenge mismatch")
+	}
+
+	if !slices.Contains(rp.Origins, clientData.Origin) {
+		s.logger.Warn("WebAuthn response from unexpected origin", "origin", clientData.Origin, "rp_id", rp.Id)
+		return nil, fmt.Errorf("unexpected origin %q", clientData.Origin)
+	}
+
+	return clientDataJSON, nil
+}
+
+// verifyAuthenticatorData checks the RP ID hash and the user presence and verification flags
+func verifyAuthenticatorData(rp WebAuthnRelyingParty, authData *webAuthnAuthenticatorData) error {
+	rpIdHash := sha256.Sum256([]byte(rp.Id))
+	if subtle.ConstantTimeCompare(rpIdHash[:], authData.RpIdHash) != 1 {
+		return errors.New("RP ID hash mismatch")
+	}
+	if authData.Flags&authDataFlagUserPresent == 0 {
+		return errors.New("user presence flag not set")
+	}
+	if authData.Flags&authDataFlagUserVerified == 0 {
+		return errors.New("user verification flag not set")
+	}
+	return nil
+}
+
+// parseWebAuthnAuthenticatorData parses the binary authenticator data
+func parseWebAuthnAuthenticatorData(data []byte) (*webAuthnAuthenticatorData, error) {
+	if len(data) < 37 {
+		return nil, errors.New("authenticator data too short")
+	}
+
+	authData := &webAuthnAuthenticatorData{
+		RpIdHash:  data[:32],
+		Flags:     data[32],
+		SignCount: binary.BigEndian.Uint32(data[33:37]),
+	}
+
+	if authData.Flags&authDataFlagAttestedCreds != 0 {
+		rest := data[37:]
+		// AAGUID (16 bytes) followed by the credential ID length (2 bytes)
+		if len(rest) < 18 {
+			return nil, errors.New("attested credential data too short")
+		}
+		idLength := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if len(rest) < idLength {
+			return nil, errors.New("attested credential data too short")
+		}
+		authData.CredentialId = rest[:idLength]
+		rest = rest[idLength:]
+
+		// The COSE key may be followed by extension data, so its length is determined by decoding it
+		_, remaining, err := decodeCbor(rest)
+		if err != nil {
+			return nil, fmt.Errorf("invalid credential public key: %w", err)
+		}
+		authData.PublicKey = append([]byte(nil), rest[:len(rest)-len(remaining)]...)
+	}
+
+	return authData, nil
+}
+
+// parseCosePublicKey parses a COSE_Key into a public key and its algorithm
+func parseCosePublicKey(coseKey []byte) (int, crypto.PublicKey, error) {
+	decoded, _, err := decodeCbor(coseKey)
+	if err != nil {
+		return 0, nil, fmt.Errorf("invalid COSE key: %w", err)
+	}
+	key, ok := decoded.(map[any]any)
+	if !ok {
+		return 0, nil, errors.New("invalid COSE key: not a map")
+	}
+
+	keyType, _ := key[int64(1)].(int64)
+	alg, _ := key[int64(3)].(int64)
+
+	switch {
+	case keyType == 2 && alg == coseAlgES256:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		y, _ := key[int64(-3)].([]byte)
+		if curve != 1 || len(x) != 32 || len(y) != 32 {
+			return 0, nil, errors.New("invalid COSE key: unsupported EC2 key")
+		}
+		publicKey := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if _, err := publicKey.ECDH(); err != nil {
+			return 0, nil, errors.New("invalid COSE key: point is not on the curve")
+		}
+		return coseAlgES256, publicKey, nil
+	case keyType == 1 && alg == coseAlgEdDSA:
+		curve, _ := key[int64(-1)].(int64)
+		x, _ := key[int64(-2)].([]byte)
+		if curve != 6 || len(x) != ed25519.PublicKeySize {
+			return 0, nil, errors.New("invalid COSE key: unsupported OKP key")
+		}
+		return coseAlgEdDSA, ed25519.PublicKey(x), nil
+	case keyType == 3 && alg == coseAlgRS256:
+		n, _ := key[int64(-1)].([]byte)
+		e, _ := key[int64(-2)].([]byte)
+		if len(n) < 256 || len(e) == 0 || len(e) > 4 {
+			return 0, nil, errors.New("invalid COSE key: unsupported RSA key")
+		}
+		exponent := 0
+		for _, b := range e {
+			exponent = exponent<<8 | int(b)
+		}
+		return coseAlgRS256, &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: exponent}, nil
+	default:
+		return 0, nil, fmt.Errorf("unsupported COSE key type %d with algorithm %d", keyType, alg)
+	}
+}
+
+// verifyCoseSignature verifies a signature with a COSE encoded public key
+func verifyCoseSignature(coseKey []byte, signedData []byte, signature []byte) error {
+	alg, publicKey, err := parseCosePublicKey(coseKey)
+	if err != nil {
+		return err
+	}
+
+	switch alg {
+	case coseAlgES256:
+		digest := sha256.Sum256(signedData)
+		if !ecdsa.VerifyASN1(publicKey.(*ecdsa.PublicKey), digest[:], signature) {
+			return errors.New("invalid signature")
+		}
+	case coseAlgEdDSA:
+		if !ed25519.Verify(publicKey.(ed25519.PublicKey), signedData, signature) {
+			return errors.New("invalid signature")
+		}
+	case coseAlgRS256:
+		digest := sha256.Sum256(signedData)
+		if err := rsa.VerifyPKCS1v15(publicKey.(*rsa.PublicKey), crypto.SHA256, digest[:], signature); err != nil {
+			return errors.New("invalid signature")
+		}
+	}
+
+	return nil
+}
+
+// decodeBase64Url decodes base64url data with or without padding, as browsers are inconsistent about it
+func decodeBase64Url(value string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(value, "="))
+}
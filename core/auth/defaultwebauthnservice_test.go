@@ -0,0 +1,302 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+var testRelyingParty = WebAuthnRelyingParty{
+	Id:      "vault.example.com",
+	Name:    "Frozen Fortress",
+	Origins: []string{"https://vault.example.com"},
+}
+
+// softAuthenticator emulates a platform authenticator with an ES256 key
+type softAuthenticator struct {
+	t            *testing.T
+	key          *ecdsa.PrivateKey
+	credentialId []byte
+	signCount    uint32
+	origin       string
+	rpId         string
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	credentialId := make([]byte, 16)
+	rand.Read(credentialId)
+
+	return &softAuthenticator{
+		t:            t,
+		key:          key,
+		credentialId: credentialId,
+		origin:       testRelyingParty.Origins[0],
+		rpId:         testRelyingParty.Id,
+	}
+}
+
+func (a *softAuthenticator) id() string {
+	return base64.RawURLEncoding.EncodeToString(a.credentialId)
+}
+
+func (a *softAuthenticator) clientData(ceremonyType string, challenge string) []byte {
+	data, err := json.Marshal(map[string]any{
+		"type":      ceremonyType,
+		"challenge": challenge,
+		"origin":    a.origin,
+	})
+	if err != nil {
+		a.t.Fatalf("failed to marshal client data: %v", err)
+	}
+	return data
+}
+
+func (a *softAuthenticator) authenticatorData(flags byte, attested bool) []byte {
+	rpIdHash := sha256.Sum256([]byte(a.rpId))
+	data := append([]byte(nil), rpIdHash[:]...)
+	data = append(data, flags)
+	data = binary.BigEndian.AppendUint32(data, a.signCount)
+
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(a.credentialId)))
+		data = append(data, a.credentialId...)
+		data = append(data, a.coseKey()...)
+	}
+	return data
+}
+
+func (a *softAuthenticator) coseKey() []byte {
+	x := a.key.PublicKey.X.FillBytes(make([]byte, 32))
+	y := a.key.PublicKey.Y.FillBytes(make([]byte, 32))
+	return cborMap(
+		cborInt(1), cborInt(2), // kty: EC2
+		cborInt(3), cborInt(coseAlgES256),
+		cborInt(-1), cborInt(1), // crv: P-256
+		cborInt(-2), cborBytes(x),
+		cborInt(-3), cborBytes(y),
+	)
+}
+
+func (a *softAuthenticator) register(challenge string) PasskeyRegistrationResponse {
+	authData := a.authenticatorData(authDataFlagUserPresent|authDataFlagUserVerified|authDataFlagAttestedCreds, true)
+	attestationObject := cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData),
+	)
+
+	return PasskeyRegistrationResponse{
+		Id:                a.id(),
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(a.clientData("webauthn.create", challenge)),
+		AttestationObject: base64.RawURLEncoding.EncodeToString(attestationObject),
+		Transports:        []string{"internal", "hybrid"},
+	}
+}
+
+func (a *softAuthenticator) assert(challenge string) PasskeyAssertionResponse {
+	a.signCount++
+	authData := a.authenticatorData(authDataFlagUserPresent|authDataFlagUserVerified, false)
+	clientData := a.clientData("webauthn.get", challenge)
+
+	clientDataHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte(nil), authData...), clientDataHash[:]...))
+	signature, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatalf("failed to sign assertion: %v", err)
+	}
+
+	return PasskeyAssertionResponse{
+		Id:                a.id(),
+		ClientDataJSON:    base64.RawURLEncoding.EncodeToString(clientData),
+		AuthenticatorData: base64.RawURLEncoding.EncodeToString(authData),
+		Signature:         base64.RawURLEncoding.EncodeToString(signature),
+	}
+}
+
+// Minimal CBOR encoding for the values used by authenticators
+func cborHead(major byte, value uint64) []byte {
+	switch {
+	case value < 24:
+		return []byte{major<<5 | byte(value)}
+	case value <= 0xff:
+		return []byte{major<<5 | 24, byte(value)}
+	case value <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(value))
+	default:
+		return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(value))
+	}
+}
+
+func cborInt(value int) []byte {
+	if value < 0 {
+		return cborHead(1, uint64(-1-value))
+	}
+	return cborHead(0, uint64(value))
+}
+
+func cborBytes(value []byte) []byte {
+	return append(cborHead(2, uint64(len(value))), value...)
+}
+
+func cborText(value string) []byte {
+	return append(cborHead(3, uint64(len(value))), value...)
+}
+
+func cborMap(keysAndValues ...[]byte) []byte {
+	out := cborHead(5, uint64(len(keysAndValues)/2))
+	for _, item := range keysAndValues {
+		out = append(out, item...)
+	}
+	return out
+}
+
+func registerSoftAuthenticator(t *testing.T, service *DefaultWebAuthnService) (*softAuthenticator, *WebAuthnCredential) {
+	authenticator := newSoftAuthenticator(t)
+
+	challenge, err := service.NewChallenge()
+	if err != nil {
+		t.Fatalf("NewChallenge failed: %v", err)
+	}
+
+	credential, err := service.VerifyRegistration(testRelyingParty, challenge, authenticator.register(challenge))
+	if err != nil {
+		t.Fatalf("VerifyRegistration failed: %v", err)
+	}
+	return authenticator, credential
+}
+
+func TestWebAuthnServiceVerifiesRegistrationAndAssertion(t *testing.T) {
+	service := NewDefaultWebAuthnService(encryption.NewDefaultEncryptionService(), nil)
+
+	authenticator, credential := registerSoftAuthenticator(t, service)
+	if credential.CredentialId != authenticator.id() {
+		t.Errorf("expected credential ID %s, got %s", authenticator.id(), credential.CredentialId)
+	}
+	if credential.Transports != "internal,hybrid" {
+		t.Errorf("unexpected transports %q", credential.Transports)
+	}
+
+	for i := 0; i < 2; i++ {
+		challenge, _ := service.NewChallenge()
+		signCount, err := service.VerifyAssertion(testRelyingParty, challenge, credential, authenticator.assert(challenge))
+		if err != nil {
+			t.Fatalf("VerifyAssertion failed: %v", err)
+		}
+		if signCount != authenticator.signCount {
+			t.Errorf("expected sign count %d, got %d", authenticator.signCount, signCount)
+		}
+		credential.SignCount = signCount
+	}
+}
+
+func TestWebAuthnServiceRejectsInvalidAssertions(t *testing.T) {
+	service := NewDefaultWebAuthnService(encryption.NewDefaultEncryptionService(), nil)
+	authenticator, credential := registerSoftAuthenticator(t, service)
+
+	challenge, _ := service.NewChallenge()
+	otherChallenge, _ := service.NewChallenge()
+
+	if _, err := service.VerifyAssertion(testRelyingParty, otherChallenge, credential, authenticator.assert(challenge)); err == nil {
+		t.Error("expected an assertion for another challenge to be rejected")
+	}
+
+	response := authenticator.assert(challenge)
+	response.Signature = authenticator.assert(otherChallenge).Signature
+	if _, err := service.VerifyAssertion(testRelyingParty, challenge, credential, response); err == nil {
+		t.Error("expected an invalid signature to be rejected")
+	}
+
+	authenticator.origin = "https://phishing.example.net"
+	if _, err := service.VerifyAssertion(testRelyingParty, challenge, credential, authenticator.assert(challenge)); err == nil {
+		t.Error("expected an assertion from another origin to be rejected")
+	}
+	authenticator.origin = testRelyingParty.Origins[0]
+
+	authenticator.rpId = "example.net"
+	if _, err := service.VerifyAssertion(testRelyingParty, challenge, credential, authenticator.assert(challenge)); err == nil {
+		t.Error("expected an assertion for another RP ID to be rejected")
+	}
+	authenticator.rpId = testRelyingParty.Id
+
+	// A cloned authenticator reports a counter that does not exceed the stored one
+	credential.SignCount = authenticator.signCount + 1
+	if _, err := service.VerifyAssertion(testRelyingParty, challenge, credential, authenticator.assert(challenge)); err == nil {
+		t.Error("expected a sign count regression to be rejected")
+	}
+}
+
+func TestWebAuthnServiceRejectsRegistrationWithoutUserVerification(t *testing.T) {
+	service := NewDefaultWebAuthnService(encryption.NewDefaultEncryptionService(), nil)
+	authenticator := newSoftAuthenticator(t)
+	challenge, _ := service.NewChallenge()
+
+	authData := authenticator.authenticatorData(authDataFlagUserPresent|authDataFlagAttestedCreds, true)
+	response := authenticator.register(challenge)
+	response.AttestationObject = base64.RawURLEncoding.EncodeToString(cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(authData),
+	))
+
+	if _, err := service.VerifyRegistration(testRelyingParty, challenge, response); err == nil {
+		t.Error("expected a registration without user verification to be rejected")
+	}
+}
+
+func TestWebAuthnServiceWrapsMekWithPrfOutput(t *testing.T) {
+	encryptionService := encryption.NewDefaultEncryptionService()
+	service := NewDefaultWebAuthnService(encryptionService, nil)
+
+	prfOutput := make([]byte, 32)
+	rand.Read(prfOutput)
+	encodedPrfOutput := base64.RawURLEncoding.EncodeToString(prfOutput)
+
+	mek, err := encryptionService.GenerateKey()
+	if err != nil {
+		t.Fatalf("GenerateKey failed: %v", err)
+	}
+
+	wrappingKey, err := service.DeriveMekWrappingKey(encodedPrfOutput)
+	if err != nil {
+		t.Fatalf("DeriveMekWrappingKey failed: %v", err)
+	}
+	wrappedMek, err := encryptionService.Encrypt(mek, wrappingKey)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+
+	// The same PRF output has to yield the same key on every sign-in
+	unwrappingKey, err := service.DeriveMekWrappingKey(encodedPrfOutput)
+	if err != nil {
+		t.Fatalf("DeriveMekWrappingKey failed: %v", err)
+	}
+	unwrappedMek, err := encryptionService.Decrypt(wrappedMek, unwrappingKey)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if unwrappedMek != mek {
+		t.Error("unwrapped MEK does not match")
+	}
+
+	prfOutput[0] ^= 0xff
+	otherKey, _ := service.DeriveMekWrappingKey(base64.RawURLEncoding.EncodeToString(prfOutput))
+	if _, err := encryptionService.Decrypt(wrappedMek, otherKey); err == nil {
+		t.Error("expected another PRF output to fail unwrapping the MEK")
+	}
+
+	if _, err := service.DeriveMekWrappingKey(base64.RawURLEncoding.EncodeToString(prfOutput[:16])); err == nil {
+		t.Error("expected a short PRF output to be rejected")
+	}
+}
@@ -2,6 +2,7 @@ package auth
 
 import (
 	"net/http"
+	"time"
 )
 
 type UserRepository interface {
@@ -36,6 +37,14 @@ type UserManager interface {
 	DeleteUser(id string) (bool, error)
 	VerifyPassword(userId string, password string) error
 	GenerateRecoveryCode(request GenerateRecoveryCodeRequest) (GenerateRecoveryCodeResponse, error)
+	// BeginTotpEnrollment generates a new TOTP seed for the user. TOTP stays disabled until the enrolment is confirmed.
+	BeginTotpEnrollment(request BeginTotpEnrollmentRequest) (BeginTotpEnrollmentResponse, error)
+	// ConfirmTotpEnrollment enables TOTP after the user proved possession of the seed with a valid code.
+	ConfirmTotpEnrollment(request ConfirmTotpEnrollmentRequest) error
+	// DisableTotp removes the TOTP second factor after verifying the user's password.
+	DisableTotp(request DisableTotpRequest) error
+	// ResetTotp removes the TOTP second factor without verification. Intended for administrators.
+	ResetTotp(userId string) (bool, error)
 }
 
 type SecurityService interface {
@@ -65,17 +74,47 @@ type UserIdGenerator interface {
 	GenerateId() string
 }
 
+type WebAuthnCredentialIdGenerator interface {
+	GenerateId() string
+}
+
+type ApiTokenIdGenerator interface {
+	GenerateId() string
+}
+
 type SignInHandler interface {
+	// HandleSignIn verifies the password. For users with TOTP enabled, the result requires a second step via HandleTotpSignIn.
 	HandleSignIn(request SignInRequest, context SignInContext) (SignInResult, error)
+	HandleTotpSignIn(request TotpSignInRequest, context SignInContext) (SignInResult, error)
 	HandleRecoverySignIn(request RecoverySignInRequest, context SignInContext) (RecoverySignInResult, error)
+	// BeginPasskeySignIn creates the options for a usernameless passkey sign-in
+	BeginPasskeySignIn(rp WebAuthnRelyingParty) (PasskeyRequestOptions, error)
+	// HandlePasskeySignIn verifies a passkey assertion and unwraps the MEK with the PRF output. No TOTP code is required.
+	HandlePasskeySignIn(request PasskeySignInRequest, context SignInContext) (SignInResult, error)
+}
+
+// SignInListener is told about every successful sign-in while the MEK of the user is at hand,
+// e.g. to update data that can only be read with it. The returned notices are shown to the user.
+type SignInListener interface {
+	UserSignedIn(userId string, mek string) (notices []string)
 }
 
 type SignInManager interface {
 	SignIn(w http.ResponseWriter, r *http.Request, request SignInRequest) (SignInResponse, error)
+	// CompleteTotpSignIn completes a pending sign-in that requires a TOTP code
+	CompleteTotpSignIn(w http.ResponseWriter, r *http.Request, code string) (SignInResponse, error)
+	// HasPendingTotpSignIn reports whether the session holds a sign-in awaiting a TOTP code
+	HasPendingTotpSignIn(r *http.Request) (bool, error)
 	RecoverySignIn(w http.ResponseWriter, r *http.Request, request RecoverySignInRequest) (RecoverySignInResponse, error)
+	// BeginPasskeySignIn creates passkey request options and remembers the challenge in the session
+	BeginPasskeySignIn(w http.ResponseWriter, r *http.Request) (PasskeyRequestOptions, error)
+	// CompletePasskeySignIn verifies the passkey assertion and creates the session if successful
+	CompletePasskeySignIn(w http.ResponseWriter, r *http.Request, response PasskeyAssertionResponse) (SignInResponse, error)
 	SignOut(w http.ResponseWriter, r *http.Request) error
 	GetCurrentUser(r *http.Request) (UserDto, error)
 	IsSignedIn(r *http.Request) (bool, error)
+	// TakeSignInNotices returns the notices of the sign-in listeners once, after the user signed in
+	TakeSignInNotices(w http.ResponseWriter, r *http.Request) ([]string, error)
 }
 
 type MekStore interface {
@@ -84,6 +123,77 @@ type MekStore interface {
 	Delete(w http.ResponseWriter, r *http.Request) error
 }
 
+// TotpService implements time-based one-time passwords (RFC 6238).
+type TotpService interface {
+	// GenerateSecret generates a new random base32 encoded seed
+	GenerateSecret() (string, error)
+	// GenerateCode generates the code for the given point in time
+	GenerateCode(secret string, t time.Time) (string, error)
+	// ValidateCode validates a code allowing for clock skew and returns the matched time step
+	ValidateCode(secret string, code string, t time.Time) (step int64, valid bool, err error)
+	// BuildUri builds an otpauth:// URI for authenticator apps
+	BuildUri(issuer, accountName, secret string) string
+}
+
+// WebAuthnChallengeStore keeps the challenge of a pending WebAuthn ceremony between the options and the response request.
+type WebAuthnChallengeStore interface {
+	Store(w http.ResponseWriter, r *http.Request, ceremony WebAuthnCeremony, challenge string) error
+	// Take returns and removes the challenge. It returns an empty string if there is no valid challenge.
+	Take(w http.ResponseWriter, r *http.Request, ceremony WebAuthnCeremony) (string, error)
+}
+
+type WebAuthnCredentialRepository interface {
+	FindById(id string) (*WebAuthnCredential, error)
+	FindByCredentialId(credentialId string) (*WebAuthnCredential, error)
+	FindByUserId(userId string) ([]*WebAuthnCredential, error)
+	Add(credential *WebAuthnCredential) error
+	Update(credential *WebAuthnCredential) error
+	Remove(id string) error
+}
+
+// WebAuthnService implements the verification of WebAuthn registration and assertion responses.
+type WebAuthnService interface {
+	// NewChallenge generates a random base64url encoded challenge
+	NewChallenge() (string, error)
+	// VerifyRegistration verifies a registration response and returns the attested credential.
+	// The returned credential holds the credential ID, public key and sign count only.
+	VerifyRegistration(rp WebAuthnRelyingParty, challenge string, response PasskeyRegistrationResponse) (*WebAuthnCredential, error)
+	// VerifyAssertion verifies an assertion signed by the given credential and returns the new sign count
+	VerifyAssertion(rp WebAuthnRelyingParty, challenge string, credential *WebAuthnCredential, response PasskeyAssertionResponse) (uint32, error)
+	// PrfSalt returns the base64url encoded salt evaluated by the PRF extension
+	PrfSalt() string
+	// DeriveMekWrappingKey derives the key wrapping the MEK from a base64url encoded PRF output
+	DeriveMekWrappingKey(prfOutput string) (string, error)
+}
+
+// PasskeyManager manages the passkeys of a user.
+type PasskeyManager interface {
+	// BeginRegistration creates the options for registering a new passkey
+	BeginRegistration(userId string, rp WebAuthnRelyingParty) (PasskeyCreationOptions, error)
+	// FinishRegistration verifies the registration response and stores the passkey with its MEK envelope
+	FinishRegistration(request FinishPasskeyRegistrationRequest) (PasskeyDto, error)
+	GetPasskeys(userId string) ([]PasskeyDto, error)
+	DeletePasskey(userId string, passkeyId string) error
+}
+
+type ApiTokenRepository interface {
+	FindById(id string) (*ApiToken, error)
+	FindByUserId(userId string) ([]*ApiToken, error)
+	Add(token *ApiToken) error
+	UpdateLastUsedAt(id string, lastUsedAt time.Time) error
+	Remove(id string) error
+}
+
+// ApiTokenManager manages personal access tokens and authenticates REST API requests.
+type ApiTokenManager interface {
+	// CreateToken creates a new token holding a wrapped copy of the MEK. The token itself is only returned once.
+	CreateToken(request CreateApiTokenRequest) (CreateApiTokenResponse, error)
+	GetTokens(userId string) ([]ApiTokenDto, error)
+	RevokeToken(userId string, tokenId string) error
+	// Authenticate verifies a token and unwraps the MEK. Invalid, expired or revoked tokens result in an unauthorized error.
+	Authenticate(token string) (ApiPrincipal, error)
+}
+
 // SessionKeyProvider is responsible for providing session signing and encryption keys.
 type SessionKeyProvider interface {
 	GetSigningKey() ([]byte, error)
@@ -10,6 +10,22 @@ type SignInMethod string
 const (
 	SignInMethodPassword SignInMethod = "PASSWORD"
 	SignInMethodRecovery SignInMethod = "RECOVERY"
+	SignInMethodTotp     SignInMethod = "TOTP"    // Second step of a password sign-in for users with TOTP enabled
+	SignInMethodPasskey  SignInMethod = "PASSKEY" // WebAuthn sign-in with a passkey or security key
+)
+
+// Denial reasons recorded for the TOTP step of a sign-in
+const (
+	DenialReasonInvalidTotpCode      = "Invalid TOTP code"
+	DenialReasonTotpCodeReused       = "TOTP code already used"
+	DenialReasonTotpChallengeExpired = "TOTP challenge expired"
+)
+
+// Denial reasons recorded for passkey sign-ins
+const (
+	DenialReasonUnknownPasskey      = "Unknown passkey"
+	DenialReasonInvalidPasskey      = "Invalid passkey assertion"
+	DenialReasonPasskeyUnlockFailed = "Passkey could not unlock the MEK"
 )
 
 type User struct {
@@ -25,6 +41,9 @@ type User struct {
 	RecoveryCodeSalt  string
 	RecoveryMek       string // MEK encrypted with recovery code for recovery purposes
 	RecoveryGenerated time.Time
+	TotpSecret        string // TOTP seed encrypted with the MEK, empty if no TOTP enrolment exists
+	TotpEnabled       bool   // false while an enrolment awaits confirmation
+	TotpLastUsedStep  int64  // time step of the last accepted TOTP code, used to reject replays
 	CreatedAt         time.Time
 	ModifiedAt        time.Time
 }
@@ -36,8 +55,65 @@ type SignInHistoryItem struct {
 	IPAddress    string
 	UserAgent    string
 	ClientType   string
-	SignInMethod SignInMethod // SignInMethodPassword or SignInMethodRecovery
+	SignInMethod SignInMethod // SignInMethodPassword, SignInMethodRecovery, SignInMethodTotp or SignInMethodPasskey
 	Successful   bool
 	Timestamp    time.Time
 	DenialReason string
 }
+
+// WebAuthnCeremony distinguishes the challenges of pending WebAuthn ceremonies
+type WebAuthnCeremony string
+
+const (
+	WebAuthnCeremonyRegistration WebAuthnCeremony = "registration"
+	WebAuthnCeremonySignIn       WebAuthnCeremony = "signin"
+)
+
+// WebAuthnCredential is a passkey or security key registered by a user.
+// Since the MEK is normally wrapped with a password-derived key, every credential carries its own
+// MEK envelope, wrapped with a key derived from the output of the WebAuthn PRF extension.
+type WebAuthnCredential struct {
+	Id           string
+	UserId       string
+	CredentialId string // base64url encoded credential ID chosen by the authenticator
+	PublicKey    []byte // COSE encoded public key
+	SignCount    uint32 // signature counter of the authenticator, 0 if not supported
+	Name         string
+	Transports   string // comma separated transport hints reported by the browser
+	WrappedMek   string // MEK encrypted with the key derived from the PRF output
+	CreatedAt    time.Time
+	LastUsedAt   *time.Time
+}
+
+// ApiTokenScope grants a personal access token access to a part of the REST API
+type ApiTokenScope string
+
+const (
+	ApiTokenScopeSecretsRead    ApiTokenScope = "secrets:read"
+	ApiTokenScopeSecretsWrite   ApiTokenScope = "secrets:write"
+	ApiTokenScopeDocumentsRead  ApiTokenScope = "documents:read"  // documents, files, tags and notes
+	ApiTokenScopeDocumentsWrite ApiTokenScope = "documents:write" // documents, files, tags and notes
+)
+
+// ApiTokenScopes lists all scopes a token can be granted
+var ApiTokenScopes = []ApiTokenScope{
+	ApiTokenScopeSecretsRead,
+	ApiTokenScopeSecretsWrite,
+	ApiTokenScopeDocumentsRead,
+	ApiTokenScopeDocumentsWrite,
+}
+
+// ApiToken is a personal access token for the REST API.
+// The token secret itself is never stored. It is hashed for lookups and used to derive the key
+// that wraps the token's own copy of the MEK, so that API requests do not need the password.
+type ApiToken struct {
+	Id         string
+	UserId     string
+	Name       string
+	SecretHash string // hex encoded SHA-256 hash of the token secret
+	Scopes     string // comma separated ApiTokenScope values
+	WrappedMek string // MEK encrypted with the key derived from the token secret
+	ExpiresAt  time.Time
+	CreatedAt  time.Time
+	LastUsedAt *time.Time
+}
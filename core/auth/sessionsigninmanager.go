@@ -1,7 +1,9 @@
 package auth
 
 import (
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
@@ -12,6 +14,21 @@ import (
 const sessionName = "frozenfortress_session"
 const mekSessionKey = "ffmek"
 
+// Session keys of a sign-in that passed the password step and awaits the TOTP code
+const pendingTotpUserIdSessionKey = "ffTotpUserId"
+const pendingTotpMekSessionKey = "ffTotpMek"
+const pendingTotpIssuedAtSessionKey = "ffTotpIssuedAt"
+
+// signInNoticesFlashKey is the session flash key of the notices shown to the user after signing in
+const signInNoticesFlashKey = "ffSignInNotices"
+
+// Session key prefixes of the challenge of a pending WebAuthn ceremony
+const webAuthnChallengeSessionKeyPrefix = "ffWebAuthnChallenge_"
+const webAuthnIssuedAtSessionKeyPrefix = "ffWebAuthnIssuedAt_"
+
+// webAuthnChallengeLifetime limits how long a WebAuthn challenge can be answered
+const webAuthnChallengeLifetime = 5 * time.Minute
+
 // SessionSignInManager implements SignInManager using gorilla sessions
 // and delegates core sign-in logic to a SignInHandler.
 type SessionSignInManager struct {
@@ -19,6 +36,8 @@ type SessionSignInManager struct {
 	signInHandler  SignInHandler
 	sessionStore   sessions.Store
 	mekStore       MekStore
+	challengeStore WebAuthnChallengeStore
+	webAuthnConfig ccc.WebAuthnConfig
 	logger         ccc.Logger
 }
 
@@ -28,6 +47,8 @@ func NewSessionSignInManager(
 	signInHandler SignInHandler,
 	store sessions.Store,
 	mekStore MekStore,
+	challengeStore WebAuthnChallengeStore,
+	webAuthnConfig ccc.WebAuthnConfig,
 	logger ccc.Logger) *SessionSignInManager {
 
 	if logger == nil {
@@ -39,6 +60,8 @@ func NewSessionSignInManager(
 		signInHandler:  signInHandler,
 		sessionStore:   store,
 		mekStore:       mekStore,
+		challengeStore: challengeStore,
+		webAuthnConfig: webAuthnConfig,
 		logger:         logger,
 	}
 }
@@ -68,6 +91,30 @@ func (m *SessionSignInManager) SignIn(w http.ResponseWriter, r *http.Request, re
 		return SignInResponse{Success: false, Error: "Internal error"}, err
 	}
 
+	// The password was correct but the user has to provide a TOTP code as well.
+	// Remember the pending sign-in in the session without signing the user in yet.
+	if result.RequiresTotp {
+		m.logger.Info("Password accepted, awaiting TOTP code", "username", request.UserName, "user_id", result.User.Id)
+
+		session, err := m.sessionStore.Get(r, sessionName)
+		if err != nil {
+			m.logger.Error("Failed to get session from store", "username", request.UserName, "user_id", result.User.Id, "error", err)
+			return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to get session", err)
+		}
+
+		delete(session.Values, "userId")
+		delete(session.Values, mekSessionKey)
+		session.Values[pendingTotpUserIdSessionKey] = result.User.Id
+		session.Values[pendingTotpMekSessionKey] = result.Mek
+		session.Values[pendingTotpIssuedAtSessionKey] = time.Now().Unix()
+		if err := session.Save(r, w); err != nil {
+			m.logger.Error("Failed to save session", "username", request.UserName, "user_id", result.User.Id, "error", err)
+			return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to save session", err)
+		}
+
+		return SignInResponse{Success: false, RequiresTotp: true}, nil
+	}
+
 	// If authentication failed, return the result without an error
 	// Authentication failures (invalid credentials, locked accounts, etc.) are not errors
 	// but simply unsuccessful responses
@@ -86,6 +133,7 @@ func (m *SessionSignInManager) SignIn(w http.ResponseWriter, r *http.Request, re
 	}
 
 	session.Values["userId"] = result.User.Id
+	addSignInNotices(session, result.Notices)
 	err = session.Save(r, w)
 	if err != nil {
 		m.logger.Error("Failed to save session", "username", request.UserName, "user_id", result.User.Id, "error", err)
@@ -106,16 +154,235 @@ func (m *SessionSignInManager) SignIn(w http.ResponseWriter, r *http.Request, re
 	return SignInResponse{
 		Success: true,
 		User: UserDto{
-			Id:         result.User.Id,
-			UserName:   result.User.UserName,
-			IsActive:   result.User.IsActive,
-			IsLocked:   result.User.IsLocked,
-			CreatedAt:  result.User.CreatedAt.Format(time.RFC3339),
-			ModifiedAt: result.User.ModifiedAt.Format(time.RFC3339),
+			Id:          result.User.Id,
+			UserName:    result.User.UserName,
+			IsActive:    result.User.IsActive,
+			IsLocked:    result.User.IsLocked,
+			TotpEnabled: result.User.TotpEnabled,
+			CreatedAt:   result.User.CreatedAt.Format(time.RFC3339),
+			ModifiedAt:  result.User.ModifiedAt.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// CompleteTotpSignIn verifies the TOTP code of a pending sign-in and creates the session if successful.
+func (m *SessionSignInManager) CompleteTotpSignIn(w http.ResponseWriter, r *http.Request, code string) (SignInResponse, error) {
+	m.logger.Info("Processing web TOTP sign-in step")
+
+	session, err := m.sessionStore.Get(r, sessionName)
+	if err != nil {
+		m.logger.Error("Failed to get session from store", "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to get session", err)
+	}
+
+	userId, _ := session.Values[pendingTotpUserIdSessionKey].(string)
+	mek, _ := session.Values[pendingTotpMekSessionKey].(string)
+	issuedAt, _ := session.Values[pendingTotpIssuedAtSessionKey].(int64)
+
+	context := SignInContext{
+		ClientType: ClientTypeWeb,
+		IPAddress:  r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+	}
+
+	result, err := m.signInHandler.HandleTotpSignIn(TotpSignInRequest{
+		UserId:            userId,
+		Mek:               mek,
+		Code:              code,
+		ChallengeIssuedAt: time.Unix(issuedAt, 0),
+	}, context)
+	if err != nil {
+		m.logger.Error("Sign-in handler returned internal error", "user_id", userId, "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, err
+	}
+
+	if !result.Success {
+		m.logger.Debug("TOTP verification failed via sign-in handler", "user_id", userId, "error", result.ErrorMessage)
+
+		if !result.RequiresTotp {
+			// The pending sign-in cannot be completed anymore, so the password step has to be repeated
+			m.clearPendingTotpSignIn(session)
+			if err := session.Save(r, w); err != nil {
+				m.logger.Warn("Failed to clear pending TOTP sign-in", "user_id", userId, "error", err)
+			}
+		}
+
+		return SignInResponse{Success: false, RequiresTotp: result.RequiresTotp, Error: result.ErrorMessage}, nil
+	}
+
+	m.clearPendingTotpSignIn(session)
+	session.Values["userId"] = result.User.Id
+	addSignInNotices(session, result.Notices)
+	if err := session.Save(r, w); err != nil {
+		m.logger.Error("Failed to save session", "user_id", result.User.Id, "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to save session", err)
+	}
+
+	if err := m.mekStore.Store(w, r, result.Mek); err != nil {
+		m.logger.Error("Failed to store MEK in session", "user_id", result.User.Id, "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to store MEK", err)
+	}
+
+	m.logger.Info("Web sign-in completed successfully", "username", result.User.UserName, "user_id", result.User.Id)
+
+	return SignInResponse{
+		Success: true,
+		User: UserDto{
+			Id:          result.User.Id,
+			UserName:    result.User.UserName,
+			IsActive:    result.User.IsActive,
+			IsLocked:    result.User.IsLocked,
+			TotpEnabled: result.User.TotpEnabled,
+			CreatedAt:   result.User.CreatedAt.Format(time.RFC3339),
+			ModifiedAt:  result.User.ModifiedAt.Format(time.RFC3339),
 		},
 	}, nil
 }
 
+// HasPendingTotpSignIn checks if the session holds a sign-in that awaits a TOTP code
+func (m *SessionSignInManager) HasPendingTotpSignIn(r *http.Request) (bool, error) {
+	session, err := m.sessionStore.Get(r, sessionName)
+	if err != nil {
+		m.logger.Error("Failed to get session for pending TOTP lookup", "error", err)
+		return false, ccc.NewInternalError("failed to get session", err)
+	}
+
+	userId, _ := session.Values[pendingTotpUserIdSessionKey].(string)
+	issuedAt, _ := session.Values[pendingTotpIssuedAtSessionKey].(int64)
+
+	return userId != "" && time.Since(time.Unix(issuedAt, 0)) <= totpChallengeLifetime, nil
+}
+
+// clearPendingTotpSignIn removes a pending TOTP sign-in from the session values
+func (m *SessionSignInManager) clearPendingTotpSignIn(session *sessions.Session) {
+	delete(session.Values, pendingTotpUserIdSessionKey)
+	delete(session.Values, pendingTotpMekSessionKey)
+	delete(session.Values, pendingTotpIssuedAtSessionKey)
+}
+
+// BeginPasskeySignIn creates the options for a passkey sign-in and remembers the challenge in the session.
+func (m *SessionSignInManager) BeginPasskeySignIn(w http.ResponseWriter, r *http.Request) (PasskeyRequestOptions, error) {
+	m.logger.Info("Processing web passkey sign-in options request")
+
+	rp := ResolveRelyingParty(m.webAuthnConfig, r)
+
+	options, err := m.signInHandler.BeginPasskeySignIn(rp)
+	if err != nil {
+		m.logger.Error("Sign-in handler failed to create passkey options", "error", err)
+		return PasskeyRequestOptions{}, err
+	}
+
+	if err := m.challengeStore.Store(w, r, WebAuthnCeremonySignIn, options.Challenge); err != nil {
+		m.logger.Error("Failed to store passkey sign-in challenge", "error", err)
+		return PasskeyRequestOptions{}, ccc.NewInternalError("failed to store WebAuthn challenge", err)
+	}
+
+	return options, nil
+}
+
+// CompletePasskeySignIn verifies the passkey assertion against the pending challenge and creates the session if successful.
+func (m *SessionSignInManager) CompletePasskeySignIn(w http.ResponseWriter, r *http.Request, response PasskeyAssertionResponse) (SignInResponse, error) {
+	m.logger.Info("Processing web passkey sign-in request")
+
+	challenge, err := m.challengeStore.Take(w, r, WebAuthnCeremonySignIn)
+	if err != nil {
+		m.logger.Error("Failed to read passkey sign-in challenge", "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to read WebAuthn challenge", err)
+	}
+	if challenge == "" {
+		m.logger.Debug("No pending passkey sign-in challenge")
+		return SignInResponse{Success: false, Error: "The sign-in request has expired. Please try again."}, nil
+	}
+
+	context := SignInContext{
+		ClientType: ClientTypeWeb,
+		IPAddress:  r.RemoteAddr,
+		UserAgent:  r.UserAgent(),
+	}
+
+	result, err := m.signInHandler.HandlePasskeySignIn(PasskeySignInRequest{
+		Challenge:    challenge,
+		RelyingParty: ResolveRelyingParty(m.webAuthnConfig, r),
+		Response:     response,
+	}, context)
+	if err != nil {
+		m.logger.Error("Sign-in handler returned internal error", "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, err
+	}
+
+	if !result.Success {
+		m.logger.Debug("Passkey authentication failed via sign-in handler", "error", result.ErrorMessage)
+		return SignInResponse{Success: false, Error: result.ErrorMessage}, nil
+	}
+
+	session, err := m.sessionStore.Get(r, sessionName)
+	if err != nil {
+		m.logger.Error("Failed to get session from store", "user_id", result.User.Id, "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to get session", err)
+	}
+
+	m.clearPendingTotpSignIn(session)
+	session.Values["userId"] = result.User.Id
+	addSignInNotices(session, result.Notices)
+	if err := session.Save(r, w); err != nil {
+		m.logger.Error("Failed to save session", "user_id", result.User.Id, "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to save session", err)
+	}
+
+	if err := m.mekStore.Store(w, r, result.Mek); err != nil {
+		m.logger.Error("Failed to store MEK in session", "user_id", result.User.Id, "error", err)
+		return SignInResponse{Success: false, Error: "Internal error"}, ccc.NewInternalError("failed to store MEK", err)
+	}
+
+	m.logger.Info("Web passkey sign-in completed successfully", "username", result.User.UserName, "user_id", result.User.Id)
+
+	return SignInResponse{
+		Success: true,
+		User: UserDto{
+			Id:          result.User.Id,
+			UserName:    result.User.UserName,
+			IsActive:    result.User.IsActive,
+			IsLocked:    result.User.IsLocked,
+			TotpEnabled: result.User.TotpEnabled,
+			CreatedAt:   result.User.CreatedAt.Format(time.RFC3339),
+			ModifiedAt:  result.User.ModifiedAt.Format(time.RFC3339),
+		},
+	}, nil
+}
+
+// addSignInNotices keeps the notices of the sign-in listeners in the session until they are shown
+func addSignInNotices(session *sessions.Session, notices []string) {
+	for _, notice := range notices {
+		session.AddFlash(notice, signInNoticesFlashKey)
+	}
+}
+
+// TakeSignInNotices returns the notices of the sign-in listeners and removes them from the session
+func (m *SessionSignInManager) TakeSignInNotices(w http.ResponseWriter, r *http.Request) ([]string, error) {
+	session, err := m.sessionStore.Get(r, sessionName)
+	if err != nil {
+		m.logger.Error("Failed to get session for sign-in notices", "error", err)
+		return nil, ccc.NewInternalError("failed to get session", err)
+	}
+
+	flashes := session.Flashes(signInNoticesFlashKey)
+	if len(flashes) == 0 {
+		return nil, nil
+	}
+	if err := session.Save(r, w); err != nil {
+		m.logger.Error("Failed to remove sign-in notices from session", "error", err)
+		return nil, ccc.NewInternalError("failed to save session", err)
+	}
+
+	notices := make([]string, 0, len(flashes))
+	for _, flash := range flashes {
+		if notice, ok := flash.(string); ok {
+			notices = append(notices, notice)
+		}
+	}
+	return notices, nil
+}
+
 // SignOut clears the session for the user.
 func (m *SessionSignInManager) SignOut(w http.ResponseWriter, r *http.Request) error {
 	m.logger.Info("Processing sign-out request")
@@ -186,12 +453,13 @@ func (m *SessionSignInManager) GetCurrentUser(r *http.Request) (UserDto, error)
 	m.logger.Debug("Successfully retrieved current user", "user_id", user.Id, "username", user.UserName)
 
 	return UserDto{
-		Id:         user.Id,
-		UserName:   user.UserName,
-		IsActive:   user.IsActive,
-		IsLocked:   user.IsLocked,
-		CreatedAt:  user.CreatedAt.Format(time.RFC3339),
-		ModifiedAt: user.ModifiedAt.Format(time.RFC3339),
+		Id:          user.Id,
+		UserName:    user.UserName,
+		IsActive:    user.IsActive,
+		IsLocked:    user.IsLocked,
+		TotpEnabled: user.TotpEnabled,
+		CreatedAt:   user.CreatedAt.Format(time.RFC3339),
+		ModifiedAt:  user.ModifiedAt.Format(time.RFC3339),
 	}, nil
 }
 
@@ -252,6 +520,7 @@ func (m *SessionSignInManager) RecoverySignIn(w http.ResponseWriter, r *http.Req
 	}
 
 	session.Values["userId"] = result.User.Id
+	addSignInNotices(session, result.Notices)
 	err = session.Save(r, w)
 	if err != nil {
 		m.logger.Error("Failed to save session", "username", request.UserName, "user_id", result.User.Id, "error", err)
@@ -272,12 +541,13 @@ func (m *SessionSignInManager) RecoverySignIn(w http.ResponseWriter, r *http.Req
 	return RecoverySignInResponse{
 		Success: true,
 		User: UserDto{
-			Id:         result.User.Id,
-			UserName:   result.User.UserName,
-			IsActive:   result.User.IsActive,
-			IsLocked:   result.User.IsLocked,
-			CreatedAt:  result.User.CreatedAt.Format(time.RFC3339),
-			ModifiedAt: result.User.ModifiedAt.Format(time.RFC3339),
+			Id:          result.User.Id,
+			UserName:    result.User.UserName,
+			IsActive:    result.User.IsActive,
+			IsLocked:    result.User.IsLocked,
+			TotpEnabled: result.User.TotpEnabled,
+			CreatedAt:   result.User.CreatedAt.Format(time.RFC3339),
+			ModifiedAt:  result.User.ModifiedAt.Format(time.RFC3339),
 		},
 		NewRecoveryCode: result.NewRecoveryCode,
 	}, nil
@@ -408,3 +678,110 @@ func (s *SessionMekStore) Delete(w http.ResponseWriter, r *http.Request) error {
 	s.logger.Debug("MEK deleted from session successfully")
 	return nil
 }
+
+// ResolveRelyingParty determines the WebAuthn relying party for a request.
+// Unset configuration values fall back to the host the request was sent to.
+func ResolveRelyingParty(config ccc.WebAuthnConfig, r *http.Request) WebAuthnRelyingParty {
+	rp := WebAuthnRelyingParty{
+		Id:      config.RPID,
+		Name:    config.RPName,
+		Origins: config.Origins,
+	}
+
+	if rp.Id == "" {
+		host := r.Host
+		if hostname, _, err := net.SplitHostPort(host); err == nil {
+			host = hostname
+		}
+		rp.Id = host
+	}
+
+	if rp.Name == "" {
+		rp.Name = "Frozen Fortress"
+	}
+
+	if len(rp.Origins) == 0 {
+		scheme := "http"
+		if r.TLS != nil {
+			scheme = "https"
+		}
+		// Honor the scheme reported by a TLS terminating reverse proxy
+		if forwardedProto := r.Header.Get("X-Forwarded-Proto"); forwardedProto != "" {
+			scheme = strings.ToLower(strings.TrimSpace(strings.Split(forwardedProto, ",")[0]))
+		}
+		rp.Origins = []string{scheme + "://" + r.Host}
+	}
+
+	return rp
+}
+
+// SessionWebAuthnChallengeStore implements WebAuthnChallengeStore using gorilla sessions
+type SessionWebAuthnChallengeStore struct {
+	sessionStore sessions.Store
+	logger       ccc.Logger
+}
+
+func NewSessionWebAuthnChallengeStore(sessionStore sessions.Store, logger ccc.Logger) *SessionWebAuthnChallengeStore {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &SessionWebAuthnChallengeStore{
+		sessionStore: sessionStore,
+		logger:       logger,
+	}
+}
+
+// Store saves the challenge of a WebAuthn ceremony in the session, replacing a previous one
+func (s *SessionWebAuthnChallengeStore) Store(w http.ResponseWriter, r *http.Request, ceremony WebAuthnCeremony, challenge string) error {
+	s.logger.Debug("Storing WebAuthn challenge in session store", "ceremony", ceremony)
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		s.logger.Error("Failed to get session for WebAuthn challenge storage", "error", err)
+		return err
+	}
+
+	session.Values[webAuthnChallengeSessionKeyPrefix+string(ceremony)] = challenge
+	session.Values[webAuthnIssuedAtSessionKeyPrefix+string(ceremony)] = time.Now().Unix()
+	if err := s.sessionStore.Save(r, w, session); err != nil {
+		s.logger.Error("Failed to save session with WebAuthn challenge", "error", err)
+		return err
+	}
+
+	return nil
+}
+
+// Take removes the challenge of a WebAuthn ceremony from the session so that it can only be answered once
+func (s *SessionWebAuthnChallengeStore) Take(w http.ResponseWriter, r *http.Request, ceremony WebAuthnCeremony) (string, error) {
+	s.logger.Debug("Taking WebAuthn challenge from session store", "ceremony", ceremony)
+
+	session, err := s.sessionStore.Get(r, sessionName)
+	if err != nil {
+		s.logger.Error("Failed to get session for WebAuthn challenge retrieval", "error", err)
+		return "", err
+	}
+
+	challengeKey := webAuthnChallengeSessionKeyPrefix + string(ceremony)
+	issuedAtKey := webAuthnIssuedAtSessionKeyPrefix + string(ceremony)
+
+	challenge, _ := session.Values[challengeKey].(string)
+	issuedAt, _ := session.Values[issuedAtKey].(int64)
+	if challenge == "" {
+		return "", nil
+	}
+
+	delete(session.Values, challengeKey)
+	delete(session.Values, issuedAtKey)
+	if err := s.sessionStore.Save(r, w, session); err != nil {
+		s.logger.Error("Failed to save session after WebAuthn challenge removal", "error", err)
+		return "", err
+	}
+
+	if time.Since(time.Unix(issuedAt, 0)) > webAuthnChallengeLifetime {
+		s.logger.Debug("WebAuthn challenge expired", "ceremony", ceremony)
+		return "", nil
+	}
+
+	return challenge, nil
+}
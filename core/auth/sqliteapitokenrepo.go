@@ -0,0 +1,169 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteApiTokenRepository implements ApiTokenRepository using SQLite
+type SQLiteApiTokenRepository struct {
+	db *sql.DB
+}
+
+const (
+	// Field list for ApiToken table queries
+	apiTokenFieldList = `
+	Id,
+	UserId,
+	Name,
+	SecretHash,
+	Scopes,
+	WrappedMek,
+	ExpiresAt,
+	CreatedAt,
+	LastUsedAt`
+)
+
+// NewSQLiteApiTokenRepository creates a new SQLite-backed API token repository.
+// The ApiToken table is expected to be created by the schema migrations.
+func NewSQLiteApiTokenRepository(db *sql.DB) (*SQLiteApiTokenRepository, error) {
+	return &SQLiteApiTokenRepository{db: db}, nil
+}
+
+// FindById retrieves a token by its ID
+func (repo *SQLiteApiTokenRepository) FindById(id string) (*ApiToken, error) {
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM ApiToken
+	WHERE Id = ?
+	`, apiTokenFieldList)
+
+	return scanApiToken(repo.db.QueryRow(selectSql, id))
+}
+
+// FindByUserId retrieves all tokens of a user, newest first
+func (repo *SQLiteApiTokenRepository) FindByUserId(userId string) ([]*ApiToken, error) {
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM ApiToken
+	WHERE UserId = ?
+	ORDER BY CreatedAt DESC
+	`, apiTokenFieldList)
+
+	rows, err := repo.db.Query(selectSql, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying API tokens: %w", err)
+	}
+	defer rows.Close()
+
+	var tokens []*ApiToken
+	for rows.Next() {
+		token, err := scanApiToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, token)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating API tokens: %w", err)
+	}
+
+	return tokens, nil
+}
+
+// Add inserts a new token
+func (repo *SQLiteApiTokenRepository) Add(token *ApiToken) error {
+	insertSql := fmt.Sprintf(`
+	INSERT INTO ApiToken (
+		%s
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, apiTokenFieldList)
+
+	_, err := repo.db.Exec(
+		insertSql,
+		token.Id,
+		token.UserId,
+		token.Name,
+		token.SecretHash,
+		token.Scopes,
+		token.WrappedMek,
+		ccc.FormatSQLiteTimestamp(token.ExpiresAt),
+		ccc.FormatSQLiteTimestamp(token.CreatedAt),
+		formatOptionalTimestamp(token.LastUsedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting API token: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateLastUsedAt records when a token was last used
+func (repo *SQLiteApiTokenRepository) UpdateLastUsedAt(id string, lastUsedAt time.Time) error {
+	_, err := repo.db.Exec(`UPDATE ApiToken SET LastUsedAt = ? WHERE Id = ?`, ccc.FormatSQLiteTimestamp(lastUsedAt), id)
+	if err != nil {
+		return fmt.Errorf("updating API token: %w", err)
+	}
+	return nil
+}
+
+// Remove deletes a token
+func (repo *SQLiteApiTokenRepository) Remove(id string) error {
+	if _, err := repo.db.Exec(`DELETE FROM ApiToken WHERE Id = ?`, id); err != nil {
+		return fmt.Errorf("deleting API token: %w", err)
+	}
+	return nil
+}
+
+func scanApiToken(scanner ccc.RowScanner) (*ApiToken, error) {
+	token := &ApiToken{}
+	var expiresAtStr, createdAtStr string
+	var lastUsedAtStr sql.NullString
+
+	err := scanner.Scan(
+		&token.Id,
+		&token.UserId,
+		&token.Name,
+		&token.SecretHash,
+		&token.Scopes,
+		&token.WrappedMek,
+		&expiresAtStr,
+		&createdAtStr,
+		&lastUsedAtStr,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Token not found
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading API token from database: %w", err)
+	}
+
+	expiresAt, err := ccc.ParseSQLiteTimestamp(expiresAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ExpiresAt timestamp: %w", err)
+	}
+	token.ExpiresAt = expiresAt
+
+	createdAt, err := ccc.ParseSQLiteTimestamp(createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CreatedAt timestamp: %w", err)
+	}
+	token.CreatedAt = createdAt
+
+	if lastUsedAtStr.Valid && lastUsedAtStr.String != "" {
+		lastUsedAt, err := ccc.ParseSQLiteTimestamp(lastUsedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing LastUsedAt timestamp: %w", err)
+		}
+		token.LastUsedAt = &lastUsedAt
+	}
+
+	return token, nil
+}
@@ -13,41 +13,12 @@ type SQLiteSignInHistoryItemRepository struct {
 	db *sql.DB
 }
 
-// NewSQLiteSignInHistoryItemRepository creates a new SQLite-backed sign-in history repository
+// NewSQLiteSignInHistoryItemRepository creates a new SQLite-backed sign-in history repository.
+// The sign_in_history table is expected to be created by the schema migrations.
 func NewSQLiteSignInHistoryItemRepository(db *sql.DB) (*SQLiteSignInHistoryItemRepository, error) {
-	repo := &SQLiteSignInHistoryItemRepository{
+	return &SQLiteSignInHistoryItemRepository{
 		db: db,
-	}
-
-	if err := repo.initializeTable(); err != nil {
-		return nil, err
-	}
-
-	return repo, nil
-}
-
-// initializeTable creates the sign-in history table if it doesn't exist
-func (r *SQLiteSignInHistoryItemRepository) initializeTable() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS sign_in_history (
-		id INTEGER PRIMARY KEY AUTOINCREMENT,
-		user_id TEXT NOT NULL,
-		user_name TEXT,
-		ip_address TEXT,
-		user_agent TEXT,
-		client_type TEXT,
-		sign_in_method TEXT,
-		successful INTEGER NOT NULL,
-		timestamp TIMESTAMP NOT NULL,
-		denial_reason TEXT
-	);
-	CREATE INDEX IF NOT EXISTS idx_sign_in_history_user_id ON sign_in_history(user_id);
-	CREATE INDEX IF NOT EXISTS idx_sign_in_history_user_name ON sign_in_history(user_name);
-	CREATE INDEX IF NOT EXISTS idx_sign_in_history_timestamp ON sign_in_history(timestamp);
-	`
-
-	_, err := r.db.Exec(query)
-	return err
+	}, nil
 }
 
 // Add inserts a new sign-in history record
@@ -27,48 +27,17 @@ const (
     RecoveryCodeSalt,
     RecoveryMek,
     RecoveryGenerated,
+    TotpSecret,
+    TotpEnabled,
+    TotpLastUsedStep,
     CreatedAt,
     ModifiedAt`
 )
 
-// Creates a new instance of SQLiteUserRepository
-func NewSQLiteUserRepository(db *sql.DB) (*SQLiteUserRepository, error) { // Modified signature
-	repo := &SQLiteUserRepository{db: db}
-
-	if err := repo.initializeTable(); err != nil { // Added table initialization
-		return nil, err
-	}
-
-	return repo, nil
-}
-
-// initializeTable creates the user table if it doesn't exist
-func (repo *SQLiteUserRepository) initializeTable() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS User (
-		Id TEXT PRIMARY KEY,
-		UserName TEXT NOT NULL UNIQUE,
-		PasswordHash TEXT NOT NULL,
-		PasswordSalt TEXT NOT NULL,
-		Mek TEXT NOT NULL,
-		PdkSalt TEXT NOT NULL,
-		IsActive INTEGER NOT NULL,
-		IsLocked INTEGER NOT NULL,
-		RecoveryCodeHash TEXT,
-		RecoveryCodeSalt TEXT,
-		RecoveryMek TEXT,
-		RecoveryGenerated TIMESTAMP,
-		CreatedAt TIMESTAMP NOT NULL,
-		ModifiedAt TIMESTAMP NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_user_username ON User(UserName);
-	`
-	_, err := repo.db.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	return nil
+// Creates a new instance of SQLiteUserRepository.
+// The User table is expected to be created by the schema migrations.
+func NewSQLiteUserRepository(db *sql.DB) (*SQLiteUserRepository, error) {
+	return &SQLiteUserRepository{db: db}, nil
 }
 
 // Retrieves a user by their ID
@@ -159,7 +128,7 @@ func (repo *SQLiteUserRepository) Add(user *User) (bool, error) {
 	insertSql := fmt.Sprintf(`
 	INSERT INTO User (
 		%s
-	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`, userFieldList)
 
 	statement, err := repo.db.Prepare(insertSql)
@@ -194,6 +163,9 @@ func (repo *SQLiteUserRepository) Add(user *User) (bool, error) {
 		user.RecoveryCodeSalt,
 		user.RecoveryMek,
 		recoveryGeneratedStr,
+		user.TotpSecret,
+		user.TotpEnabled,
+		user.TotpLastUsedStep,
 		createdAtStr,
 		modifiedAtStr,
 	)
@@ -223,6 +195,35 @@ func (repo *SQLiteUserRepository) Remove(id string) (bool, error) {
 	// Delete all document-related entities belonging to this user
 	// We need to delete in the correct order to handle foreign key constraints
 
+	// 0. Delete pending OCR jobs of this user, as they hold a wrapped copy of the user's MEK
+	deleteOcrJobsSql := `DELETE FROM OcrJob WHERE UserId = ?`
+	_, err = tx.Exec(deleteOcrJobsSql, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting OCR jobs: %w", err)
+	}
+
+	// Delete the passkeys of this user, as they hold wrapped copies of the user's MEK
+	_, err = tx.Exec(`DELETE FROM WebAuthnCredential WHERE UserId = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting WebAuthn credentials: %w", err)
+	}
+
+	// Delete the API tokens of this user for the same reason
+	_, err = tx.Exec(`DELETE FROM ApiToken WHERE UserId = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting API tokens: %w", err)
+	}
+
+	// Delete the unfinished uploads of this user, blobs of their parts are left to the garbage collection
+	_, err = tx.Exec(`DELETE FROM UploadSessionPart WHERE SessionId IN (SELECT Id FROM UploadSession WHERE UserId = ?)`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting upload session parts: %w", err)
+	}
+	_, err = tx.Exec(`DELETE FROM UploadSession WHERE UserId = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting upload sessions: %w", err)
+	}
+
 	// 1. Delete DocumentFileMetadata for all files in documents owned by this user
 	deleteDocumentFileMetadataSql := `
 	DELETE FROM DocumentFileMetadata 
@@ -278,8 +279,22 @@ func (repo *SQLiteUserRepository) Remove(id string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("deleting user secrets: %w", err)
 	}
+	_, err = tx.Exec(`DELETE FROM SecretVersion WHERE UserId = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting user secret versions: %w", err)
+	}
+
+	// 7. Delete the reminders and notification settings of this user, as the settings hold their email address
+	_, err = tx.Exec(`DELETE FROM Notification WHERE UserId = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting notifications: %w", err)
+	}
+	_, err = tx.Exec(`DELETE FROM NotificationSettings WHERE UserId = ?`, id)
+	if err != nil {
+		return false, fmt.Errorf("deleting notification settings: %w", err)
+	}
 
-	// 7. Finally, delete the user
+	// 8. Finally, delete the user
 	deleteUserSql := `DELETE FROM User WHERE Id = ?`
 	result, err := tx.Exec(deleteUserSql, id)
 	if err != nil {
@@ -317,6 +332,9 @@ func (repo *SQLiteUserRepository) Update(user *User) (bool, error) {
 		RecoveryCodeSalt = ?,
 		RecoveryMek = ?,
 		RecoveryGenerated = ?,
+		TotpSecret = ?,
+		TotpEnabled = ?,
+		TotpLastUsedStep = ?,
 		CreatedAt = ?, 
 		ModifiedAt = ?
 	WHERE Id = ?
@@ -353,6 +371,9 @@ func (repo *SQLiteUserRepository) Update(user *User) (bool, error) {
 		user.RecoveryCodeSalt,
 		user.RecoveryMek,
 		recoveryGeneratedStr,
+		user.TotpSecret,
+		user.TotpEnabled,
+		user.TotpLastUsedStep,
 		createdAtStr,
 		modifiedAtStr,
 		user.Id,
@@ -391,6 +412,9 @@ func scanUser(scanner ccc.RowScanner) (*User, error) {
 		&user.RecoveryCodeSalt,
 		&user.RecoveryMek,
 		&recoveryGeneratedStr,
+		&user.TotpSecret,
+		&user.TotpEnabled,
+		&user.TotpLastUsedStep,
 		&createdAtStr,
 		&modifiedAtStr,
 	)
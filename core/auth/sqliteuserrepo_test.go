@@ -0,0 +1,51 @@
+package auth
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestRemoveUserDeletesNotifications(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	userRepo, _ := NewSQLiteUserRepository(db)
+	now := time.Now().UTC()
+	if _, err := userRepo.Add(&User{Id: "user-1", UserName: "alice", IsActive: true, CreatedAt: now, ModifiedAt: now}); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO NotificationSettings (UserId, Email, EmailEnabled) VALUES ('user-1', 'alice@example.com', 1)`); err != nil {
+		t.Fatalf("failed to add notification settings: %v", err)
+	}
+	if _, err := db.Exec(`INSERT INTO Notification (Id, UserId, Kind, SecretId, DueAt, CreatedAt) VALUES ('notification-1', 'user-1', 'secret_expired', 'secret-1', ?, ?)`,
+		ccc.FormatSQLiteTimestamp(now), ccc.FormatSQLiteTimestamp(now)); err != nil {
+		t.Fatalf("failed to add notification: %v", err)
+	}
+
+	removed, err := userRepo.Remove("user-1")
+	if err != nil || !removed {
+		t.Fatalf("Remove failed: removed=%v, err=%v", removed, err)
+	}
+
+	for _, table := range []string{"Notification", "NotificationSettings"} {
+		var count int
+		if err := db.QueryRow(`SELECT COUNT(*) FROM ` + table).Scan(&count); err != nil {
+			t.Fatalf("failed to count %s rows: %v", table, err)
+		}
+		if count != 0 {
+			t.Errorf("expected %s to be empty after removing the user, got %d rows", table, count)
+		}
+	}
+}
@@ -0,0 +1,202 @@
+package auth
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteWebAuthnCredentialRepository implements WebAuthnCredentialRepository using SQLite
+type SQLiteWebAuthnCredentialRepository struct {
+	db *sql.DB
+}
+
+const (
+	// Field list for WebAuthnCredential table queries
+	webAuthnCredentialFieldList = `
+	Id,
+	UserId,
+	CredentialId,
+	PublicKey,
+	SignCount,
+	Name,
+	Transports,
+	WrappedMek,
+	CreatedAt,
+	LastUsedAt`
+)
+
+// NewSQLiteWebAuthnCredentialRepository creates a new SQLite-backed WebAuthn credential repository.
+// The WebAuthnCredential table is expected to be created by the schema migrations.
+func NewSQLiteWebAuthnCredentialRepository(db *sql.DB) (*SQLiteWebAuthnCredentialRepository, error) {
+	return &SQLiteWebAuthnCredentialRepository{db: db}, nil
+}
+
+// FindById retrieves a credential by its ID
+func (repo *SQLiteWebAuthnCredentialRepository) FindById(id string) (*WebAuthnCredential, error) {
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM WebAuthnCredential
+	WHERE Id = ?
+	`, webAuthnCredentialFieldList)
+
+	return scanWebAuthnCredential(repo.db.QueryRow(selectSql, id))
+}
+
+// FindByCredentialId retrieves a credential by the credential ID chosen by the authenticator
+func (repo *SQLiteWebAuthnCredentialRepository) FindByCredentialId(credentialId string) (*WebAuthnCredential, error) {
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM WebAuthnCredential
+	WHERE CredentialId = ?
+	`, webAuthnCredentialFieldList)
+
+	return scanWebAuthnCredential(repo.db.QueryRow(selectSql, credentialId))
+}
+
+// FindByUserId retrieves all credentials of a user, oldest first
+func (repo *SQLiteWebAuthnCredentialRepository) FindByUserId(userId string) ([]*WebAuthnCredential, error) {
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM WebAuthnCredential
+	WHERE UserId = ?
+	ORDER BY CreatedAt ASC
+	`, webAuthnCredentialFieldList)
+
+	rows, err := repo.db.Query(selectSql, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying WebAuthn credentials: %w", err)
+	}
+	defer rows.Close()
+
+	var credentials []*WebAuthnCredential
+	for rows.Next() {
+		credential, err := scanWebAuthnCredential(rows)
+		if err != nil {
+			return nil, err
+		}
+		credentials = append(credentials, credential)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating WebAuthn credentials: %w", err)
+	}
+
+	return credentials, nil
+}
+
+// Add inserts a new credential
+func (repo *SQLiteWebAuthnCredentialRepository) Add(credential *WebAuthnCredential) error {
+	insertSql := fmt.Sprintf(`
+	INSERT INTO WebAuthnCredential (
+		%s
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, webAuthnCredentialFieldList)
+
+	_, err := repo.db.Exec(
+		insertSql,
+		credential.Id,
+		credential.UserId,
+		credential.CredentialId,
+		credential.PublicKey,
+		credential.SignCount,
+		credential.Name,
+		credential.Transports,
+		credential.WrappedMek,
+		ccc.FormatSQLiteTimestamp(credential.CreatedAt),
+		formatOptionalTimestamp(credential.LastUsedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("inserting WebAuthn credential: %w", err)
+	}
+
+	return nil
+}
+
+// Update updates the mutable fields of a credential
+func (repo *SQLiteWebAuthnCredentialRepository) Update(credential *WebAuthnCredential) error {
+	updateSql := `
+	UPDATE WebAuthnCredential SET
+		SignCount = ?,
+		Name = ?,
+		WrappedMek = ?,
+		LastUsedAt = ?
+	WHERE Id = ?
+	`
+
+	_, err := repo.db.Exec(
+		updateSql,
+		credential.SignCount,
+		credential.Name,
+		credential.WrappedMek,
+		formatOptionalTimestamp(credential.LastUsedAt),
+		credential.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("updating WebAuthn credential: %w", err)
+	}
+
+	return nil
+}
+
+// Remove deletes a credential
+func (repo *SQLiteWebAuthnCredentialRepository) Remove(id string) error {
+	if _, err := repo.db.Exec(`DELETE FROM WebAuthnCredential WHERE Id = ?`, id); err != nil {
+		return fmt.Errorf("deleting WebAuthn credential: %w", err)
+	}
+	return nil
+}
+
+func scanWebAuthnCredential(scanner ccc.RowScanner) (*WebAuthnCredential, error) {
+	credential := &WebAuthnCredential{}
+	var createdAtStr string
+	var lastUsedAtStr sql.NullString
+
+	err := scanner.Scan(
+		&credential.Id,
+		&credential.UserId,
+		&credential.CredentialId,
+		&credential.PublicKey,
+		&credential.SignCount,
+		&credential.Name,
+		&credential.Transports,
+		&credential.WrappedMek,
+		&createdAtStr,
+		&lastUsedAtStr,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Credential not found
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading WebAuthn credential from database: %w", err)
+	}
+
+	createdAt, err := ccc.ParseSQLiteTimestamp(createdAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CreatedAt timestamp: %w", err)
+	}
+	credential.CreatedAt = createdAt
+
+	if lastUsedAtStr.Valid && lastUsedAtStr.String != "" {
+		lastUsedAt, err := ccc.ParseSQLiteTimestamp(lastUsedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing LastUsedAt timestamp: %w", err)
+		}
+		credential.LastUsedAt = &lastUsedAt
+	}
+
+	return credential, nil
+}
+
+// formatOptionalTimestamp formats a nullable timestamp for SQLite
+func formatOptionalTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return ccc.FormatSQLiteTimestamp(*t)
+}
@@ -0,0 +1,106 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// overdueGracePeriod is how long a backup may be late before it is reported. The worker checks
+// the schedule at least hourly and retries failed runs after an hour.
+const overdueGracePeriod = 2 * failedRunRetryDelay
+
+// DefaultBackupMonitor implements BackupMonitor using the recorded runs of the backup worker
+type DefaultBackupMonitor struct {
+	backupService BackupService
+	runRepo       BackupRunRepository
+	config        ccc.AppConfig
+	logger        ccc.Logger
+}
+
+// NewDefaultBackupMonitor creates a new DefaultBackupMonitor
+func NewDefaultBackupMonitor(backupService BackupService, runRepo BackupRunRepository, config ccc.AppConfig, logger ccc.Logger) *DefaultBackupMonitor {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &DefaultBackupMonitor{
+		backupService: backupService,
+		runRepo:       runRepo,
+		config:        config,
+		logger:        logger,
+	}
+}
+
+// GetStatus returns the status of automatic backups with the latest historyLimit runs.
+// Backups are unhealthy if the latest run failed or had problems, or if a backup is overdue.
+func (m *DefaultBackupMonitor) GetStatus(historyLimit int) (*BackupStatus, error) {
+	now := time.Now()
+	status := &BackupStatus{Enabled: m.config.Backup.Enabled}
+
+	runs, err := m.runRepo.FindRecent(max(historyLimit, 1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load backup runs: %w", err)
+	}
+	if len(runs) > 0 {
+		status.LastRun = runs[0]
+	}
+	status.Runs = runs[:min(max(historyLimit, 0), len(runs))]
+
+	status.LastSuccess, err = m.runRepo.FindLatestByStatus(BackupRunStatusSucceeded, BackupRunStatusWarning)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the last successful backup run: %w", err)
+	}
+
+	var lastSuccessAt time.Time
+	if status.LastSuccess != nil {
+		lastSuccessAt = status.LastSuccess.StartedAt
+	}
+	status.ConsecutiveFailures, err = m.runRepo.CountByStatusSince(BackupRunStatusFailed, lastSuccessAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to count failed backup runs: %w", err)
+	}
+
+	if !status.Enabled {
+		return status, nil
+	}
+
+	schedule, err := NewBackupSchedule(m.config.Backup)
+	if err != nil {
+		status.Problems = append(status.Problems, err.Error())
+		return status, nil
+	}
+	status.Schedule = schedule.String()
+
+	lastBackupTime, err := m.backupService.GetLastBackupTime()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get the time of the last backup: %w", err)
+	}
+	nextRunAt := NextBackupRun(schedule, lastBackupTime, status.LastRun, now)
+	status.NextRunAt = &nextRunAt
+
+	if lastRun := status.LastRun; lastRun != nil {
+		switch lastRun.Status {
+		case BackupRunStatusFailed:
+			if status.ConsecutiveFailures > 1 {
+				status.Problems = append(status.Problems, fmt.Sprintf("the last %d backup runs failed, the latest at %s: %s",
+					status.ConsecutiveFailures, lastRun.StartedAt.Local().Format("2006-01-02 15:04"), lastRun.Error))
+			} else {
+				status.Problems = append(status.Problems, fmt.Sprintf("the backup run at %s failed: %s",
+					lastRun.StartedAt.Local().Format("2006-01-02 15:04"), lastRun.Error))
+			}
+		case BackupRunStatusWarning:
+			status.Problems = append(status.Problems, fmt.Sprintf("the backup run at %s had problems: %s",
+				lastRun.StartedAt.Local().Format("2006-01-02 15:04"), lastRun.Error))
+		}
+	}
+
+	isRunning := status.LastRun != nil && status.LastRun.Status == BackupRunStatusRunning
+	if !isRunning && now.Sub(nextRunAt) > overdueGracePeriod {
+		status.Problems = append(status.Problems, fmt.Sprintf("a backup is overdue since %s; is the web UI running?",
+			nextRunAt.Local().Format("2006-01-02 15:04")))
+	}
+
+	return status, nil
+}
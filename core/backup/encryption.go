@@ -0,0 +1,230 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"golang.org/x/crypto/argon2"
+)
+
+// Encrypted backups use the chunked format of the encryption package. The key is derived
+// from the configured passphrase with Argon2id, or from the configured key file with HKDF.
+//
+// Header layout (big endian):
+//
+//	magic "FFBKENC1" (8) | version (1) | key source (1) | salt (16) |
+//	argon2 time (4) | argon2 memory in KiB (4) | argon2 threads (1) |
+//	nonce prefix (7) | chunk size (4)
+const (
+	encryptedBackupExtension = ".enc"
+	encryptedBackupMagic     = "FFBKENC1"
+	encryptedBackupVersion   = 1
+	encryptedBackupHeaderLen = 46
+
+	backupSaltLen = 16
+
+	// Argon2id parameters for new backups protected with a passphrase
+	backupArgon2Time    = 3
+	backupArgon2Memory  = 64 * 1024
+	backupArgon2Threads = 4
+
+	// minKeyFileLength is the minimum number of bytes a key file must contain
+	minKeyFileLength = 32
+
+	backupKeyFileDomain = "frozenfortress backup key file v1"
+)
+
+// keySource identifies the kind of secret the key of an encrypted backup is derived from
+type keySource byte
+
+const (
+	keySourcePassphrase keySource = 1
+	keySourceKeyFile    keySource = 2
+)
+
+// backupSecret is the admin-supplied secret protecting encrypted backups.
+// Exactly one of passphrase and keyFile is set.
+type backupSecret struct {
+	passphrase string
+	keyFile    []byte
+}
+
+// loadBackupSecret returns the secret configured by a passphrase or the path of a key file.
+// It returns nil if neither is set.
+func loadBackupSecret(passphrase, keyFilePath string) (*backupSecret, error) {
+	switch {
+	case passphrase != "" && keyFilePath != "":
+		return nil, fmt.Errorf("configure either a backup passphrase or a backup key file, not both")
+	case passphrase != "":
+		return &backupSecret{passphrase: passphrase}, nil
+	case keyFilePath != "":
+		keyFile, err := os.ReadFile(keyFilePath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup key file: %w", err)
+		}
+		if len(keyFile) < minKeyFileLength {
+			return nil, fmt.Errorf("backup key file must contain at least %d bytes", minKeyFileLength)
+		}
+		return &backupSecret{keyFile: keyFile}, nil
+	default:
+		return nil, nil
+	}
+}
+
+// backupHeader holds the parameters stored in the header of an encrypted backup
+type backupHeader struct {
+	source        keySource
+	salt          []byte
+	argon2Time    uint32
+	argon2Memory  uint32
+	argon2Threads uint8
+	chunks        encryption.ChunkedStream
+}
+
+func (h *backupHeader) marshal() []byte {
+	data := make([]byte, 0, encryptedBackupHeaderLen)
+	data = append(data, encryptedBackupMagic...)
+	data = append(data, encryptedBackupVersion, byte(h.source))
+	data = append(data, h.salt...)
+	data = binary.BigEndian.AppendUint32(data, h.argon2Time)
+	data = binary.BigEndian.AppendUint32(data, h.argon2Memory)
+	data = append(data, h.argon2Threads)
+	data = append(data, h.chunks.NoncePrefix...)
+	data = binary.BigEndian.AppendUint32(data, h.chunks.ChunkSize)
+	return data
+}
+
+// readBackupHeader reads and validates the header of an encrypted backup
+func readBackupHeader(src io.Reader) (*backupHeader, error) {
+	data := make([]byte, encryptedBackupHeaderLen)
+	if _, err := io.ReadFull(src, data); err != nil {
+		return nil, fmt.Errorf("failed to read backup header: %w", err)
+	}
+	if !bytes.Equal(data[:8], []byte(encryptedBackupMagic)) {
+		return nil, fmt.Errorf("file is not an encrypted backup")
+	}
+	if data[8] != encryptedBackupVersion {
+		return nil, fmt.Errorf("unsupported encrypted backup version: %d", data[8])
+	}
+
+	header := &backupHeader{
+		source:        keySource(data[9]),
+		salt:          data[10:26],
+		argon2Time:    binary.BigEndian.Uint32(data[26:30]),
+		argon2Memory:  binary.BigEndian.Uint32(data[30:34]),
+		argon2Threads: data[34],
+		chunks: encryption.ChunkedStream{
+			Header:      data,
+			NoncePrefix: data[35:42],
+			ChunkSize:   binary.BigEndian.Uint32(data[42:46]),
+		},
+	}
+
+	// The header is authenticated with the first chunk, but bounds keep a forged header
+	// from making us spend minutes deriving a key
+	if header.source != keySourcePassphrase && header.source != keySourceKeyFile {
+		return nil, fmt.Errorf("unknown key source in backup header: %d", header.source)
+	}
+	if header.source == keySourcePassphrase && (header.argon2Time == 0 || header.argon2Time > 16 ||
+		header.argon2Memory < 8*1024 || header.argon2Memory > 1024*1024 || header.argon2Threads == 0) {
+		return nil, fmt.Errorf("invalid key derivation parameters in backup header")
+	}
+
+	return header, nil
+}
+
+// deriveKey derives the AES-256 key of a backup from the secret and the header parameters
+func (h *backupHeader) deriveKey(secret *backupSecret) ([]byte, error) {
+	switch h.source {
+	case keySourcePassphrase:
+		if secret == nil || secret.passphrase == "" {
+			return nil, fmt.Errorf("backup is encrypted with a passphrase, but no backup passphrase is configured")
+		}
+		return argon2.IDKey([]byte(secret.passphrase), h.salt, h.argon2Time, h.argon2Memory, h.argon2Threads, 32), nil
+	case keySourceKeyFile:
+		if secret == nil || secret.keyFile == nil {
+			return nil, fmt.Errorf("backup is encrypted with a key file, but no backup key file is configured")
+		}
+		return hkdf.Key(sha256.New, secret.keyFile, h.salt, backupKeyFileDomain, 32)
+	default:
+		return nil, fmt.Errorf("unknown key source: %d", h.source)
+	}
+}
+
+// encryptBackup writes src to dst in the encrypted backup format
+func encryptBackup(dst io.Writer, src io.Reader, secret *backupSecret) error {
+	header := &backupHeader{salt: make([]byte, backupSaltLen)}
+	if secret.passphrase != "" {
+		header.source = keySourcePassphrase
+		header.argon2Time = backupArgon2Time
+		header.argon2Memory = backupArgon2Memory
+		header.argon2Threads = backupArgon2Threads
+	} else {
+		header.source = keySourceKeyFile
+	}
+	if _, err := rand.Read(header.salt); err != nil {
+		return fmt.Errorf("failed to generate salt: %w", err)
+	}
+	noncePrefix, err := encryption.NewChunkNoncePrefix()
+	if err != nil {
+		return err
+	}
+	header.chunks = encryption.ChunkedStream{NoncePrefix: noncePrefix, ChunkSize: encryption.DefaultChunkSize}
+	header.chunks.Header = header.marshal()
+
+	key, err := header.deriveKey(secret)
+	if err != nil {
+		return err
+	}
+	aead, err := encryption.NewChunkAead(key)
+	if err != nil {
+		return err
+	}
+
+	writer, err := encryption.NewChunkWriter(dst, aead, header.chunks)
+	if err != nil {
+		return fmt.Errorf("failed to write backup header: %w", err)
+	}
+	if _, err := io.Copy(writer, src); err != nil {
+		return fmt.Errorf("failed to encrypt backup: %w", err)
+	}
+	return writer.Close()
+}
+
+// decryptBackup verifies and decrypts an encrypted backup from src to dst.
+// Data written to dst before an error is returned must be discarded.
+func decryptBackup(dst io.Writer, src io.Reader, secret *backupSecret) error {
+	header, err := readBackupHeader(src)
+	if err != nil {
+		return err
+	}
+
+	key, err := header.deriveKey(secret)
+	if err != nil {
+		return err
+	}
+	aead, err := encryption.NewChunkAead(key)
+	if err != nil {
+		return err
+	}
+	reader, err := encryption.NewChunkReader(src, aead, header.chunks)
+	if err != nil {
+		return fmt.Errorf("invalid backup header: %w", err)
+	}
+
+	if _, err := io.Copy(dst, reader); err != nil {
+		if errors.Is(err, encryption.ErrStreamDecryptionFailed) || errors.Is(err, encryption.ErrStreamTruncated) {
+			return fmt.Errorf("failed to decrypt backup: wrong passphrase or key file, or the backup is damaged")
+		}
+		return fmt.Errorf("failed to decrypt backup: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package backup
+
+import (
+	"bytes"
+	"crypto/rand"
+	"testing"
+)
+
+func TestEncryptDecryptBackup(t *testing.T) {
+	keyFileSecret := &backupSecret{keyFile: bytes.Repeat([]byte{0x42}, minKeyFileLength)}
+	plain := make([]byte, 100*1024)
+	rand.Read(plain)
+
+	for _, secret := range []*backupSecret{keyFileSecret, {passphrase: "correct horse battery"}} {
+		var encrypted bytes.Buffer
+		if err := encryptBackup(&encrypted, bytes.NewReader(plain), secret); err != nil {
+			t.Fatalf("encrypt failed: %v", err)
+		}
+
+		var decrypted bytes.Buffer
+		if err := decryptBackup(&decrypted, bytes.NewReader(encrypted.Bytes()), secret); err != nil {
+			t.Fatalf("decrypt failed: %v", err)
+		}
+		if !bytes.Equal(decrypted.Bytes(), plain) {
+			t.Fatalf("decrypted data differs")
+		}
+	}
+
+	var encrypted bytes.Buffer
+	if err := encryptBackup(&encrypted, bytes.NewReader(plain), keyFileSecret); err != nil {
+		t.Fatalf("encrypt failed: %v", err)
+	}
+	otherSecret := &backupSecret{keyFile: bytes.Repeat([]byte{0x43}, minKeyFileLength)}
+	if err := decryptBackup(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), otherSecret); err == nil {
+		t.Fatalf("expected a wrong key file to fail")
+	}
+	if err := decryptBackup(&bytes.Buffer{}, bytes.NewReader(encrypted.Bytes()), &backupSecret{passphrase: "x"}); err == nil {
+		t.Fatalf("expected a passphrase to be rejected for a backup encrypted with a key file")
+	}
+}
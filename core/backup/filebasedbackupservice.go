@@ -1,8 +1,10 @@
 package backup
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -12,24 +14,43 @@ import (
 
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
+// backupFilePattern matches backup filenames: ff_backup_YYYYMMDD_HHMMSS_{trigger}.db[.enc]
+var backupFilePattern = regexp.MustCompile(`^ff_backup_(\d{8})_(\d{6})_(manual|auto)\.db(\.enc)?$`)
+
+// targetUploadTimeout limits how long uploading a backup to a single target may take
+const targetUploadTimeout = time.Hour
+
 // FileBasedBackupService implements BackupService using file system operations
 type FileBasedBackupService struct {
-	config ccc.AppConfig
-	logger ccc.Logger
+	config         ccc.AppConfig
+	blobReferences blobstore.BlobReferenceSourceFactory
+	logger         ccc.Logger
+	targets        []*configuredTarget
+	targetsErr     error // why the configured targets could not be parsed
 }
 
-// NewFileBasedBackupService creates a new file-based backup service
-func NewFileBasedBackupService(config ccc.AppConfig, logger ccc.Logger) *FileBasedBackupService {
+// NewFileBasedBackupService creates a new file-based backup service. blobReferences reads the blobs
+// referenced by a backup, which are recorded in its manifest; if it is nil, they are not recorded.
+func NewFileBasedBackupService(config ccc.AppConfig, blobReferences blobstore.BlobReferenceSourceFactory, logger ccc.Logger) *FileBasedBackupService {
 	if logger == nil {
 		logger = ccc.NopLogger
 	}
 
+	targets, err := parseBackupTargets(config.Backup.Targets, config.Backup.MaxGenerations)
+	if err != nil {
+		logger.Error("Invalid backup target configuration", "error", err)
+	}
+
 	return &FileBasedBackupService{
-		config: config,
-		logger: logger,
+		config:         config,
+		blobReferences: blobReferences,
+		logger:         logger,
+		targets:        targets,
+		targetsErr:     err,
 	}
 }
 
@@ -55,16 +76,52 @@ func (s *FileBasedBackupService) CreateBackup(trigger BackupTrigger) (*BackupInf
 		return nil, fmt.Errorf("failed to ensure backup directory: %w", err)
 	}
 
+	secret, err := loadBackupSecret(s.config.Backup.Passphrase, s.config.Backup.KeyFile)
+	if err != nil {
+		s.logger.Error("Failed to load backup encryption secret", "error", err)
+		return nil, err
+	}
+
 	// Generate backup filename
-	filename := s.generateBackupFilename(trigger)
+	filename := s.generateBackupFilename(trigger, secret != nil)
 	backupPath := filepath.Join(s.config.Backup.Directory, filename)
 
-	// Create the backup
-	if err := s.copyDatabase(s.config.DatabasePath, backupPath); err != nil {
+	// Create the backup. Encrypted backups are copied to a temporary file first,
+	// which is inspected for the manifest and then encrypted.
+	plainPath := backupPath
+	if secret != nil {
+		plainPath = backupPath + ".tmp"
+		os.Remove(plainPath) // VACUUM INTO fails if the target exists
+		defer os.Remove(plainPath)
+	}
+	if err := s.copyDatabase(s.config.DatabasePath, plainPath); err != nil {
 		s.logger.Error("Failed to copy database for backup", "source", s.config.DatabasePath, "destination", backupPath, "error", err)
 		return nil, fmt.Errorf("failed to copy database: %w", err)
 	}
 
+	schemaVersion, rowCounts, err := inspectDatabase(plainPath)
+	if err != nil {
+		s.logger.Error("Failed to inspect backup", "path", plainPath, "error", err)
+		return nil, fmt.Errorf("failed to inspect backup: %w", err)
+	}
+
+	// Blobs are not part of the backup, so the garbage collection has to keep the ones it references.
+	// Without recorded references, the garbage collection reads them from the backup itself.
+	var blobRefs []string
+	if s.blobReferences != nil {
+		blobRefs, err = s.readBlobRefs(context.Background(), plainPath)
+		if err != nil {
+			s.logger.Warn("Failed to record blob references of backup", "path", plainPath, "error", err)
+		}
+	}
+
+	if secret != nil {
+		if err := encryptFile(plainPath, backupPath, secret); err != nil {
+			s.logger.Error("Failed to encrypt backup", "destination", backupPath, "error", err)
+			return nil, fmt.Errorf("failed to encrypt backup: %w", err)
+		}
+	}
+
 	// Get file info
 	fileInfo, err := os.Stat(backupPath)
 	if err != nil {
@@ -72,18 +129,44 @@ func (s *FileBasedBackupService) CreateBackup(trigger BackupTrigger) (*BackupInf
 		return nil, fmt.Errorf("failed to get backup file info: %w", err)
 	}
 
+	checksum, err := hashFile(backupPath)
+	if err != nil {
+		s.logger.Error("Failed to hash backup", "path", backupPath, "error", err)
+		return nil, err
+	}
+
+	manifest := &BackupManifest{
+		Filename:      filename,
+		Sha256:        checksum,
+		SizeBytes:     fileInfo.Size(),
+		CreatedAt:     fileInfo.ModTime(),
+		Trigger:       trigger,
+		Encrypted:     secret != nil,
+		AppVersion:    ccc.AppVersion,
+		SchemaVersion: schemaVersion,
+		RowCounts:     rowCounts,
+		BlobRefs:      blobRefs,
+	}
+	if err := writeManifest(backupPath, manifest); err != nil {
+		s.logger.Error("Failed to write backup manifest", "path", backupPath, "error", err)
+		return nil, err
+	}
+
 	backupInfo := &BackupInfo{
 		Filename:  filename,
 		FilePath:  backupPath,
 		CreatedAt: fileInfo.ModTime(),
 		Trigger:   trigger,
 		SizeBytes: fileInfo.Size(),
+		Encrypted: secret != nil,
+		Manifest:  manifest,
 	}
 
 	s.logger.Info("Backup created successfully",
 		"filename", filename,
 		"size_bytes", backupInfo.SizeBytes,
-		"trigger", trigger.String())
+		"trigger", trigger.String(),
+		"encrypted", backupInfo.Encrypted)
 
 	return backupInfo, nil
 }
@@ -129,6 +212,11 @@ func (s *FileBasedBackupService) ListBackups() ([]*BackupInfo, error) {
 		backupInfo.CreatedAt = fileInfo.ModTime()
 		backupInfo.SizeBytes = fileInfo.Size()
 
+		backupInfo.Manifest, err = readManifest(fullPath)
+		if err != nil {
+			s.logger.Warn("Failed to read backup manifest", "filename", entry.Name(), "error", err)
+		}
+
 		backups = append(backups, backupInfo)
 	}
 
@@ -170,45 +258,61 @@ func (s *FileBasedBackupService) DeleteBackup(filename string) error {
 		s.logger.Error("Failed to delete backup file", "filename", filename, "error", err)
 		return fmt.Errorf("failed to delete backup file: %w", err)
 	}
+	if err := os.Remove(manifestPath(fullPath)); err != nil && !os.IsNotExist(err) {
+		s.logger.Warn("Failed to delete backup manifest", "filename", filename, "error", err)
+	}
 
 	s.logger.Info("Backup deleted successfully", "filename", filename)
 	return nil
 }
 
-// CleanupOldBackups removes old backup files according to MaxGenerations config
-func (s *FileBasedBackupService) CleanupOldBackups() error {
-	s.logger.Debug("Starting backup cleanup", "max_generations", s.config.Backup.MaxGenerations)
-
-	if s.config.Backup.MaxGenerations <= 0 {
-		s.logger.Debug("Backup cleanup skipped: MaxGenerations is 0 or negative")
-		return nil
-	}
+// CleanupOldBackups removes the backups that no retention rule keeps
+func (s *FileBasedBackupService) CleanupOldBackups(options CleanupOptions) ([]*RetentionDecision, error) {
+	s.logger.Debug("Starting backup cleanup",
+		"max_generations", s.config.Backup.MaxGenerations,
+		"keep_daily", s.config.Backup.KeepDaily,
+		"keep_weekly", s.config.Backup.KeepWeekly,
+		"keep_monthly", s.config.Backup.KeepMonthly,
+		"keep_yearly", s.config.Backup.KeepYearly,
+		"dry_run", options.DryRun)
 
 	backups, err := s.ListBackups()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	if len(backups) <= s.config.Backup.MaxGenerations {
-		s.logger.Debug("No cleanup needed", "current_count", len(backups), "max_generations", s.config.Backup.MaxGenerations)
-		return nil
-	}
+	decisions := planRetention(backups, s.config.Backup, s.config.Backup.PruneManual || options.IncludeManual)
 
-	// Calculate how many to delete
-	toDelete := len(backups) - s.config.Backup.MaxGenerations
-	backupsToDelete := backups[s.config.Backup.MaxGenerations:] // Skip the newest ones
+	var toDelete []*RetentionDecision
+	for _, decision := range decisions {
+		if !decision.Keep {
+			toDelete = append(toDelete, decision)
+		}
+	}
+	if len(toDelete) == 0 {
+		s.logger.Debug("No cleanup needed", "current_count", len(backups))
+		return decisions, nil
+	}
+	if options.DryRun {
+		s.logger.Debug("Backup cleanup dry run", "would_delete", len(toDelete))
+		return decisions, nil
+	}
 
-	s.logger.Info("Cleaning up old backups", "to_delete", toDelete, "keeping", s.config.Backup.MaxGenerations)
+	s.logger.Info("Cleaning up old backups", "to_delete", len(toDelete), "keeping", len(decisions)-len(toDelete))
 
-	for _, backup := range backupsToDelete {
-		if err := s.DeleteBackup(backup.Filename); err != nil {
-			s.logger.Error("Failed to delete backup during cleanup", "filename", backup.Filename, "error", err)
+	deleted := 0
+	for _, decision := range toDelete {
+		if err := s.DeleteBackup(decision.Backup.Filename); err != nil {
+			s.logger.Error("Failed to delete backup during cleanup", "filename", decision.Backup.Filename, "error", err)
 			// Continue with other deletions even if one fails
+			decision.Err = err
+			continue
 		}
+		deleted++
 	}
 
-	s.logger.Info("Backup cleanup completed", "deleted", toDelete)
-	return nil
+	s.logger.Info("Backup cleanup completed", "deleted", deleted)
+	return decisions, nil
 }
 
 // GetLastBackupTime returns the creation time of the most recent backup
@@ -225,15 +329,16 @@ func (s *FileBasedBackupService) GetLastBackupTime() (time.Time, error) {
 	return backups[0].CreatedAt, nil
 }
 
-// NeedsBackup checks if a backup should be created based on config and last backup time
+// NeedsBackup checks if a backup should be created based on the schedule and last backup time
 func (s *FileBasedBackupService) NeedsBackup() (bool, error) {
 	// Check if backups are enabled
 	if !s.config.Backup.Enabled {
 		return false, nil
 	}
 
-	// Check if interval is configured
-	if s.config.Backup.IntervalDays <= 0 {
+	// Check if a schedule is configured
+	schedule, err := NewBackupSchedule(s.config.Backup)
+	if err != nil {
 		return false, nil
 	}
 
@@ -248,15 +353,15 @@ func (s *FileBasedBackupService) NeedsBackup() (bool, error) {
 		return true, nil
 	}
 
-	// Check if enough time has passed
-	timeSinceLastBackup := time.Since(lastBackupTime)
-	requiredInterval := time.Duration(s.config.Backup.IntervalDays) * 24 * time.Hour
+	// Check if the next scheduled backup is due
+	now := time.Now()
+	nextBackupTime := schedule.Next(lastBackupTime)
 
-	needed := timeSinceLastBackup >= requiredInterval
+	needed := !nextBackupTime.After(now)
 	s.logger.Debug("Checked backup necessity",
 		"last_backup", lastBackupTime,
-		"time_since", timeSinceLastBackup,
-		"required_interval", requiredInterval,
+		"next_backup", nextBackupTime,
+		"schedule", schedule.String(),
 		"needed", needed)
 
 	return needed, nil
@@ -271,21 +376,21 @@ func (s *FileBasedBackupService) ensureBackupDirectory() error {
 }
 
 // generateBackupFilename creates a backup filename with timestamp and trigger
-// Format: ff_backup_YYYYMMDD_HHMMSS_{trigger}.db
-func (s *FileBasedBackupService) generateBackupFilename(trigger BackupTrigger) string {
+// Format: ff_backup_YYYYMMDD_HHMMSS_{trigger}.db, with an additional .enc suffix for encrypted backups
+func (s *FileBasedBackupService) generateBackupFilename(trigger BackupTrigger, encrypted bool) string {
 	now := time.Now()
 	timestamp := now.Format("20060102_150405") // YYYYMMDD_HHMMSS
-	return fmt.Sprintf("ff_backup_%s_%s.db", timestamp, trigger.String())
+	filename := fmt.Sprintf("ff_backup_%s_%s.db", timestamp, trigger.String())
+	if encrypted {
+		filename += encryptedBackupExtension
+	}
+	return filename
 }
 
 // parseBackupFile extracts information from a backup filename
 func (s *FileBasedBackupService) parseBackupFile(filename string) (*BackupInfo, error) {
-	// Pattern: ff_backup_YYYYMMDD_HHMMSS_{trigger}.db
-	pattern := `^ff_backup_(\d{8})_(\d{6})_(manual|auto)\.db$`
-	re := regexp.MustCompile(pattern)
-
-	matches := re.FindStringSubmatch(filename)
-	if len(matches) != 4 {
+	matches := backupFilePattern.FindStringSubmatch(filename)
+	if len(matches) != 5 {
 		return nil, fmt.Errorf("filename does not match backup pattern: %s", filename)
 	}
 
@@ -309,6 +414,7 @@ func (s *FileBasedBackupService) parseBackupFile(filename string) (*BackupInfo,
 		Filename:  filename,
 		CreatedAt: createdAt,
 		Trigger:   trigger,
+		Encrypted: matches[4] != "",
 	}, nil
 }
 
@@ -326,3 +432,422 @@ func (s *FileBasedBackupService) copyDatabase(sourcePath, destPath string) error
 
 	return nil
 }
+
+// encryptFile encrypts the file at plainPath into destPath
+func encryptFile(plainPath, destPath string, secret *backupSecret) error {
+	plain, err := os.Open(plainPath)
+	if err != nil {
+		return fmt.Errorf("failed to open database copy: %w", err)
+	}
+	defer plain.Close()
+
+	return writeFileAtomically(destPath, func(dst io.Writer) error {
+		return encryptBackup(dst, plain, secret)
+	})
+}
+
+// RestoreBackup replaces the database with the content of a backup. The backup is decrypted
+// into a temporary file next to the database and checked with PRAGMA integrity_check before it
+// is renamed over the database, so the database is never left half-written.
+func (s *FileBasedBackupService) RestoreBackup(filename string, options RestoreOptions) (*RestoreInfo, error) {
+	s.logger.Info("Restoring backup", "filename", filename)
+
+	backupInfo, err := s.parseBackupFile(filename)
+	if err != nil {
+		s.logger.Warn("Invalid backup filename for restore", "filename", filename)
+		return nil, fmt.Errorf("invalid backup filename: %s", filename)
+	}
+
+	backupPath := filepath.Join(s.config.Backup.Directory, filename)
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		s.logger.Warn("Backup file not found for restore", "filename", filename)
+		return nil, fmt.Errorf("backup file not found: %s", filename)
+	}
+
+	restoreInfo := &RestoreInfo{
+		Filename:     filename,
+		DatabasePath: s.config.DatabasePath,
+	}
+
+	// A running web UI keeps the old database open and would keep serving or even writing it
+	if pid := runningServerPid(s.config.DatabasePath); pid != 0 {
+		if !options.Force {
+			return nil, fmt.Errorf("the web UI appears to be running (pid %d); stop it before restoring or use --force", pid)
+		}
+		s.logger.Warn("Restoring backup while the web UI appears to be running", "pid", pid)
+		restoreInfo.ServerPid = pid
+	}
+
+	restorePath := s.config.DatabasePath + ".restore.tmp"
+	os.Remove(restorePath)
+	defer os.Remove(restorePath)
+
+	if err := s.extractBackup(backupPath, restorePath, backupInfo.Encrypted, options); err != nil {
+		s.logger.Error("Failed to extract backup", "filename", filename, "error", err)
+		return nil, err
+	}
+
+	if err := checkDatabaseIntegrity(restorePath); err != nil {
+		s.logger.Error("Backup failed the integrity check", "filename", filename, "error", err)
+		return nil, err
+	}
+
+	// Keep a consistent copy of the current database in case the restore was a mistake
+	if _, err := os.Stat(s.config.DatabasePath); err == nil {
+		restoreInfo.PreviousDatabasePath = fmt.Sprintf("%s.pre-restore_%s", s.config.DatabasePath, time.Now().Format("20060102_150405"))
+		if err := s.copyDatabase(s.config.DatabasePath, restoreInfo.PreviousDatabasePath); err != nil {
+			s.logger.Error("Failed to copy the current database before restoring", "error", err)
+			return nil, fmt.Errorf("failed to keep a copy of the current database: %w", err)
+		}
+	}
+
+	if err := os.Rename(restorePath, s.config.DatabasePath); err != nil {
+		s.logger.Error("Failed to replace the database", "error", err)
+		return nil, fmt.Errorf("failed to replace the database: %w", err)
+	}
+
+	// Journal files of the replaced database must not be applied to the restored one
+	for _, suffix := range []string{"-journal", "-wal", "-shm"} {
+		if err := os.Remove(s.config.DatabasePath + suffix); err != nil && !os.IsNotExist(err) {
+			s.logger.Warn("Failed to remove journal file of the replaced database", "suffix", suffix, "error", err)
+		}
+	}
+
+	s.logger.Info("Backup restored successfully",
+		"filename", filename,
+		"database", s.config.DatabasePath,
+		"previous_database", restoreInfo.PreviousDatabasePath)
+
+	return restoreInfo, nil
+}
+
+// VerifyBackup checks a backup against its manifest and records the result in the manifest
+func (s *FileBasedBackupService) VerifyBackup(filename string) (*BackupVerification, error) {
+	s.logger.Info("Verifying backup", "filename", filename)
+
+	backupInfo, err := s.parseBackupFile(filename)
+	if err != nil {
+		s.logger.Warn("Invalid backup filename for verification", "filename", filename)
+		return nil, fmt.Errorf("invalid backup filename: %s", filename)
+	}
+
+	backupPath := filepath.Join(s.config.Backup.Directory, filename)
+	fileInfo, err := os.Stat(backupPath)
+	if os.IsNotExist(err) {
+		s.logger.Warn("Backup file not found for verification", "filename", filename)
+		return nil, fmt.Errorf("backup file not found: %s", filename)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get backup file info: %w", err)
+	}
+
+	verification := &BackupVerification{
+		Filename:   filename,
+		VerifiedAt: time.Now().UTC(),
+	}
+
+	manifest, err := readManifest(backupPath)
+	switch {
+	case err != nil:
+		verification.Problems = append(verification.Problems, err.Error())
+	case manifest == nil:
+		verification.Problems = append(verification.Problems, "manifest is missing")
+	default:
+		if fileInfo.Size() != manifest.SizeBytes {
+			verification.Problems = append(verification.Problems, fmt.Sprintf("file has %d bytes, manifest records %d", fileInfo.Size(), manifest.SizeBytes))
+		}
+		checksum, err := hashFile(backupPath)
+		if err != nil {
+			return nil, err
+		}
+		if checksum != manifest.Sha256 {
+			verification.Problems = append(verification.Problems, "SHA-256 checksum does not match the manifest")
+		}
+	}
+
+	verification.Problems = append(verification.Problems, s.verifyContent(backupPath, backupInfo.Encrypted, manifest)...)
+
+	if manifest != nil {
+		manifest.Verification = verification
+		if err := writeManifest(backupPath, manifest); err != nil {
+			s.logger.Warn("Failed to record verification result in backup manifest", "filename", filename, "error", err)
+		}
+	}
+
+	if verification.Ok() {
+		s.logger.Info("Backup verified successfully", "filename", filename)
+	} else {
+		s.logger.Warn("Backup verification failed", "filename", filename, "problems", strings.Join(verification.Problems, "; "))
+	}
+
+	return verification, nil
+}
+
+// verifyContent runs an integrity check on the database contained in a backup and compares
+// its schema version and row counts with the manifest, if there is one
+func (s *FileBasedBackupService) verifyContent(backupPath string, encrypted bool, manifest *BackupManifest) []string {
+	databasePath := backupPath
+	if encrypted {
+		databasePath = backupPath + ".verify.tmp"
+		os.Remove(databasePath)
+		defer os.Remove(databasePath)
+
+		if err := s.extractBackup(backupPath, databasePath, true, RestoreOptions{}); err != nil {
+			return []string{err.Error()}
+		}
+	}
+
+	if err := checkDatabaseIntegrity(databasePath); err != nil {
+		return []string{err.Error()}
+	}
+	if manifest == nil {
+		return nil
+	}
+
+	schemaVersion, rowCounts, err := inspectDatabase(databasePath)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	return compareManifest(manifest, schemaVersion, rowCounts)
+}
+
+// UploadBackup copies a backup and its manifest to all configured targets and applies their retention
+func (s *FileBasedBackupService) UploadBackup(filename string) ([]*TargetUploadResult, error) {
+	if s.targetsErr != nil {
+		return nil, fmt.Errorf("invalid backup target configuration: %w", s.targetsErr)
+	}
+	if len(s.targets) == 0 {
+		return []*TargetUploadResult{}, nil
+	}
+
+	if _, err := s.parseBackupFile(filename); err != nil {
+		s.logger.Warn("Invalid backup filename for upload", "filename", filename)
+		return nil, fmt.Errorf("invalid backup filename: %s", filename)
+	}
+
+	backupPath := filepath.Join(s.config.Backup.Directory, filename)
+	if _, err := os.Stat(backupPath); os.IsNotExist(err) {
+		s.logger.Warn("Backup file not found for upload", "filename", filename)
+		return nil, fmt.Errorf("backup file not found: %s", filename)
+	}
+
+	results := make([]*TargetUploadResult, 0, len(s.targets))
+	for _, target := range s.targets {
+		result := &TargetUploadResult{Target: target.target.Name()}
+		result.Deleted, result.Err = s.uploadToTarget(target, backupPath)
+
+		if result.Err != nil {
+			s.logger.Error("Failed to upload backup to target", "filename", filename, "target", result.Target, "error", result.Err)
+		} else {
+			s.logger.Info("Backup uploaded to target", "filename", filename, "target", result.Target, "deleted", result.Deleted)
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+// uploadToTarget uploads a backup and then its manifest, so a manifest on a target always belongs
+// to a complete backup, and applies the retention of the target
+func (s *FileBasedBackupService) uploadToTarget(target *configuredTarget, backupPath string) (int, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), targetUploadTimeout)
+	defer cancel()
+
+	for _, path := range []string{backupPath, manifestPath(backupPath)} {
+		if err := uploadFile(ctx, target.target, path); err != nil {
+			if os.IsNotExist(err) && path != backupPath {
+				continue // backups created before manifests existed
+			}
+			return 0, err
+		}
+	}
+
+	deleted, err := applyTargetRetention(ctx, target.target, target.keep)
+	if err != nil {
+		return deleted, fmt.Errorf("retention failed: %w", err)
+	}
+	return deleted, nil
+}
+
+// uploadFile uploads a local file to a target under its base name
+func uploadFile(ctx context.Context, target BackupTarget, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	fileInfo, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	if err := target.Upload(ctx, filepath.Base(path), file, fileInfo.Size()); err != nil {
+		return fmt.Errorf("failed to upload %s: %w", filepath.Base(path), err)
+	}
+	return nil
+}
+
+// FindReferencedBlobs returns the hashes of the blobs referenced by the databases in the local backups.
+// An error stops the garbage collection, so that no blob a backup needs is deleted.
+func (s *FileBasedBackupService) FindReferencedBlobs(ctx context.Context) (map[string]bool, error) {
+	backups, err := s.ListBackups()
+	if err != nil {
+		return nil, err
+	}
+
+	referenced := make(map[string]bool)
+	for _, backupInfo := range backups {
+		blobRefs, err := s.backupBlobRefs(ctx, backupInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read blob references of backup %s: %w", backupInfo.Filename, err)
+		}
+		for _, hash := range blobRefs {
+			referenced[hash] = true
+		}
+	}
+	return referenced, nil
+}
+
+// backupBlobRefs returns the blob references recorded in the manifest of a backup. References of backups
+// without them, e.g. those created before they were recorded, are read from the backup and added to its manifest.
+func (s *FileBasedBackupService) backupBlobRefs(ctx context.Context, backupInfo *BackupInfo) ([]string, error) {
+	if backupInfo.Manifest != nil && backupInfo.Manifest.BlobRefs != nil {
+		return backupInfo.Manifest.BlobRefs, nil
+	}
+
+	databasePath := backupInfo.FilePath
+	if backupInfo.Encrypted {
+		databasePath = backupInfo.FilePath + ".blobs.tmp"
+		os.Remove(databasePath)
+		defer os.Remove(databasePath)
+
+		if err := s.extractBackup(backupInfo.FilePath, databasePath, true, RestoreOptions{}); err != nil {
+			return nil, err
+		}
+	}
+
+	blobRefs, err := s.readBlobRefs(ctx, databasePath)
+	if err != nil {
+		return nil, err
+	}
+
+	if backupInfo.Manifest != nil {
+		backupInfo.Manifest.BlobRefs = blobRefs
+		if err := writeManifest(backupInfo.FilePath, backupInfo.Manifest); err != nil {
+			s.logger.Warn("Failed to record blob references in backup manifest", "filename", backupInfo.Filename, "error", err)
+		}
+	}
+	return blobRefs, nil
+}
+
+// readBlobRefs returns the sorted hashes of the blobs referenced by the database at databasePath
+func (s *FileBasedBackupService) readBlobRefs(ctx context.Context, databasePath string) ([]string, error) {
+	if s.blobReferences == nil {
+		return nil, fmt.Errorf("no source of blob references is configured")
+	}
+
+	db, err := openReadOnly(databasePath)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	hashes, err := s.blobReferences.Create(db).FindReferencedBlobs(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob references: %w", err)
+	}
+
+	blobRefs := make([]string, 0, len(hashes))
+	for hash := range hashes {
+		blobRefs = append(blobRefs, hash)
+	}
+	sort.Strings(blobRefs)
+	return blobRefs, nil
+}
+
+// extractBackup writes the plain database contained in a backup file to destPath
+func (s *FileBasedBackupService) extractBackup(backupPath, destPath string, encrypted bool, options RestoreOptions) error {
+	src, err := os.Open(backupPath)
+	if err != nil {
+		return fmt.Errorf("failed to open backup: %w", err)
+	}
+	defer src.Close()
+
+	if !encrypted {
+		return writeFileAtomically(destPath, func(dst io.Writer) error {
+			_, err := io.Copy(dst, src)
+			return err
+		})
+	}
+
+	passphrase, keyFile := options.Passphrase, options.KeyFile
+	if passphrase == "" && keyFile == "" {
+		passphrase, keyFile = s.config.Backup.Passphrase, s.config.Backup.KeyFile
+	}
+	secret, err := loadBackupSecret(passphrase, keyFile)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(destPath, func(dst io.Writer) error {
+		return decryptBackup(dst, src, secret)
+	})
+}
+
+// checkDatabaseIntegrity runs PRAGMA integrity_check on the database at path
+func checkDatabaseIntegrity(path string) error {
+	db, err := openReadOnly(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("PRAGMA integrity_check")
+	if err != nil {
+		return fmt.Errorf("failed to check integrity of database: %w", err)
+	}
+	defer rows.Close()
+
+	var problems []string
+	for rows.Next() {
+		var result string
+		if err := rows.Scan(&result); err != nil {
+			return fmt.Errorf("failed to read integrity check result: %w", err)
+		}
+		if result != "ok" {
+			problems = append(problems, result)
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to check integrity of database: %w", err)
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("integrity check failed: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// writeFileAtomically writes a file through a temporary file that is renamed into place once write succeeds
+func writeFileAtomically(path string, write func(dst io.Writer) error) error {
+	tmpPath := path + ".partial"
+	file, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Base(tmpPath), err)
+	}
+	defer os.Remove(tmpPath)
+
+	if err := write(file); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to flush %s: %w", filepath.Base(tmpPath), err)
+	}
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("failed to close %s: %w", filepath.Base(tmpPath), err)
+	}
+
+	return os.Rename(tmpPath, path)
+}
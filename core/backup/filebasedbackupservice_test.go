@@ -1,13 +1,17 @@
 package backup
 
 import (
+	"bytes"
+	"context"
 	"database/sql"
 	"os"
 	"path/filepath"
+	"reflect"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
 
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
@@ -45,7 +49,7 @@ func TestVacuumIntoBackup(t *testing.T) {
 		},
 	}
 
-	svc := NewFileBasedBackupService(config, ccc.NopLogger)
+	svc := NewFileBasedBackupService(config, nil, ccc.NopLogger)
 
 	backupInfo, err := svc.CreateBackup(BackupTriggerManual)
 	if err != nil {
@@ -78,3 +82,243 @@ func TestVacuumIntoBackup(t *testing.T) {
 		t.Fatalf("expected 2 rows in backup, got %d", count)
 	}
 }
+
+func TestEncryptedBackupRestore(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO users (id, name) VALUES (1, 'Alice'), (2, 'Bob')"); err != nil {
+		t.Fatalf("failed to create data: %v", err)
+	}
+
+	config := ccc.AppConfig{
+		DatabasePath: dbPath,
+		Backup: ccc.BackupConfig{
+			Enabled:    true,
+			Directory:  filepath.Join(tmpDir, "backups"),
+			Passphrase: "correct horse battery staple",
+		},
+	}
+	svc := NewFileBasedBackupService(config, nil, ccc.NopLogger)
+
+	backupInfo, err := svc.CreateBackup(BackupTriggerManual)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if !backupInfo.Encrypted || filepath.Ext(backupInfo.Filename) != ".enc" {
+		t.Fatalf("expected an encrypted backup, got %s", backupInfo.Filename)
+	}
+
+	content, err := os.ReadFile(backupInfo.FilePath)
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	if bytes.Contains(content, []byte("Alice")) || bytes.Contains(content, []byte("SQLite format")) {
+		t.Fatal("encrypted backup contains plaintext")
+	}
+
+	if _, err := db.Exec("DELETE FROM users"); err != nil {
+		t.Fatalf("failed to delete data: %v", err)
+	}
+
+	if _, err := svc.RestoreBackup(backupInfo.Filename, RestoreOptions{Passphrase: "wrong"}); err == nil {
+		t.Fatal("expected restore with a wrong passphrase to fail")
+	}
+
+	restoreInfo, err := svc.RestoreBackup(backupInfo.Filename, RestoreOptions{})
+	if err != nil {
+		t.Fatalf("RestoreBackup failed: %v", err)
+	}
+	if _, err := os.Stat(restoreInfo.PreviousDatabasePath); err != nil {
+		t.Fatalf("expected a copy of the previous database: %v", err)
+	}
+
+	restoredDB, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open restored database: %v", err)
+	}
+	defer restoredDB.Close()
+
+	var count int
+	if err := restoredDB.QueryRow("SELECT COUNT(*) FROM users").Scan(&count); err != nil {
+		t.Fatalf("failed to query restored database: %v", err)
+	}
+	if count != 2 {
+		t.Fatalf("expected 2 rows after restore, got %d", count)
+	}
+
+	// A modified backup must be rejected without touching the database
+	content[len(content)/2] ^= 0x01
+	if err := os.WriteFile(backupInfo.FilePath, content, 0600); err != nil {
+		t.Fatalf("failed to tamper with backup: %v", err)
+	}
+	if _, err := svc.RestoreBackup(backupInfo.Filename, RestoreOptions{}); err == nil {
+		t.Fatal("expected restore of a tampered backup to fail")
+	}
+}
+
+func TestVerifyBackup(t *testing.T) {
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT); INSERT INTO users (id, name) VALUES (1, 'Alice'), (2, 'Bob')"); err != nil {
+		t.Fatalf("failed to create data: %v", err)
+	}
+
+	config := ccc.AppConfig{
+		DatabasePath: dbPath,
+		Backup: ccc.BackupConfig{
+			Enabled:   true,
+			Directory: filepath.Join(tmpDir, "backups"),
+		},
+	}
+	svc := NewFileBasedBackupService(config, nil, ccc.NopLogger)
+
+	backupInfo, err := svc.CreateBackup(BackupTriggerAuto)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if backupInfo.Manifest == nil || backupInfo.Manifest.RowCounts["users"] != 2 || backupInfo.Manifest.Trigger != BackupTriggerAuto {
+		t.Fatalf("unexpected manifest: %+v", backupInfo.Manifest)
+	}
+
+	verification, err := svc.VerifyBackup(backupInfo.Filename)
+	if err != nil {
+		t.Fatalf("VerifyBackup failed: %v", err)
+	}
+	if !verification.Ok() {
+		t.Fatalf("expected intact backup, got problems: %v", verification.Problems)
+	}
+
+	content, err := os.ReadFile(backupInfo.FilePath)
+	if err != nil {
+		t.Fatalf("failed to read backup: %v", err)
+	}
+	content[len(content)-1] ^= 0x01
+	if err := os.WriteFile(backupInfo.FilePath, content, 0644); err != nil {
+		t.Fatalf("failed to tamper with backup: %v", err)
+	}
+
+	verification, err = svc.VerifyBackup(backupInfo.Filename)
+	if err != nil {
+		t.Fatalf("VerifyBackup failed: %v", err)
+	}
+	if verification.Ok() {
+		t.Fatal("expected tampered backup to fail verification")
+	}
+
+	backups, err := svc.ListBackups()
+	if err != nil {
+		t.Fatalf("ListBackups failed: %v", err)
+	}
+	if len(backups) != 1 || backups[0].VerificationStatus() != "failed" {
+		t.Fatalf("expected one backup with a failed verification, got %d", len(backups))
+	}
+}
+
+// tableBlobReferences reads blob references from the BlobRef table of a database
+type tableBlobReferences struct{}
+
+func (tableBlobReferences) Create(db *sql.DB) blobstore.BlobReferenceSource {
+	return tableBlobReferenceSource{db: db}
+}
+
+type tableBlobReferenceSource struct{ db *sql.DB }
+
+func (s tableBlobReferenceSource) FindReferencedBlobs(ctx context.Context) (map[string]bool, error) {
+	rows, err := s.db.QueryContext(ctx, "SELECT Hash FROM BlobRef")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	referenced := make(map[string]bool)
+	for rows.Next() {
+		var hash string
+		if err := rows.Scan(&hash); err != nil {
+			return nil, err
+		}
+		referenced[hash] = true
+	}
+	return referenced, rows.Err()
+}
+
+func TestBackupsKeepReferencedBlobs(t *testing.T) {
+	ctx := context.Background()
+	tmpDir := t.TempDir()
+	dbPath := filepath.Join(tmpDir, "test.db")
+
+	db, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+
+	if _, err := db.Exec("CREATE TABLE BlobRef (Hash TEXT); INSERT INTO BlobRef (Hash) VALUES ('hash-b'), ('hash-a')"); err != nil {
+		t.Fatalf("failed to create data: %v", err)
+	}
+
+	config := ccc.AppConfig{
+		DatabasePath: dbPath,
+		Backup: ccc.BackupConfig{
+			Enabled:    true,
+			Directory:  filepath.Join(tmpDir, "backups"),
+			Passphrase: "correct horse battery staple",
+		},
+	}
+	svc := NewFileBasedBackupService(config, tableBlobReferences{}, ccc.NopLogger)
+
+	backupInfo, err := svc.CreateBackup(BackupTriggerAuto)
+	if err != nil {
+		t.Fatalf("CreateBackup failed: %v", err)
+	}
+	if want := []string{"hash-a", "hash-b"}; !reflect.DeepEqual(backupInfo.Manifest.BlobRefs, want) {
+		t.Fatalf("expected the manifest to record %v, got %v", want, backupInfo.Manifest.BlobRefs)
+	}
+
+	// The blobs stay referenced by the backup after the database dropped them
+	if _, err := db.Exec("DELETE FROM BlobRef WHERE Hash = 'hash-a'; INSERT INTO BlobRef (Hash) VALUES ('hash-c')"); err != nil {
+		t.Fatalf("failed to change data: %v", err)
+	}
+	want := map[string]bool{"hash-a": true, "hash-b": true}
+	referenced, err := svc.FindReferencedBlobs(ctx)
+	if err != nil || !reflect.DeepEqual(referenced, want) {
+		t.Fatalf("expected backup references %v, got %v: %v", want, referenced, err)
+	}
+
+	// References of backups created before they were recorded are read from the backup and recorded
+	backupInfo.Manifest.BlobRefs = nil
+	if err := writeManifest(backupInfo.FilePath, backupInfo.Manifest); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	referenced, err = svc.FindReferencedBlobs(ctx)
+	if err != nil || !reflect.DeepEqual(referenced, want) {
+		t.Fatalf("expected references read from the backup %v, got %v: %v", want, referenced, err)
+	}
+	manifest, err := readManifest(backupInfo.FilePath)
+	if err != nil || len(manifest.BlobRefs) != 2 {
+		t.Fatalf("expected the references to be recorded in the manifest, got %+v: %v", manifest, err)
+	}
+
+	// Without readable references, the garbage collection must not go ahead
+	manifest.BlobRefs = nil
+	if err := writeManifest(backupInfo.FilePath, manifest); err != nil {
+		t.Fatalf("failed to write manifest: %v", err)
+	}
+	config.Backup.Passphrase = "another passphrase"
+	if _, err := NewFileBasedBackupService(config, tableBlobReferences{}, ccc.NopLogger).FindReferencedBlobs(ctx); err == nil {
+		t.Fatal("expected unreadable backup references to fail")
+	}
+}
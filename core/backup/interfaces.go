@@ -1,6 +1,8 @@
 package backup
 
 import (
+	"context"
+	"io"
 	"time"
 )
 
@@ -15,13 +17,81 @@ type BackupService interface {
 	// DeleteBackup removes a backup file by filename
 	DeleteBackup(filename string) error
 
-	// CleanupOldBackups removes old backup files according to MaxGenerations config
-	CleanupOldBackups() error
+	// CleanupOldBackups removes the backups that no retention rule keeps. The daily, weekly, monthly
+	// and yearly rules are computed from the creation time; manual backups are kept unless pruning
+	// them is configured or requested. It returns the decision for every backup, newest first.
+	CleanupOldBackups(options CleanupOptions) ([]*RetentionDecision, error)
 
 	// GetLastBackupTime returns the creation time of the most recent backup
 	// Returns zero time if no backups exist
 	GetLastBackupTime() (time.Time, error)
 
-	// NeedsBackup checks if a backup should be created based on config and last backup time
+	// NeedsBackup checks if a backup should be created based on the schedule and last backup time
 	NeedsBackup() (bool, error)
+
+	// RestoreBackup replaces the database with the content of a backup after decrypting it
+	// and checking its integrity. The current database is kept as a copy next to it.
+	RestoreBackup(filename string, options RestoreOptions) (*RestoreInfo, error)
+
+	// VerifyBackup checks a backup against its manifest: it re-hashes the file, runs an integrity
+	// check on a read-only copy and compares the row counts. The result is recorded in the manifest.
+	// Problems with the backup are reported in the result, not as an error.
+	VerifyBackup(filename string) (*BackupVerification, error)
+
+	// UploadBackup copies a backup and its manifest to all configured backup targets and applies
+	// the retention of each target. Failures of single targets are reported in the results.
+	UploadBackup(filename string) ([]*TargetUploadResult, error)
+
+	// FindReferencedBlobs returns the hashes of the blobs referenced by the databases in the local backups,
+	// so that the blob garbage collection keeps them. It implements blobstore.BlobReferenceSource.
+	FindReferencedBlobs(ctx context.Context) (map[string]bool, error)
+}
+
+// BackupTarget stores copies of backups outside the local backup directory
+type BackupTarget interface {
+	// Name returns a description of the target without credentials, e.g. s3://minio:9000/backups
+	Name() string
+
+	// Upload stores the content of src as a file with the given name, replacing an existing one
+	Upload(ctx context.Context, name string, src io.Reader, size int64) error
+
+	// List returns the names of all files stored on the target
+	List(ctx context.Context) ([]string, error)
+
+	// Delete removes the file with the given name
+	Delete(ctx context.Context, name string) error
+}
+
+// BackupSchedule determines when automatic backups are due
+type BackupSchedule interface {
+	// Next returns the first time after the given time at which a backup is due
+	Next(after time.Time) time.Time
+
+	// String describes the schedule, e.g. "every 7 days" or "0 3 * * *"
+	String() string
+}
+
+// BackupRunRepository stores the runs of the backup worker
+type BackupRunRepository interface {
+	Add(run *BackupRun) error
+	Update(run *BackupRun) error
+	// FindRecent returns the latest runs, newest first
+	FindRecent(limit int) ([]*BackupRun, error)
+	// FindLatestByStatus returns the latest run with one of the given statuses, nil if there is none
+	FindLatestByStatus(statuses ...BackupRunStatus) (*BackupRun, error)
+	// CountByStatusSince returns the number of runs with the given status started after since
+	CountByStatusSince(status BackupRunStatus, since time.Time) (int, error)
+	// FailUnfinished marks runs that are still running as failed, e.g. after the server was killed
+	FailUnfinished(finishedAt time.Time, reason string) (int64, error)
+}
+
+// BackupRunIdGenerator generates IDs for backup runs
+type BackupRunIdGenerator interface {
+	GenerateId() string
+}
+
+// BackupMonitor reports whether automatic backups work as scheduled
+type BackupMonitor interface {
+	// GetStatus returns the status of automatic backups with the latest historyLimit runs
+	GetStatus(historyLimit int) (*BackupStatus, error)
 }
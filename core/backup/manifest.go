@@ -0,0 +1,155 @@
+package backup
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// manifestExtension is appended to the filename of a backup to get the filename of its manifest
+const manifestExtension = ".manifest.json"
+
+// manifestPath returns the path of the manifest belonging to the backup at backupPath
+func manifestPath(backupPath string) string {
+	return backupPath + manifestExtension
+}
+
+// readManifest reads the manifest of a backup. It returns nil without an error if the backup has no manifest.
+func readManifest(backupPath string) (*BackupManifest, error) {
+	data, err := os.ReadFile(manifestPath(backupPath))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup manifest: %w", err)
+	}
+
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse backup manifest: %w", err)
+	}
+	return &manifest, nil
+}
+
+// writeManifest writes the manifest of a backup
+func writeManifest(backupPath string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode backup manifest: %w", err)
+	}
+
+	return writeFileAtomically(manifestPath(backupPath), func(dst io.Writer) error {
+		_, err := dst.Write(data)
+		return err
+	})
+}
+
+// hashFile returns the hex encoded SHA-256 checksum of a file
+func hashFile(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s for hashing: %w", path, err)
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// openReadOnly opens a SQLite database file without allowing any modification
+func openReadOnly(path string) (*sql.DB, error) {
+	dsn := "file:" + (&url.URL{Path: path}).EscapedPath() + "?mode=ro"
+	db, err := sql.Open("sqlite3", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database %s: %w", path, err)
+	}
+	return db, nil
+}
+
+// inspectDatabase returns the schema version and the number of rows of every table of a database
+func inspectDatabase(path string) (int, map[string]int64, error) {
+	db, err := openReadOnly(path)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer db.Close()
+
+	rows, err := db.Query("SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name")
+	if err != nil {
+		return 0, nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	var tables []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			rows.Close()
+			return 0, nil, fmt.Errorf("failed to read table name: %w", err)
+		}
+		tables = append(tables, name)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, nil, fmt.Errorf("failed to list tables: %w", err)
+	}
+
+	rowCounts := make(map[string]int64, len(tables))
+	schemaVersion := 0
+	for _, table := range tables {
+		var count int64
+		query := fmt.Sprintf(`SELECT COUNT(*) FROM "%s"`, strings.ReplaceAll(table, `"`, `""`))
+		if err := db.QueryRow(query).Scan(&count); err != nil {
+			return 0, nil, fmt.Errorf("failed to count rows of table %s: %w", table, err)
+		}
+		rowCounts[table] = count
+
+		if table == "schema_migrations" {
+			if err := db.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_migrations").Scan(&schemaVersion); err != nil {
+				return 0, nil, fmt.Errorf("failed to read schema version: %w", err)
+			}
+		}
+	}
+
+	return schemaVersion, rowCounts, nil
+}
+
+// compareManifest compares the content of a database with the values recorded in the manifest
+func compareManifest(manifest *BackupManifest, schemaVersion int, rowCounts map[string]int64) []string {
+	var problems []string
+	if schemaVersion != manifest.SchemaVersion {
+		problems = append(problems, fmt.Sprintf("schema version is %d, manifest records %d", schemaVersion, manifest.SchemaVersion))
+	}
+
+	tables := make([]string, 0, len(manifest.RowCounts))
+	for table := range manifest.RowCounts {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		count, exists := rowCounts[table]
+		switch {
+		case !exists:
+			problems = append(problems, fmt.Sprintf("table %s is missing", table))
+		case count != manifest.RowCounts[table]:
+			problems = append(problems, fmt.Sprintf("table %s has %d rows, manifest records %d", table, count, manifest.RowCounts[table]))
+		}
+	}
+	for table := range rowCounts {
+		if _, exists := manifest.RowCounts[table]; !exists {
+			problems = append(problems, fmt.Sprintf("table %s is not recorded in the manifest", table))
+		}
+	}
+
+	return problems
+}
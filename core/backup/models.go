@@ -21,6 +21,125 @@ type BackupInfo struct {
 	CreatedAt time.Time     // When the backup was created
 	Trigger   BackupTrigger // What triggered the backup (manual/auto)
 	SizeBytes int64         // Size of the backup file in bytes
+	Encrypted bool          // Whether the backup is encrypted (".db.enc" files)
+
+	Manifest *BackupManifest // The manifest stored next to the backup, nil if the backup has none
+}
+
+// TargetUploadResult is the result of uploading a backup to one backup target
+type TargetUploadResult struct {
+	Target  string // Name of the target without credentials
+	Err     error  // Why the upload or the retention failed, nil on success
+	Deleted int    // Number of old backups removed from the target by its retention
+}
+
+// BackupManifest describes a backup at the time it was created. It is stored next to the
+// backup as <filename>.manifest.json and used to verify the backup later.
+type BackupManifest struct {
+	Filename      string           `json:"filename"`
+	Sha256        string           `json:"sha256"`    // Hex encoded SHA-256 checksum of the backup file
+	SizeBytes     int64            `json:"sizeBytes"` // Size of the backup file in bytes
+	CreatedAt     time.Time        `json:"createdAt"`
+	Trigger       BackupTrigger    `json:"trigger"`
+	Encrypted     bool             `json:"encrypted"`
+	AppVersion    string           `json:"appVersion"`    // Version of the application that created the backup
+	SchemaVersion int              `json:"schemaVersion"` // Latest applied schema migration
+	RowCounts     map[string]int64 `json:"rowCounts"`     // Number of rows per table
+	BlobRefs      []string         `json:"blobRefs"`      // Hashes of the blobs the database references, nil if they were not recorded
+
+	Verification *BackupVerification `json:"verification,omitempty"` // Result of the latest verification
+}
+
+// BackupVerification is the result of verifying a backup
+type BackupVerification struct {
+	Filename   string    `json:"filename"`
+	VerifiedAt time.Time `json:"verifiedAt"`
+	Problems   []string  `json:"problems,omitempty"` // Empty if the backup is intact
+}
+
+// Ok reports whether the verification found no problems
+func (v *BackupVerification) Ok() bool {
+	return len(v.Problems) == 0
+}
+
+// RestoreOptions controls how a backup is restored
+type RestoreOptions struct {
+	Force      bool   // Restore even if the web UI appears to be running
+	Passphrase string // Passphrase for encrypted backups, overrides the configured one
+	KeyFile    string // Path of the key file for encrypted backups, overrides the configured one
+}
+
+// RestoreInfo describes a completed restore
+type RestoreInfo struct {
+	Filename             string // The restored backup file
+	DatabasePath         string // Path of the replaced database
+	PreviousDatabasePath string // Copy of the database as it was before the restore
+	ServerPid            int    // Process ID of a running web UI that was ignored with Force, 0 if none
+}
+
+// CleanupOptions controls how old backups are cleaned up
+type CleanupOptions struct {
+	DryRun        bool // Only decide which backups to delete, without deleting them
+	IncludeManual bool // Apply the retention rules to manual backups too, overrides the configuration
+}
+
+// RetentionDecision records whether cleanup keeps or deletes a backup, and why
+type RetentionDecision struct {
+	Backup  *BackupInfo
+	Keep    bool
+	Reasons []string // The rules that keep the backup, or why none of them does
+	Err     error    // Why deleting the backup failed, nil otherwise
+}
+
+// BackupRunStatus is the outcome of a run of the backup worker
+type BackupRunStatus string
+
+const (
+	// BackupRunStatusRunning indicates the run has not finished yet
+	BackupRunStatusRunning BackupRunStatus = "running"
+	// BackupRunStatusSucceeded indicates the backup was created, verified, uploaded and cleaned up
+	BackupRunStatusSucceeded BackupRunStatus = "succeeded"
+	// BackupRunStatusWarning indicates an intact backup was created, but uploading it or the cleanup failed
+	BackupRunStatusWarning BackupRunStatus = "warning"
+	// BackupRunStatusFailed indicates no intact backup was created
+	BackupRunStatusFailed BackupRunStatus = "failed"
+)
+
+// BackupRun records a run of the backup worker
+type BackupRun struct {
+	Id         string
+	Trigger    BackupTrigger
+	Status     BackupRunStatus
+	StartedAt  time.Time
+	FinishedAt *time.Time // nil while the run is in progress
+	Filename   string     // The created backup, empty if creating it failed
+	SizeBytes  int64
+	Error      string // What went wrong, empty if the run succeeded
+}
+
+// Duration returns how long the run took, or zero if it has not finished
+func (r *BackupRun) Duration() time.Duration {
+	if r.FinishedAt == nil {
+		return 0
+	}
+	return r.FinishedAt.Sub(r.StartedAt)
+}
+
+// BackupStatus summarizes the health of automatic backups
+type BackupStatus struct {
+	Enabled             bool
+	Schedule            string       // Description of the schedule, empty if none is configured
+	NextRunAt           *time.Time   // When the next automatic backup is due, nil if automatic backups are off
+	LastRun             *BackupRun   // The latest run, nil if there was none
+	LastSuccess         *BackupRun   // The latest run that created an intact backup, nil if there was none
+	ConsecutiveFailures int          // Number of failed runs since the last one that created an intact backup
+	Problems            []string     // Why the backups are unhealthy, empty if they are healthy
+	Runs                []*BackupRun // The latest runs, newest first
+}
+
+// Healthy reports whether no problems were found
+func (s *BackupStatus) Healthy() bool {
+	return len(s.Problems) == 0
 }
 
 // String returns the string representation of BackupTrigger
@@ -32,3 +151,17 @@ func (bt BackupTrigger) String() string {
 func (bt BackupTrigger) IsValid() bool {
 	return bt == BackupTriggerManual || bt == BackupTriggerAuto
 }
+
+// VerificationStatus summarizes the latest verification of a backup: ok, failed, unverified or no manifest
+func (b *BackupInfo) VerificationStatus() string {
+	switch {
+	case b.Manifest == nil:
+		return "no manifest"
+	case b.Manifest.Verification == nil:
+		return "unverified"
+	case b.Manifest.Verification.Ok():
+		return "ok"
+	default:
+		return "failed"
+	}
+}
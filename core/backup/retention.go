@@ -0,0 +1,119 @@
+package backup
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// retentionRule keeps the newest backup of each of the last Keep periods that contain backups
+type retentionRule struct {
+	Name   string // Name used in the reasons, e.g. "daily"
+	Unit   string // Plural of the period, e.g. "days"
+	Keep   int
+	Period func(t time.Time) string // Returns the period a backup belongs to, e.g. "2025-06-10"
+}
+
+// retentionRules returns the grandfather-father-son rules of the configuration
+func retentionRules(config ccc.BackupConfig) []retentionRule {
+	return []retentionRule{
+		{Name: "daily", Unit: "days", Keep: config.KeepDaily, Period: func(t time.Time) string {
+			return t.Format("2006-01-02")
+		}},
+		{Name: "weekly", Unit: "weeks", Keep: config.KeepWeekly, Period: func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{Name: "monthly", Unit: "months", Keep: config.KeepMonthly, Period: func(t time.Time) string {
+			return t.Format("2006-01")
+		}},
+		{Name: "yearly", Unit: "years", Keep: config.KeepYearly, Period: func(t time.Time) string {
+			return t.Format("2006")
+		}},
+	}
+}
+
+// retentionEnabled reports whether any retention rule is configured
+func retentionEnabled(config ccc.BackupConfig) bool {
+	if config.MaxGenerations > 0 {
+		return true
+	}
+	for _, rule := range retentionRules(config) {
+		if rule.Keep > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// planRetention decides which backups to keep. A backup is kept if it is among the newest
+// MaxGenerations backups or if any of the daily, weekly, monthly or yearly rules selects it.
+// Manual backups are always kept unless pruneManual is set; they are not counted by the rules.
+// The backups must be sorted newest first, as returned by ListBackups.
+func planRetention(backups []*BackupInfo, config ccc.BackupConfig, pruneManual bool) []*RetentionDecision {
+	decisions := make([]*RetentionDecision, len(backups))
+	for i, backup := range backups {
+		decisions[i] = &RetentionDecision{Backup: backup, Keep: true}
+	}
+
+	if !retentionEnabled(config) {
+		for _, decision := range decisions {
+			decision.Reasons = []string{"no retention rules configured"}
+		}
+		return decisions
+	}
+
+	var candidates []*RetentionDecision
+	for _, decision := range decisions {
+		if decision.Backup.Trigger == BackupTriggerManual && !pruneManual {
+			decision.Reasons = []string{"manual backup"}
+			continue
+		}
+		decision.Keep = false
+		candidates = append(candidates, decision)
+	}
+
+	// rejections collects why each rule passed over a backup, reported if no rule keeps it
+	rejections := make(map[*RetentionDecision][]string)
+	if config.MaxGenerations > 0 {
+		for i, decision := range candidates {
+			if i < config.MaxGenerations {
+				decision.Keep = true
+				decision.Reasons = append(decision.Reasons, fmt.Sprintf("one of the newest %d", config.MaxGenerations))
+			} else {
+				rejections[decision] = append(rejections[decision], fmt.Sprintf("not among the newest %d", config.MaxGenerations))
+			}
+		}
+	}
+
+	for _, rule := range retentionRules(config) {
+		if rule.Keep <= 0 {
+			continue
+		}
+
+		kept := 0
+		lastPeriod := ""
+		for _, decision := range candidates {
+			period := rule.Period(decision.Backup.CreatedAt.Local())
+			switch {
+			case period == lastPeriod:
+				rejections[decision] = append(rejections[decision], fmt.Sprintf("%s %s has a newer backup", rule.Name, period))
+			case kept >= rule.Keep:
+				rejections[decision] = append(rejections[decision], fmt.Sprintf("older than the last %d %s with backups", rule.Keep, rule.Unit))
+			default:
+				kept++
+				decision.Keep = true
+				decision.Reasons = append(decision.Reasons, fmt.Sprintf("%s %s", rule.Name, period))
+			}
+			lastPeriod = period
+		}
+	}
+
+	for _, decision := range candidates {
+		if !decision.Keep {
+			decision.Reasons = rejections[decision]
+		}
+	}
+	return decisions
+}
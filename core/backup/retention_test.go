@@ -0,0 +1,88 @@
+package backup
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+func TestPlanRetention(t *testing.T) {
+	// One automatic backup per day for 400 days and a manual backup 100 days ago, newest first
+	now := time.Date(2025, 6, 10, 3, 0, 0, 0, time.Local)
+	var backups []*BackupInfo
+	for day := 0; day < 400; day++ {
+		createdAt := now.AddDate(0, 0, -day)
+		backups = append(backups, &BackupInfo{
+			Filename:  fmt.Sprintf("ff_backup_%s_auto.db", createdAt.Format("20060102_150405")),
+			CreatedAt: createdAt,
+			Trigger:   BackupTriggerAuto,
+		})
+		if day == 100 {
+			backups = append(backups, &BackupInfo{
+				Filename:  fmt.Sprintf("ff_backup_%s_manual.db", createdAt.Add(-time.Hour).Format("20060102_150405")),
+				CreatedAt: createdAt.Add(-time.Hour),
+				Trigger:   BackupTriggerManual,
+			})
+		}
+	}
+
+	config := ccc.BackupConfig{KeepDaily: 7, KeepWeekly: 4, KeepMonthly: 12, KeepYearly: 2}
+
+	kept := func(decisions []*RetentionDecision) map[string][]string {
+		result := map[string][]string{}
+		for _, decision := range decisions {
+			if decision.Keep {
+				result[decision.Backup.CreatedAt.Format("2006-01-02")+" "+string(decision.Backup.Trigger)] = decision.Reasons
+			} else if len(decision.Reasons) == 0 {
+				t.Fatalf("%s is deleted without a reason", decision.Backup.Filename)
+			}
+		}
+		return result
+	}
+
+	decisions := planRetention(backups, config, false)
+	if len(decisions) != len(backups) {
+		t.Fatalf("expected a decision for each of the %d backups, got %d", len(backups), len(decisions))
+	}
+	result := kept(decisions)
+
+	// 7 daily, 2 more Sundays for weekly, 11 more month ends (including the end of 2024 for yearly)
+	// and the manual backup
+	if len(result) != 7+2+11+1 {
+		t.Fatalf("unexpected number of kept backups %d: %v", len(result), result)
+	}
+	for _, expected := range []string{
+		"2025-06-10 auto", "2025-06-04 auto", // daily
+		"2025-06-01 auto", "2025-05-25 auto", // weekly, Sundays
+		"2025-05-31 auto", "2024-07-31 auto", // monthly
+		"2024-12-31 auto",   // monthly and yearly
+		"2025-03-02 manual", // manual backups are protected
+	} {
+		if _, ok := result[expected]; !ok {
+			t.Errorf("expected backup of %s to be kept: %v", expected, result)
+		}
+	}
+	if reasons := result["2025-05-31 auto"]; strings.Join(reasons, ",") != "monthly 2025-05" {
+		t.Errorf("unexpected reasons for 2025-05-31: %v", reasons)
+	}
+
+	// Manual backups are pruned on request
+	if _, ok := kept(planRetention(backups, config, true))["2025-03-02 manual"]; ok {
+		t.Errorf("expected manual backup to be pruned")
+	}
+
+	// Without any rules nothing is deleted
+	for _, decision := range planRetention(backups, ccc.BackupConfig{}, true) {
+		if !decision.Keep {
+			t.Fatalf("expected all backups to be kept without rules, %s is deleted", decision.Backup.Filename)
+		}
+	}
+
+	// MaxGenerations alone keeps the newest backups
+	if result := kept(planRetention(backups, ccc.BackupConfig{MaxGenerations: 10}, false)); len(result) != 11 {
+		t.Errorf("expected the newest 10 and the manual backup to be kept, got %d", len(result))
+	}
+}
@@ -0,0 +1,54 @@
+package backup
+
+import (
+	"context"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// s3Target stores backups in a bucket of an S3-compatible object storage such as AWS S3 or MinIO
+type s3Target struct {
+	client *ccc.S3Client
+}
+
+// newS3Target creates an S3 target from a URL like s3://ACCESS_KEY:SECRET_KEY@host/bucket/prefix
+func newS3Target(targetUrl *url.URL) (*s3Target, error) {
+	client, err := ccc.NewS3Client(targetUrl)
+	if err != nil {
+		return nil, err
+	}
+	return &s3Target{client: client}, nil
+}
+
+// Name returns the endpoint, bucket and prefix of the target
+func (t *s3Target) Name() string {
+	return t.client.Name()
+}
+
+// Upload stores a file as an object
+func (t *s3Target) Upload(ctx context.Context, name string, src io.Reader, size int64) error {
+	return t.client.PutObject(ctx, name, src, size)
+}
+
+// List returns the names of all objects below the prefix
+func (t *s3Target) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := t.client.ListObjects(ctx, "", func(object ccc.S3Object) error {
+		if !strings.Contains(object.Name, "/") {
+			names = append(names, object.Name)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Delete removes an object
+func (t *s3Target) Delete(ctx context.Context, name string) error {
+	return t.client.DeleteObject(ctx, name)
+}
@@ -0,0 +1,258 @@
+package backup
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// failedRunRetryDelay is how long the worker waits before retrying a failed automatic backup,
+// unless the schedule is due earlier anyway
+const failedRunRetryDelay = time.Hour
+
+// NewBackupSchedule returns the schedule of automatic backups. A cron expression in Schedule takes
+// precedence over IntervalDays. It fails if neither is configured or the expression is invalid.
+func NewBackupSchedule(config ccc.BackupConfig) (BackupSchedule, error) {
+	if config.Schedule != "" {
+		return ParseCronSchedule(config.Schedule)
+	}
+	if config.IntervalDays > 0 {
+		return intervalSchedule{days: config.IntervalDays}, nil
+	}
+	return nil, fmt.Errorf("no backup schedule configured")
+}
+
+// NextBackupRun returns when the next automatic backup is due. The schedule continues from the
+// newest backup or run attempt, so backups missed while the server was down are made up at once.
+// A failed run is retried after an hour unless a backup was created since. Without any backup or run a backup is due immediately.
+func NextBackupRun(schedule BackupSchedule, lastBackupTime time.Time, lastRun *BackupRun, now time.Time) time.Time {
+	base := lastBackupTime
+	if lastRun != nil && lastRun.StartedAt.After(base) {
+		base = lastRun.StartedAt
+	}
+	if base.IsZero() {
+		return now
+	}
+
+	next := schedule.Next(base)
+	if lastRun != nil && lastRun.Status == BackupRunStatusFailed && !lastRun.StartedAt.Before(lastBackupTime) {
+		if retryAt := lastRun.StartedAt.Add(failedRunRetryDelay); retryAt.Before(next) {
+			next = retryAt
+		}
+	}
+	return next
+}
+
+// intervalSchedule runs a backup a fixed number of days after the previous one
+type intervalSchedule struct {
+	days int
+}
+
+func (s intervalSchedule) Next(after time.Time) time.Time {
+	return after.AddDate(0, 0, s.days)
+}
+
+func (s intervalSchedule) String() string {
+	if s.days == 1 {
+		return "every day"
+	}
+	return fmt.Sprintf("every %d days", s.days)
+}
+
+// cronSchedule is a parsed cron expression. Each field is a bit set of the allowed values.
+type cronSchedule struct {
+	expression string
+	minute     uint64
+	hour       uint64
+	dayOfMonth uint64
+	month      uint64
+	dayOfWeek  uint64
+	// In cron, a day matches either field if both day fields are restricted
+	dayOfMonthRestricted bool
+	dayOfWeekRestricted  bool
+}
+
+// cronField describes the allowed range and names of a cron field
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string // Names of the values starting at min, e.g. JAN for 1
+}
+
+var cronFields = []cronField{
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}},
+}
+
+var cronMacros = map[string]string{
+	"@hourly":   "0 * * * *",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@weekly":   "0 0 * * 0",
+	"@monthly":  "0 0 1 * *",
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+}
+
+// ParseCronSchedule parses a schedule in local time. Accepted are standard five-field cron
+// expressions ("minute hour day-of-month month day-of-week", e.g. "30 2 * * 1-5"), the macros
+// @hourly, @daily, @weekly, @monthly and @yearly, and a time of day like "03:00" for a daily backup.
+func ParseCronSchedule(expression string) (BackupSchedule, error) {
+	expression = strings.TrimSpace(expression)
+	spec := expression
+	if macro, ok := cronMacros[strings.ToLower(spec)]; ok {
+		spec = macro
+	} else if hour, minute, ok := parseTimeOfDay(spec); ok {
+		spec = fmt.Sprintf("%d %d * * *", minute, hour)
+	}
+
+	fields := strings.Fields(spec)
+	if len(fields) != len(cronFields) {
+		return nil, fmt.Errorf("invalid backup schedule %q: expected 5 fields, a macro like @daily or a time like 03:00", expression)
+	}
+
+	var sets [5]uint64
+	for i, field := range fields {
+		set, err := parseCronField(field, cronFields[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid backup schedule %q: %w", expression, err)
+		}
+		sets[i] = set
+	}
+
+	// 7 is an alias for Sunday
+	if sets[4]&(1<<7) != 0 {
+		sets[4] |= 1
+	}
+
+	schedule := &cronSchedule{
+		expression:           expression,
+		minute:               sets[0],
+		hour:                 sets[1],
+		dayOfMonth:           sets[2],
+		month:                sets[3],
+		dayOfWeek:            sets[4],
+		dayOfMonthRestricted: fields[2] != "*",
+		dayOfWeekRestricted:  fields[4] != "*",
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		return nil, fmt.Errorf("invalid backup schedule %q: it never matches", expression)
+	}
+	return schedule, nil
+}
+
+// parseTimeOfDay parses a time like "03:00"
+func parseTimeOfDay(value string) (hour, minute int, ok bool) {
+	parsed, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, 0, false
+	}
+	return parsed.Hour(), parsed.Minute(), true
+}
+
+// parseCronField parses a comma-separated list of values, ranges and steps like "1-5", "*/15" or "MON,WED"
+func parseCronField(value string, field cronField) (uint64, error) {
+	var set uint64
+	for _, part := range strings.Split(value, ",") {
+		rangePart, stepPart, hasStep := strings.Cut(part, "/")
+		step := 1
+		if hasStep {
+			var err error
+			step, err = strconv.Atoi(stepPart)
+			if err != nil || step <= 0 {
+				return 0, fmt.Errorf("invalid step %q in %s field", stepPart, field.name)
+			}
+		}
+
+		var first, last int
+		switch {
+		case rangePart == "*":
+			first, last = field.min, field.max
+		case strings.Contains(rangePart, "-"):
+			lowPart, highPart, _ := strings.Cut(rangePart, "-")
+			var err error
+			if first, err = parseCronValue(lowPart, field); err != nil {
+				return 0, err
+			}
+			if last, err = parseCronValue(highPart, field); err != nil {
+				return 0, err
+			}
+			if first > last {
+				return 0, fmt.Errorf("invalid range %q in %s field", rangePart, field.name)
+			}
+		default:
+			var err error
+			if first, err = parseCronValue(rangePart, field); err != nil {
+				return 0, err
+			}
+			last = first
+			if hasStep {
+				last = field.max
+			}
+		}
+
+		for v := first; v <= last; v += step {
+			set |= 1 << v
+		}
+	}
+	return set, nil
+}
+
+// parseCronValue parses a number or a name like JAN or MON
+func parseCronValue(value string, field cronField) (int, error) {
+	for i, name := range field.names {
+		if strings.EqualFold(value, name) {
+			return field.min + i, nil
+		}
+	}
+	number, err := strconv.Atoi(value)
+	if err != nil || number < field.min || number > field.max {
+		return 0, fmt.Errorf("invalid value %q in %s field (allowed: %d-%d)", value, field.name, field.min, field.max)
+	}
+	return number, nil
+}
+
+// Next returns the first matching minute after the given time
+func (s *cronSchedule) Next(after time.Time) time.Time {
+	t := after.Truncate(time.Minute).Add(time.Minute)
+
+	// Every valid expression matches within eight years, even one for February 29 only
+	limit := t.AddDate(8, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.month&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, t.Location())
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, t.Location())
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour()+1, 0, 0, 0, t.Location())
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+
+	// Expressions like "0 0 31 2 *" never match
+	return time.Time{}
+}
+
+func (s *cronSchedule) matchesDay(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.dayOfMonthRestricted && s.dayOfWeekRestricted {
+		return dayOfMonth || dayOfWeek
+	}
+	return dayOfMonth && dayOfWeek
+}
+
+func (s *cronSchedule) String() string {
+	return s.expression
+}
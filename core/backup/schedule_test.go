@@ -0,0 +1,79 @@
+package backup
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	// Tuesday, June 10 2025
+	after := time.Date(2025, 6, 10, 14, 30, 0, 0, time.Local)
+
+	tests := []struct {
+		expression string
+		expected   time.Time
+	}{
+		{"03:00", time.Date(2025, 6, 11, 3, 0, 0, 0, time.Local)},
+		{"@daily", time.Date(2025, 6, 11, 0, 0, 0, 0, time.Local)},
+		{"@hourly", time.Date(2025, 6, 10, 15, 0, 0, 0, time.Local)},
+		{"*/20 * * * *", time.Date(2025, 6, 10, 14, 40, 0, 0, time.Local)},
+		{"30 2 * * SAT,SUN", time.Date(2025, 6, 14, 2, 30, 0, 0, time.Local)},
+		{"0 4 * * 7", time.Date(2025, 6, 15, 4, 0, 0, 0, time.Local)},
+		{"0 3 1 * *", time.Date(2025, 7, 1, 3, 0, 0, 0, time.Local)},
+		{"0 3 1-7 JAN *", time.Date(2026, 1, 1, 3, 0, 0, 0, time.Local)},
+		// Either day field matches if both are restricted
+		{"0 0 20 * MON", time.Date(2025, 6, 16, 0, 0, 0, 0, time.Local)},
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.Local)},
+	}
+
+	for _, test := range tests {
+		schedule, err := ParseCronSchedule(test.expression)
+		if err != nil {
+			t.Fatalf("ParseCronSchedule(%q) failed: %v", test.expression, err)
+		}
+		if next := schedule.Next(after); !next.Equal(test.expected) {
+			t.Errorf("%q: expected %s, got %s", test.expression, test.expected, next)
+		}
+	}
+
+	for _, invalid := range []string{"", "* * * *", "60 * * * *", "0 3 * * MONDAY", "5-1 * * * *", "*/0 * * * *", "0 0 31 2 *", "25:00"} {
+		if _, err := ParseCronSchedule(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestNextBackupRun(t *testing.T) {
+	schedule, err := NewBackupSchedule(ccc.BackupConfig{Schedule: "03:00", IntervalDays: 7})
+	if err != nil {
+		t.Fatalf("NewBackupSchedule failed: %v", err)
+	}
+	now := time.Date(2025, 6, 10, 14, 30, 0, 0, time.Local)
+	lastBackup := time.Date(2025, 6, 10, 3, 0, 5, 0, time.Local)
+
+	if next := NextBackupRun(schedule, time.Time{}, nil, now); !next.Equal(now) {
+		t.Errorf("expected a backup to be due immediately without backups, got %s", next)
+	}
+	if next := NextBackupRun(schedule, lastBackup, nil, now); !next.Equal(time.Date(2025, 6, 11, 3, 0, 0, 0, time.Local)) {
+		t.Errorf("expected the next backup tomorrow at 03:00, got %s", next)
+	}
+
+	// A backup missed while the server was down is due at once
+	if next := NextBackupRun(schedule, lastBackup.AddDate(0, 0, -3), nil, now); next.After(now) {
+		t.Errorf("expected a missed backup to be due, got %s", next)
+	}
+
+	// A failed run is retried after an hour instead of waiting for the next day
+	failedRun := &BackupRun{Status: BackupRunStatusFailed, StartedAt: lastBackup.Add(time.Hour)}
+	if next := NextBackupRun(schedule, lastBackup.AddDate(0, 0, -1), failedRun, now); !next.Equal(failedRun.StartedAt.Add(time.Hour)) {
+		t.Errorf("expected a retry an hour after the failed run, got %s", next)
+	}
+
+	// Without a cron expression the interval is used
+	schedule, _ = NewBackupSchedule(ccc.BackupConfig{IntervalDays: 7})
+	if next := NextBackupRun(schedule, lastBackup, nil, now); !next.Equal(lastBackup.AddDate(0, 0, 7)) {
+		t.Errorf("expected the next backup in 7 days, got %s", next)
+	}
+}
@@ -0,0 +1,60 @@
+package backup
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+// ServerPidFilePath returns the path of the file in which the web UI records its process ID
+// while it uses the database at databasePath
+func ServerPidFilePath(databasePath string) string {
+	return databasePath + ".webui.pid"
+}
+
+// WriteServerPidFile records the process ID of the current process as the web UI using the database.
+// Restores refuse to replace the database while this file points to a running process.
+func WriteServerPidFile(databasePath string) error {
+	pid := strconv.Itoa(os.Getpid())
+	if err := os.WriteFile(ServerPidFilePath(databasePath), []byte(pid+"\n"), 0644); err != nil {
+		return fmt.Errorf("failed to write web UI pid file: %w", err)
+	}
+	return nil
+}
+
+// RemoveServerPidFile removes the pid file written by WriteServerPidFile
+func RemoveServerPidFile(databasePath string) error {
+	if err := os.Remove(ServerPidFilePath(databasePath)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to remove web UI pid file: %w", err)
+	}
+	return nil
+}
+
+// runningServerPid returns the process ID of the web UI using the database, or 0 if none is running.
+// Stale pid files of processes that no longer exist are ignored.
+func runningServerPid(databasePath string) int {
+	data, err := os.ReadFile(ServerPidFilePath(databasePath))
+	if err != nil {
+		return 0
+	}
+
+	pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+	if err != nil || pid <= 0 || pid == os.Getpid() {
+		return 0
+	}
+
+	process, err := os.FindProcess(pid)
+	if err != nil {
+		return 0
+	}
+
+	// Signal 0 only checks whether the process exists. Platforms that cannot send it
+	// report a different error, in which case we assume the web UI is running.
+	if err := process.Signal(syscall.Signal(0)); errors.Is(err, os.ErrProcessDone) || errors.Is(err, syscall.ESRCH) {
+		return 0
+	}
+	return pid
+}
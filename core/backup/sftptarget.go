@@ -0,0 +1,514 @@
+package backup
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// sftpTarget stores backups in a directory on an SSH server using the SFTP protocol.
+// Only the few SFTP operations needed for backups are implemented.
+type sftpTarget struct {
+	host        string // host:port
+	directory   string
+	user        string
+	password    string
+	keyFile     string
+	knownHosts  string
+	fingerprint string // SHA256 fingerprint of the host key, alternative to knownHosts
+}
+
+// newSftpTarget creates an SFTP target from a URL like sftp://user@host/path?key=/path/to/key
+func newSftpTarget(targetUrl *url.URL) (*sftpTarget, error) {
+	query := targetUrl.Query()
+	target := &sftpTarget{
+		host:        targetUrl.Host,
+		directory:   targetUrl.Path,
+		user:        targetUrl.User.Username(),
+		keyFile:     userHomePath(query.Get("key")),
+		knownHosts:  userHomePath(query.Get("known_hosts")),
+		fingerprint: query.Get("fingerprint"),
+	}
+	target.password, _ = targetUrl.User.Password()
+
+	if target.user == "" {
+		return nil, fmt.Errorf("backup target %s has no user", ccc.RedactUrl(targetUrl))
+	}
+	if target.password == "" && target.keyFile == "" {
+		return nil, fmt.Errorf("backup target %s needs a password or a key file", ccc.RedactUrl(targetUrl))
+	}
+	if target.directory == "" {
+		target.directory = "."
+	}
+	if targetUrl.Port() == "" {
+		target.host = net.JoinHostPort(targetUrl.Hostname(), "22")
+	}
+	if target.knownHosts == "" && target.fingerprint == "" {
+		target.knownHosts = userHomePath("~/.ssh/known_hosts")
+	}
+
+	return target, nil
+}
+
+// Name returns the host and directory of the target
+func (t *sftpTarget) Name() string {
+	return "sftp://" + t.host + t.directory
+}
+
+// Upload writes a file to a temporary name and renames it, so incomplete uploads are never mistaken for backups
+func (t *sftpTarget) Upload(ctx context.Context, name string, src io.Reader, size int64) error {
+	return t.withSession(ctx, func(session *sftpSession) error {
+		if err := session.mkdirAll(t.directory); err != nil {
+			return err
+		}
+
+		finalPath := path.Join(t.directory, name)
+		partialPath := finalPath + ".partial"
+		if err := session.writeFile(partialPath, src); err != nil {
+			return err
+		}
+
+		// SFTP version 3 does not overwrite on rename. Servers report a missing file with
+		// different codes, so a failed removal is left to the rename to report.
+		session.remove(finalPath)
+		return session.rename(partialPath, finalPath)
+	})
+}
+
+// List returns the names of the files in the directory
+func (t *sftpTarget) List(ctx context.Context) ([]string, error) {
+	var names []string
+	err := t.withSession(ctx, func(session *sftpSession) error {
+		var err error
+		names, err = session.readDir(t.directory)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	})
+	return names, err
+}
+
+// Delete removes a file from the directory
+func (t *sftpTarget) Delete(ctx context.Context, name string) error {
+	return t.withSession(ctx, func(session *sftpSession) error {
+		return session.remove(path.Join(t.directory, name))
+	})
+}
+
+// withSession connects to the server, starts the SFTP subsystem and runs fn. The connection
+// is closed when fn returns or the context is cancelled.
+func (t *sftpTarget) withSession(ctx context.Context, fn func(session *sftpSession) error) error {
+	config := &ssh.ClientConfig{
+		User:    t.user,
+		Timeout: 30 * time.Second,
+	}
+
+	if t.fingerprint != "" {
+		config.HostKeyCallback = func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+			if ssh.FingerprintSHA256(key) != t.fingerprint {
+				return fmt.Errorf("host key fingerprint %s does not match the configured one", ssh.FingerprintSHA256(key))
+			}
+			return nil
+		}
+	} else {
+		callback, err := knownhosts.New(t.knownHosts)
+		if err != nil {
+			return fmt.Errorf("failed to load known hosts: %w", err)
+		}
+		config.HostKeyCallback = callback
+	}
+
+	if t.keyFile != "" {
+		keyData, err := os.ReadFile(t.keyFile)
+		if err != nil {
+			return fmt.Errorf("failed to read SSH key: %w", err)
+		}
+		signer, err := ssh.ParsePrivateKey(keyData)
+		if err != nil {
+			return fmt.Errorf("failed to parse SSH key: %w", err)
+		}
+		config.Auth = append(config.Auth, ssh.PublicKeys(signer))
+	}
+	if t.password != "" {
+		config.Auth = append(config.Auth, ssh.Password(t.password))
+	}
+
+	client, err := ssh.Dial("tcp", t.host, config)
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", t.host, err)
+	}
+	defer client.Close()
+
+	stop := context.AfterFunc(ctx, func() { client.Close() })
+	defer stop()
+
+	sshSession, err := client.NewSession()
+	if err != nil {
+		return fmt.Errorf("failed to open SSH session: %w", err)
+	}
+	defer sshSession.Close()
+
+	stdin, err := sshSession.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open SFTP input: %w", err)
+	}
+	stdout, err := sshSession.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to open SFTP output: %w", err)
+	}
+	if err := sshSession.RequestSubsystem("sftp"); err != nil {
+		return fmt.Errorf("failed to start SFTP subsystem: %w", err)
+	}
+
+	session := &sftpSession{in: stdin, out: stdout}
+	if err := session.init(); err != nil {
+		return err
+	}
+
+	if err := fn(session); err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		return err
+	}
+	return nil
+}
+
+// SFTP version 3 packet types and constants, see draft-ietf-secsh-filexfer-02
+const (
+	sftpInit     = 1
+	sftpVersion  = 2
+	sftpOpen     = 3
+	sftpClose    = 4
+	sftpWrite    = 6
+	sftpOpendir  = 11
+	sftpReaddir  = 12
+	sftpRemove   = 13
+	sftpMkdir    = 14
+	sftpRename   = 18
+	sftpStatus   = 101
+	sftpHandle   = 102
+	sftpName     = 104
+	sftpMaxWrite = 32 * 1024
+
+	sftpOpenWrite    = 0x02
+	sftpOpenCreate   = 0x08
+	sftpOpenTruncate = 0x10
+
+	sftpStatusOk         = 0
+	sftpStatusEof        = 1
+	sftpStatusNoSuchFile = 2
+)
+
+// sftpSession sends SFTP requests one at a time over the streams of an SSH subsystem
+type sftpSession struct {
+	in     io.Writer
+	out    io.Reader
+	nextId uint32
+}
+
+// sftpStatusError is an SSH_FXP_STATUS response other than OK
+type sftpStatusError struct {
+	code    uint32
+	message string
+}
+
+func (e *sftpStatusError) Error() string {
+	return fmt.Sprintf("SFTP error %d: %s", e.code, e.message)
+}
+
+// Is makes errors.Is(err, os.ErrNotExist) work for missing files
+func (e *sftpStatusError) Is(target error) bool {
+	return target == os.ErrNotExist && e.code == sftpStatusNoSuchFile
+}
+
+func (s *sftpSession) init() error {
+	if err := s.send(sftpInit, binary.BigEndian.AppendUint32(nil, 3)); err != nil {
+		return err
+	}
+	packetType, _, err := s.receive()
+	if err != nil {
+		return err
+	}
+	if packetType != sftpVersion {
+		return fmt.Errorf("unexpected SFTP packet %d during initialization", packetType)
+	}
+	return nil
+}
+
+// request sends a request with a new ID and returns the type and payload of the response after the ID
+func (s *sftpSession) request(packetType byte, payload []byte) (byte, []byte, error) {
+	s.nextId++
+	id := s.nextId
+	if err := s.send(packetType, append(binary.BigEndian.AppendUint32(nil, id), payload...)); err != nil {
+		return 0, nil, err
+	}
+
+	responseType, response, err := s.receive()
+	if err != nil {
+		return 0, nil, err
+	}
+	if len(response) < 4 || binary.BigEndian.Uint32(response) != id {
+		return 0, nil, fmt.Errorf("unexpected SFTP response ID")
+	}
+	return responseType, response[4:], nil
+}
+
+// requestStatus sends a request that is answered with a status and returns it as an error unless it is OK
+func (s *sftpSession) requestStatus(packetType byte, payload []byte) error {
+	responseType, response, err := s.request(packetType, payload)
+	if err != nil {
+		return err
+	}
+	return statusError(responseType, response)
+}
+
+// requestHandle sends a request that is answered with a handle
+func (s *sftpSession) requestHandle(packetType byte, payload []byte) ([]byte, error) {
+	responseType, response, err := s.request(packetType, payload)
+	if err != nil {
+		return nil, err
+	}
+	if responseType != sftpHandle {
+		return nil, statusError(responseType, response)
+	}
+	handle, _, err := readSftpString(response)
+	return handle, err
+}
+
+func (s *sftpSession) send(packetType byte, payload []byte) error {
+	packet := binary.BigEndian.AppendUint32(nil, uint32(len(payload)+1))
+	packet = append(packet, packetType)
+	packet = append(packet, payload...)
+	if _, err := s.in.Write(packet); err != nil {
+		return fmt.Errorf("failed to send SFTP request: %w", err)
+	}
+	return nil
+}
+
+func (s *sftpSession) receive() (byte, []byte, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(s.out, length[:]); err != nil {
+		return 0, nil, fmt.Errorf("failed to read SFTP response: %w", err)
+	}
+	size := binary.BigEndian.Uint32(length[:])
+	if size == 0 || size > 256*1024 {
+		return 0, nil, fmt.Errorf("invalid SFTP packet length: %d", size)
+	}
+
+	packet := make([]byte, size)
+	if _, err := io.ReadFull(s.out, packet); err != nil {
+		return 0, nil, fmt.Errorf("failed to read SFTP response: %w", err)
+	}
+	return packet[0], packet[1:], nil
+}
+
+// writeFile creates or truncates a file and writes the content of src to it
+func (s *sftpSession) writeFile(filePath string, src io.Reader) error {
+	payload := appendSftpString(nil, []byte(filePath))
+	payload = binary.BigEndian.AppendUint32(payload, sftpOpenWrite|sftpOpenCreate|sftpOpenTruncate)
+	payload = binary.BigEndian.AppendUint32(payload, 0) // no attributes
+	handle, err := s.requestHandle(sftpOpen, payload)
+	if err != nil {
+		return fmt.Errorf("failed to open %s: %w", filePath, err)
+	}
+
+	buffer := make([]byte, sftpMaxWrite)
+	offset := uint64(0)
+	for {
+		n, readErr := io.ReadFull(src, buffer)
+		if n > 0 {
+			payload := appendSftpString(nil, handle)
+			payload = binary.BigEndian.AppendUint64(payload, offset)
+			payload = appendSftpString(payload, buffer[:n])
+			if err := s.requestStatus(sftpWrite, payload); err != nil {
+				s.closeHandle(handle)
+				return fmt.Errorf("failed to write %s: %w", filePath, err)
+			}
+			offset += uint64(n)
+		}
+		if readErr == io.EOF || readErr == io.ErrUnexpectedEOF {
+			break
+		}
+		if readErr != nil {
+			s.closeHandle(handle)
+			return fmt.Errorf("failed to read upload data: %w", readErr)
+		}
+	}
+
+	if err := s.closeHandle(handle); err != nil {
+		return fmt.Errorf("failed to close %s: %w", filePath, err)
+	}
+	return nil
+}
+
+func (s *sftpSession) closeHandle(handle []byte) error {
+	return s.requestStatus(sftpClose, appendSftpString(nil, handle))
+}
+
+// readDir returns the names of the regular entries of a directory
+func (s *sftpSession) readDir(directory string) ([]string, error) {
+	handle, err := s.requestHandle(sftpOpendir, appendSftpString(nil, []byte(directory)))
+	if err != nil {
+		return nil, err
+	}
+	defer s.closeHandle(handle)
+
+	var names []string
+	for {
+		responseType, response, err := s.request(sftpReaddir, appendSftpString(nil, handle))
+		if err != nil {
+			return nil, err
+		}
+		if responseType != sftpName {
+			if err := statusError(responseType, response); err != nil && !isSftpEof(err) {
+				return nil, err
+			}
+			return names, nil
+		}
+
+		if len(response) < 4 {
+			return nil, fmt.Errorf("invalid SFTP name response")
+		}
+		count := binary.BigEndian.Uint32(response)
+		rest := response[4:]
+		for i := uint32(0); i < count; i++ {
+			var name []byte
+			if name, rest, err = readSftpString(rest); err != nil {
+				return nil, err
+			}
+			if _, rest, err = readSftpString(rest); err != nil { // long name
+				return nil, err
+			}
+			if rest, err = skipSftpAttributes(rest); err != nil {
+				return nil, err
+			}
+			if entry := string(name); entry != "." && entry != ".." {
+				names = append(names, entry)
+			}
+		}
+	}
+}
+
+func (s *sftpSession) remove(filePath string) error {
+	return s.requestStatus(sftpRemove, appendSftpString(nil, []byte(filePath)))
+}
+
+func (s *sftpSession) rename(oldPath, newPath string) error {
+	payload := appendSftpString(nil, []byte(oldPath))
+	payload = appendSftpString(payload, []byte(newPath))
+	return s.requestStatus(sftpRename, payload)
+}
+
+// mkdirAll creates a directory and its parents, ignoring directories that already exist
+func (s *sftpSession) mkdirAll(directory string) error {
+	current := ""
+	if strings.HasPrefix(directory, "/") {
+		current = "/"
+	}
+	for _, segment := range strings.Split(strings.Trim(directory, "/"), "/") {
+		if segment == "" || segment == "." {
+			continue
+		}
+		current = path.Join(current, segment)
+
+		payload := appendSftpString(nil, []byte(current))
+		payload = binary.BigEndian.AppendUint32(payload, 0) // no attributes
+		if err := s.requestStatus(sftpMkdir, payload); err != nil {
+			// Servers report existing directories with different codes, so check whether it can be opened
+			handle, openErr := s.requestHandle(sftpOpendir, appendSftpString(nil, []byte(current)))
+			if openErr != nil {
+				return fmt.Errorf("failed to create directory %s: %w", current, err)
+			}
+			s.closeHandle(handle)
+		}
+	}
+	return nil
+}
+
+// statusError converts a status response to an error, nil for OK
+func statusError(responseType byte, response []byte) error {
+	if responseType != sftpStatus || len(response) < 4 {
+		return fmt.Errorf("unexpected SFTP response %d", responseType)
+	}
+	code := binary.BigEndian.Uint32(response)
+	if code == sftpStatusOk {
+		return nil
+	}
+	message, _, _ := readSftpString(response[4:])
+	return &sftpStatusError{code: code, message: string(message)}
+}
+
+func isSftpEof(err error) bool {
+	var statusErr *sftpStatusError
+	return errors.As(err, &statusErr) && statusErr.code == sftpStatusEof
+}
+
+func appendSftpString(data []byte, value []byte) []byte {
+	data = binary.BigEndian.AppendUint32(data, uint32(len(value)))
+	return append(data, value...)
+}
+
+func readSftpString(data []byte) ([]byte, []byte, error) {
+	if len(data) < 4 {
+		return nil, nil, fmt.Errorf("invalid SFTP string")
+	}
+	length := binary.BigEndian.Uint32(data)
+	if uint32(len(data)-4) < length {
+		return nil, nil, fmt.Errorf("invalid SFTP string")
+	}
+	return data[4 : 4+length], data[4+length:], nil
+}
+
+// skipSftpAttributes skips the file attributes of a name entry
+func skipSftpAttributes(data []byte) ([]byte, error) {
+	if len(data) < 4 {
+		return nil, fmt.Errorf("invalid SFTP attributes")
+	}
+	flags := binary.BigEndian.Uint32(data)
+	size := 4
+	if flags&0x1 != 0 { // size
+		size += 8
+	}
+	if flags&0x2 != 0 { // uid and gid
+		size += 8
+	}
+	if flags&0x4 != 0 { // permissions
+		size += 4
+	}
+	if flags&0x8 != 0 { // access and modification time
+		size += 8
+	}
+	if len(data) < size {
+		return nil, fmt.Errorf("invalid SFTP attributes")
+	}
+	data = data[size:]
+
+	if flags&0x80000000 != 0 { // extended attributes
+		if len(data) < 4 {
+			return nil, fmt.Errorf("invalid SFTP attributes")
+		}
+		count := binary.BigEndian.Uint32(data)
+		data = data[4:]
+		for i := uint32(0); i < count*2; i++ {
+			var err error
+			if _, data, err = readSftpString(data); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return data, nil
+}
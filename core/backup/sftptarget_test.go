@@ -0,0 +1,331 @@
+package backup
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/pem"
+	"errors"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestSftpTarget(t *testing.T) {
+	server := newSftpTestServer(t)
+	fingerprint := url.QueryEscape(ssh.FingerprintSHA256(server.hostKey.PublicKey()))
+	target, _, err := ParseBackupTarget("sftp://backup:secret@" + server.address() + "/backups/nightly?fingerprint=" + fingerprint)
+	if err != nil {
+		t.Fatalf("ParseBackupTarget failed: %v", err)
+	}
+
+	ctx := context.Background()
+	names, err := target.List(ctx)
+	if err != nil || len(names) != 0 {
+		t.Fatalf("expected a missing directory to be empty, got %v: %v", names, err)
+	}
+
+	// More than one write request, and an existing file is replaced
+	content := bytes.Repeat([]byte("backup"), 20000)
+	for _, data := range [][]byte{[]byte("old"), content} {
+		if err := target.Upload(ctx, "ff_backup_20250101_120000_auto.db", bytes.NewReader(data), int64(len(data))); err != nil {
+			t.Fatalf("Upload failed: %v", err)
+		}
+	}
+	if err := target.Upload(ctx, "ff_backup_20250102_120000_auto.db", strings.NewReader("data"), 4); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	stored, err := os.ReadFile(filepath.Join(server.root, "backups", "nightly", "ff_backup_20250101_120000_auto.db"))
+	if err != nil || !bytes.Equal(stored, content) {
+		t.Fatalf("uploaded file not stored: %v", err)
+	}
+
+	names, err = target.List(ctx)
+	sort.Strings(names)
+	if err != nil || strings.Join(names, ",") != "ff_backup_20250101_120000_auto.db,ff_backup_20250102_120000_auto.db" {
+		t.Fatalf("unexpected listing %v: %v", names, err)
+	}
+
+	if err := target.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if names, _ = target.List(ctx); len(names) != 1 || names[0] != "ff_backup_20250102_120000_auto.db" {
+		t.Fatalf("file was not deleted: %v", names)
+	}
+	if err := target.Delete(ctx, "missing.db"); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected deleting a missing file to fail with ErrNotExist, got %v", err)
+	}
+}
+
+func TestSftpTargetVerifiesHostKey(t *testing.T) {
+	server := newSftpTestServer(t)
+	dir := t.TempDir()
+
+	// Authentication with a key file
+	_, clientPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	clientKey, _ := ssh.NewSignerFromKey(clientPrivateKey)
+	server.authorizedKey = clientKey.PublicKey()
+	keyBlock, _ := ssh.MarshalPrivateKey(clientPrivateKey, "")
+	keyFile := filepath.Join(dir, "id_ed25519")
+	os.WriteFile(keyFile, pem.EncodeToMemory(keyBlock), 0600)
+
+	_, otherPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	otherKey, _ := ssh.NewSignerFromKey(otherPrivateKey)
+
+	knownHosts := func(name string, key ssh.PublicKey) string {
+		file := filepath.Join(dir, name)
+		os.WriteFile(file, []byte(knownhosts.Line([]string{knownhosts.Normalize(server.address())}, key)+"\n"), 0600)
+		return file
+	}
+
+	tests := []struct {
+		name  string
+		query string
+		valid bool
+	}{
+		{"matching fingerprint", "fingerprint=" + url.QueryEscape(ssh.FingerprintSHA256(server.hostKey.PublicKey())), true},
+		{"other fingerprint", "fingerprint=" + url.QueryEscape(ssh.FingerprintSHA256(otherKey.PublicKey())), false},
+		{"known host", "known_hosts=" + knownHosts("known_hosts", server.hostKey.PublicKey()), true},
+		{"changed host key", "known_hosts=" + knownHosts("changed_known_hosts", otherKey.PublicKey()), false},
+		{"unknown host", "known_hosts=" + filepath.Join(dir, "empty_known_hosts"), false},
+	}
+	os.WriteFile(filepath.Join(dir, "empty_known_hosts"), nil, 0600)
+
+	for _, test := range tests {
+		target, _, err := ParseBackupTarget("sftp://backup@" + server.address() + "/backups?key=" + keyFile + "&" + test.query)
+		if err != nil {
+			t.Fatalf("%s: ParseBackupTarget failed: %v", test.name, err)
+		}
+		_, err = target.List(context.Background())
+		if test.valid && err != nil {
+			t.Errorf("%s: expected the host to be accepted, got %v", test.name, err)
+		}
+		if !test.valid && err == nil {
+			t.Errorf("%s: expected the host to be rejected", test.name)
+		}
+	}
+}
+
+// sftpTestServer is an SSH server with a minimal SFTP subsystem serving the files below root.
+// Like common servers, it neither overwrites on rename nor creates existing directories.
+type sftpTestServer struct {
+	listener      net.Listener
+	hostKey       ssh.Signer
+	authorizedKey ssh.PublicKey
+	root          string
+}
+
+const sftpStatusFailure = 4
+
+func newSftpTestServer(t *testing.T) *sftpTestServer {
+	_, hostPrivateKey, _ := ed25519.GenerateKey(rand.Reader)
+	hostKey, err := ssh.NewSignerFromKey(hostPrivateKey)
+	if err != nil {
+		t.Fatalf("failed to create host key: %v", err)
+	}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	server := &sftpTestServer{listener: listener, hostKey: hostKey, root: t.TempDir()}
+	config := &ssh.ServerConfig{
+		PasswordCallback: func(conn ssh.ConnMetadata, password []byte) (*ssh.Permissions, error) {
+			if conn.User() == "backup" && string(password) == "secret" {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+		PublicKeyCallback: func(conn ssh.ConnMetadata, key ssh.PublicKey) (*ssh.Permissions, error) {
+			if server.authorizedKey != nil && bytes.Equal(key.Marshal(), server.authorizedKey.Marshal()) {
+				return nil, nil
+			}
+			return nil, errors.New("access denied")
+		},
+	}
+	config.AddHostKey(hostKey)
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.handleConnection(conn, config)
+		}
+	}()
+	return server
+}
+
+func (s *sftpTestServer) address() string {
+	return s.listener.Addr().String()
+}
+
+func (s *sftpTestServer) handleConnection(conn net.Conn, config *ssh.ServerConfig) {
+	serverConn, channels, requests, err := ssh.NewServerConn(conn, config)
+	if err != nil {
+		conn.Close()
+		return
+	}
+	defer serverConn.Close()
+	go ssh.DiscardRequests(requests)
+
+	for newChannel := range channels {
+		if newChannel.ChannelType() != "session" {
+			newChannel.Reject(ssh.UnknownChannelType, "only sessions are supported")
+			continue
+		}
+		channel, channelRequests, err := newChannel.Accept()
+		if err != nil {
+			return
+		}
+		go func() {
+			for request := range channelRequests {
+				subsystem, _, _ := readSftpString(request.Payload)
+				isSftp := request.Type == "subsystem" && string(subsystem) == "sftp"
+				request.Reply(isSftp, nil)
+				if isSftp {
+					go func() {
+						s.serveSftp(channel)
+						channel.Close()
+					}()
+				}
+			}
+		}()
+	}
+}
+
+// serveSftp answers SFTP requests until the client closes the channel
+func (s *sftpTestServer) serveSftp(channel io.ReadWriter) {
+	session := &sftpSession{in: channel, out: channel}
+	files := map[string]*os.File{}
+	directories := map[string][]os.DirEntry{}
+	nextHandle := 0
+	newHandle := func() string {
+		nextHandle++
+		return strconv.Itoa(nextHandle)
+	}
+
+	for {
+		packetType, payload, err := session.receive()
+		if err != nil {
+			return
+		}
+		if packetType == sftpInit {
+			session.send(sftpVersion, binary.BigEndian.AppendUint32(nil, 3))
+			continue
+		}
+
+		id, data := payload[:4], payload[4:]
+		reply := func(responseType byte, response []byte) {
+			session.send(responseType, append(bytes.Clone(id), response...))
+		}
+		replyStatus := func(err error) {
+			code, message := uint32(sftpStatusOk), "OK"
+			switch {
+			case err == io.EOF:
+				code, message = sftpStatusEof, "end of directory"
+			case errors.Is(err, os.ErrNotExist):
+				code, message = sftpStatusNoSuchFile, "no such file"
+			case err != nil:
+				code, message = sftpStatusFailure, err.Error()
+			}
+			response := binary.BigEndian.AppendUint32(nil, code)
+			response = appendSftpString(response, []byte(message))
+			reply(sftpStatus, appendSftpString(response, nil)) // language tag
+		}
+		localPath := func(data []byte) (string, []byte) {
+			name, rest, _ := readSftpString(data)
+			return filepath.Join(s.root, filepath.FromSlash(string(name))), rest
+		}
+
+		switch packetType {
+		case sftpOpen:
+			filePath, _ := localPath(data)
+			file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+			if err != nil {
+				replyStatus(err)
+				continue
+			}
+			handle := newHandle()
+			files[handle] = file
+			reply(sftpHandle, appendSftpString(nil, []byte(handle)))
+		case sftpWrite:
+			handle, rest, _ := readSftpString(data)
+			offset := binary.BigEndian.Uint64(rest)
+			content, _, _ := readSftpString(rest[8:])
+			_, err := files[string(handle)].WriteAt(content, int64(offset))
+			replyStatus(err)
+		case sftpClose:
+			handle, _, _ := readSftpString(data)
+			if file, ok := files[string(handle)]; ok {
+				file.Close()
+				delete(files, string(handle))
+			}
+			delete(directories, string(handle))
+			replyStatus(nil)
+		case sftpOpendir:
+			directory, _ := localPath(data)
+			entries, err := os.ReadDir(directory)
+			if err != nil {
+				replyStatus(err)
+				continue
+			}
+			handle := newHandle()
+			directories[handle] = entries
+			reply(sftpHandle, appendSftpString(nil, []byte(handle)))
+		case sftpReaddir:
+			handle, _, _ := readSftpString(data)
+			entries, ok := directories[string(handle)]
+			if !ok || entries == nil {
+				replyStatus(io.EOF)
+				continue
+			}
+			// All entries are sent at once, the next request is answered with EOF
+			directories[string(handle)] = nil
+			names := []string{".", ".."}
+			for _, entry := range entries {
+				names = append(names, entry.Name())
+			}
+			response := binary.BigEndian.AppendUint32(nil, uint32(len(names)))
+			for _, name := range names {
+				response = appendSftpString(response, []byte(name))
+				response = appendSftpString(response, []byte("-rw------- 1 backup backup "+name))
+				// Size, permissions and times, as sent by common servers
+				response = binary.BigEndian.AppendUint32(response, 0x1|0x4|0x8)
+				response = binary.BigEndian.AppendUint64(response, 0)
+				response = binary.BigEndian.AppendUint32(response, 0600)
+				response = binary.BigEndian.AppendUint64(response, 0)
+			}
+			reply(sftpName, response)
+		case sftpRemove:
+			filePath, _ := localPath(data)
+			replyStatus(os.Remove(filePath))
+		case sftpMkdir:
+			directory, _ := localPath(data)
+			replyStatus(os.Mkdir(directory, 0700))
+		case sftpRename:
+			oldPath, rest := localPath(data)
+			newPath, _ := localPath(rest)
+			if _, err := os.Stat(newPath); err == nil {
+				replyStatus(errors.New("file exists"))
+				continue
+			}
+			replyStatus(os.Rename(oldPath, newPath))
+		default:
+			replyStatus(errors.New("unsupported operation"))
+		}
+	}
+}
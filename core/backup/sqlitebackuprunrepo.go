@@ -0,0 +1,206 @@
+package backup
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteBackupRunRepository implements BackupRunRepository using SQLite
+type SQLiteBackupRunRepository struct {
+	db *sql.DB
+}
+
+const (
+	// Field list for backup_runs table queries
+	backupRunFieldList = `
+	id,
+	trigger_type,
+	status,
+	started_at,
+	finished_at,
+	filename,
+	size_bytes,
+	error`
+)
+
+// NewSQLiteBackupRunRepository creates a new SQLite-backed backup run repository.
+// The backup_runs table is expected to be created by the schema migrations.
+func NewSQLiteBackupRunRepository(db *sql.DB) (*SQLiteBackupRunRepository, error) {
+	return &SQLiteBackupRunRepository{db: db}, nil
+}
+
+// Add inserts a new run
+func (repo *SQLiteBackupRunRepository) Add(run *BackupRun) error {
+	insertSql := fmt.Sprintf(`
+	INSERT INTO backup_runs (
+		%s
+	) VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`, backupRunFieldList)
+
+	_, err := repo.db.Exec(
+		insertSql,
+		run.Id,
+		run.Trigger,
+		run.Status,
+		ccc.FormatSQLiteTimestamp(run.StartedAt),
+		formatOptionalTimestamp(run.FinishedAt),
+		run.Filename,
+		run.SizeBytes,
+		run.Error,
+	)
+	if err != nil {
+		return fmt.Errorf("inserting backup run: %w", err)
+	}
+
+	return nil
+}
+
+// Update stores the outcome of a run
+func (repo *SQLiteBackupRunRepository) Update(run *BackupRun) error {
+	_, err := repo.db.Exec(`
+	UPDATE backup_runs
+	SET status = ?, finished_at = ?, filename = ?, size_bytes = ?, error = ?
+	WHERE id = ?
+	`,
+		run.Status,
+		formatOptionalTimestamp(run.FinishedAt),
+		run.Filename,
+		run.SizeBytes,
+		run.Error,
+		run.Id,
+	)
+	if err != nil {
+		return fmt.Errorf("updating backup run: %w", err)
+	}
+	return nil
+}
+
+// FindRecent returns the latest runs, newest first
+func (repo *SQLiteBackupRunRepository) FindRecent(limit int) ([]*BackupRun, error) {
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM backup_runs
+	ORDER BY started_at DESC
+	LIMIT ?
+	`, backupRunFieldList)
+
+	rows, err := repo.db.Query(selectSql, limit)
+	if err != nil {
+		return nil, fmt.Errorf("querying backup runs: %w", err)
+	}
+	defer rows.Close()
+
+	var runs []*BackupRun
+	for rows.Next() {
+		run, err := scanBackupRun(rows)
+		if err != nil {
+			return nil, err
+		}
+		runs = append(runs, run)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating backup runs: %w", err)
+	}
+
+	return runs, nil
+}
+
+// FindLatestByStatus returns the latest run with one of the given statuses, nil if there is none
+func (repo *SQLiteBackupRunRepository) FindLatestByStatus(statuses ...BackupRunStatus) (*BackupRun, error) {
+	if len(statuses) == 0 {
+		return nil, nil
+	}
+
+	args := make([]any, len(statuses))
+	for i, status := range statuses {
+		args[i] = status
+	}
+
+	selectSql := fmt.Sprintf(`
+	SELECT %s
+	FROM backup_runs
+	WHERE status IN (%s)
+	ORDER BY started_at DESC
+	LIMIT 1
+	`, backupRunFieldList, strings.TrimSuffix(strings.Repeat("?, ", len(statuses)), ", "))
+
+	return scanBackupRun(repo.db.QueryRow(selectSql, args...))
+}
+
+// CountByStatusSince returns the number of runs with the given status started after since
+func (repo *SQLiteBackupRunRepository) CountByStatusSince(status BackupRunStatus, since time.Time) (int, error) {
+	var count int
+	err := repo.db.QueryRow(`SELECT COUNT(*) FROM backup_runs WHERE status = ? AND started_at > ?`,
+		status, ccc.FormatSQLiteTimestamp(since)).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("counting backup runs: %w", err)
+	}
+	return count, nil
+}
+
+// FailUnfinished marks runs that are still running as failed
+func (repo *SQLiteBackupRunRepository) FailUnfinished(finishedAt time.Time, reason string) (int64, error) {
+	result, err := repo.db.Exec(`UPDATE backup_runs SET status = ?, finished_at = ?, error = ? WHERE status = ?`,
+		BackupRunStatusFailed, ccc.FormatSQLiteTimestamp(finishedAt), reason, BackupRunStatusRunning)
+	if err != nil {
+		return 0, fmt.Errorf("updating unfinished backup runs: %w", err)
+	}
+	return result.RowsAffected()
+}
+
+func scanBackupRun(scanner ccc.RowScanner) (*BackupRun, error) {
+	run := &BackupRun{}
+	var trigger, status, startedAtStr string
+	var finishedAtStr sql.NullString
+
+	err := scanner.Scan(
+		&run.Id,
+		&trigger,
+		&status,
+		&startedAtStr,
+		&finishedAtStr,
+		&run.Filename,
+		&run.SizeBytes,
+		&run.Error,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Run not found
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("reading backup run from database: %w", err)
+	}
+
+	run.Trigger = BackupTrigger(trigger)
+	run.Status = BackupRunStatus(status)
+
+	startedAt, err := ccc.ParseSQLiteTimestamp(startedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing started_at timestamp: %w", err)
+	}
+	run.StartedAt = startedAt
+
+	if finishedAtStr.Valid && finishedAtStr.String != "" {
+		finishedAt, err := ccc.ParseSQLiteTimestamp(finishedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing finished_at timestamp: %w", err)
+		}
+		run.FinishedAt = &finishedAt
+	}
+
+	return run, nil
+}
+
+func formatOptionalTimestamp(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return ccc.FormatSQLiteTimestamp(*t)
+}
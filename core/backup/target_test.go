@@ -0,0 +1,144 @@
+package backup
+
+import (
+	"context"
+	"encoding/xml"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestApplyTargetRetention(t *testing.T) {
+	target := &memoryTarget{files: map[string][]byte{}}
+	for _, name := range []string{
+		"ff_backup_20250101_120000_auto.db",
+		"ff_backup_20250101_120000_auto.db.manifest.json",
+		"ff_backup_20250102_120000_manual.db.enc",
+		"ff_backup_20250103_120000_auto.db",
+		"notes.txt",
+	} {
+		target.files[name] = []byte("x")
+	}
+
+	deleted, err := applyTargetRetention(context.Background(), target, 2)
+	if err != nil {
+		t.Fatalf("applyTargetRetention failed: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("expected 1 deleted backup, got %d", deleted)
+	}
+
+	names, _ := target.List(context.Background())
+	sort.Strings(names)
+	expected := []string{"ff_backup_20250102_120000_manual.db.enc", "ff_backup_20250103_120000_auto.db", "notes.txt"}
+	if strings.Join(names, ",") != strings.Join(expected, ",") {
+		t.Fatalf("unexpected files after retention: %v", names)
+	}
+}
+
+func TestS3Target(t *testing.T) {
+	objects := map[string]string{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=access/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		key := strings.TrimPrefix(r.URL.Path, "/bucket/")
+		switch r.Method {
+		case http.MethodPut:
+			body, _ := io.ReadAll(r.Body)
+			objects[key] = string(body)
+		case http.MethodDelete:
+			delete(objects, key)
+			w.WriteHeader(http.StatusNoContent)
+		case http.MethodGet:
+			type content struct {
+				Key string `xml:"Key"`
+			}
+			var result struct {
+				XMLName  xml.Name  `xml:"ListBucketResult"`
+				Contents []content `xml:"Contents"`
+			}
+			for objectKey := range objects {
+				if strings.HasPrefix(objectKey, r.URL.Query().Get("prefix")) {
+					result.Contents = append(result.Contents, content{Key: objectKey})
+				}
+			}
+			xml.NewEncoder(w).Encode(result)
+		}
+	}))
+	defer server.Close()
+
+	serverUrl, _ := url.Parse(server.URL)
+	target, keep, err := ParseBackupTarget("s3://access:secret@" + serverUrl.Host + "/bucket/frozen?insecure=true&keep=3")
+	if err != nil {
+		t.Fatalf("ParseBackupTarget failed: %v", err)
+	}
+	if keep != 3 || strings.Contains(target.Name(), "secret") {
+		t.Fatalf("unexpected target %s with keep %d", target.Name(), keep)
+	}
+
+	ctx := context.Background()
+	if err := target.Upload(ctx, "ff_backup_20250101_120000_auto.db", strings.NewReader("data"), 4); err != nil {
+		t.Fatalf("Upload failed: %v", err)
+	}
+	if objects["frozen/ff_backup_20250101_120000_auto.db"] != "data" {
+		t.Fatalf("object not stored below the prefix: %v", objects)
+	}
+
+	names, err := target.List(ctx)
+	if err != nil || len(names) != 1 || names[0] != "ff_backup_20250101_120000_auto.db" {
+		t.Fatalf("unexpected listing %v: %v", names, err)
+	}
+
+	if err := target.Delete(ctx, names[0]); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if len(objects) != 0 {
+		t.Fatalf("object was not deleted: %v", objects)
+	}
+}
+
+// memoryTarget is a BackupTarget keeping files in memory
+type memoryTarget struct {
+	mutex sync.Mutex
+	files map[string][]byte
+}
+
+func (t *memoryTarget) Name() string {
+	return "memory"
+}
+
+func (t *memoryTarget) Upload(ctx context.Context, name string, src io.Reader, size int64) error {
+	data, err := io.ReadAll(src)
+	if err != nil {
+		return err
+	}
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.files[name] = data
+	return nil
+}
+
+func (t *memoryTarget) List(ctx context.Context) ([]string, error) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	names := make([]string, 0, len(t.files))
+	for name := range t.files {
+		names = append(names, name)
+	}
+	return names, nil
+}
+
+func (t *memoryTarget) Delete(ctx context.Context, name string) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.files, name)
+	return nil
+}
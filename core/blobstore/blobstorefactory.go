@@ -0,0 +1,32 @@
+package blobstore
+
+import (
+	"fmt"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// NewBlobStoreFromConfig creates the configured blob store. It returns nil if contents are stored in the database.
+func NewBlobStoreFromConfig(config ccc.BlobStoreConfig) (BlobStore, error) {
+	switch config.Type {
+	case "", StoreTypeDatabase:
+		return nil, nil
+	case StoreTypeFileSystem:
+		store, err := NewFileSystemBlobStore(config.Directory)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	case StoreTypeS3:
+		if config.S3Url == "" {
+			return nil, fmt.Errorf("the s3 blob store needs a bucket URL in %s", ccc.EnvBlobStoreS3Url)
+		}
+		store, err := NewS3BlobStore(config.S3Url)
+		if err != nil {
+			return nil, err
+		}
+		return store, nil
+	default:
+		return nil, fmt.Errorf("unsupported blob store type %q, use %s, %s or %s", config.Type, StoreTypeDatabase, StoreTypeFileSystem, StoreTypeS3)
+	}
+}
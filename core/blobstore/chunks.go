@@ -0,0 +1,127 @@
+package blobstore
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ChunkSize is the size of the chunks contents are split into before they are stored
+const ChunkSize = 4 * 1024 * 1024
+
+// referenceSeparator separates the hashes of the chunks in a reference
+const referenceSeparator = ","
+
+// HashBlob returns the hex encoded SHA-256 hash a blob is stored under
+func HashBlob(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+// IsValidHash reports whether a string is a hash produced by HashBlob. Stores reject other names,
+// so that a hash can never point outside of the store.
+func IsValidHash(hash string) bool {
+	if len(hash) != sha256.Size*2 {
+		return false
+	}
+	for _, c := range hash {
+		if !('0' <= c && c <= '9' || 'a' <= c && c <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// StoreChunks splits a content into chunks, stores them and returns a reference to the content,
+// which lists the hashes of the chunks in order. Only one chunk is held in memory at a time.
+func StoreChunks(ctx context.Context, store BlobStore, content io.Reader) (string, error) {
+	var hashes []string
+	chunk := make([]byte, ChunkSize)
+	for {
+		n, err := io.ReadFull(content, chunk)
+		if errors.Is(err, io.EOF) && len(hashes) > 0 {
+			break
+		}
+		if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+			return "", fmt.Errorf("failed to read content: %w", err)
+		}
+
+		hash := HashBlob(chunk[:n])
+		if err := store.Put(ctx, hash, chunk[:n]); err != nil {
+			return "", fmt.Errorf("failed to store chunk %d: %w", len(hashes)+1, err)
+		}
+		hashes = append(hashes, hash)
+
+		// A short chunk ends the content, an empty content is stored as one empty chunk
+		if n < ChunkSize {
+			break
+		}
+	}
+	return strings.Join(hashes, referenceSeparator), nil
+}
+
+// LoadChunks reads the chunks of a reference returned by StoreChunks and joins them.
+// Chunks whose content does not match their hash are rejected.
+func LoadChunks(ctx context.Context, store BlobStore, reference string) ([]byte, error) {
+	reader, err := OpenChunks(ctx, store, reference)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+// OpenChunks returns a reader for the content of a reference returned by StoreChunks.
+// The chunks are loaded one at a time while the content is read.
+func OpenChunks(ctx context.Context, store BlobStore, reference string) (io.Reader, error) {
+	hashes, err := ReferencedHashes(reference)
+	if err != nil {
+		return nil, err
+	}
+	return &chunkReader{ctx: ctx, store: store, hashes: hashes}, nil
+}
+
+// chunkReader reads the chunks of a content in order and verifies each of them against its hash
+type chunkReader struct {
+	ctx    context.Context
+	store  BlobStore
+	hashes []string
+	next   int
+	chunk  []byte
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.chunk) == 0 {
+		if r.next == len(r.hashes) {
+			return 0, io.EOF
+		}
+		hash := r.hashes[r.next]
+		chunk, err := r.store.Get(r.ctx, hash)
+		if err != nil {
+			return 0, fmt.Errorf("failed to load chunk %d: %w", r.next+1, err)
+		}
+		if HashBlob(chunk) != hash {
+			return 0, fmt.Errorf("chunk %d is corrupted", r.next+1)
+		}
+		r.chunk = chunk
+		r.next++
+	}
+
+	n := copy(p, r.chunk)
+	r.chunk = r.chunk[n:]
+	return n, nil
+}
+
+// ReferencedHashes returns the hashes of the chunks of a reference
+func ReferencedHashes(reference string) ([]string, error) {
+	hashes := strings.Split(reference, referenceSeparator)
+	for _, hash := range hashes {
+		if !IsValidHash(hash) {
+			return nil, fmt.Errorf("invalid blob reference")
+		}
+	}
+	return hashes, nil
+}
@@ -0,0 +1,71 @@
+package blobstore
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// DefaultBlobGarbageCollector deletes the blobs of a store that none of the reference sources uses anymore
+type DefaultBlobGarbageCollector struct {
+	store   BlobStore
+	sources []BlobReferenceSource
+	logger  ccc.Logger
+}
+
+// NewDefaultBlobGarbageCollector creates a new DefaultBlobGarbageCollector. Every source of references to the blobs
+// of the store has to be given, as blobs that are not referenced by any of them are deleted.
+func NewDefaultBlobGarbageCollector(store BlobStore, sources []BlobReferenceSource, logger ccc.Logger) *DefaultBlobGarbageCollector {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &DefaultBlobGarbageCollector{
+		store:   store,
+		sources: sources,
+		logger:  logger,
+	}
+}
+
+// CollectGarbage deletes the unreferenced blobs stored before the given time
+func (gc *DefaultBlobGarbageCollector) CollectGarbage(ctx context.Context, storedBefore time.Time, dryRun bool) (*GarbageCollectionSummary, error) {
+	// References are read before the blobs are listed. A blob stored in the meantime is younger than
+	// storedBefore, so it is kept even though its reference is missing.
+	referenced := make(map[string]bool)
+	for _, source := range gc.sources {
+		hashes, err := source.FindReferencedBlobs(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to find referenced blobs: %w", err)
+		}
+		for hash := range hashes {
+			referenced[hash] = true
+		}
+	}
+
+	summary := &GarbageCollectionSummary{}
+	err := gc.store.List(ctx, func(blob BlobInfo) error {
+		summary.Scanned++
+		if referenced[blob.Hash] {
+			return nil
+		}
+		summary.Unreferenced++
+		if dryRun || !blob.ModifiedAt.Before(storedBefore) {
+			return nil
+		}
+
+		if err := gc.store.Delete(ctx, blob.Hash); err != nil {
+			return fmt.Errorf("failed to delete blob %s: %w", blob.Hash, err)
+		}
+		summary.Deleted++
+		summary.DeletedBytes += blob.Size
+		return nil
+	})
+	if err != nil {
+		return summary, err
+	}
+
+	gc.logger.Info("Collected unreferenced blobs", "store", gc.store.Name(), "scanned", summary.Scanned,
+		"unreferenced", summary.Unreferenced, "deleted", summary.Deleted, "dry_run", dryRun)
+	return summary, nil
+}
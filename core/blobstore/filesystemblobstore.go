@@ -0,0 +1,133 @@
+package blobstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// FileSystemBlobStore stores blobs as files in a local directory. Blobs are spread over subdirectories
+// named after the first two characters of their hash, so that no directory holds too many files.
+type FileSystemBlobStore struct {
+	directory string
+}
+
+// NewFileSystemBlobStore creates a blob store in the given directory, which is created if it does not exist
+func NewFileSystemBlobStore(directory string) (*FileSystemBlobStore, error) {
+	if directory == "" {
+		return nil, fmt.Errorf("blob store directory is not set")
+	}
+	if err := os.MkdirAll(directory, 0700); err != nil {
+		return nil, fmt.Errorf("failed to create blob store directory: %w", err)
+	}
+	return &FileSystemBlobStore{directory: directory}, nil
+}
+
+// Name returns the directory of the store
+func (s *FileSystemBlobStore) Name() string {
+	return s.directory
+}
+
+// Put writes a blob to a temporary file and renames it, so that a blob is either complete or missing
+func (s *FileSystemBlobStore) Put(ctx context.Context, hash string, data []byte) error {
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return err
+	}
+
+	// Refresh the modification time of an existing blob, so that the garbage collection keeps it
+	// until the new reference to it has been committed
+	now := time.Now()
+	if err := os.Chtimes(path, now, now); err == nil {
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("failed to create blob directory: %w", err)
+	}
+	tempFile, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create blob file: %w", err)
+	}
+	defer os.Remove(tempFile.Name())
+
+	if _, err := tempFile.Write(data); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write blob file: %w", err)
+	}
+	if err := tempFile.Sync(); err != nil {
+		tempFile.Close()
+		return fmt.Errorf("failed to write blob file: %w", err)
+	}
+	if err := tempFile.Close(); err != nil {
+		return fmt.Errorf("failed to write blob file: %w", err)
+	}
+	if err := os.Rename(tempFile.Name(), path); err != nil {
+		return fmt.Errorf("failed to store blob file: %w", err)
+	}
+	return nil
+}
+
+// Get reads a blob
+func (s *FileSystemBlobStore) Get(ctx context.Context, hash string) ([]byte, error) {
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob file: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes a blob
+func (s *FileSystemBlobStore) Delete(ctx context.Context, hash string) error {
+	path, err := s.blobPath(hash)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to delete blob file: %w", err)
+	}
+	return nil
+}
+
+// List calls fn for every blob file. Temporary files of interrupted writes are skipped.
+func (s *FileSystemBlobStore) List(ctx context.Context, fn func(blob BlobInfo) error) error {
+	return filepath.WalkDir(s.directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if entry.IsDir() || !IsValidHash(entry.Name()) {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil // Deleted while listing
+		}
+		if err != nil {
+			return err
+		}
+		return fn(BlobInfo{Hash: entry.Name(), Size: info.Size(), ModifiedAt: info.ModTime()})
+	})
+}
+
+// blobPath returns the path of the file of a blob
+func (s *FileSystemBlobStore) blobPath(hash string) (string, error) {
+	if !IsValidHash(hash) {
+		return "", fmt.Errorf("invalid blob hash %q", hash)
+	}
+	return filepath.Join(s.directory, hash[:2], hash), nil
+}
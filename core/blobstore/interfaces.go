@@ -0,0 +1,41 @@
+package blobstore
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// BlobStore stores encrypted blobs under the SHA-256 hash of their content. Blobs are never modified,
+// storing a blob that exists already only refreshes its modification time. Blobs are only deleted by the
+// garbage collection once nothing references them anymore.
+type BlobStore interface {
+	// Name describes where the blobs are stored, for logs and messages
+	Name() string
+	// Put stores a blob under the hash of its content
+	Put(ctx context.Context, hash string, data []byte) error
+	// Get returns the content of a blob. It returns ErrBlobNotFound if the blob does not exist.
+	Get(ctx context.Context, hash string) ([]byte, error)
+	// Delete removes a blob. Deleting a blob that does not exist succeeds.
+	Delete(ctx context.Context, hash string) error
+	// List calls fn for every stored blob. Listing stops at the first error returned by fn.
+	List(ctx context.Context, fn func(blob BlobInfo) error) error
+}
+
+// BlobReferenceSource provides the hashes of all blobs that are still in use
+type BlobReferenceSource interface {
+	FindReferencedBlobs(ctx context.Context) (map[string]bool, error)
+}
+
+// BlobReferenceSourceFactory creates a BlobReferenceSource for the references held by a database,
+// e.g. by the copy of the database in a backup
+type BlobReferenceSourceFactory interface {
+	Create(db *sql.DB) BlobReferenceSource
+}
+
+// BlobGarbageCollector deletes blobs that are no longer referenced
+type BlobGarbageCollector interface {
+	// CollectGarbage deletes the unreferenced blobs that were stored before the given time. Younger blobs are kept,
+	// as they may belong to a file whose transaction has not been committed yet.
+	CollectGarbage(ctx context.Context, storedBefore time.Time, dryRun bool) (*GarbageCollectionSummary, error)
+}
@@ -0,0 +1,35 @@
+package blobstore
+
+import (
+	"errors"
+	"time"
+)
+
+// Blob store types
+const (
+	StoreTypeDatabase   = "database"   // Contents stay in the SQLite database, no blob store is used
+	StoreTypeFileSystem = "filesystem" // Blobs are files in a local directory
+	StoreTypeS3         = "s3"         // Blobs are objects in a bucket of an S3-compatible object storage
+)
+
+// GarbageCollectionGracePeriod is how long unreferenced blobs are kept, so that blobs stored by
+// transactions that have not been committed yet are not collected
+const GarbageCollectionGracePeriod = 24 * time.Hour
+
+// ErrBlobNotFound is returned when a requested blob does not exist
+var ErrBlobNotFound = errors.New("blob not found")
+
+// BlobInfo describes a stored blob
+type BlobInfo struct {
+	Hash       string
+	Size       int64
+	ModifiedAt time.Time // When the blob was last stored
+}
+
+// GarbageCollectionSummary describes the outcome of a garbage collection
+type GarbageCollectionSummary struct {
+	Scanned      int   // Number of stored blobs
+	Unreferenced int   // Number of stored blobs that are no longer referenced
+	Deleted      int   // Number of deleted blobs, which excludes young and, on dry runs, all unreferenced blobs
+	DeletedBytes int64 // Total size of the deleted blobs
+}
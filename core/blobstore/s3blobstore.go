@@ -0,0 +1,88 @@
+package blobstore
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// S3BlobStore stores blobs as objects in a bucket of an S3-compatible object storage such as AWS S3 or MinIO
+type S3BlobStore struct {
+	client *ccc.S3Client
+}
+
+// NewS3BlobStore creates a blob store from a URL like s3://ACCESS_KEY:SECRET_KEY@host/bucket/prefix
+func NewS3BlobStore(rawUrl string) (*S3BlobStore, error) {
+	s3Url, err := url.Parse(rawUrl)
+	if err != nil {
+		// The error of url.Parse contains the URL and therefore possibly credentials
+		return nil, fmt.Errorf("invalid blob store S3 URL")
+	}
+	if s3Url.Scheme != "s3" || s3Url.Host == "" {
+		return nil, fmt.Errorf("blob store S3 URL %s must look like s3://ACCESS_KEY:SECRET_KEY@host/bucket", ccc.RedactUrl(s3Url))
+	}
+
+	client, err := ccc.NewS3Client(s3Url)
+	if err != nil {
+		return nil, err
+	}
+	return &S3BlobStore{client: client}, nil
+}
+
+// Name returns the endpoint, bucket and prefix of the store
+func (s *S3BlobStore) Name() string {
+	return s.client.Name()
+}
+
+// Put uploads a blob. Uploading an existing blob again refreshes its modification time.
+func (s *S3BlobStore) Put(ctx context.Context, hash string, data []byte) error {
+	if !IsValidHash(hash) {
+		return fmt.Errorf("invalid blob hash %q", hash)
+	}
+	return s.client.PutObject(ctx, hash, bytes.NewReader(data), int64(len(data)))
+}
+
+// Get downloads a blob
+func (s *S3BlobStore) Get(ctx context.Context, hash string) ([]byte, error) {
+	if !IsValidHash(hash) {
+		return nil, fmt.Errorf("invalid blob hash %q", hash)
+	}
+
+	object, err := s.client.GetObject(ctx, hash)
+	if errors.Is(err, ccc.ErrS3ObjectNotFound) {
+		return nil, ErrBlobNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer object.Close()
+
+	data, err := io.ReadAll(object)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download blob: %w", err)
+	}
+	return data, nil
+}
+
+// Delete removes a blob
+func (s *S3BlobStore) Delete(ctx context.Context, hash string) error {
+	if !IsValidHash(hash) {
+		return fmt.Errorf("invalid blob hash %q", hash)
+	}
+	return s.client.DeleteObject(ctx, hash)
+}
+
+// List calls fn for every blob in the bucket. Other objects below the prefix are skipped.
+func (s *S3BlobStore) List(ctx context.Context, fn func(blob BlobInfo) error) error {
+	return s.client.ListObjects(ctx, "", func(object ccc.S3Object) error {
+		if !IsValidHash(object.Name) {
+			return nil
+		}
+		return fn(BlobInfo{Hash: object.Name, Size: object.Size, ModifiedAt: object.LastModified})
+	})
+}
@@ -24,13 +24,23 @@ const (
 	EnvRedisNetwork         = "FF_REDIS_NETWORK"
 	EnvSigningKey           = "FF_SIGNING_KEY"
 	EnvEncryptionKey        = "FF_ENCRYPTION_KEY"
+	EnvOcrJobKey            = "FF_OCR_JOB_KEY"
 	EnvKeyDir               = "FF_KEY_DIR"
 	EnvWebUIPort            = "FF_WEB_UI_PORT"
 	EnvLogLevel             = "FF_LOG_LEVEL"
 	EnvBackupEnabled        = "FF_BACKUP_ENABLED"
 	EnvBackupIntervalDays   = "FF_BACKUP_INTERVAL_DAYS"
+	EnvBackupSchedule       = "FF_BACKUP_SCHEDULE"
 	EnvBackupDirectory      = "FF_BACKUP_DIRECTORY"
 	EnvBackupMaxGenerations = "FF_BACKUP_MAX_GENERATIONS"
+	EnvBackupPassphrase     = "FF_BACKUP_PASSPHRASE"
+	EnvBackupKeyFile        = "FF_BACKUP_KEY_FILE"
+	EnvBackupTargets        = "FF_BACKUP_TARGETS"
+	EnvBackupKeepDaily      = "FF_BACKUP_KEEP_DAILY"
+	EnvBackupKeepWeekly     = "FF_BACKUP_KEEP_WEEKLY"
+	EnvBackupKeepMonthly    = "FF_BACKUP_KEEP_MONTHLY"
+	EnvBackupKeepYearly     = "FF_BACKUP_KEEP_YEARLY"
+	EnvBackupPruneManual    = "FF_BACKUP_PRUNE_MANUAL"
 	EnvOcrEnabled           = "FF_OCR_ENABLED"
 	EnvOCRProvider          = "FF_OCR_PROVIDER"
 	EnvOCRLanguages         = "FF_OCR_LANGUAGES"
@@ -42,14 +52,46 @@ const (
 	EnvOCRRetryInitial      = "FF_OCR_RETRY_INITIAL_BACKOFF_SECONDS"
 	EnvOCRRetryMax          = "FF_OCR_RETRY_MAX_BACKOFF_SECONDS"
 	EnvOCRImageMaxDimension = "FF_OCR_IMAGE_MAX_DIMENSION"
+	EnvOCRWorkers           = "FF_OCR_WORKERS"
+	EnvPDFRendererPath      = "FF_PDF_RENDERER_PATH"
+	EnvPDFRenderDPI         = "FF_PDF_RENDER_DPI"
+	EnvPDFMaxOCRPages       = "FF_PDF_MAX_OCR_PAGES"
+	EnvImageConverterPath   = "FF_IMAGE_CONVERTER_PATH"
+	EnvBlobStore            = "FF_BLOB_STORE"
+	EnvBlobStoreDirectory   = "FF_BLOB_STORE_DIRECTORY"
+	EnvBlobStoreS3Url       = "FF_BLOB_STORE_S3_URL"
+	EnvBlobGCIntervalHours  = "FF_BLOB_GC_INTERVAL_HOURS"
+	EnvMaxUploadSizeMB      = "FF_MAX_UPLOAD_SIZE_MB"
+	EnvWebAuthnRPID         = "FF_WEBAUTHN_RP_ID"
+	EnvWebAuthnRPName       = "FF_WEBAUTHN_RP_NAME"
+	EnvWebAuthnOrigins      = "FF_WEBAUTHN_ORIGINS"
+	EnvHealthToken          = "FF_HEALTH_TOKEN"
+	EnvSecretHistoryLimit   = "FF_SECRET_HISTORY_LIMIT"
+	EnvTrashRetentionDays   = "FF_TRASH_RETENTION_DAYS"
+	EnvReminderLeadDays     = "FF_REMINDER_LEAD_DAYS"
+	EnvSMTPHost             = "FF_SMTP_HOST"
+	EnvSMTPPort             = "FF_SMTP_PORT"
+	EnvSMTPUsername         = "FF_SMTP_USERNAME"
+	EnvSMTPPassword         = "FF_SMTP_PASSWORD"
+	EnvSMTPFrom             = "FF_SMTP_FROM"
+	EnvSMTPSecurity         = "FF_SMTP_SECURITY"
 )
 
 // BackupConfig contains all backup-related configuration settings
 type BackupConfig struct {
-	Enabled        bool   // Enable/disable backup functionality
-	IntervalDays   int    // Backup interval in days (0 = disabled)
-	Directory      string // Directory where backup files are stored
-	MaxGenerations int    // Maximum number of backup files to keep
+	Enabled        bool     // Enable/disable backup functionality
+	IntervalDays   int      // Backup interval in days (0 = disabled), used if Schedule is empty
+	Schedule       string   // Cron expression, macro like @daily or time of day like 03:00 for automatic backups
+	Directory      string   // Directory where backup files are stored
+	MaxGenerations int      // Number of newest backups to keep regardless of the rules below
+	KeepDaily      int      // Keep the newest backup of each of the last N days with backups (0 = rule disabled)
+	KeepWeekly     int      // Keep the newest backup of each of the last N weeks with backups (0 = rule disabled)
+	KeepMonthly    int      // Keep the newest backup of each of the last N months with backups (0 = rule disabled)
+	KeepYearly     int      // Keep the newest backup of each of the last N years with backups (0 = rule disabled)
+	PruneManual    bool     // Let cleanup delete manual backups, which are otherwise kept forever
+	Passphrase     string   `json:"-"` // Passphrase encrypting new backups (empty = unencrypted unless KeyFile is set)
+	KeyFile        string   // Path of a key file encrypting new backups, alternative to Passphrase
+	Targets        []string `json:"-"` // URLs of off-site targets backups are uploaded to (may contain credentials)
 }
 
 // OCRConfig contains OCR-related configuration settings
@@ -61,10 +103,43 @@ type OCRConfig struct {
 	OllamaModel                string   // Ollama model name for GLM OCR
 	OllamaKeepAlive            string   // Ollama keep_alive value
 	OllamaTimeoutSeconds       int      // Timeout for Ollama requests
-	MaxAttempts                int      // Best-effort OCR attempts per upload goroutine
+	MaxAttempts                int      // OCR attempts per queued file before it is marked as failed
 	RetryInitialBackoffSeconds int      // Initial retry backoff
 	RetryMaxBackoffSeconds     int      // Maximum retry backoff
 	ImageMaxDimension          int      // Max image width/height before Ollama OCR
+	Workers                    int      // Number of background OCR workers processing the job queue
+}
+
+// PDFConfig contains the settings for rendering PDF pages to images
+type PDFConfig struct {
+	RendererPath string // Path or name of the pdftoppm executable (empty = pages are not rendered, set with "none")
+	RenderDPI    int    // Resolution pages without a text layer are rendered at for OCR
+	MaxOCRPages  int    // Maximum number of pages without a text layer sent to OCR per PDF or TIFF file
+}
+
+// BlobStoreConfig contains the settings of the store for the encrypted contents and previews of document files
+type BlobStoreConfig struct {
+	Type            string // Where contents are stored: database (inside SQLite), filesystem or s3
+	Directory       string // Directory of the filesystem store
+	S3Url           string `json:"-"` // URL of the bucket of the s3 store (contains credentials)
+	GCIntervalHours int    // Interval in which unreferenced blobs are deleted (0 = garbage collection disabled)
+}
+
+// WebAuthnConfig contains the relying party settings for passkeys
+type WebAuthnConfig struct {
+	RPID    string   // Relying party ID (domain), derived from the request host if empty
+	RPName  string   // Relying party name shown by browsers
+	Origins []string // Allowed origins, derived from the request if empty
+}
+
+// SMTPConfig contains the settings of the mail relay used to send reminders
+type SMTPConfig struct {
+	Host     string // Host name of the relay (empty = no mails are sent)
+	Port     int    // Port of the relay
+	Username string // User name for authentication (empty = no authentication)
+	Password string `json:"-"` // Password for authentication
+	From     string // Sender address of the mails
+	Security string // Connection security: starttls, tls or none (e.g. for a local mail catcher)
 }
 
 type AppConfig struct {
@@ -81,6 +156,7 @@ type AppConfig struct {
 
 	SigningKey    string // Session signing key
 	EncryptionKey string // Session encryption key
+	OcrJobKey     string // Key wrapping the MEK of queued text extraction jobs
 	KeyDir        string // Directory to store persistent key files
 
 	WebUiPort int    // Port for the Web UI server
@@ -88,6 +164,24 @@ type AppConfig struct {
 
 	Backup BackupConfig // Backup configuration
 	OCR    OCRConfig    // OCR configuration
+	PDF    PDFConfig    // PDF rendering configuration
+
+	ImageConverterPath string // Path or name of the ImageMagick executable converting HEIC images (empty = HEIC images get no previews and no OCR)
+
+	BlobStore BlobStoreConfig // Storage of file contents and previews
+
+	MaxUploadSizeMB int // Maximum size of an uploaded document file in MB
+
+	WebAuthn WebAuthnConfig // Passkey configuration
+
+	HealthToken string `json:"-"` // Bearer token for the admin health endpoint (empty = endpoint disabled)
+
+	SecretHistoryLimit int // Number of prior versions kept per secret (0 = history disabled)
+
+	TrashRetentionDays int // Days deleted items stay in the trash before they are purged (0 = never purged)
+
+	ReminderLeadDays int        // Days before an expiry or rotation date users are reminded of it
+	SMTP             SMTPConfig // Mail relay for reminders
 }
 
 // String returns a JSON representation of the AppConfig.
@@ -99,6 +193,25 @@ func (c AppConfig) String() string {
 	return string(data)
 }
 
+// MaxDatabaseUploadSizeMB caps the upload size while contents are stored in the database,
+// since SQLite rows are written and read as a whole and a file is then held in memory
+const MaxDatabaseUploadSizeMB = 30
+
+// UsesDatabase reports whether contents are stored in the database instead of a blob store
+func (c BlobStoreConfig) UsesDatabase() bool {
+	return c.Type == "" || c.Type == "database"
+}
+
+// MaxUploadSize returns the maximum size of an uploaded document file in bytes.
+// Without a blob store, it is capped at MaxDatabaseUploadSizeMB.
+func (c AppConfig) MaxUploadSize() int64 {
+	sizeMB := c.MaxUploadSizeMB
+	if c.BlobStore.UsesDatabase() {
+		sizeMB = min(sizeMB, MaxDatabaseUploadSizeMB)
+	}
+	return int64(sizeMB) * 1024 * 1024
+}
+
 var DefaultConfig = AppConfig{
 	DatabasePath:        filepath.Join(GetUserDataDir(), "frozenfortress.db"),
 	MaxSignInAttempts:   3,
@@ -110,6 +223,7 @@ var DefaultConfig = AppConfig{
 	RedisNetwork:        "tcp",
 	SigningKey:          "",
 	EncryptionKey:       "",
+	OcrJobKey:           "",
 	KeyDir:              "",
 	WebUiPort:           8080,   // Default Web UI port
 	LogLevel:            "Info", // Default log level
@@ -131,6 +245,32 @@ var DefaultConfig = AppConfig{
 		RetryInitialBackoffSeconds: 2,
 		RetryMaxBackoffSeconds:     30,
 		ImageMaxDimension:          640,
+		Workers:                    2,
+	},
+	PDF: PDFConfig{
+		RendererPath: "pdftoppm",
+		RenderDPI:    200,
+		MaxOCRPages:  50,
+	},
+	ImageConverterPath: "convert",
+	BlobStore: BlobStoreConfig{
+		Type:            "database",
+		Directory:       filepath.Join(GetUserDataDir(), "blobs"),
+		GCIntervalHours: 24,
+	},
+	MaxUploadSizeMB: 100,
+	WebAuthn: WebAuthnConfig{
+		RPID:    "", // Derived from the request host
+		RPName:  "Frozen Fortress",
+		Origins: nil, // Derived from the request
+	},
+	SecretHistoryLimit: 10, // Keep the 10 newest prior versions of each secret
+	TrashRetentionDays: 30, // Purge deleted items after 30 days
+	ReminderLeadDays:   14, // Remind users two weeks ahead
+	SMTP: SMTPConfig{
+		Port:     587,
+		From:     "frozenfortress@localhost",
+		Security: "starttls",
 	},
 }
 
@@ -182,6 +322,9 @@ func LoadConfigFromEnv() AppConfig {
 	if encKey := os.Getenv(EnvEncryptionKey); encKey != "" {
 		config.EncryptionKey = encKey
 	}
+	if ocrJobKey := os.Getenv(EnvOcrJobKey); ocrJobKey != "" {
+		config.OcrJobKey = ocrJobKey
+	}
 	if keyDir := os.Getenv(EnvKeyDir); keyDir != "" {
 		config.KeyDir = keyDir
 	}
@@ -207,6 +350,9 @@ func LoadConfigFromEnv() AppConfig {
 			config.Backup.IntervalDays = interval
 		}
 	}
+	if backupSchedule := os.Getenv(EnvBackupSchedule); backupSchedule != "" {
+		config.Backup.Schedule = backupSchedule
+	}
 	if backupDir := os.Getenv(EnvBackupDirectory); backupDir != "" {
 		config.Backup.Directory = backupDir
 	}
@@ -215,6 +361,43 @@ func LoadConfigFromEnv() AppConfig {
 			config.Backup.MaxGenerations = generations
 		}
 	}
+	if keepDaily := os.Getenv(EnvBackupKeepDaily); keepDaily != "" {
+		if keep, err := strconv.Atoi(keepDaily); err == nil && keep >= 0 {
+			config.Backup.KeepDaily = keep
+		}
+	}
+	if keepWeekly := os.Getenv(EnvBackupKeepWeekly); keepWeekly != "" {
+		if keep, err := strconv.Atoi(keepWeekly); err == nil && keep >= 0 {
+			config.Backup.KeepWeekly = keep
+		}
+	}
+	if keepMonthly := os.Getenv(EnvBackupKeepMonthly); keepMonthly != "" {
+		if keep, err := strconv.Atoi(keepMonthly); err == nil && keep >= 0 {
+			config.Backup.KeepMonthly = keep
+		}
+	}
+	if keepYearly := os.Getenv(EnvBackupKeepYearly); keepYearly != "" {
+		if keep, err := strconv.Atoi(keepYearly); err == nil && keep >= 0 {
+			config.Backup.KeepYearly = keep
+		}
+	}
+	if pruneManual := os.Getenv(EnvBackupPruneManual); pruneManual != "" {
+		config.Backup.PruneManual = pruneManual == "true"
+	}
+	if backupPassphrase := os.Getenv(EnvBackupPassphrase); backupPassphrase != "" {
+		config.Backup.Passphrase = backupPassphrase
+	}
+	if backupKeyFile := os.Getenv(EnvBackupKeyFile); backupKeyFile != "" {
+		config.Backup.KeyFile = backupKeyFile
+	}
+	if backupTargets := os.Getenv(EnvBackupTargets); backupTargets != "" {
+		config.Backup.Targets = nil
+		for _, target := range strings.Split(backupTargets, ",") {
+			if target = strings.TrimSpace(target); target != "" {
+				config.Backup.Targets = append(config.Backup.Targets, target)
+			}
+		}
+	}
 
 	// OCR configuration
 	if ocrEnabled := os.Getenv(EnvOcrEnabled); ocrEnabled != "" {
@@ -273,6 +456,118 @@ func LoadConfigFromEnv() AppConfig {
 			config.OCR.ImageMaxDimension = pixels
 		}
 	}
+	if workers := os.Getenv(EnvOCRWorkers); workers != "" {
+		if count, err := strconv.Atoi(workers); err == nil && count > 0 {
+			config.OCR.Workers = count
+		}
+	}
+
+	// PDF configuration
+	if rendererPath := os.Getenv(EnvPDFRendererPath); rendererPath != "" {
+		config.PDF.RendererPath = strings.TrimSpace(rendererPath)
+		if strings.EqualFold(config.PDF.RendererPath, "none") {
+			config.PDF.RendererPath = ""
+		}
+	}
+	if renderDPI := os.Getenv(EnvPDFRenderDPI); renderDPI != "" {
+		if dpi, err := strconv.Atoi(renderDPI); err == nil && dpi > 0 {
+			config.PDF.RenderDPI = dpi
+		}
+	}
+	if maxOCRPages := os.Getenv(EnvPDFMaxOCRPages); maxOCRPages != "" {
+		if pages, err := strconv.Atoi(maxOCRPages); err == nil && pages >= 0 {
+			config.PDF.MaxOCRPages = pages
+		}
+	}
+
+	if converterPath := os.Getenv(EnvImageConverterPath); converterPath != "" {
+		config.ImageConverterPath = strings.TrimSpace(converterPath)
+		if strings.EqualFold(config.ImageConverterPath, "none") {
+			config.ImageConverterPath = ""
+		}
+	}
+
+	// Blob store configuration
+	if blobStore := os.Getenv(EnvBlobStore); blobStore != "" {
+		config.BlobStore.Type = strings.ToLower(strings.TrimSpace(blobStore))
+	}
+	if blobDirectory := os.Getenv(EnvBlobStoreDirectory); blobDirectory != "" {
+		config.BlobStore.Directory = blobDirectory
+	}
+	if blobS3Url := os.Getenv(EnvBlobStoreS3Url); blobS3Url != "" {
+		config.BlobStore.S3Url = strings.TrimSpace(blobS3Url)
+	}
+	if gcInterval := os.Getenv(EnvBlobGCIntervalHours); gcInterval != "" {
+		if hours, err := strconv.Atoi(gcInterval); err == nil && hours >= 0 {
+			config.BlobStore.GCIntervalHours = hours
+		}
+	}
+
+	if maxUploadSize := os.Getenv(EnvMaxUploadSizeMB); maxUploadSize != "" {
+		if sizeMB, err := strconv.Atoi(maxUploadSize); err == nil && sizeMB > 0 {
+			config.MaxUploadSizeMB = sizeMB
+		}
+	}
+
+	// WebAuthn configuration
+	if rpId := os.Getenv(EnvWebAuthnRPID); rpId != "" {
+		config.WebAuthn.RPID = strings.TrimSpace(rpId)
+	}
+	if rpName := os.Getenv(EnvWebAuthnRPName); rpName != "" {
+		config.WebAuthn.RPName = rpName
+	}
+	if origins := os.Getenv(EnvWebAuthnOrigins); origins != "" {
+		var cleanOrigins []string
+		for _, origin := range strings.Split(origins, ",") {
+			if trimmed := strings.TrimRight(strings.TrimSpace(origin), "/"); trimmed != "" {
+				cleanOrigins = append(cleanOrigins, trimmed)
+			}
+		}
+		config.WebAuthn.Origins = cleanOrigins
+	}
+	if healthToken := os.Getenv(EnvHealthToken); healthToken != "" {
+		config.HealthToken = healthToken
+	}
+	if historyLimit := os.Getenv(EnvSecretHistoryLimit); historyLimit != "" {
+		if limit, err := strconv.Atoi(historyLimit); err == nil && limit >= 0 {
+			config.SecretHistoryLimit = limit
+		}
+	}
+	if retentionDays := os.Getenv(EnvTrashRetentionDays); retentionDays != "" {
+		if days, err := strconv.Atoi(retentionDays); err == nil && days >= 0 {
+			config.TrashRetentionDays = days
+		}
+	}
+
+	// Reminder configuration
+	if leadDays := os.Getenv(EnvReminderLeadDays); leadDays != "" {
+		if days, err := strconv.Atoi(leadDays); err == nil && days >= 0 {
+			config.ReminderLeadDays = days
+		}
+	}
+	if smtpHost := os.Getenv(EnvSMTPHost); smtpHost != "" {
+		config.SMTP.Host = strings.TrimSpace(smtpHost)
+	}
+	if smtpPort := os.Getenv(EnvSMTPPort); smtpPort != "" {
+		if port, err := strconv.Atoi(smtpPort); err == nil && port > 0 {
+			config.SMTP.Port = port
+		}
+	}
+	if smtpUsername := os.Getenv(EnvSMTPUsername); smtpUsername != "" {
+		config.SMTP.Username = smtpUsername
+	}
+	if smtpPassword := os.Getenv(EnvSMTPPassword); smtpPassword != "" {
+		config.SMTP.Password = smtpPassword
+	}
+	if smtpFrom := os.Getenv(EnvSMTPFrom); smtpFrom != "" {
+		config.SMTP.From = strings.TrimSpace(smtpFrom)
+	}
+	if smtpSecurity := os.Getenv(EnvSMTPSecurity); smtpSecurity != "" {
+		switch security := strings.ToLower(strings.TrimSpace(smtpSecurity)); security {
+		case "starttls", "tls", "none":
+			config.SMTP.Security = security
+		}
+	}
 
 	return config
 }
@@ -16,6 +16,7 @@ const (
 	ErrCodeInternalError    ErrorCode = "INTERNAL_ERROR"
 	ErrCodeOperationFailed  ErrorCode = "OPERATION_FAILED"
 	ErrCodeUserNameTaken    ErrorCode = "USERNAME_TAKEN"
+	ErrCodeConflict         ErrorCode = "CONFLICT"
 )
 
 // ApiError represents application-specific errors with both user-friendly and technical details
@@ -142,6 +143,16 @@ func NewForbiddenError(message string) *ApiError {
 	}
 }
 
+// NewConflictError creates an error for a request that conflicts with the current state of a resource
+func NewConflictError(userMessage, technicalMessage string) *ApiError {
+	return &ApiError{
+		StatusCode:       409,
+		Code:             ErrCodeConflict,
+		UserMessage:      userMessage,
+		TechnicalMessage: technicalMessage,
+	}
+}
+
 // Helper functions for error checking
 
 // IsApiError checks if an error is an ApiError and returns it
@@ -0,0 +1,399 @@
+package ccc
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Migration describes a single versioned schema change.
+// Versions must be unique and positive; migrations are applied in ascending version order.
+// Up and Down hold plain SQL. UpFunc and DownFunc are optional hooks for changes that cannot be
+// expressed idempotently in SQL (e.g. adding a column only if it is missing). UpFunc runs after Up,
+// DownFunc runs before Down. Both run within the same transaction as the SQL.
+//
+// Code cannot be hashed, so the checksum covers UpFunc and DownFunc only through FuncRevision.
+// A migration that has been released must not change what its hooks do; if a hook has to be fixed
+// anyway, FuncRevision must be incremented so that databases which already applied it are detected.
+type Migration struct {
+	Version      int
+	Name         string
+	Up           string
+	Down         string
+	UpFunc       func(tx *sql.Tx) error
+	DownFunc     func(tx *sql.Tx) error
+	FuncRevision int // Revision of UpFunc and DownFunc, starting at 0
+}
+
+// Checksum returns a SHA-256 checksum of the migration's SQL and the revision of its hooks.
+// It is recorded when the migration is applied to detect migrations that were modified afterwards.
+func (m Migration) Checksum() string {
+	content := fmt.Sprintf("%d\n%s\n%s\n--down--\n%s", m.Version, m.Name, m.Up, m.Down)
+	// Revision 0 is left out, so that the checksums recorded before revisions existed stay valid
+	if m.FuncRevision > 0 {
+		content += fmt.Sprintf("\n--func-revision--\n%d", m.FuncRevision)
+	}
+	hash := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(hash[:])
+}
+
+// MigrationStatus describes the state of a migration in a specific database.
+type MigrationStatus struct {
+	Version          int
+	Name             string
+	Applied          bool
+	AppliedAt        time.Time
+	Checksum         string // Checksum recorded in the database, or the current checksum if not applied
+	ChecksumMismatch bool   // True if the migration was modified after it was applied
+	Unknown          bool   // True if the migration is recorded in the database but not known to this build
+}
+
+// MigrationRunner applies and reverts versioned schema migrations.
+type MigrationRunner interface {
+	Status() ([]MigrationStatus, error)
+	Up() ([]MigrationStatus, error)
+	Down(steps int) ([]MigrationStatus, error)
+}
+
+// SQLiteMigrationRunner implements MigrationRunner for SQLite databases.
+// Applied migrations are tracked in the schema_migrations table. Each migration runs in its own transaction.
+type SQLiteMigrationRunner struct {
+	db         *sql.DB
+	migrations []Migration
+	logger     Logger
+}
+
+// NewSQLiteMigrationRunner creates a new SQLiteMigrationRunner for the given set of migrations.
+func NewSQLiteMigrationRunner(db *sql.DB, migrations []Migration, logger Logger) *SQLiteMigrationRunner {
+	if logger == nil {
+		logger = NopLogger
+	}
+
+	sorted := make([]Migration, len(migrations))
+	copy(sorted, migrations)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].Version < sorted[j].Version
+	})
+
+	return &SQLiteMigrationRunner{
+		db:         db,
+		migrations: sorted,
+		logger:     logger,
+	}
+}
+
+// appliedMigration is a row of the schema_migrations table
+type appliedMigration struct {
+	version   int
+	name      string
+	checksum  string
+	appliedAt time.Time
+}
+
+// Status returns the status of all known and applied migrations, ordered by version.
+func (r *SQLiteMigrationRunner) Status() ([]MigrationStatus, error) {
+	if err := r.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := r.ensureMigrationsTable(); err != nil {
+		return nil, err
+	}
+
+	applied, err := r.loadApplied()
+	if err != nil {
+		return nil, err
+	}
+
+	var statuses []MigrationStatus
+	known := make(map[int]bool, len(r.migrations))
+
+	for _, migration := range r.migrations {
+		known[migration.Version] = true
+
+		status := MigrationStatus{
+			Version:  migration.Version,
+			Name:     migration.Name,
+			Checksum: migration.Checksum(),
+		}
+
+		if record, ok := applied[migration.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = record.appliedAt
+			status.ChecksumMismatch = record.checksum != status.Checksum
+			status.Checksum = record.checksum
+		}
+
+		statuses = append(statuses, status)
+	}
+
+	for version, record := range applied {
+		if known[version] {
+			continue
+		}
+		statuses = append(statuses, MigrationStatus{
+			Version:   record.version,
+			Name:      record.name,
+			Applied:   true,
+			AppliedAt: record.appliedAt,
+			Checksum:  record.checksum,
+			Unknown:   true,
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+
+	return statuses, nil
+}
+
+// Up applies all pending migrations in ascending version order and returns the migrations that were applied.
+// It refuses to run if an applied migration was modified after it was applied.
+func (r *SQLiteMigrationRunner) Up() ([]MigrationStatus, error) {
+	statuses, err := r.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkIntegrity(statuses); err != nil {
+		return nil, err
+	}
+
+	pending := make(map[int]bool)
+	for _, status := range statuses {
+		if !status.Applied {
+			pending[status.Version] = true
+		}
+	}
+
+	var appliedNow []MigrationStatus
+
+	for _, migration := range r.migrations {
+		if !pending[migration.Version] {
+			continue
+		}
+
+		r.logger.Info("Applying schema migration", "version", migration.Version, "name", migration.Name)
+
+		appliedAt, err := r.apply(migration)
+		if err != nil {
+			r.logger.Error("Schema migration failed", "version", migration.Version, "name", migration.Name, "error", err)
+			return appliedNow, NewDatabaseError(fmt.Sprintf("apply migration %d (%s)", migration.Version, migration.Name), err)
+		}
+
+		appliedNow = append(appliedNow, MigrationStatus{
+			Version:   migration.Version,
+			Name:      migration.Name,
+			Applied:   true,
+			AppliedAt: appliedAt,
+			Checksum:  migration.Checksum(),
+		})
+	}
+
+	if len(appliedNow) > 0 {
+		r.logger.Info("Schema migrations applied", "count", len(appliedNow))
+	} else {
+		r.logger.Debug("Database schema is up to date")
+	}
+
+	return appliedNow, nil
+}
+
+// Down reverts the given number of most recently applied migrations in descending version order
+// and returns the migrations that were reverted.
+func (r *SQLiteMigrationRunner) Down(steps int) ([]MigrationStatus, error) {
+	if steps <= 0 {
+		return nil, NewInvalidInputError("steps", "must be greater than zero")
+	}
+
+	statuses, err := r.Status()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := r.checkIntegrity(statuses); err != nil {
+		return nil, err
+	}
+
+	migrationsByVersion := make(map[int]Migration, len(r.migrations))
+	for _, migration := range r.migrations {
+		migrationsByVersion[migration.Version] = migration
+	}
+
+	var reverted []MigrationStatus
+
+	for i := len(statuses) - 1; i >= 0 && len(reverted) < steps; i-- {
+		status := statuses[i]
+		if !status.Applied {
+			continue
+		}
+
+		migration := migrationsByVersion[status.Version]
+
+		r.logger.Info("Reverting schema migration", "version", migration.Version, "name", migration.Name)
+
+		if err := r.revert(migration); err != nil {
+			r.logger.Error("Schema migration revert failed", "version", migration.Version, "name", migration.Name, "error", err)
+			return reverted, NewDatabaseError(fmt.Sprintf("revert migration %d (%s)", migration.Version, migration.Name), err)
+		}
+
+		status.Applied = false
+		reverted = append(reverted, status)
+	}
+
+	r.logger.Info("Schema migrations reverted", "count", len(reverted))
+
+	return reverted, nil
+}
+
+// validate ensures that migration versions are positive and unique
+func (r *SQLiteMigrationRunner) validate() error {
+	seen := make(map[int]bool, len(r.migrations))
+	for _, migration := range r.migrations {
+		if migration.Version <= 0 {
+			return NewInternalError(fmt.Sprintf("invalid migration version %d", migration.Version), nil)
+		}
+		if seen[migration.Version] {
+			return NewInternalError(fmt.Sprintf("duplicate migration version %d", migration.Version), nil)
+		}
+		seen[migration.Version] = true
+	}
+	return nil
+}
+
+// checkIntegrity ensures that no applied migration was modified or is unknown to this build
+func (r *SQLiteMigrationRunner) checkIntegrity(statuses []MigrationStatus) error {
+	for _, status := range statuses {
+		if status.ChecksumMismatch {
+			return NewOperationFailedError("migrate database schema",
+				fmt.Sprintf("migration %d (%s) was modified after it was applied", status.Version, status.Name))
+		}
+		if status.Unknown {
+			return NewOperationFailedError("migrate database schema",
+				fmt.Sprintf("migration %d (%s) is applied but unknown to this version of the application", status.Version, status.Name))
+		}
+	}
+	return nil
+}
+
+// ensureMigrationsTable creates the schema_migrations table if it doesn't exist
+func (r *SQLiteMigrationRunner) ensureMigrationsTable() error {
+	query := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	);
+	`
+	if _, err := r.db.Exec(query); err != nil {
+		return NewDatabaseError("create schema_migrations table", err)
+	}
+	return nil
+}
+
+// loadApplied reads all applied migrations from the schema_migrations table
+func (r *SQLiteMigrationRunner) loadApplied() (map[int]appliedMigration, error) {
+	rows, err := r.db.Query(`SELECT version, name, checksum, applied_at FROM schema_migrations`)
+	if err != nil {
+		return nil, NewDatabaseError("query schema_migrations", err)
+	}
+	defer rows.Close()
+
+	applied := make(map[int]appliedMigration)
+	for rows.Next() {
+		var record appliedMigration
+		var appliedAtStr string
+
+		if err := rows.Scan(&record.version, &record.name, &record.checksum, &appliedAtStr); err != nil {
+			return nil, NewDatabaseError("scan schema_migrations row", err)
+		}
+
+		record.appliedAt, err = ParseSQLiteTimestamp(appliedAtStr)
+		if err != nil {
+			return nil, NewDatabaseError("parse schema migration timestamp", err)
+		}
+
+		applied[record.version] = record
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, NewDatabaseError("iterate schema_migrations rows", err)
+	}
+
+	return applied, nil
+}
+
+// apply runs a single migration and records it, all within one transaction
+func (r *SQLiteMigrationRunner) apply(migration Migration) (time.Time, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() succeeds
+
+	if migration.Up != "" {
+		if _, err := tx.Exec(migration.Up); err != nil {
+			return time.Time{}, fmt.Errorf("executing up script: %w", err)
+		}
+	}
+
+	if migration.UpFunc != nil {
+		if err := migration.UpFunc(tx); err != nil {
+			return time.Time{}, fmt.Errorf("executing up function: %w", err)
+		}
+	}
+
+	appliedAt := time.Now()
+	_, err = tx.Exec(
+		`INSERT INTO schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)`,
+		migration.Version,
+		migration.Name,
+		migration.Checksum(),
+		FormatSQLiteTimestamp(appliedAt),
+	)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("recording migration: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return time.Time{}, fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return appliedAt, nil
+}
+
+// revert reverts a single migration and removes its record, all within one transaction
+func (r *SQLiteMigrationRunner) revert(migration Migration) error {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() succeeds
+
+	if migration.DownFunc != nil {
+		if err := migration.DownFunc(tx); err != nil {
+			return fmt.Errorf("executing down function: %w", err)
+		}
+	}
+
+	if migration.Down != "" {
+		if _, err := tx.Exec(migration.Down); err != nil {
+			return fmt.Errorf("executing down script: %w", err)
+		}
+	}
+
+	if _, err := tx.Exec(`DELETE FROM schema_migrations WHERE version = ?`, migration.Version); err != nil {
+		return fmt.Errorf("removing migration record: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+
+	return nil
+}
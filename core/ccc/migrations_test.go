@@ -0,0 +1,165 @@
+package ccc
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func openMigrationTestDB(t *testing.T) *sql.DB {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return db
+}
+
+func testMigrations() []Migration {
+	return []Migration{
+		{
+			Version: 2,
+			Name:    "add_email",
+			UpFunc: func(tx *sql.Tx) error {
+				return AddSQLiteColumnIfNotExists(tx, "Account", "Email", "TEXT")
+			},
+			Down: `ALTER TABLE Account DROP COLUMN Email`,
+		},
+		{
+			Version: 1,
+			Name:    "create_account",
+			Up:      `CREATE TABLE Account (Id TEXT PRIMARY KEY, Name TEXT NOT NULL)`,
+			Down:    `DROP TABLE Account`,
+		},
+	}
+}
+
+func TestMigrationRunnerUpAndDown(t *testing.T) {
+	db := openMigrationTestDB(t)
+	runner := NewSQLiteMigrationRunner(db, testMigrations(), nil)
+
+	applied, err := runner.Up()
+	if err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+	if len(applied) != 2 || applied[0].Version != 1 || applied[1].Version != 2 {
+		t.Fatalf("expected migrations 1 and 2 to be applied in order, got %+v", applied)
+	}
+
+	exists, err := SQLiteColumnExists(db, "Account", "Email")
+	if err != nil || !exists {
+		t.Fatalf("expected Email column to exist (err: %v)", err)
+	}
+
+	// Running Up again must be a no-op
+	applied, err = runner.Up()
+	if err != nil {
+		t.Fatalf("second Up failed: %v", err)
+	}
+	if len(applied) != 0 {
+		t.Fatalf("expected no migrations to be applied, got %d", len(applied))
+	}
+
+	reverted, err := runner.Down(1)
+	if err != nil {
+		t.Fatalf("Down failed: %v", err)
+	}
+	if len(reverted) != 1 || reverted[0].Version != 2 {
+		t.Fatalf("expected migration 2 to be reverted, got %+v", reverted)
+	}
+
+	exists, err = SQLiteColumnExists(db, "Account", "Email")
+	if err != nil || exists {
+		t.Fatalf("expected Email column to be dropped (err: %v)", err)
+	}
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !statuses[0].Applied || statuses[1].Applied {
+		t.Fatalf("expected only migration 1 to be applied, got %+v", statuses)
+	}
+}
+
+func TestMigrationRunnerRollsBackFailedMigration(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	migrations := append(testMigrations(), Migration{
+		Version: 3,
+		Name:    "broken",
+		Up:      `CREATE TABLE Broken (Id TEXT); INSERT INTO MissingTable VALUES (1);`,
+	})
+
+	runner := NewSQLiteMigrationRunner(db, migrations, nil)
+
+	applied, err := runner.Up()
+	if err == nil {
+		t.Fatal("expected Up to fail")
+	}
+	if len(applied) != 2 {
+		t.Fatalf("expected the first two migrations to be applied, got %d", len(applied))
+	}
+
+	var count int
+	if err := db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'Broken'`).Scan(&count); err != nil {
+		t.Fatalf("failed to query schema: %v", err)
+	}
+	if count != 0 {
+		t.Fatal("expected the failed migration to be rolled back")
+	}
+}
+
+func TestMigrationRunnerDetectsModifiedMigration(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	if _, err := NewSQLiteMigrationRunner(db, testMigrations(), nil).Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	modified := testMigrations()
+	modified[1].Up = `CREATE TABLE Account (Id TEXT PRIMARY KEY, Name TEXT)`
+
+	runner := NewSQLiteMigrationRunner(db, modified, nil)
+
+	statuses, err := runner.Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !statuses[0].ChecksumMismatch {
+		t.Fatal("expected checksum mismatch for modified migration")
+	}
+
+	if _, err := runner.Up(); err == nil {
+		t.Fatal("expected Up to refuse running with a modified migration")
+	}
+}
+
+func TestMigrationRunnerDetectsRevisedMigrationHooks(t *testing.T) {
+	db := openMigrationTestDB(t)
+
+	if _, err := NewSQLiteMigrationRunner(db, testMigrations(), nil).Up(); err != nil {
+		t.Fatalf("Up failed: %v", err)
+	}
+
+	// Changing a hook alone goes unnoticed, which is why its revision has to be incremented
+	modified := testMigrations()
+	modified[0].UpFunc = func(tx *sql.Tx) error { return nil }
+	if statuses, _ := NewSQLiteMigrationRunner(db, modified, nil).Status(); statuses[1].ChecksumMismatch {
+		t.Fatal("expected the checksum to ignore the hook itself")
+	}
+
+	modified[0].FuncRevision = 1
+	statuses, err := NewSQLiteMigrationRunner(db, modified, nil).Status()
+	if err != nil {
+		t.Fatalf("Status failed: %v", err)
+	}
+	if !statuses[1].ChecksumMismatch {
+		t.Fatal("expected checksum mismatch for a migration with a revised hook")
+	}
+}
@@ -0,0 +1,311 @@
+package ccc
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ErrS3ObjectNotFound is returned when a requested object does not exist in the bucket
+var ErrS3ObjectNotFound = errors.New("S3 object not found")
+
+// S3Object describes an object of a bucket
+type S3Object struct {
+	Name         string // Name of the object relative to the prefix of the client
+	Size         int64
+	LastModified time.Time
+}
+
+// S3Client accesses the objects below a prefix in a bucket of an S3-compatible object storage
+// such as AWS S3 or MinIO. Requests use path-style addressing and are signed with AWS Signature Version 4.
+type S3Client struct {
+	endpoint  url.URL // scheme and host of the storage
+	bucket    string
+	prefix    string // key prefix ending with "/", or empty
+	region    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3Client creates an S3 client from a URL like
+//
+//	s3://ACCESS_KEY:SECRET_KEY@host[:port]/bucket[/prefix][?region=us-east-1&insecure=true]
+//
+// Requests use HTTPS unless insecure=true is given.
+func NewS3Client(s3Url *url.URL) (*S3Client, error) {
+	bucket, prefix, _ := strings.Cut(strings.Trim(s3Url.Path, "/"), "/")
+	if bucket == "" {
+		return nil, fmt.Errorf("S3 URL %s has no bucket", RedactUrl(s3Url))
+	}
+
+	secretKey, _ := s3Url.User.Password()
+	if s3Url.User.Username() == "" || secretKey == "" {
+		return nil, fmt.Errorf("S3 URL %s needs an access key and a secret key", RedactUrl(s3Url))
+	}
+
+	query := s3Url.Query()
+	client := &S3Client{
+		endpoint:  url.URL{Scheme: "https", Host: s3Url.Host},
+		bucket:    bucket,
+		region:    query.Get("region"),
+		accessKey: s3Url.User.Username(),
+		secretKey: secretKey,
+		client:    &http.Client{},
+	}
+	if prefix != "" {
+		client.prefix = prefix + "/"
+	}
+	if client.region == "" {
+		client.region = "us-east-1"
+	}
+	if query.Get("insecure") == "true" {
+		client.endpoint.Scheme = "http"
+	}
+
+	return client, nil
+}
+
+// RedactUrl returns the URL without user info and query, for logs and error messages
+func RedactUrl(rawUrl *url.URL) string {
+	redacted := *rawUrl
+	redacted.User = nil
+	redacted.RawQuery = ""
+	redacted.Fragment = ""
+	return redacted.String()
+}
+
+// Name returns the endpoint, bucket and prefix of the client
+func (c *S3Client) Name() string {
+	return "s3://" + c.endpoint.Host + "/" + c.bucket + "/" + c.prefix
+}
+
+// PutObject stores an object, replacing an existing object of the same name
+func (c *S3Client) PutObject(ctx context.Context, name string, body io.Reader, size int64) error {
+	request, err := c.newRequest(ctx, http.MethodPut, c.prefix+name, nil, body)
+	if err != nil {
+		return err
+	}
+	request.ContentLength = size
+
+	response, err := c.do(request, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// GetObject opens the content of an object. The caller has to close it.
+// It returns ErrS3ObjectNotFound if the object does not exist.
+func (c *S3Client) GetObject(ctx context.Context, name string) (io.ReadCloser, error) {
+	request, err := c.newRequest(ctx, http.MethodGet, c.prefix+name, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	response, err := c.do(request, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	return response.Body, nil
+}
+
+// DeleteObject removes an object. Deleting an object that does not exist succeeds.
+func (c *S3Client) DeleteObject(ctx context.Context, name string) error {
+	request, err := c.newRequest(ctx, http.MethodDelete, c.prefix+name, nil, nil)
+	if err != nil {
+		return err
+	}
+
+	response, err := c.do(request, http.StatusNoContent)
+	if err != nil {
+		return err
+	}
+	return response.Body.Close()
+}
+
+// ListObjects calls fn for every object below the prefix whose name starts with namePrefix.
+// Listing stops at the first error returned by fn.
+func (c *S3Client) ListObjects(ctx context.Context, namePrefix string, fn func(object S3Object) error) error {
+	continuationToken := ""
+	for {
+		query := url.Values{"list-type": {"2"}, "prefix": {c.prefix + namePrefix}}
+		if continuationToken != "" {
+			query.Set("continuation-token", continuationToken)
+		}
+
+		request, err := c.newRequest(ctx, http.MethodGet, "", query, nil)
+		if err != nil {
+			return err
+		}
+
+		var result struct {
+			Contents []struct {
+				Key          string    `xml:"Key"`
+				Size         int64     `xml:"Size"`
+				LastModified time.Time `xml:"LastModified"`
+			} `xml:"Contents"`
+			IsTruncated           bool   `xml:"IsTruncated"`
+			NextContinuationToken string `xml:"NextContinuationToken"`
+		}
+		response, err := c.do(request, http.StatusOK)
+		if err != nil {
+			return err
+		}
+		err = xml.NewDecoder(response.Body).Decode(&result)
+		response.Body.Close()
+		if err != nil {
+			return fmt.Errorf("failed to decode S3 response: %w", err)
+		}
+
+		for _, object := range result.Contents {
+			name := strings.TrimPrefix(object.Key, c.prefix)
+			if name == "" {
+				continue
+			}
+			if err := fn(S3Object{Name: name, Size: object.Size, LastModified: object.LastModified}); err != nil {
+				return err
+			}
+		}
+
+		if !result.IsTruncated || result.NextContinuationToken == "" {
+			return nil
+		}
+		continuationToken = result.NextContinuationToken
+	}
+}
+
+// newRequest creates a signed request for an object key in the bucket, or for the bucket itself if key is empty
+func (c *S3Client) newRequest(ctx context.Context, method, key string, query url.Values, body io.Reader) (*http.Request, error) {
+	path := "/" + c.bucket
+	if key != "" {
+		path += "/" + key
+	}
+
+	requestUrl := c.endpoint
+	requestUrl.Path = path
+	requestUrl.RawPath = s3UriEncode(path, false)
+	requestUrl.RawQuery = s3CanonicalQuery(query)
+
+	request, err := http.NewRequestWithContext(ctx, method, requestUrl.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 request: %w", err)
+	}
+
+	c.sign(request, time.Now().UTC())
+	return request, nil
+}
+
+// sign adds the AWS Signature Version 4 headers to a request. The payload is not signed,
+// so uploads can be streamed without hashing the content twice.
+func (c *S3Client) sign(request *http.Request, now time.Time) {
+	const payloadHash = "UNSIGNED-PAYLOAD"
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+
+	request.Header.Set("X-Amz-Date", amzDate)
+	request.Header.Set("X-Amz-Content-Sha256", payloadHash)
+
+	signedHeaders := "host;x-amz-content-sha256;x-amz-date"
+	canonicalRequest := strings.Join([]string{
+		request.Method,
+		request.URL.EscapedPath(),
+		request.URL.RawQuery,
+		"host:" + request.URL.Host + "\n" +
+			"x-amz-content-sha256:" + payloadHash + "\n" +
+			"x-amz-date:" + amzDate + "\n",
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(canonicalHash[:])
+
+	signingKey := hmacSha256([]byte("AWS4"+c.secretKey), date)
+	signingKey = hmacSha256(signingKey, c.region)
+	signingKey = hmacSha256(signingKey, "s3")
+	signingKey = hmacSha256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSha256(signingKey, stringToSign))
+
+	request.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+// do sends a request and returns the response if it has the expected status. The caller has to close its body.
+func (c *S3Client) do(request *http.Request, expectedStatus int) (*http.Response, error) {
+	response, err := c.client.Do(request)
+	if err != nil {
+		return nil, fmt.Errorf("S3 request failed: %w", err)
+	}
+
+	// DELETE returns 204 on AWS, but some implementations answer with 200
+	if response.StatusCode == expectedStatus || response.StatusCode == http.StatusOK {
+		return response, nil
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == http.StatusNotFound && request.Method == http.MethodGet && request.URL.RawQuery == "" {
+		return nil, ErrS3ObjectNotFound
+	}
+
+	var s3Error struct {
+		Code    string `xml:"Code"`
+		Message string `xml:"Message"`
+	}
+	body, _ := io.ReadAll(io.LimitReader(response.Body, 64*1024))
+	if xml.Unmarshal(body, &s3Error) == nil && s3Error.Code != "" {
+		return nil, fmt.Errorf("S3 %s %s returned %d %s: %s", request.Method, request.URL.Path, response.StatusCode, s3Error.Code, s3Error.Message)
+	}
+	return nil, fmt.Errorf("S3 %s %s returned status %d", request.Method, request.URL.Path, response.StatusCode)
+}
+
+func hmacSha256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// s3UriEncode encodes a string as required by Signature Version 4: everything but
+// unreserved characters is percent-encoded, and slashes only if encodeSlash is set
+func s3UriEncode(value string, encodeSlash bool) string {
+	var encoded strings.Builder
+	for _, b := range []byte(value) {
+		switch {
+		case 'A' <= b && b <= 'Z', 'a' <= b && b <= 'z', '0' <= b && b <= '9', b == '-', b == '_', b == '.', b == '~':
+			encoded.WriteByte(b)
+		case b == '/' && !encodeSlash:
+			encoded.WriteByte(b)
+		default:
+			fmt.Fprintf(&encoded, "%%%02X", b)
+		}
+	}
+	return encoded.String()
+}
+
+// s3CanonicalQuery encodes query parameters sorted by name, as required by Signature Version 4
+func s3CanonicalQuery(query url.Values) string {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	parts := make([]string, 0, len(keys))
+	for _, key := range keys {
+		for _, value := range query[key] {
+			parts = append(parts, s3UriEncode(key, true)+"="+s3UriEncode(value, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
@@ -1,6 +1,7 @@
 package ccc
 
 import (
+	"context"
 	"fmt"
 	"time"
 )
@@ -37,3 +38,43 @@ func ParseSQLiteTimestamp(timestampStr string) (time.Time, error) {
 func FormatSQLiteTimestamp(t time.Time) string {
 	return t.UTC().Format("2006-01-02 15:04:05")
 }
+
+// SQLiteColumnExists reports whether the given table has a column with the given name.
+func SQLiteColumnExists(db DBExecutor, table, column string) (bool, error) {
+	rows, err := db.QueryContext(context.Background(), fmt.Sprintf("SELECT name FROM pragma_table_info('%s')", table))
+	if err != nil {
+		return false, fmt.Errorf("reading columns of table %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return false, fmt.Errorf("scanning column of table %s: %w", table, err)
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+
+	return false, rows.Err()
+}
+
+// AddSQLiteColumnIfNotExists adds a column to a table unless it already exists.
+// The definition is the column type and constraints, e.g. "INTEGER DEFAULT 0".
+func AddSQLiteColumnIfNotExists(db DBExecutor, table, column, definition string) error {
+	exists, err := SQLiteColumnExists(db, table, column)
+	if err != nil {
+		return err
+	}
+	if exists {
+		return nil
+	}
+
+	query := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition)
+	if _, err := db.ExecContext(context.Background(), query); err != nil {
+		return fmt.Errorf("adding column %s to table %s: %w", column, table, err)
+	}
+
+	return nil
+}
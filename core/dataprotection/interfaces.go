@@ -1,8 +1,22 @@
 package dataprotection
 
+import "io"
+
 type DataProtector interface {
 	Protect(data string) (protectedData string, err error)
 	Unprotect(protectedData string) (data string, err error)
 	ProtectBytes(data []byte) (protectedData []byte, err error)
 	UnprotectBytes(protectedData []byte) (data []byte, err error)
+	// ProtectStream returns a writer encrypting everything written to it into dst. It has to be closed to complete the stream.
+	ProtectStream(dst io.Writer) (plainWriter io.WriteCloser, err error)
+	// UnprotectStream returns a reader decrypting a stream written by ProtectStream, or data encrypted by Protect.
+	UnprotectStream(src io.Reader) (plainReader io.Reader, err error)
+	BlindIndex(data string) (index string, err error)
+}
+
+// MekWrapper is implemented by data protectors that can hand out their MEK (Master Encryption Key)
+// encrypted with another key, so that work can continue outside the scope the protector was created for
+// (e.g. background jobs that outlive a web request).
+type MekWrapper interface {
+	WrapMek(wrappingKey string) (wrappedMek string, err error)
 }
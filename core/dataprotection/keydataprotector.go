@@ -0,0 +1,74 @@
+package dataprotection
+
+import (
+	"errors"
+	"io"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+// KeyDataProtector is a struct that provides methods to encrypt and decrypt data using a MEK (Master Encryption Key)
+// that was obtained outside of a web request or password prompt, e.g. by unwrapping the MEK stored with a background job.
+// The MEK is held in memory for the lifetime of the protector, so it should be discarded as soon as the work is done.
+type KeyDataProtector struct {
+	encryptionService encryption.EncryptionService
+	mek               string
+}
+
+// NewKeyDataProtector creates a new KeyDataProtector instance for the given plain MEK.
+func NewKeyDataProtector(encryptionService encryption.EncryptionService, mek string) *KeyDataProtector {
+	return &KeyDataProtector{
+		encryptionService: encryptionService,
+		mek:               mek,
+	}
+}
+
+// UnwrapKeyDataProtector decrypts a MEK that was wrapped with the given wrapping key and creates a KeyDataProtector for it.
+func UnwrapKeyDataProtector(encryptionService encryption.EncryptionService, wrappedMek, wrappingKey string) (*KeyDataProtector, error) {
+	mek, err := encryptionService.Decrypt(wrappedMek, wrappingKey)
+	if err != nil || mek == "" {
+		return nil, errors.New("MEK not available")
+	}
+
+	return NewKeyDataProtector(encryptionService, mek), nil
+}
+
+// Protect encrypts the given piece of data using the MEK.
+func (p *KeyDataProtector) Protect(data string) (protectedData string, err error) {
+	return p.encryptionService.Encrypt(data, p.mek)
+}
+
+// Unprotect decrypts the given piece of data using the MEK.
+func (p *KeyDataProtector) Unprotect(protectedData string) (data string, err error) {
+	return p.encryptionService.Decrypt(protectedData, p.mek)
+}
+
+// ProtectBytes encrypts the given byte slice using the MEK.
+func (p *KeyDataProtector) ProtectBytes(data []byte) (protectedData []byte, err error) {
+	return p.encryptionService.EncryptBytes(data, p.mek)
+}
+
+// UnprotectBytes decrypts the given byte slice using the MEK.
+func (p *KeyDataProtector) UnprotectBytes(protectedData []byte) (data []byte, err error) {
+	return p.encryptionService.DecryptBytes(protectedData, p.mek)
+}
+
+// ProtectStream returns a writer encrypting everything written to it into dst using the MEK.
+func (p *KeyDataProtector) ProtectStream(dst io.Writer) (plainWriter io.WriteCloser, err error) {
+	return p.encryptionService.EncryptStream(dst, p.mek)
+}
+
+// UnprotectStream returns a reader decrypting the given stream using the MEK.
+func (p *KeyDataProtector) UnprotectStream(src io.Reader) (plainReader io.Reader, err error) {
+	return p.encryptionService.DecryptStream(src, p.mek)
+}
+
+// BlindIndex computes a deterministic keyed index for the given piece of data using the MEK.
+func (p *KeyDataProtector) BlindIndex(data string) (index string, err error) {
+	return p.encryptionService.BlindIndex(data, p.mek)
+}
+
+// WrapMek encrypts the MEK with the given wrapping key.
+func (p *KeyDataProtector) WrapMek(wrappingKey string) (wrappedMek string, err error) {
+	return p.encryptionService.Encrypt(p.mek, wrappingKey)
+}
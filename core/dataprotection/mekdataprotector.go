@@ -2,6 +2,7 @@ package dataprotection
 
 import (
 	"errors"
+	"io"
 	"net/http"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
@@ -93,3 +94,58 @@ func (p *MekDataProtector) UnprotectBytes(protectedData []byte) (data []byte, er
 
 	return decryptedData, nil
 }
+
+// ProtectStream returns a writer encrypting everything written to it into dst using the MEK (Master Encryption Key) stored in the MekStore.
+func (p *MekDataProtector) ProtectStream(dst io.Writer) (plainWriter io.WriteCloser, err error) {
+
+	mek, err := p.mekStore.Retrieve(p.request)
+	if err != nil || mek == "" {
+		return nil, errors.New("MEK not available")
+	}
+
+	return p.encryptionService.EncryptStream(dst, mek)
+}
+
+// UnprotectStream returns a reader decrypting the given stream using the MEK (Master Encryption Key) stored in the MekStore.
+func (p *MekDataProtector) UnprotectStream(src io.Reader) (plainReader io.Reader, err error) {
+
+	mek, err := p.mekStore.Retrieve(p.request)
+	if err != nil || mek == "" {
+		return nil, errors.New("MEK not available")
+	}
+
+	return p.encryptionService.DecryptStream(src, mek)
+}
+
+// BlindIndex computes a deterministic keyed index for the given piece of data using the MEK (Master Encryption Key) stored in the MekStore.
+// The index can be used for exact-match lookups on data that is stored encrypted.
+func (p *MekDataProtector) BlindIndex(data string) (index string, err error) {
+
+	mek, err := p.mekStore.Retrieve(p.request)
+	if err != nil || mek == "" {
+		return "", errors.New("MEK not available")
+	}
+
+	index, err = p.encryptionService.BlindIndex(data, mek)
+	if err != nil {
+		return "", err
+	}
+
+	return index, nil
+}
+
+// WrapMek encrypts the MEK (Master Encryption Key) stored in the MekStore with the given wrapping key.
+func (p *MekDataProtector) WrapMek(wrappingKey string) (wrappedMek string, err error) {
+
+	mek, err := p.mekStore.Retrieve(p.request)
+	if err != nil || mek == "" {
+		return "", errors.New("MEK not available")
+	}
+
+	wrappedMek, err = p.encryptionService.Encrypt(mek, wrappingKey)
+	if err != nil {
+		return "", err
+	}
+
+	return wrappedMek, nil
+}
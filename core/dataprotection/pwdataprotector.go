@@ -2,6 +2,7 @@ package dataprotection
 
 import (
 	"errors"
+	"io"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
@@ -113,6 +114,90 @@ func (p *PasswordDataProtector) UnprotectBytes(protectedData []byte) (data []byt
 	return data, nil
 }
 
+// ProtectStream returns a writer encrypting everything written to it into dst using the user's password.
+func (p *PasswordDataProtector) ProtectStream(dst io.Writer) (plainWriter io.WriteCloser, err error) {
+
+	user, err := p.getUser()
+	if err != nil {
+		return nil, err
+	}
+
+	plainMek, err := p.securityService.UncoverMek(*user, p.password)
+	if err != nil {
+		return nil, errors.New(("MEK not available: " + err.Error()))
+	}
+
+	plainWriter, err = p.encryptionService.EncryptStream(dst, plainMek)
+	if err != nil {
+		return nil, errors.New(("Encryption failed: " + err.Error()))
+	}
+
+	return plainWriter, nil
+}
+
+// UnprotectStream returns a reader decrypting the given stream using the user's password.
+func (p *PasswordDataProtector) UnprotectStream(src io.Reader) (plainReader io.Reader, err error) {
+
+	user, err := p.getUser()
+	if err != nil {
+		return nil, err
+	}
+
+	plainMek, err := p.securityService.UncoverMek(*user, p.password)
+	if err != nil {
+		return nil, errors.New(("MEK not available: " + err.Error()))
+	}
+
+	plainReader, err = p.encryptionService.DecryptStream(src, plainMek)
+	if err != nil {
+		return nil, errors.New(("Decryption failed: " + err.Error()))
+	}
+
+	return plainReader, nil
+}
+
+// BlindIndex computes a deterministic keyed index for the given piece of data using the user's password.
+func (p *PasswordDataProtector) BlindIndex(data string) (index string, err error) {
+
+	user, err := p.getUser()
+	if err != nil {
+		return "", err
+	}
+
+	plainMek, err := p.securityService.UncoverMek(*user, p.password)
+	if err != nil {
+		return "", errors.New(("MEK not available: " + err.Error()))
+	}
+
+	index, err = p.encryptionService.BlindIndex(data, plainMek)
+	if err != nil {
+		return "", errors.New(("Blind index computation failed: " + err.Error()))
+	}
+
+	return index, nil
+}
+
+// WrapMek uncovers the MEK using the user's password and encrypts it with the given wrapping key.
+func (p *PasswordDataProtector) WrapMek(wrappingKey string) (wrappedMek string, err error) {
+
+	user, err := p.getUser()
+	if err != nil {
+		return "", err
+	}
+
+	plainMek, err := p.securityService.UncoverMek(*user, p.password)
+	if err != nil {
+		return "", errors.New(("MEK not available: " + err.Error()))
+	}
+
+	wrappedMek, err = p.encryptionService.Encrypt(plainMek, wrappingKey)
+	if err != nil {
+		return "", errors.New(("Encryption failed: " + err.Error()))
+	}
+
+	return wrappedMek, nil
+}
+
 // getUser returns the user associated with the PasswordDataProtector and caches it for future use.
 func (p *PasswordDataProtector) getUser() (*auth.User, error) {
 	if p.user == nil {
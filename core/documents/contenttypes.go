@@ -0,0 +1,71 @@
+package documents
+
+import (
+	"mime"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// SupportedFormatsDescription names the formats document files may have, for messages shown to users
+const SupportedFormatsDescription = "PDF, images (JPEG, PNG, GIF, WebP, TIFF, HEIC), text, Markdown, DOCX, ODT and EML files"
+
+// documentContentType describes a content type document files may have
+type documentContentType struct {
+	contentType string   // Canonical content type stored with the file
+	extensions  []string // File extensions of the format
+	aliases     []string // Other content types browsers send for the format
+}
+
+// documentContentTypes lists all content types a processor exists for
+var documentContentTypes = []documentContentType{
+	{contentType: "application/pdf", extensions: []string{".pdf"}},
+	{contentType: "image/jpeg", extensions: []string{".jpg", ".jpeg"}, aliases: []string{"image/jpg", "image/pjpeg"}},
+	{contentType: "image/png", extensions: []string{".png"}},
+	{contentType: "image/gif", extensions: []string{".gif"}},
+	{contentType: "image/webp", extensions: []string{".webp"}},
+	{contentType: "image/tiff", extensions: []string{".tif", ".tiff"}, aliases: []string{"image/tif", "image/x-tiff"}},
+	{contentType: "image/heic", extensions: []string{".heic"}, aliases: []string{"image/heic-sequence"}},
+	{contentType: "image/heif", extensions: []string{".heif"}, aliases: []string{"image/heif-sequence"}},
+	{contentType: "text/plain", extensions: []string{".txt", ".text"}},
+	{contentType: "text/markdown", extensions: []string{".md", ".markdown"}, aliases: []string{"text/x-markdown"}},
+	{contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", extensions: []string{".docx"}},
+	{contentType: "application/vnd.oasis.opendocument.text", extensions: []string{".odt"}},
+	{contentType: "message/rfc822", extensions: []string{".eml"}},
+}
+
+// ResolveContentType returns the canonical content type of an uploaded file. Browsers send no or a generic
+// content type for formats like HEIC, Markdown or EML on some systems, so the file extension decides then.
+// Unknown content types of files with unknown extensions are returned unchanged.
+func ResolveContentType(fileName string, contentType string) string {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		mediaType = strings.TrimSpace(contentType)
+	}
+	mediaType = strings.ToLower(mediaType)
+
+	for _, known := range documentContentTypes {
+		if mediaType == known.contentType || slices.Contains(known.aliases, mediaType) {
+			return known.contentType
+		}
+	}
+
+	extension := strings.ToLower(filepath.Ext(fileName))
+	for _, known := range documentContentTypes {
+		if slices.Contains(known.extensions, extension) {
+			return known.contentType
+		}
+	}
+
+	return mediaType
+}
+
+// IsSupportedContentType reports whether document files may have the given canonical content type
+func IsSupportedContentType(contentType string) bool {
+	for _, known := range documentContentTypes {
+		if contentType == known.contentType {
+			return true
+		}
+	}
+	return false
+}
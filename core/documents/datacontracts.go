@@ -1,6 +1,9 @@
 package documents
 
-import "time"
+import (
+	"io"
+	"time"
+)
 
 // Request/Response DTOs for service layer
 type CreateDocumentRequest struct {
@@ -24,6 +27,21 @@ type AddFileRequest struct {
 	FileName    string
 	ContentType string
 	FileData    []byte
+	Content     io.Reader // Streamed content, used instead of FileData if set
+}
+
+// CreateUploadSessionRequest announces a file that is uploaded in parts
+type CreateUploadSessionRequest struct {
+	FileName    string
+	ContentType string
+	Size        int64 // Size of the file in bytes
+}
+
+// UploadChunkRequest is a part of a file uploaded to an upload session
+type UploadChunkRequest struct {
+	Offset  int64 // Position of the part in the file, which has to match the offset of the session
+	Size    int64 // Size of the part in bytes, the content has to have exactly this size
+	Content io.Reader
 }
 
 type GetDocumentsRequest struct {
@@ -126,6 +144,14 @@ type DocumentDto struct {
 	ModifiedAt  time.Time
 }
 
+// TrashedDocumentDto is a document in the trash
+type TrashedDocumentDto struct {
+	Id        string
+	Title     string // Decrypted
+	FileCount int
+	DeletedAt time.Time
+}
+
 type DocumentFileDto struct {
 	Id            string
 	DocumentId    string
@@ -137,11 +163,23 @@ type DocumentFileDto struct {
 	Confidence    float32
 	OcrStatus     string
 	OcrError      string
-	FileData      []byte // Decrypted, only when explicitly requested
 	CreatedAt     time.Time
 	ModifiedAt    time.Time
 }
 
+// UploadSessionDto represents the state of a resumable upload
+type UploadSessionDto struct {
+	Id           string
+	DocumentId   string
+	FileName     string // Decrypted
+	ContentType  string
+	Size         int64
+	Offset       int64 // Number of bytes received so far, the next part has to start here
+	MaxChunkSize int64 // Maximum size of a single part
+	CreatedAt    time.Time
+	ExpiresAt    time.Time
+}
+
 // DocumentFilePreviewDto represents a document file with preview data but without full file content
 // This is used for listing files in the UI where we want preview images but not the entire file data
 type DocumentFilePreviewDto struct {
@@ -168,6 +206,26 @@ type TagDto struct {
 	ModifiedAt time.Time
 }
 
+// RenamedTag describes a tag that was given a new name because its name was taken
+type RenamedTag struct {
+	OriginalName string
+	Name         string
+}
+
+// TagNameReport reports the outcome of encrypting the names of tags stored before tag names were encrypted
+type TagNameReport struct {
+	Encrypted int
+	Renamed   []RenamedTag
+}
+
+// TrashedTagDto is a tag in the trash
+type TrashedTagDto struct {
+	Id        string
+	Name      string
+	Color     string
+	DeletedAt time.Time
+}
+
 // NoteDto represents a note with decrypted content for API responses
 type NoteDto struct {
 	Id         string
@@ -193,6 +251,7 @@ type CreateFileRequest struct {
 	FileName    string
 	ContentType string
 	FileData    []byte
+	Content     io.Reader // Streamed content, used instead of FileData if set
 }
 
 // CreateDocumentResponse represents the response from creating a document
@@ -229,3 +288,11 @@ type DocumentListItem struct {
 	MatchTypes      []string // Only populated for search results: "title", "description", "filename", "content"
 	IsSearchResult  bool     // Indicates whether this item came from search or regular listing
 }
+
+// BlobMigrationSummary counts the file contents and previews moved to the blob store
+type BlobMigrationSummary struct {
+	Files    int
+	Previews int
+	Bytes    int64 // Size of the moved contents and previews
+	Skipped  int   // Files that were modified during the migration and are moved by the next run
+}
@@ -0,0 +1,137 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// DefaultDocumentBlobMigrator implements DocumentBlobMigrator
+type DefaultDocumentBlobMigrator struct {
+	db     *sql.DB
+	blobs  blobstore.BlobStore
+	logger ccc.Logger
+}
+
+// NewDefaultDocumentBlobMigrator creates a new DefaultDocumentBlobMigrator instance
+func NewDefaultDocumentBlobMigrator(db *sql.DB, blobs blobstore.BlobStore, logger ccc.Logger) *DefaultDocumentBlobMigrator {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &DefaultDocumentBlobMigrator{
+		db:     db,
+		blobs:  blobs,
+		logger: logger,
+	}
+}
+
+// MigrateToBlobStore moves the file contents and previews that are still stored in the database to the blob store.
+// The contents stay encrypted, as they are moved as they are. Files that are modified while they are moved are
+// skipped and moved by the next run. The space freed in the database is only returned to the file system by a VACUUM.
+func (m *DefaultDocumentBlobMigrator) MigrateToBlobStore(ctx context.Context) (*BlobMigrationSummary, error) {
+	if m.blobs == nil {
+		return nil, ccc.NewInvalidInputError("blob store", fmt.Sprintf("no blob store is configured, set %s", ccc.EnvBlobStore))
+	}
+
+	fileIds, err := m.findFilesInDatabase(ctx)
+	if err != nil {
+		return nil, ccc.NewDatabaseError("failed to find document files stored in the database", err)
+	}
+
+	summary := &BlobMigrationSummary{}
+	for _, fileId := range fileIds {
+		if err := m.migrateFile(ctx, fileId, summary); err != nil {
+			return summary, fmt.Errorf("failed to move document file %s to blob store: %w", fileId, err)
+		}
+	}
+
+	m.logger.Info("Moved document files to blob store", "store", m.blobs.Name(), "files", summary.Files,
+		"previews", summary.Previews, "bytes", summary.Bytes, "skipped", summary.Skipped)
+	return summary, nil
+}
+
+// findFilesInDatabase returns the IDs of the files whose content or preview is stored in the database
+func (m *DefaultDocumentBlobMigrator) findFilesInDatabase(ctx context.Context) ([]string, error) {
+	query := `SELECT Id FROM DocumentFile WHERE FileBlobRef IS NULL OR (PreviewData IS NOT NULL AND PreviewBlobRef IS NULL)`
+	rows, err := m.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var fileIds []string
+	for rows.Next() {
+		var fileId string
+		if err := rows.Scan(&fileId); err != nil {
+			return nil, err
+		}
+		fileIds = append(fileIds, fileId)
+	}
+	return fileIds, rows.Err()
+}
+
+// migrateFile moves the content and preview of a single file. The rows are only updated if the file
+// has not been modified since it was read, so that no concurrent change is overwritten.
+func (m *DefaultDocumentBlobMigrator) migrateFile(ctx context.Context, fileId string, summary *BlobMigrationSummary) error {
+	// ModifiedAt is read as stored, as the driver would reformat it when parsing it as a timestamp
+	query := `SELECT FileData, FileBlobRef, PreviewData, PreviewBlobRef, CAST(ModifiedAt AS TEXT) FROM DocumentFile WHERE Id = ?`
+
+	var fileData, previewData []byte
+	var fileBlobRef, previewBlobRef sql.NullString
+	var modifiedAt string
+	err := m.db.QueryRowContext(ctx, query, fileId).Scan(&fileData, &fileBlobRef, &previewData, &previewBlobRef, &modifiedAt)
+	if err == sql.ErrNoRows {
+		return nil // Deleted in the meantime
+	}
+	if err != nil {
+		return err
+	}
+
+	if !fileBlobRef.Valid {
+		reference, err := blobstore.StoreChunks(ctx, m.blobs, bytes.NewReader(fileData))
+		if err != nil {
+			return err
+		}
+		result, err := m.db.ExecContext(ctx,
+			`UPDATE DocumentFile SET FileData = ?, FileBlobRef = ? WHERE Id = ? AND FileBlobRef IS NULL AND ModifiedAt = ?`,
+			[]byte{}, reference, fileId, modifiedAt)
+		if err != nil {
+			return err
+		}
+		if moved, err := result.RowsAffected(); err != nil {
+			return err
+		} else if moved == 0 {
+			summary.Skipped++
+			return nil
+		}
+		summary.Files++
+		summary.Bytes += int64(len(fileData))
+	}
+
+	if previewData != nil && !previewBlobRef.Valid {
+		reference, err := blobstore.StoreChunks(ctx, m.blobs, bytes.NewReader(previewData))
+		if err != nil {
+			return err
+		}
+		result, err := m.db.ExecContext(ctx,
+			`UPDATE DocumentFile SET PreviewData = NULL, PreviewBlobRef = ? WHERE Id = ? AND PreviewBlobRef IS NULL AND ModifiedAt = ?`,
+			reference, fileId, modifiedAt)
+		if err != nil {
+			return err
+		}
+		if moved, err := result.RowsAffected(); err != nil {
+			return err
+		} else if moved == 0 {
+			summary.Skipped++
+			return nil
+		}
+		summary.Previews++
+		summary.Bytes += int64(len(previewData))
+	}
+
+	return nil
+}
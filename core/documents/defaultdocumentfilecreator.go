@@ -1,8 +1,11 @@
 package documents
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"path/filepath"
 	"strings"
 	"time"
@@ -11,17 +14,27 @@ import (
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
 )
 
+// MaxProcessedFileSize is the size up to which files are held in memory to generate their previews and extract
+// their text. Larger files are streamed into the store without a preview and without text extraction.
+const MaxProcessedFileSize = 30 * 1024 * 1024
+
+// errFileTooLarge is returned by the content reader once a file exceeds the maximum file size
+var errFileTooLarge = errors.New("file exceeds the maximum file size")
+
 // DefaultDocumentFileCreator implements DocumentFileCreator interface
 type DefaultDocumentFileCreator struct {
 	fileIdGen           DocumentFileIdGenerator
 	docProcessorFactory DocumentFileProcessorFactory
+	maxFileSize         int64
 	logger              ccc.Logger
 }
 
-// NewDefaultDocumentFileCreator creates a new DefaultDocumentFileCreator instance
+// NewDefaultDocumentFileCreator creates a new DefaultDocumentFileCreator instance.
+// Files larger than maxFileSize bytes are rejected.
 func NewDefaultDocumentFileCreator(
 	fileIdGen DocumentFileIdGenerator,
 	docProcessorFactory DocumentFileProcessorFactory,
+	maxFileSize int64,
 	logger ccc.Logger,
 ) *DefaultDocumentFileCreator {
 	if logger == nil {
@@ -31,6 +44,7 @@ func NewDefaultDocumentFileCreator(
 	return &DefaultDocumentFileCreator{
 		fileIdGen:           fileIdGen,
 		docProcessorFactory: docProcessorFactory,
+		maxFileSize:         maxFileSize,
 		logger:              logger,
 	}
 }
@@ -69,11 +83,21 @@ func (c *DefaultDocumentFileCreator) CreateDocumentFile(
 		return nil, nil, fmt.Errorf("failed to encrypt file name: %w", err)
 	}
 
-	// Encrypt file data
-	encryptedFileData, err := dataProtector.Protect(string(request.FileData))
+	content := request.Content
+	if content == nil {
+		content = bytes.NewReader(request.FileData)
+	}
+
+	// Files small enough to be processed are read into memory to generate the preview and extract the text,
+	// the rest of larger files is streamed into the store without being held in memory
+	head, err := io.ReadAll(io.LimitReader(content, MaxProcessedFileSize+1))
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to encrypt file data: %w", err)
+		return nil, nil, fmt.Errorf("failed to read file data: %w", err)
+	}
+	if len(head) == 0 {
+		return nil, nil, ccc.NewInvalidInputErrorWithMessage("fileData", "cannot be empty", "File data is required")
 	}
+	processed := len(head) <= MaxProcessedFileSize
 
 	now := time.Now()
 
@@ -83,9 +107,8 @@ func (c *DefaultDocumentFileCreator) CreateDocumentFile(
 		DocumentId:  request.DocumentId,
 		FileName:    encryptedFileName,
 		ContentType: request.ContentType,
-		FileSize:    int64(len(request.FileData)),
+		FileSize:    int64(len(head)),
 		PageCount:   initialPageCount(request.ContentType),
-		FileData:    []byte(encryptedFileData),
 		CreatedAt:   now,
 		ModifiedAt:  now,
 	}
@@ -98,48 +121,42 @@ func (c *DefaultDocumentFileCreator) CreateDocumentFile(
 
 	// Generate preview if the processor supports it
 	var preview *DocumentFilePreview
-	previewResult, err := processor.GeneratePreview(ctx, request.FileData)
+	if processed {
+		preview = c.generatePreview(ctx, processor, fileId, request.ContentType, head, dataProtector)
+	}
+
+	// Encrypt file data while it is stored
+	counter := &limitedCountingReader{reader: io.MultiReader(bytes.NewReader(head), content), limit: c.maxFileSize}
+	encryptedContent, waitForEncryption := encryptContent(counter, dataProtector)
+	err = uow.DocumentFileRepo().AddWithPreview(ctx, documentFile, encryptedContent, preview)
+	waitForEncryption()
+	if errors.Is(err, errFileTooLarge) {
+		return nil, nil, c.fileTooLargeError()
+	}
 	if err != nil {
-		c.logger.Warn("Failed to generate preview", "error", err, "fileId", fileId, "contentType", request.ContentType)
-		// Continue without preview - not critical for file creation
-	} else if previewResult != nil {
-		// Encrypt preview data if it exists
-		var encryptedPreviewData string
-		if len(previewResult.PreviewData) > 0 {
-			encryptedPreviewData, err = dataProtector.Protect(string(previewResult.PreviewData))
-			if err != nil {
-				c.logger.Error("Failed to encrypt preview data", "error", err, "fileId", fileId)
-				// Continue without preview
-			} else {
-				preview = &DocumentFilePreview{
-					DocumentFileId: fileId,
-					PreviewData:    []byte(encryptedPreviewData),
-					PreviewType:    previewResult.PreviewType,
-					Width:          previewResult.Width,
-					Height:         previewResult.Height,
-				}
-			}
-		} else if previewResult.PreviewType != "" {
-			// For non-image previews (like PDF), just store the type without data
-			preview = &DocumentFilePreview{
-				DocumentFileId: fileId,
-				PreviewData:    nil,
-				PreviewType:    previewResult.PreviewType,
-				Width:          previewResult.Width,
-				Height:         previewResult.Height,
-			}
+		return nil, nil, ccc.NewDatabaseError("failed to add document file", err)
+	}
+
+	// The size of files that were not read into memory is only known once they are stored
+	if documentFile.FileSize != counter.count {
+		documentFile.FileSize = counter.count
+		if err := uow.DocumentFileRepo().Update(ctx, documentFile); err != nil {
+			return nil, nil, ccc.NewDatabaseError("failed to update document file size", err)
 		}
 	}
 
-	// Persist DocumentFile with preview if available
-	if preview != nil {
-		if err := uow.DocumentFileRepo().AddWithPreview(ctx, documentFile, preview); err != nil {
-			return nil, nil, ccc.NewDatabaseError("failed to add document file with preview", err)
+	if !processed {
+		documentFileMetadata := &DocumentFileMetadata{
+			DocumentFileId: fileId,
+			OcrStatus:      OcrStatusSkipped,
+			OcrError:       fmt.Sprintf("The file is larger than %d MB, which is the limit for text extraction", MaxProcessedFileSize/(1024*1024)),
 		}
-	} else {
-		if err := uow.DocumentFileRepo().Add(ctx, documentFile); err != nil {
-			return nil, nil, ccc.NewDatabaseError("failed to add document file", err)
+		if err := uow.DocumentFileMetadataRepo().Add(ctx, documentFileMetadata); err != nil {
+			return nil, nil, ccc.NewDatabaseError("failed to add document file metadata", err)
 		}
+
+		c.logger.Info("Successfully created document file without processing it", "fileId", fileId, "documentId", request.DocumentId, "fileSize", documentFile.FileSize)
+		return documentFile, documentFileMetadata, nil
 	}
 
 	startedAt := time.Now()
@@ -153,14 +170,14 @@ func (c *DefaultDocumentFileCreator) CreateDocumentFile(
 	}
 
 	if ocrDispatcher != nil {
-		ocrDispatcher.Enqueue(OCRDispatchRequest{
+		err := ocrDispatcher.Enqueue(ctx, uow, OCRDispatchRequest{
 			DocumentFileId: fileId,
-			ContentType:    request.ContentType,
-			FileData:       request.FileData,
-			Processor:      processor,
+			UserId:         request.UserId,
 			DataProtector:  dataProtector,
-			StartedAt:      startedAt,
 		})
+		if err != nil {
+			return nil, nil, ccc.NewDatabaseError("failed to queue text extraction", err)
+		}
 	}
 
 	c.logger.Info("Successfully created document file", "fileId", fileId, "documentId", request.DocumentId, "fileName", request.FileName)
@@ -168,6 +185,93 @@ func (c *DefaultDocumentFileCreator) CreateDocumentFile(
 	return documentFile, documentFileMetadata, nil
 }
 
+// generatePreview generates and encrypts the preview of a file. Failures are logged, as a file can be stored without a preview.
+func (c *DefaultDocumentFileCreator) generatePreview(
+	ctx context.Context,
+	processor DocumentFileProcessor,
+	fileId, contentType string,
+	fileData []byte,
+	dataProtector dataprotection.DataProtector,
+) *DocumentFilePreview {
+	previewResult, err := processor.GeneratePreview(ctx, fileData)
+	if err != nil {
+		c.logger.Warn("Failed to generate preview", "error", err, "fileId", fileId, "contentType", contentType)
+		return nil
+	}
+	if previewResult == nil {
+		return nil
+	}
+
+	if len(previewResult.PreviewData) > 0 {
+		// Encrypt preview data if it exists
+		encryptedPreviewData, err := dataProtector.Protect(string(previewResult.PreviewData))
+		if err != nil {
+			c.logger.Error("Failed to encrypt preview data", "error", err, "fileId", fileId)
+			return nil
+		}
+		return &DocumentFilePreview{
+			DocumentFileId: fileId,
+			PreviewData:    []byte(encryptedPreviewData),
+			PreviewType:    previewResult.PreviewType,
+			Width:          previewResult.Width,
+			Height:         previewResult.Height,
+		}
+	}
+	if previewResult.PreviewType != "" {
+		// For non-image previews (like PDF), just store the type without data
+		return &DocumentFilePreview{
+			DocumentFileId: fileId,
+			PreviewData:    nil,
+			PreviewType:    previewResult.PreviewType,
+			Width:          previewResult.Width,
+			Height:         previewResult.Height,
+		}
+	}
+	return nil
+}
+
+// encryptContent returns a reader for the encrypted content, which is encrypted while the reader is read.
+// The returned function closes the reader and waits until the content is no longer read.
+func encryptContent(content io.Reader, dataProtector dataprotection.DataProtector) (io.Reader, func()) {
+	reader, writer := io.Pipe()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		encrypter, err := dataProtector.ProtectStream(writer)
+		if err == nil {
+			if _, err = io.Copy(encrypter, content); err == nil {
+				err = encrypter.Close()
+			}
+		}
+		writer.CloseWithError(err)
+	}()
+
+	return reader, func() {
+		reader.Close()
+		<-done
+	}
+}
+
+// limitedCountingReader counts the bytes read and fails once more than limit bytes were read
+type limitedCountingReader struct {
+	reader io.Reader
+	limit  int64
+	count  int64
+}
+
+func (r *limitedCountingReader) Read(p []byte) (int, error) {
+	n, err := r.reader.Read(p)
+	r.count += int64(n)
+	if r.count > r.limit {
+		return n, errFileTooLarge
+	}
+	return n, err
+}
+
+func (c *DefaultDocumentFileCreator) fileTooLargeError() error {
+	return ccc.NewInvalidInputErrorWithMessage("fileData", "exceeds maximum size", fmt.Sprintf("File cannot be larger than %d MB", c.maxFileSize/(1024*1024)))
+}
+
 func initialPageCount(contentType string) int {
 	if strings.HasPrefix(strings.ToLower(contentType), "image/") {
 		return 1
@@ -178,7 +282,6 @@ func initialPageCount(contentType string) int {
 // ValidateFileRequest performs basic validation on file request data
 func (c *DefaultDocumentFileCreator) ValidateFileRequest(request CreateFileRequest) error {
 	const maxFileNameLength = 255
-	const maxFileSize = 100 * 1024 * 1024 // 100MB
 
 	if request.UserId == "" {
 		return ccc.NewInvalidInputErrorWithMessage("userId", "cannot be empty", "User ID is required")
@@ -195,11 +298,11 @@ func (c *DefaultDocumentFileCreator) ValidateFileRequest(request CreateFileReque
 	if request.ContentType == "" {
 		return ccc.NewInvalidInputErrorWithMessage("contentType", "cannot be empty", "Content type is required")
 	}
-	if len(request.FileData) == 0 {
+	if request.Content == nil && len(request.FileData) == 0 {
 		return ccc.NewInvalidInputErrorWithMessage("fileData", "cannot be empty", "File data is required")
 	}
-	if len(request.FileData) > maxFileSize {
-		return ccc.NewInvalidInputErrorWithMessage("fileData", "exceeds maximum size", fmt.Sprintf("File cannot be larger than %d bytes", maxFileSize))
+	if int64(len(request.FileData)) > c.maxFileSize {
+		return c.fileTooLargeError()
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package documents
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
@@ -75,11 +76,15 @@ func (m *DefaultDocumentFileManager) AddDocumentFile(
 			FileName:    request.FileName,
 			ContentType: request.ContentType,
 			FileData:    request.FileData,
+			Content:     request.Content,
 		}
 
 		// Create the file using the file creator
 		var createErr error
 		createdFile, createdMetadata, createErr = m.fileCreator.CreateDocumentFile(ctx, uow, createFileReq, dataProtector, ocrDispatcher)
+		if ccc.IsValidationError(createErr) {
+			return createErr
+		}
 		if createErr != nil {
 			return ccc.NewDatabaseError("failed to create document file", createErr)
 		}
@@ -155,53 +160,92 @@ func (m *DefaultDocumentFileManager) GetDocumentFiles(
 	return fileDtos, nil
 }
 
-// GetDocumentFile retrieves a single file by ID with full data
+// GetDocumentFile retrieves a single file by ID without its content
 func (m *DefaultDocumentFileManager) GetDocumentFile(
 	ctx context.Context,
 	userId, documentId, fileId string,
 	dataProtector dataprotection.DataProtector,
 ) (*DocumentFileDto, error) {
+	file, metadata, err := m.findDocumentFile(ctx, m.uowFactory.Create(), userId, documentId, fileId)
+	if err != nil {
+		return nil, err
+	}
+	return m.buildDocumentFileDto(file, metadata, dataProtector), nil
+}
+
+// OpenDocumentFile retrieves a single file by ID along with a reader for its decrypted content.
+// The content is decrypted and verified chunk by chunk while it is read, so reading it fails if it was tampered with.
+func (m *DefaultDocumentFileManager) OpenDocumentFile(
+	ctx context.Context,
+	userId, documentId, fileId string,
+	dataProtector dataprotection.DataProtector,
+) (*DocumentFileDto, io.Reader, error) {
+	uow := m.uowFactory.Create()
+	file, metadata, err := m.findDocumentFile(ctx, uow, userId, documentId, fileId)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	encryptedContent, err := uow.DocumentFileRepo().OpenContent(ctx, fileId)
+	if err != nil {
+		return nil, nil, ccc.NewDatabaseError("failed to open document file content", err)
+	}
+	if encryptedContent == nil {
+		return nil, nil, ccc.NewResourceNotFoundError("document file", fileId)
+	}
+
+	content, err := dataProtector.UnprotectStream(encryptedContent)
+	if err != nil {
+		return nil, nil, ccc.NewInternalError("failed to decrypt document file content", err)
+	}
+
+	return m.buildDocumentFileDto(file, metadata, dataProtector), content, nil
+}
+
+// findDocumentFile finds a file and its metadata and verifies that it belongs to the document of the user
+func (m *DefaultDocumentFileManager) findDocumentFile(
+	ctx context.Context,
+	uow DocumentUnitOfWork,
+	userId, documentId, fileId string,
+) (*DocumentFile, *DocumentFileMetadata, error) {
 	if userId == "" {
-		return nil, ccc.NewInvalidInputError("userId", "cannot be empty")
+		return nil, nil, ccc.NewInvalidInputError("userId", "cannot be empty")
 	}
 	if documentId == "" {
-		return nil, ccc.NewInvalidInputError("documentId", "cannot be empty")
+		return nil, nil, ccc.NewInvalidInputError("documentId", "cannot be empty")
 	}
 	if fileId == "" {
-		return nil, ccc.NewInvalidInputError("fileId", "cannot be empty")
+		return nil, nil, ccc.NewInvalidInputError("fileId", "cannot be empty")
 	}
 
-	uow := m.uowFactory.Create()
-
 	// Verify document exists and belongs to user
 	document, err := uow.DocumentRepo().FindById(ctx, documentId)
 	if err != nil {
-		return nil, ccc.NewDatabaseError("failed to find document", err)
+		return nil, nil, ccc.NewDatabaseError("failed to find document", err)
 	}
 	if document == nil || document.UserId != userId {
-		return nil, ccc.NewResourceNotFoundError("document", documentId)
+		return nil, nil, ccc.NewResourceNotFoundError("document", documentId)
 	}
 
 	// Get the specific file
 	file, err := uow.DocumentFileRepo().FindById(ctx, fileId)
 	if err != nil {
-		return nil, ccc.NewDatabaseError("failed to find document file", err)
+		return nil, nil, ccc.NewDatabaseError("failed to find document file", err)
 	}
 	if file == nil {
-		return nil, ccc.NewResourceNotFoundError("document file", fileId)
+		return nil, nil, ccc.NewResourceNotFoundError("document file", fileId)
 	}
 	if file.DocumentId != documentId {
-		return nil, ccc.NewResourceNotFoundError("document file", fileId)
+		return nil, nil, ccc.NewResourceNotFoundError("document file", fileId)
 	}
 
 	// Get metadata for the file
 	metadata, err := uow.DocumentFileMetadataRepo().FindByDocumentFileId(ctx, fileId)
 	if err != nil {
-		return nil, ccc.NewDatabaseError("failed to find document file metadata", err)
+		return nil, nil, ccc.NewDatabaseError("failed to find document file metadata", err)
 	}
 
-	// Build and return the DTO with full data
-	return m.buildDocumentFileDto(file, metadata, dataProtector), nil
+	return file, metadata, nil
 }
 
 // DeleteDocumentFile deletes a file from a document
@@ -243,6 +287,11 @@ func (m *DefaultDocumentFileManager) DeleteDocumentFile(
 			return ccc.NewResourceNotFoundError("document file", fileId)
 		}
 
+		// Delete pending OCR jobs
+		if err := uow.OcrJobRepo().DeleteByDocumentFileId(ctx, fileId); err != nil {
+			return ccc.NewDatabaseError("failed to delete OCR jobs", err)
+		}
+
 		// Delete file metadata
 		if err := uow.DocumentFileMetadataRepo().Delete(ctx, fileId); err != nil {
 			return ccc.NewDatabaseError("failed to delete document file metadata", err)
@@ -270,6 +319,206 @@ func (m *DefaultDocumentFileManager) DeleteDocumentFile(
 	return err
 }
 
+// ReprocessFile queues the text extraction of a file again
+func (m *DefaultDocumentFileManager) ReprocessFile(
+	ctx context.Context,
+	userId, documentId, fileId string,
+	dataProtector dataprotection.DataProtector,
+) error {
+	if userId == "" {
+		return ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if documentId == "" {
+		return ccc.NewInvalidInputError("documentId", "cannot be empty")
+	}
+	if fileId == "" {
+		return ccc.NewInvalidInputError("fileId", "cannot be empty")
+	}
+
+	uow := m.uowFactory.Create()
+	ocrDispatcher := m.ocrDispatcherFactory.Create()
+
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		// Verify document exists and belongs to user
+		document, err := uow.DocumentRepo().FindById(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document", err)
+		}
+		if document == nil || document.UserId != userId {
+			return ccc.NewResourceNotFoundError("document", documentId)
+		}
+
+		// Verify file exists and belongs to document
+		file, err := uow.DocumentFileRepo().FindById(ctx, fileId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document file", err)
+		}
+		if file == nil || file.DocumentId != documentId {
+			return ccc.NewResourceNotFoundError("document file", fileId)
+		}
+
+		metadata, err := uow.DocumentFileMetadataRepo().FindByDocumentFileId(ctx, fileId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document file metadata", err)
+		}
+		if metadata != nil && metadata.OcrStatus == OcrStatusProcessing {
+			return ccc.NewInvalidInputErrorWithMessage("fileId", "text extraction is already in progress", "Text extraction is already in progress for this file")
+		}
+
+		return m.requeueFile(ctx, uow, ocrDispatcher, userId, fileId, metadata, dataProtector)
+	})
+
+	if err != nil {
+		return err
+	}
+	ocrDispatcher.Dispatch()
+
+	m.logger.Info("Queued text extraction of document file again", "userId", userId, "documentId", documentId, "fileId", fileId)
+	return nil
+}
+
+// ReprocessDocument queues the text extraction of all files of a document again.
+// Files whose extraction is already in progress are left untouched.
+func (m *DefaultDocumentFileManager) ReprocessDocument(
+	ctx context.Context,
+	userId, documentId string,
+	dataProtector dataprotection.DataProtector,
+) (int, error) {
+	if userId == "" {
+		return 0, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if documentId == "" {
+		return 0, ccc.NewInvalidInputError("documentId", "cannot be empty")
+	}
+
+	uow := m.uowFactory.Create()
+	ocrDispatcher := m.ocrDispatcherFactory.Create()
+	queued := 0
+
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		// Verify document exists and belongs to user
+		document, err := uow.DocumentRepo().FindById(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document", err)
+		}
+		if document == nil || document.UserId != userId {
+			return ccc.NewResourceNotFoundError("document", documentId)
+		}
+
+		metadataList, err := uow.DocumentFileMetadataRepo().FindByDocumentId(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document file metadata", err)
+		}
+
+		for _, metadata := range metadataList {
+			if metadata.OcrStatus == OcrStatusProcessing {
+				continue
+			}
+			if err := m.requeueFile(ctx, uow, ocrDispatcher, userId, metadata.DocumentFileId, metadata, dataProtector); err != nil {
+				return err
+			}
+			queued++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	ocrDispatcher.Dispatch()
+
+	m.logger.Info("Queued text extraction of document files again", "userId", userId, "documentId", documentId, "count", queued)
+	return queued, nil
+}
+
+// ReprocessFailedFiles queues the text extraction of all files of a user whose extraction failed
+func (m *DefaultDocumentFileManager) ReprocessFailedFiles(
+	ctx context.Context,
+	userId string,
+	dataProtector dataprotection.DataProtector,
+) (int, error) {
+	if userId == "" {
+		return 0, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+
+	uow := m.uowFactory.Create()
+	ocrDispatcher := m.ocrDispatcherFactory.Create()
+	queued := 0
+
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		metadataList, err := uow.DocumentFileMetadataRepo().FindByUserIdAndOcrStatus(ctx, userId, []string{OcrStatusFailed})
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find failed document files", err)
+		}
+
+		for _, metadata := range metadataList {
+			if err := m.requeueFile(ctx, uow, ocrDispatcher, userId, metadata.DocumentFileId, metadata, dataProtector); err != nil {
+				return err
+			}
+			queued++
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return 0, err
+	}
+	ocrDispatcher.Dispatch()
+
+	m.logger.Info("Queued failed text extractions again", "userId", userId, "count", queued)
+	return queued, nil
+}
+
+// requeueFile resets the OCR state of a file and enqueues a new OCR job within the given unit of work.
+// Previously extracted text is kept until the new extraction has finished.
+func (m *DefaultDocumentFileManager) requeueFile(
+	ctx context.Context,
+	uow DocumentUnitOfWork,
+	ocrDispatcher OCRDispatcher,
+	userId, fileId string,
+	metadata *DocumentFileMetadata,
+	dataProtector dataprotection.DataProtector,
+) error {
+	startedAt := time.Now()
+
+	if metadata == nil {
+		metadata = &DocumentFileMetadata{
+			DocumentFileId: fileId,
+			OcrStatus:      OcrStatusProcessing,
+			OcrStartedAt:   &startedAt,
+		}
+		if err := uow.DocumentFileMetadataRepo().Add(ctx, metadata); err != nil {
+			return ccc.NewDatabaseError("failed to add document file metadata", err)
+		}
+	} else {
+		metadata.OcrStatus = OcrStatusProcessing
+		metadata.OcrError = ""
+		metadata.OcrStartedAt = &startedAt
+		metadata.OcrCompletedAt = nil
+		if err := uow.DocumentFileMetadataRepo().Update(ctx, metadata); err != nil {
+			return ccc.NewDatabaseError("failed to update document file metadata", err)
+		}
+	}
+
+	// Remove leftovers of earlier jobs, so that a file is never queued twice
+	if err := uow.OcrJobRepo().DeleteByDocumentFileId(ctx, fileId); err != nil {
+		return ccc.NewDatabaseError("failed to delete OCR jobs", err)
+	}
+
+	err := ocrDispatcher.Enqueue(ctx, uow, OCRDispatchRequest{
+		DocumentFileId: fileId,
+		UserId:         userId,
+		DataProtector:  dataProtector,
+	})
+	if err != nil {
+		return ccc.NewDatabaseError("failed to queue text extraction", err)
+	}
+
+	return nil
+}
+
 // validateAddFileRequest validates the add file request
 func (m *DefaultDocumentFileManager) validateAddFileRequest(request AddFileRequest) error {
 	if request.FileName == "" {
@@ -278,12 +527,9 @@ func (m *DefaultDocumentFileManager) validateAddFileRequest(request AddFileReque
 	if request.ContentType == "" {
 		return ccc.NewInvalidInputError("contentType", "cannot be empty")
 	}
-	if len(request.FileData) == 0 {
+	if request.Content == nil && len(request.FileData) == 0 {
 		return ccc.NewInvalidInputError("fileData", "cannot be empty")
 	}
-	if len(request.FileData) > 100*1024*1024 { // 100MB limit
-		return ccc.NewInvalidInputError("fileData", "file size exceeds 100MB limit")
-	}
 	return nil
 }
 
@@ -311,14 +557,6 @@ func (m *DefaultDocumentFileManager) buildDocumentFileDto(
 		dto.FileName = "Encrypted File"
 	}
 
-	// Decrypt file data
-	if decrypted, err := dataProtector.Unprotect(string(file.FileData)); err == nil {
-		dto.FileData = []byte(decrypted)
-	} else {
-		m.logger.Warn("Failed to decrypt file data", "fileId", file.Id, "error", err)
-		dto.FileData = nil
-	}
-
 	// Add metadata if available
 	if metadata != nil {
 		dto.Confidence = metadata.OcrConfidence
@@ -0,0 +1,196 @@
+package documents
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// checkingOCRDispatcherFactory creates dispatchers that record how many jobs a file still had when a new one was enqueued
+type checkingOCRDispatcherFactory struct {
+	inner         OCRDispatcherFactory
+	jobsAtEnqueue map[string]int
+}
+
+func (f *checkingOCRDispatcherFactory) Create() OCRDispatcher {
+	return &checkingOCRDispatcher{inner: f.inner.Create(), jobsAtEnqueue: f.jobsAtEnqueue}
+}
+
+type checkingOCRDispatcher struct {
+	inner         OCRDispatcher
+	jobsAtEnqueue map[string]int
+}
+
+func (d *checkingOCRDispatcher) Enqueue(ctx context.Context, uow DocumentUnitOfWork, request OCRDispatchRequest) error {
+	jobs, err := uow.OcrJobRepo().FindByStatus(ctx, OcrJobStatusQueued)
+	if err != nil {
+		return err
+	}
+	count := 0
+	for _, job := range jobs {
+		if job.DocumentFileId == request.DocumentFileId {
+			count++
+		}
+	}
+	d.jobsAtEnqueue[request.DocumentFileId] = count
+	return d.inner.Enqueue(ctx, uow, request)
+}
+
+func (d *checkingOCRDispatcher) Dispatch() {
+	d.inner.Dispatch()
+}
+
+func (s *ocrJobTest) newFileManager() (*DefaultDocumentFileManager, map[string]int) {
+	jobsAtEnqueue := map[string]int{}
+	dispatcherFactory := &checkingOCRDispatcherFactory{inner: s.dispatcher, jobsAtEnqueue: jobsAtEnqueue}
+	return NewDefaultDocumentFileManager(s.uowFactory, s.fileCreator, dispatcherFactory, nil), jobsAtEnqueue
+}
+
+func (s *ocrJobTest) addDocument(userId, documentId string) {
+	now := time.Now()
+	if err := s.uowFactory.Create().DocumentRepo().Add(context.Background(), &Document{Id: documentId, UserId: userId, CreatedAt: now, ModifiedAt: now}); err != nil {
+		s.t.Fatalf("failed to add document: %v", err)
+	}
+}
+
+// failFile marks the text extraction of a file as failed, keeping its queued job as a leftover
+func (s *ocrJobTest) failFile(fileId string) {
+	metadata := s.metadata(fileId)
+	completedAt := time.Now()
+	metadata.OcrStatus = OcrStatusFailed
+	metadata.OcrError = "OCR engine unavailable"
+	metadata.OcrCompletedAt = &completedAt
+	if err := s.uowFactory.Create().DocumentFileMetadataRepo().Update(context.Background(), metadata); err != nil {
+		s.t.Fatalf("failed to update metadata: %v", err)
+	}
+}
+
+// fileJobs returns the queued jobs of a file
+func (s *ocrJobTest) fileJobs(fileId string) []*OcrJob {
+	var fileJobs []*OcrJob
+	for _, job := range s.jobs(OcrJobStatusQueued) {
+		if job.DocumentFileId == fileId {
+			fileJobs = append(fileJobs, job)
+		}
+	}
+	return fileJobs
+}
+
+func TestReprocessFileRequeuesFailedFile(t *testing.T) {
+	ctx := context.Background()
+	s := newOCRJobTest(t)
+	manager, jobsAtEnqueue := s.newFileManager()
+	fileId := s.addFile("invoice 4711")
+	s.failFile(fileId)
+	earlierJob := s.fileJobs(fileId)[0]
+
+	if err := manager.ReprocessFile(ctx, "user-1", "doc-1", fileId, s.dataProtector); err != nil {
+		t.Fatalf("ReprocessFile failed: %v", err)
+	}
+
+	metadata := s.metadata(fileId)
+	if metadata.OcrStatus != OcrStatusProcessing || metadata.OcrError != "" || metadata.OcrCompletedAt != nil {
+		t.Fatalf("expected the metadata to be reset to processing, got %s (%q)", metadata.OcrStatus, metadata.OcrError)
+	}
+	if count, enqueued := jobsAtEnqueue[fileId]; !enqueued || count != 0 {
+		t.Fatalf("expected the earlier job to be deleted before the new one was enqueued, found %d", count)
+	}
+	jobs := s.fileJobs(fileId)
+	if len(jobs) != 1 || jobs[0].Id == earlierJob.Id {
+		t.Fatalf("expected the earlier job to be replaced by a new one, got %v", jobs)
+	}
+
+	if err := manager.ReprocessFile(ctx, "user-1", "doc-1", fileId, s.dataProtector); !ccc.IsValidationError(err) {
+		t.Fatalf("expected a file in processing to be rejected, got %v", err)
+	}
+}
+
+func TestReprocessDocumentSkipsFilesInProcessing(t *testing.T) {
+	ctx := context.Background()
+	s := newOCRJobTest(t)
+	manager, jobsAtEnqueue := s.newFileManager()
+	failedFileId := s.addFile("invoice 4711")
+	processingFileId := s.addFile("invoice 4712")
+	s.failFile(failedFileId)
+	processingJob := s.fileJobs(processingFileId)[0]
+
+	queued, err := manager.ReprocessDocument(ctx, "user-1", "doc-1", s.dataProtector)
+	if err != nil {
+		t.Fatalf("ReprocessDocument failed: %v", err)
+	}
+	if queued != 1 {
+		t.Fatalf("expected 1 file to be queued, got %d", queued)
+	}
+	if _, enqueued := jobsAtEnqueue[processingFileId]; enqueued {
+		t.Fatalf("expected the file in processing to be skipped")
+	}
+	if jobs := s.fileJobs(processingFileId); len(jobs) != 1 || jobs[0].Id != processingJob.Id {
+		t.Fatalf("expected the job of the file in processing to be kept, got %v", jobs)
+	}
+	if metadata := s.metadata(failedFileId); metadata.OcrStatus != OcrStatusProcessing {
+		t.Fatalf("expected the failed file to be processed again, got %s", metadata.OcrStatus)
+	}
+}
+
+func TestReprocessFailedFilesOnlyRequeuesFilesOfUser(t *testing.T) {
+	ctx := context.Background()
+	s := newOCRJobTest(t)
+	manager, _ := s.newFileManager()
+	s.addDocument("user-2", "doc-2")
+	ownFailedFileId := s.addFile("invoice 4711")
+	ownProcessingFileId := s.addFile("invoice 4712")
+	foreignFailedFileId := s.addFileTo("user-2", "doc-2", "invoice 4713")
+	s.failFile(ownFailedFileId)
+	s.failFile(foreignFailedFileId)
+	processingJob := s.fileJobs(ownProcessingFileId)[0]
+
+	queued, err := manager.ReprocessFailedFiles(ctx, "user-1", s.dataProtector)
+	if err != nil {
+		t.Fatalf("ReprocessFailedFiles failed: %v", err)
+	}
+	if queued != 1 {
+		t.Fatalf("expected 1 file to be queued, got %d", queued)
+	}
+	if metadata := s.metadata(ownFailedFileId); metadata.OcrStatus != OcrStatusProcessing {
+		t.Fatalf("expected the failed file of the user to be processed again, got %s", metadata.OcrStatus)
+	}
+	if metadata := s.metadata(foreignFailedFileId); metadata.OcrStatus != OcrStatusFailed {
+		t.Fatalf("expected the failed file of another user to be left alone, got %s", metadata.OcrStatus)
+	}
+	if jobs := s.fileJobs(ownProcessingFileId); len(jobs) != 1 || jobs[0].Id != processingJob.Id {
+		t.Fatalf("expected the job of the file in processing to be kept, got %v", jobs)
+	}
+}
+
+func TestReprocessReturnsNotFoundForForeignAndTrashedDocuments(t *testing.T) {
+	ctx := context.Background()
+	s := newOCRJobTest(t)
+	manager, _ := s.newFileManager()
+	fileId := s.addFile("invoice 4711")
+	s.failFile(fileId)
+
+	if err := manager.ReprocessFile(ctx, "user-2", "doc-1", fileId, s.dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected a file of another user to be not found, got %v", err)
+	}
+	if _, err := manager.ReprocessDocument(ctx, "user-2", "doc-1", s.dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected a document of another user to be not found, got %v", err)
+	}
+
+	if err := s.uowFactory.Create().DocumentRepo().MoveToTrash(ctx, "doc-1", time.Now()); err != nil {
+		t.Fatalf("failed to move document to trash: %v", err)
+	}
+	if err := manager.ReprocessFile(ctx, "user-1", "doc-1", fileId, s.dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected a file of a trashed document to be not found, got %v", err)
+	}
+	if _, err := manager.ReprocessDocument(ctx, "user-1", "doc-1", s.dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected a trashed document to be not found, got %v", err)
+	}
+	if queued, err := manager.ReprocessFailedFiles(ctx, "user-1", s.dataProtector); err != nil || queued != 0 {
+		t.Fatalf("expected failed files of trashed documents to be skipped, got %d: %v", queued, err)
+	}
+	if metadata := s.metadata(fileId); metadata.OcrStatus != OcrStatusFailed {
+		t.Fatalf("expected the file of the trashed document to be left alone, got %s", metadata.OcrStatus)
+	}
+}
@@ -125,9 +125,13 @@ func (m *DefaultDocumentManager) CreateDocument(
 					FileName:    fileRequest.FileName,
 					ContentType: fileRequest.ContentType,
 					FileData:    fileRequest.FileData,
+					Content:     fileRequest.Content,
 				}
 
 				_, _, err := m.fileCreator.CreateDocumentFile(ctx, uow, createFileReq, dataProtector, ocrDispatcher)
+				if ccc.IsValidationError(err) {
+					return err
+				}
 				if err != nil {
 					return ccc.NewDatabaseError("failed to create document file", err)
 				}
@@ -184,6 +188,8 @@ func (m *DefaultDocumentManager) GetDocument(
 	if err != nil {
 		return nil, ccc.NewDatabaseError("failed to find document tags", err)
 	}
+	decryptTagNames(tags, dataProtector, m.logger)
+	sortTagsByName(tags)
 
 	// Decrypt fields upfront
 	if decrypted, err := dataProtector.Unprotect(document.Title); err == nil {
@@ -434,7 +440,7 @@ func (m *DefaultDocumentManager) UpdateDocument(
 	})
 }
 
-// DeleteDocument deletes a document and all its associated files
+// DeleteDocument moves a document to the trash. Its files, notes and tags are kept until it is purged.
 func (m *DefaultDocumentManager) DeleteDocument(ctx context.Context, userId, documentId string) error {
 	if userId == "" {
 		return ccc.NewInvalidInputError("userId", "cannot be empty")
@@ -454,35 +460,180 @@ func (m *DefaultDocumentManager) DeleteDocument(ctx context.Context, userId, doc
 			return ccc.NewResourceNotFoundError("document", documentId)
 		}
 
-		// Delete document tags
-		if err := uow.DocumentTagRepo().RemoveAllDocumentTags(ctx, documentId); err != nil {
-			return ccc.NewDatabaseError("failed to remove document tags", err)
+		if err := uow.DocumentRepo().MoveToTrash(ctx, documentId, time.Now()); err != nil {
+			return ccc.NewDatabaseError("failed to move document to trash", err)
 		}
 
-		// Delete document notes
-		if err := uow.NoteRepo().DeleteByDocumentId(ctx, documentId); err != nil {
-			return ccc.NewDatabaseError("failed to delete document notes", err)
+		m.logger.Info("Document moved to the trash", "userId", userId, "documentId", documentId)
+		return nil
+	})
+}
+
+// GetTrashedDocuments retrieves the documents of a user that are in the trash
+func (m *DefaultDocumentManager) GetTrashedDocuments(
+	ctx context.Context,
+	userId string,
+	dataProtector dataprotection.DataProtector,
+) ([]*TrashedDocumentDto, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+
+	// Read-only operations - no transaction needed
+	uow := m.uowFactory.Create()
+
+	documents, err := uow.DocumentRepo().FindTrashedByUserId(ctx, userId)
+	if err != nil {
+		return nil, ccc.NewDatabaseError("failed to find trashed documents", err)
+	}
+
+	dtos := make([]*TrashedDocumentDto, 0, len(documents))
+	for _, document := range documents {
+		title, err := dataProtector.Unprotect(document.Title)
+		if err != nil {
+			m.logger.Warn("Failed to decrypt title", "documentId", document.Id, "error", err)
+			title = ""
 		}
 
-		// Delete file metadata
-		if err := uow.DocumentFileMetadataRepo().DeleteByDocumentId(ctx, documentId); err != nil {
-			return ccc.NewDatabaseError("failed to delete file metadata", err)
+		fileCount, err := uow.DocumentRepo().GetFileCountByDocumentId(ctx, document.Id)
+		if err != nil {
+			return nil, ccc.NewDatabaseError("failed to get file count", err)
 		}
 
-		// Delete files
-		if err := uow.DocumentFileRepo().DeleteByDocumentId(ctx, documentId); err != nil {
-			return ccc.NewDatabaseError("failed to delete document files", err)
+		dtos = append(dtos, &TrashedDocumentDto{
+			Id:        document.Id,
+			Title:     title,
+			FileCount: fileCount,
+			DeletedAt: *document.DeletedAt,
+		})
+	}
+
+	return dtos, nil
+}
+
+// RestoreDocument moves a document of the user out of the trash
+func (m *DefaultDocumentManager) RestoreDocument(ctx context.Context, userId, documentId string) error {
+	if userId == "" {
+		return ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if documentId == "" {
+		return ccc.NewInvalidInputError("documentId", "cannot be empty")
+	}
+
+	uow := m.uowFactory.Create()
+	return uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		document, err := uow.DocumentRepo().FindTrashedById(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find trashed document", err)
+		}
+		if document == nil || document.UserId != userId {
+			return ccc.NewResourceNotFoundError("document", documentId)
 		}
 
-		// Delete document
-		if err := uow.DocumentRepo().Delete(ctx, documentId); err != nil {
-			return ccc.NewDatabaseError("failed to delete document", err)
+		if err := uow.DocumentRepo().RestoreFromTrash(ctx, documentId); err != nil {
+			return ccc.NewDatabaseError("failed to restore document", err)
 		}
 
+		m.logger.Info("Document restored from the trash", "userId", userId, "documentId", documentId)
 		return nil
 	})
 }
 
+// PurgeDocument permanently deletes a document of the user that is in the trash
+func (m *DefaultDocumentManager) PurgeDocument(ctx context.Context, userId, documentId string) error {
+	if userId == "" {
+		return ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if documentId == "" {
+		return ccc.NewInvalidInputError("documentId", "cannot be empty")
+	}
+
+	uow := m.uowFactory.Create()
+	return uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		document, err := uow.DocumentRepo().FindTrashedById(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find trashed document", err)
+		}
+		if document == nil || document.UserId != userId {
+			return ccc.NewResourceNotFoundError("document", documentId)
+		}
+
+		if err := purgeDocument(ctx, uow, documentId); err != nil {
+			return err
+		}
+
+		m.logger.Info("Document purged", "userId", userId, "documentId", documentId)
+		return nil
+	})
+}
+
+// PurgeTrashedDocuments permanently deletes the documents of all users that were moved to the trash before the given time.
+// Each document is purged in its own transaction; the number of purged documents is returned also if one of them failed.
+func (m *DefaultDocumentManager) PurgeTrashedDocuments(ctx context.Context, deletedBefore time.Time) (int, error) {
+	documents, err := m.uowFactory.Create().DocumentRepo().FindTrashedBefore(ctx, deletedBefore)
+	if err != nil {
+		return 0, ccc.NewDatabaseError("failed to find expired trashed documents", err)
+	}
+
+	purged := 0
+	for _, document := range documents {
+		uow := m.uowFactory.Create()
+		err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+			return purgeDocument(ctx, uow, document.Id)
+		})
+		if err != nil {
+			m.logger.Error("Failed to purge document", "userId", document.UserId, "documentId", document.Id, "error", err)
+			return purged, err
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		m.logger.Info("Purged expired documents from the trash", "count", purged, "deletedBefore", deletedBefore)
+	}
+	return purged, nil
+}
+
+// purgeDocument deletes a document and all its associated data within the given unit of work
+func purgeDocument(ctx context.Context, uow DocumentUnitOfWork, documentId string) error {
+	// Delete document tags
+	if err := uow.DocumentTagRepo().RemoveAllDocumentTags(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to remove document tags", err)
+	}
+
+	// Delete document notes
+	if err := uow.NoteRepo().DeleteByDocumentId(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to delete document notes", err)
+	}
+
+	// Delete pending OCR jobs
+	if err := uow.OcrJobRepo().DeleteByDocumentId(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to delete OCR jobs", err)
+	}
+
+	// Delete unfinished uploads
+	if err := uow.UploadSessionRepo().DeleteByDocumentId(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to delete upload sessions", err)
+	}
+
+	// Delete file metadata
+	if err := uow.DocumentFileMetadataRepo().DeleteByDocumentId(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to delete file metadata", err)
+	}
+
+	// Delete files
+	if err := uow.DocumentFileRepo().DeleteByDocumentId(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to delete document files", err)
+	}
+
+	// Delete document
+	if err := uow.DocumentRepo().Delete(ctx, documentId); err != nil {
+		return ccc.NewDatabaseError("failed to delete document", err)
+	}
+
+	return nil
+}
+
 // Helper methods
 
 func (m *DefaultDocumentManager) validateCreateDocumentRequest(request CreateDocumentRequest) error {
@@ -572,5 +723,8 @@ func (m *DefaultDocumentManager) decryptDocumentDetails(documentDetails []*Docum
 			m.logger.Warn("Failed to decrypt issuer", "documentId", detail.Document.Id, "error", err)
 			detail.Document.Issuer = "" // Fallback
 		}
+
+		decryptTagNames(detail.Tags, dataProtector, m.logger)
+		sortTagsByName(detail.Tags)
 	}
 }
@@ -0,0 +1,325 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+// DefaultOCRJobProcessor processes the persisted OCR job queue.
+// Each job is claimed atomically, so multiple workers can share the same processor.
+type DefaultOCRJobProcessor struct {
+	uowFactory        DocumentUnitOfWorkFactory
+	processorFactory  DocumentFileProcessorFactory
+	encryptionService encryption.EncryptionService
+	keyProvider       OCRJobKeyProvider
+	ocrConfig         ccc.OCRConfig
+	logger            ccc.Logger
+}
+
+// NewDefaultOCRJobProcessor creates a new DefaultOCRJobProcessor instance.
+func NewDefaultOCRJobProcessor(
+	uowFactory DocumentUnitOfWorkFactory,
+	processorFactory DocumentFileProcessorFactory,
+	encryptionService encryption.EncryptionService,
+	keyProvider OCRJobKeyProvider,
+	ocrConfig ccc.OCRConfig,
+	logger ccc.Logger,
+) *DefaultOCRJobProcessor {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &DefaultOCRJobProcessor{
+		uowFactory:        uowFactory,
+		processorFactory:  processorFactory,
+		encryptionService: encryptionService,
+		keyProvider:       keyProvider,
+		ocrConfig:         ocrConfig,
+		logger:            logger,
+	}
+}
+
+// ProcessNext claims and processes the next due job. It returns false if no job was due.
+func (p *DefaultOCRJobProcessor) ProcessNext(ctx context.Context) (bool, error) {
+	job, err := p.uowFactory.Create().OcrJobRepo().ClaimNext(ctx, time.Now())
+	if err != nil {
+		return false, fmt.Errorf("failed to claim OCR job: %w", err)
+	}
+	if job == nil {
+		return false, nil
+	}
+
+	p.logger.Debug("Processing text extraction job", "jobId", job.Id, "fileId", job.DocumentFileId, "attempt", job.Attempts)
+
+	if err := p.process(ctx, job); err != nil {
+		return true, err
+	}
+	return true, nil
+}
+
+// RecoverOrphanedJobs re-queues jobs that were being processed when the application stopped
+// and fails files that are stuck in the processing state without a job.
+func (p *DefaultOCRJobProcessor) RecoverOrphanedJobs(ctx context.Context) error {
+	return p.uowFactory.Create().Execute(ctx, func(uow DocumentUnitOfWork) error {
+		jobs, err := uow.OcrJobRepo().FindByStatus(ctx, OcrJobStatusProcessing)
+		if err != nil {
+			return fmt.Errorf("failed to find interrupted OCR jobs: %w", err)
+		}
+
+		now := time.Now()
+		for _, job := range jobs {
+			if job.Attempts >= p.maxAttempts() {
+				p.logger.Warn("Interrupted text extraction job has no attempts left", "jobId", job.Id, "fileId", job.DocumentFileId)
+				if err := p.failJob(ctx, uow, job, errors.New("text extraction was interrupted")); err != nil {
+					return err
+				}
+				continue
+			}
+
+			job.Status = OcrJobStatusQueued
+			job.NextRunAt = now
+			job.ModifiedAt = now
+			if err := uow.OcrJobRepo().Update(ctx, job); err != nil {
+				return fmt.Errorf("failed to re-queue OCR job: %w", err)
+			}
+			p.logger.Info("Re-queued interrupted text extraction job", "jobId", job.Id, "fileId", job.DocumentFileId)
+		}
+
+		// Files queued before the job queue existed cannot be processed anymore
+		orphans, err := uow.DocumentFileMetadataRepo().FindProcessingWithoutJob(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to find orphaned OCR files: %w", err)
+		}
+		for _, metadata := range orphans {
+			metadata.OcrStatus = OcrStatusFailed
+			metadata.OcrError = "text extraction was interrupted"
+			metadata.OcrCompletedAt = &now
+			if err := uow.DocumentFileMetadataRepo().Update(ctx, metadata); err != nil {
+				return fmt.Errorf("failed to update orphaned OCR file: %w", err)
+			}
+			p.logger.Warn("Marked orphaned text extraction as failed", "fileId", metadata.DocumentFileId)
+		}
+
+		return nil
+	})
+}
+
+// process runs the text extraction of a claimed job and persists its outcome.
+func (p *DefaultOCRJobProcessor) process(ctx context.Context, job *OcrJob) error {
+	file, err := p.uowFactory.Create().DocumentFileRepo().FindById(ctx, job.DocumentFileId)
+	if err != nil {
+		return p.retryJob(job, fmt.Errorf("failed to load document file: %w", err))
+	}
+	if file == nil {
+		// The file was deleted while the job was queued
+		return p.uowFactory.Create().OcrJobRepo().Delete(context.Background(), job.Id)
+	}
+
+	key, err := p.keyProvider.GetOCRJobKey()
+	if err != nil {
+		return p.retryJob(job, fmt.Errorf("failed to get OCR job key: %w", err))
+	}
+	wrappingKey, err := p.encryptionService.ConvertKeyToString(key)
+	if err != nil {
+		return p.retryJob(job, fmt.Errorf("failed to convert OCR job key: %w", err))
+	}
+
+	// A MEK that cannot be unwrapped will never become readable, e.g. because the job key was rotated
+	dataProtector, err := dataprotection.UnwrapKeyDataProtector(p.encryptionService, job.WrappedMek, wrappingKey)
+	if err != nil {
+		return p.finishJob(job, 0, failedMetadata(job.DocumentFileId, err))
+	}
+
+	if file.FileSize > MaxProcessedFileSize {
+		return p.finishJob(job, 0, &DocumentFileMetadata{
+			DocumentFileId: job.DocumentFileId,
+			OcrStatus:      OcrStatusSkipped,
+			OcrError:       fmt.Sprintf("The file is larger than %d MB, which is the limit for text extraction", MaxProcessedFileSize/(1024*1024)),
+		})
+	}
+
+	encryptedContent, err := p.uowFactory.Create().DocumentFileRepo().OpenContent(ctx, job.DocumentFileId)
+	if err != nil {
+		return p.retryJob(job, fmt.Errorf("failed to open document file content: %w", err))
+	}
+	if encryptedContent == nil {
+		return p.uowFactory.Create().OcrJobRepo().Delete(context.Background(), job.Id)
+	}
+	content, err := dataProtector.UnprotectStream(encryptedContent)
+	if err != nil {
+		return p.finishJob(job, 0, failedMetadata(job.DocumentFileId, fmt.Errorf("failed to decrypt file data: %w", err)))
+	}
+	// Reading fails for damaged contents as well as for unavailable blob stores, so the job is retried
+	fileData, err := io.ReadAll(content)
+	if err != nil {
+		return p.retryJob(job, fmt.Errorf("failed to read file data: %w", err))
+	}
+
+	processor, err := p.processorFactory.GetProcessor(file.ContentType)
+	if err != nil {
+		return p.finishJob(job, 0, failedMetadata(job.DocumentFileId, err))
+	}
+
+	text, confidence, pageCount, err := processor.ExtractText(ctx, fileData)
+
+	if errors.Is(err, ErrOCRSkipped) {
+		return p.finishJob(job, pageCount, &DocumentFileMetadata{
+			DocumentFileId: job.DocumentFileId,
+			OcrStatus:      OcrStatusSkipped,
+		})
+	}
+
+	if err != nil {
+		if ctx.Err() != nil {
+			// Shutdown interrupted the extraction, so the attempt does not count
+			job.Attempts--
+			return p.retryJob(job, err)
+		}
+		p.logger.Warn("Text extraction attempt failed", "error", err, "jobId", job.Id, "fileId", job.DocumentFileId, "attempt", job.Attempts)
+		return p.retryJob(job, err)
+	}
+
+	var encryptedText string
+	if text != "" {
+		encryptedText, err = dataProtector.Protect(text)
+		if err != nil {
+			return p.finishJob(job, 0, failedMetadata(job.DocumentFileId, err))
+		}
+	}
+
+	return p.finishJob(job, pageCount, &DocumentFileMetadata{
+		DocumentFileId: job.DocumentFileId,
+		ExtractedText:  encryptedText,
+		OcrConfidence:  confidence,
+		OcrStatus:      OcrStatusCompleted,
+	})
+}
+
+// retryJob puts the job back into the queue with an exponential backoff, or fails it if no attempts are left.
+func (p *DefaultOCRJobProcessor) retryJob(job *OcrJob, jobErr error) error {
+	if job.Attempts >= p.maxAttempts() {
+		return p.finishJob(job, 0, failedMetadata(job.DocumentFileId, jobErr))
+	}
+
+	backoff := retryBackoff(p.ocrConfig.RetryInitialBackoffSeconds, 2)
+	maxBackoff := retryBackoff(p.ocrConfig.RetryMaxBackoffSeconds, 30)
+	for i := 1; i < job.Attempts && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+
+	now := time.Now()
+	job.Status = OcrJobStatusQueued
+	job.NextRunAt = now.Add(minDuration(backoff, maxBackoff))
+	job.LastError = jobErr.Error()
+	job.ModifiedAt = now
+
+	// The job context may already be cancelled, but the job state must be persisted regardless
+	if err := p.uowFactory.Create().OcrJobRepo().Update(context.Background(), job); err != nil {
+		return fmt.Errorf("failed to re-queue OCR job: %w", err)
+	}
+	return nil
+}
+
+// finishJob persists the final extraction result and removes the job in a single transaction.
+func (p *DefaultOCRJobProcessor) finishJob(job *OcrJob, pageCount int, metadata *DocumentFileMetadata) error {
+	ctx := context.Background()
+
+	if metadata.OcrStatus == OcrStatusFailed {
+		p.logger.Warn("Text extraction failed", "error", metadata.OcrError, "jobId", job.Id, "fileId", job.DocumentFileId)
+	}
+
+	return p.uowFactory.Create().Execute(ctx, func(uow DocumentUnitOfWork) error {
+		existing, err := uow.DocumentFileMetadataRepo().FindByDocumentFileId(ctx, job.DocumentFileId)
+		if err != nil {
+			return err
+		}
+		if existing == nil {
+			// The file was deleted in the meantime
+			return uow.OcrJobRepo().Delete(ctx, job.Id)
+		}
+
+		completedAt := time.Now()
+		metadata.OcrStartedAt = existing.OcrStartedAt
+		metadata.OcrCompletedAt = &completedAt
+
+		// A failed or skipped re-run must not discard text extracted by an earlier run
+		if metadata.OcrStatus != OcrStatusCompleted {
+			metadata.ExtractedText = existing.ExtractedText
+			metadata.OcrConfidence = existing.OcrConfidence
+		}
+
+		if pageCount > 0 {
+			file, err := uow.DocumentFileRepo().FindById(ctx, job.DocumentFileId)
+			if err != nil {
+				return err
+			}
+			if file != nil {
+				file.PageCount = pageCount
+				file.ModifiedAt = completedAt
+				if err := uow.DocumentFileRepo().Update(ctx, file); err != nil {
+					return err
+				}
+			}
+		}
+
+		if err := uow.DocumentFileMetadataRepo().Update(ctx, metadata); err != nil {
+			return err
+		}
+		return uow.OcrJobRepo().Delete(ctx, job.Id)
+	})
+}
+
+// failJob marks the file of the job as failed and removes the job within the given unit of work.
+func (p *DefaultOCRJobProcessor) failJob(ctx context.Context, uow DocumentUnitOfWork, job *OcrJob, jobErr error) error {
+	existing, err := uow.DocumentFileMetadataRepo().FindByDocumentFileId(ctx, job.DocumentFileId)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		metadata := failedMetadata(job.DocumentFileId, jobErr)
+		completedAt := time.Now()
+		metadata.ExtractedText = existing.ExtractedText
+		metadata.OcrConfidence = existing.OcrConfidence
+		metadata.OcrStartedAt = existing.OcrStartedAt
+		metadata.OcrCompletedAt = &completedAt
+		if err := uow.DocumentFileMetadataRepo().Update(ctx, metadata); err != nil {
+			return err
+		}
+	}
+	return uow.OcrJobRepo().Delete(ctx, job.Id)
+}
+
+func (p *DefaultOCRJobProcessor) maxAttempts() int {
+	if p.ocrConfig.MaxAttempts <= 0 {
+		return 3
+	}
+	return p.ocrConfig.MaxAttempts
+}
+
+func failedMetadata(fileId string, err error) *DocumentFileMetadata {
+	return &DocumentFileMetadata{
+		DocumentFileId: fileId,
+		OcrStatus:      OcrStatusFailed,
+		OcrError:       err.Error(),
+	}
+}
+
+func retryBackoff(configuredSeconds int, defaultSeconds int) time.Duration {
+	if configuredSeconds <= 0 {
+		configuredSeconds = defaultSeconds
+	}
+	return time.Duration(configuredSeconds) * time.Second
+}
+
+func minDuration(left, right time.Duration) time.Duration {
+	if left < right {
+		return left
+	}
+	return right
+}
@@ -0,0 +1,238 @@
+package documents
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// flakyFileProcessor extracts the contents of text files as text after failing a given number of times
+type flakyFileProcessor struct{ failures int }
+
+func (p *flakyFileProcessor) SupportsContentType(contentType string) bool {
+	return contentType == "text/plain"
+}
+
+func (p *flakyFileProcessor) ExtractText(ctx context.Context, fileData []byte) (string, float32, int, error) {
+	if p.failures > 0 {
+		p.failures--
+		return "", 0, 0, errors.New("OCR engine unavailable")
+	}
+	return string(fileData), 100, 1, nil
+}
+
+func (p *flakyFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	return nil, errors.New("no preview")
+}
+
+type staticOCRJobKeyProvider struct{ key []byte }
+
+func (p staticOCRJobKeyProvider) GetOCRJobKey() ([]byte, error) { return p.key, nil }
+
+// ocrJobTest holds a database with a document of user-1 and the services queueing and processing its OCR jobs
+type ocrJobTest struct {
+	t             *testing.T
+	uowFactory    DocumentUnitOfWorkFactory
+	fileProcessor *flakyFileProcessor
+	jobProcessor  *DefaultOCRJobProcessor
+	fileCreator   *DefaultDocumentFileCreator
+	dispatcher    *DefaultOCRDispatcherFactory
+	dataProtector dataprotection.DataProtector
+}
+
+func newOCRJobTest(t *testing.T) *ocrJobTest {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	encryptionService := encryption.NewDefaultEncryptionService()
+	mek, _ := encryptionService.GenerateKey()
+	jobKey, _ := encryptionService.GenerateKey()
+	jobKeyBytes, _ := encryptionService.ConvertStringToKey(jobKey)
+	keyProvider := staticOCRJobKeyProvider{key: jobKeyBytes}
+
+	uowFactory := NewDocumentUnitOfWorkFactory(db, nil)
+	fileProcessor := &flakyFileProcessor{}
+	processorFactory := NewDefaultDocumentFileProcessorFactory(fileProcessor)
+	ocrConfig := ccc.OCRConfig{MaxAttempts: 2, RetryInitialBackoffSeconds: 60, RetryMaxBackoffSeconds: 60}
+
+	now := time.Now()
+	if err := uowFactory.Create().DocumentRepo().Add(context.Background(), &Document{Id: "doc-1", UserId: "user-1", CreatedAt: now, ModifiedAt: now}); err != nil {
+		t.Fatalf("failed to add document: %v", err)
+	}
+
+	return &ocrJobTest{
+		t:             t,
+		uowFactory:    uowFactory,
+		fileProcessor: fileProcessor,
+		jobProcessor:  NewDefaultOCRJobProcessor(uowFactory, processorFactory, encryptionService, keyProvider, ocrConfig, nil),
+		fileCreator:   NewDefaultDocumentFileCreator(ccc.NewUuidGenerator(), processorFactory, 1024*1024, nil),
+		dispatcher:    NewDefaultOCRDispatcherFactory(ccc.NewUuidGenerator(), encryptionService, keyProvider, nil, nil),
+		dataProtector: dataprotection.NewKeyDataProtector(encryptionService, mek),
+	}
+}
+
+// addFile stores a text file in the document of user-1 and queues its text extraction
+func (s *ocrJobTest) addFile(content string) string {
+	return s.addFileTo("user-1", "doc-1", content)
+}
+
+// addFileTo stores a text file in a document of a user and queues its text extraction
+func (s *ocrJobTest) addFileTo(userId, documentId, content string) string {
+	ctx := context.Background()
+	var fileId string
+	err := s.uowFactory.Create().Execute(ctx, func(uow DocumentUnitOfWork) error {
+		file, _, err := s.fileCreator.CreateDocumentFile(ctx, uow, CreateFileRequest{
+			UserId: userId, DocumentId: documentId, FileName: "scan.txt", ContentType: "text/plain", FileData: []byte(content),
+		}, s.dataProtector, s.dispatcher.Create())
+		if err != nil {
+			return err
+		}
+		fileId = file.Id
+		return nil
+	})
+	if err != nil {
+		s.t.Fatalf("failed to add file: %v", err)
+	}
+	return fileId
+}
+
+func (s *ocrJobTest) processNext() bool {
+	processed, err := s.jobProcessor.ProcessNext(context.Background())
+	if err != nil {
+		s.t.Fatalf("failed to process job: %v", err)
+	}
+	return processed
+}
+
+func (s *ocrJobTest) jobs(status string) []*OcrJob {
+	jobs, err := s.uowFactory.Create().OcrJobRepo().FindByStatus(context.Background(), status)
+	if err != nil {
+		s.t.Fatalf("failed to find jobs: %v", err)
+	}
+	return jobs
+}
+
+func (s *ocrJobTest) metadata(fileId string) *DocumentFileMetadata {
+	metadata, err := s.uowFactory.Create().DocumentFileMetadataRepo().FindByDocumentFileId(context.Background(), fileId)
+	if err != nil || metadata == nil {
+		s.t.Fatalf("failed to find metadata: %v", err)
+	}
+	return metadata
+}
+
+func TestOCRJobIsClaimedAndCompleted(t *testing.T) {
+	s := newOCRJobTest(t)
+	fileId := s.addFile("invoice 4711")
+
+	if jobs := s.jobs(OcrJobStatusQueued); len(jobs) != 1 || jobs[0].WrappedMek == "" {
+		t.Fatalf("expected one queued job with a wrapped MEK, got %v", jobs)
+	}
+
+	if !s.processNext() {
+		t.Fatalf("expected the queued job to be claimed")
+	}
+	if s.processNext() {
+		t.Fatalf("expected no further job to be claimed")
+	}
+
+	metadata := s.metadata(fileId)
+	if metadata.OcrStatus != OcrStatusCompleted {
+		t.Fatalf("expected status %s, got %s (%s)", OcrStatusCompleted, metadata.OcrStatus, metadata.OcrError)
+	}
+	if text, err := s.dataProtector.Unprotect(metadata.ExtractedText); err != nil || text != "invoice 4711" {
+		t.Fatalf("unexpected extracted text %q: %v", text, err)
+	}
+	if len(s.jobs(OcrJobStatusQueued))+len(s.jobs(OcrJobStatusProcessing)) != 0 {
+		t.Fatalf("expected the job to be removed")
+	}
+}
+
+func TestOCRJobIsRetriedUntilNoAttemptsAreLeft(t *testing.T) {
+	ctx := context.Background()
+	s := newOCRJobTest(t)
+	s.fileProcessor.failures = 2
+	fileId := s.addFile("invoice 4711")
+
+	s.processNext()
+	jobs := s.jobs(OcrJobStatusQueued)
+	if len(jobs) != 1 || jobs[0].Attempts != 1 || jobs[0].LastError == "" {
+		t.Fatalf("expected the failed job to be queued again, got %v", jobs)
+	}
+	if !jobs[0].NextRunAt.After(time.Now()) {
+		t.Fatalf("expected the retry to be delayed")
+	}
+	if s.processNext() {
+		t.Fatalf("expected the delayed job not to be claimed")
+	}
+
+	// Make the retry due, the second failure uses up the attempts
+	jobs[0].NextRunAt = time.Now()
+	if err := s.uowFactory.Create().OcrJobRepo().Update(ctx, jobs[0]); err != nil {
+		t.Fatalf("failed to update job: %v", err)
+	}
+	if !s.processNext() {
+		t.Fatalf("expected the due job to be claimed")
+	}
+
+	if metadata := s.metadata(fileId); metadata.OcrStatus != OcrStatusFailed || metadata.OcrError == "" {
+		t.Fatalf("expected the file to fail, got %s", metadata.OcrStatus)
+	}
+	if len(s.jobs(OcrJobStatusQueued)) != 0 {
+		t.Fatalf("expected the failed job to be removed")
+	}
+}
+
+func TestOCRJobsAreRecoveredAfterInterruption(t *testing.T) {
+	ctx := context.Background()
+	s := newOCRJobTest(t)
+	interruptedFileId := s.addFile("invoice 4711")
+	exhaustedFileId := s.addFile("invoice 4712")
+
+	// Both jobs are claimed by a worker that stops before processing them
+	repo := s.uowFactory.Create().OcrJobRepo()
+	for range 2 {
+		if job, err := repo.ClaimNext(ctx, time.Now()); err != nil || job == nil {
+			t.Fatalf("failed to claim job: %v", err)
+		}
+	}
+	for _, job := range s.jobs(OcrJobStatusProcessing) {
+		if job.DocumentFileId == exhaustedFileId {
+			job.Attempts = 2
+			if err := repo.Update(ctx, job); err != nil {
+				t.Fatalf("failed to update job: %v", err)
+			}
+		}
+	}
+
+	if err := s.jobProcessor.RecoverOrphanedJobs(ctx); err != nil {
+		t.Fatalf("failed to recover jobs: %v", err)
+	}
+
+	jobs := s.jobs(OcrJobStatusQueued)
+	if len(jobs) != 1 || jobs[0].DocumentFileId != interruptedFileId {
+		t.Fatalf("expected the interrupted job to be queued again, got %v", jobs)
+	}
+	if metadata := s.metadata(exhaustedFileId); metadata.OcrStatus != OcrStatusFailed {
+		t.Fatalf("expected the job without attempts left to fail, got %s", metadata.OcrStatus)
+	}
+
+	s.processNext()
+	if metadata := s.metadata(interruptedFileId); metadata.OcrStatus != OcrStatusCompleted {
+		t.Fatalf("expected the recovered job to complete, got %s", metadata.OcrStatus)
+	}
+}
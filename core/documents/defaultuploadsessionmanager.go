@@ -0,0 +1,479 @@
+package documents
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+)
+
+const (
+	// UploadSessionLifetime is the time after which an upload session expires if no part is received
+	UploadSessionLifetime = 24 * time.Hour
+	// MaxUploadChunkSize is the maximum size of a single part. Without a blob store, parts are held in memory while they are stored.
+	MaxUploadChunkSize = 16 * 1024 * 1024
+	// maxUploadSessionsPerUser limits the unfinished uploads of a user, whose parts take up storage until they expire
+	maxUploadSessionsPerUser = 20
+)
+
+// DefaultUploadSessionManager implements UploadSessionManager interface
+type DefaultUploadSessionManager struct {
+	uowFactory           DocumentUnitOfWorkFactory
+	sessionIdGen         UploadSessionIdGenerator
+	fileCreator          DocumentFileCreator
+	ocrDispatcherFactory OCRDispatcherFactory
+	maxFileSize          int64
+	logger               ccc.Logger
+}
+
+// NewDefaultUploadSessionManager creates a new DefaultUploadSessionManager instance.
+// Uploads of files larger than maxFileSize bytes are rejected when they are announced.
+func NewDefaultUploadSessionManager(
+	uowFactory DocumentUnitOfWorkFactory,
+	sessionIdGen UploadSessionIdGenerator,
+	fileCreator DocumentFileCreator,
+	ocrDispatcherFactory OCRDispatcherFactory,
+	maxFileSize int64,
+	logger ccc.Logger,
+) *DefaultUploadSessionManager {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &DefaultUploadSessionManager{
+		uowFactory:           uowFactory,
+		sessionIdGen:         sessionIdGen,
+		fileCreator:          fileCreator,
+		ocrDispatcherFactory: ocrDispatcherFactory,
+		maxFileSize:          maxFileSize,
+		logger:               logger,
+	}
+}
+
+// CreateUploadSession announces a file that is uploaded to a document in parts
+func (m *DefaultUploadSessionManager) CreateUploadSession(
+	ctx context.Context,
+	userId, documentId string,
+	request CreateUploadSessionRequest,
+	dataProtector dataprotection.DataProtector,
+) (*UploadSessionDto, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if documentId == "" {
+		return nil, ccc.NewInvalidInputError("documentId", "cannot be empty")
+	}
+	if err := m.validateCreateUploadSessionRequest(request); err != nil {
+		return nil, err
+	}
+
+	encryptedFileName, err := dataProtector.Protect(request.FileName)
+	if err != nil {
+		return nil, ccc.NewInternalError("failed to encrypt file name", err)
+	}
+
+	now := time.Now()
+	session := &UploadSession{
+		Id:          m.sessionIdGen.GenerateId(),
+		UserId:      userId,
+		DocumentId:  documentId,
+		FileName:    encryptedFileName,
+		ContentType: request.ContentType,
+		Size:        request.Size,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(UploadSessionLifetime),
+	}
+
+	uow := m.uowFactory.Create()
+	err = uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		// Verify document exists and belongs to user
+		document, err := uow.DocumentRepo().FindById(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document", err)
+		}
+		if document == nil || document.UserId != userId {
+			return ccc.NewResourceNotFoundError("document", documentId)
+		}
+
+		count, err := uow.UploadSessionRepo().CountByUserId(ctx, userId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to count upload sessions", err)
+		}
+		if count >= maxUploadSessionsPerUser {
+			return ccc.NewConflictError("Too many unfinished uploads. Complete or cancel them before starting another one.",
+				fmt.Sprintf("user %s has %d upload sessions", userId, count))
+		}
+
+		if err := uow.UploadSessionRepo().Add(ctx, session); err != nil {
+			return ccc.NewDatabaseError("failed to add upload session", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Info("Upload session created", "userId", userId, "documentId", documentId, "sessionId", session.Id, "size", session.Size)
+	return m.buildUploadSessionDto(session, dataProtector), nil
+}
+
+// GetUploadSession returns the state of an upload, e.g. to resume it at its offset after a connection was lost
+func (m *DefaultUploadSessionManager) GetUploadSession(
+	ctx context.Context,
+	userId, documentId, sessionId string,
+	dataProtector dataprotection.DataProtector,
+) (*UploadSessionDto, error) {
+	session, err := m.findUploadSession(ctx, m.uowFactory.Create(), userId, documentId, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	return m.buildUploadSessionDto(session, dataProtector), nil
+}
+
+// UploadChunk encrypts and stores the next part of a file and extends the lifetime of the session.
+// The part is rejected with a conflict if it does not start at the offset of the session, so a part
+// whose response was lost can be sent again without being stored twice.
+func (m *DefaultUploadSessionManager) UploadChunk(
+	ctx context.Context,
+	userId, documentId, sessionId string,
+	request UploadChunkRequest,
+	dataProtector dataprotection.DataProtector,
+) (*UploadSessionDto, error) {
+	uow := m.uowFactory.Create()
+	session, err := m.findUploadSession(ctx, uow, userId, documentId, sessionId)
+	if err != nil {
+		return nil, err
+	}
+
+	if request.Offset != session.Offset {
+		return nil, offsetConflictError(session)
+	}
+	if request.Content == nil || request.Size <= 0 {
+		return nil, ccc.NewInvalidInputErrorWithMessage("content", "cannot be empty", "The part of the file is empty")
+	}
+	if request.Size > MaxUploadChunkSize {
+		return nil, ccc.NewInvalidInputErrorWithMessage("size", "exceeds maximum chunk size",
+			fmt.Sprintf("A part cannot be larger than %d MB", MaxUploadChunkSize/(1024*1024)))
+	}
+	if request.Offset+request.Size > session.Size {
+		return nil, ccc.NewInvalidInputErrorWithMessage("size", "exceeds the size of the file",
+			fmt.Sprintf("The part exceeds the announced file size of %d bytes", session.Size))
+	}
+
+	part := &UploadSessionPart{
+		SessionId: session.Id,
+		Offset:    request.Offset,
+		Size:      request.Size,
+	}
+	expiresAt := time.Now().Add(UploadSessionLifetime)
+
+	// The part is encrypted while it is received; the parts of a session are encrypted separately,
+	// so a lost connection only costs the part that was being uploaded
+	counter := &limitedCountingReader{reader: request.Content, limit: request.Size}
+	err = uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		encryptedContent, waitForEncryption := encryptContent(counter, dataProtector)
+		added, err := uow.UploadSessionRepo().AddPart(ctx, part, encryptedContent, expiresAt)
+		waitForEncryption()
+		if errors.Is(err, errFileTooLarge) || (err == nil && counter.count != request.Size) {
+			return ccc.NewInvalidInputErrorWithMessage("content", "size differs from the announced size",
+				fmt.Sprintf("The part does not have the announced size of %d bytes", request.Size))
+		}
+		if err != nil {
+			return ccc.NewDatabaseError("failed to store upload part", err)
+		}
+		if !added {
+			// Another part was stored since the session was read
+			current, err := uow.UploadSessionRepo().FindById(ctx, session.Id)
+			if err != nil || current == nil {
+				return ccc.NewResourceNotFoundError("upload session", session.Id)
+			}
+			return offsetConflictError(current)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	session.Offset += request.Size
+	session.ExpiresAt = expiresAt
+	return m.buildUploadSessionDto(session, dataProtector), nil
+}
+
+// CompleteUploadSession creates the file from the parts of a completely received upload and deletes the session.
+// The parts are decrypted one after another while the file creator reads them, so the file is processed and
+// encrypted exactly like a file uploaded at once.
+func (m *DefaultUploadSessionManager) CompleteUploadSession(
+	ctx context.Context,
+	userId, documentId, sessionId string,
+	dataProtector dataprotection.DataProtector,
+) (*DocumentFileDto, error) {
+	uow := m.uowFactory.Create()
+	var createdFile *DocumentFile
+	var createdMetadata *DocumentFileMetadata
+	var fileName string
+	ocrDispatcher := m.ocrDispatcherFactory.Create()
+
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		session, err := m.findUploadSession(ctx, uow, userId, documentId, sessionId)
+		if err != nil {
+			return err
+		}
+		if session.Offset != session.Size {
+			return ccc.NewConflictError(fmt.Sprintf("The upload is incomplete, %d of %d bytes were received", session.Offset, session.Size),
+				fmt.Sprintf("upload session %s is at offset %d of %d", session.Id, session.Offset, session.Size))
+		}
+
+		// Verify document exists and belongs to user
+		document, err := uow.DocumentRepo().FindById(ctx, documentId)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find document", err)
+		}
+		if document == nil || document.UserId != userId {
+			return ccc.NewResourceNotFoundError("document", documentId)
+		}
+
+		fileName, err = dataProtector.Unprotect(session.FileName)
+		if err != nil {
+			return ccc.NewInternalError("failed to decrypt file name", err)
+		}
+
+		parts, err := uow.UploadSessionRepo().FindParts(ctx, session.Id)
+		if err != nil {
+			return ccc.NewDatabaseError("failed to find upload parts", err)
+		}
+		var received int64
+		for _, part := range parts {
+			if part.Offset != received {
+				return ccc.NewInternalError(fmt.Sprintf("upload session %s is missing the part at offset %d", session.Id, received), nil)
+			}
+			received += part.Size
+		}
+		if received != session.Size {
+			return ccc.NewInternalError(fmt.Sprintf("upload session %s has parts of %d bytes, expected %d", session.Id, received, session.Size), nil)
+		}
+
+		createFileReq := CreateFileRequest{
+			UserId:      userId,
+			DocumentId:  documentId,
+			FileName:    fileName,
+			ContentType: session.ContentType,
+			Content:     &uploadPartsReader{ctx: ctx, repo: uow.UploadSessionRepo(), dataProtector: dataProtector, parts: parts},
+		}
+
+		var createErr error
+		createdFile, createdMetadata, createErr = m.fileCreator.CreateDocumentFile(ctx, uow, createFileReq, dataProtector, ocrDispatcher)
+		if ccc.IsValidationError(createErr) {
+			return createErr
+		}
+		if createErr != nil {
+			return ccc.NewDatabaseError("failed to create document file", createErr)
+		}
+
+		if err := uow.UploadSessionRepo().Delete(ctx, session.Id); err != nil {
+			return ccc.NewDatabaseError("failed to delete upload session", err)
+		}
+
+		// Update document's modified time
+		document.ModifiedAt = time.Now()
+		if err := uow.DocumentRepo().Update(ctx, document); err != nil {
+			return ccc.NewDatabaseError("failed to update document modified time", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	ocrDispatcher.Dispatch()
+
+	m.logger.Info("Upload session completed", "userId", userId, "documentId", documentId, "sessionId", sessionId, "fileId", createdFile.Id)
+
+	dto := &DocumentFileDto{
+		Id:          createdFile.Id,
+		DocumentId:  createdFile.DocumentId,
+		FileName:    fileName,
+		ContentType: createdFile.ContentType,
+		FileSize:    createdFile.FileSize,
+		PageCount:   createdFile.PageCount,
+		CreatedAt:   createdFile.CreatedAt,
+		ModifiedAt:  createdFile.ModifiedAt,
+	}
+	if createdMetadata != nil {
+		dto.OcrStatus = createdMetadata.OcrStatus
+		dto.OcrError = createdMetadata.OcrError
+	}
+	return dto, nil
+}
+
+// AbortUploadSession deletes an upload session together with the parts received so far
+func (m *DefaultUploadSessionManager) AbortUploadSession(ctx context.Context, userId, documentId, sessionId string) error {
+	uow := m.uowFactory.Create()
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		session, err := m.findUploadSession(ctx, uow, userId, documentId, sessionId)
+		if err != nil {
+			return err
+		}
+		if err := uow.UploadSessionRepo().Delete(ctx, session.Id); err != nil {
+			return ccc.NewDatabaseError("failed to delete upload session", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	m.logger.Info("Upload session aborted", "userId", userId, "documentId", documentId, "sessionId", sessionId)
+	return nil
+}
+
+// DeleteExpiredUploadSessions deletes the sessions of all users that expired before the given time.
+// Each session is deleted in its own transaction; the number of deleted sessions is returned also if one of them failed.
+func (m *DefaultUploadSessionManager) DeleteExpiredUploadSessions(ctx context.Context, now time.Time) (int, error) {
+	sessions, err := m.uowFactory.Create().UploadSessionRepo().FindExpired(ctx, now)
+	if err != nil {
+		return 0, ccc.NewDatabaseError("failed to find expired upload sessions", err)
+	}
+
+	deleted := 0
+	for _, session := range sessions {
+		uow := m.uowFactory.Create()
+		err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+			return uow.UploadSessionRepo().Delete(ctx, session.Id)
+		})
+		if err != nil {
+			m.logger.Error("Failed to delete expired upload session", "userId", session.UserId, "sessionId", session.Id, "error", err)
+			return deleted, ccc.NewDatabaseError("failed to delete upload session", err)
+		}
+		deleted++
+	}
+
+	if deleted > 0 {
+		m.logger.Info("Deleted expired upload sessions", "count", deleted)
+	}
+	return deleted, nil
+}
+
+// findUploadSession finds an upload session of the given user and document. Expired sessions that were not
+// deleted yet are not found either.
+func (m *DefaultUploadSessionManager) findUploadSession(
+	ctx context.Context,
+	uow DocumentUnitOfWork,
+	userId, documentId, sessionId string,
+) (*UploadSession, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if documentId == "" {
+		return nil, ccc.NewInvalidInputError("documentId", "cannot be empty")
+	}
+	if sessionId == "" {
+		return nil, ccc.NewInvalidInputError("sessionId", "cannot be empty")
+	}
+
+	session, err := uow.UploadSessionRepo().FindById(ctx, sessionId)
+	if err != nil {
+		return nil, ccc.NewDatabaseError("failed to find upload session", err)
+	}
+	if session == nil || session.UserId != userId || session.DocumentId != documentId || !session.ExpiresAt.After(time.Now()) {
+		return nil, ccc.NewResourceNotFoundError("upload session", sessionId)
+	}
+	return session, nil
+}
+
+func (m *DefaultUploadSessionManager) validateCreateUploadSessionRequest(request CreateUploadSessionRequest) error {
+	const maxFileNameLength = 255
+
+	if request.FileName == "" {
+		return ccc.NewInvalidInputErrorWithMessage("fileName", "cannot be empty", "File name is required")
+	}
+	if len(request.FileName) > maxFileNameLength {
+		return ccc.NewInvalidInputErrorWithMessage("fileName", "exceeds maximum length", fmt.Sprintf("File name cannot be longer than %d characters", maxFileNameLength))
+	}
+	if request.ContentType == "" {
+		return ccc.NewInvalidInputErrorWithMessage("contentType", "cannot be empty", "Content type is required")
+	}
+	if request.Size <= 0 {
+		return ccc.NewInvalidInputErrorWithMessage("size", "must be positive", "File data is required")
+	}
+	if request.Size > m.maxFileSize {
+		return ccc.NewInvalidInputErrorWithMessage("size", "exceeds maximum size", fmt.Sprintf("File cannot be larger than %d MB", m.maxFileSize/(1024*1024)))
+	}
+	return nil
+}
+
+func (m *DefaultUploadSessionManager) buildUploadSessionDto(session *UploadSession, dataProtector dataprotection.DataProtector) *UploadSessionDto {
+	dto := &UploadSessionDto{
+		Id:           session.Id,
+		DocumentId:   session.DocumentId,
+		ContentType:  session.ContentType,
+		Size:         session.Size,
+		Offset:       session.Offset,
+		MaxChunkSize: MaxUploadChunkSize,
+		CreatedAt:    session.CreatedAt,
+		ExpiresAt:    session.ExpiresAt,
+	}
+
+	if decrypted, err := dataProtector.Unprotect(session.FileName); err == nil {
+		dto.FileName = decrypted
+	} else {
+		m.logger.Warn("Failed to decrypt filename", "sessionId", session.Id, "error", err)
+		dto.FileName = "Encrypted File"
+	}
+
+	return dto
+}
+
+// offsetConflictError is returned if a part does not start at the offset of its session
+func offsetConflictError(session *UploadSession) error {
+	return ccc.NewConflictError(fmt.Sprintf("The upload continues at offset %d", session.Offset),
+		fmt.Sprintf("upload session %s is at offset %d", session.Id, session.Offset))
+}
+
+// uploadPartsReader reads the decrypted parts of an upload session one after another.
+// Each part is loaded and decrypted only once the previous one was read completely.
+type uploadPartsReader struct {
+	ctx           context.Context
+	repo          UploadSessionRepository
+	dataProtector dataprotection.DataProtector
+	parts         []*UploadSessionPart
+	current       io.Reader
+}
+
+func (r *uploadPartsReader) Read(p []byte) (int, error) {
+	for {
+		if r.current == nil {
+			if len(r.parts) == 0 {
+				return 0, io.EOF
+			}
+			part := r.parts[0]
+			r.parts = r.parts[1:]
+
+			encryptedContent, err := r.repo.OpenPartContent(r.ctx, part.SessionId, part.Offset)
+			if err != nil {
+				return 0, err
+			}
+			if encryptedContent == nil {
+				return 0, fmt.Errorf("part at offset %d of upload session %s is missing", part.Offset, part.SessionId)
+			}
+			r.current, err = r.dataProtector.UnprotectStream(encryptedContent)
+			if err != nil {
+				return 0, fmt.Errorf("failed to decrypt part at offset %d of upload session %s: %w", part.Offset, part.SessionId, err)
+			}
+		}
+
+		n, err := r.current.Read(p)
+		if err == io.EOF {
+			r.current = nil
+			if n == 0 {
+				continue
+			}
+			err = nil
+		}
+		return n, err
+	}
+}
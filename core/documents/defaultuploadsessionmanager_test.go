@@ -0,0 +1,153 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// queueingOCRDispatcherFactory creates dispatchers that only count the queued jobs
+type queueingOCRDispatcherFactory struct{ queued int }
+
+func (f *queueingOCRDispatcherFactory) Create() OCRDispatcher { return f }
+
+func (f *queueingOCRDispatcherFactory) Enqueue(ctx context.Context, uow DocumentUnitOfWork, request OCRDispatchRequest) error {
+	f.queued++
+	return nil
+}
+
+func (f *queueingOCRDispatcherFactory) Dispatch() {}
+
+func TestUploadSessionIsResumedAndCompleted(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+	store, err := blobstore.NewFileSystemBlobStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	encryptionService := encryption.NewDefaultEncryptionService()
+	mek, _ := encryptionService.GenerateKey()
+	dataProtector := dataprotection.NewKeyDataProtector(encryptionService, mek)
+
+	uowFactory := NewDocumentUnitOfWorkFactory(db, store)
+	ocrDispatcherFactory := &queueingOCRDispatcherFactory{}
+	fileCreator := NewDefaultDocumentFileCreator(ccc.NewUuidGenerator(), NewDefaultDocumentFileProcessorFactory(NewTextFileProcessor()), 1024*1024, nil)
+	manager := NewDefaultUploadSessionManager(uowFactory, ccc.NewUuidGenerator(), fileCreator, ocrDispatcherFactory, 1024*1024, nil)
+
+	now := time.Now().UTC()
+	document := &Document{Id: "doc-1", UserId: "user-1", CreatedAt: now, ModifiedAt: now}
+	if err := uowFactory.Create().DocumentRepo().Add(ctx, document); err != nil {
+		t.Fatalf("failed to add document: %v", err)
+	}
+
+	content := []byte(strings.Repeat("scanned page\n", 10000))
+	session, err := manager.CreateUploadSession(ctx, "user-1", "doc-1", CreateUploadSessionRequest{
+		FileName: "scan.txt", ContentType: "text/plain", Size: int64(len(content)),
+	}, dataProtector)
+	if err != nil {
+		t.Fatalf("failed to create upload session: %v", err)
+	}
+
+	uploadChunk := func(offset, end int) (*UploadSessionDto, error) {
+		return manager.UploadChunk(ctx, "user-1", "doc-1", session.Id, UploadChunkRequest{
+			Offset: int64(offset), Size: int64(end - offset), Content: bytes.NewReader(content[offset:end]),
+		}, dataProtector)
+	}
+
+	if _, err := uploadChunk(0, 50000); err != nil {
+		t.Fatalf("failed to upload first part: %v", err)
+	}
+	// A part whose response was lost is sent again and rejected, the client continues at the reported offset
+	if _, err := uploadChunk(0, 50000); !ccc.IsErrorCode(err, ccc.ErrCodeConflict) {
+		t.Fatalf("expected a part at an old offset to conflict, got %v", err)
+	}
+	// A part that was cut off is not stored
+	_, err = manager.UploadChunk(ctx, "user-1", "doc-1", session.Id, UploadChunkRequest{
+		Offset: 50000, Size: 50000, Content: bytes.NewReader(content[50000:60000]),
+	}, dataProtector)
+	if !ccc.IsValidationError(err) {
+		t.Fatalf("expected an incomplete part to be rejected, got %v", err)
+	}
+	if _, err := manager.CompleteUploadSession(ctx, "user-1", "doc-1", session.Id, dataProtector); !ccc.IsErrorCode(err, ccc.ErrCodeConflict) {
+		t.Fatalf("expected completing an incomplete upload to conflict, got %v", err)
+	}
+
+	// Sessions are scoped to their user and document
+	if _, err := manager.GetUploadSession(ctx, "user-2", "doc-1", session.Id, dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected the session to be hidden from other users, got %v", err)
+	}
+	resumed, err := manager.GetUploadSession(ctx, "user-1", "doc-1", session.Id, dataProtector)
+	if err != nil || resumed.Offset != 50000 || resumed.FileName != "scan.txt" {
+		t.Fatalf("unexpected session state: %+v, %v", resumed, err)
+	}
+
+	if _, err := uploadChunk(50000, 100000); err != nil {
+		t.Fatalf("failed to upload second part: %v", err)
+	}
+	if _, err := uploadChunk(100000, len(content)); err != nil {
+		t.Fatalf("failed to upload last part: %v", err)
+	}
+
+	// The encrypted parts in the blob store are kept by the garbage collection, only the rejected part is unreferenced
+	sources := []blobstore.BlobReferenceSource{NewSQLiteDocumentBlobReferenceRepository(db)}
+	summary, err := blobstore.NewDefaultBlobGarbageCollector(store, sources, nil).CollectGarbage(ctx, time.Now().Add(time.Hour), true)
+	if err != nil || summary.Scanned != 4 || summary.Unreferenced != 1 {
+		t.Fatalf("expected the parts to be referenced: %+v, %v", summary, err)
+	}
+	var stored []byte
+	if err := db.QueryRow(`SELECT Data FROM UploadSessionPart WHERE PartOffset = 0`).Scan(&stored); err != nil || len(stored) != 0 {
+		t.Fatalf("expected the part to be kept in the blob store: %v", err)
+	}
+
+	file, err := manager.CompleteUploadSession(ctx, "user-1", "doc-1", session.Id, dataProtector)
+	if err != nil {
+		t.Fatalf("failed to complete upload: %v", err)
+	}
+	if file.FileName != "scan.txt" || file.FileSize != int64(len(content)) || ocrDispatcherFactory.queued != 1 {
+		t.Fatalf("unexpected file: %+v, queued jobs: %d", file, ocrDispatcherFactory.queued)
+	}
+	if _, err := manager.GetUploadSession(ctx, "user-1", "doc-1", session.Id, dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected the completed session to be deleted, got %v", err)
+	}
+
+	_, reader, err := NewDefaultDocumentFileManager(uowFactory, fileCreator, ocrDispatcherFactory, nil).OpenDocumentFile(ctx, "user-1", "doc-1", file.Id, dataProtector)
+	if err != nil {
+		t.Fatalf("failed to open file: %v", err)
+	}
+	if data, err := io.ReadAll(reader); err != nil || !bytes.Equal(data, content) {
+		t.Fatalf("file content differs from the uploaded parts: %v", err)
+	}
+
+	// Unfinished sessions are deleted once they expired
+	if _, err := manager.CreateUploadSession(ctx, "user-1", "doc-1", CreateUploadSessionRequest{
+		FileName: "other.txt", ContentType: "text/plain", Size: 10,
+	}, dataProtector); err != nil {
+		t.Fatalf("failed to create upload session: %v", err)
+	}
+	if deleted, err := manager.DeleteExpiredUploadSessions(ctx, time.Now()); err != nil || deleted != 0 {
+		t.Fatalf("expected no session to be expired yet: %d, %v", deleted, err)
+	}
+	if deleted, err := manager.DeleteExpiredUploadSessions(ctx, time.Now().Add(UploadSessionLifetime+time.Minute)); err != nil || deleted != 1 {
+		t.Fatalf("expected the unfinished session to be deleted: %d, %v", deleted, err)
+	}
+}
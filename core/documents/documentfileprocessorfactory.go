@@ -3,6 +3,8 @@ package documents
 import (
 	"fmt"
 	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
 // DefaultDocumentFileProcessorFactory implements DocumentFileProcessorFactory
@@ -17,6 +19,25 @@ func NewDefaultDocumentFileProcessorFactory(processors ...DocumentFileProcessor)
 	}
 }
 
+// NewDocumentFileProcessorFactoryForConfig creates a factory with processors for all supported content types.
+// PDF pages are rendered and HEIC images converted with the configured tools if they are installed.
+func NewDocumentFileProcessorFactoryForConfig(config ccc.AppConfig, ocrService OCRService, logger ccc.Logger) *DefaultDocumentFileProcessorFactory {
+	imageProcessor := NewImageFileProcessor(ocrService)
+	processors := []DocumentFileProcessor{
+		NewPDFFileProcessor(NewPDFRendererFromConfig(config.PDF, logger), ocrService, config.PDF, logger),
+		imageProcessor,
+		NewTIFFFileProcessor(imageProcessor, config.PDF.MaxOCRPages, logger),
+		NewHEICFileProcessor(NewImageConverterFromConfig(config, logger), imageProcessor, logger),
+		NewTextFileProcessor(),
+		NewDOCXFileProcessor(),
+		NewODTFileProcessor(),
+	}
+
+	// Attachments of mails are processed like uploaded files
+	emlProcessor := NewEMLFileProcessor(NewDefaultDocumentFileProcessorFactory(processors...), logger)
+	return NewDefaultDocumentFileProcessorFactory(append(processors, emlProcessor)...)
+}
+
 // GetProcessor returns an appropriate DocumentFileProcessor for the given content type
 func (f *DefaultDocumentFileProcessorFactory) GetProcessor(contentType string) (DocumentFileProcessor, error) {
 	contentType = strings.ToLower(contentType)
@@ -0,0 +1,201 @@
+package documents
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// widthOCRService "recognizes" the width of an image, so tests can tell the pages of a file apart
+type widthOCRService struct{}
+
+func (s *widthOCRService) IsOcrEnabled() bool { return true }
+
+func (s *widthOCRService) ExtractText(ctx context.Context, imageData []byte) (string, float32, error) {
+	config, _, err := image.DecodeConfig(bytes.NewReader(imageData))
+	if err != nil {
+		return "", 0, err
+	}
+	return fmt.Sprintf("%d pixels wide", config.Width), 0.5, nil
+}
+
+// buildTestTIFF writes an uncompressed grayscale TIFF with one page of the given width per entry
+func buildTestTIFF(widths ...int) []byte {
+	const height = 4
+	var buf bytes.Buffer
+	buf.WriteString("II*\x00")
+	binary.Write(&buf, binary.LittleEndian, uint32(0))
+
+	// Position of the offset that points at the next directory, which is in the header for the first page
+	nextOffsetPosition := 4
+	for _, width := range widths {
+		pixelOffset := uint32(buf.Len())
+		buf.Write(bytes.Repeat([]byte{0xff}, width*height))
+
+		type entry struct {
+			tag, kind uint16
+			count     uint32
+			value     uint32
+		}
+		entries := []entry{
+			{256, 3, 1, uint32(width)},          // ImageWidth
+			{257, 3, 1, height},                 // ImageLength
+			{258, 3, 1, 8},                      // BitsPerSample
+			{259, 3, 1, 1},                      // Compression: none
+			{262, 3, 1, 1},                      // PhotometricInterpretation: black is zero
+			{273, 4, 1, pixelOffset},            // StripOffsets
+			{277, 3, 1, 1},                      // SamplesPerPixel
+			{278, 3, 1, height},                 // RowsPerStrip
+			{279, 4, 1, uint32(width * height)}, // StripByteCounts
+		}
+		ifdOffset := uint32(buf.Len())
+		binary.LittleEndian.PutUint32(buf.Bytes()[nextOffsetPosition:], ifdOffset)
+		binary.Write(&buf, binary.LittleEndian, uint16(len(entries)))
+		for _, e := range entries {
+			binary.Write(&buf, binary.LittleEndian, e.tag)
+			binary.Write(&buf, binary.LittleEndian, e.kind)
+			binary.Write(&buf, binary.LittleEndian, e.count)
+			binary.Write(&buf, binary.LittleEndian, e.value)
+		}
+		nextOffsetPosition = buf.Len()
+		binary.Write(&buf, binary.LittleEndian, uint32(0))
+	}
+	return buf.Bytes()
+}
+
+// buildTestZip writes a zip archive like an office document
+func buildTestZip(files map[string]string) []byte {
+	var buf bytes.Buffer
+	writer := zip.NewWriter(&buf)
+	for name, content := range files {
+		file, _ := writer.Create(name)
+		file.Write([]byte(content))
+	}
+	writer.Close()
+	return buf.Bytes()
+}
+
+func TestProcessorsExtractTextOfAllFormats(t *testing.T) {
+	config := ccc.AppConfig{PDF: ccc.PDFConfig{MaxOCRPages: 10}}
+	factory := NewDocumentFileProcessorFactoryForConfig(config, &widthOCRService{}, nil)
+
+	docx := buildTestZip(map[string]string{
+		"word/document.xml": `<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+			`<w:p><w:r><w:t>Invoice</w:t></w:r><w:r><w:tab/><w:t xml:space="preserve">2024-0815</w:t></w:r></w:p>` +
+			`<w:p><w:r><w:t>Total due</w:t></w:r></w:p></w:body></w:document>`,
+		"docProps/app.xml": `<Properties xmlns="http://schemas.openxmlformats.org/officeDocument/2006/extended-properties"><Pages>3</Pages></Properties>`,
+	})
+	odt := buildTestZip(map[string]string{
+		"content.xml": `<office:document-content xmlns:office="urn:oasis:names:tc:opendocument:xmlns:office:1.0" xmlns:text="urn:oasis:names:tc:opendocument:xmlns:text:1.0">` +
+			`<office:body><office:text><text:h>Lease</text:h><text:p>Rent<text:s text:c="3"/>monthly</text:p></office:text></office:body></office:document-content>`,
+	})
+	eml := strings.ReplaceAll(`From: Alice <alice@example.com>
+To: bob@example.com
+Subject: =?utf-8?q?Gr=C3=BC=C3=9Fe?=
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain; charset=utf-8
+Content-Transfer-Encoding: quoted-printable
+
+Please find the invoice attached. Sch=C3=B6ne Gr=C3=BC=C3=9Fe
+--inner
+Content-Type: text/html; charset=utf-8
+
+<p>HTML version</p>
+--inner--
+--outer
+Content-Type: application/octet-stream; name="invoice.docx"
+Content-Disposition: attachment; filename="invoice.docx"
+Content-Transfer-Encoding: base64
+
+`+base64.StdEncoding.EncodeToString(docx)+`
+--outer--
+`, "\n", "\r\n")
+
+	tests := []struct {
+		name          string
+		fileName      string
+		contentType   string
+		data          []byte
+		wantPages     int
+		wantText      []string
+		unwantedTexts []string
+	}{
+		{
+			name: "multi-page TIFF", fileName: "fax.tif", contentType: "image/tiff", data: buildTestTIFF(10, 20, 30),
+			wantPages: 3, wantText: []string{"10 pixels wide\n\n20 pixels wide\n\n30 pixels wide"},
+		},
+		{
+			name: "Windows-1252 text", fileName: "notes.txt", contentType: "text/plain", data: []byte("Caf\xe9 receipt"),
+			wantPages: 1, wantText: []string{"Café receipt"},
+		},
+		{
+			name: "Markdown", fileName: "README.md", contentType: "application/octet-stream", data: []byte("# Warranty\n\nValid until 2027"),
+			wantPages: 1, wantText: []string{"# Warranty", "Valid until 2027"},
+		},
+		{
+			name: "DOCX", fileName: "invoice.docx", contentType: "application/vnd.openxmlformats-officedocument.wordprocessingml.document", data: docx,
+			wantPages: 3, wantText: []string{"Invoice\t2024-0815\nTotal due"},
+		},
+		{
+			name: "ODT", fileName: "lease.odt", contentType: "", data: odt,
+			wantPages: 1, wantText: []string{"Lease\nRent   monthly"},
+		},
+		{
+			name: "EML", fileName: "mail.eml", contentType: "message/rfc822", data: []byte(eml),
+			wantPages: 1,
+			wantText: []string{"Subject: Grüße", "From: Alice <alice@example.com>", "Schöne Grüße",
+				"Attachment: invoice.docx\nInvoice\t2024-0815"},
+			unwantedTexts: []string{"HTML version"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			contentType := ResolveContentType(test.fileName, test.contentType)
+			if !IsSupportedContentType(contentType) {
+				t.Fatalf("content type %q of %s is not supported", contentType, test.fileName)
+			}
+			processor, err := factory.GetProcessor(contentType)
+			if err != nil {
+				t.Fatalf("GetProcessor failed: %v", err)
+			}
+
+			text, _, pageCount, err := processor.ExtractText(context.Background(), test.data)
+			if err != nil {
+				t.Fatalf("ExtractText failed: %v", err)
+			}
+			if pageCount != test.wantPages {
+				t.Errorf("expected %d pages, got %d", test.wantPages, pageCount)
+			}
+			for _, want := range test.wantText {
+				if !strings.Contains(text, want) {
+					t.Errorf("expected the text to contain %q, got %q", want, text)
+				}
+			}
+			for _, unwanted := range test.unwantedTexts {
+				if strings.Contains(text, unwanted) {
+					t.Errorf("expected the text not to contain %q, got %q", unwanted, text)
+				}
+			}
+
+			preview, err := processor.GeneratePreview(context.Background(), test.data)
+			if err != nil || preview == nil || len(preview.PreviewData) == 0 {
+				t.Errorf("expected a preview, got %v with error %v", preview, err)
+			}
+		})
+	}
+}
@@ -157,6 +157,8 @@ func (s *DefaultDocumentSearchEngine) SearchDocuments(
 		// Only add result if we found any matches
 		if len(matchTypes) > 0 {
 			// Build tag DTOs from DocumentDetails
+			decryptTagNames(docDetail.Tags, dataProtector, s.logger)
+			sortTagsByName(docDetail.Tags)
 			tagDtos := make([]*TagDto, 0, len(docDetail.Tags))
 			for _, tag := range docDetail.Tags {
 				tagDtos = append(tagDtos, &TagDto{
@@ -4,14 +4,16 @@ import (
 	"context"
 	"database/sql"
 
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
 // DefaultDocumentUnitOfWork implements DocumentUnitOfWork interface.
 // It acts as both a transaction manager and a repository factory.
 type DefaultDocumentUnitOfWork struct {
-	db *sql.DB
-	tx *sql.Tx
+	db    *sql.DB
+	tx    *sql.Tx
+	blobs blobstore.BlobStore
 
 	// Cached repository instances - created lazily
 	documentRepo     DocumentRepository
@@ -20,12 +22,16 @@ type DefaultDocumentUnitOfWork struct {
 	tagRepo          TagRepository
 	documentTagRepo  DocumentTagRepository
 	noteRepo         NoteRepository
+	ocrJobRepo       OcrJobRepository
+	uploadRepo       UploadSessionRepository
 }
 
 // NewDocumentUnitOfWork creates a new DefaultDocumentUnitOfWork instance.
-func NewDocumentUnitOfWork(db *sql.DB) *DefaultDocumentUnitOfWork {
+// If blobs is nil, file contents and previews are stored in the database.
+func NewDocumentUnitOfWork(db *sql.DB, blobs blobstore.BlobStore) *DefaultDocumentUnitOfWork {
 	return &DefaultDocumentUnitOfWork{
-		db: db,
+		db:    db,
+		blobs: blobs,
 	}
 }
 
@@ -85,7 +91,7 @@ func (uow *DefaultDocumentUnitOfWork) DocumentRepo() DocumentRepository {
 func (uow *DefaultDocumentUnitOfWork) DocumentFileRepo() DocumentFileRepository {
 	if uow.documentFileRepo == nil {
 		executor := uow.getExecutor()
-		uow.documentFileRepo = newSQLiteDocumentFileRepository(executor)
+		uow.documentFileRepo = newSQLiteDocumentFileRepository(executor, uow.blobs)
 	}
 	return uow.documentFileRepo
 }
@@ -126,6 +132,24 @@ func (uow *DefaultDocumentUnitOfWork) NoteRepo() NoteRepository {
 	return uow.noteRepo
 }
 
+// OcrJobRepo returns an OcrJobRepository instance.
+func (uow *DefaultDocumentUnitOfWork) OcrJobRepo() OcrJobRepository {
+	if uow.ocrJobRepo == nil {
+		executor := uow.getExecutor()
+		uow.ocrJobRepo = newSQLiteOcrJobRepository(executor)
+	}
+	return uow.ocrJobRepo
+}
+
+// UploadSessionRepo returns an UploadSessionRepository instance.
+func (uow *DefaultDocumentUnitOfWork) UploadSessionRepo() UploadSessionRepository {
+	if uow.uploadRepo == nil {
+		executor := uow.getExecutor()
+		uow.uploadRepo = newSQLiteUploadSessionRepository(executor, uow.blobs)
+	}
+	return uow.uploadRepo
+}
+
 // getExecutor returns the appropriate database executor.
 // If a transaction is active, it returns the transaction.
 // Otherwise, it returns the regular database connection.
@@ -145,6 +169,8 @@ func (uow *DefaultDocumentUnitOfWork) clearRepositoryCache() {
 	uow.tagRepo = nil
 	uow.documentTagRepo = nil
 	uow.noteRepo = nil
+	uow.ocrJobRepo = nil
+	uow.uploadRepo = nil
 }
 
 // cleanup resets the transaction state and clears repository cache.
@@ -218,35 +244,20 @@ func (uow *DefaultDocumentUnitOfWork) ExecuteWithResult(ctx context.Context, fn
 
 // DefaultDocumentUnitOfWorkFactory implements DocumentUnitOfWorkFactory interface.
 type DefaultDocumentUnitOfWorkFactory struct {
-	db *sql.DB
+	db    *sql.DB
+	blobs blobstore.BlobStore
 }
 
 // NewDocumentUnitOfWorkFactory creates a new DefaultDocumentUnitOfWorkFactory instance.
-func NewDocumentUnitOfWorkFactory(db *sql.DB) *DefaultDocumentUnitOfWorkFactory {
-	factory := &DefaultDocumentUnitOfWorkFactory{
-		db: db,
+// If blobs is nil, file contents and previews are stored in the database.
+func NewDocumentUnitOfWorkFactory(db *sql.DB, blobs blobstore.BlobStore) *DefaultDocumentUnitOfWorkFactory {
+	return &DefaultDocumentUnitOfWorkFactory{
+		db:    db,
+		blobs: blobs,
 	}
-
-	// Initialize all tables immediately to ensure they exist before any operations
-	factory.initializeTables()
-
-	return factory
 }
 
 // Create creates a new DocumentUnitOfWork instance.
 func (f *DefaultDocumentUnitOfWorkFactory) Create() DocumentUnitOfWork {
-	return NewDocumentUnitOfWork(f.db)
-}
-
-// initializeTables initializes all database tables by creating temporary repositories
-// This ensures all tables exist before any transactional operations occur
-func (f *DefaultDocumentUnitOfWorkFactory) initializeTables() {
-	// Create temporary repositories with direct DB connection to trigger table initialization
-	// These calls will create the tables if they don't exist
-	newSQLiteDocumentRepository(f.db)
-	newSQLiteDocumentFileRepository(f.db)
-	newSQLiteDocumentFileMetadataRepository(f.db)
-	newSQLiteTagRepository(f.db)
-	newSQLiteDocumentTagRepository(f.db)
-	newSQLiteNoteRepository(f.db)
+	return NewDocumentUnitOfWork(f.db, f.blobs)
 }
@@ -0,0 +1,153 @@
+package documents
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// maxOfficeXMLSize limits the uncompressed size of an XML part read from an office document
+const maxOfficeXMLSize = 64 * 1024 * 1024
+
+// wordprocessingMLNamespace is the XML namespace of the elements of Word documents
+const wordprocessingMLNamespace = "http://schemas.openxmlformats.org/wordprocessingml/2006/main"
+
+// DOCXFileProcessor handles Word documents. Their text is read from the document XML, without rendering.
+type DOCXFileProcessor struct {
+	// No dependencies needed for this simple implementation
+}
+
+// NewDOCXFileProcessor creates a new DOCXFileProcessor
+func NewDOCXFileProcessor() *DOCXFileProcessor {
+	return &DOCXFileProcessor{}
+}
+
+// SupportsContentType checks if this processor can handle Word document content types
+func (p *DOCXFileProcessor) SupportsContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+}
+
+// ExtractText extracts the text of the document body, footnotes and endnotes
+func (p *DOCXFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
+	archive, err := zip.NewReader(bytes.NewReader(fileData), int64(len(fileData)))
+	if err != nil {
+		return "", 0.0, 0, fmt.Errorf("failed to open Word document: %w", err)
+	}
+
+	var parts []string
+	for i, name := range []string{"word/document.xml", "word/footnotes.xml", "word/endnotes.xml"} {
+		data, err := readZipFile(archive, name)
+		if err != nil {
+			if i == 0 {
+				return "", 0.0, 0, err
+			}
+			continue // Footnotes and endnotes are optional
+		}
+		partText, err := wordprocessingMLText(data)
+		if err != nil {
+			return "", 0.0, 0, fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		if partText = strings.TrimSpace(partText); partText != "" {
+			parts = append(parts, partText)
+		}
+	}
+
+	return strings.Join(parts, "\n\n"), 1.0, docxPageCount(archive), nil
+}
+
+// GeneratePreview renders the beginning of the text as a page thumbnail
+func (p *DOCXFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	text, _, _, err := p.ExtractText(ctx, fileData)
+	if err != nil {
+		return nil, err
+	}
+	return renderTextPreview(text)
+}
+
+// wordprocessingMLText returns the text of the runs of a WordprocessingML part with one line per paragraph
+func wordprocessingMLText(data []byte) (string, error) {
+	var text strings.Builder
+	inText := false
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Space != wordprocessingMLNamespace {
+				continue
+			}
+			switch element.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				text.WriteString("\t")
+			case "br", "cr":
+				text.WriteString("\n")
+			}
+		case xml.EndElement:
+			if element.Name.Space != wordprocessingMLNamespace {
+				continue
+			}
+			switch element.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				text.WriteString("\n")
+			}
+		case xml.CharData:
+			if inText {
+				text.Write(element)
+			}
+		}
+	}
+}
+
+// docxPageCount returns the page count Word saved in the document properties, or 1 if there is none
+func docxPageCount(archive *zip.Reader) int {
+	data, err := readZipFile(archive, "docProps/app.xml")
+	if err != nil {
+		return 1
+	}
+	var properties struct {
+		Pages string `xml:"Pages"`
+	}
+	if err := xml.Unmarshal(data, &properties); err != nil {
+		return 1
+	}
+	if pages, err := strconv.Atoi(strings.TrimSpace(properties.Pages)); err == nil && pages > 0 {
+		return pages
+	}
+	return 1
+}
+
+// readZipFile reads a file of a zip archive like an office document, refusing files that are suspiciously large
+func readZipFile(archive *zip.Reader, name string) ([]byte, error) {
+	file, err := archive.Open(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", name, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, maxOfficeXMLSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", name, err)
+	}
+	if len(data) > maxOfficeXMLSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", name, maxOfficeXMLSize)
+	}
+	return data, nil
+}
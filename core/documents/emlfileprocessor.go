@@ -0,0 +1,317 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"strings"
+	"unicode"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/charset"
+)
+
+// maxEmailDepth limits how deep multipart bodies and attached mails are followed
+const maxEmailDepth = 10
+
+// emailHeaders are the headers of a mail that become part of its searchable text
+var emailHeaders = []string{"Subject", "From", "To", "Cc", "Date"}
+
+// EMLFileProcessor handles saved emails. Their headers, text bodies and the text of their attachments
+// become the searchable text of the file.
+type EMLFileProcessor struct {
+	attachmentProcessors DocumentFileProcessorFactory
+	logger               ccc.Logger
+}
+
+// NewEMLFileProcessor creates a new EMLFileProcessor. The text of attachments is extracted with the processors
+// of the given factory; attachments without a processor are listed by name only.
+func NewEMLFileProcessor(attachmentProcessors DocumentFileProcessorFactory, logger ccc.Logger) *EMLFileProcessor {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &EMLFileProcessor{
+		attachmentProcessors: attachmentProcessors,
+		logger:               logger,
+	}
+}
+
+// SupportsContentType checks if this processor can handle email content types
+func (p *EMLFileProcessor) SupportsContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "message/rfc822"
+}
+
+// ExtractText returns the headers, the text body and the text of the attachments of a mail
+func (p *EMLFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
+	email, err := p.readEmail(ctx, fileData, true, 0)
+	if err != nil {
+		return "", 0.0, 1, err
+	}
+	return email, 1.0, 1, nil
+}
+
+// GeneratePreview renders the headers and the beginning of the body as a page thumbnail
+func (p *EMLFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	email, err := p.readEmail(ctx, fileData, false, 0)
+	if err != nil {
+		return nil, err
+	}
+	return renderTextPreview(email)
+}
+
+// readEmail returns the text of a mail. The text of attachments is only extracted if withAttachments is set.
+func (p *EMLFileProcessor) readEmail(ctx context.Context, data []byte, withAttachments bool, depth int) (string, error) {
+	message, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return "", fmt.Errorf("failed to read email: %w", err)
+	}
+
+	var text strings.Builder
+	for _, name := range emailHeaders {
+		if value := message.Header.Get(name); value != "" {
+			fmt.Fprintf(&text, "%s: %s\n", name, decodeEmailHeader(value))
+		}
+	}
+
+	reader := &emailReader{processor: p, ctx: ctx, withAttachments: withAttachments}
+	err = reader.readPart(textproto.MIMEHeader(message.Header), message.Body, depth)
+	if err != nil {
+		return "", err
+	}
+
+	for _, body := range reader.bodies {
+		text.WriteString("\n" + body + "\n")
+	}
+	for _, attachment := range reader.attachments {
+		text.WriteString("\n" + attachment + "\n")
+	}
+	return text.String(), nil
+}
+
+// emailReader collects the bodies and attachments while walking through the parts of a mail
+type emailReader struct {
+	processor       *EMLFileProcessor
+	ctx             context.Context
+	withAttachments bool
+	bodies          []string
+	attachments     []string
+}
+
+// readPart adds the text of a part of a mail, following multipart bodies and attached mails
+func (r *emailReader) readPart(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxEmailDepth {
+		return nil
+	}
+	if err := r.ctx.Err(); err != nil {
+		return err
+	}
+
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", map[string]string{}
+	}
+	body = decodeTransferEncoding(header.Get("Content-Transfer-Encoding"), body)
+
+	fileName := ""
+	disposition, dispositionParams, err := mime.ParseMediaType(header.Get("Content-Disposition"))
+	if err == nil {
+		fileName = decodeEmailHeader(dispositionParams["filename"])
+	}
+	if fileName == "" {
+		fileName = decodeEmailHeader(params["name"])
+	}
+	isAttachment := disposition == "attachment" || fileName != ""
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return r.readMultipart(mediaType, params["boundary"], body, depth)
+	case !isAttachment && (mediaType == "text/plain" || mediaType == "text/html"):
+		text, err := readEmailText(mediaType, params["charset"], body)
+		if err != nil {
+			return err
+		}
+		if text = strings.TrimSpace(text); text != "" {
+			r.bodies = append(r.bodies, text)
+		}
+		return nil
+	case fileName == "" && disposition != "attachment" && mediaType != "message/rfc822":
+		// Inline resources like logos in HTML bodies are not attachments of interest
+		return nil
+	default:
+		return r.readAttachment(fileName, mediaType, body, depth)
+	}
+}
+
+// readMultipart reads the parts of a multipart body. Of alternative bodies, only the first text is used,
+// which is the plain text version in well-formed mails.
+func (r *emailReader) readMultipart(mediaType string, boundary string, body io.Reader, depth int) error {
+	if boundary == "" {
+		return nil
+	}
+
+	partReader := multipart.NewReader(body, boundary)
+	for {
+		part, err := partReader.NextRawPart()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			// A truncated mail still yields the parts read so far
+			r.processor.logger.Debug("Failed to read part of email", "error", err)
+			return nil
+		}
+
+		bodyCount := len(r.bodies)
+		if err := r.readPart(part.Header, part, depth+1); err != nil {
+			return err
+		}
+		if mediaType == "multipart/alternative" && len(r.bodies) > bodyCount {
+			return nil
+		}
+	}
+}
+
+// readAttachment adds the name and, if a processor exists for it, the text of an attachment
+func (r *emailReader) readAttachment(fileName string, mediaType string, body io.Reader, depth int) error {
+	if fileName == "" {
+		fileName = "unnamed attachment"
+	}
+	label := "Attachment: " + fileName
+	if !r.withAttachments {
+		r.attachments = append(r.attachments, label)
+		return nil
+	}
+
+	data, err := io.ReadAll(body)
+	if err != nil {
+		r.processor.logger.Debug("Failed to read attachment of email", "error", err)
+		r.attachments = append(r.attachments, label)
+		return nil
+	}
+
+	var text string
+	contentType := ResolveContentType(fileName, mediaType)
+	if contentType == "message/rfc822" {
+		text, err = r.processor.readEmail(r.ctx, data, true, depth+1)
+	} else if processor, lookupErr := r.processor.attachmentProcessors.GetProcessor(contentType); lookupErr == nil {
+		text, _, _, err = processor.ExtractText(r.ctx, data)
+	}
+	if err != nil {
+		if r.ctx.Err() != nil {
+			return r.ctx.Err()
+		}
+		if !errors.Is(err, ErrOCRSkipped) {
+			r.processor.logger.Warn("Failed to extract text of email attachment, skipping it", "content_type", contentType, "error", err)
+		}
+		text = ""
+	}
+
+	if text = strings.TrimSpace(text); text != "" {
+		label += "\n" + text
+	}
+	r.attachments = append(r.attachments, label)
+	return nil
+}
+
+// decodeTransferEncoding decodes base64 and quoted-printable bodies
+func decodeTransferEncoding(encoding string, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(encoding)) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	default:
+		return body
+	}
+}
+
+// readEmailText reads a text body in the given charset and converts HTML bodies to plain text
+func readEmailText(mediaType string, charsetLabel string, body io.Reader) (string, error) {
+	if charsetLabel != "" {
+		if decoded, err := charset.NewReaderLabel(charsetLabel, body); err == nil {
+			body = decoded
+		}
+	}
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return "", fmt.Errorf("failed to read email body: %w", err)
+	}
+	if mediaType == "text/html" {
+		return htmlText(data), nil
+	}
+	return string(data), nil
+}
+
+// htmlText returns the visible text of an HTML document, with a line break after block elements
+func htmlText(data []byte) string {
+	var text strings.Builder
+	skipDepth := 0
+
+	tokenizer := html.NewTokenizer(bytes.NewReader(data))
+	for {
+		switch tokenizer.Next() {
+		case html.ErrorToken:
+			return text.String()
+		case html.StartTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "head", "title":
+				skipDepth++
+			case "br":
+				text.WriteString("\n")
+			}
+		case html.EndTagToken:
+			name, _ := tokenizer.TagName()
+			switch string(name) {
+			case "script", "style", "head", "title":
+				skipDepth = max(skipDepth-1, 0)
+			case "p", "div", "tr", "li", "h1", "h2", "h3", "h4", "h5", "h6", "table", "blockquote":
+				text.WriteString("\n")
+			case "td", "th":
+				text.WriteString("\t")
+			}
+		case html.TextToken:
+			if skipDepth == 0 {
+				text.WriteString(collapseWhitespace(string(tokenizer.Text())))
+			}
+		}
+	}
+}
+
+// collapseWhitespace replaces runs of whitespace with a single space, like browsers do when rendering HTML
+func collapseWhitespace(value string) string {
+	var collapsed strings.Builder
+	inSpace := false
+	for _, r := range value {
+		if unicode.IsSpace(r) {
+			if !inSpace {
+				collapsed.WriteRune(' ')
+			}
+			inSpace = true
+			continue
+		}
+		collapsed.WriteRune(r)
+		inSpace = false
+	}
+	return collapsed.String()
+}
+
+// decodeEmailHeader decodes encoded words like =?utf-8?q?...?= in header values
+func decodeEmailHeader(value string) string {
+	decoder := &mime.WordDecoder{CharsetReader: charset.NewReaderLabel}
+	decoded, err := decoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+	return decoded
+}
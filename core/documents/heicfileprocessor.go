@@ -0,0 +1,60 @@
+package documents
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// HEICFileProcessor handles HEIC and HEIF photos, e.g. scans taken with a phone. The images are converted
+// to PNG with an ImageConverter and then processed like other images.
+type HEICFileProcessor struct {
+	converter      ImageConverter
+	imageProcessor *ImageFileProcessor
+	logger         ccc.Logger
+}
+
+// NewHEICFileProcessor creates a new HEICFileProcessor. A nil converter stores HEIC images without preview and text.
+func NewHEICFileProcessor(converter ImageConverter, imageProcessor *ImageFileProcessor, logger ccc.Logger) *HEICFileProcessor {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &HEICFileProcessor{
+		converter:      converter,
+		imageProcessor: imageProcessor,
+		logger:         logger,
+	}
+}
+
+// SupportsContentType checks if this processor can handle HEIC content types
+func (p *HEICFileProcessor) SupportsContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "image/heic" || contentType == "image/heif"
+}
+
+// ExtractText converts the image and extracts its text using OCR
+func (p *HEICFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
+	const imgPageCount = 1
+
+	if p.converter == nil {
+		return "", 0.0, imgPageCount, ErrOCRSkipped
+	}
+	pngData, err := p.converter.ConvertToPNG(ctx, fileData, "heic")
+	if err != nil {
+		return "", 0.0, imgPageCount, err
+	}
+	return p.imageProcessor.ExtractText(ctx, pngData)
+}
+
+// GeneratePreview converts the image and creates a thumbnail from it
+func (p *HEICFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	if p.converter == nil {
+		return nil, nil
+	}
+	pngData, err := p.converter.ConvertToPNG(ctx, fileData, "heic")
+	if err != nil {
+		return nil, err
+	}
+	return p.imageProcessor.GeneratePreview(ctx, pngData)
+}
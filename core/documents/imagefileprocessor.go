@@ -5,14 +5,16 @@ import (
 	"context"
 	"fmt"
 	"image"
+	_ "image/gif" // Register the GIF decoder
 	"image/jpeg"
 	"image/png"
 	"strings"
 
 	"github.com/nfnt/resize"
+	_ "golang.org/x/image/webp" // Register the WebP decoder
 )
 
-// ImageFileProcessor handles image file processing (PNG, JPEG, etc.)
+// ImageFileProcessor handles image file processing (PNG, JPEG, GIF, WebP)
 type ImageFileProcessor struct {
 	ocrService       OCRService
 	maxPreviewWidth  uint
@@ -44,7 +46,7 @@ func NewImageFileProcessorWithOptions(ocrService OCRService, maxWidth, maxHeight
 func (p *ImageFileProcessor) SupportsContentType(contentType string) bool {
 	contentType = strings.ToLower(contentType)
 	switch contentType {
-	case "image/jpeg", "image/jpg", "image/png":
+	case "image/jpeg", "image/jpg", "image/png", "image/gif", "image/webp":
 		return true
 	default:
 		return false
@@ -66,8 +68,14 @@ func (p *ImageFileProcessor) ExtractText(ctx context.Context, fileData []byte) (
 		return "", 0.0, imgPageCount, ErrOCRSkipped
 	}
 
+	// OCR engines reliably read PNG and JPEG only, so other formats are converted first
+	ocrData, err := ocrImageData(fileData)
+	if err != nil {
+		return "", 0.0, imgPageCount, err
+	}
+
 	// Use the OCR service to extract text from the image data
-	text, confidence, err = p.ocrService.ExtractText(ctx, fileData)
+	text, confidence, err = p.ocrService.ExtractText(ctx, ocrData)
 	if err != nil {
 		return "", 0.0, imgPageCount, err
 	}
@@ -123,6 +131,27 @@ func (p *ImageFileProcessor) GeneratePreview(ctx context.Context, fileData []byt
 	}, nil
 }
 
+// ocrImageData returns PNG and JPEG images unchanged and converts images of all other formats to PNG
+func ocrImageData(fileData []byte) ([]byte, error) {
+	_, format, err := image.DecodeConfig(bytes.NewReader(fileData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if format == "png" || format == "jpeg" {
+		return fileData, nil
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(fileData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to convert %s image for OCR: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
+
 // calculatePreviewDimensions calculates the preview dimensions while maintaining aspect ratio
 func (p *ImageFileProcessor) calculatePreviewDimensions(originalWidth, originalHeight uint) (uint, uint) {
 	// If image is already smaller than max dimensions, return original size
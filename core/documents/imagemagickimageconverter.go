@@ -0,0 +1,75 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// ImageMagickImageConverter converts images with ImageMagick. The image is passed on stdin and the
+// result is read from stdout, so no decrypted data is written to disk.
+type ImageMagickImageConverter struct {
+	executable string
+	logger     ccc.Logger
+}
+
+// NewImageMagickImageConverter creates a converter for the given ImageMagick executable, e.g. convert or magick.
+// It returns an error if the executable cannot be found.
+func NewImageMagickImageConverter(executablePath string, logger ccc.Logger) (*ImageMagickImageConverter, error) {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	if executablePath == "" {
+		return nil, fmt.Errorf("no image converter configured")
+	}
+	executable, err := exec.LookPath(executablePath)
+	if err != nil {
+		return nil, fmt.Errorf("image converter %q not found: %w", executablePath, err)
+	}
+	return &ImageMagickImageConverter{executable: executable, logger: logger}, nil
+}
+
+// ConvertToPNG converts the first image of the given format, e.g. heic, to PNG and applies its orientation
+func (c *ImageMagickImageConverter) ConvertToPNG(ctx context.Context, imageData []byte, format string) ([]byte, error) {
+	if format == "" || strings.ContainsAny(format, ":[]") {
+		return nil, fmt.Errorf("invalid image format %q", format)
+	}
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, c.executable, format+":-[0]", "-auto-orient", "png:-")
+	cmd.Stdin = bytes.NewReader(imageData)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to convert %s image: %w: %s", format, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("image converter returned no image")
+	}
+
+	c.logger.Debug("Converted image", "format", format, "bytes", stdout.Len())
+	return stdout.Bytes(), nil
+}
+
+// NewImageConverterFromConfig returns the configured image converter, or nil if it is disabled or not installed.
+// Without a converter, HEIC images are stored without preview and text.
+func NewImageConverterFromConfig(config ccc.AppConfig, logger ccc.Logger) ImageConverter {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	if config.ImageConverterPath == "" {
+		logger.Info("Image conversion is disabled, HEIC images get no previews and no OCR")
+		return nil
+	}
+	converter, err := NewImageMagickImageConverter(config.ImageConverterPath, logger)
+	if err != nil {
+		logger.Warn("Image converter not available, HEIC images get no previews and no OCR. Install ImageMagick with HEIC support to enable them.", "error", err)
+		return nil
+	}
+	return converter
+}
@@ -2,6 +2,7 @@ package documents
 
 import (
 	"context"
+	"io"
 	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
@@ -24,6 +25,14 @@ type NoteIdGenerator interface {
 	GenerateId() string
 }
 
+type OcrJobIdGenerator interface {
+	GenerateId() string
+}
+
+type UploadSessionIdGenerator interface {
+	GenerateId() string
+}
+
 // Core Repository Interfaces - Simple CRUD operations only
 type DocumentRepository interface {
 	FindById(ctx context.Context, documentId string) (*Document, error)
@@ -33,14 +42,23 @@ type DocumentRepository interface {
 	Update(ctx context.Context, document *Document) error
 	Delete(ctx context.Context, documentId string) error
 	GetFileCountByDocumentId(ctx context.Context, documentId string) (int, error)
+	// MoveToTrash marks a document as deleted, which hides it from all other queries
+	MoveToTrash(ctx context.Context, documentId string, deletedAt time.Time) error
+	RestoreFromTrash(ctx context.Context, documentId string) error
+	FindTrashedById(ctx context.Context, documentId string) (*Document, error)
+	FindTrashedByUserId(ctx context.Context, userId string) ([]*Document, error)
+	// FindTrashedBefore returns the documents of all users that were moved to the trash before the given time
+	FindTrashedBefore(ctx context.Context, deletedBefore time.Time) ([]*Document, error)
 }
 
 type DocumentFileRepository interface {
 	FindById(ctx context.Context, fileId string) (*DocumentFile, error)
 	FindByDocumentId(ctx context.Context, documentId string) ([]*DocumentFile, error)
 	FindDetailed(ctx context.Context, documentIds []string) ([]*DocumentFileDetails, error)
-	Add(ctx context.Context, file *DocumentFile) error
-	AddWithPreview(ctx context.Context, file *DocumentFile, preview *DocumentFilePreview) error
+	// OpenContent returns a reader for the encrypted content of a file, or nil if the file doesn't exist
+	OpenContent(ctx context.Context, fileId string) (io.Reader, error)
+	Add(ctx context.Context, file *DocumentFile, content io.Reader) error
+	AddWithPreview(ctx context.Context, file *DocumentFile, content io.Reader, preview *DocumentFilePreview) error
 	Update(ctx context.Context, file *DocumentFile) error
 	Delete(ctx context.Context, fileId string) error
 	DeleteByDocumentId(ctx context.Context, documentId string) error
@@ -54,26 +72,49 @@ type DocumentFileMetadataRepository interface {
 	FindByDocumentFileId(ctx context.Context, fileId string) (*DocumentFileMetadata, error)
 	FindByDocumentId(ctx context.Context, documentId string) ([]*DocumentFileMetadata, error)
 	FindExtended(ctx context.Context, documentIds []string) ([]*ExtendedDocumentFileMetadata, error)
+	FindProcessingWithoutJob(ctx context.Context) ([]*DocumentFileMetadata, error)
+	FindByUserIdAndOcrStatus(ctx context.Context, userId string, statuses []string) ([]*DocumentFileMetadata, error)
 	Add(ctx context.Context, metadata *DocumentFileMetadata) error
 	Update(ctx context.Context, metadata *DocumentFileMetadata) error
 	Delete(ctx context.Context, fileId string) error
 	DeleteByDocumentId(ctx context.Context, documentId string) error
 }
 
+type OcrJobRepository interface {
+	FindById(ctx context.Context, jobId string) (*OcrJob, error)
+	FindByStatus(ctx context.Context, status string) ([]*OcrJob, error)
+	ClaimNext(ctx context.Context, now time.Time) (*OcrJob, error)
+	Add(ctx context.Context, job *OcrJob) error
+	Update(ctx context.Context, job *OcrJob) error
+	Delete(ctx context.Context, jobId string) error
+	DeleteByDocumentFileId(ctx context.Context, fileId string) error
+	DeleteByDocumentId(ctx context.Context, documentId string) error
+}
+
 type TagRepository interface {
 	FindById(ctx context.Context, tagId string) (*Tag, error)
 	FindByUserId(ctx context.Context, userId string) ([]*Tag, error)
 	FindByDocumentId(ctx context.Context, documentId string) ([]*Tag, error)
-	FindByNameForUser(ctx context.Context, userId, name string) (*Tag, error)
+	FindByNameIndexForUser(ctx context.Context, userId, nameIndex string) (*Tag, error)
+	// FindWithoutNameIndexForUser returns the tags of a user whose names were stored before tag names were encrypted
+	FindWithoutNameIndexForUser(ctx context.Context, userId string) ([]*Tag, error)
 	Add(ctx context.Context, tag *Tag) error
 	Update(ctx context.Context, tag *Tag) error
 	Delete(ctx context.Context, tagId string) error
+	// MoveToTrash marks a tag as deleted, which hides it from all other queries
+	MoveToTrash(ctx context.Context, tagId string, deletedAt time.Time) error
+	RestoreFromTrash(ctx context.Context, tagId string) error
+	FindTrashedById(ctx context.Context, tagId string) (*Tag, error)
+	FindTrashedByUserId(ctx context.Context, userId string) ([]*Tag, error)
+	// FindTrashedBefore returns the tags of all users that were moved to the trash before the given time
+	FindTrashedBefore(ctx context.Context, deletedBefore time.Time) ([]*Tag, error)
 }
 
 type DocumentTagRepository interface {
 	AddDocumentTag(ctx context.Context, documentId, tagId string) error
 	RemoveDocumentTag(ctx context.Context, documentId, tagId string) error
 	RemoveAllDocumentTags(ctx context.Context, documentId string) error
+	RemoveAllTagDocuments(ctx context.Context, tagId string) error
 	FindDocumentsByTagId(ctx context.Context, tagId string) ([]*Document, error)
 }
 
@@ -86,6 +127,24 @@ type NoteRepository interface {
 	DeleteByDocumentId(ctx context.Context, documentId string) error
 }
 
+type UploadSessionRepository interface {
+	FindById(ctx context.Context, sessionId string) (*UploadSession, error)
+	// FindExpired returns the sessions of all users that expired before the given time
+	FindExpired(ctx context.Context, before time.Time) ([]*UploadSession, error)
+	CountByUserId(ctx context.Context, userId string) (int, error)
+	Add(ctx context.Context, session *UploadSession) error
+	// AddPart stores the encrypted content of a part and advances the offset of the session by the size of the part.
+	// It returns false without adding the part if the offset of the session is not the offset of the part anymore.
+	AddPart(ctx context.Context, part *UploadSessionPart, content io.Reader, expiresAt time.Time) (bool, error)
+	// FindParts returns the parts of a session ordered by their offset
+	FindParts(ctx context.Context, sessionId string) ([]*UploadSessionPart, error)
+	// OpenPartContent returns a reader for the encrypted content of a part, or nil if the part doesn't exist
+	OpenPartContent(ctx context.Context, sessionId string, offset int64) (io.Reader, error)
+	// Delete deletes a session together with its parts
+	Delete(ctx context.Context, sessionId string) error
+	DeleteByDocumentId(ctx context.Context, documentId string) error
+}
+
 // Unit of Work for transaction management
 type DocumentUnitOfWork interface {
 	Begin(ctx context.Context) error
@@ -99,6 +158,8 @@ type DocumentUnitOfWork interface {
 	TagRepo() TagRepository
 	DocumentTagRepo() DocumentTagRepository
 	NoteRepo() NoteRepository
+	OcrJobRepo() OcrJobRepository
+	UploadSessionRepo() UploadSessionRepository
 
 	// Fluent transaction execution
 	Execute(ctx context.Context, fn func(uow DocumentUnitOfWork) error) error
@@ -116,22 +177,37 @@ type OCRService interface {
 	ExtractText(ctx context.Context, imageData []byte) (text string, confidence float32, err error)
 }
 
+// PDFRenderer renders single pages of a PDF to PNG images. Pages are numbered from 1.
+// If maxDimension is positive, the page is scaled to fit into a square of that size,
+// otherwise it is rendered at the configured resolution.
+type PDFRenderer interface {
+	RenderPage(ctx context.Context, pdfData []byte, page int, maxDimension int) ([]byte, error)
+}
+
+// ImageConverter converts images in formats Go cannot decode, like HEIC, to PNG
+type ImageConverter interface {
+	ConvertToPNG(ctx context.Context, imageData []byte, format string) ([]byte, error)
+}
+
 // Document Search Engine interface
 type DocumentSearchEngine interface {
 	SearchDocuments(ctx context.Context, userId string, request DocumentSearchRequest, dataProtector dataprotection.DataProtector) (*PaginatedDocumentSearchResponse, error)
 }
 
+// OCRDispatchRequest describes a document file whose text should be extracted in the background.
+// The data protector must implement dataprotection.MekWrapper so that the job can be processed
+// after the request that created it has ended.
 type OCRDispatchRequest struct {
 	DocumentFileId string
-	ContentType    string
-	FileData       []byte
-	Processor      DocumentFileProcessor
+	UserId         string
 	DataProtector  dataprotection.DataProtector
-	StartedAt      time.Time
 }
 
+// OCRDispatcher persists OCR jobs and hands them to the background workers.
 type OCRDispatcher interface {
-	Enqueue(request OCRDispatchRequest)
+	// Enqueue persists an OCR job within the given unit of work, so that it is only queued if the file is created
+	Enqueue(ctx context.Context, uow DocumentUnitOfWork, request OCRDispatchRequest) error
+	// Dispatch notifies the background workers about newly queued jobs. It should be called after the unit of work was committed.
 	Dispatch()
 }
 
@@ -139,6 +215,32 @@ type OCRDispatcherFactory interface {
 	Create() OCRDispatcher
 }
 
+// OCRJobNotifier is notified whenever new OCR jobs have been queued
+type OCRJobNotifier interface {
+	Notify()
+}
+
+// OCRJobKeyProvider provides the server-side key used to wrap the MEK of queued OCR jobs.
+// It must not be a key used for anything else, such as the session encryption key.
+type OCRJobKeyProvider interface {
+	GetOCRJobKey() ([]byte, error)
+}
+
+// OCRJobProcessor processes persisted OCR jobs
+type OCRJobProcessor interface {
+	// ProcessNext claims and processes the next due job. It returns false if no job was due.
+	ProcessNext(ctx context.Context) (processed bool, err error)
+	// RecoverOrphanedJobs re-queues jobs that were being processed when the application stopped
+	// and fails files that are stuck in the processing state without a job.
+	RecoverOrphanedJobs(ctx context.Context) error
+}
+
+// DocumentBlobMigrator moves file contents and previews that are stored in the database to the blob store
+type DocumentBlobMigrator interface {
+	// MigrateToBlobStore moves the contents one file at a time, so that an interrupted migration can be continued
+	MigrateToBlobStore(ctx context.Context) (*BlobMigrationSummary, error)
+}
+
 // DocumentFileCreator is a domain service that handles the complete file creation workflow
 // This ensures consistent behavior between DocumentManager.CreateDocument and DocumentFileManager.AddDocumentFile
 type DocumentFileCreator interface {
@@ -148,6 +250,7 @@ type DocumentFileCreator interface {
 	// - Text extraction (OCR) if applicable
 	// - Preview generation if applicable
 	// - Database persistence
+	// The content is encrypted and stored while it is read, files larger than MaxProcessedFileSize are not processed.
 	// This method operates within the provided UOW transaction scope to ensure atomicity.
 	CreateDocumentFile(
 		ctx context.Context,
@@ -168,7 +271,15 @@ type DocumentManager interface {
 	GetDocument(ctx context.Context, userId, documentId string, dataProtector dataprotection.DataProtector) (*DocumentDto, error)
 	GetDocuments(ctx context.Context, userId string, request GetDocumentsRequest, dataProtector dataprotection.DataProtector) (*PaginatedDocumentResponse, error)
 	UpdateDocument(ctx context.Context, userId, documentId string, request UpdateDocumentRequest, dataProtector dataprotection.DataProtector) error
+	// DeleteDocument moves a document to the trash, from where it can be restored until it is purged
 	DeleteDocument(ctx context.Context, userId, documentId string) error
+	// GetTrashedDocuments returns the documents of a user that are in the trash, most recently deleted first
+	GetTrashedDocuments(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TrashedDocumentDto, error)
+	RestoreDocument(ctx context.Context, userId, documentId string) error
+	// PurgeDocument permanently deletes a document in the trash together with its files, notes and tag relations
+	PurgeDocument(ctx context.Context, userId, documentId string) error
+	// PurgeTrashedDocuments permanently deletes the documents of all users that were moved to the trash before the given time
+	PurgeTrashedDocuments(ctx context.Context, deletedBefore time.Time) (int, error)
 }
 
 // High-level Document File Manager - consumer-facing service.
@@ -178,16 +289,51 @@ type DocumentFileManager interface {
 	GetDocumentFiles(ctx context.Context, userId, documentId string, dataProtector dataprotection.DataProtector) ([]*DocumentFileDto, error)
 	GetDocumentFilePreviews(ctx context.Context, userId, documentId string, dataProtector dataprotection.DataProtector) ([]*DocumentFilePreviewDto, error)
 	GetDocumentFile(ctx context.Context, userId, documentId, fileId string, dataProtector dataprotection.DataProtector) (*DocumentFileDto, error)
+	// OpenDocumentFile returns a file along with a reader that decrypts its content while it is read
+	OpenDocumentFile(ctx context.Context, userId, documentId, fileId string, dataProtector dataprotection.DataProtector) (*DocumentFileDto, io.Reader, error)
 	DeleteDocumentFile(ctx context.Context, userId, documentId, fileId string) error
+	// ReprocessFile queues the text extraction of a file again, e.g. after it failed or the OCR provider was changed
+	ReprocessFile(ctx context.Context, userId, documentId, fileId string, dataProtector dataprotection.DataProtector) error
+	// ReprocessDocument queues the text extraction of all files of a document again and returns the number of queued files
+	ReprocessDocument(ctx context.Context, userId, documentId string, dataProtector dataprotection.DataProtector) (int, error)
+	// ReprocessFailedFiles queues the text extraction of all files of a user whose extraction failed and returns the number of queued files
+	ReprocessFailedFiles(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) (int, error)
+}
+
+// UploadSessionManager handles resumable uploads of document files. A file is announced, uploaded in parts that
+// are stored encrypted, and created by the DocumentFileCreator once all parts were received. Sessions belong to the
+// user and document they were created for and expire if no part is received for UploadSessionLifetime.
+type UploadSessionManager interface {
+	CreateUploadSession(ctx context.Context, userId, documentId string, request CreateUploadSessionRequest, dataProtector dataprotection.DataProtector) (*UploadSessionDto, error)
+	GetUploadSession(ctx context.Context, userId, documentId, sessionId string, dataProtector dataprotection.DataProtector) (*UploadSessionDto, error)
+	// UploadChunk stores the next part of a file. It fails with a conflict if the part does not start at the offset of the session.
+	UploadChunk(ctx context.Context, userId, documentId, sessionId string, request UploadChunkRequest, dataProtector dataprotection.DataProtector) (*UploadSessionDto, error)
+	// CompleteUploadSession creates the file from the received parts and deletes the session
+	CompleteUploadSession(ctx context.Context, userId, documentId, sessionId string, dataProtector dataprotection.DataProtector) (*DocumentFileDto, error)
+	AbortUploadSession(ctx context.Context, userId, documentId, sessionId string) error
+	// DeleteExpiredUploadSessions deletes the sessions of all users that expired before the given time and returns their number
+	DeleteExpiredUploadSessions(ctx context.Context, now time.Time) (int, error)
 }
 
 // Tag Manager - dedicated service for tag CRUD operations
 type TagManager interface {
-	CreateTag(ctx context.Context, userId string, request CreateTagRequest) (*TagDto, error)
-	GetTag(ctx context.Context, userId, tagId string) (*TagDto, error)
-	GetUserTags(ctx context.Context, userId string) ([]*TagDto, error)
-	UpdateTag(ctx context.Context, userId, tagId string, request UpdateTagRequest) error
+	CreateTag(ctx context.Context, userId string, request CreateTagRequest, dataProtector dataprotection.DataProtector) (*TagDto, error)
+	GetTag(ctx context.Context, userId, tagId string, dataProtector dataprotection.DataProtector) (*TagDto, error)
+	// GetUserTags returns the tags of a user sorted by name
+	GetUserTags(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TagDto, error)
+	UpdateTag(ctx context.Context, userId, tagId string, request UpdateTagRequest, dataProtector dataprotection.DataProtector) error
+	// DeleteTag moves a tag to the trash. The tag keeps its documents, so that they are tagged again when it is restored.
 	DeleteTag(ctx context.Context, userId, tagId string) error
+	// GetTrashedTags returns the tags of a user that are in the trash, most recently deleted first
+	GetTrashedTags(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TrashedTagDto, error)
+	// RestoreTag moves a tag out of the trash. It fails if another tag has taken its name in the meantime.
+	RestoreTag(ctx context.Context, userId, tagId string, dataProtector dataprotection.DataProtector) error
+	PurgeTag(ctx context.Context, userId, tagId string) error
+	// PurgeTrashedTags permanently deletes the tags of all users that were moved to the trash before the given time
+	PurgeTrashedTags(ctx context.Context, deletedBefore time.Time) (int, error)
+	// EncryptTagNames encrypts and indexes the names of the tags of a user that were stored before tag names were encrypted.
+	// Tags whose name is taken by another tag are renamed.
+	EncryptTagNames(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) (*TagNameReport, error)
 }
 
 // Note Manager - dedicated service for note CRUD operations
@@ -3,13 +3,20 @@ package documents
 import (
 	"errors"
 	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+)
+
+const (
+	OcrStatusProcessing = apicontracts.OcrStatusProcessing
+	OcrStatusCompleted  = apicontracts.OcrStatusCompleted
+	OcrStatusFailed     = apicontracts.OcrStatusFailed
+	OcrStatusSkipped    = apicontracts.OcrStatusSkipped
 )
 
 const (
-	OcrStatusProcessing = "processing"
-	OcrStatusCompleted  = "completed"
-	OcrStatusFailed     = "failed"
-	OcrStatusSkipped    = "skipped"
+	OcrJobStatusQueued     = "queued"
+	OcrJobStatusProcessing = "processing"
 )
 
 // ErrOCRSkipped is returned by a DocumentFileProcessor when OCR is not available
@@ -26,6 +33,7 @@ type Document struct {
 	IssueDate   *time.Time
 	CreatedAt   time.Time
 	ModifiedAt  time.Time
+	DeletedAt   *time.Time // When the document was moved to the trash, nil for active documents
 }
 
 type DocumentFile struct {
@@ -35,7 +43,6 @@ type DocumentFile struct {
 	ContentType string
 	FileSize    int64
 	PageCount   int
-	FileData    []byte // Encrypted file data/content
 	CreatedAt   time.Time
 	ModifiedAt  time.Time
 }
@@ -50,13 +57,53 @@ type DocumentFileMetadata struct {
 	OcrCompletedAt *time.Time
 }
 
+// OcrJob represents a persisted text extraction job for a document file.
+// Jobs are removed once the extraction has completed, been skipped or finally failed,
+// so only queued and processing jobs exist in the database.
+type OcrJob struct {
+	Id             string
+	DocumentFileId string
+	UserId         string
+	Status         string
+	Attempts       int
+	NextRunAt      time.Time
+	WrappedMek     string // MEK of the owning user, encrypted with the server-side job key
+	LastError      string
+	CreatedAt      time.Time
+	ModifiedAt     time.Time
+}
+
+// UploadSession is a resumable upload of a document file. The parts of the file are uploaded one after another
+// and stored encrypted until the upload is completed, which creates the file, or the session expires.
+type UploadSession struct {
+	Id          string
+	UserId      string
+	DocumentId  string
+	FileName    string // Encrypted file name
+	ContentType string
+	Size        int64 // Announced size of the file
+	Offset      int64 // Number of bytes received so far
+	CreatedAt   time.Time
+	ExpiresAt   time.Time // Extended whenever a part is received
+}
+
+// UploadSessionPart is a part of a file uploaded to an upload session, its content is stored encrypted
+type UploadSessionPart struct {
+	SessionId string
+	Offset    int64 // Position of the part in the file
+	Size      int64
+}
+
 type Tag struct {
-	Id         string
-	UserId     string
+	Id     string
+	UserId string
+	// Name is encrypted, except for tags stored before tag names were encrypted, which have no NameIndex yet
 	Name       string
+	NameIndex  string // Keyed blind index of the plaintext name, used for lookups and uniqueness
 	Color      string
 	CreatedAt  time.Time
 	ModifiedAt time.Time
+	DeletedAt  *time.Time // When the tag was moved to the trash, nil for active tags
 }
 
 type DocumentTag struct {
@@ -0,0 +1,145 @@
+package documents
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Namespaces of the elements and attributes of OpenDocument files
+const (
+	openDocumentTextNamespace = "urn:oasis:names:tc:opendocument:xmlns:text:1.0"
+	openDocumentMetaNamespace = "urn:oasis:names:tc:opendocument:xmlns:meta:1.0"
+)
+
+// ODTFileProcessor handles OpenDocument text documents. Their text is read from the content XML, without rendering.
+type ODTFileProcessor struct {
+	// No dependencies needed for this simple implementation
+}
+
+// NewODTFileProcessor creates a new ODTFileProcessor
+func NewODTFileProcessor() *ODTFileProcessor {
+	return &ODTFileProcessor{}
+}
+
+// SupportsContentType checks if this processor can handle OpenDocument text content types
+func (p *ODTFileProcessor) SupportsContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "application/vnd.oasis.opendocument.text"
+}
+
+// ExtractText extracts the text of the paragraphs and headings of the document
+func (p *ODTFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
+	archive, err := zip.NewReader(bytes.NewReader(fileData), int64(len(fileData)))
+	if err != nil {
+		return "", 0.0, 0, fmt.Errorf("failed to open OpenDocument file: %w", err)
+	}
+
+	data, err := readZipFile(archive, "content.xml")
+	if err != nil {
+		return "", 0.0, 0, err
+	}
+	text, err = openDocumentText(data)
+	if err != nil {
+		return "", 0.0, 0, fmt.Errorf("failed to read content.xml: %w", err)
+	}
+
+	return strings.TrimSpace(text), 1.0, odtPageCount(archive), nil
+}
+
+// GeneratePreview renders the beginning of the text as a page thumbnail
+func (p *ODTFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	text, _, _, err := p.ExtractText(ctx, fileData)
+	if err != nil {
+		return nil, err
+	}
+	return renderTextPreview(text)
+}
+
+// openDocumentText returns the text of the paragraphs and headings of an OpenDocument content part,
+// one line per paragraph
+func openDocumentText(data []byte) (string, error) {
+	var text strings.Builder
+	paragraphDepth := 0
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return text.String(), nil
+		}
+		if err != nil {
+			return "", err
+		}
+
+		switch element := token.(type) {
+		case xml.StartElement:
+			if element.Name.Space != openDocumentTextNamespace {
+				continue
+			}
+			switch element.Name.Local {
+			case "p", "h":
+				paragraphDepth++
+			case "tab":
+				text.WriteString("\t")
+			case "line-break":
+				text.WriteString("\n")
+			case "s":
+				// Runs of spaces are stored as an element with the number of spaces
+				count := 1
+				for _, attr := range element.Attr {
+					if attr.Name.Space == openDocumentTextNamespace && attr.Name.Local == "c" {
+						if c, err := strconv.Atoi(attr.Value); err == nil && c > 0 && c <= 1000 {
+							count = c
+						}
+					}
+				}
+				text.WriteString(strings.Repeat(" ", count))
+			}
+		case xml.EndElement:
+			if element.Name.Space == openDocumentTextNamespace && (element.Name.Local == "p" || element.Name.Local == "h") {
+				paragraphDepth--
+				if paragraphDepth == 0 {
+					text.WriteString("\n")
+				}
+			}
+		case xml.CharData:
+			if paragraphDepth > 0 {
+				text.Write(element)
+			}
+		}
+	}
+}
+
+// odtPageCount returns the page count saved in the document statistics, or 1 if there is none
+func odtPageCount(archive *zip.Reader) int {
+	data, err := readZipFile(archive, "meta.xml")
+	if err != nil {
+		return 1
+	}
+
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 1
+		}
+		element, ok := token.(xml.StartElement)
+		if !ok || element.Name.Space != openDocumentMetaNamespace || element.Name.Local != "document-statistic" {
+			continue
+		}
+		for _, attr := range element.Attr {
+			if attr.Name.Space == openDocumentMetaNamespace && attr.Name.Local == "page-count" {
+				if pages, err := strconv.Atoi(attr.Value); err == nil && pages > 0 {
+					return pages
+				}
+			}
+		}
+		return 1
+	}
+}
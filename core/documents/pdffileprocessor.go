@@ -3,20 +3,42 @@ package documents
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"image"
+	"image/jpeg"
+	_ "image/png" // Rendered pages are PNG images
 	"strings"
 
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/ledongthuc/pdf"
 )
 
-// PDFFileProcessor handles PDF file processing
+// PDFFileProcessor handles PDF file processing. Embedded text is extracted with the ledongthuc/pdf library.
+// With a renderer, pages without a text layer are sent through OCR and the first page becomes the preview.
 type PDFFileProcessor struct {
-	// No dependencies needed for this simple implementation
+	renderer            PDFRenderer
+	ocrService          OCRService
+	maxOCRPages         int
+	maxPreviewDimension int
+	previewQuality      int
+	logger              ccc.Logger
 }
 
-// NewPDFFileProcessor creates a new PDFFileProcessor
-func NewPDFFileProcessor() *PDFFileProcessor {
-	return &PDFFileProcessor{}
+// NewPDFFileProcessor creates a new PDFFileProcessor. A nil renderer disables previews and OCR of
+// scanned pages, so only embedded text is extracted.
+func NewPDFFileProcessor(renderer PDFRenderer, ocrService OCRService, config ccc.PDFConfig, logger ccc.Logger) *PDFFileProcessor {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &PDFFileProcessor{
+		renderer:            renderer,
+		ocrService:          ocrService,
+		maxOCRPages:         max(config.MaxOCRPages, 0),
+		maxPreviewDimension: 256,
+		previewQuality:      85, // JPEG quality for preview generation
+		logger:              logger,
+	}
 }
 
 // SupportsContentType checks if this processor can handle PDF content types
@@ -25,53 +47,149 @@ func (p *PDFFileProcessor) SupportsContentType(contentType string) bool {
 	return contentType == "application/pdf"
 }
 
-// ExtractText extracts text from PDF files using the ledongthuc/pdf library
+// ExtractText extracts the text of every page of a PDF. Pages without embedded text are rendered and
+// sent through OCR, up to the configured number of pages. The texts of the pages are separated by blank lines.
 func (p *PDFFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
-	// Create a reader from the byte data
-	reader := bytes.NewReader(fileData)
-
-	// Create PDF reader
-	pdfReader, err := pdf.NewReader(reader, int64(len(fileData)))
+	pdfReader, err := pdf.NewReader(bytes.NewReader(fileData), int64(len(fileData)))
 	if err != nil {
 		return "", 0.0, 0, fmt.Errorf("failed to create PDF reader: %w", err)
 	}
 
-	// Get the number of pages
 	pageCount = pdfReader.NumPage()
 
-	// Extract text from all pages
-	textReader, err := pdfReader.GetPlainText()
-	if err != nil {
-		return "", 0.0, pageCount, fmt.Errorf("failed to extract text from PDF: %w", err)
+	var pageTexts []string
+	var confidenceSum float32
+	countedPages := 0 // Pages that contribute to the confidence
+	ocrPages := 0     // Pages sent through OCR
+	skippedPages := 0 // Pages without text that could not be sent through OCR
+
+	fonts := make(map[string]*pdf.Font)
+	for i := 1; i <= pageCount; i++ {
+		if err := ctx.Err(); err != nil {
+			return "", 0.0, pageCount, err
+		}
+
+		pageText := p.embeddedPageText(pdfReader.Page(i), fonts)
+		if strings.TrimSpace(pageText) != "" {
+			// Embedded text has no confidence score like OCR, so it counts as 100%
+			pageTexts = append(pageTexts, strings.TrimSpace(pageText))
+			confidenceSum += 1.0
+			countedPages++
+			continue
+		}
+
+		if !p.canRunOCR() || ocrPages >= p.maxOCRPages {
+			skippedPages++
+			countedPages++
+			continue
+		}
+
+		pageImage, err := p.renderer.RenderPage(ctx, fileData, i, 0)
+		if err != nil {
+			if ctx.Err() != nil {
+				return "", 0.0, pageCount, ctx.Err()
+			}
+			// A page that cannot be rendered will not render on the next attempt either
+			p.logger.Warn("Failed to render PDF page for OCR, skipping it", "page", i, "error", err)
+			skippedPages++
+			countedPages++
+			continue
+		}
+
+		ocrText, ocrConfidence, err := p.ocrService.ExtractText(ctx, pageImage)
+		if err != nil {
+			return "", 0.0, pageCount, fmt.Errorf("OCR of PDF page %d failed: %w", i, err)
+		}
+		ocrPages++
+		countedPages++
+		confidenceSum += ocrConfidence
+		if strings.TrimSpace(ocrText) != "" {
+			pageTexts = append(pageTexts, strings.TrimSpace(ocrText))
+		}
 	}
 
-	// Read all text into a buffer
-	var buf bytes.Buffer
-	_, err = buf.ReadFrom(textReader)
-	if err != nil {
-		return "", 0.0, pageCount, fmt.Errorf("failed to read extracted text: %w", err)
+	if len(pageTexts) == 0 && skippedPages > 0 && ocrPages == 0 {
+		// A scanned PDF whose pages could not be sent through OCR
+		return "", 0.0, pageCount, ErrOCRSkipped
+	}
+	if skippedPages > 0 {
+		p.logger.Info("Some PDF pages without text were not sent through OCR", "pages", pageCount, "skipped", skippedPages, "max_ocr_pages", p.maxOCRPages)
 	}
 
-	text = buf.String()
+	if countedPages > 0 {
+		confidence = confidenceSum / float32(countedPages)
+	}
+	return strings.Join(pageTexts, "\n\n"), confidence, pageCount, nil
+}
 
-	// For PDF text extraction, we don't have a confidence score like OCR
-	// We'll return 1.0 (100%) if we successfully extracted text, 0.0 if no text was found
-	confidence = 1.0
-	if strings.TrimSpace(text) == "" {
-		confidence = 0.0
+// embeddedPageText returns the text layer of a page, or an empty string if it has none or it cannot be read
+func (p *PDFFileProcessor) embeddedPageText(page pdf.Page, fonts map[string]*pdf.Font) string {
+	if page.V.IsNull() {
+		return ""
 	}
+	for _, name := range page.Fonts() { // Cache fonts so the charmaps are parsed once per document
+		if _, ok := fonts[name]; !ok {
+			font := page.Font(name)
+			fonts[name] = &font
+		}
+	}
+	text, err := page.GetPlainText(fonts)
+	if err != nil {
+		p.logger.Debug("Failed to read the text layer of a PDF page", "error", err)
+		return ""
+	}
+	return text
+}
 
-	return text, confidence, pageCount, nil
+// canRunOCR reports whether pages without text can be rendered and sent through OCR
+func (p *PDFFileProcessor) canRunOCR() bool {
+	return p.renderer != nil && p.ocrService != nil && p.ocrService.IsOcrEnabled()
 }
 
-// GeneratePreview creates a preview for PDF files
-// For PDFs, we don't generate actual preview images, just return the content type
-// The frontend will show a generic PDF icon based on the PreviewType
+// GeneratePreview renders the first page of a PDF as a JPEG thumbnail. Without a renderer, or if the page
+// cannot be rendered, only the content type is returned and the frontend shows a generic PDF icon.
 func (p *PDFFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
-	return &PreviewGenerationResult{
+	iconPreview := &PreviewGenerationResult{
 		PreviewData: nil, // No actual preview data
 		PreviewType: "application/pdf",
-		Width:       0, // No dimensions for PDF previews
-		Height:      0,
+	}
+	if p.renderer == nil {
+		return iconPreview, nil
+	}
+
+	preview, err := p.renderPreview(ctx, fileData)
+	if err != nil {
+		if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+			return nil, err
+		}
+		p.logger.Warn("Failed to render PDF preview, falling back to an icon", "error", err)
+		return iconPreview, nil
+	}
+	return preview, nil
+}
+
+// renderPreview renders the first page scaled to the preview size and encodes it as JPEG
+func (p *PDFFileProcessor) renderPreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	pageImage, err := p.renderer.RenderPage(ctx, fileData, 1, p.maxPreviewDimension)
+	if err != nil {
+		return nil, err
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(pageImage))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode rendered page: %w", err)
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.previewQuality}); err != nil {
+		return nil, fmt.Errorf("failed to encode preview image: %w", err)
+	}
+
+	bounds := img.Bounds()
+	return &PreviewGenerationResult{
+		PreviewData: buf.Bytes(),
+		PreviewType: "image/jpeg",
+		Width:       bounds.Dx(),
+		Height:      bounds.Dy(),
 	}, nil
 }
@@ -0,0 +1,124 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// buildTestPDF writes a PDF with one page per entry. Pages with an empty text have no text layer,
+// like the pages of a scanned document.
+func buildTestPDF(pageTexts ...string) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // Page tree, filled in below
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+	var kids []string
+	for _, text := range pageTexts {
+		content := ""
+		if text != "" {
+			content = fmt.Sprintf("BT /F1 12 Tf 72 720 Td (%s) Tj ET", text)
+		}
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", len(objects)))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+// fakePDFRenderer renders every page as a white PNG and records the rendered pages
+type fakePDFRenderer struct {
+	pages []int
+}
+
+func (r *fakePDFRenderer) RenderPage(ctx context.Context, pdfData []byte, page int, maxDimension int) ([]byte, error) {
+	r.pages = append(r.pages, page)
+	width, height := 850, 1100
+	if maxDimension > 0 {
+		width, height = maxDimension*850/1100, maxDimension
+	}
+	img := image.NewGray(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+type fakeOCRService struct {
+	calls int
+}
+
+func (s *fakeOCRService) IsOcrEnabled() bool { return true }
+
+func (s *fakeOCRService) ExtractText(ctx context.Context, imageData []byte) (string, float32, error) {
+	s.calls++
+	if _, _, err := image.Decode(bytes.NewReader(imageData)); err != nil {
+		return "", 0, err
+	}
+	return "Scanned page", 0.8, nil
+}
+
+func TestPDFPagesWithoutTextAreSentThroughOCR(t *testing.T) {
+	renderer := &fakePDFRenderer{}
+	ocrService := &fakeOCRService{}
+	processor := NewPDFFileProcessor(renderer, ocrService, ccc.PDFConfig{MaxOCRPages: 5}, nil)
+	pdfData := buildTestPDF("Embedded text", "")
+
+	text, confidence, pageCount, err := processor.ExtractText(context.Background(), pdfData)
+	if err != nil {
+		t.Fatalf("ExtractText failed: %v", err)
+	}
+	if pageCount != 2 {
+		t.Errorf("expected 2 pages, got %d", pageCount)
+	}
+	if text != "Embedded text\n\nScanned page" {
+		t.Errorf("unexpected text %q", text)
+	}
+	if confidence < 0.89 || confidence > 0.91 {
+		t.Errorf("expected the average confidence 0.9, got %v", confidence)
+	}
+	if ocrService.calls != 1 || len(renderer.pages) != 1 || renderer.pages[0] != 2 {
+		t.Errorf("expected only page 2 to be sent through OCR, rendered %v with %d OCR calls", renderer.pages, ocrService.calls)
+	}
+
+	preview, err := processor.GeneratePreview(context.Background(), pdfData)
+	if err != nil {
+		t.Fatalf("GeneratePreview failed: %v", err)
+	}
+	if preview.PreviewType != "image/jpeg" || len(preview.PreviewData) == 0 || preview.Height != 256 {
+		t.Errorf("unexpected preview %s %dx%d with %d bytes", preview.PreviewType, preview.Width, preview.Height, len(preview.PreviewData))
+	}
+
+	// Without OCR, a scanned PDF is skipped but its pages are still counted
+	scanned := buildTestPDF("", "", "")
+	_, _, pageCount, err = NewPDFFileProcessor(nil, ocrService, ccc.PDFConfig{MaxOCRPages: 5}, nil).ExtractText(context.Background(), scanned)
+	if err != ErrOCRSkipped || pageCount != 3 {
+		t.Errorf("expected ErrOCRSkipped with 3 pages, got %v with %d pages", err, pageCount)
+	}
+}
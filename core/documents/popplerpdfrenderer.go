@@ -0,0 +1,95 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// PopplerPDFRenderer renders PDF pages with pdftoppm from poppler-utils. The PDF is passed on stdin
+// and the image is read from stdout, so no decrypted data is written to disk.
+type PopplerPDFRenderer struct {
+	executable string
+	dpi        int
+	logger     ccc.Logger
+}
+
+// NewPopplerPDFRenderer creates a renderer for the configured pdftoppm executable.
+// It returns an error if the executable cannot be found.
+func NewPopplerPDFRenderer(config ccc.PDFConfig, logger ccc.Logger) (*PopplerPDFRenderer, error) {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	if config.RendererPath == "" {
+		return nil, fmt.Errorf("no PDF renderer configured")
+	}
+	executable, err := exec.LookPath(config.RendererPath)
+	if err != nil {
+		return nil, fmt.Errorf("PDF renderer %q not found: %w", config.RendererPath, err)
+	}
+	if config.RenderDPI <= 0 {
+		config.RenderDPI = 200
+	}
+
+	return &PopplerPDFRenderer{
+		executable: executable,
+		dpi:        config.RenderDPI,
+		logger:     logger,
+	}, nil
+}
+
+// RenderPage renders one page of a PDF to a PNG image
+func (r *PopplerPDFRenderer) RenderPage(ctx context.Context, pdfData []byte, page int, maxDimension int) ([]byte, error) {
+	if page < 1 {
+		return nil, fmt.Errorf("invalid page number %d", page)
+	}
+
+	pageArg := strconv.Itoa(page)
+	args := []string{"-png", "-f", pageArg, "-l", pageArg, "-singlefile"}
+	if maxDimension > 0 {
+		args = append(args, "-scale-to", strconv.Itoa(maxDimension))
+	} else {
+		args = append(args, "-r", strconv.Itoa(r.dpi))
+	}
+	// Read the PDF from stdin and write the image to stdout
+	args = append(args, "-")
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, r.executable, args...)
+	cmd.Stdin = bytes.NewReader(pdfData)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("failed to render PDF page %d: %w: %s", page, err, strings.TrimSpace(stderr.String()))
+	}
+	if stdout.Len() == 0 {
+		return nil, fmt.Errorf("PDF renderer returned no image for page %d", page)
+	}
+
+	r.logger.Debug("Rendered PDF page", "page", page, "bytes", stdout.Len())
+	return stdout.Bytes(), nil
+}
+
+// NewPDFRendererFromConfig returns the configured PDF renderer, or nil if it is disabled or not installed.
+// A nil renderer makes the PDF processor fall back to embedded text and generic previews.
+func NewPDFRendererFromConfig(config ccc.PDFConfig, logger ccc.Logger) PDFRenderer {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	if config.RendererPath == "" {
+		logger.Info("PDF rendering is disabled, PDFs get no previews and scanned pages no OCR")
+		return nil
+	}
+	renderer, err := NewPopplerPDFRenderer(config, logger)
+	if err != nil {
+		logger.Warn("PDF renderer not available, PDFs get no previews and scanned pages no OCR. Install poppler-utils to enable them.", "error", err)
+		return nil
+	}
+	return renderer
+}
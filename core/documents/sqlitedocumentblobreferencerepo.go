@@ -0,0 +1,85 @@
+package documents
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// SQLiteDocumentBlobReferenceRepository finds the blobs referenced by document files, previews and the parts of uploads.
+// It implements blobstore.BlobReferenceSource for the blob garbage collection.
+type SQLiteDocumentBlobReferenceRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteDocumentBlobReferenceRepository creates a new SQLiteDocumentBlobReferenceRepository instance.
+func NewSQLiteDocumentBlobReferenceRepository(db *sql.DB) *SQLiteDocumentBlobReferenceRepository {
+	return &SQLiteDocumentBlobReferenceRepository{db: db}
+}
+
+// FindReferencedBlobs returns the hashes of all chunks referenced by document files and previews,
+// including those of documents in the trash, and by the parts of unfinished uploads. Databases of older
+// schema versions, e.g. in backups, lack some of the columns and reference no blobs in them.
+func (r *SQLiteDocumentBlobReferenceRepository) FindReferencedBlobs(ctx context.Context) (map[string]bool, error) {
+	referenceColumns := []struct{ table, column string }{
+		{"DocumentFile", "FileBlobRef"},
+		{"DocumentFile", "PreviewBlobRef"},
+		{"UploadSessionPart", "BlobRef"},
+	}
+
+	var queries []string
+	for _, c := range referenceColumns {
+		exists, err := ccc.SQLiteColumnExists(r.db, c.table, c.column)
+		if err != nil {
+			return nil, err
+		}
+		if exists {
+			queries = append(queries, fmt.Sprintf("SELECT %s FROM %s WHERE %s IS NOT NULL", c.column, c.table, c.column))
+		}
+	}
+
+	referenced := make(map[string]bool)
+	if len(queries) == 0 {
+		return referenced, nil
+	}
+
+	rows, err := r.db.QueryContext(ctx, strings.Join(queries, " UNION ALL "))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var reference string
+		if err := rows.Scan(&reference); err != nil {
+			return nil, err
+		}
+		hashes, err := blobstore.ReferencedHashes(reference)
+		if err != nil {
+			// A reference that cannot be parsed would let the garbage collection delete blobs that are still in use
+			return nil, fmt.Errorf("document file references blobs invalidly: %w", err)
+		}
+		for _, hash := range hashes {
+			referenced[hash] = true
+		}
+	}
+	return referenced, rows.Err()
+}
+
+// SQLiteDocumentBlobReferenceRepositoryFactory creates SQLiteDocumentBlobReferenceRepository instances for a database.
+// It implements blobstore.BlobReferenceSourceFactory, so backups can record the blobs they reference.
+type SQLiteDocumentBlobReferenceRepositoryFactory struct{}
+
+// NewSQLiteDocumentBlobReferenceRepositoryFactory creates a new SQLiteDocumentBlobReferenceRepositoryFactory instance.
+func NewSQLiteDocumentBlobReferenceRepositoryFactory() *SQLiteDocumentBlobReferenceRepositoryFactory {
+	return &SQLiteDocumentBlobReferenceRepositoryFactory{}
+}
+
+// Create returns a repository finding the blobs referenced by the given database
+func (f *SQLiteDocumentBlobReferenceRepositoryFactory) Create(db *sql.DB) blobstore.BlobReferenceSource {
+	return NewSQLiteDocumentBlobReferenceRepository(db)
+}
@@ -22,87 +22,92 @@ const (
 
 // newSQLiteDocumentFileMetadataRepository creates a new SQLiteDocumentFileMetadataRepository instance.
 func newSQLiteDocumentFileMetadataRepository(db ccc.DBExecutor) DocumentFileMetadataRepository {
-	repo := &SQLiteDocumentFileMetadataRepository{db: db}
-
-	// Initialize table if we have a *sql.DB (not transaction)
-	if sqlDB, ok := db.(*sql.DB); ok {
-		if err := repo.initializeTable(sqlDB); err != nil {
-			// Log error but don't fail - table might already exist
-		}
-	}
+	return &SQLiteDocumentFileMetadataRepository{db: db}
+}
 
-	return repo
+// FindByDocumentFileId finds metadata by document file ID.
+func (r *SQLiteDocumentFileMetadataRepository) FindByDocumentFileId(ctx context.Context, fileId string) (*DocumentFileMetadata, error) {
+	query := `SELECT ` + documentFileMetadataFieldList + ` FROM DocumentFileMetadata WHERE DocumentFileId = ?`
+	row := r.db.QueryRowContext(ctx, query, fileId)
+	return scanDocumentFileMetadata(row)
 }
 
-// initializeTable creates the DocumentFileMetadata table if it doesn't exist
-func (r *SQLiteDocumentFileMetadataRepository) initializeTable(db *sql.DB) error {
+// FindByDocumentId finds all metadata for files in a document.
+func (r *SQLiteDocumentFileMetadataRepository) FindByDocumentId(ctx context.Context, documentId string) ([]*DocumentFileMetadata, error) {
 	query := `
-	CREATE TABLE IF NOT EXISTS DocumentFileMetadata (
-		DocumentFileId TEXT PRIMARY KEY,
-		ExtractedText TEXT,
-		OcrConfidence REAL DEFAULT 0.0,
-		OcrStatus TEXT DEFAULT '',
-		OcrError TEXT DEFAULT '',
-		OcrStartedAt TIMESTAMP,
-		OcrCompletedAt TIMESTAMP
-	);
-	CREATE INDEX IF NOT EXISTS idx_documentfilemetadata_confidence ON DocumentFileMetadata(OcrConfidence);
-	CREATE INDEX IF NOT EXISTS idx_documentfilemetadata_status ON DocumentFileMetadata(OcrStatus);
-	`
-	_, err := db.Exec(query)
+	SELECT ` + documentFileMetadataFieldList + `
+	FROM DocumentFileMetadata dfm
+	INNER JOIN DocumentFile df ON dfm.DocumentFileId = df.Id
+	WHERE df.DocumentId = ?
+	ORDER BY dfm.OcrConfidence DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, documentId)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	for _, migration := range []string{
-		`ALTER TABLE DocumentFileMetadata ADD COLUMN OcrStatus TEXT DEFAULT ''`,
-		`ALTER TABLE DocumentFileMetadata ADD COLUMN OcrError TEXT DEFAULT ''`,
-		`ALTER TABLE DocumentFileMetadata ADD COLUMN OcrStartedAt TIMESTAMP`,
-		`ALTER TABLE DocumentFileMetadata ADD COLUMN OcrCompletedAt TIMESTAMP`,
-	} {
-		_, _ = db.Exec(migration)
+	var metadata []*DocumentFileMetadata
+	for rows.Next() {
+		meta, err := scanDocumentFileMetadata(rows)
+		if err != nil {
+			continue // Skip problematic rows
+		}
+		metadata = append(metadata, meta)
 	}
+	return metadata, rows.Err()
+}
 
-	_, err = db.Exec(`
-		UPDATE DocumentFileMetadata
-		SET OcrStatus = ?
-		WHERE COALESCE(OcrStatus, '') = ''
-		  AND COALESCE(ExtractedText, '') <> ''
-	`, OcrStatusCompleted)
+// FindProcessingWithoutJob finds metadata of files that are in the processing OCR state but have no queued or running OCR job.
+// This happens if the application stopped before a job was persisted or after it was lost.
+func (r *SQLiteDocumentFileMetadataRepository) FindProcessingWithoutJob(ctx context.Context) ([]*DocumentFileMetadata, error) {
+	query := `
+	SELECT ` + documentFileMetadataFieldList + `
+	FROM DocumentFileMetadata
+	WHERE OcrStatus = ?
+	AND NOT EXISTS (SELECT 1 FROM OcrJob j WHERE j.DocumentFileId = DocumentFileMetadata.DocumentFileId)`
+
+	rows, err := r.db.QueryContext(ctx, query, OcrStatusProcessing)
 	if err != nil {
-		return err
+		return nil, err
 	}
+	defer rows.Close()
 
-	// Try to add foreign key constraint from DocumentFileMetadata.DocumentFileId to DocumentFile.Id
-	fkQuery := `
-	ALTER TABLE DocumentFileMetadata ADD CONSTRAINT fk_documentfilemetadata_fileid 
-	FOREIGN KEY (DocumentFileId) REFERENCES DocumentFile(Id) ON DELETE CASCADE;
-	`
-	_, fkErr := db.Exec(fkQuery)
-	if fkErr != nil {
-		// Log or ignore the error - foreign key constraint is optional
+	var metadata []*DocumentFileMetadata
+	for rows.Next() {
+		meta, err := scanDocumentFileMetadata(rows)
+		if err != nil {
+			continue // Skip problematic rows
+		}
+		metadata = append(metadata, meta)
 	}
-
-	return nil
+	return metadata, rows.Err()
 }
 
-// FindByDocumentFileId finds metadata by document file ID.
-func (r *SQLiteDocumentFileMetadataRepository) FindByDocumentFileId(ctx context.Context, fileId string) (*DocumentFileMetadata, error) {
-	query := `SELECT ` + documentFileMetadataFieldList + ` FROM DocumentFileMetadata WHERE DocumentFileId = ?`
-	row := r.db.QueryRowContext(ctx, query, fileId)
-	return scanDocumentFileMetadata(row)
-}
+// FindByUserIdAndOcrStatus finds metadata of all files owned by a user whose OCR status is one of the given statuses.
+func (r *SQLiteDocumentFileMetadataRepository) FindByUserIdAndOcrStatus(ctx context.Context, userId string, statuses []string) ([]*DocumentFileMetadata, error) {
+	if len(statuses) == 0 {
+		return []*DocumentFileMetadata{}, nil
+	}
+
+	// Build parameterized query with placeholders
+	placeholders := make([]string, len(statuses))
+	args := make([]interface{}, 0, len(statuses)+1)
+	args = append(args, userId)
+	for i, status := range statuses {
+		placeholders[i] = "?"
+		args = append(args, status)
+	}
 
-// FindByDocumentId finds all metadata for files in a document.
-func (r *SQLiteDocumentFileMetadataRepository) FindByDocumentId(ctx context.Context, documentId string) ([]*DocumentFileMetadata, error) {
 	query := `
 	SELECT ` + documentFileMetadataFieldList + `
 	FROM DocumentFileMetadata dfm
 	INNER JOIN DocumentFile df ON dfm.DocumentFileId = df.Id
-	WHERE df.DocumentId = ?
-	ORDER BY dfm.OcrConfidence DESC`
+	INNER JOIN Document d ON df.DocumentId = d.Id
+	WHERE d.UserId = ? AND d.DeletedAt IS NULL AND dfm.OcrStatus IN (` + strings.Join(placeholders, ",") + `)
+	ORDER BY df.CreatedAt ASC`
 
-	rows, err := r.db.QueryContext(ctx, query, documentId)
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
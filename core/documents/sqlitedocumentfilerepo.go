@@ -1,82 +1,48 @@
 package documents
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"fmt"
+	"io"
 	"strings"
 	"time"
 
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	_ "github.com/mattn/go-sqlite3"
 )
 
 // SQLiteDocumentFileRepository implements DocumentFileRepository interface using SQLite.
+// If a blob store is set, file contents and previews are stored in it and the rows only keep references to them.
+// Blobs are never deleted by the repository, as a transaction that is rolled back must not lose them;
+// the blob garbage collection removes them once no row references them anymore.
 type SQLiteDocumentFileRepository struct {
-	db ccc.DBExecutor
+	db    ccc.DBExecutor
+	blobs blobstore.BlobStore
 }
 
+// errBlobStoreNotConfigured is returned when a content is stored in a blob store that is not configured
+var errBlobStoreNotConfigured = fmt.Errorf("the content is stored in a blob store, but %s is not configured", ccc.EnvBlobStore)
+
 const (
-	// Field list for DocumentFile table queries (excludes preview fields)
-	documentFileFieldList = `Id, DocumentId, FileName, ContentType, FileSize, PageCount, FileData, CreatedAt, ModifiedAt`
+	// Field list for DocumentFile table queries (excludes content and preview fields)
+	documentFileFieldList = `Id, DocumentId, FileName, ContentType, FileSize, PageCount, CreatedAt, ModifiedAt`
 	// Field list for DocumentFilePreview queries (from DocumentFile table)
-	documentFilePreviewFieldList = `Id, PreviewData, PreviewType, Width, Height`
+	documentFilePreviewFieldList = `Id, PreviewData, PreviewBlobRef, PreviewType, Width, Height`
+	// Condition matching files that have a preview, which is stored either in the row or in the blob store
+	documentFileHasPreviewCondition = `(PreviewData IS NOT NULL OR PreviewBlobRef IS NOT NULL)`
 )
 
 // newSQLiteDocumentFileRepository creates a new SQLiteDocumentFileRepository instance.
-func newSQLiteDocumentFileRepository(db ccc.DBExecutor) DocumentFileRepository {
-	repo := &SQLiteDocumentFileRepository{db: db}
-
-	// Initialize table if we have a *sql.DB (not transaction)
-	if sqlDB, ok := db.(*sql.DB); ok {
-		if err := repo.initializeTable(sqlDB); err != nil {
-			// Log error but don't fail - table might already exist
-		}
-	}
-
-	return repo
-}
-
-// initializeTable creates the DocumentFile table if it doesn't exist
-func (r *SQLiteDocumentFileRepository) initializeTable(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS DocumentFile (
-		Id TEXT PRIMARY KEY,
-		DocumentId TEXT NOT NULL,
-		FileName TEXT NOT NULL,
-		ContentType TEXT NOT NULL,
-		FileSize INTEGER NOT NULL,
-		PageCount INTEGER DEFAULT 0,
-		FileData BLOB NOT NULL,
-		PreviewData BLOB,
-		PreviewType TEXT,
-		Width INTEGER DEFAULT 0,
-		Height INTEGER DEFAULT 0,
-		CreatedAt TIMESTAMP NOT NULL,
-		ModifiedAt TIMESTAMP NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_documentfile_documentid ON DocumentFile(DocumentId);
-	CREATE INDEX IF NOT EXISTS idx_documentfile_created ON DocumentFile(CreatedAt);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	// Try to add foreign key constraint from DocumentFile.DocumentId to Document.Id
-	fkQuery := `
-	ALTER TABLE DocumentFile ADD CONSTRAINT fk_documentfile_documentid 
-	FOREIGN KEY (DocumentId) REFERENCES Document(Id) ON DELETE CASCADE;
-	`
-	_, fkErr := db.Exec(fkQuery)
-	if fkErr != nil {
-		// Log or ignore the error - foreign key constraint is optional
-	}
-
-	return nil
+// Without a blob store, file contents and previews are stored in the database.
+func newSQLiteDocumentFileRepository(db ccc.DBExecutor, blobs blobstore.BlobStore) DocumentFileRepository {
+	return &SQLiteDocumentFileRepository{db: db, blobs: blobs}
 }
 
 // FindById finds a document file by its ID.
+// The content is not loaded, it can be read with OpenContent.
 func (r *SQLiteDocumentFileRepository) FindById(ctx context.Context, fileId string) (*DocumentFile, error) {
 	query := `SELECT ` + documentFileFieldList + ` FROM DocumentFile WHERE Id = ?`
 	row := r.db.QueryRowContext(ctx, query, fileId)
@@ -84,6 +50,7 @@ func (r *SQLiteDocumentFileRepository) FindById(ctx context.Context, fileId stri
 }
 
 // FindByDocumentId finds all files for a document.
+// The contents are not loaded, they can be read with OpenContent.
 func (r *SQLiteDocumentFileRepository) FindByDocumentId(ctx context.Context, documentId string) ([]*DocumentFile, error) {
 	query := `SELECT ` + documentFileFieldList + ` FROM DocumentFile WHERE DocumentId = ? ORDER BY CreatedAt ASC`
 	rows, err := r.db.QueryContext(ctx, query, documentId)
@@ -103,58 +70,86 @@ func (r *SQLiteDocumentFileRepository) FindByDocumentId(ctx context.Context, doc
 	return files, rows.Err()
 }
 
-// Add adds a new document file.
-func (r *SQLiteDocumentFileRepository) Add(ctx context.Context, file *DocumentFile) error {
-	query := `INSERT INTO DocumentFile (` + documentFileFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+// OpenContent returns a reader for the encrypted content of a document file.
+// Contents in the blob store are loaded chunk by chunk while they are read, contents in the database at once,
+// which is why the upload size is capped without a blob store (see ccc.MaxDatabaseUploadSizeMB).
+// Returns nil if the document file doesn't exist.
+func (r *SQLiteDocumentFileRepository) OpenContent(ctx context.Context, fileId string) (io.Reader, error) {
+	var blobRef sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT FileBlobRef FROM DocumentFile WHERE Id = ?`, fileId).Scan(&blobRef)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, err
+	}
 
-	createdAtStr := ccc.FormatSQLiteTimestamp(file.CreatedAt)
-	modifiedAtStr := ccc.FormatSQLiteTimestamp(file.ModifiedAt)
+	if blobRef.Valid {
+		if r.blobs == nil {
+			return nil, errBlobStoreNotConfigured
+		}
+		content, err := blobstore.OpenChunks(ctx, r.blobs, blobRef.String)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open content of document file %s: %w", fileId, err)
+		}
+		return content, nil
+	}
 
-	_, err := r.db.ExecContext(ctx, query,
-		file.Id,
-		file.DocumentId,
-		file.FileName,
-		file.ContentType,
-		file.FileSize,
-		file.PageCount,
-		file.FileData,
-		createdAtStr,
-		modifiedAtStr,
-	)
-	return err
+	var content []byte
+	if err := r.db.QueryRowContext(ctx, `SELECT FileData FROM DocumentFile WHERE Id = ?`, fileId).Scan(&content); err != nil {
+		return nil, err
+	}
+	return bytes.NewReader(content), nil
 }
 
-// AddWithPreview adds a new document file with optional preview data in a single atomic operation.
+// Add adds a new document file with the given encrypted content.
+// The content is stored before the row is inserted; without a blob store it is read into memory.
+func (r *SQLiteDocumentFileRepository) Add(ctx context.Context, file *DocumentFile, content io.Reader) error {
+	return r.AddWithPreview(ctx, file, content, nil)
+}
+
+// AddWithPreview adds a new document file with its encrypted content and optional preview data in a single atomic operation.
 // This prevents the ModifiedAt timestamp from being updated twice when creating a file with preview.
-func (r *SQLiteDocumentFileRepository) AddWithPreview(ctx context.Context, file *DocumentFile, preview *DocumentFilePreview) error {
-	// Build the full field list including preview fields
-	fullFieldList := `Id, DocumentId, FileName, ContentType, FileSize, PageCount, FileData, PreviewData, PreviewType, Width, Height, CreatedAt, ModifiedAt`
-	query := `INSERT INTO DocumentFile (` + fullFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+func (r *SQLiteDocumentFileRepository) AddWithPreview(ctx context.Context, file *DocumentFile, content io.Reader, preview *DocumentFilePreview) error {
+	// Build the full field list including content and preview fields
+	fullFieldList := `Id, DocumentId, FileName, ContentType, FileSize, PageCount, FileData, FileBlobRef, PreviewData, PreviewBlobRef, PreviewType, Width, Height, CreatedAt, ModifiedAt`
+	query := `INSERT INTO DocumentFile (` + fullFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	createdAtStr := ccc.FormatSQLiteTimestamp(file.CreatedAt)
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(file.ModifiedAt)
 
 	// Use preview data if provided, otherwise use null/default values
 	var previewData []byte
-	var previewType string
+	var previewType sql.NullString
 	var width, height int
 
 	if preview != nil {
 		previewData = preview.PreviewData
-		previewType = preview.PreviewType
+		previewType = sql.NullString{String: preview.PreviewType, Valid: true}
 		width = preview.Width
 		height = preview.Height
 	}
 
-	_, err := r.db.ExecContext(ctx, query,
+	fileData, fileBlobRef, err := r.storeFileContent(ctx, content)
+	if err != nil {
+		return err
+	}
+	previewData, previewBlobRef, err := r.storeContent(ctx, previewData)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, query,
 		file.Id,
 		file.DocumentId,
 		file.FileName,
 		file.ContentType,
 		file.FileSize,
 		file.PageCount,
-		file.FileData,
+		fileData,
+		fileBlobRef,
 		previewData,
+		previewBlobRef,
 		previewType,
 		width,
 		height,
@@ -164,9 +159,9 @@ func (r *SQLiteDocumentFileRepository) AddWithPreview(ctx context.Context, file
 	return err
 }
 
-// Update updates an existing document file.
+// Update updates the fields of an existing document file. The content of a file cannot be changed.
 func (r *SQLiteDocumentFileRepository) Update(ctx context.Context, file *DocumentFile) error {
-	query := `UPDATE DocumentFile SET FileName = ?, ContentType = ?, FileSize = ?, PageCount = ?, FileData = ?, ModifiedAt = ? WHERE Id = ?`
+	query := `UPDATE DocumentFile SET FileName = ?, ContentType = ?, FileSize = ?, PageCount = ?, ModifiedAt = ? WHERE Id = ?`
 
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(file.ModifiedAt)
 
@@ -175,7 +170,6 @@ func (r *SQLiteDocumentFileRepository) Update(ctx context.Context, file *Documen
 		file.ContentType,
 		file.FileSize,
 		file.PageCount,
-		file.FileData,
 		modifiedAtStr,
 		file.Id,
 	)
@@ -198,6 +192,7 @@ func (r *SQLiteDocumentFileRepository) DeleteByDocumentId(ctx context.Context, d
 
 // FindDetailed finds all files for multiple documents along with their metadata in a single query.
 // Returns DocumentFileDetails structs that combine file and metadata information.
+// The contents of the files are not loaded.
 // If a file has no metadata, the Metadata field will be nil.
 // Supports batching with multiple document IDs for improved performance.
 func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documentIds []string) ([]*DocumentFileDetails, error) {
@@ -215,9 +210,9 @@ func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documen
 
 	query := fmt.Sprintf(`
 	SELECT 
-		df.Id, df.DocumentId, df.FileName, df.ContentType, df.FileSize, df.PageCount, df.FileData, df.CreatedAt, df.ModifiedAt,
+		df.Id, df.DocumentId, df.FileName, df.ContentType, df.FileSize, df.PageCount, df.CreatedAt, df.ModifiedAt,
 		dfm.ExtractedText, dfm.OcrConfidence, dfm.OcrStatus, dfm.OcrError, dfm.OcrStartedAt, dfm.OcrCompletedAt,
-		df.PreviewData, df.PreviewType, df.Width, df.Height
+		df.PreviewData, df.PreviewBlobRef, df.PreviewType, df.Width, df.Height
 	FROM DocumentFile df
 	LEFT JOIN DocumentFileMetadata dfm ON df.Id = dfm.DocumentFileId
 	WHERE df.DocumentId IN (%s)
@@ -230,6 +225,7 @@ func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documen
 	defer rows.Close()
 
 	var fileDetails []*DocumentFileDetails
+	previewBlobRefs := make(map[*DocumentFilePreview]sql.NullString)
 
 	for rows.Next() {
 		file := &DocumentFile{}
@@ -240,7 +236,8 @@ func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documen
 		var ocrError sql.NullString
 		var ocrStartedAt sql.NullString
 		var ocrCompletedAt sql.NullString
-		var previewData sql.NullString
+		var previewData []byte
+		var previewBlobRef sql.NullString
 		var previewType sql.NullString
 		var width sql.NullInt64
 		var height sql.NullInt64
@@ -253,7 +250,6 @@ func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documen
 			&file.ContentType,
 			&file.FileSize,
 			&file.PageCount,
-			&file.FileData,
 			&createdAtStr,
 			&modifiedAtStr,
 			// DocumentFileMetadata fields (nullable)
@@ -265,6 +261,7 @@ func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documen
 			&ocrCompletedAt,
 			// DocumentFilePreview fields (nullable)
 			&previewData,
+			&previewBlobRef,
 			&previewType,
 			&width,
 			&height,
@@ -304,22 +301,33 @@ func (r *SQLiteDocumentFileRepository) FindDetailed(ctx context.Context, documen
 		// If no metadata, fileDetail.Metadata remains nil
 
 		// Handle preview (might be null if no preview exists)
-		if previewData.Valid && previewType.Valid {
+		if (previewData != nil || previewBlobRef.Valid) && previewType.Valid {
 			preview := &DocumentFilePreview{
 				DocumentFileId: file.Id,
-				PreviewData:    []byte(previewData.String),
+				PreviewData:    previewData,
 				PreviewType:    previewType.String,
 				Width:          int(width.Int64),
 				Height:         int(height.Int64),
 			}
 			fileDetail.Preview = preview
+			previewBlobRefs[preview] = previewBlobRef
 		}
 		// If no preview, fileDetail.Preview remains nil
 
 		fileDetails = append(fileDetails, fileDetail)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	rows.Close()
 
-	return fileDetails, rows.Err()
+	for preview, blobRef := range previewBlobRefs {
+		if preview.PreviewData, err = r.loadContent(ctx, preview.PreviewData, blobRef); err != nil {
+			return nil, fmt.Errorf("failed to load preview of document file %s: %w", preview.DocumentFileId, err)
+		}
+	}
+
+	return fileDetails, nil
 }
 
 // scanDocumentFile scans a database row into a DocumentFile struct.
@@ -334,7 +342,6 @@ func scanDocumentFile(scanner ccc.RowScanner) (*DocumentFile, error) {
 		&file.ContentType,
 		&file.FileSize,
 		&file.PageCount,
-		&file.FileData,
 		&createdAtStr,
 		&modifiedAtStr,
 	)
@@ -358,12 +365,69 @@ func scanDocumentFile(scanner ccc.RowScanner) (*DocumentFile, error) {
 	return file, nil
 }
 
+// storeFileContent stores a file content in the blob store chunk by chunk and returns the reference to it.
+// Without a blob store, the content is read to be stored in the row.
+func (r *SQLiteDocumentFileRepository) storeFileContent(ctx context.Context, content io.Reader) ([]byte, sql.NullString, error) {
+	return storeStreamedContent(ctx, r.blobs, content)
+}
+
+// storeStreamedContent stores a content in the blob store chunk by chunk and returns the reference to it.
+// Without a blob store, the content is read to be stored in a row.
+func storeStreamedContent(ctx context.Context, blobs blobstore.BlobStore, content io.Reader) ([]byte, sql.NullString, error) {
+	if blobs == nil {
+		data, err := io.ReadAll(content)
+		if err != nil {
+			return nil, sql.NullString{}, fmt.Errorf("failed to read content: %w", err)
+		}
+		return data, sql.NullString{}, nil
+	}
+
+	blobRef, err := blobstore.StoreChunks(ctx, blobs, content)
+	if err != nil {
+		return nil, sql.NullString{}, fmt.Errorf("failed to store content in blob store %s: %w", blobs.Name(), err)
+	}
+	// Content columns are NOT NULL, so contents in the blob store keep an empty content in the row
+	return []byte{}, sql.NullString{String: blobRef, Valid: true}, nil
+}
+
+// storeContent stores a preview in the blob store and returns the reference to it.
+// Without a blob store, the preview is returned to be stored in the row. Nil previews stay nil.
+func (r *SQLiteDocumentFileRepository) storeContent(ctx context.Context, content []byte) ([]byte, sql.NullString, error) {
+	if r.blobs == nil || content == nil {
+		return content, sql.NullString{}, nil
+	}
+
+	blobRef, err := blobstore.StoreChunks(ctx, r.blobs, bytes.NewReader(content))
+	if err != nil {
+		return nil, sql.NullString{}, fmt.Errorf("failed to store content in blob store %s: %w", r.blobs.Name(), err)
+	}
+	return []byte{}, sql.NullString{String: blobRef, Valid: true}, nil
+}
+
+// loadContent returns the preview of a row, or loads it from the blob store if the row references it
+func (r *SQLiteDocumentFileRepository) loadContent(ctx context.Context, content []byte, blobRef sql.NullString) ([]byte, error) {
+	if !blobRef.Valid {
+		return content, nil
+	}
+	if r.blobs == nil {
+		return nil, errBlobStoreNotConfigured
+	}
+	return blobstore.LoadChunks(ctx, r.blobs, blobRef.String)
+}
+
 // GetPreview retrieves a document file preview by document file ID.
 // Returns nil if no preview exists or if the document file doesn't exist.
 func (r *SQLiteDocumentFileRepository) GetPreview(ctx context.Context, documentFileId string) (*DocumentFilePreview, error) {
-	query := `SELECT ` + documentFilePreviewFieldList + ` FROM DocumentFile WHERE Id = ? AND PreviewData IS NOT NULL`
+	query := `SELECT ` + documentFilePreviewFieldList + ` FROM DocumentFile WHERE Id = ? AND ` + documentFileHasPreviewCondition
 	row := r.db.QueryRowContext(ctx, query, documentFileId)
-	return scanDocumentFilePreview(row)
+	preview, blobRef, err := scanDocumentFilePreview(row)
+	if err != nil || preview == nil {
+		return nil, err
+	}
+	if preview.PreviewData, err = r.loadContent(ctx, preview.PreviewData, blobRef); err != nil {
+		return nil, fmt.Errorf("failed to load preview of document file %s: %w", documentFileId, err)
+	}
+	return preview, nil
 }
 
 // SetPreview sets the preview data for a document file.
@@ -371,11 +435,17 @@ func (r *SQLiteDocumentFileRepository) GetPreview(ctx context.Context, documentF
 // This method handles both creating and updating preview data.
 func (r *SQLiteDocumentFileRepository) SetPreview(ctx context.Context, preview *DocumentFilePreview, modifiedAt time.Time) error {
 	// Update the preview fields and ModifiedAt timestamp
-	query := `UPDATE DocumentFile SET PreviewData = ?, PreviewType = ?, Width = ?, Height = ?, ModifiedAt = ? WHERE Id = ?`
+	query := `UPDATE DocumentFile SET PreviewData = ?, PreviewBlobRef = ?, PreviewType = ?, Width = ?, Height = ?, ModifiedAt = ? WHERE Id = ?`
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(modifiedAt)
 
+	previewData, previewBlobRef, err := r.storeContent(ctx, preview.PreviewData)
+	if err != nil {
+		return err
+	}
+
 	result, err := r.db.ExecContext(ctx, query,
-		preview.PreviewData,
+		previewData,
+		previewBlobRef,
 		preview.PreviewType,
 		preview.Width,
 		preview.Height,
@@ -402,7 +472,7 @@ func (r *SQLiteDocumentFileRepository) SetPreview(ctx context.Context, preview *
 // This operation is idempotent - if the document file doesn't exist, nothing happens.
 func (r *SQLiteDocumentFileRepository) DeletePreview(ctx context.Context, documentFileId string) error {
 	// Null out the preview fields and update ModifiedAt timestamp
-	query := `UPDATE DocumentFile SET PreviewData = NULL, PreviewType = NULL, Width = 0, Height = 0, ModifiedAt = ? WHERE Id = ?`
+	query := `UPDATE DocumentFile SET PreviewData = NULL, PreviewBlobRef = NULL, PreviewType = NULL, Width = 0, Height = 0, ModifiedAt = ? WHERE Id = ?`
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(time.Now())
 
 	_, err := r.db.ExecContext(ctx, query, modifiedAtStr, documentFileId)
@@ -410,25 +480,28 @@ func (r *SQLiteDocumentFileRepository) DeletePreview(ctx context.Context, docume
 }
 
 // scanDocumentFilePreview scans a database row into a DocumentFilePreview struct.
-func scanDocumentFilePreview(scanner ccc.RowScanner) (*DocumentFilePreview, error) {
+// It returns the reference to the preview in the blob store separately.
+func scanDocumentFilePreview(scanner ccc.RowScanner) (*DocumentFilePreview, sql.NullString, error) {
 	preview := &DocumentFilePreview{}
+	var blobRef sql.NullString
 
 	err := scanner.Scan(
 		&preview.DocumentFileId,
 		&preview.PreviewData,
+		&blobRef,
 		&preview.PreviewType,
 		&preview.Width,
 		&preview.Height,
 	)
 
 	if err == sql.ErrNoRows {
-		return nil, nil // Not found
+		return nil, blobRef, nil // Not found
 	}
 	if err != nil {
-		return nil, err
+		return nil, blobRef, err
 	}
 
-	return preview, nil
+	return preview, blobRef, nil
 }
 
 // FindOldestPreviewsByDocumentIds retrieves the oldest preview for each of the specified document IDs.
@@ -454,6 +527,7 @@ func (r *SQLiteDocumentFileRepository) FindOldestPreviewsByDocumentIds(ctx conte
 			DocumentId,
 			Id as DocumentFileId,
 			PreviewData,
+			PreviewBlobRef,
 			PreviewType,
 			Width,
 			Height
@@ -462,13 +536,14 @@ func (r *SQLiteDocumentFileRepository) FindOldestPreviewsByDocumentIds(ctx conte
 				DocumentId,
 				Id,
 				PreviewData,
+				PreviewBlobRef,
 				PreviewType,
 				Width,
 				Height,
 				ROW_NUMBER() OVER (PARTITION BY DocumentId ORDER BY CreatedAt ASC) as rn
 			FROM DocumentFile 
 			WHERE DocumentId IN (%s)
-			AND PreviewData IS NOT NULL 
+			AND `+documentFileHasPreviewCondition+`
 			AND PreviewType IS NOT NULL
 		) ranked_files
 		WHERE rn = 1
@@ -481,14 +556,17 @@ func (r *SQLiteDocumentFileRepository) FindOldestPreviewsByDocumentIds(ctx conte
 	defer rows.Close()
 
 	result := make(map[string]*DocumentFilePreview)
+	blobRefs := make(map[*DocumentFilePreview]sql.NullString)
 	for rows.Next() {
 		var documentId string
+		var blobRef sql.NullString
 		preview := &DocumentFilePreview{}
 
 		err := rows.Scan(
 			&documentId,
 			&preview.DocumentFileId,
 			&preview.PreviewData,
+			&blobRef,
 			&preview.PreviewType,
 			&preview.Width,
 			&preview.Height,
@@ -498,11 +576,19 @@ func (r *SQLiteDocumentFileRepository) FindOldestPreviewsByDocumentIds(ctx conte
 		}
 
 		result[documentId] = preview
+		blobRefs[preview] = blobRef
 	}
 
 	if err = rows.Err(); err != nil {
 		return nil, err
 	}
+	rows.Close()
+
+	for preview, blobRef := range blobRefs {
+		if preview.PreviewData, err = r.loadContent(ctx, preview.PreviewData, blobRef); err != nil {
+			return nil, fmt.Errorf("failed to load preview of document file %s: %w", preview.DocumentFileId, err)
+		}
+	}
 
 	return result, nil
 }
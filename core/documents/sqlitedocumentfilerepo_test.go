@@ -0,0 +1,123 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestDocumentFilesAreMovedToBlobStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	store, err := blobstore.NewFileSystemBlobStore(filepath.Join(t.TempDir(), "blobs"))
+	if err != nil {
+		t.Fatalf("failed to create blob store: %v", err)
+	}
+
+	// A file stored in the database before the blob store was configured, larger than a chunk
+	now := time.Now().UTC()
+	content := bytes.Repeat([]byte("encrypted "), blobstore.ChunkSize/5)
+	previewData := []byte("encrypted preview")
+	document := &Document{Id: "doc-1", UserId: "user-1", CreatedAt: now, ModifiedAt: now}
+	file := &DocumentFile{Id: "file-1", DocumentId: document.Id, FileName: "name", ContentType: "application/pdf",
+		FileSize: int64(len(content)), PageCount: 1, CreatedAt: now, ModifiedAt: now}
+	preview := &DocumentFilePreview{DocumentFileId: file.Id, PreviewData: previewData, PreviewType: "image/png", Width: 1, Height: 1}
+
+	legacyUow := NewDocumentUnitOfWork(db, nil)
+	if err := legacyUow.DocumentRepo().Add(ctx, document); err != nil {
+		t.Fatalf("failed to add document: %v", err)
+	}
+	if err := legacyUow.DocumentFileRepo().AddWithPreview(ctx, file, bytes.NewReader(content), preview); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+
+	summary, err := NewDefaultDocumentBlobMigrator(db, store, nil).MigrateToBlobStore(ctx)
+	if err != nil {
+		t.Fatalf("failed to migrate files: %v", err)
+	}
+	if summary.Files != 1 || summary.Previews != 1 || summary.Skipped != 0 {
+		t.Fatalf("unexpected migration summary: %+v", summary)
+	}
+
+	var storedLength int
+	if err := db.QueryRow(`SELECT length(FileData) FROM DocumentFile WHERE Id = ?`, file.Id).Scan(&storedLength); err != nil {
+		t.Fatalf("failed to query file: %v", err)
+	}
+	if storedLength != 0 {
+		t.Fatalf("expected the content to be removed from the database, found %d bytes", storedLength)
+	}
+
+	// The repository loads the contents from the blob store, but fails without it
+	uow := NewDocumentUnitOfWork(db, store)
+	if loaded := readContent(t, uow, file.Id); !bytes.Equal(loaded, content) {
+		t.Fatalf("loaded content differs from the stored content")
+	}
+	loadedPreview, err := uow.DocumentFileRepo().GetPreview(ctx, file.Id)
+	if err != nil || loadedPreview == nil || !bytes.Equal(loadedPreview.PreviewData, previewData) {
+		t.Fatalf("failed to load preview: %v", err)
+	}
+	if _, err := legacyUow.DocumentFileRepo().OpenContent(ctx, file.Id); err == nil {
+		t.Fatalf("expected loading without blob store to fail")
+	}
+
+	// Deleting a file leaves the chunks of its content and preview unreferenced
+	replacement := &DocumentFile{Id: "file-2", DocumentId: document.Id, FileName: "name", ContentType: "text/plain",
+		FileSize: 11, CreatedAt: now, ModifiedAt: now}
+	if err := uow.DocumentFileRepo().Add(ctx, replacement, bytes.NewReader([]byte("new content"))); err != nil {
+		t.Fatalf("failed to add file: %v", err)
+	}
+	if err := uow.DocumentFileRepo().Delete(ctx, file.Id); err != nil {
+		t.Fatalf("failed to delete file: %v", err)
+	}
+
+	sources := []blobstore.BlobReferenceSource{NewSQLiteDocumentBlobReferenceRepository(db)}
+	garbageCollector := blobstore.NewDefaultBlobGarbageCollector(store, sources, nil)
+	young, err := garbageCollector.CollectGarbage(ctx, time.Now().Add(-time.Hour), false)
+	if err != nil {
+		t.Fatalf("failed to collect garbage: %v", err)
+	}
+	if young.Unreferenced != 3 || young.Deleted != 0 {
+		t.Fatalf("expected three young unreferenced chunks to be kept: %+v", young)
+	}
+
+	collected, err := garbageCollector.CollectGarbage(ctx, time.Now().Add(time.Hour), false)
+	if err != nil {
+		t.Fatalf("failed to collect garbage: %v", err)
+	}
+	if collected.Deleted != 3 || collected.Scanned != 4 {
+		t.Fatalf("expected the chunks of the deleted file to be deleted: %+v", collected)
+	}
+
+	if loaded := readContent(t, uow, replacement.Id); string(loaded) != "new content" {
+		t.Fatalf("failed to load remaining file after garbage collection")
+	}
+}
+
+func readContent(t *testing.T, uow DocumentUnitOfWork, fileId string) []byte {
+	t.Helper()
+	content, err := uow.DocumentFileRepo().OpenContent(context.Background(), fileId)
+	if err != nil || content == nil {
+		t.Fatalf("failed to open content of file %s: %v", fileId, err)
+	}
+	data, err := io.ReadAll(content)
+	if err != nil {
+		t.Fatalf("failed to read content of file %s: %v", fileId, err)
+	}
+	return data
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"database/sql"
 	"strings"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	_ "github.com/mattn/go-sqlite3"
@@ -16,82 +17,49 @@ type SQLiteDocumentRepository struct {
 
 const (
 	// Field list for Document table queries
-	documentFieldList = `Id, UserId, Title, Description, Issuer, IssueDate, CreatedAt, ModifiedAt`
+	documentFieldList = `Id, UserId, Title, Description, Issuer, IssueDate, CreatedAt, ModifiedAt, DeletedAt`
 )
 
 // newSQLiteDocumentRepository creates a new SQLiteDocumentRepository instance.
 func newSQLiteDocumentRepository(db ccc.DBExecutor) DocumentRepository {
-	repo := &SQLiteDocumentRepository{db: db}
-
-	// Initialize table if we have a *sql.DB (not transaction)
-	if sqlDB, ok := db.(*sql.DB); ok {
-		if err := repo.initializeTable(sqlDB); err != nil {
-			// Log error but don't fail - table might already exist
-			// In a real application, you'd want proper logging here
-		}
-	}
-
-	return repo
+	return &SQLiteDocumentRepository{db: db}
 }
 
-// initializeTable creates the Document table if it doesn't exist
-func (r *SQLiteDocumentRepository) initializeTable(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS Document (
-		Id TEXT PRIMARY KEY,
-		UserId TEXT NOT NULL,
-		Title TEXT NOT NULL,
-		Description TEXT,
-		Issuer TEXT,
-		IssueDate TIMESTAMP,
-		CreatedAt TIMESTAMP NOT NULL,
-		ModifiedAt TIMESTAMP NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_document_userid ON Document(UserId);
-	CREATE INDEX IF NOT EXISTS idx_document_created ON Document(CreatedAt);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	// Try to add foreign key constraint from Document.UserId to User.Id
-	// If this fails, it's okay and we can proceed anyway
-	fkQuery := `
-	ALTER TABLE Document ADD CONSTRAINT fk_document_userid 
-	FOREIGN KEY (UserId) REFERENCES User(Id) ON DELETE CASCADE;
-	`
-	_, fkErr := db.Exec(fkQuery)
-	if fkErr != nil {
-		// Log or ignore the error - foreign key constraint is optional
-		// The constraint might fail if:
-		// - User table doesn't exist yet
-		// - Constraint already exists
-		// - SQLite was compiled without foreign key support
-		// We continue anyway as this is not critical for basic functionality
-	}
-
-	// Migrate: add Issuer column if it doesn't exist
-	db.Exec(`ALTER TABLE Document ADD COLUMN Issuer TEXT;`)
-	// Migrate: add IssueDate column if it doesn't exist
-	db.Exec(`ALTER TABLE Document ADD COLUMN IssueDate TIMESTAMP;`)
-	// Migrate: add index on IssueDate if it doesn't exist
-	db.Exec(`CREATE INDEX IF NOT EXISTS idx_document_issuedate ON Document(IssueDate);`)
+// FindById finds a document by its ID. Documents in the trash are not found.
+func (r *SQLiteDocumentRepository) FindById(ctx context.Context, documentId string) (*Document, error) {
+	query := `SELECT ` + documentFieldList + ` FROM Document WHERE Id = ? AND DeletedAt IS NULL`
+	row := r.db.QueryRowContext(ctx, query, documentId)
+	return scanDocument(row)
+}
 
-	return nil
+// FindByUserId finds all documents for a user, except for those in the trash.
+func (r *SQLiteDocumentRepository) FindByUserId(ctx context.Context, userId string) ([]*Document, error) {
+	query := `SELECT ` + documentFieldList + ` FROM Document WHERE UserId = ? AND DeletedAt IS NULL ORDER BY ModifiedAt DESC`
+	return r.findDocuments(ctx, query, userId)
 }
 
-// FindById finds a document by its ID.
-func (r *SQLiteDocumentRepository) FindById(ctx context.Context, documentId string) (*Document, error) {
-	query := `SELECT ` + documentFieldList + ` FROM Document WHERE Id = ?`
+// FindTrashedById finds a document in the trash by its ID.
+func (r *SQLiteDocumentRepository) FindTrashedById(ctx context.Context, documentId string) (*Document, error) {
+	query := `SELECT ` + documentFieldList + ` FROM Document WHERE Id = ? AND DeletedAt IS NOT NULL`
 	row := r.db.QueryRowContext(ctx, query, documentId)
 	return scanDocument(row)
 }
 
-// FindByUserId finds all documents for a user.
-func (r *SQLiteDocumentRepository) FindByUserId(ctx context.Context, userId string) ([]*Document, error) {
-	query := `SELECT ` + documentFieldList + ` FROM Document WHERE UserId = ? ORDER BY ModifiedAt DESC`
-	rows, err := r.db.QueryContext(ctx, query, userId)
+// FindTrashedByUserId finds all documents of a user that are in the trash, most recently deleted first.
+func (r *SQLiteDocumentRepository) FindTrashedByUserId(ctx context.Context, userId string) ([]*Document, error) {
+	query := `SELECT ` + documentFieldList + ` FROM Document WHERE UserId = ? AND DeletedAt IS NOT NULL ORDER BY DeletedAt DESC`
+	return r.findDocuments(ctx, query, userId)
+}
+
+// FindTrashedBefore finds the documents of all users that were moved to the trash before the given time.
+func (r *SQLiteDocumentRepository) FindTrashedBefore(ctx context.Context, deletedBefore time.Time) ([]*Document, error) {
+	query := `SELECT ` + documentFieldList + ` FROM Document WHERE DeletedAt IS NOT NULL AND DeletedAt < ?`
+	return r.findDocuments(ctx, query, ccc.FormatSQLiteTimestamp(deletedBefore))
+}
+
+// findDocuments finds all documents matched by a query.
+func (r *SQLiteDocumentRepository) findDocuments(ctx context.Context, query string, args ...interface{}) ([]*Document, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +78,7 @@ func (r *SQLiteDocumentRepository) FindByUserId(ctx context.Context, userId stri
 
 // Add adds a new document.
 func (r *SQLiteDocumentRepository) Add(ctx context.Context, document *Document) error {
-	query := `INSERT INTO Document (` + documentFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO Document (` + documentFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 
 	createdAtStr := ccc.FormatSQLiteTimestamp(document.CreatedAt)
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(document.ModifiedAt)
@@ -129,6 +97,7 @@ func (r *SQLiteDocumentRepository) Add(ctx context.Context, document *Document)
 		issueDateStr,
 		createdAtStr,
 		modifiedAtStr,
+		nil,
 	)
 	return err
 }
@@ -155,6 +124,20 @@ func (r *SQLiteDocumentRepository) Update(ctx context.Context, document *Documen
 	return err
 }
 
+// MoveToTrash marks a document as deleted, which hides it from all other queries.
+func (r *SQLiteDocumentRepository) MoveToTrash(ctx context.Context, documentId string, deletedAt time.Time) error {
+	query := `UPDATE Document SET DeletedAt = ? WHERE Id = ? AND DeletedAt IS NULL`
+	_, err := r.db.ExecContext(ctx, query, ccc.FormatSQLiteTimestamp(deletedAt), documentId)
+	return err
+}
+
+// RestoreFromTrash clears the deletion mark of a document.
+func (r *SQLiteDocumentRepository) RestoreFromTrash(ctx context.Context, documentId string) error {
+	query := `UPDATE Document SET DeletedAt = NULL WHERE Id = ?`
+	_, err := r.db.ExecContext(ctx, query, documentId)
+	return err
+}
+
 // Delete deletes a document by its ID.
 func (r *SQLiteDocumentRepository) Delete(ctx context.Context, documentId string) error {
 	query := `DELETE FROM Document WHERE Id = ?`
@@ -179,19 +162,19 @@ func (r *SQLiteDocumentRepository) FindDetailed(ctx context.Context, userId stri
 	queryParts = append(queryParts, `
 		SELECT 
 			d.Id, d.UserId, d.Title, d.Description, d.Issuer, d.IssueDate, d.CreatedAt, d.ModifiedAt,
-			t.Id as TagId, t.Name as TagName, t.Color as TagColor, t.CreatedAt as TagCreatedAt, t.ModifiedAt as TagModifiedAt,
+			t.Id as TagId, t.Name as TagName, t.NameIndex as TagNameIndex, t.Color as TagColor, t.CreatedAt as TagCreatedAt, t.ModifiedAt as TagModifiedAt,
 			COALESCE(fc.FileCount, 0) as FileCount
 		FROM Document d
 		LEFT JOIN DocumentTag dt ON d.Id = dt.DocumentId
-		LEFT JOIN Tag t ON dt.TagId = t.Id
+		LEFT JOIN Tag t ON dt.TagId = t.Id AND t.DeletedAt IS NULL
 		LEFT JOIN (
 			SELECT DocumentId, COUNT(*) as FileCount 
 			FROM DocumentFile 
 			GROUP BY DocumentId
 		) fc ON d.Id = fc.DocumentId`)
 
-	// Base WHERE clause
-	whereParts := []string{"d.UserId = ?"}
+	// Base WHERE clause, documents in the trash are excluded
+	whereParts := []string{"d.UserId = ?", "d.DeletedAt IS NULL"}
 	args = append(args, userId)
 
 	// Add date range filters
@@ -229,7 +212,7 @@ func (r *SQLiteDocumentRepository) FindDetailed(ctx context.Context, userId stri
 
 	// Build final query
 	queryParts = append(queryParts, "WHERE "+strings.Join(whereParts, " AND "))
-	queryParts = append(queryParts, "ORDER BY d.ModifiedAt DESC, t.Name ASC")
+	queryParts = append(queryParts, "ORDER BY d.ModifiedAt DESC")
 
 	query := strings.Join(queryParts, " ")
 
@@ -246,12 +229,12 @@ func (r *SQLiteDocumentRepository) FindDetailed(ctx context.Context, userId stri
 		var doc Document
 		var createdAtStr, modifiedAtStr string
 		var issuerStr, issueDateStr sql.NullString
-		var tagId, tagName, tagColor, tagCreatedAtStr, tagModifiedAtStr sql.NullString
+		var tagId, tagName, tagNameIndex, tagColor, tagCreatedAtStr, tagModifiedAtStr sql.NullString
 		var fileCount int
 
 		err := rows.Scan(
 			&doc.Id, &doc.UserId, &doc.Title, &doc.Description, &issuerStr, &issueDateStr, &createdAtStr, &modifiedAtStr,
-			&tagId, &tagName, &tagColor, &tagCreatedAtStr, &tagModifiedAtStr,
+			&tagId, &tagName, &tagNameIndex, &tagColor, &tagCreatedAtStr, &tagModifiedAtStr,
 			&fileCount,
 		)
 		if err != nil {
@@ -302,10 +285,11 @@ func (r *SQLiteDocumentRepository) FindDetailed(ctx context.Context, userId stri
 
 			if !tagExists {
 				tag := &Tag{
-					Id:     tagId.String,
-					UserId: userId, // We know this from the query
-					Name:   tagName.String,
-					Color:  tagColor.String,
+					Id:        tagId.String,
+					UserId:    userId, // We know this from the query
+					Name:      tagName.String,
+					NameIndex: tagNameIndex.String,
+					Color:     tagColor.String,
 				}
 
 				if tagCreatedAtStr.Valid {
@@ -334,7 +318,7 @@ func (r *SQLiteDocumentRepository) FindDetailed(ctx context.Context, userId stri
 func scanDocument(scanner ccc.RowScanner) (*Document, error) {
 	doc := &Document{}
 	var createdAtStr, modifiedAtStr string
-	var issuerStr, issueDateStr sql.NullString
+	var issuerStr, issueDateStr, deletedAtStr sql.NullString
 
 	err := scanner.Scan(
 		&doc.Id,
@@ -345,6 +329,7 @@ func scanDocument(scanner ccc.RowScanner) (*Document, error) {
 		&issueDateStr,
 		&createdAtStr,
 		&modifiedAtStr,
+		&deletedAtStr,
 	)
 
 	if err == sql.ErrNoRows {
@@ -372,6 +357,13 @@ func scanDocument(scanner ccc.RowScanner) (*Document, error) {
 			doc.IssueDate = &parsed
 		}
 	}
+	if deletedAtStr.Valid {
+		deletedAt, err := ccc.ParseSQLiteTimestamp(deletedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+		doc.DeletedAt = &deletedAt
+	}
 
 	return doc, nil
 }
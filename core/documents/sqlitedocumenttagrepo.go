@@ -2,7 +2,6 @@ package documents
 
 import (
 	"context"
-	"database/sql"
 	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
@@ -16,55 +15,7 @@ type SQLiteDocumentTagRepository struct {
 
 // newSQLiteDocumentTagRepository creates a new SQLiteDocumentTagRepository instance.
 func newSQLiteDocumentTagRepository(db ccc.DBExecutor) DocumentTagRepository {
-	repo := &SQLiteDocumentTagRepository{db: db}
-
-	// Initialize table if we have a *sql.DB (not transaction)
-	if sqlDB, ok := db.(*sql.DB); ok {
-		if err := repo.initializeTable(sqlDB); err != nil {
-			// Log error but don't fail - table might already exist
-		}
-	}
-
-	return repo
-}
-
-// initializeTable creates the DocumentTag junction table if it doesn't exist
-func (r *SQLiteDocumentTagRepository) initializeTable(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS DocumentTag (
-		DocumentId TEXT NOT NULL,
-		TagId TEXT NOT NULL,
-		CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
-		PRIMARY KEY (DocumentId, TagId)
-	);
-	CREATE INDEX IF NOT EXISTS idx_documenttag_documentid ON DocumentTag(DocumentId);
-	CREATE INDEX IF NOT EXISTS idx_documenttag_tagid ON DocumentTag(TagId);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	// Try to add foreign key constraints
-	fkQuery1 := `
-	ALTER TABLE DocumentTag ADD CONSTRAINT fk_documenttag_documentid 
-	FOREIGN KEY (DocumentId) REFERENCES Document(Id) ON DELETE CASCADE;
-	`
-	_, fkErr1 := db.Exec(fkQuery1)
-	if fkErr1 != nil {
-		// Log or ignore the error - foreign key constraint is optional
-	}
-
-	fkQuery2 := `
-	ALTER TABLE DocumentTag ADD CONSTRAINT fk_documenttag_tagid 
-	FOREIGN KEY (TagId) REFERENCES Tag(Id) ON DELETE CASCADE;
-	`
-	_, fkErr2 := db.Exec(fkQuery2)
-	if fkErr2 != nil {
-		// Log or ignore the error - foreign key constraint is optional
-	}
-
-	return nil
+	return &SQLiteDocumentTagRepository{db: db}
 }
 
 // AddDocumentTag adds a tag to a document.
@@ -91,13 +42,20 @@ func (r *SQLiteDocumentTagRepository) RemoveAllDocumentTags(ctx context.Context,
 	return err
 }
 
-// FindDocumentsByTagId finds all documents that have a specific tag.
+// RemoveAllTagDocuments removes a tag from all documents.
+func (r *SQLiteDocumentTagRepository) RemoveAllTagDocuments(ctx context.Context, tagId string) error {
+	query := `DELETE FROM DocumentTag WHERE TagId = ?`
+	_, err := r.db.ExecContext(ctx, query, tagId)
+	return err
+}
+
+// FindDocumentsByTagId finds all documents that have a specific tag, except for those in the trash.
 func (r *SQLiteDocumentTagRepository) FindDocumentsByTagId(ctx context.Context, tagId string) ([]*Document, error) {
 	query := `
-	SELECT d.Id, d.UserId, d.Title, d.Description, d.CreatedAt, d.ModifiedAt 
+	SELECT d.Id, d.UserId, d.Title, d.Description, d.Issuer, d.IssueDate, d.CreatedAt, d.ModifiedAt, d.DeletedAt
 	FROM Document d
 	INNER JOIN DocumentTag dt ON d.Id = dt.DocumentId
-	WHERE dt.TagId = ?
+	WHERE dt.TagId = ? AND d.DeletedAt IS NULL
 	ORDER BY d.ModifiedAt DESC`
 
 	rows, err := r.db.QueryContext(ctx, query, tagId)
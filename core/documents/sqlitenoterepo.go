@@ -20,53 +20,7 @@ const (
 
 // newSQLiteNoteRepository creates a new SQLiteNoteRepository instance.
 func newSQLiteNoteRepository(db ccc.DBExecutor) NoteRepository {
-	repo := &SQLiteNoteRepository{db: db}
-
-	// Initialize table if we have a *sql.DB (not transaction)
-	if sqlDB, ok := db.(*sql.DB); ok {
-		if err := repo.initializeTable(sqlDB); err != nil {
-			// Log error but don't fail - table might already exist
-		}
-	}
-
-	return repo
-}
-
-// initializeTable creates the Note table if it doesn't exist
-func (r *SQLiteNoteRepository) initializeTable(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS Note (
-		Id TEXT PRIMARY KEY,
-		DocumentId TEXT NOT NULL,
-		UserId TEXT NOT NULL,
-		Content TEXT NOT NULL,
-		CreatedAt TIMESTAMP NOT NULL,
-		ModifiedAt TIMESTAMP NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_note_documentid ON Note(DocumentId);
-	CREATE INDEX IF NOT EXISTS idx_note_userid ON Note(UserId);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	// Try to add foreign key constraints
-	fkQueries := []string{
-		`ALTER TABLE Note ADD CONSTRAINT fk_note_documentid 
-		FOREIGN KEY (DocumentId) REFERENCES Document(Id) ON DELETE CASCADE;`,
-		`ALTER TABLE Note ADD CONSTRAINT fk_note_userid 
-		FOREIGN KEY (UserId) REFERENCES User(Id) ON DELETE CASCADE;`,
-	}
-
-	for _, fkQuery := range fkQueries {
-		_, fkErr := db.Exec(fkQuery)
-		if fkErr != nil {
-			// Log or ignore the error - foreign key constraints are optional
-		}
-	}
-
-	return nil
+	return &SQLiteNoteRepository{db: db}
 }
 
 // FindById finds a note by its ID.
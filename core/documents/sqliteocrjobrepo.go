@@ -0,0 +1,177 @@
+package documents
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteOcrJobRepository implements OcrJobRepository interface using SQLite.
+type SQLiteOcrJobRepository struct {
+	db ccc.DBExecutor
+}
+
+const (
+	// Field list for OcrJob table queries
+	ocrJobFieldList = `Id, DocumentFileId, UserId, Status, Attempts, NextRunAt, WrappedMek, LastError, CreatedAt, ModifiedAt`
+)
+
+// newSQLiteOcrJobRepository creates a new SQLiteOcrJobRepository instance.
+func newSQLiteOcrJobRepository(db ccc.DBExecutor) OcrJobRepository {
+	return &SQLiteOcrJobRepository{db: db}
+}
+
+// FindById finds an OCR job by its ID.
+func (r *SQLiteOcrJobRepository) FindById(ctx context.Context, jobId string) (*OcrJob, error) {
+	query := `SELECT ` + ocrJobFieldList + ` FROM OcrJob WHERE Id = ?`
+	row := r.db.QueryRowContext(ctx, query, jobId)
+	return scanOcrJob(row)
+}
+
+// FindByStatus finds all OCR jobs with the given status.
+func (r *SQLiteOcrJobRepository) FindByStatus(ctx context.Context, status string) ([]*OcrJob, error) {
+	query := `SELECT ` + ocrJobFieldList + ` FROM OcrJob WHERE Status = ? ORDER BY CreatedAt`
+	rows, err := r.db.QueryContext(ctx, query, status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []*OcrJob
+	for rows.Next() {
+		job, err := scanOcrJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, rows.Err()
+}
+
+// ClaimNext atomically marks the next due queued job as processing, increments its attempt counter and returns it.
+// Returns nil if no job is due.
+func (r *SQLiteOcrJobRepository) ClaimNext(ctx context.Context, now time.Time) (*OcrJob, error) {
+	query := `
+	UPDATE OcrJob
+	SET Status = ?, Attempts = Attempts + 1, ModifiedAt = ?
+	WHERE Id = (
+		SELECT Id FROM OcrJob
+		WHERE Status = ? AND NextRunAt <= ?
+		ORDER BY NextRunAt, CreatedAt
+		LIMIT 1
+	)
+	RETURNING ` + ocrJobFieldList
+
+	nowStr := ccc.FormatSQLiteTimestamp(now)
+
+	row := r.db.QueryRowContext(ctx, query,
+		OcrJobStatusProcessing,
+		nowStr,
+		OcrJobStatusQueued,
+		nowStr,
+	)
+	return scanOcrJob(row)
+}
+
+// Add adds a new OCR job.
+func (r *SQLiteOcrJobRepository) Add(ctx context.Context, job *OcrJob) error {
+	query := `INSERT INTO OcrJob (` + ocrJobFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+
+	_, err := r.db.ExecContext(ctx, query,
+		job.Id,
+		job.DocumentFileId,
+		job.UserId,
+		job.Status,
+		job.Attempts,
+		ccc.FormatSQLiteTimestamp(job.NextRunAt),
+		job.WrappedMek,
+		job.LastError,
+		ccc.FormatSQLiteTimestamp(job.CreatedAt),
+		ccc.FormatSQLiteTimestamp(job.ModifiedAt),
+	)
+	return err
+}
+
+// Update updates an existing OCR job.
+func (r *SQLiteOcrJobRepository) Update(ctx context.Context, job *OcrJob) error {
+	query := `
+	UPDATE OcrJob
+	SET Status = ?, Attempts = ?, NextRunAt = ?, WrappedMek = ?, LastError = ?, ModifiedAt = ?
+	WHERE Id = ?`
+
+	_, err := r.db.ExecContext(ctx, query,
+		job.Status,
+		job.Attempts,
+		ccc.FormatSQLiteTimestamp(job.NextRunAt),
+		job.WrappedMek,
+		job.LastError,
+		ccc.FormatSQLiteTimestamp(job.ModifiedAt),
+		job.Id,
+	)
+	return err
+}
+
+// Delete deletes an OCR job by its ID.
+func (r *SQLiteOcrJobRepository) Delete(ctx context.Context, jobId string) error {
+	query := `DELETE FROM OcrJob WHERE Id = ?`
+	_, err := r.db.ExecContext(ctx, query, jobId)
+	return err
+}
+
+// DeleteByDocumentFileId deletes all OCR jobs for a document file.
+func (r *SQLiteOcrJobRepository) DeleteByDocumentFileId(ctx context.Context, fileId string) error {
+	query := `DELETE FROM OcrJob WHERE DocumentFileId = ?`
+	_, err := r.db.ExecContext(ctx, query, fileId)
+	return err
+}
+
+// DeleteByDocumentId deletes all OCR jobs for the files of a document.
+func (r *SQLiteOcrJobRepository) DeleteByDocumentId(ctx context.Context, documentId string) error {
+	query := `DELETE FROM OcrJob WHERE DocumentFileId IN (SELECT Id FROM DocumentFile WHERE DocumentId = ?)`
+	_, err := r.db.ExecContext(ctx, query, documentId)
+	return err
+}
+
+// scanOcrJob scans a database row into an OcrJob struct.
+func scanOcrJob(scanner ccc.RowScanner) (*OcrJob, error) {
+	job := &OcrJob{}
+	var nextRunAtStr, createdAtStr, modifiedAtStr string
+
+	err := scanner.Scan(
+		&job.Id,
+		&job.DocumentFileId,
+		&job.UserId,
+		&job.Status,
+		&job.Attempts,
+		&nextRunAtStr,
+		&job.WrappedMek,
+		&job.LastError,
+		&createdAtStr,
+		&modifiedAtStr,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	job.NextRunAt, err = ccc.ParseSQLiteTimestamp(nextRunAtStr)
+	if err != nil {
+		return nil, err
+	}
+	job.CreatedAt, err = ccc.ParseSQLiteTimestamp(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	job.ModifiedAt, err = ccc.ParseSQLiteTimestamp(modifiedAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return job, nil
+}
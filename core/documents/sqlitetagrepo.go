@@ -3,6 +3,7 @@ package documents
 import (
 	"context"
 	"database/sql"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	_ "github.com/mattn/go-sqlite3"
@@ -15,92 +16,61 @@ type SQLiteTagRepository struct {
 
 const (
 	// Field list for Tag table queries
-	tagFieldList = `Id, UserId, Name, Color, CreatedAt, ModifiedAt`
+	tagFieldList = `Id, UserId, Name, NameIndex, Color, CreatedAt, ModifiedAt, DeletedAt`
 )
 
 // newSQLiteTagRepository creates a new SQLiteTagRepository instance.
 func newSQLiteTagRepository(db ccc.DBExecutor) TagRepository {
-	repo := &SQLiteTagRepository{db: db}
-
-	// Initialize table if we have a *sql.DB (not transaction)
-	if sqlDB, ok := db.(*sql.DB); ok {
-		if err := repo.initializeTable(sqlDB); err != nil {
-			// Log error but don't fail - table might already exist
-		}
-	}
-
-	return repo
+	return &SQLiteTagRepository{db: db}
 }
 
-// initializeTable creates the Tag table if it doesn't exist
-func (r *SQLiteTagRepository) initializeTable(db *sql.DB) error {
-	query := `
-	CREATE TABLE IF NOT EXISTS Tag (
-		Id TEXT PRIMARY KEY,
-		UserId TEXT NOT NULL,
-		Name TEXT NOT NULL,
-		Color TEXT NOT NULL DEFAULT '#007bff',
-		CreatedAt TIMESTAMP NOT NULL,
-		ModifiedAt TIMESTAMP NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_tag_userid ON Tag(UserId);
-	CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_user_name ON Tag(UserId, Name);
-	`
-	_, err := db.Exec(query)
-	if err != nil {
-		return err
-	}
-
-	// Try to add foreign key constraint from Tag.UserId to User.Id
-	fkQuery := `
-	ALTER TABLE Tag ADD CONSTRAINT fk_tag_userid 
-	FOREIGN KEY (UserId) REFERENCES User(Id) ON DELETE CASCADE;
-	`
-	_, fkErr := db.Exec(fkQuery)
-	if fkErr != nil {
-		// Log or ignore the error - foreign key constraint is optional
-	}
+// FindById finds a tag by its ID. Tags in the trash are not found.
+func (r *SQLiteTagRepository) FindById(ctx context.Context, tagId string) (*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE Id = ? AND DeletedAt IS NULL`
+	row := r.db.QueryRowContext(ctx, query, tagId)
+	return scanTag(row)
+}
 
-	return nil
+// FindByUserId finds all tags for a user, except for those in the trash.
+// The names are encrypted, so the tags have to be sorted by the caller.
+func (r *SQLiteTagRepository) FindByUserId(ctx context.Context, userId string) ([]*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE UserId = ? AND DeletedAt IS NULL`
+	return r.findTags(ctx, query, userId)
 }
 
-// FindById finds a tag by its ID.
-func (r *SQLiteTagRepository) FindById(ctx context.Context, tagId string) (*Tag, error) {
-	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE Id = ?`
+// FindTrashedById finds a tag in the trash by its ID.
+func (r *SQLiteTagRepository) FindTrashedById(ctx context.Context, tagId string) (*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE Id = ? AND DeletedAt IS NOT NULL`
 	row := r.db.QueryRowContext(ctx, query, tagId)
 	return scanTag(row)
 }
 
-// FindByUserId finds all tags for a user.
-func (r *SQLiteTagRepository) FindByUserId(ctx context.Context, userId string) ([]*Tag, error) {
-	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE UserId = ? ORDER BY Name ASC`
-	rows, err := r.db.QueryContext(ctx, query, userId)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
+// FindTrashedByUserId finds all tags of a user that are in the trash, most recently deleted first.
+func (r *SQLiteTagRepository) FindTrashedByUserId(ctx context.Context, userId string) ([]*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE UserId = ? AND DeletedAt IS NOT NULL ORDER BY DeletedAt DESC`
+	return r.findTags(ctx, query, userId)
+}
 
-	var tags []*Tag
-	for rows.Next() {
-		tag, err := scanTag(rows)
-		if err != nil {
-			continue // Skip problematic rows
-		}
-		tags = append(tags, tag)
-	}
-	return tags, rows.Err()
+// FindTrashedBefore finds the tags of all users that were moved to the trash before the given time.
+func (r *SQLiteTagRepository) FindTrashedBefore(ctx context.Context, deletedBefore time.Time) ([]*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE DeletedAt IS NOT NULL AND DeletedAt < ?`
+	return r.findTags(ctx, query, ccc.FormatSQLiteTimestamp(deletedBefore))
 }
 
-// FindByDocumentId finds all tags for a document.
+// FindByDocumentId finds all tags for a document, except for those in the trash.
+// The names are encrypted, so the tags have to be sorted by the caller.
 func (r *SQLiteTagRepository) FindByDocumentId(ctx context.Context, documentId string) ([]*Tag, error) {
 	query := `
-	SELECT t.Id, t.UserId, t.Name, t.Color, t.CreatedAt, t.ModifiedAt 
+	SELECT t.Id, t.UserId, t.Name, t.NameIndex, t.Color, t.CreatedAt, t.ModifiedAt, t.DeletedAt
 	FROM Tag t
 	INNER JOIN DocumentTag dt ON t.Id = dt.TagId
-	WHERE dt.DocumentId = ?
-	ORDER BY t.Name ASC`
+	WHERE dt.DocumentId = ? AND t.DeletedAt IS NULL`
+	return r.findTags(ctx, query, documentId)
+}
 
-	rows, err := r.db.QueryContext(ctx, query, documentId)
+// findTags finds all tags matched by a query.
+func (r *SQLiteTagRepository) findTags(ctx context.Context, query string, args ...interface{}) ([]*Tag, error) {
+	rows, err := r.db.QueryContext(ctx, query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -117,23 +87,23 @@ func (r *SQLiteTagRepository) FindByDocumentId(ctx context.Context, documentId s
 	return tags, rows.Err()
 }
 
-// FindByNameForUser finds a tag by name for a specific user.
-func (r *SQLiteTagRepository) FindByNameForUser(ctx context.Context, userId, name string) (*Tag, error) {
-	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE UserId = ? AND Name = ?`
-	row := r.db.QueryRowContext(ctx, query, userId, name)
-	tag, err := scanTag(row)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil // Tag not found
-		}
-		return nil, err
-	}
-	return tag, nil
+// FindByNameIndexForUser finds a tag by the blind index of its name for a specific user. Tags in the trash are not found.
+func (r *SQLiteTagRepository) FindByNameIndexForUser(ctx context.Context, userId, nameIndex string) (*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE UserId = ? AND NameIndex = ? AND DeletedAt IS NULL`
+	row := r.db.QueryRowContext(ctx, query, userId, nameIndex)
+	return scanTag(row)
+}
+
+// FindWithoutNameIndexForUser finds all tags of a user whose names were stored before tag names were encrypted.
+// Tags in the trash are included, so that a name conflict can be detected when they are restored.
+func (r *SQLiteTagRepository) FindWithoutNameIndexForUser(ctx context.Context, userId string) ([]*Tag, error) {
+	query := `SELECT ` + tagFieldList + ` FROM Tag WHERE UserId = ? AND (NameIndex IS NULL OR NameIndex = '')`
+	return r.findTags(ctx, query, userId)
 }
 
 // Add adds a new tag.
 func (r *SQLiteTagRepository) Add(ctx context.Context, tag *Tag) error {
-	query := `INSERT INTO Tag (` + tagFieldList + `) VALUES (?, ?, ?, ?, ?, ?)`
+	query := `INSERT INTO Tag (` + tagFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?)`
 
 	createdAtStr := ccc.FormatSQLiteTimestamp(tag.CreatedAt)
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(tag.ModifiedAt)
@@ -142,21 +112,24 @@ func (r *SQLiteTagRepository) Add(ctx context.Context, tag *Tag) error {
 		tag.Id,
 		tag.UserId,
 		tag.Name,
+		sql.NullString{String: tag.NameIndex, Valid: tag.NameIndex != ""},
 		tag.Color,
 		createdAtStr,
 		modifiedAtStr,
+		nil,
 	)
 	return err
 }
 
 // Update updates an existing tag.
 func (r *SQLiteTagRepository) Update(ctx context.Context, tag *Tag) error {
-	query := `UPDATE Tag SET Name = ?, Color = ?, ModifiedAt = ? WHERE Id = ?`
+	query := `UPDATE Tag SET Name = ?, NameIndex = ?, Color = ?, ModifiedAt = ? WHERE Id = ?`
 
 	modifiedAtStr := ccc.FormatSQLiteTimestamp(tag.ModifiedAt)
 
 	_, err := r.db.ExecContext(ctx, query,
 		tag.Name,
+		sql.NullString{String: tag.NameIndex, Valid: tag.NameIndex != ""},
 		tag.Color,
 		modifiedAtStr,
 		tag.Id,
@@ -164,6 +137,21 @@ func (r *SQLiteTagRepository) Update(ctx context.Context, tag *Tag) error {
 	return err
 }
 
+// MoveToTrash marks a tag as deleted, which hides it from all other queries.
+// The tag keeps its document relations, so that they are back when it is restored.
+func (r *SQLiteTagRepository) MoveToTrash(ctx context.Context, tagId string, deletedAt time.Time) error {
+	query := `UPDATE Tag SET DeletedAt = ? WHERE Id = ? AND DeletedAt IS NULL`
+	_, err := r.db.ExecContext(ctx, query, ccc.FormatSQLiteTimestamp(deletedAt), tagId)
+	return err
+}
+
+// RestoreFromTrash clears the deletion mark of a tag.
+func (r *SQLiteTagRepository) RestoreFromTrash(ctx context.Context, tagId string) error {
+	query := `UPDATE Tag SET DeletedAt = NULL WHERE Id = ?`
+	_, err := r.db.ExecContext(ctx, query, tagId)
+	return err
+}
+
 // Delete deletes a tag by its ID.
 func (r *SQLiteTagRepository) Delete(ctx context.Context, tagId string) error {
 	query := `DELETE FROM Tag WHERE Id = ?`
@@ -175,14 +163,17 @@ func (r *SQLiteTagRepository) Delete(ctx context.Context, tagId string) error {
 func scanTag(scanner ccc.RowScanner) (*Tag, error) {
 	tag := &Tag{}
 	var createdAtStr, modifiedAtStr string
+	var nameIndex, deletedAtStr sql.NullString
 
 	err := scanner.Scan(
 		&tag.Id,
 		&tag.UserId,
 		&tag.Name,
+		&nameIndex,
 		&tag.Color,
 		&createdAtStr,
 		&modifiedAtStr,
+		&deletedAtStr,
 	)
 
 	if err == sql.ErrNoRows {
@@ -191,6 +182,7 @@ func scanTag(scanner ccc.RowScanner) (*Tag, error) {
 	if err != nil {
 		return nil, err
 	}
+	tag.NameIndex = nameIndex.String
 
 	tag.CreatedAt, err = ccc.ParseSQLiteTimestamp(createdAtStr)
 	if err != nil {
@@ -200,6 +192,13 @@ func scanTag(scanner ccc.RowScanner) (*Tag, error) {
 	if err != nil {
 		return nil, err
 	}
+	if deletedAtStr.Valid {
+		deletedAt, err := ccc.ParseSQLiteTimestamp(deletedAtStr.String)
+		if err != nil {
+			return nil, err
+		}
+		tag.DeletedAt = &deletedAt
+	}
 
 	return tag, nil
 }
@@ -0,0 +1,220 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteUploadSessionRepository implements UploadSessionRepository interface using SQLite.
+// If a blob store is set, the contents of parts are stored in it like the contents of document files,
+// the blobs of deleted parts are removed by the blob garbage collection.
+type SQLiteUploadSessionRepository struct {
+	db    ccc.DBExecutor
+	blobs blobstore.BlobStore
+}
+
+const (
+	// Field list for UploadSession table queries
+	uploadSessionFieldList = `Id, UserId, DocumentId, FileName, ContentType, Size, UploadOffset, CreatedAt, ExpiresAt`
+)
+
+// newSQLiteUploadSessionRepository creates a new SQLiteUploadSessionRepository instance.
+// Without a blob store, the contents of parts are stored in the database.
+func newSQLiteUploadSessionRepository(db ccc.DBExecutor, blobs blobstore.BlobStore) UploadSessionRepository {
+	return &SQLiteUploadSessionRepository{db: db, blobs: blobs}
+}
+
+// FindById finds an upload session by its ID.
+func (r *SQLiteUploadSessionRepository) FindById(ctx context.Context, sessionId string) (*UploadSession, error) {
+	query := `SELECT ` + uploadSessionFieldList + ` FROM UploadSession WHERE Id = ?`
+	row := r.db.QueryRowContext(ctx, query, sessionId)
+	return scanUploadSession(row)
+}
+
+// FindExpired finds the upload sessions of all users that expired before the given time.
+func (r *SQLiteUploadSessionRepository) FindExpired(ctx context.Context, before time.Time) ([]*UploadSession, error) {
+	query := `SELECT ` + uploadSessionFieldList + ` FROM UploadSession WHERE ExpiresAt < ? ORDER BY ExpiresAt`
+	rows, err := r.db.QueryContext(ctx, query, ccc.FormatSQLiteTimestamp(before))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sessions []*UploadSession
+	for rows.Next() {
+		session, err := scanUploadSession(rows)
+		if err != nil {
+			return nil, err
+		}
+		sessions = append(sessions, session)
+	}
+	return sessions, rows.Err()
+}
+
+// CountByUserId counts the upload sessions of a user, including expired ones that were not deleted yet.
+func (r *SQLiteUploadSessionRepository) CountByUserId(ctx context.Context, userId string) (int, error) {
+	var count int
+	err := r.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM UploadSession WHERE UserId = ?`, userId).Scan(&count)
+	return count, err
+}
+
+// Add adds a new upload session.
+func (r *SQLiteUploadSessionRepository) Add(ctx context.Context, session *UploadSession) error {
+	query := `INSERT INTO UploadSession (` + uploadSessionFieldList + `) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, query,
+		session.Id,
+		session.UserId,
+		session.DocumentId,
+		session.FileName,
+		session.ContentType,
+		session.Size,
+		session.Offset,
+		ccc.FormatSQLiteTimestamp(session.CreatedAt),
+		ccc.FormatSQLiteTimestamp(session.ExpiresAt),
+	)
+	return err
+}
+
+// AddPart stores the encrypted content of a part and advances the offset of its session.
+// The content is stored before any statement is executed, so a transaction does not lock the database
+// while a part is received. Returns false if the offset of the session has changed in the meantime.
+func (r *SQLiteUploadSessionRepository) AddPart(ctx context.Context, part *UploadSessionPart, content io.Reader, expiresAt time.Time) (bool, error) {
+	data, blobRef, err := storeStreamedContent(ctx, r.blobs, content)
+	if err != nil {
+		return false, err
+	}
+
+	result, err := r.db.ExecContext(ctx, `UPDATE UploadSession SET UploadOffset = ?, ExpiresAt = ? WHERE Id = ? AND UploadOffset = ?`,
+		part.Offset+part.Size,
+		ccc.FormatSQLiteTimestamp(expiresAt),
+		part.SessionId,
+		part.Offset,
+	)
+	if err != nil {
+		return false, err
+	}
+	if affected, err := result.RowsAffected(); err != nil || affected == 0 {
+		return false, err
+	}
+
+	_, err = r.db.ExecContext(ctx, `INSERT INTO UploadSessionPart (SessionId, PartOffset, Size, Data, BlobRef) VALUES (?, ?, ?, ?, ?)`,
+		part.SessionId,
+		part.Offset,
+		part.Size,
+		data,
+		blobRef,
+	)
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// FindParts finds the parts of an upload session ordered by their offset. The contents are not loaded.
+func (r *SQLiteUploadSessionRepository) FindParts(ctx context.Context, sessionId string) ([]*UploadSessionPart, error) {
+	query := `SELECT SessionId, PartOffset, Size FROM UploadSessionPart WHERE SessionId = ? ORDER BY PartOffset`
+	rows, err := r.db.QueryContext(ctx, query, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var parts []*UploadSessionPart
+	for rows.Next() {
+		part := &UploadSessionPart{}
+		if err := rows.Scan(&part.SessionId, &part.Offset, &part.Size); err != nil {
+			return nil, err
+		}
+		parts = append(parts, part)
+	}
+	return parts, rows.Err()
+}
+
+// OpenPartContent returns a reader for the encrypted content of a part.
+// Returns nil if the part doesn't exist.
+func (r *SQLiteUploadSessionRepository) OpenPartContent(ctx context.Context, sessionId string, offset int64) (io.Reader, error) {
+	var data []byte
+	var blobRef sql.NullString
+	err := r.db.QueryRowContext(ctx, `SELECT Data, BlobRef FROM UploadSessionPart WHERE SessionId = ? AND PartOffset = ?`, sessionId, offset).Scan(&data, &blobRef)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if !blobRef.Valid {
+		return bytes.NewReader(data), nil
+	}
+	if r.blobs == nil {
+		return nil, errBlobStoreNotConfigured
+	}
+	content, err := blobstore.OpenChunks(ctx, r.blobs, blobRef.String)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open part %d of upload session %s: %w", offset, sessionId, err)
+	}
+	return content, nil
+}
+
+// Delete deletes an upload session together with its parts.
+func (r *SQLiteUploadSessionRepository) Delete(ctx context.Context, sessionId string) error {
+	if _, err := r.db.ExecContext(ctx, `DELETE FROM UploadSessionPart WHERE SessionId = ?`, sessionId); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM UploadSession WHERE Id = ?`, sessionId)
+	return err
+}
+
+// DeleteByDocumentId deletes all upload sessions for a document together with their parts.
+func (r *SQLiteUploadSessionRepository) DeleteByDocumentId(ctx context.Context, documentId string) error {
+	query := `DELETE FROM UploadSessionPart WHERE SessionId IN (SELECT Id FROM UploadSession WHERE DocumentId = ?)`
+	if _, err := r.db.ExecContext(ctx, query, documentId); err != nil {
+		return err
+	}
+	_, err := r.db.ExecContext(ctx, `DELETE FROM UploadSession WHERE DocumentId = ?`, documentId)
+	return err
+}
+
+// scanUploadSession scans a database row into an UploadSession struct.
+func scanUploadSession(scanner ccc.RowScanner) (*UploadSession, error) {
+	session := &UploadSession{}
+	var createdAtStr, expiresAtStr string
+
+	err := scanner.Scan(
+		&session.Id,
+		&session.UserId,
+		&session.DocumentId,
+		&session.FileName,
+		&session.ContentType,
+		&session.Size,
+		&session.Offset,
+		&createdAtStr,
+		&expiresAtStr,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	session.CreatedAt, err = ccc.ParseSQLiteTimestamp(createdAtStr)
+	if err != nil {
+		return nil, err
+	}
+	session.ExpiresAt, err = ccc.ParseSQLiteTimestamp(expiresAtStr)
+	if err != nil {
+		return nil, err
+	}
+
+	return session, nil
+}
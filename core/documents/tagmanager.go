@@ -4,9 +4,18 @@ import (
 	"context"
 	"fmt"
 	"regexp"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+)
+
+const (
+	maxTagNameLength = 20
+	// maxTagRenameAttempts limits the search for a free name of a renamed tag
+	maxTagRenameAttempts = 100
 )
 
 // DefaultTagManager implements TagManager using a DocumentUnitOfWorkFactory and Logger
@@ -32,13 +41,11 @@ func NewDefaultTagManager(uowFactory DocumentUnitOfWorkFactory, idGenerator TagI
 
 // validateTagInput validates the tag name and color according to business rules.
 func validateTagInput(name, color string) error {
-	const maxNameLength = 20
-
-	if len(name) > maxNameLength {
+	if len(name) > maxTagNameLength {
 		return ccc.NewInvalidInputErrorWithMessage(
 			"name",
-			fmt.Sprintf("must not exceed %d characters", maxNameLength),
-			fmt.Sprintf("The tag name must not exceed %d characters.", maxNameLength),
+			fmt.Sprintf("must not exceed %d characters", maxTagNameLength),
+			fmt.Sprintf("The tag name must not exceed %d characters.", maxTagNameLength),
 		)
 	}
 	matched, _ := regexp.MatchString(`^#[0-9a-fA-F]{6}$`, color)
@@ -53,19 +60,24 @@ func validateTagInput(name, color string) error {
 }
 
 // CreateTag creates a new tag for the given user and request, assigning a generated ID.
+// The name is stored encrypted, together with its blind index to keep names unique.
 // The operation is performed in a transaction scope.
-func (m *DefaultTagManager) CreateTag(ctx context.Context, userId string, request CreateTagRequest) (*TagDto, error) {
+func (m *DefaultTagManager) CreateTag(ctx context.Context, userId string, request CreateTagRequest, dataProtector dataprotection.DataProtector) (*TagDto, error) {
 	if err := validateTagInput(request.Name, request.Color); err != nil {
 		return nil, err
 	}
+	encryptedName, nameIndex, err := m.protectTagName(userId, request.Name, dataProtector)
+	if err != nil {
+		return nil, err
+	}
 	now := time.Now()
 	uow := m.uowFactory.Create()
 	var tag *Tag
-	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+	err = uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
 		// Check if a tag with the same name already exists for this user
-		existingTag, err := uow.TagRepo().FindByNameForUser(ctx, userId, request.Name)
+		existingTag, err := uow.TagRepo().FindByNameIndexForUser(ctx, userId, nameIndex)
 		if err != nil {
-			m.logger.Error("Failed to check for existing tag", "userId", userId, "name", request.Name, "err", err)
+			m.logger.Error("Failed to check for existing tag", "userId", userId, "err", err)
 			return ccc.NewDatabaseError("check for existing tag", err)
 		}
 		if existingTag != nil {
@@ -79,13 +91,14 @@ func (m *DefaultTagManager) CreateTag(ctx context.Context, userId string, reques
 		tag = &Tag{
 			Id:         m.idGenerator.GenerateId(),
 			UserId:     userId,
-			Name:       request.Name,
+			Name:       encryptedName,
+			NameIndex:  nameIndex,
 			Color:      request.Color,
 			CreatedAt:  now,
 			ModifiedAt: now,
 		}
 		if err := uow.TagRepo().Add(ctx, tag); err != nil {
-			m.logger.Error("Failed to create tag", "userId", userId, "name", request.Name, "err", err)
+			m.logger.Error("Failed to create tag", "userId", userId, "err", err)
 			return ccc.NewDatabaseError("add tag", err)
 		}
 		return nil
@@ -93,10 +106,10 @@ func (m *DefaultTagManager) CreateTag(ctx context.Context, userId string, reques
 	if err != nil {
 		return nil, err
 	}
-	m.logger.Info("Tag created", "userId", userId, "name", request.Name)
+	m.logger.Info("Tag created", "userId", userId, "tagId", tag.Id)
 	return &TagDto{
 		Id:         tag.Id,
-		Name:       tag.Name,
+		Name:       request.Name,
 		Color:      tag.Color,
 		CreatedAt:  tag.CreatedAt,
 		ModifiedAt: tag.ModifiedAt,
@@ -105,7 +118,7 @@ func (m *DefaultTagManager) CreateTag(ctx context.Context, userId string, reques
 
 // GetTag retrieves a tag by its ID for the given user.
 // Returns a TagDto if found and owned by the user, otherwise a not found error.
-func (m *DefaultTagManager) GetTag(ctx context.Context, userId, tagId string) (*TagDto, error) {
+func (m *DefaultTagManager) GetTag(ctx context.Context, userId, tagId string, dataProtector dataprotection.DataProtector) (*TagDto, error) {
 	uow := m.uowFactory.Create()
 	tag, err := uow.TagRepo().FindById(ctx, tagId)
 	if err != nil {
@@ -116,6 +129,7 @@ func (m *DefaultTagManager) GetTag(ctx context.Context, userId, tagId string) (*
 		m.logger.Warn("Tag not found or not owned by user", "userId", userId, "tagId", tagId)
 		return nil, ccc.NewResourceNotFoundError(tagId, "Tag")
 	}
+	decryptTagNames([]*Tag{tag}, dataProtector, m.logger)
 	return &TagDto{
 		Id:         tag.Id,
 		Name:       tag.Name,
@@ -125,14 +139,16 @@ func (m *DefaultTagManager) GetTag(ctx context.Context, userId, tagId string) (*
 	}, nil
 }
 
-// GetUserTags retrieves all tags belonging to the given user.
-func (m *DefaultTagManager) GetUserTags(ctx context.Context, userId string) ([]*TagDto, error) {
+// GetUserTags retrieves all tags belonging to the given user, sorted by name.
+func (m *DefaultTagManager) GetUserTags(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TagDto, error) {
 	uow := m.uowFactory.Create()
 	tags, err := uow.TagRepo().FindByUserId(ctx, userId)
 	if err != nil {
 		m.logger.Error("Failed to get user tags", "userId", userId, "err", err)
 		return nil, ccc.NewDatabaseError("find user tags", err)
 	}
+	decryptTagNames(tags, dataProtector, m.logger)
+	sortTagsByName(tags)
 	var dtos []*TagDto
 	for _, tag := range tags {
 		dtos = append(dtos, &TagDto{
@@ -148,25 +164,7 @@ func (m *DefaultTagManager) GetUserTags(ctx context.Context, userId string) ([]*
 
 // UpdateTag updates the name and/or color of a tag for the given user and tag ID.
 // The operation is performed in a transaction scope.
-func (m *DefaultTagManager) UpdateTag(ctx context.Context, userId, tagId string, request UpdateTagRequest) error {
-	if request.Name != "" || request.Color != "" {
-		name := request.Name
-		color := request.Color
-		if name == "" || color == "" {
-			tag, _ := m.uowFactory.Create().TagRepo().FindById(ctx, tagId)
-			if tag != nil {
-				if name == "" {
-					name = tag.Name
-				}
-				if color == "" {
-					color = tag.Color
-				}
-			}
-		}
-		if err := validateTagInput(name, color); err != nil {
-			return err
-		}
-	}
+func (m *DefaultTagManager) UpdateTag(ctx context.Context, userId, tagId string, request UpdateTagRequest, dataProtector dataprotection.DataProtector) error {
 	uow := m.uowFactory.Create()
 	return uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
 		tag, err := uow.TagRepo().FindById(ctx, tagId)
@@ -179,29 +177,45 @@ func (m *DefaultTagManager) UpdateTag(ctx context.Context, userId, tagId string,
 			return ccc.NewResourceNotFoundError(tagId, "Tag")
 		}
 
+		name, err := m.tagName(tag, dataProtector)
+		if err != nil {
+			return err
+		}
+		color := tag.Color
+		if request.Name != "" {
+			name = request.Name
+		}
+		if request.Color != "" {
+			color = request.Color
+		}
+		if err := validateTagInput(name, color); err != nil {
+			return err
+		}
+
+		encryptedName, nameIndex, err := m.protectTagName(userId, name, dataProtector)
+		if err != nil {
+			return err
+		}
+
 		// Check for duplicate name if the name is being changed
-		if request.Name != "" && request.Name != tag.Name {
-			existingTag, err := uow.TagRepo().FindByNameForUser(ctx, userId, request.Name)
+		if nameIndex != tag.NameIndex {
+			existingTag, err := uow.TagRepo().FindByNameIndexForUser(ctx, userId, nameIndex)
 			if err != nil {
-				m.logger.Error("Failed to check for existing tag during update", "userId", userId, "name", request.Name, "err", err)
+				m.logger.Error("Failed to check for existing tag during update", "userId", userId, "tagId", tagId, "err", err)
 				return ccc.NewDatabaseError("check for existing tag", err)
 			}
-			if existingTag != nil {
+			if existingTag != nil && existingTag.Id != tag.Id {
 				return ccc.NewInvalidInputErrorWithMessage(
 					"name",
 					"already exists",
-					fmt.Sprintf("A tag with the name '%s' already exists.", request.Name),
+					fmt.Sprintf("A tag with the name '%s' already exists.", name),
 				)
 			}
 		}
 
-		if request.Name != "" {
-			tag.Name = request.Name
-		}
-		if request.Color != "" {
-			tag.Color = request.Color
-		}
-
+		tag.Name = encryptedName
+		tag.NameIndex = nameIndex
+		tag.Color = color
 		tag.ModifiedAt = time.Now()
 
 		if err := uow.TagRepo().Update(ctx, tag); err != nil {
@@ -213,8 +227,8 @@ func (m *DefaultTagManager) UpdateTag(ctx context.Context, userId, tagId string,
 	})
 }
 
-// DeleteTag deletes a tag and all its document-tag relations for the given user and tag ID.
-// The operation is idempotent and performed in a transaction scope.
+// DeleteTag moves a tag to the trash for the given user and tag ID. Its document-tag relations are kept
+// until the tag is purged. The operation is idempotent and performed in a transaction scope.
 func (m *DefaultTagManager) DeleteTag(ctx context.Context, userId, tagId string) error {
 	uow := m.uowFactory.Create()
 	alreadyDeleted := false
@@ -234,13 +248,9 @@ func (m *DefaultTagManager) DeleteTag(ctx context.Context, userId, tagId string)
 			m.logger.Warn("Tag not owned by user for delete", "userId", userId, "tagId", tagId)
 			return ccc.NewResourceNotFoundError(tagId, "Tag")
 		}
-		if err := uow.DocumentTagRepo().RemoveAllDocumentTags(ctx, tagId); err != nil {
-			m.logger.Error("Failed to remove document tags for tag delete", "userId", userId, "tagId", tagId, "err", err)
-			return ccc.NewDatabaseError("remove document tags for tag delete", err)
-		}
-		if err := uow.TagRepo().Delete(ctx, tagId); err != nil {
-			m.logger.Error("Failed to delete tag", "userId", userId, "tagId", tagId, "err", err)
-			return ccc.NewDatabaseError("delete tag", err)
+		if err := uow.TagRepo().MoveToTrash(ctx, tagId, time.Now()); err != nil {
+			m.logger.Error("Failed to move tag to the trash", "userId", userId, "tagId", tagId, "err", err)
+			return ccc.NewDatabaseError("move tag to trash", err)
 		}
 		return nil
 	})
@@ -250,6 +260,287 @@ func (m *DefaultTagManager) DeleteTag(ctx context.Context, userId, tagId string)
 	if alreadyDeleted {
 		return nil
 	}
-	m.logger.Info("Tag and all document tags deleted", "userId", userId, "tagId", tagId)
+	m.logger.Info("Tag moved to the trash", "userId", userId, "tagId", tagId)
+	return nil
+}
+
+// GetTrashedTags retrieves all tags of the given user that are in the trash.
+func (m *DefaultTagManager) GetTrashedTags(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TrashedTagDto, error) {
+	uow := m.uowFactory.Create()
+	tags, err := uow.TagRepo().FindTrashedByUserId(ctx, userId)
+	if err != nil {
+		m.logger.Error("Failed to get trashed tags", "userId", userId, "err", err)
+		return nil, ccc.NewDatabaseError("find trashed tags", err)
+	}
+	decryptTagNames(tags, dataProtector, m.logger)
+	dtos := make([]*TrashedTagDto, 0, len(tags))
+	for _, tag := range tags {
+		dtos = append(dtos, &TrashedTagDto{
+			Id:        tag.Id,
+			Name:      tag.Name,
+			Color:     tag.Color,
+			DeletedAt: *tag.DeletedAt,
+		})
+	}
+	return dtos, nil
+}
+
+// RestoreTag moves a tag of the given user out of the trash, together with its document-tag relations.
+// The operation is performed in a transaction scope.
+func (m *DefaultTagManager) RestoreTag(ctx context.Context, userId, tagId string, dataProtector dataprotection.DataProtector) error {
+	uow := m.uowFactory.Create()
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		tag, err := uow.TagRepo().FindTrashedById(ctx, tagId)
+		if err != nil {
+			m.logger.Error("Failed to find trashed tag", "userId", userId, "tagId", tagId, "err", err)
+			return ccc.NewDatabaseError("find trashed tag", err)
+		}
+		if tag == nil || tag.UserId != userId {
+			m.logger.Warn("Trashed tag not found or not owned by user", "userId", userId, "tagId", tagId)
+			return ccc.NewResourceNotFoundError(tagId, "Tag")
+		}
+
+		name, err := m.tagName(tag, dataProtector)
+		if err != nil {
+			return err
+		}
+		if tag.NameIndex == "" {
+			// The tag was moved to the trash before tag names were encrypted
+			if tag.Name, tag.NameIndex, err = m.protectTagName(userId, name, dataProtector); err != nil {
+				return err
+			}
+			if err := uow.TagRepo().Update(ctx, tag); err != nil {
+				m.logger.Error("Failed to encrypt name of trashed tag", "userId", userId, "tagId", tagId, "err", err)
+				return ccc.NewDatabaseError("update tag", err)
+			}
+		}
+
+		existingTag, err := uow.TagRepo().FindByNameIndexForUser(ctx, userId, tag.NameIndex)
+		if err != nil {
+			m.logger.Error("Failed to check for existing tag during restore", "userId", userId, "tagId", tagId, "err", err)
+			return ccc.NewDatabaseError("check for existing tag", err)
+		}
+		if existingTag != nil {
+			return ccc.NewInvalidInputErrorWithMessage(
+				"name",
+				"already exists",
+				fmt.Sprintf("A tag with the name '%s' already exists. Rename it before restoring this tag.", name),
+			)
+		}
+
+		if err := uow.TagRepo().RestoreFromTrash(ctx, tagId); err != nil {
+			m.logger.Error("Failed to restore tag", "userId", userId, "tagId", tagId, "err", err)
+			return ccc.NewDatabaseError("restore tag", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	m.logger.Info("Tag restored from the trash", "userId", userId, "tagId", tagId)
+	return nil
+}
+
+// PurgeTag permanently deletes a tag of the given user that is in the trash, together with its document-tag relations.
+// The operation is performed in a transaction scope.
+func (m *DefaultTagManager) PurgeTag(ctx context.Context, userId, tagId string) error {
+	uow := m.uowFactory.Create()
+	err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+		tag, err := uow.TagRepo().FindTrashedById(ctx, tagId)
+		if err != nil {
+			m.logger.Error("Failed to find trashed tag", "userId", userId, "tagId", tagId, "err", err)
+			return ccc.NewDatabaseError("find trashed tag", err)
+		}
+		if tag == nil || tag.UserId != userId {
+			m.logger.Warn("Trashed tag not found or not owned by user", "userId", userId, "tagId", tagId)
+			return ccc.NewResourceNotFoundError(tagId, "Tag")
+		}
+		return purgeTag(ctx, uow, tagId)
+	})
+	if err != nil {
+		return err
+	}
+	m.logger.Info("Tag purged", "userId", userId, "tagId", tagId)
+	return nil
+}
+
+// PurgeTrashedTags permanently deletes the tags of all users that were moved to the trash before the given time.
+// Each tag is purged in its own transaction; the number of purged tags is returned also if one of them failed.
+func (m *DefaultTagManager) PurgeTrashedTags(ctx context.Context, deletedBefore time.Time) (int, error) {
+	tags, err := m.uowFactory.Create().TagRepo().FindTrashedBefore(ctx, deletedBefore)
+	if err != nil {
+		m.logger.Error("Failed to find expired trashed tags", "deletedBefore", deletedBefore, "err", err)
+		return 0, ccc.NewDatabaseError("find expired trashed tags", err)
+	}
+
+	purged := 0
+	for _, tag := range tags {
+		uow := m.uowFactory.Create()
+		err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+			return purgeTag(ctx, uow, tag.Id)
+		})
+		if err != nil {
+			m.logger.Error("Failed to purge tag", "userId", tag.UserId, "tagId", tag.Id, "err", err)
+			return purged, err
+		}
+		purged++
+	}
+
+	if purged > 0 {
+		m.logger.Info("Purged expired tags from the trash", "count", purged, "deletedBefore", deletedBefore)
+	}
+	return purged, nil
+}
+
+// EncryptTagNames encrypts the names of the tags of a user that were stored before tag names were encrypted and
+// computes their blind index. An active tag whose name is taken by another tag is renamed, e.g. to "Bills (2)".
+// Tags in the trash keep their name, a conflict is detected when they are restored.
+func (m *DefaultTagManager) EncryptTagNames(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) (*TagNameReport, error) {
+	tags, err := m.uowFactory.Create().TagRepo().FindWithoutNameIndexForUser(ctx, userId)
+	if err != nil {
+		m.logger.Error("Failed to find tags with plain names", "userId", userId, "err", err)
+		return nil, ccc.NewDatabaseError("find tags with plain names", err)
+	}
+
+	report := &TagNameReport{}
+	for _, tag := range tags {
+		plainName := tag.Name
+		var name string
+		uow := m.uowFactory.Create()
+		err := uow.Execute(ctx, func(uow DocumentUnitOfWork) error {
+			var nameIndex string
+			var err error
+			name, nameIndex, err = m.freeTagName(ctx, uow, tag, dataProtector)
+			if err != nil {
+				return err
+			}
+			if tag.Name, err = dataProtector.Protect(name); err != nil {
+				m.logger.Error("Failed to encrypt tag name", "userId", userId, "tagId", tag.Id, "err", err)
+				return ccc.NewInternalError("failed to encrypt tag name", err)
+			}
+			tag.NameIndex = nameIndex
+			if err := uow.TagRepo().Update(ctx, tag); err != nil {
+				m.logger.Error("Failed to store encrypted tag name", "userId", userId, "tagId", tag.Id, "err", err)
+				return ccc.NewDatabaseError("update tag name", err)
+			}
+			return nil
+		})
+		if err != nil {
+			return report, err
+		}
+
+		report.Encrypted++
+		if name != plainName {
+			m.logger.Info("Renamed tag with a duplicate name", "userId", userId, "tagId", tag.Id)
+			report.Renamed = append(report.Renamed, RenamedTag{OriginalName: plainName, Name: name})
+		}
+	}
+
+	if report.Encrypted > 0 {
+		m.logger.Info("Tag names encrypted", "userId", userId, "count", report.Encrypted, "renamedCount", len(report.Renamed))
+	}
+	return report, nil
+}
+
+// freeTagName returns the plain name of a tag and its blind index, appending a number if the name is taken by another
+// active tag. Tags in the trash keep their name.
+func (m *DefaultTagManager) freeTagName(ctx context.Context, uow DocumentUnitOfWork, tag *Tag, dataProtector dataprotection.DataProtector) (string, string, error) {
+	for attempt := 1; attempt <= maxTagRenameAttempts; attempt++ {
+		candidate := tag.Name
+		if attempt > 1 {
+			candidate = numberedTagName(tag.Name, attempt)
+		}
+
+		nameIndex, err := dataProtector.BlindIndex(candidate)
+		if err != nil {
+			m.logger.Error("Failed to compute tag name index", "userId", tag.UserId, "tagId", tag.Id, "err", err)
+			return "", "", ccc.NewInternalError("failed to compute tag name index", err)
+		}
+		if tag.DeletedAt != nil {
+			return candidate, nameIndex, nil
+		}
+
+		existing, err := uow.TagRepo().FindByNameIndexForUser(ctx, tag.UserId, nameIndex)
+		if err != nil {
+			m.logger.Error("Failed to check for existing tag name", "userId", tag.UserId, "tagId", tag.Id, "err", err)
+			return "", "", ccc.NewDatabaseError("check for existing tag", err)
+		}
+		if existing == nil {
+			return candidate, nameIndex, nil
+		}
+	}
+	m.logger.Error("No free name found for tag", "userId", tag.UserId, "tagId", tag.Id)
+	return "", "", ccc.NewInternalError("no free name found for tag "+tag.Id, nil)
+}
+
+// numberedTagName appends a number to a tag name, e.g. "Bills (2)", shortening the name to keep it within the length limit
+func numberedTagName(name string, number int) string {
+	suffix := fmt.Sprintf(" (%d)", number)
+	for len(name)+len(suffix) > maxTagNameLength {
+		runes := []rune(name)
+		name = string(runes[:len(runes)-1])
+	}
+	return strings.TrimSpace(name) + suffix
+}
+
+// tagName returns the plain name of a tag. Tags without a name index were stored before tag names were encrypted.
+func (m *DefaultTagManager) tagName(tag *Tag, dataProtector dataprotection.DataProtector) (string, error) {
+	if tag.NameIndex == "" {
+		return tag.Name, nil
+	}
+	name, err := dataProtector.Unprotect(tag.Name)
+	if err != nil {
+		m.logger.Error("Failed to decrypt tag name", "userId", tag.UserId, "tagId", tag.Id, "err", err)
+		return "", ccc.NewInternalError("failed to decrypt tag name", err)
+	}
+	return name, nil
+}
+
+// protectTagName encrypts a tag name and computes its blind index
+func (m *DefaultTagManager) protectTagName(userId, name string, dataProtector dataprotection.DataProtector) (string, string, error) {
+	encryptedName, err := dataProtector.Protect(name)
+	if err != nil {
+		m.logger.Error("Failed to encrypt tag name", "userId", userId, "err", err)
+		return "", "", ccc.NewInternalError("failed to encrypt tag name", err)
+	}
+	nameIndex, err := dataProtector.BlindIndex(name)
+	if err != nil {
+		m.logger.Error("Failed to compute tag name index", "userId", userId, "err", err)
+		return "", "", ccc.NewInternalError("failed to compute tag name index", err)
+	}
+	return encryptedName, nameIndex, nil
+}
+
+// decryptTagNames decrypts the names of tags in place. Tags without a name index were stored before tag names
+// were encrypted and keep their plain name; names that cannot be decrypted are cleared.
+func decryptTagNames(tags []*Tag, dataProtector dataprotection.DataProtector, logger ccc.Logger) {
+	for _, tag := range tags {
+		if tag.NameIndex == "" {
+			continue
+		}
+		if decrypted, err := dataProtector.Unprotect(tag.Name); err == nil {
+			tag.Name = decrypted
+		} else {
+			logger.Warn("Failed to decrypt tag name", "tagId", tag.Id, "error", err)
+			tag.Name = ""
+		}
+	}
+}
+
+// sortTagsByName sorts tags by their decrypted names, ignoring case
+func sortTagsByName(tags []*Tag) {
+	sort.SliceStable(tags, func(i, j int) bool {
+		return strings.ToLower(tags[i].Name) < strings.ToLower(tags[j].Name)
+	})
+}
+
+// purgeTag deletes a tag and all its document-tag relations within the given unit of work
+func purgeTag(ctx context.Context, uow DocumentUnitOfWork, tagId string) error {
+	if err := uow.DocumentTagRepo().RemoveAllTagDocuments(ctx, tagId); err != nil {
+		return ccc.NewDatabaseError("remove document tags for tag purge", err)
+	}
+	if err := uow.TagRepo().Delete(ctx, tagId); err != nil {
+		return ccc.NewDatabaseError("delete tag", err)
+	}
 	return nil
 }
@@ -0,0 +1,89 @@
+package documents
+
+import (
+	"context"
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func TestTagNamesAreEncrypted(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	defer db.Close()
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	encryptionService := encryption.NewDefaultEncryptionService()
+	mek, _ := encryptionService.GenerateKey()
+	dataProtector := dataprotection.NewKeyDataProtector(encryptionService, mek)
+
+	uowFactory := NewDocumentUnitOfWorkFactory(db, nil)
+	manager := NewDefaultTagManager(uowFactory, ccc.NewUuidGenerator(), nil)
+
+	bills, err := manager.CreateTag(ctx, "user-1", CreateTagRequest{Name: "Bills", Color: "#225566"}, dataProtector)
+	if err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+	stored, _ := uowFactory.Create().TagRepo().FindById(ctx, bills.Id)
+	if stored.Name == "Bills" || stored.NameIndex == "" {
+		t.Fatalf("expected an encrypted name with an index, got %q", stored.Name)
+	}
+	if _, err := manager.CreateTag(ctx, "user-1", CreateTagRequest{Name: "Bills", Color: "#225566"}, dataProtector); err == nil {
+		t.Fatalf("expected a duplicate name to be rejected")
+	}
+	if err := manager.UpdateTag(ctx, "user-1", bills.Id, UpdateTagRequest{Color: "#ffffff"}, dataProtector); err != nil {
+		t.Fatalf("failed to update tag color: %v", err)
+	}
+
+	// Tags stored before tag names were encrypted keep their plain name until their owner signs in
+	now := time.Now()
+	for _, tag := range []*Tag{
+		{Id: "legacy-1", UserId: "user-1", Name: "Bills", Color: "#000000", CreatedAt: now, ModifiedAt: now},
+		{Id: "legacy-2", UserId: "user-1", Name: "Archive", Color: "#000000", CreatedAt: now, ModifiedAt: now},
+	} {
+		if err := uowFactory.Create().TagRepo().Add(ctx, tag); err != nil {
+			t.Fatalf("failed to add legacy tag: %v", err)
+		}
+	}
+
+	report, err := manager.EncryptTagNames(ctx, "user-1", dataProtector)
+	if err != nil {
+		t.Fatalf("failed to encrypt tag names: %v", err)
+	}
+	if report.Encrypted != 2 || len(report.Renamed) != 1 || report.Renamed[0] != (RenamedTag{OriginalName: "Bills", Name: "Bills (2)"}) {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+
+	tags, err := manager.GetUserTags(ctx, "user-1", dataProtector)
+	if err != nil {
+		t.Fatalf("failed to get tags: %v", err)
+	}
+	var names []string
+	for _, tag := range tags {
+		names = append(names, tag.Name)
+	}
+	if len(names) != 3 || names[0] != "Archive" || names[1] != "Bills" || names[2] != "Bills (2)" {
+		t.Fatalf("unexpected tag names: %v", names)
+	}
+	if report, _ := manager.EncryptTagNames(ctx, "user-1", dataProtector); report.Encrypted != 0 {
+		t.Fatalf("expected all tag names to be encrypted already, got %+v", report)
+	}
+}
+
+func TestNumberedTagName(t *testing.T) {
+	if name := numberedTagName("Tax returns 2024 all", 2); name != "Tax returns 2024 (2)" || len(name) > maxTagNameLength {
+		t.Fatalf("unexpected name %q", name)
+	}
+}
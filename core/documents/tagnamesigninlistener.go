@@ -0,0 +1,46 @@
+package documents
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+// TagNameSignInListener encrypts the names of tags stored before tag names were encrypted when their owner signs in,
+// since the MEK of the user is only available then. It implements auth.SignInListener.
+type TagNameSignInListener struct {
+	tagManager        TagManager
+	encryptionService encryption.EncryptionService
+	logger            ccc.Logger
+}
+
+// NewTagNameSignInListener creates a new TagNameSignInListener
+func NewTagNameSignInListener(tagManager TagManager, encryptionService encryption.EncryptionService, logger ccc.Logger) *TagNameSignInListener {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &TagNameSignInListener{
+		tagManager:        tagManager,
+		encryptionService: encryptionService,
+		logger:            logger,
+	}
+}
+
+// UserSignedIn encrypts the tag names of the user and returns notices about renamed tags. Failures do not prevent
+// the sign-in, the remaining tags are encrypted at the next sign-in.
+func (l *TagNameSignInListener) UserSignedIn(userId string, mek string) []string {
+	report, err := l.tagManager.EncryptTagNames(context.Background(), userId, dataprotection.NewKeyDataProtector(l.encryptionService, mek))
+	if err != nil {
+		l.logger.Error("Failed to encrypt tag names at sign-in", "userId", userId, "err", err)
+		return nil
+	}
+
+	var notices []string
+	for _, renamed := range report.Renamed {
+		notices = append(notices, fmt.Sprintf("The tag \"%s\" had the same name as another tag and was renamed to \"%s\".", renamed.OriginalName, renamed.Name))
+	}
+	return notices
+}
@@ -0,0 +1,76 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+)
+
+// TextFileProcessor handles plain text and Markdown files. Their content is the searchable text as is.
+type TextFileProcessor struct {
+	// No dependencies needed for this simple implementation
+}
+
+// NewTextFileProcessor creates a new TextFileProcessor
+func NewTextFileProcessor() *TextFileProcessor {
+	return &TextFileProcessor{}
+}
+
+// SupportsContentType checks if this processor can handle text content types
+func (p *TextFileProcessor) SupportsContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	switch contentType {
+	case "text/plain", "text/markdown", "text/x-markdown":
+		return true
+	default:
+		return false
+	}
+}
+
+// ExtractText returns the content of the file as UTF-8
+func (p *TextFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
+	text, err = decodeText(fileData)
+	if err != nil {
+		return "", 0.0, 1, err
+	}
+	// The text is read directly, so there is no uncertainty like with OCR
+	return text, 1.0, 1, nil
+}
+
+// GeneratePreview renders the beginning of the text as a page thumbnail
+func (p *TextFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	text, err := decodeText(fileData)
+	if err != nil {
+		return nil, err
+	}
+	return renderTextPreview(text)
+}
+
+// decodeText converts text files to UTF-8. Files with a UTF-16 byte order mark are decoded as UTF-16,
+// other files that are not valid UTF-8 are assumed to be Windows-1252, a superset of ISO 8859-1.
+func decodeText(data []byte) (string, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xEF, 0xBB, 0xBF}):
+		data = data[3:]
+	case bytes.HasPrefix(data, []byte{0xFF, 0xFE}), bytes.HasPrefix(data, []byte{0xFE, 0xFF}):
+		decoded, err := unicode.UTF16(unicode.BigEndian, unicode.ExpectBOM).NewDecoder().Bytes(data)
+		if err != nil {
+			return "", fmt.Errorf("failed to decode UTF-16 text: %w", err)
+		}
+		return string(decoded), nil
+	}
+
+	if utf8.Valid(data) {
+		return string(data), nil
+	}
+	decoded, err := charmap.Windows1252.NewDecoder().Bytes(data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode text: %w", err)
+	}
+	return string(decoded), nil
+}
@@ -0,0 +1,102 @@
+package documents
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// Text previews look like the top of a printed page
+const (
+	textPreviewWidth      = 256
+	textPreviewHeight     = 331 // Aspect ratio of A4 and letter paper
+	textPreviewMargin     = 12
+	textPreviewLineHeight = 15
+)
+
+// renderTextPreview renders the beginning of a text onto a page shaped PNG image.
+// It returns nil if the text is blank.
+func renderTextPreview(text string) (*PreviewGenerationResult, error) {
+	face := basicfont.Face7x13
+	maxColumns := (textPreviewWidth - 2*textPreviewMargin) / face.Advance
+	maxLines := (textPreviewHeight - 2*textPreviewMargin) / textPreviewLineHeight
+
+	lines := wrapPreviewText(text, maxColumns, maxLines)
+	if len(lines) == 0 {
+		return nil, nil
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, textPreviewWidth, textPreviewHeight))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	drawer := &font.Drawer{
+		Dst:  img,
+		Src:  image.NewUniform(color.RGBA{R: 0x33, G: 0x33, B: 0x33, A: 0xff}),
+		Face: face,
+	}
+	for i, line := range lines {
+		drawer.Dot = fixed.P(textPreviewMargin, textPreviewMargin+face.Ascent+i*textPreviewLineHeight)
+		drawer.DrawString(line)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode preview image: %w", err)
+	}
+	return &PreviewGenerationResult{
+		PreviewData: buf.Bytes(),
+		PreviewType: "image/png",
+		Width:       textPreviewWidth,
+		Height:      textPreviewHeight,
+	}, nil
+}
+
+// wrapPreviewText splits a text into at most maxLines lines of at most maxColumns characters.
+// Leading blank lines are dropped.
+func wrapPreviewText(text string, maxColumns int, maxLines int) []string {
+	text = strings.TrimLeftFunc(text, unicode.IsSpace)
+	if text == "" {
+		return nil
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	text = strings.ReplaceAll(text, "\t", "    ")
+
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		runes := []rune(strings.Map(func(r rune) rune {
+			if unicode.IsControl(r) {
+				return -1
+			}
+			return r
+		}, strings.TrimRightFunc(paragraph, unicode.IsSpace)))
+
+		for {
+			if len(lines) >= maxLines {
+				return lines
+			}
+			if len(runes) <= maxColumns {
+				lines = append(lines, string(runes))
+				break
+			}
+			// Break at the last space that fits, or within the word if there is none
+			cut := maxColumns
+			for i := maxColumns; i > maxColumns/2; i-- {
+				if runes[i] == ' ' {
+					cut = i
+					break
+				}
+			}
+			lines = append(lines, string(runes[:cut]))
+			runes = []rune(strings.TrimLeft(string(runes[cut:]), " "))
+		}
+	}
+	return lines
+}
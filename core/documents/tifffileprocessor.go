@@ -0,0 +1,139 @@
+package documents
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image/png"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"golang.org/x/image/tiff"
+)
+
+// maxTIFFPages limits the number of pages read from a TIFF file
+const maxTIFFPages = 10000
+
+// TIFFFileProcessor handles TIFF files with one or more pages, like faxes and scans. Every page is
+// sent through OCR on its own, the first page becomes the preview.
+type TIFFFileProcessor struct {
+	imageProcessor *ImageFileProcessor
+	maxOCRPages    int
+	logger         ccc.Logger
+}
+
+// NewTIFFFileProcessor creates a new TIFFFileProcessor that sends at most maxOCRPages pages through OCR
+func NewTIFFFileProcessor(imageProcessor *ImageFileProcessor, maxOCRPages int, logger ccc.Logger) *TIFFFileProcessor {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &TIFFFileProcessor{
+		imageProcessor: imageProcessor,
+		maxOCRPages:    max(maxOCRPages, 0),
+		logger:         logger,
+	}
+}
+
+// SupportsContentType checks if this processor can handle TIFF content types
+func (p *TIFFFileProcessor) SupportsContentType(contentType string) bool {
+	contentType = strings.ToLower(contentType)
+	return contentType == "image/tiff" || contentType == "image/tif"
+}
+
+// ExtractText sends the pages of a TIFF file through OCR one by one. The texts of the pages are separated by blank lines.
+func (p *TIFFFileProcessor) ExtractText(ctx context.Context, fileData []byte) (text string, confidence float32, pageCount int, err error) {
+	pageOffsets, err := tiffPageOffsets(fileData)
+	if err != nil {
+		return "", 0.0, 0, err
+	}
+	pageCount = len(pageOffsets)
+
+	// The decoder reads the first page only, so a copy of the file is pointed at one page after another
+	pageFile := bytes.Clone(fileData)
+	byteOrder := tiffByteOrder(fileData)
+
+	var pageTexts []string
+	var confidenceSum float32
+	for i, offset := range pageOffsets {
+		if i >= p.maxOCRPages {
+			p.logger.Info("Some TIFF pages were not sent through OCR", "pages", pageCount, "max_ocr_pages", p.maxOCRPages)
+			break
+		}
+		if err := ctx.Err(); err != nil {
+			return "", 0.0, pageCount, err
+		}
+
+		byteOrder.PutUint32(pageFile[4:8], offset)
+		img, err := tiff.Decode(bytes.NewReader(pageFile))
+		if err != nil {
+			p.logger.Warn("Failed to decode TIFF page, skipping it", "page", i+1, "error", err)
+			continue
+		}
+		var pageImage bytes.Buffer
+		if err := png.Encode(&pageImage, img); err != nil {
+			return "", 0.0, pageCount, fmt.Errorf("failed to convert TIFF page %d: %w", i+1, err)
+		}
+
+		pageText, pageConfidence, _, err := p.imageProcessor.ExtractText(ctx, pageImage.Bytes())
+		if err != nil {
+			if errors.Is(err, ErrOCRSkipped) {
+				return "", 0.0, pageCount, err
+			}
+			return "", 0.0, pageCount, fmt.Errorf("OCR of TIFF page %d failed: %w", i+1, err)
+		}
+		confidenceSum += pageConfidence
+		if strings.TrimSpace(pageText) != "" {
+			pageTexts = append(pageTexts, strings.TrimSpace(pageText))
+		}
+	}
+
+	// Pages that were not sent through OCR count with a confidence of 0
+	confidence = confidenceSum / float32(pageCount)
+	return strings.Join(pageTexts, "\n\n"), confidence, pageCount, nil
+}
+
+// GeneratePreview creates a thumbnail of the first page
+func (p *TIFFFileProcessor) GeneratePreview(ctx context.Context, fileData []byte) (*PreviewGenerationResult, error) {
+	return p.imageProcessor.GeneratePreview(ctx, fileData)
+}
+
+// tiffByteOrder returns the byte order declared in the header of a TIFF file
+func tiffByteOrder(fileData []byte) binary.ByteOrder {
+	if len(fileData) >= 2 && fileData[0] == 'M' && fileData[1] == 'M' {
+		return binary.BigEndian
+	}
+	return binary.LittleEndian
+}
+
+// tiffPageOffsets walks the chain of image file directories of a TIFF file and returns their offsets,
+// one per page
+func tiffPageOffsets(fileData []byte) ([]uint32, error) {
+	if len(fileData) < 8 || !(bytes.HasPrefix(fileData, []byte("II*\x00")) || bytes.HasPrefix(fileData, []byte("MM\x00*"))) {
+		return nil, fmt.Errorf("not a TIFF file")
+	}
+	byteOrder := tiffByteOrder(fileData)
+
+	var offsets []uint32
+	visited := make(map[uint32]bool)
+	offset := byteOrder.Uint32(fileData[4:8])
+	for offset != 0 && !visited[offset] && len(offsets) < maxTIFFPages {
+		// Every directory has a 2 byte entry count, 12 bytes per entry and a 4 byte offset of the next directory
+		if uint64(offset)+2 > uint64(len(fileData)) {
+			return nil, fmt.Errorf("invalid TIFF page offset %d", offset)
+		}
+		entries := uint64(byteOrder.Uint16(fileData[offset : offset+2]))
+		next := uint64(offset) + 2 + entries*12
+		if next+4 > uint64(len(fileData)) {
+			return nil, fmt.Errorf("truncated TIFF page directory at offset %d", offset)
+		}
+		offsets = append(offsets, offset)
+		visited[offset] = true
+		offset = byteOrder.Uint32(fileData[next : next+4])
+	}
+	if len(offsets) == 0 {
+		return nil, fmt.Errorf("TIFF file has no pages")
+	}
+	return offsets, nil
+}
@@ -0,0 +1,242 @@
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Document files, encrypted backups and vault archives share one chunked format: a header specific
+// to the kind of data, followed by the content split into chunks, each sealed with AES-256-GCM.
+// The nonce of a chunk consists of a random prefix, the chunk counter and a flag marking the final
+// chunk, and the header is authenticated as additional data of every chunk. Reordered, truncated or
+// extended data and modified headers are therefore detected. The formats only differ in their header
+// and in how they derive the key.
+const (
+	// ChunkNoncePrefixLen is the length of the random nonce prefix stored in the header
+	ChunkNoncePrefixLen = 7
+	// DefaultChunkSize is the number of plain bytes per chunk used for new data
+	DefaultChunkSize = 64 * 1024
+
+	// Bounds of the chunk size, which keep a forged header from making us allocate huge buffers
+	minChunkSize = 1024
+	maxChunkSize = 16 * 1024 * 1024
+)
+
+var (
+	// ErrStreamDecryptionFailed is returned if a chunk cannot be authenticated
+	ErrStreamDecryptionFailed = errors.New("failed to decrypt stream: wrong key, or the data is damaged")
+	// ErrStreamTruncated is returned if the data ends before its final chunk
+	ErrStreamTruncated = errors.New("encrypted stream is incomplete")
+)
+
+// ChunkedStream holds the parameters of data sealed in chunks, which formats store in their header
+type ChunkedStream struct {
+	// Header is the complete marshalled header of the format, authenticated with every chunk
+	Header []byte
+	// NoncePrefix is the random prefix of the chunk nonces
+	NoncePrefix []byte
+	// ChunkSize is the number of plain bytes of every chunk but the final one
+	ChunkSize uint32
+}
+
+// NewChunkNoncePrefix generates the random nonce prefix of new data
+func NewChunkNoncePrefix() ([]byte, error) {
+	prefix := make([]byte, ChunkNoncePrefixLen)
+	if _, err := rand.Read(prefix); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce prefix: %w", err)
+	}
+	return prefix, nil
+}
+
+// NewChunkAead creates the AES-GCM cipher sealing the chunks with the given 32-byte key
+func NewChunkAead(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *ChunkedStream) validate() error {
+	if len(s.NoncePrefix) != ChunkNoncePrefixLen {
+		return fmt.Errorf("invalid nonce prefix length: %d", len(s.NoncePrefix))
+	}
+	if s.ChunkSize < minChunkSize || s.ChunkSize > maxChunkSize {
+		return fmt.Errorf("invalid chunk size: %d", s.ChunkSize)
+	}
+	return nil
+}
+
+// chunkNonce returns the nonce of the chunk with the given counter
+func (s *ChunkedStream) chunkNonce(counter uint32, last bool) []byte {
+	nonce := make([]byte, 0, 12)
+	nonce = append(nonce, s.NoncePrefix...)
+	nonce = binary.BigEndian.AppendUint32(nonce, counter)
+	if last {
+		return append(nonce, 1)
+	}
+	return append(nonce, 0)
+}
+
+// chunkWriter encrypts everything written to it into dst. The final chunk is only
+// written by Close, so data whose writing failed half way is rejected when it is read.
+type chunkWriter struct {
+	dst     io.Writer
+	stream  ChunkedStream
+	aead    cipher.AEAD
+	buf     []byte
+	sealed  []byte
+	counter uint32
+	closed  bool
+}
+
+// NewChunkWriter writes the header of the stream to dst and returns a writer for its content.
+// Close has to be called to write the final chunk; it does not close dst.
+func NewChunkWriter(dst io.Writer, aead cipher.AEAD, stream ChunkedStream) (io.WriteCloser, error) {
+	if err := stream.validate(); err != nil {
+		return nil, err
+	}
+	if _, err := dst.Write(stream.Header); err != nil {
+		return nil, fmt.Errorf("failed to write header: %w", err)
+	}
+
+	return &chunkWriter{
+		dst:    dst,
+		stream: stream,
+		aead:   aead,
+		buf:    make([]byte, 0, stream.ChunkSize),
+		sealed: make([]byte, 0, int(stream.ChunkSize)+aead.Overhead()),
+	}, nil
+}
+
+func (w *chunkWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errors.New("write to closed stream")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		// A full chunk is only sealed once more data follows, since the last one has to be flagged
+		if len(w.buf) == cap(w.buf) {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):cap(w.buf)], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close writes the final chunk. It does not close dst.
+func (w *chunkWriter) Close() error {
+	if w.closed {
+		return nil
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *chunkWriter) seal(last bool) error {
+	if w.counter == ^uint32(0) && !last {
+		return errors.New("stream is too large")
+	}
+	w.sealed = w.aead.Seal(w.sealed[:0], w.stream.chunkNonce(w.counter, last), w.buf, w.stream.Header)
+	if _, err := w.dst.Write(w.sealed); err != nil {
+		return fmt.Errorf("failed to write encrypted stream: %w", err)
+	}
+	w.counter++
+	w.buf = w.buf[:0]
+	return nil
+}
+
+// chunkReader verifies and decrypts data chunk by chunk. It returns io.EOF only after
+// the final chunk was authenticated and no data follows it.
+type chunkReader struct {
+	src     io.Reader
+	stream  ChunkedStream
+	aead    cipher.AEAD
+	sealed  []byte
+	plain   []byte
+	buf     []byte
+	counter uint32
+	done    bool
+	err     error
+}
+
+// NewChunkReader returns a reader for the content of a stream whose header was already read from src.
+// Chunks that cannot be authenticated yield ErrStreamDecryptionFailed, a missing final chunk ErrStreamTruncated.
+func NewChunkReader(src io.Reader, aead cipher.AEAD, stream ChunkedStream) (io.Reader, error) {
+	if err := stream.validate(); err != nil {
+		return nil, err
+	}
+
+	return &chunkReader{
+		src:    src,
+		stream: stream,
+		aead:   aead,
+		sealed: make([]byte, int(stream.ChunkSize)+aead.Overhead()),
+		buf:    make([]byte, 0, stream.ChunkSize),
+	}, nil
+}
+
+func (r *chunkReader) Read(p []byte) (int, error) {
+	for len(r.plain) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		if r.done {
+			return 0, io.EOF
+		}
+		r.err = r.readChunk()
+	}
+
+	n := copy(p, r.plain)
+	r.plain = r.plain[n:]
+	return n, nil
+}
+
+func (r *chunkReader) readChunk() error {
+	n, err := io.ReadFull(r.src, r.sealed)
+	switch {
+	case errors.Is(err, io.EOF):
+		return ErrStreamTruncated
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		// Only the final chunk may be shorter than the chunk size
+		return r.open(r.sealed[:n], true)
+	case err != nil:
+		return fmt.Errorf("failed to read encrypted stream: %w", err)
+	}
+
+	// A full chunk is the final one if the content size is a multiple of the chunk size
+	if err := r.open(r.sealed, false); err == nil {
+		return nil
+	}
+	if err := r.open(r.sealed, true); err != nil {
+		return err
+	}
+
+	var trailing [1]byte
+	if n, _ := io.ReadFull(r.src, trailing[:]); n > 0 {
+		return errors.New("encrypted stream contains data after its end")
+	}
+	return nil
+}
+
+func (r *chunkReader) open(chunk []byte, last bool) error {
+	plain, err := r.aead.Open(r.buf[:0], r.stream.chunkNonce(r.counter, last), chunk, r.stream.Header)
+	if err != nil {
+		return ErrStreamDecryptionFailed
+	}
+	r.plain = plain
+	r.counter++
+	r.done = last
+	return nil
+}
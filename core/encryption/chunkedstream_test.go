@@ -0,0 +1,95 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestChunkedStream(t *testing.T) {
+	key := make([]byte, keyLength)
+	rand.Read(key)
+	aead, _ := NewChunkAead(key)
+	noncePrefix, _ := NewChunkNoncePrefix()
+	stream := ChunkedStream{Header: []byte("HEADER"), NoncePrefix: noncePrefix, ChunkSize: DefaultChunkSize}
+
+	encrypt := func(plain []byte) []byte {
+		var encrypted bytes.Buffer
+		writer, err := NewChunkWriter(&encrypted, aead, stream)
+		if err != nil {
+			t.Fatalf("NewChunkWriter failed: %v", err)
+		}
+		// Write in odd pieces to cross chunk boundaries
+		for data := plain; len(data) > 0; {
+			n := min(len(data), 10000)
+			if _, err := writer.Write(data[:n]); err != nil {
+				t.Fatalf("Write failed: %v", err)
+			}
+			data = data[n:]
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Close failed: %v", err)
+		}
+		return encrypted.Bytes()
+	}
+	decrypt := func(encrypted []byte, header []byte) ([]byte, error) {
+		src := bytes.NewReader(encrypted)
+		src.Seek(int64(len(stream.Header)), io.SeekStart)
+		reader, err := NewChunkReader(src, aead, ChunkedStream{Header: header, NoncePrefix: stream.NoncePrefix, ChunkSize: stream.ChunkSize})
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}
+
+	for _, size := range []int{0, 1, DefaultChunkSize, DefaultChunkSize + 1, 3*DefaultChunkSize - 7} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+
+		decrypted, err := decrypt(encrypt(plain), stream.Header)
+		if err != nil {
+			t.Fatalf("size %d: decryption failed: %v", size, err)
+		}
+		if !bytes.Equal(decrypted, plain) {
+			t.Fatalf("size %d: decrypted data differs", size)
+		}
+	}
+
+	plain := make([]byte, 2*DefaultChunkSize+100)
+	rand.Read(plain)
+	encrypted := encrypt(plain)
+	sealedChunkSize := DefaultChunkSize + aead.Overhead()
+
+	if _, err := decrypt(encrypted, []byte("HEADEX")); !errors.Is(err, ErrStreamDecryptionFailed) {
+		t.Errorf("expected a modified header to be detected, got %v", err)
+	}
+
+	modified := bytes.Clone(encrypted)
+	modified[len(stream.Header)+sealedChunkSize+40] ^= 1
+	if _, err := decrypt(modified, stream.Header); !errors.Is(err, ErrStreamDecryptionFailed) {
+		t.Errorf("expected a modified chunk to be detected, got %v", err)
+	}
+
+	reordered := bytes.Clone(encrypted)
+	first := len(stream.Header)
+	copy(reordered[first:], encrypted[first+sealedChunkSize:first+2*sealedChunkSize])
+	copy(reordered[first+sealedChunkSize:], encrypted[first:first+sealedChunkSize])
+	if _, err := decrypt(reordered, stream.Header); !errors.Is(err, ErrStreamDecryptionFailed) {
+		t.Errorf("expected reordered chunks to be detected, got %v", err)
+	}
+
+	// Dropping the final chunk leaves whole chunks, which must not pass as complete
+	if _, err := decrypt(encrypted[:first+2*sealedChunkSize], stream.Header); !errors.Is(err, ErrStreamTruncated) {
+		t.Errorf("expected truncated data to be detected, got %v", err)
+	}
+
+	if _, err := decrypt(append(bytes.Clone(encrypted), 0), stream.Header); err == nil {
+		t.Errorf("expected trailing data to be detected")
+	}
+
+	if _, err := NewChunkReader(bytes.NewReader(nil), aead, ChunkedStream{NoncePrefix: noncePrefix, ChunkSize: 1 << 30}); err == nil {
+		t.Errorf("expected a huge chunk size to be rejected")
+	}
+}
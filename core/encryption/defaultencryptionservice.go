@@ -1,8 +1,11 @@
 package encryption
 
 import (
+	"bufio"
+	"bytes"
 	"crypto/aes"
 	"crypto/cipher"
+	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
 	"crypto/subtle"
@@ -18,6 +21,9 @@ import (
 const (
 	iterationCount = 10000 // PBKDF2 iterations
 	keyLength      = 32    // 256 bits for AES-256
+
+	// blindIndexContext separates the blind index key from the encryption key derived from the same MEK
+	blindIndexContext = "frozenfortress/blind-index/v1"
 )
 
 // DefaultEncryptionService provides encryption, decryption, and hashing capabilities
@@ -203,6 +209,52 @@ func (s *DefaultEncryptionService) DecryptBytes(cipherData []byte, key string) (
 	return decrypted, nil
 }
 
+// EncryptStream returns a writer that encrypts everything written to it into dst in authenticated chunks
+func (s *DefaultEncryptionService) EncryptStream(dst io.Writer, key string) (plainWriter io.WriteCloser, err error) {
+	return NewEncryptingWriter(dst, key)
+}
+
+// DecryptStream returns a reader that decrypts a stream written by EncryptStream.
+// Data encrypted by Encrypt, as document files were before streams were introduced, is decrypted in memory.
+func (s *DefaultEncryptionService) DecryptStream(src io.Reader, key string) (plainReader io.Reader, err error) {
+	buffered := bufio.NewReader(src)
+	prefix, _ := buffered.Peek(len(streamMagic))
+	if IsEncryptedStream(prefix) {
+		return NewDecryptingReader(buffered, key)
+	}
+
+	cipherText, err := io.ReadAll(buffered)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read cipher text: %w", err)
+	}
+	plainText, err := s.Decrypt(string(cipherText), key)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.NewReader([]byte(plainText)), nil
+}
+
+// BlindIndex computes a deterministic keyed hash (HMAC-SHA256) of the input.
+// The HMAC key is derived from the provided key, so the same input yields the same
+// index for the same key while revealing nothing about the plaintext to anyone without it.
+// This allows exact-match lookups on values that are otherwise stored with randomized encryption.
+func (s *DefaultEncryptionService) BlindIndex(input string, key string) (index string, err error) {
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil || len(keyBytes) != keyLength {
+		return "", errors.New("invalid encryption key")
+	}
+
+	// Derive a dedicated index key so the MEK itself is never used directly as an HMAC key
+	keyMac := hmac.New(sha256.New, keyBytes)
+	keyMac.Write([]byte(blindIndexContext))
+	indexKey := keyMac.Sum(nil)
+
+	mac := hmac.New(sha256.New, indexKey)
+	mac.Write([]byte(input))
+
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
 // GenerateKey generates a new random encryption key
 func (s *DefaultEncryptionService) GenerateKey() (key string, err error) {
 
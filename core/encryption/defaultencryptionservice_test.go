@@ -0,0 +1,33 @@
+package encryption
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestBlindIndex(t *testing.T) {
+	service := NewDefaultEncryptionService()
+	key, _ := service.GenerateKey()
+	otherKey, _ := service.GenerateKey()
+
+	index, err := service.BlindIndex("GitHub", key)
+	if err != nil {
+		t.Fatalf("BlindIndex failed: %v", err)
+	}
+	if again, _ := service.BlindIndex("GitHub", key); again != index {
+		t.Errorf("expected the same index for the same input and key")
+	}
+	if other, _ := service.BlindIndex("Github", key); other == index {
+		t.Errorf("expected another index for another input")
+	}
+	if other, _ := service.BlindIndex("GitHub", otherKey); other == index {
+		t.Errorf("expected another index for another key")
+	}
+	if len(index) != 64 || strings.Contains(index, "GitHub") {
+		t.Errorf("unexpected index %q", index)
+	}
+
+	if _, err := service.BlindIndex("GitHub", "not a key"); err == nil {
+		t.Errorf("expected an invalid key to be rejected")
+	}
+}
@@ -1,5 +1,7 @@
 package encryption
 
+import "io"
+
 type Hasher interface {
 	Hash(input string) (output string, salt string, err error)
 	VerifyHash(input string, hash string, salt string) (isValid bool, err error)
@@ -11,6 +13,13 @@ type EncryptionService interface {
 	Decrypt(cipherText string, key string) (plainText string, err error)
 	EncryptBytes(plainData []byte, key string) (cipherData []byte, err error)
 	DecryptBytes(cipherData []byte, key string) (plainData []byte, err error)
+	// EncryptStream returns a writer encrypting everything written to it into dst in authenticated chunks.
+	// The writer has to be closed to complete the stream.
+	EncryptStream(dst io.Writer, key string) (plainWriter io.WriteCloser, err error)
+	// DecryptStream returns a reader decrypting a stream written by EncryptStream.
+	// Data encrypted by Encrypt is accepted as well, but decrypted in memory.
+	DecryptStream(src io.Reader, key string) (plainReader io.Reader, err error)
+	BlindIndex(input string, key string) (index string, err error)
 	GenerateKey() (key string, err error)
 	GenerateKeyFromPassword(password string, salt string) (key string, err error)
 	GenerateSalt() (saltBytes []byte, salt string, err error)
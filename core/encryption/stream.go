@@ -0,0 +1,135 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Streams, such as the contents of document files, use the chunked format with a header of their own.
+// Every stream is sealed with its own key, derived from the given key and the random salt of the header
+// with HKDF-SHA256.
+//
+// Header layout (big endian):
+//
+//	magic "FFSTREAM" (8) | version (1) | salt (16) | nonce prefix (7) | chunk size (4)
+const (
+	streamMagic     = "FFSTREAM"
+	streamVersion   = 1
+	streamHeaderLen = 36
+
+	streamSaltLen = 16
+
+	// streamKeyContext separates the keys of streams from other keys derived from the same key
+	streamKeyContext = "frozenfortress/stream/v1"
+)
+
+// streamHeader holds the parameters stored in the header of an encrypted stream
+type streamHeader struct {
+	salt   []byte
+	chunks ChunkedStream
+}
+
+func (h *streamHeader) marshal() []byte {
+	data := make([]byte, 0, streamHeaderLen)
+	data = append(data, streamMagic...)
+	data = append(data, streamVersion)
+	data = append(data, h.salt...)
+	data = append(data, h.chunks.NoncePrefix...)
+	data = binary.BigEndian.AppendUint32(data, h.chunks.ChunkSize)
+	return data
+}
+
+// IsEncryptedStream reports whether data starts with the header of a stream encrypted by NewEncryptingWriter.
+// Data encrypted by Encrypt is hex encoded and therefore never does.
+func IsEncryptedStream(data []byte) bool {
+	return bytes.HasPrefix(data, []byte(streamMagic))
+}
+
+// readStreamHeader reads and validates the header of an encrypted stream
+func readStreamHeader(src io.Reader) (*streamHeader, error) {
+	data := make([]byte, streamHeaderLen)
+	if _, err := io.ReadFull(src, data); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, ErrStreamTruncated
+		}
+		return nil, fmt.Errorf("failed to read stream header: %w", err)
+	}
+	if !IsEncryptedStream(data) {
+		return nil, errors.New("data is not an encrypted stream")
+	}
+	if data[8] != streamVersion {
+		return nil, fmt.Errorf("unsupported stream version: %d", data[8])
+	}
+
+	return &streamHeader{
+		salt: data[9:25],
+		chunks: ChunkedStream{
+			Header:      data,
+			NoncePrefix: data[25:32],
+			ChunkSize:   binary.BigEndian.Uint32(data[32:36]),
+		},
+	}, nil
+}
+
+// deriveKey derives the key of a stream from the given key and the salt of its header
+func (h *streamHeader) deriveKey(key string) ([]byte, error) {
+	keyBytes, err := hex.DecodeString(key)
+	if err != nil || len(keyBytes) != keyLength {
+		return nil, errors.New("invalid encryption key")
+	}
+	streamKey, err := hkdf.Key(sha256.New, keyBytes, h.salt, streamKeyContext, keyLength)
+	if err != nil {
+		return nil, fmt.Errorf("failed to derive stream key: %w", err)
+	}
+	return streamKey, nil
+}
+
+// NewEncryptingWriter writes the header of a new encrypted stream to dst and returns a writer for its content.
+// Close has to be called to write the final chunk; it does not close dst.
+func NewEncryptingWriter(dst io.Writer, key string) (io.WriteCloser, error) {
+	header := &streamHeader{salt: make([]byte, streamSaltLen)}
+	if _, err := rand.Read(header.salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	noncePrefix, err := NewChunkNoncePrefix()
+	if err != nil {
+		return nil, err
+	}
+	header.chunks = ChunkedStream{NoncePrefix: noncePrefix, ChunkSize: DefaultChunkSize}
+	header.chunks.Header = header.marshal()
+
+	streamKey, err := header.deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := NewChunkAead(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewChunkWriter(dst, aead, header.chunks)
+}
+
+// NewDecryptingReader reads the header of a stream encrypted by NewEncryptingWriter from src
+// and returns a reader for its content
+func NewDecryptingReader(src io.Reader, key string) (io.Reader, error) {
+	header, err := readStreamHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	streamKey, err := header.deriveKey(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := NewChunkAead(streamKey)
+	if err != nil {
+		return nil, err
+	}
+	return NewChunkReader(src, aead, header.chunks)
+}
@@ -0,0 +1,56 @@
+package encryption
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStreamEncryption(t *testing.T) {
+	service := NewDefaultEncryptionService()
+	key, _ := service.GenerateKey()
+	otherKey, _ := service.GenerateKey()
+
+	plain := make([]byte, 2*DefaultChunkSize+100)
+	rand.Read(plain)
+
+	var encrypted bytes.Buffer
+	writer, err := service.EncryptStream(&encrypted, key)
+	if err != nil {
+		t.Fatalf("EncryptStream failed: %v", err)
+	}
+	if _, err := writer.Write(plain); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	decryptStream := func(data []byte, key string) ([]byte, error) {
+		reader, err := service.DecryptStream(bytes.NewReader(data), key)
+		if err != nil {
+			return nil, err
+		}
+		return io.ReadAll(reader)
+	}
+
+	decrypted, err := decryptStream(encrypted.Bytes(), key)
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Fatalf("failed to decrypt stream: %v", err)
+	}
+	if _, err := decryptStream(encrypted.Bytes(), otherKey); !errors.Is(err, ErrStreamDecryptionFailed) {
+		t.Fatalf("expected wrong key to fail, got %v", err)
+	}
+
+	// Contents encrypted before streams were introduced are still readable
+	legacy, err := service.Encrypt(string(plain), key)
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	decrypted, err = decryptStream([]byte(legacy), key)
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Fatalf("failed to decrypt legacy content: %v", err)
+	}
+}
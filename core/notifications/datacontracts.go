@@ -0,0 +1,26 @@
+package notifications
+
+import "time"
+
+// NotificationDto is a notification with the decrypted name of its secret
+type NotificationDto struct {
+	Id         string
+	Kind       NotificationKind
+	SecretId   string
+	SecretName string
+	DueAt      time.Time
+	CreatedAt  time.Time
+	Read       bool
+}
+
+// NotificationSettingsDto holds how a user wants to be notified in addition to the web UI
+type NotificationSettingsDto struct {
+	Email        string
+	EmailEnabled bool
+}
+
+// ReminderSummary counts the notifications written and mailed by a reminder run
+type ReminderSummary struct {
+	Created int
+	Emailed int
+}
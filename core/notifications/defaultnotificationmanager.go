@@ -0,0 +1,289 @@
+package notifications
+
+import (
+	"context"
+	"fmt"
+	"net/mail"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// DefaultNotificationManager implements NotificationManager on top of the secret manager
+type DefaultNotificationManager struct {
+	notificationRepository NotificationRepository
+	settingsRepository     NotificationSettingsRepository
+	secretManager          secrets.SecretManager
+	idGenerator            NotificationIdGenerator
+	mailer                 Mailer
+	leadDays               int
+	logger                 ccc.Logger
+}
+
+// NewDefaultNotificationManager creates a notification manager that reminds users leadDays days before
+// a secret expires or is due for rotation. A nil mailer disables mails.
+func NewDefaultNotificationManager(
+	notificationRepository NotificationRepository,
+	settingsRepository NotificationSettingsRepository,
+	secretManager secrets.SecretManager,
+	idGenerator NotificationIdGenerator,
+	mailer Mailer,
+	leadDays int,
+	logger ccc.Logger,
+) *DefaultNotificationManager {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &DefaultNotificationManager{
+		notificationRepository: notificationRepository,
+		settingsRepository:     settingsRepository,
+		secretManager:          secretManager,
+		idGenerator:            idGenerator,
+		mailer:                 mailer,
+		leadDays:               max(leadDays, 0),
+		logger:                 logger,
+	}
+}
+
+// CreateReminders writes a notification for every expiry and rotation date within the lead time that has none yet.
+// Mails that cannot be sent are logged and not retried; the notification remains visible in the web UI.
+func (m *DefaultNotificationManager) CreateReminders(ctx context.Context, now time.Time) (*ReminderSummary, error) {
+	reminders, err := m.secretManager.GetSecretReminders(now.AddDate(0, 0, m.leadDays))
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &ReminderSummary{}
+	settingsByUser := make(map[string]*NotificationSettings)
+	for _, reminder := range reminders {
+		notification := &Notification{
+			Id:        m.idGenerator.GenerateId(),
+			UserId:    reminder.UserId,
+			Kind:      reminderNotificationKind(reminder, now),
+			SecretId:  reminder.SecretId,
+			DueAt:     reminder.DueAt,
+			CreatedAt: now,
+		}
+
+		added, err := m.notificationRepository.Add(notification)
+		if err != nil {
+			m.logger.Error("Failed to store reminder", "user_id", reminder.UserId, "secret_id", reminder.SecretId, "error", err)
+			return summary, ccc.NewDatabaseError("add notification", err)
+		}
+		if !added {
+			continue
+		}
+		summary.Created++
+
+		if m.mailer == nil {
+			continue
+		}
+		settings, ok := settingsByUser[notification.UserId]
+		if !ok {
+			if settings, err = m.settingsRepository.FindByUserId(notification.UserId); err != nil {
+				m.logger.Error("Failed to load notification settings", "user_id", notification.UserId, "error", err)
+			}
+			settingsByUser[notification.UserId] = settings
+		}
+		if settings == nil || !settings.EmailEnabled || settings.Email == "" {
+			continue
+		}
+
+		if err := m.mailer.Send(ctx, reminderMail(settings.Email, notification)); err != nil {
+			m.logger.Error("Failed to mail reminder", "user_id", notification.UserId, "notification_id", notification.Id, "error", err)
+			continue
+		}
+		if err := m.notificationRepository.MarkEmailed(notification.Id, time.Now()); err != nil {
+			m.logger.Warn("Failed to record mailed reminder", "notification_id", notification.Id, "error", err)
+		}
+		summary.Emailed++
+	}
+
+	return summary, nil
+}
+
+// reminderNotificationKind returns the kind of notification for a reminder. An expiry date is reminded of
+// once ahead of time and once more when it has passed.
+func reminderNotificationKind(reminder *secrets.SecretReminder, now time.Time) NotificationKind {
+	switch {
+	case reminder.Kind == secrets.ReminderRotation:
+		return NotificationSecretRotationDue
+	case reminder.DueAt.After(now):
+		return NotificationSecretExpiring
+	default:
+		return NotificationSecretExpired
+	}
+}
+
+// reminderMail returns the mail for a notification. Secret names are encrypted with the key of the user,
+// so the mail can only tell that one of the secrets needs attention.
+func reminderMail(to string, notification *Notification) MailMessage {
+	dueDate := notification.DueAt.Format("2006-01-02")
+
+	var subject string
+	switch notification.Kind {
+	case NotificationSecretExpiring:
+		subject = "A secret expires on " + dueDate
+	case NotificationSecretExpired:
+		subject = "A secret expired on " + dueDate
+	default:
+		subject = "A secret is due for rotation on " + dueDate
+	}
+
+	body := fmt.Sprintf(`%s.
+
+Sign in to Frozen Fortress to see which secret needs your attention. Secret names are
+encrypted with your password, so they cannot be included in this mail.
+
+You receive this mail because you enabled reminder mails in Frozen Fortress.
+`, subject)
+
+	return MailMessage{To: to, Subject: "Frozen Fortress: " + subject, Body: body}
+}
+
+// GetNotifications returns the notifications of a user with the decrypted names of their secrets
+func (m *DefaultNotificationManager) GetNotifications(ctx context.Context, userId string, unreadOnly bool, dataProtector dataprotection.DataProtector) ([]*NotificationDto, error) {
+	notifications, err := m.notificationRepository.FindByUserId(userId, unreadOnly)
+	if err != nil {
+		m.logger.Error("Failed to find notifications", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find notifications", err)
+	}
+
+	secretsById := make(map[string]*secrets.SecretDto)
+	result := make([]*NotificationDto, 0, len(notifications))
+	for _, notification := range notifications {
+		secret, ok := secretsById[notification.SecretId]
+		if !ok {
+			secret, err = m.secretManager.GetSecret(userId, notification.SecretId, dataProtector)
+			if err != nil && !ccc.IsNotFound(err) {
+				m.logger.Warn("Failed to load secret of notification, skipping", "user_id", userId, "notification_id", notification.Id, "error", err)
+				continue
+			}
+			secretsById[notification.SecretId] = secret
+		}
+
+		if secret == nil || !reminderCurrent(notification, secret) {
+			// The secret has been deleted, rotated or got another expiry date since the reminder was written
+			if err := m.notificationRepository.Remove(notification.Id); err != nil {
+				m.logger.Warn("Failed to remove outdated notification", "notification_id", notification.Id, "error", err)
+			}
+			continue
+		}
+
+		result = append(result, &NotificationDto{
+			Id:         notification.Id,
+			Kind:       notification.Kind,
+			SecretId:   notification.SecretId,
+			SecretName: secret.Name,
+			DueAt:      notification.DueAt,
+			CreatedAt:  notification.CreatedAt,
+			Read:       notification.ReadAt != nil,
+		})
+	}
+
+	return result, nil
+}
+
+// reminderCurrent reports whether the due date of a notification is still the one of its secret
+func reminderCurrent(notification *Notification, secret *secrets.SecretDto) bool {
+	dueAt := secret.ExpiresAt
+	if notification.Kind == NotificationSecretRotationDue {
+		dueAt = secret.RotationDueAt
+	}
+	return dueAt != nil && dueAt.Equal(notification.DueAt)
+}
+
+// MarkRead dismisses a notification of a user
+func (m *DefaultNotificationManager) MarkRead(ctx context.Context, userId string, notificationId string) error {
+	notification, err := m.notificationRepository.FindByIdForUser(userId, notificationId)
+	if err != nil {
+		m.logger.Error("Failed to find notification", "user_id", userId, "notification_id", notificationId, "error", err)
+		return ccc.NewDatabaseError("find notification", err)
+	}
+	if notification == nil {
+		return ccc.NewResourceNotFoundError(notificationId, "Notification")
+	}
+	if err := m.notificationRepository.MarkRead(notificationId, time.Now()); err != nil {
+		m.logger.Error("Failed to mark notification as read", "user_id", userId, "notification_id", notificationId, "error", err)
+		return ccc.NewDatabaseError("mark notification as read", err)
+	}
+	return nil
+}
+
+// MarkAllRead dismisses all notifications of a user
+func (m *DefaultNotificationManager) MarkAllRead(ctx context.Context, userId string) error {
+	if err := m.notificationRepository.MarkAllRead(userId, time.Now()); err != nil {
+		m.logger.Error("Failed to mark notifications as read", "user_id", userId, "error", err)
+		return ccc.NewDatabaseError("mark notifications as read", err)
+	}
+	return nil
+}
+
+// GetSettings returns the notification settings of a user, which are empty until the user saves them
+func (m *DefaultNotificationManager) GetSettings(ctx context.Context, userId string) (*NotificationSettingsDto, error) {
+	settings, err := m.settingsRepository.FindByUserId(userId)
+	if err != nil {
+		m.logger.Error("Failed to find notification settings", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find notification settings", err)
+	}
+	if settings == nil {
+		return &NotificationSettingsDto{}, nil
+	}
+	return &NotificationSettingsDto{Email: settings.Email, EmailEnabled: settings.EmailEnabled}, nil
+}
+
+// SaveSettings validates and stores the notification settings of a user
+func (m *DefaultNotificationManager) SaveSettings(ctx context.Context, userId string, request NotificationSettingsDto) error {
+	email := strings.TrimSpace(request.Email)
+	if email != "" {
+		address, err := mail.ParseAddress(email)
+		if err != nil || address.Address != email {
+			return ccc.NewInvalidInputErrorWithMessage("email", "invalid address", "Please enter a valid email address")
+		}
+	}
+	if request.EmailEnabled && email == "" {
+		return ccc.NewInvalidInputErrorWithMessage("email", "cannot be empty", "Enter an email address to receive reminder mails")
+	}
+
+	settings := &NotificationSettings{UserId: userId, Email: email, EmailEnabled: request.EmailEnabled}
+	if err := m.settingsRepository.Save(settings); err != nil {
+		m.logger.Error("Failed to save notification settings", "user_id", userId, "error", err)
+		return ccc.NewDatabaseError("save notification settings", err)
+	}
+
+	m.logger.Info("Notification settings saved", "user_id", userId, "email_enabled", request.EmailEnabled)
+	return nil
+}
+
+// SendTestEmail sends a mail to the saved address of a user
+func (m *DefaultNotificationManager) SendTestEmail(ctx context.Context, userId string) error {
+	if m.mailer == nil {
+		return ccc.NewInvalidInputErrorWithMessage("smtp", "not configured", "Mails are not configured on this server")
+	}
+	settings, err := m.GetSettings(ctx, userId)
+	if err != nil {
+		return err
+	}
+	if settings.Email == "" {
+		return ccc.NewInvalidInputErrorWithMessage("email", "cannot be empty", "Save an email address first")
+	}
+
+	err = m.mailer.Send(ctx, MailMessage{
+		To:      settings.Email,
+		Subject: "Frozen Fortress: test mail",
+		Body:    "This is a test mail from Frozen Fortress. Reminder mails will be sent to this address.\n",
+	})
+	if err != nil {
+		m.logger.Error("Failed to send test mail", "user_id", userId, "error", err)
+		return ccc.NewOperationFailedError("send test mail", err.Error())
+	}
+	return nil
+}
+
+// EmailAvailable reports whether a mailer is configured
+func (m *DefaultNotificationManager) EmailAvailable() bool {
+	return m.mailer != nil
+}
@@ -0,0 +1,176 @@
+package notifications
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"net"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// startMailCatcher runs a minimal SMTP server like a local mail catcher and returns its port
+// together with a channel receiving the data of every accepted mail
+func startMailCatcher(t *testing.T) (int, <-chan string) {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	mails := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go serveSMTP(conn, mails)
+		}
+	}()
+
+	return listener.Addr().(*net.TCPAddr).Port, mails
+}
+
+func serveSMTP(conn net.Conn, mails chan<- string) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { conn.Write([]byte(line + "\r\n")) }
+
+	reply("220 localhost ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"):
+			reply("250-localhost")
+			reply("250 8BITMIME")
+		case command == "DATA":
+			reply("354 End data with <CR><LF>.<CR><LF>")
+			var data strings.Builder
+			for {
+				line, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if line == ".\r\n" {
+					break
+				}
+				data.WriteString(line)
+			}
+			mails <- data.String()
+			reply("250 OK")
+		case command == "QUIT":
+			reply("221 Bye")
+			return
+		default:
+			reply("250 OK")
+		}
+	}
+}
+
+func TestRemindersAreWrittenOnceAndMailed(t *testing.T) {
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	userRepo, _ := auth.NewSQLiteUserRepository(db)
+	now := time.Now().UTC()
+	if _, err := userRepo.Add(&auth.User{Id: "user-1", UserName: "alice", IsActive: true, CreatedAt: now, ModifiedAt: now}); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	secretRepo, _ := secrets.NewSQLiteSecretRepository(db)
+	secretManager := secrets.NewDefaultSecretManager(secretRepo, ccc.NewUuidGenerator(), userRepo, 3, nil)
+	encryptionService := encryption.NewDefaultEncryptionService()
+	mek, _ := encryptionService.GenerateKey()
+	dataProtector := dataprotection.NewKeyDataProtector(encryptionService, mek)
+
+	port, mails := startMailCatcher(t)
+	mailer := NewSMTPMailer(ccc.SMTPConfig{Host: "127.0.0.1", Port: port, From: "frozenfortress@localhost", Security: "none"}, nil)
+
+	notificationRepo, _ := NewSQLiteNotificationRepository(db)
+	settingsRepo, _ := NewSQLiteNotificationSettingsRepository(db)
+	manager := NewDefaultNotificationManager(notificationRepo, settingsRepo, secretManager, ccc.NewUuidGenerator(), mailer, 14, nil)
+
+	ctx := context.Background()
+	if err := manager.SaveSettings(ctx, "user-1", NotificationSettingsDto{Email: "alice@example.com", EmailEnabled: true}); err != nil {
+		t.Fatalf("SaveSettings failed: %v", err)
+	}
+
+	expiresAt := now.AddDate(0, 0, 3).Truncate(24 * time.Hour)
+	created, err := secretManager.CreateSecret("user-1", secrets.UpsertSecretRequest{SecretName: "Deploy key", SecretValue: "s3cret", ExpiresAt: &expiresAt}, dataProtector)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+	if _, err := secretManager.CreateSecret("user-1", secrets.UpsertSecretRequest{SecretName: "Later", SecretValue: "x", RotateEvery: 90}, dataProtector); err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	summary, err := manager.CreateReminders(ctx, now)
+	if err != nil {
+		t.Fatalf("CreateReminders failed: %v", err)
+	}
+	if summary.Created != 1 || summary.Emailed != 1 {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+
+	select {
+	case mail := <-mails:
+		if !strings.Contains(mail, "To: <alice@example.com>") || !strings.Contains(mail, "A secret expires on "+expiresAt.Format("2006-01-02")) {
+			t.Errorf("unexpected mail:\n%s", mail)
+		}
+		if strings.Contains(mail, "Deploy key") {
+			t.Errorf("the mail must not contain the secret name:\n%s", mail)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail received")
+	}
+
+	// A reminder is only written once for a due date
+	if summary, _ := manager.CreateReminders(ctx, now.Add(time.Hour)); summary.Created != 0 {
+		t.Errorf("expected no new reminders, got %+v", summary)
+	}
+
+	notifications, err := manager.GetNotifications(ctx, "user-1", true, dataProtector)
+	if err != nil {
+		t.Fatalf("GetNotifications failed: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].SecretName != "Deploy key" || notifications[0].Kind != NotificationSecretExpiring {
+		t.Fatalf("unexpected notifications %+v", notifications)
+	}
+
+	// Moving the expiry date makes the reminder outdated
+	renewed := expiresAt.AddDate(1, 0, 0)
+	update := secrets.UpsertSecretRequest{SecretName: "Deploy key", SecretValue: "s3cret", ExpiresAt: &renewed}
+	if _, err := secretManager.UpdateSecret("user-1", created.SecretId, update, dataProtector); err != nil {
+		t.Fatalf("UpdateSecret failed: %v", err)
+	}
+	if notifications, _ := manager.GetNotifications(ctx, "user-1", false, dataProtector); len(notifications) != 0 {
+		t.Errorf("expected the outdated reminder to be removed, got %+v", notifications)
+	}
+
+	if err := manager.SaveSettings(ctx, "user-1", NotificationSettingsDto{Email: "not an address", EmailEnabled: true}); !ccc.IsValidationError(err) {
+		t.Errorf("expected a validation error for an invalid address, got %v", err)
+	}
+}
@@ -0,0 +1,55 @@
+package notifications
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+)
+
+// NotificationIdGenerator generates unique IDs for notifications
+type NotificationIdGenerator interface {
+	GenerateId() string
+}
+
+type NotificationRepository interface {
+	// Add stores a notification unless one for the same secret, kind and due date exists.
+	// It returns false if the notification was not stored for that reason.
+	Add(notification *Notification) (bool, error)
+	FindByIdForUser(userId, notificationId string) (*Notification, error)
+	// FindByUserId returns the notifications of a user, newest first
+	FindByUserId(userId string, unreadOnly bool) ([]*Notification, error)
+	MarkRead(notificationId string, readAt time.Time) error
+	MarkAllRead(userId string, readAt time.Time) error
+	MarkEmailed(notificationId string, emailedAt time.Time) error
+	Remove(notificationId string) error
+}
+
+type NotificationSettingsRepository interface {
+	// FindByUserId returns the settings of a user, nil if the user has not saved any
+	FindByUserId(userId string) (*NotificationSettings, error)
+	Save(settings *NotificationSettings) error
+}
+
+// Mailer sends mails, e.g. through an SMTP relay
+type Mailer interface {
+	Send(ctx context.Context, message MailMessage) error
+}
+
+// NotificationManager writes reminders of expiring secrets and lets users read and dismiss them
+type NotificationManager interface {
+	// CreateReminders writes notifications for the secrets of all users that expire or are due for rotation
+	// within the lead time, and mails new ones to users who enabled it. It runs without the keys of the users.
+	CreateReminders(ctx context.Context, now time.Time) (*ReminderSummary, error)
+	// GetNotifications returns the notifications of a user with the names of their secrets, newest first.
+	// Notifications of secrets that have been deleted, rotated or got another expiry date are removed.
+	GetNotifications(ctx context.Context, userId string, unreadOnly bool, dataProtector dataprotection.DataProtector) ([]*NotificationDto, error)
+	MarkRead(ctx context.Context, userId string, notificationId string) error
+	MarkAllRead(ctx context.Context, userId string) error
+	GetSettings(ctx context.Context, userId string) (*NotificationSettingsDto, error)
+	SaveSettings(ctx context.Context, userId string, request NotificationSettingsDto) error
+	// SendTestEmail sends a mail to the address in the settings of a user, e.g. to check the SMTP relay
+	SendTestEmail(ctx context.Context, userId string) error
+	// EmailAvailable reports whether an SMTP relay is configured
+	EmailAvailable() bool
+}
@@ -0,0 +1,44 @@
+package notifications
+
+import (
+	"time"
+)
+
+// NotificationKind tells what a notification reminds the user of
+type NotificationKind string
+
+const (
+	// NotificationSecretExpiring reminds of a secret that expires within the lead time
+	NotificationSecretExpiring NotificationKind = "secret_expiring"
+	// NotificationSecretExpired reminds of a secret whose expiry date has passed
+	NotificationSecretExpired NotificationKind = "secret_expired"
+	// NotificationSecretRotationDue reminds of a secret whose value should be rotated
+	NotificationSecretRotationDue NotificationKind = "secret_rotation_due"
+)
+
+// Notification is a reminder written for a user by the reminder worker. It refers to the secret by its ID,
+// since the worker cannot decrypt secret names. There is at most one notification per secret, kind and due date.
+type Notification struct {
+	Id        string
+	UserId    string
+	Kind      NotificationKind
+	SecretId  string
+	DueAt     time.Time
+	CreatedAt time.Time
+	ReadAt    *time.Time // When the user dismissed the notification, nil if it is unread
+	EmailedAt *time.Time // When the notification was sent by mail, nil if it was not
+}
+
+// NotificationSettings holds how a user wants to be notified in addition to the web UI
+type NotificationSettings struct {
+	UserId       string
+	Email        string
+	EmailEnabled bool
+}
+
+// MailMessage is a plain text mail
+type MailMessage struct {
+	To      string
+	Subject string
+	Body    string
+}
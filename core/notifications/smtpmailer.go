@@ -0,0 +1,132 @@
+package notifications
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// smtpTimeout limits the time a mail may take from connecting to the relay until it is accepted
+const smtpTimeout = 30 * time.Second
+
+// SMTPMailer sends mails through an SMTP relay. With the security mode none, it also works against
+// local mail catchers like Mailpit or MailHog, which accept plain connections without authentication.
+type SMTPMailer struct {
+	config ccc.SMTPConfig
+	logger ccc.Logger
+}
+
+// NewSMTPMailer creates a mailer for the given relay
+func NewSMTPMailer(config ccc.SMTPConfig, logger ccc.Logger) *SMTPMailer {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &SMTPMailer{config: config, logger: logger}
+}
+
+// Send delivers a plain text mail to the relay
+func (m *SMTPMailer) Send(ctx context.Context, message MailMessage) error {
+	from, err := mail.ParseAddress(m.config.From)
+	if err != nil {
+		return fmt.Errorf("invalid sender address %q: %w", m.config.From, err)
+	}
+	to, err := mail.ParseAddress(message.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient address %q: %w", message.To, err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, smtpTimeout)
+	defer cancel()
+
+	address := net.JoinHostPort(m.config.Host, strconv.Itoa(m.config.Port))
+	tlsConfig := &tls.Config{ServerName: m.config.Host, MinVersion: tls.VersionTLS12}
+
+	var conn net.Conn
+	if m.config.Security == "tls" {
+		conn, err = (&tls.Dialer{Config: tlsConfig}).DialContext(ctx, "tcp", address)
+	} else {
+		conn, err = (&net.Dialer{}).DialContext(ctx, "tcp", address)
+	}
+	if err != nil {
+		return fmt.Errorf("connecting to SMTP relay %s: %w", address, err)
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("greeting SMTP relay %s: %w", address, err)
+	}
+	defer client.Close()
+
+	if m.config.Security == "starttls" {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("SMTP relay %s does not support STARTTLS", address)
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("starting TLS with SMTP relay %s: %w", address, err)
+		}
+	}
+	if m.config.Username != "" {
+		// PlainAuth refuses to send the password over an unencrypted connection to a remote host
+		if err := client.Auth(smtp.PlainAuth("", m.config.Username, m.config.Password, m.config.Host)); err != nil {
+			return fmt.Errorf("authenticating with SMTP relay %s: %w", address, err)
+		}
+	}
+
+	if err := client.Mail(from.Address); err != nil {
+		return fmt.Errorf("setting sender: %w", err)
+	}
+	if err := client.Rcpt(to.Address); err != nil {
+		return fmt.Errorf("setting recipient: %w", err)
+	}
+	writer, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("starting mail data: %w", err)
+	}
+	if _, err := writer.Write(buildMail(from, to, message)); err != nil {
+		writer.Close()
+		return fmt.Errorf("writing mail data: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return fmt.Errorf("finishing mail data: %w", err)
+	}
+
+	m.logger.Debug("Mail sent", "relay", address, "subject", message.Subject)
+	return client.Quit()
+}
+
+// buildMail formats the headers and body of a plain text mail with CRLF line endings
+func buildMail(from, to *mail.Address, message MailMessage) []byte {
+	var buffer bytes.Buffer
+	header := func(name, value string) {
+		buffer.WriteString(name + ": " + value + "\r\n")
+	}
+	header("From", from.String())
+	header("To", to.String())
+	header("Subject", mime.QEncoding.Encode("utf-8", message.Subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "8bit")
+	buffer.WriteString("\r\n")
+
+	body := strings.ReplaceAll(message.Body, "\r\n", "\n")
+	buffer.WriteString(strings.ReplaceAll(body, "\n", "\r\n"))
+	if !strings.HasSuffix(body, "\n") {
+		buffer.WriteString("\r\n")
+	}
+	return buffer.Bytes()
+}
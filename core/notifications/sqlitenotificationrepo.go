@@ -0,0 +1,175 @@
+package notifications
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteNotificationRepository implements NotificationRepository using SQLite
+type SQLiteNotificationRepository struct {
+	db *sql.DB
+}
+
+const (
+	// notificationFieldList defines the column order for notification queries
+	notificationFieldList = `Id, UserId, Kind, SecretId, DueAt, CreatedAt, ReadAt, EmailedAt`
+)
+
+// NewSQLiteNotificationRepository creates a new SQLite-backed notification repository.
+// The Notification table is expected to be created by the schema migrations.
+func NewSQLiteNotificationRepository(db *sql.DB) (*SQLiteNotificationRepository, error) {
+	return &SQLiteNotificationRepository{db: db}, nil
+}
+
+// Add inserts a notification, unless the unique reminder index already holds one for the same secret, kind and due date
+func (repo *SQLiteNotificationRepository) Add(notification *Notification) (bool, error) {
+	result, err := repo.db.Exec(
+		"INSERT OR IGNORE INTO Notification ("+notificationFieldList+") VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		notification.Id,
+		notification.UserId,
+		string(notification.Kind),
+		notification.SecretId,
+		ccc.FormatSQLiteTimestamp(notification.DueAt),
+		ccc.FormatSQLiteTimestamp(notification.CreatedAt),
+		nullableTimestamp(notification.ReadAt),
+		nullableTimestamp(notification.EmailedAt),
+	)
+	if err != nil {
+		return false, fmt.Errorf("inserting notification: %w", err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("getting rows affected after inserting notification: %w", err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// FindByIdForUser retrieves a notification of a user by its ID
+func (repo *SQLiteNotificationRepository) FindByIdForUser(userId, notificationId string) (*Notification, error) {
+	query := fmt.Sprintf("SELECT %s FROM Notification WHERE UserId = ? AND Id = ?", notificationFieldList)
+	return scanNotification(repo.db.QueryRow(query, userId, notificationId))
+}
+
+// FindByUserId retrieves the notifications of a user, newest first
+func (repo *SQLiteNotificationRepository) FindByUserId(userId string, unreadOnly bool) ([]*Notification, error) {
+	query := fmt.Sprintf("SELECT %s FROM Notification WHERE UserId = ?", notificationFieldList)
+	if unreadOnly {
+		query += " AND ReadAt IS NULL"
+	}
+	query += " ORDER BY CreatedAt DESC, DueAt ASC"
+
+	rows, err := repo.db.Query(query, userId)
+	if err != nil {
+		return nil, fmt.Errorf("querying notifications: %w", err)
+	}
+	defer rows.Close()
+
+	var notifications []*Notification
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notifications = append(notifications, notification)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating notifications: %w", err)
+	}
+	return notifications, nil
+}
+
+// MarkRead marks a notification as read
+func (repo *SQLiteNotificationRepository) MarkRead(notificationId string, readAt time.Time) error {
+	if _, err := repo.db.Exec("UPDATE Notification SET ReadAt = ? WHERE Id = ? AND ReadAt IS NULL", ccc.FormatSQLiteTimestamp(readAt), notificationId); err != nil {
+		return fmt.Errorf("marking notification %s as read: %w", notificationId, err)
+	}
+	return nil
+}
+
+// MarkAllRead marks all unread notifications of a user as read
+func (repo *SQLiteNotificationRepository) MarkAllRead(userId string, readAt time.Time) error {
+	if _, err := repo.db.Exec("UPDATE Notification SET ReadAt = ? WHERE UserId = ? AND ReadAt IS NULL", ccc.FormatSQLiteTimestamp(readAt), userId); err != nil {
+		return fmt.Errorf("marking notifications of user %s as read: %w", userId, err)
+	}
+	return nil
+}
+
+// MarkEmailed records when a notification was sent by mail
+func (repo *SQLiteNotificationRepository) MarkEmailed(notificationId string, emailedAt time.Time) error {
+	if _, err := repo.db.Exec("UPDATE Notification SET EmailedAt = ? WHERE Id = ?", ccc.FormatSQLiteTimestamp(emailedAt), notificationId); err != nil {
+		return fmt.Errorf("marking notification %s as emailed: %w", notificationId, err)
+	}
+	return nil
+}
+
+// Remove deletes a notification
+func (repo *SQLiteNotificationRepository) Remove(notificationId string) error {
+	if _, err := repo.db.Exec("DELETE FROM Notification WHERE Id = ?", notificationId); err != nil {
+		return fmt.Errorf("removing notification %s: %w", notificationId, err)
+	}
+	return nil
+}
+
+// scanNotification scans a database row into a Notification struct
+func scanNotification(scanner ccc.RowScanner) (*Notification, error) {
+	notification := &Notification{}
+	var kind, dueAtStr, createdAtStr string
+	var readAtStr, emailedAtStr sql.NullString
+
+	err := scanner.Scan(
+		&notification.Id,
+		&notification.UserId,
+		&kind,
+		&notification.SecretId,
+		&dueAtStr,
+		&createdAtStr,
+		&readAtStr,
+		&emailedAtStr,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning notification row: %w", err)
+	}
+
+	notification.Kind = NotificationKind(kind)
+	if notification.DueAt, err = ccc.ParseSQLiteTimestamp(dueAtStr); err != nil {
+		return nil, fmt.Errorf("parsing DueAt for notification %s: %w", notification.Id, err)
+	}
+	if notification.CreatedAt, err = ccc.ParseSQLiteTimestamp(createdAtStr); err != nil {
+		return nil, fmt.Errorf("parsing CreatedAt for notification %s: %w", notification.Id, err)
+	}
+	if notification.ReadAt, err = parseNullableTimestamp(readAtStr); err != nil {
+		return nil, fmt.Errorf("parsing ReadAt for notification %s: %w", notification.Id, err)
+	}
+	if notification.EmailedAt, err = parseNullableTimestamp(emailedAtStr); err != nil {
+		return nil, fmt.Errorf("parsing EmailedAt for notification %s: %w", notification.Id, err)
+	}
+
+	return notification, nil
+}
+
+// nullableTimestamp maps a nil time to NULL
+func nullableTimestamp(value *time.Time) any {
+	if value == nil {
+		return nil
+	}
+	return ccc.FormatSQLiteTimestamp(*value)
+}
+
+// parseNullableTimestamp parses a timestamp column that may be NULL
+func parseNullableTimestamp(value sql.NullString) (*time.Time, error) {
+	if !value.Valid {
+		return nil, nil
+	}
+	parsed, err := ccc.ParseSQLiteTimestamp(value.String)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
@@ -0,0 +1,46 @@
+package notifications
+
+import (
+	"database/sql"
+	"fmt"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteNotificationSettingsRepository implements NotificationSettingsRepository using SQLite
+type SQLiteNotificationSettingsRepository struct {
+	db *sql.DB
+}
+
+// NewSQLiteNotificationSettingsRepository creates a new SQLite-backed notification settings repository.
+// The NotificationSettings table is expected to be created by the schema migrations.
+func NewSQLiteNotificationSettingsRepository(db *sql.DB) (*SQLiteNotificationSettingsRepository, error) {
+	return &SQLiteNotificationSettingsRepository{db: db}, nil
+}
+
+// FindByUserId retrieves the settings of a user, nil if the user has not saved any
+func (repo *SQLiteNotificationSettingsRepository) FindByUserId(userId string) (*NotificationSettings, error) {
+	settings := &NotificationSettings{}
+	err := repo.db.QueryRow(
+		"SELECT UserId, Email, EmailEnabled FROM NotificationSettings WHERE UserId = ?", userId,
+	).Scan(&settings.UserId, &settings.Email, &settings.EmailEnabled)
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("querying notification settings of user %s: %w", userId, err)
+	}
+	return settings, nil
+}
+
+// Save inserts or replaces the settings of a user
+func (repo *SQLiteNotificationSettingsRepository) Save(settings *NotificationSettings) error {
+	_, err := repo.db.Exec(`
+	INSERT INTO NotificationSettings (UserId, Email, EmailEnabled) VALUES (?, ?, ?)
+	ON CONFLICT(UserId) DO UPDATE SET Email = excluded.Email, EmailEnabled = excluded.EmailEnabled`,
+		settings.UserId, settings.Email, settings.EmailEnabled)
+	if err != nil {
+		return fmt.Errorf("saving notification settings of user %s: %w", settings.UserId, err)
+	}
+	return nil
+}
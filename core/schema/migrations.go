@@ -0,0 +1,623 @@
+package schema
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// NewMigrationRunner creates a migration runner for the FrozenFortress database schema.
+func NewMigrationRunner(db *sql.DB, logger ccc.Logger) *ccc.SQLiteMigrationRunner {
+	return ccc.NewSQLiteMigrationRunner(db, Migrations(), logger)
+}
+
+// Migrations returns all schema migrations of the application in ascending version order.
+// Applied migrations must never be modified; schema changes are shipped by appending a new migration.
+// The checksum does not cover the code of UpFunc and DownFunc, see ccc.Migration.FuncRevision.
+func Migrations() []ccc.Migration {
+	return []ccc.Migration{
+		baselineMigration(),
+		secretNameIndexMigration(),
+		ocrJobMigration(),
+		userTotpMigration(),
+		webAuthnCredentialMigration(),
+		apiTokenMigration(),
+		backupRunMigration(),
+		secretTypeMigration(),
+		secretVersionMigration(),
+		trashMigration(),
+		secretReminderMigration(),
+		blobStoreMigration(),
+		uploadSessionMigration(),
+		tagNameIndexMigration(),
+	}
+}
+
+// baselineMigration creates the schema as it existed before versioned migrations were introduced.
+// All statements are idempotent so that existing installations, whose tables were created by the
+// repositories themselves, can adopt the migration history without data loss.
+func baselineMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 1,
+		Name:    "baseline",
+		Up: `
+		CREATE TABLE IF NOT EXISTS User (
+			Id TEXT PRIMARY KEY,
+			UserName TEXT NOT NULL UNIQUE,
+			PasswordHash TEXT NOT NULL,
+			PasswordSalt TEXT NOT NULL,
+			Mek TEXT NOT NULL,
+			PdkSalt TEXT NOT NULL,
+			IsActive INTEGER NOT NULL,
+			IsLocked INTEGER NOT NULL,
+			RecoveryCodeHash TEXT,
+			RecoveryCodeSalt TEXT,
+			RecoveryMek TEXT,
+			RecoveryGenerated TIMESTAMP,
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_user_username ON User(UserName);
+
+		CREATE TABLE IF NOT EXISTS sign_in_history (
+			id INTEGER PRIMARY KEY AUTOINCREMENT,
+			user_id TEXT NOT NULL,
+			user_name TEXT,
+			ip_address TEXT,
+			user_agent TEXT,
+			client_type TEXT,
+			sign_in_method TEXT,
+			successful INTEGER NOT NULL,
+			timestamp TIMESTAMP NOT NULL,
+			denial_reason TEXT
+		);
+		CREATE INDEX IF NOT EXISTS idx_sign_in_history_user_id ON sign_in_history(user_id);
+		CREATE INDEX IF NOT EXISTS idx_sign_in_history_user_name ON sign_in_history(user_name);
+		CREATE INDEX IF NOT EXISTS idx_sign_in_history_timestamp ON sign_in_history(timestamp);
+
+		CREATE TABLE IF NOT EXISTS Secret (
+			Id TEXT PRIMARY KEY,
+			UserId TEXT NOT NULL,
+			Name TEXT NOT NULL,
+			Value TEXT NOT NULL,
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_secret_userid ON Secret(UserId);
+
+		CREATE TABLE IF NOT EXISTS Document (
+			Id TEXT PRIMARY KEY,
+			UserId TEXT NOT NULL,
+			Title TEXT NOT NULL,
+			Description TEXT,
+			Issuer TEXT,
+			IssueDate TIMESTAMP,
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_document_userid ON Document(UserId);
+		CREATE INDEX IF NOT EXISTS idx_document_created ON Document(CreatedAt);
+
+		CREATE TABLE IF NOT EXISTS DocumentFile (
+			Id TEXT PRIMARY KEY,
+			DocumentId TEXT NOT NULL,
+			FileName TEXT NOT NULL,
+			ContentType TEXT NOT NULL,
+			FileSize INTEGER NOT NULL,
+			PageCount INTEGER DEFAULT 0,
+			FileData BLOB NOT NULL,
+			PreviewData BLOB,
+			PreviewType TEXT,
+			Width INTEGER DEFAULT 0,
+			Height INTEGER DEFAULT 0,
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_documentfile_documentid ON DocumentFile(DocumentId);
+		CREATE INDEX IF NOT EXISTS idx_documentfile_created ON DocumentFile(CreatedAt);
+
+		CREATE TABLE IF NOT EXISTS DocumentFileMetadata (
+			DocumentFileId TEXT PRIMARY KEY,
+			ExtractedText TEXT,
+			OcrConfidence REAL DEFAULT 0.0,
+			OcrStatus TEXT DEFAULT '',
+			OcrError TEXT DEFAULT '',
+			OcrStartedAt TIMESTAMP,
+			OcrCompletedAt TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_documentfilemetadata_confidence ON DocumentFileMetadata(OcrConfidence);
+
+		CREATE TABLE IF NOT EXISTS Tag (
+			Id TEXT PRIMARY KEY,
+			UserId TEXT NOT NULL,
+			Name TEXT NOT NULL,
+			Color TEXT NOT NULL DEFAULT '#007bff',
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_tag_userid ON Tag(UserId);
+		CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_user_name ON Tag(UserId, Name);
+
+		CREATE TABLE IF NOT EXISTS DocumentTag (
+			DocumentId TEXT NOT NULL,
+			TagId TEXT NOT NULL,
+			CreatedAt TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+			PRIMARY KEY (DocumentId, TagId)
+		);
+		CREATE INDEX IF NOT EXISTS idx_documenttag_documentid ON DocumentTag(DocumentId);
+		CREATE INDEX IF NOT EXISTS idx_documenttag_tagid ON DocumentTag(TagId);
+
+		CREATE TABLE IF NOT EXISTS Note (
+			Id TEXT PRIMARY KEY,
+			DocumentId TEXT NOT NULL,
+			UserId TEXT NOT NULL,
+			Content TEXT NOT NULL,
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_note_documentid ON Note(DocumentId);
+		CREATE INDEX IF NOT EXISTS idx_note_userid ON Note(UserId);
+		`,
+		UpFunc: func(tx *sql.Tx) error {
+			// Columns that older installations received through ad-hoc ALTER TABLE statements
+			legacyColumns := []struct {
+				table      string
+				column     string
+				definition string
+			}{
+				{"Document", "Issuer", "TEXT"},
+				{"Document", "IssueDate", "TIMESTAMP"},
+				{"DocumentFile", "PreviewData", "BLOB"},
+				{"DocumentFile", "PreviewType", "TEXT"},
+				{"DocumentFile", "Width", "INTEGER DEFAULT 0"},
+				{"DocumentFile", "Height", "INTEGER DEFAULT 0"},
+				{"DocumentFileMetadata", "OcrStatus", "TEXT DEFAULT ''"},
+				{"DocumentFileMetadata", "OcrError", "TEXT DEFAULT ''"},
+				{"DocumentFileMetadata", "OcrStartedAt", "TIMESTAMP"},
+				{"DocumentFileMetadata", "OcrCompletedAt", "TIMESTAMP"},
+			}
+
+			for _, c := range legacyColumns {
+				if err := ccc.AddSQLiteColumnIfNotExists(tx, c.table, c.column, c.definition); err != nil {
+					return err
+				}
+			}
+
+			// Indexes on legacy columns can only be created once the columns are guaranteed to exist
+			_, err := tx.Exec(`
+			CREATE INDEX IF NOT EXISTS idx_document_issuedate ON Document(IssueDate);
+			CREATE INDEX IF NOT EXISTS idx_documentfilemetadata_status ON DocumentFileMetadata(OcrStatus);
+			`)
+			if err != nil {
+				return err
+			}
+
+			// Files processed before OCR status tracking was introduced are considered completed
+			_, err = tx.Exec(`
+			UPDATE DocumentFileMetadata
+			SET OcrStatus = 'completed'
+			WHERE COALESCE(OcrStatus, '') = ''
+			  AND COALESCE(ExtractedText, '') <> ''
+			`)
+			return err
+		},
+		Down: `
+		DROP TABLE IF EXISTS Note;
+		DROP TABLE IF EXISTS DocumentTag;
+		DROP TABLE IF EXISTS Tag;
+		DROP TABLE IF EXISTS DocumentFileMetadata;
+		DROP TABLE IF EXISTS DocumentFile;
+		DROP TABLE IF EXISTS Document;
+		DROP TABLE IF EXISTS Secret;
+		DROP TABLE IF EXISTS sign_in_history;
+		DROP TABLE IF EXISTS User;
+		`,
+	}
+}
+
+// secretNameIndexMigration adds the blind index of secret names used for lookups and uniqueness.
+func secretNameIndexMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 2,
+		Name:    "secret_name_index",
+		UpFunc: func(tx *sql.Tx) error {
+			if err := ccc.AddSQLiteColumnIfNotExists(tx, "Secret", "NameIndex", "TEXT"); err != nil {
+				return err
+			}
+
+			// Secrets without a name index (not yet backfilled) are excluded from the uniqueness constraint
+			_, err := tx.Exec(`
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_secret_user_nameindex ON Secret(UserId, NameIndex)
+			WHERE NameIndex IS NOT NULL AND NameIndex != '';
+			`)
+			return err
+		},
+		Down: `
+		DROP INDEX IF EXISTS idx_secret_user_nameindex;
+		ALTER TABLE Secret DROP COLUMN NameIndex;
+		`,
+	}
+}
+
+// ocrJobMigration adds the persisted OCR job queue.
+func ocrJobMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 3,
+		Name:    "ocr_job_queue",
+		Up: `
+		CREATE TABLE IF NOT EXISTS OcrJob (
+			Id TEXT PRIMARY KEY,
+			DocumentFileId TEXT NOT NULL,
+			UserId TEXT NOT NULL,
+			Status TEXT NOT NULL,
+			Attempts INTEGER NOT NULL DEFAULT 0,
+			NextRunAt TIMESTAMP NOT NULL,
+			WrappedMek TEXT NOT NULL,
+			LastError TEXT NOT NULL DEFAULT '',
+			CreatedAt TIMESTAMP NOT NULL,
+			ModifiedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_ocrjob_status_nextrun ON OcrJob(Status, NextRunAt);
+		CREATE INDEX IF NOT EXISTS idx_ocrjob_documentfileid ON OcrJob(DocumentFileId);
+		CREATE INDEX IF NOT EXISTS idx_ocrjob_userid ON OcrJob(UserId);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS OcrJob;
+		`,
+	}
+}
+
+// userTotpMigration adds the TOTP second factor columns to the User table.
+func userTotpMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 4,
+		Name:    "user_totp",
+		UpFunc: func(tx *sql.Tx) error {
+			columns := []struct {
+				column     string
+				definition string
+			}{
+				{"TotpSecret", "TEXT NOT NULL DEFAULT ''"},
+				{"TotpEnabled", "INTEGER NOT NULL DEFAULT 0"},
+				{"TotpLastUsedStep", "INTEGER NOT NULL DEFAULT 0"},
+			}
+
+			for _, c := range columns {
+				if err := ccc.AddSQLiteColumnIfNotExists(tx, "User", c.column, c.definition); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		Down: `
+		ALTER TABLE User DROP COLUMN TotpLastUsedStep;
+		ALTER TABLE User DROP COLUMN TotpEnabled;
+		ALTER TABLE User DROP COLUMN TotpSecret;
+		`,
+	}
+}
+
+// webAuthnCredentialMigration adds the passkeys registered by users.
+func webAuthnCredentialMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 5,
+		Name:    "webauthn_credentials",
+		Up: `
+		CREATE TABLE IF NOT EXISTS WebAuthnCredential (
+			Id TEXT PRIMARY KEY,
+			UserId TEXT NOT NULL,
+			CredentialId TEXT NOT NULL UNIQUE,
+			PublicKey BLOB NOT NULL,
+			SignCount INTEGER NOT NULL DEFAULT 0,
+			Name TEXT NOT NULL,
+			Transports TEXT NOT NULL DEFAULT '',
+			WrappedMek TEXT NOT NULL,
+			CreatedAt TIMESTAMP NOT NULL,
+			LastUsedAt TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_webauthncredential_userid ON WebAuthnCredential(UserId);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS WebAuthnCredential;
+		`,
+	}
+}
+
+// apiTokenMigration adds personal access tokens for the REST API.
+// Like passkeys, every token carries its own copy of the MEK, wrapped with a key derived from the token secret.
+func apiTokenMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 6,
+		Name:    "api_tokens",
+		Up: `
+		CREATE TABLE IF NOT EXISTS ApiToken (
+			Id TEXT PRIMARY KEY,
+			UserId TEXT NOT NULL,
+			Name TEXT NOT NULL,
+			SecretHash TEXT NOT NULL,
+			Scopes TEXT NOT NULL,
+			WrappedMek TEXT NOT NULL,
+			ExpiresAt TIMESTAMP NOT NULL,
+			CreatedAt TIMESTAMP NOT NULL,
+			LastUsedAt TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_apitoken_userid ON ApiToken(UserId);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS ApiToken;
+		`,
+	}
+}
+
+// backupRunMigration adds the history of the backup worker, used to report failing automatic backups.
+func backupRunMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 7,
+		Name:    "backup_runs",
+		Up: `
+		CREATE TABLE IF NOT EXISTS backup_runs (
+			id TEXT PRIMARY KEY,
+			trigger_type TEXT NOT NULL,
+			status TEXT NOT NULL,
+			started_at TIMESTAMP NOT NULL,
+			finished_at TIMESTAMP,
+			filename TEXT NOT NULL DEFAULT '',
+			size_bytes INTEGER NOT NULL DEFAULT 0,
+			error TEXT NOT NULL DEFAULT ''
+		);
+		CREATE INDEX IF NOT EXISTS idx_backup_runs_started_at ON backup_runs(started_at);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS backup_runs;
+		`,
+	}
+}
+
+// secretTypeMigration adds the type of secrets, whose fields are stored as an encrypted JSON payload.
+// Existing secrets keep an empty type, which marks their Value as a single plain value.
+func secretTypeMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 8,
+		Name:    "secret_type",
+		UpFunc: func(tx *sql.Tx) error {
+			return ccc.AddSQLiteColumnIfNotExists(tx, "Secret", "Type", "TEXT")
+		},
+		Down: `
+		ALTER TABLE Secret DROP COLUMN Type;
+		`,
+	}
+}
+
+// secretVersionMigration adds the prior versions of secrets, which are recorded whenever a secret is updated.
+// Versions hold the encrypted name and value as they were stored on the secret.
+func secretVersionMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 9,
+		Name:    "secret_versions",
+		Up: `
+		CREATE TABLE IF NOT EXISTS SecretVersion (
+			Id TEXT PRIMARY KEY,
+			SecretId TEXT NOT NULL,
+			UserId TEXT NOT NULL,
+			Name TEXT NOT NULL,
+			Value TEXT NOT NULL,
+			Type TEXT,
+			ModifiedAt TIMESTAMP NOT NULL,
+			ReplacedAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_secretversion_secretid ON SecretVersion(SecretId, ReplacedAt);
+		CREATE INDEX IF NOT EXISTS idx_secretversion_userid ON SecretVersion(UserId);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS SecretVersion;
+		`,
+	}
+}
+
+// trashMigration adds the soft deletion of secrets, documents and tags, which are moved to the trash
+// before they are purged. Trashed rows are excluded from the unique names, so that a new item can take the
+// name of a trashed one. Reverting it purges trashed secrets and tags, which could violate the unique names,
+// and restores trashed documents.
+func trashMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 10,
+		Name:    "trash",
+		UpFunc: func(tx *sql.Tx) error {
+			for _, table := range []string{"Secret", "Document", "Tag"} {
+				if err := ccc.AddSQLiteColumnIfNotExists(tx, table, "DeletedAt", "TIMESTAMP"); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.Exec(`
+			DROP INDEX IF EXISTS idx_secret_user_nameindex;
+			CREATE UNIQUE INDEX idx_secret_user_nameindex ON Secret(UserId, NameIndex)
+			WHERE NameIndex IS NOT NULL AND NameIndex != '' AND DeletedAt IS NULL;
+			DROP INDEX IF EXISTS idx_tag_user_name;
+			CREATE UNIQUE INDEX idx_tag_user_name ON Tag(UserId, Name) WHERE DeletedAt IS NULL;
+			CREATE INDEX IF NOT EXISTS idx_secret_deletedat ON Secret(DeletedAt);
+			CREATE INDEX IF NOT EXISTS idx_document_deletedat ON Document(DeletedAt);
+			CREATE INDEX IF NOT EXISTS idx_tag_deletedat ON Tag(DeletedAt);
+			`)
+			return err
+		},
+		Down: `
+		DELETE FROM SecretVersion WHERE SecretId IN (SELECT Id FROM Secret WHERE DeletedAt IS NOT NULL);
+		DELETE FROM Secret WHERE DeletedAt IS NOT NULL;
+		DELETE FROM DocumentTag WHERE TagId IN (SELECT Id FROM Tag WHERE DeletedAt IS NOT NULL);
+		DELETE FROM Tag WHERE DeletedAt IS NOT NULL;
+		DROP INDEX IF EXISTS idx_secret_deletedat;
+		DROP INDEX IF EXISTS idx_document_deletedat;
+		DROP INDEX IF EXISTS idx_tag_deletedat;
+		DROP INDEX IF EXISTS idx_secret_user_nameindex;
+		CREATE UNIQUE INDEX idx_secret_user_nameindex ON Secret(UserId, NameIndex)
+		WHERE NameIndex IS NOT NULL AND NameIndex != '';
+		DROP INDEX IF EXISTS idx_tag_user_name;
+		CREATE UNIQUE INDEX idx_tag_user_name ON Tag(UserId, Name);
+		ALTER TABLE Secret DROP COLUMN DeletedAt;
+		ALTER TABLE Document DROP COLUMN DeletedAt;
+		ALTER TABLE Tag DROP COLUMN DeletedAt;
+		`,
+	}
+}
+
+// secretReminderMigration adds expiry dates and rotation intervals to secrets, which are stored in plain
+// text so that reminders can be created without the key of the user, and the notifications created for them.
+// Existing secrets count as rotated when they were last modified.
+func secretReminderMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 11,
+		Name:    "secret_reminders",
+		UpFunc: func(tx *sql.Tx) error {
+			columns := []struct{ name, definition string }{
+				{"ExpiresAt", "TIMESTAMP"},
+				{"RotateEvery", "INTEGER NOT NULL DEFAULT 0"},
+				{"RotatedAt", "TIMESTAMP"},
+			}
+			for _, column := range columns {
+				if err := ccc.AddSQLiteColumnIfNotExists(tx, "Secret", column.name, column.definition); err != nil {
+					return err
+				}
+			}
+
+			_, err := tx.Exec(`
+			UPDATE Secret SET RotatedAt = ModifiedAt WHERE RotatedAt IS NULL;
+			CREATE INDEX IF NOT EXISTS idx_secret_expiresat ON Secret(ExpiresAt);
+			CREATE TABLE IF NOT EXISTS Notification (
+				Id TEXT PRIMARY KEY,
+				UserId TEXT NOT NULL,
+				Kind TEXT NOT NULL,
+				SecretId TEXT NOT NULL,
+				DueAt TIMESTAMP NOT NULL,
+				CreatedAt TIMESTAMP NOT NULL,
+				ReadAt TIMESTAMP,
+				EmailedAt TIMESTAMP
+			);
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_notification_reminder ON Notification(UserId, Kind, SecretId, DueAt);
+			CREATE INDEX IF NOT EXISTS idx_notification_userid ON Notification(UserId, CreatedAt);
+			CREATE TABLE IF NOT EXISTS NotificationSettings (
+				UserId TEXT PRIMARY KEY,
+				Email TEXT NOT NULL DEFAULT '',
+				EmailEnabled INTEGER NOT NULL DEFAULT 0
+			);
+			`)
+			return err
+		},
+		Down: `
+		DROP TABLE IF EXISTS NotificationSettings;
+		DROP TABLE IF EXISTS Notification;
+		DROP INDEX IF EXISTS idx_secret_expiresat;
+		ALTER TABLE Secret DROP COLUMN ExpiresAt;
+		ALTER TABLE Secret DROP COLUMN RotateEvery;
+		ALTER TABLE Secret DROP COLUMN RotatedAt;
+		`,
+	}
+}
+
+// blobStoreMigration adds the references to the contents and previews of document files that are kept in a blob store.
+// A reference lists the hashes of the chunks of the content. Files without a reference keep their content in the
+// FileData and PreviewData columns. Reverting it fails while files are stored in a blob store, as their contents
+// would be lost.
+func blobStoreMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 12,
+		Name:    "blob_store",
+		UpFunc: func(tx *sql.Tx) error {
+			for _, column := range []string{"FileBlobRef", "PreviewBlobRef"} {
+				if err := ccc.AddSQLiteColumnIfNotExists(tx, "DocumentFile", column, "TEXT"); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+		DownFunc: func(tx *sql.Tx) error {
+			var count int
+			err := tx.QueryRow(`SELECT COUNT(*) FROM DocumentFile WHERE FileBlobRef IS NOT NULL OR PreviewBlobRef IS NOT NULL`).Scan(&count)
+			if err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%d document files are stored in a blob store", count)
+			}
+			return nil
+		},
+		Down: `
+		ALTER TABLE DocumentFile DROP COLUMN FileBlobRef;
+		ALTER TABLE DocumentFile DROP COLUMN PreviewBlobRef;
+		`,
+	}
+}
+
+// uploadSessionMigration adds the sessions of resumable uploads and the parts uploaded to them. Like the contents of
+// document files, the encrypted parts are kept either in the Data column or in a blob store. Sessions are transient,
+// so reverting it drops unfinished uploads.
+func uploadSessionMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 13,
+		Name:    "upload_sessions",
+		Up: `
+		CREATE TABLE IF NOT EXISTS UploadSession (
+			Id TEXT PRIMARY KEY,
+			UserId TEXT NOT NULL,
+			DocumentId TEXT NOT NULL,
+			FileName TEXT NOT NULL,
+			ContentType TEXT NOT NULL,
+			Size INTEGER NOT NULL,
+			UploadOffset INTEGER NOT NULL DEFAULT 0,
+			CreatedAt TIMESTAMP NOT NULL,
+			ExpiresAt TIMESTAMP NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_uploadsession_userid ON UploadSession(UserId);
+		CREATE INDEX IF NOT EXISTS idx_uploadsession_documentid ON UploadSession(DocumentId);
+		CREATE INDEX IF NOT EXISTS idx_uploadsession_expiresat ON UploadSession(ExpiresAt);
+		CREATE TABLE IF NOT EXISTS UploadSessionPart (
+			SessionId TEXT NOT NULL,
+			PartOffset INTEGER NOT NULL,
+			Size INTEGER NOT NULL,
+			Data BLOB,
+			BlobRef TEXT,
+			PRIMARY KEY (SessionId, PartOffset)
+		);
+		`,
+		Down: `
+		DROP TABLE IF EXISTS UploadSessionPart;
+		DROP TABLE IF EXISTS UploadSession;
+		`,
+	}
+}
+
+// tagNameIndexMigration adds the blind index of tag names. Tag names are encrypted from now on; existing tags keep
+// their plain name without an index until their owner signs in. Reverting it is refused once a tag name is encrypted.
+func tagNameIndexMigration() ccc.Migration {
+	return ccc.Migration{
+		Version: 14,
+		Name:    "tag_name_index",
+		UpFunc: func(tx *sql.Tx) error {
+			if err := ccc.AddSQLiteColumnIfNotExists(tx, "Tag", "NameIndex", "TEXT"); err != nil {
+				return err
+			}
+
+			// Tags without a name index (not yet encrypted) and trashed tags are excluded from the uniqueness constraint
+			_, err := tx.Exec(`
+			CREATE UNIQUE INDEX IF NOT EXISTS idx_tag_user_nameindex ON Tag(UserId, NameIndex)
+			WHERE NameIndex IS NOT NULL AND NameIndex != '' AND DeletedAt IS NULL;
+			`)
+			return err
+		},
+		DownFunc: func(tx *sql.Tx) error {
+			var count int
+			if err := tx.QueryRow(`SELECT COUNT(*) FROM Tag WHERE NameIndex IS NOT NULL AND NameIndex != ''`).Scan(&count); err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("%d tags have encrypted names", count)
+			}
+			return nil
+		},
+		Down: `
+		DROP INDEX IF EXISTS idx_tag_user_nameindex;
+		ALTER TABLE Tag DROP COLUMN NameIndex;
+		`,
+	}
+}
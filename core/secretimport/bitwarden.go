@@ -0,0 +1,165 @@
+package secretimport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// bitwardenJsonParser reads unencrypted JSON exports of Bitwarden
+type bitwardenJsonParser struct{}
+
+// bitwardenExport is the part of a Bitwarden JSON export that is imported. Folders are ignored.
+type bitwardenExport struct {
+	Encrypted bool            `json:"encrypted"`
+	Items     []bitwardenItem `json:"items"`
+}
+
+type bitwardenItem struct {
+	Name  string `json:"name"`
+	Notes string `json:"notes"`
+	Login *struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Totp     string `json:"totp"`
+		Uris     []struct {
+			Uri string `json:"uri"`
+		} `json:"uris"`
+	} `json:"login"`
+	Card *struct {
+		CardholderName string `json:"cardholderName"`
+		Brand          string `json:"brand"`
+		Number         string `json:"number"`
+		ExpMonth       string `json:"expMonth"`
+		ExpYear        string `json:"expYear"`
+		Code           string `json:"code"`
+	} `json:"card"`
+	SshKey *struct {
+		PrivateKey     string `json:"privateKey"`
+		PublicKey      string `json:"publicKey"`
+		KeyFingerprint string `json:"keyFingerprint"`
+	} `json:"sshKey"`
+	Identity *struct {
+		FirstName string `json:"firstName"`
+		LastName  string `json:"lastName"`
+		Email     string `json:"email"`
+		Phone     string `json:"phone"`
+		Username  string `json:"username"`
+	} `json:"identity"`
+	Fields []struct {
+		Name  string `json:"name"`
+		Value string `json:"value"`
+		Type  int    `json:"type"`
+	} `json:"fields"`
+}
+
+// bitwardenHiddenField is the type of custom fields that Bitwarden masks
+const bitwardenHiddenField = 1
+
+func (p bitwardenJsonParser) Parse(src io.Reader) ([]ImportedEntry, error) {
+	var export bitwardenExport
+	if err := json.NewDecoder(src).Decode(&export); err != nil {
+		return nil, fmt.Errorf("failed to decode Bitwarden export: %w", err)
+	}
+	if export.Encrypted {
+		return nil, ccc.NewInvalidInputErrorWithMessage(
+			"import file",
+			"Bitwarden export is encrypted",
+			"Encrypted Bitwarden exports cannot be imported. Export the vault as unencrypted JSON.",
+		)
+	}
+	if export.Items == nil {
+		return nil, errors.New("export does not contain any items")
+	}
+
+	entries := make([]ImportedEntry, 0, len(export.Items))
+	for _, item := range export.Items {
+		entry := bitwardenItemToImported(item)
+		for _, field := range item.Fields {
+			entry.addField(field.Name, field.Value, field.Type == bitwardenHiddenField)
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+// bitwardenItemToImported maps the built-in fields of an item to an entry of the matching secret type
+func bitwardenItemToImported(item bitwardenItem) ImportedEntry {
+	switch {
+	case item.Login != nil:
+		var url string
+		if len(item.Login.Uris) > 0 {
+			url = item.Login.Uris[0].Uri
+		}
+		entry := newLoginEntry(item.Name, item.Login.Username, item.Login.Password, url, item.Notes)
+		entry.addTotpField(item.Login.Totp)
+		return entry
+
+	case item.Card != nil:
+		entry := ImportedEntry{Name: item.Name, Type: secrets.SecretTypeCard}
+		entry.addField("Cardholder", item.Card.CardholderName, false)
+		entry.addField("Number", item.Card.Number, true)
+		if item.Card.ExpMonth != "" || item.Card.ExpYear != "" {
+			entry.addField("Expiry", item.Card.ExpMonth+"/"+item.Card.ExpYear, false)
+		}
+		entry.addField("Security code", item.Card.Code, true)
+		entry.addField("Brand", item.Card.Brand, false)
+		entry.addField("Notes", item.Notes, false)
+		return entry
+
+	case item.SshKey != nil:
+		entry := ImportedEntry{Name: item.Name, Type: secrets.SecretTypeSshKey}
+		entry.addField("Private key", item.SshKey.PrivateKey, true)
+		entry.addField("Public key", item.SshKey.PublicKey, false)
+		entry.addField("Fingerprint", item.SshKey.KeyFingerprint, false)
+		entry.addField("Notes", item.Notes, false)
+		return entry
+
+	case item.Identity != nil:
+		entry := ImportedEntry{Name: item.Name, Type: secrets.SecretTypeGeneric}
+		entry.addField("Name", strings.TrimSpace(item.Identity.FirstName+" "+item.Identity.LastName), false)
+		entry.addField("Email", item.Identity.Email, false)
+		entry.addField("Phone", item.Identity.Phone, false)
+		entry.addField("Username", item.Identity.Username, false)
+		entry.addField("Notes", item.Notes, false)
+		return entry
+
+	default:
+		return newLoginEntry(item.Name, "", "", "", item.Notes)
+	}
+}
+
+// bitwardenCsvParser reads CSV exports of Bitwarden, which only contain logins and secure notes.
+// Custom fields are written as "name: value" lines without telling hidden ones apart.
+type bitwardenCsvParser struct{}
+
+func (p bitwardenCsvParser) Parse(src io.Reader) ([]ImportedEntry, error) {
+	table, err := readCsvTable(src)
+	if err != nil {
+		return nil, err
+	}
+	if !table.hasColumns("name", "login_password") {
+		return nil, errors.New("file does not have the columns of a Bitwarden CSV export")
+	}
+
+	entries := make([]ImportedEntry, 0, len(table.rows))
+	for _, row := range table.rows {
+		// Multiple URIs are separated by commas
+		url := strings.Split(table.value(row, "login_uri"), ",")[0]
+		entry := newLoginEntry(table.value(row, "name"), table.value(row, "login_username"), table.value(row, "login_password"),
+			url, table.value(row, "notes"))
+		entry.addTotpField(table.value(row, "login_totp"))
+		for _, line := range strings.Split(table.value(row, "fields"), "\n") {
+			if name, value, ok := strings.Cut(line, ": "); ok {
+				entry.addField(strings.TrimSpace(name), value, false)
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
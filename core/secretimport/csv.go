@@ -0,0 +1,79 @@
+package secretimport
+
+import (
+	"bufio"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// csvTable is a CSV file whose columns are looked up by the names in its header row
+type csvTable struct {
+	columns map[string]int
+	rows    [][]string
+}
+
+// readCsvTable reads a CSV file with a header row. Column names are matched case-insensitively.
+func readCsvTable(src io.Reader) (*csvTable, error) {
+	reader := bufio.NewReader(src)
+	// Spreadsheet applications like to prepend a byte order mark
+	if bom, err := reader.Peek(3); err == nil && string(bom) == "\xef\xbb\xbf" {
+		reader.Discard(3)
+	}
+
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, errors.New("file is empty")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CSV header: %w", err)
+	}
+
+	table := &csvTable{columns: make(map[string]int, len(header))}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if _, exists := table.columns[name]; !exists {
+			table.columns[name] = i
+		}
+	}
+
+	for {
+		row, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CSV row: %w", err)
+		}
+		table.rows = append(table.rows, row)
+	}
+
+	return table, nil
+}
+
+// hasColumns reports whether the header contains all of the given columns
+func (t *csvTable) hasColumns(names ...string) bool {
+	for _, name := range names {
+		if _, ok := t.columns[name]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// value returns the value of the first of the given columns that exists and is not empty in the row
+func (t *csvTable) value(row []string, names ...string) string {
+	for _, name := range names {
+		if i, ok := t.columns[name]; ok && i < len(row) {
+			if value := strings.TrimSpace(row[i]); value != "" {
+				return value
+			}
+		}
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package secretimport
+
+import (
+	"io"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// ImportRequest describes an export file to import
+type ImportRequest struct {
+	Format     Format
+	Source     io.Reader
+	OnConflict secrets.ConflictPolicy
+}
+
+// ImportReport summarizes the outcome of an import
+type ImportReport struct {
+	Format Format
+	// Entries is the number of entries found in the export file
+	Entries     int
+	Created     int
+	Overwritten int
+	Renamed     []secrets.RenamedSecret
+	Skipped     []secrets.SkippedSecret
+}
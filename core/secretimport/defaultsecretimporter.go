@@ -0,0 +1,122 @@
+package secretimport
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// maxImportFileSize bounds the memory used to parse an export file
+const maxImportFileSize = 16 * 1024 * 1024
+
+// errImportFileTooLarge is returned by the source reader once more than maxImportFileSize bytes were read
+var errImportFileTooLarge = errors.New("import file is too large")
+
+// DefaultSecretImporter implements SecretImporter on top of the secret manager
+type DefaultSecretImporter struct {
+	secretManager secrets.SecretManager
+	parsers       map[Format]EntryParser
+	logger        ccc.Logger
+}
+
+// NewDefaultSecretImporter creates a new DefaultSecretImporter that supports all formats of SupportedFormats
+func NewDefaultSecretImporter(secretManager secrets.SecretManager, logger ccc.Logger) *DefaultSecretImporter {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	return &DefaultSecretImporter{
+		secretManager: secretManager,
+		parsers: map[Format]EntryParser{
+			FormatBitwardenJson:  bitwardenJsonParser{},
+			FormatBitwardenCsv:   bitwardenCsvParser{},
+			FormatKeePassXml:     keePassXmlParser{},
+			FormatOnePasswordCsv: onePasswordCsvParser{},
+		},
+		logger: logger,
+	}
+}
+
+// ImportSecrets parses the export file and creates a secret for each entry with all of its fields.
+// Entries without any value are skipped.
+func (i *DefaultSecretImporter) ImportSecrets(userId string, request ImportRequest, dataProtector dataprotection.DataProtector) (*ImportReport, error) {
+	i.logger.Info("Importing secrets from export file", "userId", userId, "format", request.Format, "onConflict", request.OnConflict)
+
+	parser, ok := i.parsers[request.Format]
+	if !ok {
+		_, err := ParseFormat(string(request.Format))
+		return nil, err
+	}
+
+	entries, err := parser.Parse(&limitedReader{src: request.Source, remaining: maxImportFileSize})
+	if err != nil {
+		i.logger.Warn("Failed to parse export file", "userId", userId, "format", request.Format, "error", err)
+		return nil, parseError(request.Format, err)
+	}
+
+	report := &ImportReport{Format: request.Format, Entries: len(entries)}
+
+	importRequest := secrets.ImportSecretsRequest{
+		Secrets:    make([]secrets.UpsertSecretRequest, 0, len(entries)),
+		OnConflict: request.OnConflict,
+	}
+	for _, entry := range entries {
+		importRequest.Secrets = append(importRequest.Secrets, secrets.UpsertSecretRequest{
+			SecretName: entry.Name,
+			SecretType: entry.Type,
+			Fields:     entry.Fields,
+		})
+	}
+
+	response, err := i.secretManager.ImportSecrets(userId, importRequest, dataProtector)
+	report.Created = response.Created
+	report.Overwritten = response.Overwritten
+	report.Renamed = response.Renamed
+	report.Skipped = response.Skipped
+	if err != nil {
+		return report, err
+	}
+
+	i.logger.Info("Secrets imported from export file", "userId", userId, "format", request.Format, "entries", report.Entries,
+		"created", report.Created, "overwritten", report.Overwritten, "skipped", len(report.Skipped))
+	return report, nil
+}
+
+// parseError maps a parser error to an error that tells the user what is wrong with the file
+func parseError(format Format, err error) error {
+	if _, ok := ccc.IsApiError(err); ok {
+		return err
+	}
+	if errors.Is(err, errImportFileTooLarge) {
+		return ccc.NewInvalidInputErrorWithMessage("import file", err.Error(), "The file is larger than 16 MB.")
+	}
+	return ccc.NewInvalidInputErrorWithMessage("import file", err.Error(),
+		fmt.Sprintf("The file is not a valid %s export.", format.DisplayName()))
+}
+
+// limitedReader fails with errImportFileTooLarge instead of silently truncating the source like io.LimitReader
+type limitedReader struct {
+	src       io.Reader
+	remaining int64
+}
+
+func (r *limitedReader) Read(p []byte) (int, error) {
+	if r.remaining <= 0 {
+		// Only fail if there actually is more data
+		var probe [1]byte
+		if n, _ := r.src.Read(probe[:]); n > 0 {
+			return 0, errImportFileTooLarge
+		}
+		return 0, io.EOF
+	}
+	if int64(len(p)) > r.remaining {
+		p = p[:r.remaining]
+	}
+	n, err := r.src.Read(p)
+	r.remaining -= int64(n)
+	return n, err
+}
@@ -0,0 +1,19 @@
+package secretimport
+
+import (
+	"io"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+)
+
+// SecretImporter imports secrets from the export files of other password managers
+type SecretImporter interface {
+	// ImportSecrets parses the export file and creates a secret for each of its entries. Secrets imported
+	// before an error occurred are kept; the returned report counts them.
+	ImportSecrets(userId string, request ImportRequest, dataProtector dataprotection.DataProtector) (*ImportReport, error)
+}
+
+// EntryParser reads the entries of one export format
+type EntryParser interface {
+	Parse(src io.Reader) ([]ImportedEntry, error)
+}
@@ -0,0 +1,137 @@
+package secretimport
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// keePassXmlParser reads the "KeePass XML (2.x)" export of KeePass and compatible apps like KeePassXC.
+// KDBX databases are not read directly: KDBX 4 derives its key with Argon2d by default, which is
+// not available to us, so users export their database to XML first.
+type keePassXmlParser struct{}
+
+// keePassFile is the part of a KeePass XML export that is imported
+type keePassFile struct {
+	XMLName xml.Name `xml:"KeePassFile"`
+	Meta    struct {
+		RecycleBinUUID string `xml:"RecycleBinUUID"`
+	} `xml:"Meta"`
+	Root struct {
+		Groups []keePassGroup `xml:"Group"`
+	} `xml:"Root"`
+}
+
+type keePassGroup struct {
+	UUID    string         `xml:"UUID"`
+	Name    string         `xml:"Name"`
+	Entries []keePassEntry `xml:"Entry"`
+	Groups  []keePassGroup `xml:"Group"`
+}
+
+// keePassEntry holds the current fields of an entry. Previous versions are stored in a History
+// element, which is not decoded.
+type keePassEntry struct {
+	Strings []struct {
+		Key   string `xml:"Key"`
+		Value struct {
+			Text string `xml:",chardata"`
+			// Protected values are only encrypted in the XML inside of a KDBX database
+			Protected string `xml:"Protected,attr"`
+			// Values protected in memory are masked by KeePass
+			ProtectInMemory string `xml:"ProtectInMemory,attr"`
+		} `xml:"Value"`
+	} `xml:"String"`
+}
+
+// emptyKeePassUuid is the UUID KeePass stores when there is no recycle bin
+const emptyKeePassUuid = "AAAAAAAAAAAAAAAAAAAAAA=="
+
+func (p keePassXmlParser) Parse(src io.Reader) ([]ImportedEntry, error) {
+	var file keePassFile
+	if err := xml.NewDecoder(src).Decode(&file); err != nil {
+		return nil, fmt.Errorf("failed to decode KeePass XML export: %w", err)
+	}
+
+	recycleBin := file.Meta.RecycleBinUUID
+	if recycleBin == emptyKeePassUuid {
+		recycleBin = ""
+	}
+
+	var entries []ImportedEntry
+	var walk func(groups []keePassGroup) error
+	walk = func(groups []keePassGroup) error {
+		for _, group := range groups {
+			// Deleted entries are not migrated
+			if recycleBin != "" && group.UUID == recycleBin {
+				continue
+			}
+			for _, entry := range group.Entries {
+				imported, err := keePassEntryToImported(entry)
+				if err != nil {
+					return err
+				}
+				entries = append(entries, imported)
+			}
+			if err := walk(group.Groups); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(file.Root.Groups); err != nil {
+		return nil, err
+	}
+
+	return entries, nil
+}
+
+// keePassEntryToImported maps the standard fields of an entry to a login and keeps its custom
+// strings as additional fields
+func keePassEntryToImported(entry keePassEntry) (ImportedEntry, error) {
+	var name, username, password, url, notes, totp string
+	var custom []secrets.SecretField
+	for _, field := range entry.Strings {
+		if strings.EqualFold(field.Value.Protected, "true") {
+			return ImportedEntry{}, ccc.NewInvalidInputErrorWithMessage(
+				"import file",
+				"KeePass XML contains encrypted values",
+				"The file contains encrypted values. Export the database with KeePass as \"KeePass XML (2.x)\" and import that file.",
+			)
+		}
+
+		value := strings.TrimSpace(field.Value.Text)
+		switch field.Key {
+		case "Title":
+			name = value
+		case "UserName":
+			username = value
+		case "Password":
+			password = value
+		case "URL":
+			url = value
+		case "Notes":
+			notes = value
+		// KeePassXC stores an otpauth URI, KeePass 2.47 and later a base32 seed
+		case "otp", "TimeOtp-Secret-Base32":
+			totp = value
+		default:
+			custom = append(custom, secrets.SecretField{
+				Name:      field.Key,
+				Value:     value,
+				Concealed: strings.EqualFold(field.Value.ProtectInMemory, "true"),
+			})
+		}
+	}
+
+	imported := newLoginEntry(name, username, password, url, notes)
+	imported.addTotpField(totp)
+	for _, field := range custom {
+		imported.addField(field.Name, field.Value, field.Concealed)
+	}
+	return imported, nil
+}
@@ -0,0 +1,111 @@
+package secretimport
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// Format identifies the export format of a password manager
+type Format string
+
+const (
+	FormatBitwardenJson  Format = "bitwarden-json"
+	FormatBitwardenCsv   Format = "bitwarden-csv"
+	FormatKeePassXml     Format = "keepass-xml"
+	FormatOnePasswordCsv Format = "1password-csv"
+)
+
+// SupportedFormats lists the formats that can be imported, in the order they are offered to users
+var SupportedFormats = []Format{
+	FormatBitwardenJson,
+	FormatBitwardenCsv,
+	FormatKeePassXml,
+	FormatOnePasswordCsv,
+}
+
+// DisplayName returns the name of the format shown to users
+func (f Format) DisplayName() string {
+	switch f {
+	case FormatBitwardenJson:
+		return "Bitwarden (JSON)"
+	case FormatBitwardenCsv:
+		return "Bitwarden (CSV)"
+	case FormatKeePassXml:
+		return "KeePass (XML)"
+	case FormatOnePasswordCsv:
+		return "1Password (CSV)"
+	default:
+		return string(f)
+	}
+}
+
+// ParseFormat parses the name of an import format
+func ParseFormat(name string) (Format, error) {
+	format := Format(strings.ToLower(strings.TrimSpace(name)))
+	for _, supported := range SupportedFormats {
+		if format == supported {
+			return format, nil
+		}
+	}
+
+	if format == "keepass-kdbx" || format == "kdbx" {
+		return "", ccc.NewInvalidInputErrorWithMessage(
+			"import format",
+			"KeePass databases cannot be imported directly",
+			"KeePass databases (.kdbx) cannot be imported directly. Export the database as KeePass XML (2.x) and import that file.",
+		)
+	}
+
+	return "", ccc.NewInvalidInputErrorWithMessage(
+		"import format",
+		fmt.Sprintf("unknown import format %q", name),
+		"Unknown import format. Supported formats are bitwarden-json, bitwarden-csv, keepass-xml and 1password-csv.",
+	)
+}
+
+// ImportedEntry is an entry of an export file with the fields of the secret it becomes
+type ImportedEntry struct {
+	Name   string
+	Type   secrets.SecretType
+	Fields []secrets.SecretField
+}
+
+// addField appends a field to the entry unless its value is empty
+func (e *ImportedEntry) addField(name, value string, concealed bool) {
+	value = strings.TrimSpace(value)
+	if value != "" {
+		e.Fields = append(e.Fields, secrets.SecretField{Name: name, Value: value, Concealed: concealed})
+	}
+}
+
+// addTotpField appends the one-time password seed of a login. Seeds that codes cannot be generated
+// from, e.g. those of proprietary schemes, are kept as a concealed text field.
+func (e *ImportedEntry) addTotpField(value string) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return
+	}
+	field := secrets.SecretField{Name: secrets.TotpFieldName, Value: value, Concealed: true}
+	if secrets.ValidateTotpSeed(value) == nil {
+		field.Kind = secrets.FieldKindTotp
+	}
+	e.Fields = append(e.Fields, field)
+}
+
+// newLoginEntry creates the entry of a login. Entries that only have notes become secure notes.
+func newLoginEntry(name, username, password, url, notes string) ImportedEntry {
+	entry := ImportedEntry{Name: name, Type: secrets.SecretTypeLogin}
+	if strings.TrimSpace(username+password+url) == "" && strings.TrimSpace(notes) != "" {
+		entry.Type = secrets.SecretTypeNote
+		entry.addField("Note", notes, true)
+		return entry
+	}
+	entry.addField("Username", username, false)
+	entry.addField("Password", password, true)
+	entry.addField("URL", url, false)
+	entry.addField("Notes", notes, false)
+	return entry
+}
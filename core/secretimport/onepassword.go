@@ -0,0 +1,30 @@
+package secretimport
+
+import (
+	"errors"
+	"io"
+)
+
+// onePasswordCsvParser reads CSV exports of 1Password. 1Password 8 writes the columns
+// Title, Url, Username, Password, OTPAuth, Favorite, Archived, Tags and Notes; older
+// versions let users pick the columns, so common alternative names are accepted as well.
+type onePasswordCsvParser struct{}
+
+func (p onePasswordCsvParser) Parse(src io.Reader) ([]ImportedEntry, error) {
+	table, err := readCsvTable(src)
+	if err != nil {
+		return nil, err
+	}
+	if !table.hasColumns("password") || !(table.hasColumns("title") || table.hasColumns("name")) {
+		return nil, errors.New("file does not have the columns of a 1Password CSV export")
+	}
+
+	entries := make([]ImportedEntry, 0, len(table.rows))
+	for _, row := range table.rows {
+		entry := newLoginEntry(table.value(row, "title", "name"), table.value(row, "username"), table.value(row, "password"),
+			table.value(row, "url", "website", "urls"), table.value(row, "notes", "notesplain"))
+		entry.addTotpField(table.value(row, "otpauth"))
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
@@ -0,0 +1,154 @@
+package secretimport
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+func parseEntries(t *testing.T, parser EntryParser, input string) []ImportedEntry {
+	t.Helper()
+	entries, err := parser.Parse(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	return entries
+}
+
+func expectEntry(t *testing.T, got ImportedEntry, name string, secretType secrets.SecretType, fields ...secrets.SecretField) {
+	t.Helper()
+	want := ImportedEntry{Name: name, Type: secretType, Fields: fields}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unexpected entry\n got %+v\nwant %+v", got, want)
+	}
+}
+
+func plain(name, value string) secrets.SecretField {
+	return secrets.SecretField{Name: name, Value: value}
+}
+
+func concealed(name, value string) secrets.SecretField {
+	return secrets.SecretField{Name: name, Value: value, Concealed: true}
+}
+
+func TestBitwardenJsonParser(t *testing.T) {
+	entries := parseEntries(t, bitwardenJsonParser{}, `{
+		"encrypted": false,
+		"folders": [{"id": "f1", "name": "Work"}],
+		"items": [
+			{"type": 1, "name": "GitHub", "notes": null, "folderId": "f1",
+			 "login": {"username": "octo", "password": "hunter2", "uris": [{"match": null, "uri": "https://github.com"}]}},
+			{"type": 2, "name": "Alarm code", "notes": "1234", "secureNote": {"type": 0}},
+			{"type": 3, "name": "Visa", "notes": null,
+			 "card": {"cardholderName": "Jane Doe", "brand": "Visa", "number": "4111111111111111", "expMonth": "7", "expYear": "2030", "code": "123"},
+			 "fields": [{"name": "PIN", "value": "0000", "type": 1}, {"name": "Bank", "value": "ACME", "type": 0}]}
+		]
+	}`)
+
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 entries, got %d", len(entries))
+	}
+	expectEntry(t, entries[0], "GitHub", secrets.SecretTypeLogin,
+		plain("Username", "octo"), concealed("Password", "hunter2"), plain("URL", "https://github.com"))
+	expectEntry(t, entries[1], "Alarm code", secrets.SecretTypeNote, concealed("Note", "1234"))
+	expectEntry(t, entries[2], "Visa", secrets.SecretTypeCard,
+		plain("Cardholder", "Jane Doe"), concealed("Number", "4111111111111111"), plain("Expiry", "7/2030"),
+		concealed("Security code", "123"), plain("Brand", "Visa"), concealed("PIN", "0000"), plain("Bank", "ACME"))
+
+	_, err := bitwardenJsonParser{}.Parse(strings.NewReader(`{"encrypted": true, "encKeyValidation_DO_NOT_EDIT": "x"}`))
+	if _, ok := ccc.IsApiError(err); !ok {
+		t.Errorf("expected encrypted exports to be rejected with a user message, got %v", err)
+	}
+}
+
+func TestCsvParsers(t *testing.T) {
+	bitwarden := "\xef\xbb\xbffolder,favorite,type,name,notes,fields,reprompt,login_uri,login_username,login_password,login_totp\n" +
+		"Work,,login,GitHub,,\"Team: core\",0,\"https://github.com,https://api.github.com\",octo,hunter2,JBSWY3DPEHPK3PXP\n" +
+		",,note,\"Alarm, front door\",1234,,0,,,,\n"
+	entries := parseEntries(t, bitwardenCsvParser{}, bitwarden)
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	expectEntry(t, entries[0], "GitHub", secrets.SecretTypeLogin,
+		plain("Username", "octo"), concealed("Password", "hunter2"), plain("URL", "https://github.com"),
+		secrets.SecretField{Name: secrets.TotpFieldName, Value: "JBSWY3DPEHPK3PXP", Concealed: true, Kind: secrets.FieldKindTotp}, plain("Team", "core"))
+	expectEntry(t, entries[1], "Alarm, front door", secrets.SecretTypeNote, concealed("Note", "1234"))
+
+	onePassword := "Title,Url,Username,Password,OTPAuth,Favorite,Archived,Tags,Notes\n" +
+		"Bank,https://bank.example,jane,s3cret,,false,false,,\"PIN\nis 0000\"\n"
+	entries = parseEntries(t, onePasswordCsvParser{}, onePassword)
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 entry, got %d", len(entries))
+	}
+	expectEntry(t, entries[0], "Bank", secrets.SecretTypeLogin,
+		plain("Username", "jane"), concealed("Password", "s3cret"), plain("URL", "https://bank.example"), plain("Notes", "PIN\nis 0000"))
+
+	if _, err := (onePasswordCsvParser{}).Parse(strings.NewReader(bitwarden)); err == nil {
+		t.Errorf("expected a Bitwarden export to be rejected as 1Password export")
+	}
+}
+
+func TestKeePassXmlParser(t *testing.T) {
+	entries := parseEntries(t, keePassXmlParser{}, `<?xml version="1.0" encoding="utf-8" standalone="yes"?>
+<KeePassFile>
+	<Meta><RecycleBinUUID>cmVjeWNsZWJpbjAwMDAwMA==</RecycleBinUUID></Meta>
+	<Root>
+		<Group>
+			<UUID>cm9vdDAwMDAwMDAwMDAwMA==</UUID>
+			<Name>Database</Name>
+			<Entry>
+				<String><Key>Title</Key><Value>Router</Value></String>
+				<String><Key>UserName</Key><Value>admin</Value></String>
+				<String><Key>Password</Key><Value ProtectInMemory="True">correct horse</Value></String>
+				<String><Key>WiFi key</Key><Value ProtectInMemory="True">letmein</Value></String>
+				<History>
+					<Entry><String><Key>Title</Key><Value>Old router</Value></String></Entry>
+				</History>
+			</Entry>
+			<Group>
+				<UUID>bmVzdGVkMDAwMDAwMDAwMA==</UUID>
+				<Name>Servers</Name>
+				<Entry><String><Key>Title</Key><Value>SSH</Value></String><String><Key>Notes</Key><Value>key in safe</Value></String></Entry>
+			</Group>
+			<Group>
+				<UUID>cmVjeWNsZWJpbjAwMDAwMA==</UUID>
+				<Name>Recycle Bin</Name>
+				<Entry><String><Key>Title</Key><Value>Deleted</Value></String></Entry>
+			</Group>
+		</Group>
+	</Root>
+</KeePassFile>`)
+
+	if len(entries) != 2 {
+		t.Fatalf("expected history and recycle bin to be skipped, got %+v", entries)
+	}
+	expectEntry(t, entries[0], "Router", secrets.SecretTypeLogin,
+		plain("Username", "admin"), concealed("Password", "correct horse"), concealed("WiFi key", "letmein"))
+	expectEntry(t, entries[1], "SSH", secrets.SecretTypeNote, concealed("Note", "key in safe"))
+
+	_, err := keePassXmlParser{}.Parse(strings.NewReader(`<KeePassFile><Root><Group><Entry>
+		<String><Key>Password</Key><Value Protected="True">c2VjcmV0</Value></String>
+	</Entry></Group></Root></KeePassFile>`))
+	if _, ok := ccc.IsApiError(err); !ok {
+		t.Errorf("expected encrypted values to be rejected with a user message, got %v", err)
+	}
+}
+
+func TestImportInputValidation(t *testing.T) {
+	reader := &limitedReader{src: strings.NewReader("0123456789"), remaining: 4}
+	_, err := onePasswordCsvParser{}.Parse(reader)
+	if !errors.Is(err, errImportFileTooLarge) {
+		t.Errorf("expected errImportFileTooLarge, got %v", err)
+	}
+
+	if _, err := ParseFormat("kdbx"); err == nil {
+		t.Errorf("expected KDBX files to be rejected")
+	}
+	if format, err := ParseFormat("1Password-CSV"); err != nil || format != FormatOnePasswordCsv {
+		t.Errorf("unexpected format %q (%v)", format, err)
+	}
+}
@@ -1,17 +1,72 @@
 package secrets
 
+import "time"
+
 type SecretDto struct {
-	Id         string
-	UserId     string
-	Name       string
-	Value      string
+	Id     string
+	UserId string
+	Name   string
+	Type   SecretType
+	Fields []SecretField
+	// Value is the primary value of the secret, see PrimaryValue
+	Value string
+	// Totp is the current one-time password of the secret, nil if it has no one-time password field
+	Totp       *TotpCodeDto
 	CreatedAt  string
 	ModifiedAt string
+	// ExpiresAt is when the credential held by the secret expires, nil if it does not expire
+	ExpiresAt *time.Time
+	// RotateEvery is the number of days after which the value should be rotated, 0 = no rotation
+	RotateEvery int
+	// RotationDueAt is when the value should be rotated next, nil if it is not rotated regularly
+	RotationDueAt *time.Time
+}
+
+// TotpCodeDto is a one-time password generated from the seed of a secret field
+type TotpCodeDto struct {
+	FieldName string
+	Code      string
+	// Period is the number of seconds a code is valid
+	Period int
+	// SecondsRemaining is the number of seconds until the next code
+	SecondsRemaining int
+}
+
+// SecretVersionDto is a decrypted prior version of a secret
+type SecretVersionDto struct {
+	Id       string
+	SecretId string
+	Name     string
+	Type     SecretType
+	Fields   []SecretField
+	// Value is the primary value of the version, see PrimaryValue
+	Value      string
+	ModifiedAt string
+	ReplacedAt string
+}
+
+// TrashedSecretDto is a secret in the trash
+type TrashedSecretDto struct {
+	Id        string
+	Name      string
+	Type      SecretType
+	DeletedAt time.Time
 }
 
 type UpsertSecretRequest struct {
-	SecretName  string
+	SecretName string
+	// SecretValue is stored in the first concealed field of the type if no Fields are given.
+	// When updating a secret without Fields, only its primary value is replaced.
 	SecretValue string
+	// SecretType defaults to SecretTypeGeneric
+	SecretType SecretType
+	// Fields are stored in the given order. Fields without a value are dropped.
+	Fields []SecretField
+	// ExpiresAt is when the credential held by the secret expires, nil if it does not expire.
+	// Like the other fields, the expiry and rotation interval are kept when only the primary value is updated.
+	ExpiresAt *time.Time
+	// RotateEvery is the number of days after which the value should be rotated, 0 = no rotation
+	RotateEvery int
 }
 
 type CreateSecretResponse struct {
@@ -29,6 +84,55 @@ type GetSecretsRequest struct {
 	Name     string
 	PageSize int
 	Page     int
-	SortBy   string
-	SortAsc  bool
+	// SortBy is Name, CreatedAt, ModifiedAt or ExpiresAt. ExpiresAt sorts by the date a secret expires or is
+	// due for rotation, whichever comes first; secrets without either come last in both directions.
+	SortBy  string
+	SortAsc bool
+	// ExpiringWithinDays limits the result to secrets that expire or are due for rotation within the given
+	// number of days, including those that are overdue. 0 disables the filter.
+	ExpiringWithinDays int
+}
+
+// SecretReminder is an expiry or rotation date of a secret. It holds no encrypted data, so that reminders
+// can be created in the background without the key of the user.
+type SecretReminder struct {
+	SecretId string
+	UserId   string
+	Kind     ReminderKind
+	DueAt    time.Time
+}
+
+// ImportSecretsRequest describes a set of secrets to create at once, e.g. from another password manager
+type ImportSecretsRequest struct {
+	Secrets    []UpsertSecretRequest
+	OnConflict ConflictPolicy
+	// BatchSize is the number of secrets stored per transaction. Zero selects the default of 100.
+	BatchSize int
+}
+
+// SkippedSecret describes an imported secret that was not stored
+type SkippedSecret struct {
+	Name   string
+	Reason string
+}
+
+// ImportSecretsResponse reports the outcome of an import. Only secrets of committed batches are counted.
+type ImportSecretsResponse struct {
+	Created     int
+	Overwritten int
+	Renamed     []RenamedSecret
+	Skipped     []SkippedSecret
+}
+
+// RenamedSecret describes a secret that was stored under a new name because its name was taken
+type RenamedSecret struct {
+	OriginalName string
+	Name         string
+}
+
+// NameIndexReport reports the outcome of indexing the names of secrets stored before blind indexing was introduced
+type NameIndexReport struct {
+	Indexed   int
+	Renamed   []RenamedSecret // Secrets renamed because another secret has the same name
+	Unindexed []string        // IDs of secrets that could not be indexed, e.g. because their name cannot be decrypted
 }
@@ -1,6 +1,9 @@
 package secrets
 
 import (
+	"encoding/json"
+	"fmt"
+	"slices"
 	"sort"
 	"strings"
 	"time"
@@ -10,14 +13,30 @@ import (
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
 )
 
+const (
+	maxSecretNameLength       = 200
+	maxSecretFieldCount       = 50
+	maxSecretFieldNameLength  = 100
+	maxSecretFieldValueLength = 10000
+	maxRotateEveryDays        = 3650
+
+	// defaultImportBatchSize is the number of imported secrets stored per transaction
+	defaultImportBatchSize = 100
+	// maxRenameAttempts limits the search for a free name of a renamed secret
+	maxRenameAttempts = 100
+)
+
 type DefaultSecretManager struct {
 	secretRepository  SecretRepository
 	secretIdGenerator SecretIdGenerator
 	userRepository    auth.UserRepository
+	historyLimit      int
 	logger            ccc.Logger
 }
 
-func NewDefaultSecretManager(secretRepository SecretRepository, secretIdGenerator SecretIdGenerator, userRepository auth.UserRepository, logger ccc.Logger) *DefaultSecretManager {
+// NewDefaultSecretManager creates a secret manager that keeps the historyLimit newest prior versions
+// of each secret. A historyLimit of 0 disables the version history.
+func NewDefaultSecretManager(secretRepository SecretRepository, secretIdGenerator SecretIdGenerator, userRepository auth.UserRepository, historyLimit int, logger ccc.Logger) *DefaultSecretManager {
 	if logger == nil {
 		logger = ccc.NopLogger
 	}
@@ -26,17 +45,56 @@ func NewDefaultSecretManager(secretRepository SecretRepository, secretIdGenerato
 		secretRepository:  secretRepository,
 		secretIdGenerator: secretIdGenerator,
 		userRepository:    userRepository,
+		historyLimit:      max(historyLimit, 0),
 		logger:            logger,
 	}
 }
 
-// validateSecretRequest validates the secret name and value lengths
-func (m *DefaultSecretManager) validateSecretRequest(request UpsertSecretRequest) error {
-	const (
-		maxSecretNameLength  = 200
-		maxSecretValueLength = 1000
-	)
+// normalizeSecretRequest trims the name and fields of a request, fills in the default type and drops
+// fields without a value. A request without fields gets the default fields of its type, with SecretValue
+// as the value of the first concealed one.
+func normalizeSecretRequest(request UpsertSecretRequest) (UpsertSecretRequest, error) {
+	secretType, err := ParseSecretType(string(request.SecretType))
+	if err != nil {
+		return request, err
+	}
+
+	fields := request.Fields
+	if len(fields) == 0 {
+		fields = secretType.DefaultFields()
+		for i := range fields {
+			if fields[i].Concealed {
+				fields[i].Value = request.SecretValue
+				break
+			}
+		}
+	}
+
+	normalized := make([]SecretField, 0, len(fields))
+	for _, field := range fields {
+		field.Name = strings.TrimSpace(field.Name)
+		field.Value = strings.TrimSpace(field.Value)
+		// One-time password seeds are as sensitive as passwords
+		if field.Kind == FieldKindTotp {
+			field.Concealed = true
+			if field.Name == "" {
+				field.Name = TotpFieldName
+			}
+		}
+		if field.Value != "" {
+			normalized = append(normalized, field)
+		}
+	}
+
+	request.SecretName = strings.TrimSpace(request.SecretName)
+	request.SecretType = secretType
+	request.Fields = normalized
+	request.SecretValue = PrimaryValue(normalized)
+	return request, nil
+}
 
+// validateSecretRequest validates the name and fields of a normalized secret request
+func (m *DefaultSecretManager) validateSecretRequest(request UpsertSecretRequest) error {
 	if request.SecretName == "" {
 		return ccc.NewInvalidInputError("secret name", "cannot be empty")
 	}
@@ -47,25 +105,129 @@ func (m *DefaultSecretManager) validateSecretRequest(request UpsertSecretRequest
 			"Secret name cannot be longer than 200 characters",
 		)
 	}
-	if request.SecretValue == "" {
+	if len(request.Fields) == 0 {
 		return ccc.NewInvalidInputError("secret value", "cannot be empty")
 	}
-	if len(request.SecretValue) > maxSecretValueLength {
+	if len(request.Fields) > maxSecretFieldCount {
+		return ccc.NewInvalidInputErrorWithMessage(
+			"secret fields",
+			"exceeds maximum of 50 fields",
+			"A secret cannot have more than 50 fields",
+		)
+	}
+	totpFields := 0
+	for _, field := range request.Fields {
+		switch field.Kind {
+		case "":
+		case FieldKindTotp:
+			totpFields++
+			if err := ValidateTotpSeed(field.Value); err != nil {
+				return ccc.NewInvalidInputErrorWithMessage(
+					"secret field value",
+					err.Error(),
+					fmt.Sprintf("Field '%s' must hold an otpauth:// URI or a base32 seed of a time-based one-time password", field.Name),
+				)
+			}
+		default:
+			return ccc.NewInvalidInputError("secret field kind", fmt.Sprintf("unknown kind %q", field.Kind))
+		}
+		if field.Name == "" {
+			return ccc.NewInvalidInputErrorWithMessage("secret field name", "cannot be empty", "Every field needs a name")
+		}
+		if len(field.Name) > maxSecretFieldNameLength {
+			return ccc.NewInvalidInputErrorWithMessage(
+				"secret field name",
+				"exceeds maximum length of 100 characters",
+				"Field names cannot be longer than 100 characters",
+			)
+		}
+		if len(field.Value) > maxSecretFieldValueLength {
+			return ccc.NewInvalidInputErrorWithMessage(
+				"secret field value",
+				"exceeds maximum length of 10000 characters",
+				fmt.Sprintf("The value of field '%s' cannot be longer than 10000 characters", field.Name),
+			)
+		}
+	}
+	if totpFields > 1 {
+		return ccc.NewInvalidInputErrorWithMessage(
+			"secret fields",
+			"more than one one-time password field",
+			"A secret can only have one one-time password field",
+		)
+	}
+	if request.RotateEvery < 0 || request.RotateEvery > maxRotateEveryDays {
 		return ccc.NewInvalidInputErrorWithMessage(
-			"secret value",
-			"exceeds maximum length of 1000 characters",
-			"Secret value cannot be longer than 1000 characters",
+			"rotation interval",
+			fmt.Sprintf("must be between 0 and %d days", maxRotateEveryDays),
+			"The rotation interval must be between 0 and 3650 days",
 		)
 	}
 	return nil
 }
 
+// protectSecretFields encrypts the payload holding the fields of a secret
+func protectSecretFields(fields []SecretField, dataProtector dataprotection.DataProtector) (string, error) {
+	payload, err := json.Marshal(secretPayload{Fields: fields})
+	if err != nil {
+		return "", err
+	}
+	return dataProtector.Protect(string(payload))
+}
+
+// secretFields returns the fields of a secret from its decrypted value
+func secretFields(secret *Secret, decryptedValue string) (SecretType, []SecretField, error) {
+	// Secrets without a type hold a single plain value
+	if secret.Type == "" {
+		return SecretTypeGeneric, []SecretField{{Name: "Value", Value: decryptedValue, Concealed: true}}, nil
+	}
+
+	var payload secretPayload
+	if err := json.Unmarshal([]byte(decryptedValue), &payload); err != nil {
+		return "", nil, fmt.Errorf("decoding fields of secret %s: %w", secret.Id, err)
+	}
+	return secret.Type, payload.Fields, nil
+}
+
+// newSecretDto maps a secret with its decrypted name and value to a DTO
+func newSecretDto(secret *Secret, decryptedName, decryptedValue string) (*SecretDto, error) {
+	secretType, fields, err := secretFields(secret, decryptedValue)
+	if err != nil {
+		return nil, err
+	}
+
+	dto := &SecretDto{
+		Id:         secret.Id,
+		UserId:     secret.UserId,
+		Name:       decryptedName,
+		Type:       secretType,
+		Fields:     fields,
+		Value:      PrimaryValue(fields),
+		CreatedAt:  secret.CreatedAt.Format("2006-01-02 15:04:05"),
+		ModifiedAt: secret.ModifiedAt.Format("2006-01-02 15:04:05"),
+
+		ExpiresAt:     secret.ExpiresAt,
+		RotateEvery:   secret.RotateEvery,
+		RotationDueAt: secret.RotationDueAt(),
+	}
+
+	// Seeds are validated when they are stored, so a failure leaves the secret without a code
+	if field := totpField(fields); field != nil {
+		dto.Totp, _ = generateTotpCode(*field, time.Now())
+	}
+
+	return dto, nil
+}
+
 func (m *DefaultSecretManager) CreateSecret(userId string, request UpsertSecretRequest, dataProtector dataprotection.DataProtector) (CreateSecretResponse, error) {
-	m.logger.Info("Creating secret", "user_id", userId, "secret_name", request.SecretName)
+	m.logger.Info("Creating secret", "user_id", userId, "secret_name", request.SecretName, "secret_type", request.SecretType)
 
-	// Trim whitespace from input
-	request.SecretName = strings.TrimSpace(request.SecretName)
-	request.SecretValue = strings.TrimSpace(request.SecretValue)
+	// Trim whitespace from input and apply the defaults of the secret type
+	request, err := normalizeSecretRequest(request)
+	if err != nil {
+		m.logger.Warn("Secret creation failed: invalid secret type", "user_id", userId, "error", err)
+		return CreateSecretResponse{}, err
+	}
 
 	// Validate the request
 	if err := m.validateSecretRequest(request); err != nil {
@@ -111,11 +273,16 @@ func (m *DefaultSecretManager) CreateSecret(userId string, request UpsertSecretR
 		m.logger.Error("Failed to encrypt secret name", "user_id", userId, "secret_name", request.SecretName, "error", err)
 		return CreateSecretResponse{}, ccc.NewInternalError("failed to encrypt secret name", err)
 	}
-	encryptedValue, err := dataProtector.Protect(request.SecretValue)
+	encryptedValue, err := protectSecretFields(request.Fields, dataProtector)
 	if err != nil {
 		m.logger.Error("Failed to encrypt secret value", "user_id", userId, "secret_name", request.SecretName, "error", err)
 		return CreateSecretResponse{}, ccc.NewInternalError("failed to encrypt secret value", err)
 	}
+	nameIndex, err := dataProtector.BlindIndex(request.SecretName)
+	if err != nil {
+		m.logger.Error("Failed to compute secret name index", "user_id", userId, "secret_name", request.SecretName, "error", err)
+		return CreateSecretResponse{}, ccc.NewInternalError("failed to compute secret name index", err)
+	}
 
 	m.logger.Debug("Secret data encrypted successfully", "user_id", userId, "secret_id", secretId)
 
@@ -123,12 +290,17 @@ func (m *DefaultSecretManager) CreateSecret(userId string, request UpsertSecretR
 
 	// Create a new secret object
 	secret := &Secret{
-		Id:         secretId,
-		UserId:     userId,
-		Name:       encryptedName,
-		Value:      encryptedValue,
-		CreatedAt:  now,
-		ModifiedAt: now,
+		Id:          secretId,
+		UserId:      userId,
+		Name:        encryptedName,
+		Value:       encryptedValue,
+		Type:        request.SecretType,
+		NameIndex:   nameIndex,
+		CreatedAt:   now,
+		ModifiedAt:  now,
+		ExpiresAt:   request.ExpiresAt,
+		RotateEvery: request.RotateEvery,
+		RotatedAt:   now,
 	}
 
 	// Add the secret to the repository
@@ -173,13 +345,10 @@ func (m *DefaultSecretManager) GetSecret(userId string, secretId string, dataPro
 	m.logger.Debug("Secret retrieved and decrypted successfully", "user_id", userId, "secret_id", secretId, "secret_name", decryptedName)
 
 	// Map the secret to a DTO
-	secretDto := &SecretDto{
-		Id:         secret.Id,
-		UserId:     secret.UserId,
-		Name:       decryptedName,
-		Value:      decryptedValue,
-		CreatedAt:  secret.CreatedAt.Format("2006-01-02 15:04:05"),
-		ModifiedAt: secret.ModifiedAt.Format("2006-01-02 15:04:05"),
+	secretDto, err := newSecretDto(secret, decryptedName, decryptedValue)
+	if err != nil {
+		m.logger.Error("Failed to decode secret fields", "user_id", userId, "secret_id", secretId, "error", err)
+		return nil, ccc.NewInternalError("failed to decode secret fields", err)
 	}
 
 	return secretDto, nil
@@ -189,15 +358,15 @@ func (m *DefaultSecretManager) GetSecret(userId string, secretId string, dataPro
 func (m *DefaultSecretManager) GetSecretByName(userId string, secretName string, dataProtector dataprotection.DataProtector) (*SecretDto, error) {
 	m.logger.Debug("Retrieving secret by name", "user_id", userId, "secret_name", secretName)
 
-	// Encrypt the secret name to search for it in the repository
-	encryptedName, err := dataProtector.Protect(secretName)
+	// Compute the blind index of the name to search for it in the repository
+	nameIndex, err := dataProtector.BlindIndex(secretName)
 	if err != nil {
-		m.logger.Error("Failed to encrypt secret name for lookup", "user_id", userId, "secret_name", secretName, "error", err)
-		return nil, ccc.NewInternalError("failed to encrypt secret name for lookup", err)
+		m.logger.Error("Failed to compute secret name index for lookup", "user_id", userId, "secret_name", secretName, "error", err)
+		return nil, ccc.NewInternalError("failed to compute secret name index for lookup", err)
 	}
 
-	// Retrieve the secret from the repository using the encrypted name
-	secret, err := m.secretRepository.FindByNameForUser(userId, encryptedName)
+	// Retrieve the secret from the repository using the name index
+	secret, err := m.secretRepository.FindByNameIndexForUser(userId, nameIndex)
 	if err != nil {
 		m.logger.Error("Failed to find secret by name", "user_id", userId, "secret_name", secretName, "error", err)
 		return nil, ccc.NewDatabaseError("find secret by name", err)
@@ -217,14 +386,11 @@ func (m *DefaultSecretManager) GetSecretByName(userId string, secretName string,
 
 	m.logger.Debug("Secret retrieved by name and decrypted successfully", "user_id", userId, "secret_name", secretName)
 
-	// Map the secret to a DTO
-	secretDto := &SecretDto{
-		Id:         secret.Id,
-		UserId:     secret.UserId,
-		Name:       secretName, // Use the original, unencrypted name for the DTO
-		Value:      decryptedValue,
-		CreatedAt:  secret.CreatedAt.Format("2006-01-02 15:04:05"),
-		ModifiedAt: secret.ModifiedAt.Format("2006-01-02 15:04:05"),
+	// Map the secret to a DTO, using the original, unencrypted name
+	secretDto, err := newSecretDto(secret, secretName, decryptedValue)
+	if err != nil {
+		m.logger.Error("Failed to decode secret fields", "user_id", userId, "secret_name", secretName, "error", err)
+		return nil, ccc.NewInternalError("failed to decode secret fields", err)
 	}
 
 	return secretDto, nil
@@ -243,10 +409,22 @@ func (m *DefaultSecretManager) GetSecrets(userId string, request GetSecretsReque
 
 	m.logger.Debug("Retrieved secrets from repository", "user_id", userId, "total_secrets", len(allSecrets))
 
+	// Secrets that are due after this time are left out if the request asks for expiring secrets
+	var dueBefore time.Time
+	if request.ExpiringWithinDays > 0 {
+		dueBefore = time.Now().AddDate(0, 0, request.ExpiringWithinDays)
+	}
+
 	// Decrypt names and filter in memory
 	var filteredSecrets []*Secret
 	decryptionErrors := 0
 	for _, secret := range allSecrets {
+		if !dueBefore.IsZero() {
+			if dueAt := secret.DueAt(); dueAt == nil || dueAt.After(dueBefore) {
+				continue
+			}
+		}
+
 		// Decrypt the name to check if it matches the filter
 		decryptedName, err := dataProtector.Unprotect(secret.Name)
 		if err != nil {
@@ -304,14 +482,14 @@ func (m *DefaultSecretManager) GetSecrets(userId string, request GetSecretsReque
 			continue
 		}
 
-		secretDtos = append(secretDtos, &SecretDto{
-			Id:         secret.Id,
-			UserId:     secret.UserId,
-			Name:       secret.Name, // Already decrypted above
-			Value:      decryptedValue,
-			CreatedAt:  secret.CreatedAt.Format("2006-01-02 15:04:05"),
-			ModifiedAt: secret.ModifiedAt.Format("2006-01-02 15:04:05"),
-		})
+		secretDto, err := newSecretDto(secret, secret.Name, decryptedValue) // Name already decrypted above
+		if err != nil {
+			// Skip secrets whose fields can't be decoded, like those we can't decrypt
+			valueDecryptionErrors++
+			m.logger.Warn("Failed to decode secret fields during DTO conversion, skipping", "user_id", userId, "secret_id", secret.Id, "error", err)
+			continue
+		}
+		secretDtos = append(secretDtos, secretDto)
 	}
 
 	if valueDecryptionErrors > 0 {
@@ -358,6 +536,17 @@ func (m *DefaultSecretManager) sortSecrets(secrets []*Secret, sortBy string, sor
 			} else {
 				comparison = 0
 			}
+		case "ExpiresAt":
+			dueAtI, dueAtJ := secrets[i].DueAt(), secrets[j].DueAt()
+			switch {
+			case dueAtI == nil || dueAtJ == nil:
+				// Secrets without a due date come last regardless of the direction
+				return dueAtI != nil && dueAtJ == nil
+			case dueAtI.Before(*dueAtJ):
+				comparison = -1
+			case dueAtI.After(*dueAtJ):
+				comparison = 1
+			}
 		default:
 			// Default sort by CreatedAt desc
 			if secrets[i].CreatedAt.After(secrets[j].CreatedAt) {
@@ -377,20 +566,33 @@ func (m *DefaultSecretManager) sortSecrets(secrets []*Secret, sortBy string, sor
 	})
 }
 
+// GetSecretReminders returns the expiry and rotation dates of the secrets of all users that are due before
+// the given time, including those that have passed. Secrets in the trash are left out.
+func (m *DefaultSecretManager) GetSecretReminders(dueBefore time.Time) ([]*SecretReminder, error) {
+	dueSecrets, err := m.secretRepository.FindWithDueDates()
+	if err != nil {
+		m.logger.Error("Failed to find secrets with due dates", "error", err)
+		return nil, ccc.NewDatabaseError("find secrets with due dates", err)
+	}
+
+	var reminders []*SecretReminder
+	for _, secret := range dueSecrets {
+		if secret.ExpiresAt != nil && secret.ExpiresAt.Before(dueBefore) {
+			reminders = append(reminders, &SecretReminder{SecretId: secret.Id, UserId: secret.UserId, Kind: ReminderExpiry, DueAt: *secret.ExpiresAt})
+		}
+		if dueAt := secret.RotationDueAt(); dueAt != nil && dueAt.Before(dueBefore) {
+			reminders = append(reminders, &SecretReminder{SecretId: secret.Id, UserId: secret.UserId, Kind: ReminderRotation, DueAt: *dueAt})
+		}
+	}
+
+	m.logger.Debug("Found due secret reminders", "due_before", dueBefore, "reminder_count", len(reminders))
+	return reminders, nil
+}
+
 // UpdateSecret updates an existing secret
 func (m *DefaultSecretManager) UpdateSecret(userId string, secretId string, request UpsertSecretRequest, dataProtector dataprotection.DataProtector) (bool, error) {
 	m.logger.Info("Updating secret", "user_id", userId, "secret_id", secretId, "new_secret_name", request.SecretName)
 
-	// Trim whitespace from input
-	request.SecretName = strings.TrimSpace(request.SecretName)
-	request.SecretValue = strings.TrimSpace(request.SecretValue)
-
-	// Validate the request
-	if err := m.validateSecretRequest(request); err != nil {
-		m.logger.Warn("Secret update failed: validation error", "user_id", userId, "secret_id", secretId, "error", err)
-		return false, err
-	}
-
 	// Get the existing secret
 	existingSecret, err := m.secretRepository.FindByIdForUser(userId, secretId)
 	if err != nil {
@@ -402,6 +604,42 @@ func (m *DefaultSecretManager) UpdateSecret(userId string, secretId string, requ
 		return false, ccc.NewResourceNotFoundError(secretId, "Secret")
 	}
 
+	// A request with only a value, e.g. from the CLI or the API, replaces the primary value and keeps the other fields
+	if len(request.Fields) == 0 && request.SecretType == "" {
+		decryptedValue, err := dataProtector.Unprotect(existingSecret.Value)
+		if err != nil {
+			m.logger.Error("Failed to decrypt current secret value during update", "user_id", userId, "secret_id", secretId, "error", err)
+			return false, ccc.NewInternalError("failed to decrypt current secret value", err)
+		}
+		secretType, fields, err := secretFields(existingSecret, decryptedValue)
+		if err != nil {
+			m.logger.Error("Failed to decode current secret fields during update", "user_id", userId, "secret_id", secretId, "error", err)
+			return false, ccc.NewInternalError("failed to decode current secret fields", err)
+		}
+		if i := primaryFieldIndex(fields); i >= 0 {
+			fields[i].Value = request.SecretValue
+			request.SecretType = secretType
+			request.Fields = fields
+		}
+		if request.ExpiresAt == nil && request.RotateEvery == 0 {
+			request.ExpiresAt = existingSecret.ExpiresAt
+			request.RotateEvery = existingSecret.RotateEvery
+		}
+	}
+
+	// Trim whitespace from input and apply the defaults of the secret type
+	request, err = normalizeSecretRequest(request)
+	if err != nil {
+		m.logger.Warn("Secret update failed: invalid secret type", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, err
+	}
+
+	// Validate the request
+	if err := m.validateSecretRequest(request); err != nil {
+		m.logger.Warn("Secret update failed: validation error", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, err
+	}
+
 	// Decrypt the existing secret's name to check if it's being changed.
 	decryptedCurrentName, err := dataProtector.Unprotect(existingSecret.Name)
 	if err != nil {
@@ -433,20 +671,49 @@ func (m *DefaultSecretManager) UpdateSecret(userId string, secretId string, requ
 		m.logger.Error("Failed to encrypt new secret name during update", "user_id", userId, "secret_id", secretId, "new_name", request.SecretName, "error", err)
 		return false, ccc.NewInternalError("failed to encrypt secret name", err)
 	}
-	encryptedValue, err := dataProtector.Protect(request.SecretValue)
+	encryptedValue, err := protectSecretFields(request.Fields, dataProtector)
 	if err != nil {
 		m.logger.Error("Failed to encrypt new secret value during update", "user_id", userId, "secret_id", secretId, "error", err)
 		return false, ccc.NewInternalError("failed to encrypt secret value", err)
 	}
+	nameIndex, err := dataProtector.BlindIndex(request.SecretName)
+	if err != nil {
+		m.logger.Error("Failed to compute secret name index during update", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewInternalError("failed to compute secret name index", err)
+	}
 
 	m.logger.Debug("New secret data encrypted successfully", "user_id", userId, "secret_id", secretId)
 
+	// Record the prior state of the secret, unless it is saved without changes
+	valueChanged, err := secretValueChanged(existingSecret, request, dataProtector)
+	if err != nil {
+		m.logger.Error("Failed to decrypt current secret value during update", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewInternalError("failed to decrypt current secret value", err)
+	}
+	var version *SecretVersion
+	if m.historyLimit > 0 && (valueChanged || decryptedCurrentName != request.SecretName) {
+		version = m.newSecretVersion(existingSecret)
+	}
+
 	// Update the existing secret with new values
+	now := time.Now()
 	existingSecret.Name = encryptedName
 	existingSecret.Value = encryptedValue
-	existingSecret.ModifiedAt = time.Now()
+	existingSecret.Type = request.SecretType
+	existingSecret.NameIndex = nameIndex
+	existingSecret.ModifiedAt = now
+	existingSecret.ExpiresAt = request.ExpiresAt
+	existingSecret.RotateEvery = request.RotateEvery
+	if valueChanged {
+		existingSecret.RotatedAt = now
+	}
 	// Update the secret in the repository
-	success, err := m.secretRepository.Update(existingSecret)
+	var success bool
+	if version != nil {
+		success, err = m.secretRepository.UpdateWithVersion(existingSecret, version, m.historyLimit)
+	} else {
+		success, err = m.secretRepository.Update(existingSecret)
+	}
 	if err != nil {
 		m.logger.Error("Failed to update secret in repository", "user_id", userId, "secret_id", secretId, "error", err)
 		return false, ccc.NewDatabaseError("update secret", err)
@@ -460,9 +727,243 @@ func (m *DefaultSecretManager) UpdateSecret(userId string, secretId string, requ
 	return true, nil
 }
 
-// DeleteSecret deletes a secret by its ID
+// secretValueChanged reports whether an update request changes the type or fields of a secret, which
+// counts as a rotation of its value
+func secretValueChanged(secret *Secret, request UpsertSecretRequest, dataProtector dataprotection.DataProtector) (bool, error) {
+	decryptedValue, err := dataProtector.Unprotect(secret.Value)
+	if err != nil {
+		return false, err
+	}
+	secretType, fields, err := secretFields(secret, decryptedValue)
+	if err != nil {
+		return false, err
+	}
+	return secretType != request.SecretType || !slices.Equal(fields, request.Fields), nil
+}
+
+// newSecretVersion returns the current state of a secret as a prior version, which is replaced now.
+// The encrypted name and value are taken over as they are.
+func (m *DefaultSecretManager) newSecretVersion(secret *Secret) *SecretVersion {
+	return &SecretVersion{
+		Id:         m.secretIdGenerator.GenerateId(),
+		SecretId:   secret.Id,
+		UserId:     secret.UserId,
+		Name:       secret.Name,
+		Value:      secret.Value,
+		Type:       secret.Type,
+		ModifiedAt: secret.ModifiedAt,
+		ReplacedAt: time.Now(),
+	}
+}
+
+// GetSecretHistory returns the decrypted prior versions of a secret, newest first
+func (m *DefaultSecretManager) GetSecretHistory(userId string, secretId string, dataProtector dataprotection.DataProtector) ([]*SecretVersionDto, error) {
+	m.logger.Debug("Retrieving secret history", "user_id", userId, "secret_id", secretId)
+
+	secret, err := m.secretRepository.FindByIdForUser(userId, secretId)
+	if err != nil {
+		m.logger.Error("Failed to find secret for history", "user_id", userId, "secret_id", secretId, "error", err)
+		return nil, ccc.NewDatabaseError("find secret by ID", err)
+	}
+	if secret == nil {
+		m.logger.Warn("Secret not found for history", "user_id", userId, "secret_id", secretId)
+		return nil, ccc.NewResourceNotFoundError(secretId, "Secret")
+	}
+
+	versions, err := m.secretRepository.FindVersionsForUser(userId, secretId)
+	if err != nil {
+		m.logger.Error("Failed to find secret versions", "user_id", userId, "secret_id", secretId, "error", err)
+		return nil, ccc.NewDatabaseError("find secret versions", err)
+	}
+
+	history := make([]*SecretVersionDto, 0, len(versions))
+	for _, version := range versions {
+		dto, err := newSecretVersionDto(version, dataProtector)
+		if err != nil {
+			m.logger.Error("Failed to decrypt secret version", "user_id", userId, "secret_id", secretId, "version_id", version.Id, "error", err)
+			return nil, ccc.NewInternalError("failed to decrypt secret version", err)
+		}
+		history = append(history, dto)
+	}
+
+	return history, nil
+}
+
+// newSecretVersionDto decrypts a prior version of a secret
+func newSecretVersionDto(version *SecretVersion, dataProtector dataprotection.DataProtector) (*SecretVersionDto, error) {
+	decryptedName, err := dataProtector.Unprotect(version.Name)
+	if err != nil {
+		return nil, err
+	}
+	decryptedValue, err := dataProtector.Unprotect(version.Value)
+	if err != nil {
+		return nil, err
+	}
+	secretType, fields, err := secretFields(&Secret{Id: version.SecretId, Type: version.Type}, decryptedValue)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SecretVersionDto{
+		Id:         version.Id,
+		SecretId:   version.SecretId,
+		Name:       decryptedName,
+		Type:       secretType,
+		Fields:     fields,
+		Value:      PrimaryValue(fields),
+		ModifiedAt: version.ModifiedAt.Format("2006-01-02 15:04:05"),
+		ReplacedAt: version.ReplacedAt.Format("2006-01-02 15:04:05"),
+	}, nil
+}
+
+// RestoreSecretVersion replaces the name, type and fields of a secret with those of a prior version.
+// The restore is an update itself, so the replaced state is recorded as a version and can be restored in turn.
+func (m *DefaultSecretManager) RestoreSecretVersion(userId string, secretId string, versionId string, dataProtector dataprotection.DataProtector) (bool, error) {
+	m.logger.Info("Restoring secret version", "user_id", userId, "secret_id", secretId, "version_id", versionId)
+
+	version, err := m.secretRepository.FindVersionByIdForUser(userId, versionId)
+	if err != nil {
+		m.logger.Error("Failed to find secret version", "user_id", userId, "version_id", versionId, "error", err)
+		return false, ccc.NewDatabaseError("find secret version by ID", err)
+	}
+	if version == nil || version.SecretId != secretId {
+		m.logger.Warn("Secret version not found", "user_id", userId, "secret_id", secretId, "version_id", versionId)
+		return false, ccc.NewResourceNotFoundError(versionId, "Secret version")
+	}
+
+	dto, err := newSecretVersionDto(version, dataProtector)
+	if err != nil {
+		m.logger.Error("Failed to decrypt secret version", "user_id", userId, "version_id", versionId, "error", err)
+		return false, ccc.NewInternalError("failed to decrypt secret version", err)
+	}
+
+	// Versions do not record the expiry and rotation interval, which are kept as they are
+	secret, err := m.secretRepository.FindByIdForUser(userId, secretId)
+	if err != nil {
+		m.logger.Error("Failed to find secret for version restore", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewDatabaseError("find secret by ID", err)
+	}
+	if secret == nil {
+		return false, ccc.NewResourceNotFoundError(secretId, "Secret")
+	}
+
+	return m.UpdateSecret(userId, secretId, UpsertSecretRequest{
+		SecretName:  dto.Name,
+		SecretType:  dto.Type,
+		Fields:      dto.Fields,
+		ExpiresAt:   secret.ExpiresAt,
+		RotateEvery: secret.RotateEvery,
+	}, dataProtector)
+}
+
+// IndexSecretNames computes the name index of the secrets of a user that were stored before blind indexing
+// was introduced. An active secret whose name is taken by another secret is renamed, e.g. to "Mail (duplicate)",
+// so that both can be found by name. Secrets whose name cannot be decrypted are left as they are and reported.
+func (m *DefaultSecretManager) IndexSecretNames(userId string, dataProtector dataprotection.DataProtector) (*NameIndexReport, error) {
+	unindexedSecrets, err := m.secretRepository.FindWithoutNameIndexForUser(userId)
+	if err != nil {
+		m.logger.Error("Failed to find secrets without name index", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find secrets without name index", err)
+	}
+
+	report := &NameIndexReport{}
+	if len(unindexedSecrets) == 0 {
+		return report, nil
+	}
+
+	m.logger.Info("Indexing secret names", "user_id", userId, "secret_count", len(unindexedSecrets))
+
+	for _, secret := range unindexedSecrets {
+		decryptedName, err := dataProtector.Unprotect(secret.Name)
+		if err != nil {
+			m.logger.Warn("Failed to decrypt secret name, cannot index it", "user_id", userId, "secret_id", secret.Id, "error", err)
+			report.Unindexed = append(report.Unindexed, secret.Id)
+			continue
+		}
+
+		name, nameIndex, err := m.freeSecretName(userId, secret, decryptedName, dataProtector)
+		if err != nil {
+			return report, err
+		}
+		if nameIndex == "" {
+			m.logger.Warn("No free name found for secret, cannot index it", "user_id", userId, "secret_id", secret.Id)
+			report.Unindexed = append(report.Unindexed, secret.Id)
+			continue
+		}
+
+		if name != decryptedName {
+			encryptedName, err := dataProtector.Protect(name)
+			if err != nil {
+				m.logger.Error("Failed to encrypt secret name", "user_id", userId, "secret_id", secret.Id, "error", err)
+				return report, ccc.NewInternalError("failed to encrypt secret name", err)
+			}
+			secret.Name = encryptedName
+		}
+		secret.NameIndex = nameIndex
+
+		if _, err := m.secretRepository.Update(secret); err != nil {
+			m.logger.Error("Failed to store secret name index", "user_id", userId, "secret_id", secret.Id, "error", err)
+			return report, ccc.NewDatabaseError("update secret name index", err)
+		}
+
+		report.Indexed++
+		if name != decryptedName {
+			m.logger.Info("Renamed secret with a duplicate name", "user_id", userId, "secret_id", secret.Id)
+			report.Renamed = append(report.Renamed, RenamedSecret{OriginalName: decryptedName, Name: name})
+		}
+	}
+
+	m.logger.Info("Secret names indexed", "user_id", userId, "indexed_count", report.Indexed, "renamed_count", len(report.Renamed), "unindexed_count", len(report.Unindexed))
+	return report, nil
+}
+
+// freeSecretName returns the name of an unindexed secret and its index, appending a suffix if the name is taken
+// by another active secret. Secrets in the trash keep their name, a conflict is detected when they are restored.
+// The index is empty if no free name was found.
+func (m *DefaultSecretManager) freeSecretName(userId string, secret *Secret, name string, dataProtector dataprotection.DataProtector) (string, string, error) {
+	candidate := name
+	for attempt := 0; attempt <= maxRenameAttempts; attempt++ {
+		if attempt > 0 {
+			candidate = suffixedSecretName(name, "duplicate", attempt)
+		}
+
+		nameIndex, err := dataProtector.BlindIndex(candidate)
+		if err != nil {
+			m.logger.Error("Failed to compute secret name index", "user_id", userId, "secret_id", secret.Id, "error", err)
+			return "", "", ccc.NewInternalError("failed to compute secret name index", err)
+		}
+		if secret.DeletedAt != nil {
+			return candidate, nameIndex, nil
+		}
+
+		existing, err := m.secretRepository.FindByNameIndexForUser(userId, nameIndex)
+		if err != nil {
+			m.logger.Error("Failed to check for existing secret name", "user_id", userId, "secret_id", secret.Id, "error", err)
+			return "", "", ccc.NewDatabaseError("check secret name uniqueness", err)
+		}
+		if existing == nil {
+			return candidate, nameIndex, nil
+		}
+	}
+	return "", "", nil
+}
+
+// GetTotpCode returns the current one-time password of a secret
+func (m *DefaultSecretManager) GetTotpCode(userId string, secretId string, dataProtector dataprotection.DataProtector) (*TotpCodeDto, error) {
+	secret, err := m.GetSecret(userId, secretId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+	if secret.Totp == nil {
+		m.logger.Debug("Secret has no one-time password field", "user_id", userId, "secret_id", secretId)
+		return nil, ccc.NewResourceNotFoundError(secretId, "One-time password")
+	}
+	return secret.Totp, nil
+}
+
+// DeleteSecret moves a secret to the trash. Its prior versions are kept until it is purged.
 func (m *DefaultSecretManager) DeleteSecret(userId string, secretId string) (bool, error) {
-	m.logger.Info("Deleting secret", "user_id", userId, "secret_id", secretId)
+	m.logger.Info("Moving secret to the trash", "user_id", userId, "secret_id", secretId)
 
 	// Verify that the secret exists and belongs to the user before deletion
 	existingSecret, err := m.secretRepository.FindByIdForUser(userId, secretId)
@@ -476,17 +977,388 @@ func (m *DefaultSecretManager) DeleteSecret(userId string, secretId string) (boo
 	}
 
 	// Now proceed with deletion knowing the secret belongs to the user
-	success, err := m.secretRepository.Remove(secretId)
+	success, err := m.secretRepository.MoveToTrash(secretId, time.Now())
 	if err != nil {
-		m.logger.Error("Failed to delete secret from repository", "user_id", userId, "secret_id", secretId, "error", err)
-		return false, ccc.NewDatabaseError("remove secret by ID", err)
+		m.logger.Error("Failed to move secret to the trash", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewDatabaseError("move secret to trash", err)
 	}
 
 	if success {
-		m.logger.Info("Secret deleted successfully", "user_id", userId, "secret_id", secretId)
+		m.logger.Info("Secret moved to the trash successfully", "user_id", userId, "secret_id", secretId)
 	} else {
-		m.logger.Warn("Secret deletion returned false despite ownership verification", "user_id", userId, "secret_id", secretId)
+		m.logger.Warn("Moving secret to the trash returned false despite ownership verification", "user_id", userId, "secret_id", secretId)
+	}
+
+	return success, nil
+}
+
+// GetTrashedSecrets returns the secrets of a user that are in the trash with their decrypted names
+func (m *DefaultSecretManager) GetTrashedSecrets(userId string, dataProtector dataprotection.DataProtector) ([]*TrashedSecretDto, error) {
+	trashed, err := m.secretRepository.FindTrashedByUserId(userId)
+	if err != nil {
+		m.logger.Error("Failed to find trashed secrets", "user_id", userId, "error", err)
+		return nil, ccc.NewDatabaseError("find trashed secrets", err)
+	}
+
+	dtos := make([]*TrashedSecretDto, 0, len(trashed))
+	for _, secret := range trashed {
+		decryptedName, err := dataProtector.Unprotect(secret.Name)
+		if err != nil {
+			m.logger.Error("Failed to decrypt name of trashed secret", "user_id", userId, "secret_id", secret.Id, "error", err)
+			return nil, ccc.NewInternalError("failed to decrypt secret name", err)
+		}
+
+		secretType := secret.Type
+		if secretType == "" {
+			secretType = SecretTypeGeneric
+		}
+
+		dtos = append(dtos, &TrashedSecretDto{
+			Id:        secret.Id,
+			Name:      decryptedName,
+			Type:      secretType,
+			DeletedAt: *secret.DeletedAt,
+		})
+	}
+	return dtos, nil
+}
+
+// RestoreSecret moves a secret out of the trash unless another secret has taken its name in the meantime
+func (m *DefaultSecretManager) RestoreSecret(userId string, secretId string, dataProtector dataprotection.DataProtector) (bool, error) {
+	m.logger.Info("Restoring secret from the trash", "user_id", userId, "secret_id", secretId)
+
+	secret, err := m.secretRepository.FindTrashedByIdForUser(userId, secretId)
+	if err != nil {
+		m.logger.Error("Failed to find trashed secret", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewDatabaseError("find trashed secret", err)
+	}
+	if secret == nil {
+		m.logger.Warn("Trashed secret not found", "user_id", userId, "secret_id", secretId)
+		return false, ccc.NewResourceNotFoundError(secretId, "Secret")
+	}
+
+	if secret.NameIndex != "" {
+		existingSecret, err := m.secretRepository.FindByNameIndexForUser(userId, secret.NameIndex)
+		if err != nil {
+			m.logger.Error("Failed to check for existing secret name", "user_id", userId, "secret_id", secretId, "error", err)
+			return false, ccc.NewDatabaseError("check secret name uniqueness", err)
+		}
+		if existingSecret != nil {
+			m.logger.Warn("Cannot restore secret, its name is taken", "user_id", userId, "secret_id", secretId, "existing_secret_id", existingSecret.Id)
+			decryptedName, _ := dataProtector.Unprotect(secret.Name)
+			return false, ccc.NewInvalidInputErrorWithMessage(
+				"name",
+				"already exists",
+				fmt.Sprintf("A secret with the name '%s' already exists. Rename it before restoring this secret.", decryptedName),
+			)
+		}
+	}
+
+	success, err := m.secretRepository.RestoreFromTrash(secretId)
+	if err != nil {
+		m.logger.Error("Failed to restore secret from the trash", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewDatabaseError("restore secret from trash", err)
+	}
+
+	m.logger.Info("Secret restored from the trash", "user_id", userId, "secret_id", secretId, "success", success)
+	return success, nil
+}
+
+// PurgeSecret permanently deletes a secret in the trash together with its prior versions
+func (m *DefaultSecretManager) PurgeSecret(userId string, secretId string) (bool, error) {
+	m.logger.Info("Purging secret", "user_id", userId, "secret_id", secretId)
+
+	secret, err := m.secretRepository.FindTrashedByIdForUser(userId, secretId)
+	if err != nil {
+		m.logger.Error("Failed to find trashed secret", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewDatabaseError("find trashed secret", err)
+	}
+	if secret == nil {
+		m.logger.Warn("Trashed secret not found", "user_id", userId, "secret_id", secretId)
+		return false, ccc.NewResourceNotFoundError(secretId, "Secret")
+	}
+
+	success, err := m.secretRepository.Remove(secretId)
+	if err != nil {
+		m.logger.Error("Failed to purge secret", "user_id", userId, "secret_id", secretId, "error", err)
+		return false, ccc.NewDatabaseError("remove secret by ID", err)
 	}
 
+	m.logger.Info("Secret purged", "user_id", userId, "secret_id", secretId, "success", success)
 	return success, nil
 }
+
+// PurgeTrashedSecrets permanently deletes the secrets of all users that were moved to the trash before the given time.
+// It returns the number of purged secrets, also if purging one of them failed.
+func (m *DefaultSecretManager) PurgeTrashedSecrets(deletedBefore time.Time) (int, error) {
+	expired, err := m.secretRepository.FindTrashedBefore(deletedBefore)
+	if err != nil {
+		m.logger.Error("Failed to find expired secrets in the trash", "deleted_before", deletedBefore, "error", err)
+		return 0, ccc.NewDatabaseError("find expired trashed secrets", err)
+	}
+
+	purged := 0
+	for _, secret := range expired {
+		removed, err := m.secretRepository.Remove(secret.Id)
+		if err != nil {
+			m.logger.Error("Failed to purge secret", "user_id", secret.UserId, "secret_id", secret.Id, "error", err)
+			return purged, ccc.NewDatabaseError("remove secret by ID", err)
+		}
+		if removed {
+			purged++
+		}
+	}
+
+	if purged > 0 {
+		m.logger.Info("Purged expired secrets from the trash", "count", purged, "deleted_before", deletedBefore)
+	}
+	return purged, nil
+}
+
+// ImportSecrets creates many secrets at once, e.g. when migrating from another password manager.
+// Secrets are stored in batches of one transaction each. Invalid secrets are skipped and reported, and
+// names that are already taken, by an existing secret or an earlier one of the import, are handled
+// according to the conflict policy. If a batch fails, the secrets of the batches before it are kept.
+func (m *DefaultSecretManager) ImportSecrets(userId string, request ImportSecretsRequest, dataProtector dataprotection.DataProtector) (ImportSecretsResponse, error) {
+	m.logger.Info("Importing secrets", "user_id", userId, "secret_count", len(request.Secrets), "on_conflict", request.OnConflict)
+
+	response := ImportSecretsResponse{
+		Renamed: []RenamedSecret{},
+		Skipped: []SkippedSecret{},
+	}
+
+	policy, err := ParseConflictPolicy(string(request.OnConflict))
+	if err != nil {
+		m.logger.Warn("Secret import failed: invalid conflict policy", "user_id", userId, "on_conflict", request.OnConflict)
+		return response, err
+	}
+
+	batchSize := request.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultImportBatchSize
+	}
+
+	// Check if the user exists
+	user, err := m.userRepository.FindById(userId)
+	if err != nil {
+		m.logger.Error("Failed to find user during secret import", "user_id", userId, "error", err)
+		return response, ccc.NewDatabaseError("find user by ID", err)
+	}
+	if user == nil {
+		m.logger.Warn("User not found for secret import", "user_id", userId)
+		return response, ccc.NewResourceNotFoundError(userId, "User")
+	}
+
+	batch := newImportBatch()
+	for _, item := range request.Secrets {
+		item, err := normalizeSecretRequest(item)
+		if err == nil {
+			err = m.validateSecretRequest(item)
+		}
+		if reason := importSkipReason(err, item); reason != "" {
+			response.Skipped = append(response.Skipped, SkippedSecret{Name: item.SecretName, Reason: reason})
+			continue
+		}
+
+		if err := m.stageImportedSecret(userId, item, policy, dataProtector, batch, &response); err != nil {
+			return response, err
+		}
+
+		if len(batch.added)+len(batch.updated) >= batchSize {
+			if err := m.commitImportBatch(userId, batch, &response); err != nil {
+				return response, err
+			}
+			batch = newImportBatch()
+		}
+	}
+
+	if err := m.commitImportBatch(userId, batch, &response); err != nil {
+		return response, err
+	}
+
+	m.logger.Info("Secrets imported successfully", "user_id", userId, "created", response.Created, "overwritten", response.Overwritten,
+		"renamed", len(response.Renamed), "skipped", len(response.Skipped))
+	return response, nil
+}
+
+// importBatch collects the imported secrets that are stored in the next transaction
+type importBatch struct {
+	added       []*Secret
+	updated     []*Secret
+	versions    []*SecretVersion
+	byNameIndex map[string]*Secret
+	overwritten int
+	renamed     []RenamedSecret
+}
+
+func newImportBatch() *importBatch {
+	return &importBatch{byNameIndex: map[string]*Secret{}}
+}
+
+// stageImportedSecret encrypts an imported secret and adds it to the batch, resolving a name conflict
+// according to the policy. Secrets that are skipped because of the policy are recorded in the response.
+func (m *DefaultSecretManager) stageImportedSecret(
+	userId string,
+	request UpsertSecretRequest,
+	policy ConflictPolicy,
+	dataProtector dataprotection.DataProtector,
+	batch *importBatch,
+	response *ImportSecretsResponse,
+) error {
+	name := request.SecretName
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if attempt > maxRenameAttempts {
+				response.Skipped = append(response.Skipped, SkippedSecret{Name: request.SecretName, Reason: "No free name was found for the secret"})
+				return nil
+			}
+			name = importedSecretName(request.SecretName, attempt)
+		}
+
+		nameIndex, err := dataProtector.BlindIndex(name)
+		if err != nil {
+			m.logger.Error("Failed to compute secret name index during import", "user_id", userId, "error", err)
+			return ccc.NewInternalError("failed to compute secret name index", err)
+		}
+
+		// A secret of the current batch is not in the repository yet
+		pending := batch.byNameIndex[nameIndex]
+		var existing *Secret
+		if pending == nil {
+			existing, err = m.secretRepository.FindByNameIndexForUser(userId, nameIndex)
+			if err != nil {
+				m.logger.Error("Failed to check for existing secret during import", "user_id", userId, "error", err)
+				return ccc.NewDatabaseError("find existing secret by name", err)
+			}
+		}
+
+		if pending == nil && existing == nil {
+			secret, err := m.newImportedSecret(userId, name, nameIndex, request, dataProtector)
+			if err != nil {
+				return err
+			}
+			batch.added = append(batch.added, secret)
+			batch.byNameIndex[nameIndex] = secret
+			if name != request.SecretName {
+				batch.renamed = append(batch.renamed, RenamedSecret{OriginalName: request.SecretName, Name: name})
+			}
+			return nil
+		}
+
+		switch policy {
+		case ConflictSkip:
+			response.Skipped = append(response.Skipped, SkippedSecret{Name: request.SecretName, Reason: "A secret with this name already exists"})
+			return nil
+
+		case ConflictOverwrite:
+			encryptedValue, err := protectSecretFields(request.Fields, dataProtector)
+			if err != nil {
+				m.logger.Error("Failed to encrypt secret value during import", "user_id", userId, "error", err)
+				return ccc.NewInternalError("failed to encrypt secret value", err)
+			}
+			if pending != nil {
+				pending.Value = encryptedValue
+				pending.Type = request.SecretType
+			} else {
+				if m.historyLimit > 0 {
+					batch.versions = append(batch.versions, m.newSecretVersion(existing))
+				}
+				existing.Value = encryptedValue
+				existing.Type = request.SecretType
+				existing.ModifiedAt = time.Now()
+				existing.RotatedAt = existing.ModifiedAt
+				batch.updated = append(batch.updated, existing)
+				batch.byNameIndex[nameIndex] = existing
+			}
+			batch.overwritten++
+			return nil
+		}
+
+		// ConflictRename: try the next name
+	}
+}
+
+// newImportedSecret encrypts the name and fields of an imported secret
+func (m *DefaultSecretManager) newImportedSecret(userId, name, nameIndex string, request UpsertSecretRequest, dataProtector dataprotection.DataProtector) (*Secret, error) {
+	encryptedName, err := dataProtector.Protect(name)
+	if err != nil {
+		m.logger.Error("Failed to encrypt secret name during import", "user_id", userId, "error", err)
+		return nil, ccc.NewInternalError("failed to encrypt secret name", err)
+	}
+	encryptedValue, err := protectSecretFields(request.Fields, dataProtector)
+	if err != nil {
+		m.logger.Error("Failed to encrypt secret value during import", "user_id", userId, "error", err)
+		return nil, ccc.NewInternalError("failed to encrypt secret value", err)
+	}
+
+	now := time.Now()
+	return &Secret{
+		Id:          m.secretIdGenerator.GenerateId(),
+		UserId:      userId,
+		Name:        encryptedName,
+		Value:       encryptedValue,
+		Type:        request.SecretType,
+		NameIndex:   nameIndex,
+		CreatedAt:   now,
+		ModifiedAt:  now,
+		ExpiresAt:   request.ExpiresAt,
+		RotateEvery: request.RotateEvery,
+		RotatedAt:   now,
+	}, nil
+}
+
+// commitImportBatch stores the secrets of a batch in one transaction and counts them in the response
+func (m *DefaultSecretManager) commitImportBatch(userId string, batch *importBatch, response *ImportSecretsResponse) error {
+	if len(batch.added)+len(batch.updated) == 0 {
+		return nil
+	}
+
+	if err := m.secretRepository.SaveBatch(batch.added, batch.updated, batch.versions, m.historyLimit); err != nil {
+		m.logger.Error("Failed to store batch of imported secrets", "user_id", userId, "batch_size", len(batch.added)+len(batch.updated), "error", err)
+		return ccc.NewDatabaseError("store imported secrets", err)
+	}
+
+	response.Created += len(batch.added)
+	response.Overwritten += batch.overwritten
+	response.Renamed = append(response.Renamed, batch.renamed...)
+
+	m.logger.Debug("Stored batch of imported secrets", "user_id", userId, "added", len(batch.added), "updated", len(batch.updated))
+	return nil
+}
+
+// importSkipReason returns why an imported secret that failed validation is skipped, or "" if it is valid
+func importSkipReason(validationErr error, request UpsertSecretRequest) string {
+	switch {
+	case validationErr == nil:
+		return ""
+	case request.SecretName == "":
+		return "The secret has no name"
+	case len(request.Fields) == 0:
+		return "The secret has no value"
+	}
+	if apiErr, ok := ccc.IsApiError(validationErr); ok {
+		return apiErr.UserMessage
+	}
+	return validationErr.Error()
+}
+
+// importedSecretName returns the name of an imported secret whose name is taken,
+// e.g. "Mail (imported)" or "Mail (imported 2)"
+func importedSecretName(name string, attempt int) string {
+	return suffixedSecretName(name, "imported", attempt)
+}
+
+// suffixedSecretName appends a label and the attempt to a name that is taken, e.g. "Mail (duplicate 2)",
+// shortening the name if necessary
+func suffixedSecretName(name string, label string, attempt int) string {
+	suffix := fmt.Sprintf(" (%s)", label)
+	if attempt > 1 {
+		suffix = fmt.Sprintf(" (%s %d)", label, attempt)
+	}
+
+	for len(name)+len(suffix) > maxSecretNameLength {
+		runes := []rune(name)
+		name = string(runes[:len(runes)-1])
+	}
+	return strings.TrimSpace(name) + suffix
+}
@@ -0,0 +1,409 @@
+package secrets
+
+import (
+	"database/sql"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+func newSecretTestManager(t *testing.T) (*DefaultSecretManager, dataprotection.DataProtector) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite3", filepath.Join(t.TempDir(), "test.db"))
+	if err != nil {
+		t.Fatalf("failed to open database: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if _, err := schema.NewMigrationRunner(db, nil).Up(); err != nil {
+		t.Fatalf("failed to migrate database: %v", err)
+	}
+
+	userRepo, _ := auth.NewSQLiteUserRepository(db)
+	now := time.Now().UTC()
+	if _, err := userRepo.Add(&auth.User{Id: "user-1", UserName: "alice", IsActive: true, CreatedAt: now, ModifiedAt: now}); err != nil {
+		t.Fatalf("failed to add user: %v", err)
+	}
+
+	secretRepo, _ := NewSQLiteSecretRepository(db)
+	encryptionService := encryption.NewDefaultEncryptionService()
+	mek, _ := encryptionService.GenerateKey()
+
+	manager := NewDefaultSecretManager(secretRepo, ccc.NewUuidGenerator(), userRepo, 3, nil)
+	return manager, dataprotection.NewKeyDataProtector(encryptionService, mek)
+}
+
+func TestImportSecretsHandlesConflicts(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	if _, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Mail", SecretValue: "old"}, dataProtector); err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	request := ImportSecretsRequest{
+		Secrets: []UpsertSecretRequest{
+			{SecretName: "Mail", SecretValue: "first"},
+			{SecretName: "Mail", SecretValue: "second"},
+			{SecretName: "Bank", SecretValue: "1234"},
+			{SecretName: "Empty", SecretValue: "  "},
+		},
+		OnConflict: ConflictRename,
+		BatchSize:  2,
+	}
+	response, err := manager.ImportSecrets("user-1", request, dataProtector)
+	if err != nil {
+		t.Fatalf("ImportSecrets failed: %v", err)
+	}
+	if response.Created != 3 || len(response.Renamed) != 2 || len(response.Skipped) != 1 {
+		t.Fatalf("unexpected rename result %+v", response)
+	}
+	if response.Renamed[0].Name != "Mail (imported)" || response.Renamed[1].Name != "Mail (imported 2)" {
+		t.Errorf("unexpected names %+v", response.Renamed)
+	}
+
+	request.OnConflict = ConflictSkip
+	response, err = manager.ImportSecrets("user-1", request, dataProtector)
+	if err != nil {
+		t.Fatalf("ImportSecrets failed: %v", err)
+	}
+	if response.Created != 0 || len(response.Skipped) != 4 {
+		t.Errorf("unexpected skip result %+v", response)
+	}
+
+	request.OnConflict = ConflictOverwrite
+	response, err = manager.ImportSecrets("user-1", request, dataProtector)
+	if err != nil {
+		t.Fatalf("ImportSecrets failed: %v", err)
+	}
+	if response.Created != 0 || response.Overwritten != 3 {
+		t.Errorf("unexpected overwrite result %+v", response)
+	}
+	secret, err := manager.GetSecretByName("user-1", "Mail", dataProtector)
+	if err != nil || secret.Value != "second" {
+		t.Errorf("expected the later duplicate to win, got %+v (%v)", secret, err)
+	}
+
+	all, _ := manager.GetSecrets("user-1", GetSecretsRequest{}, dataProtector)
+	if all.TotalCount != 4 {
+		t.Errorf("expected 4 secrets, got %d", all.TotalCount)
+	}
+}
+
+func TestImportedSecretName(t *testing.T) {
+	if name := importedSecretName("Mail", 1); name != "Mail (imported)" {
+		t.Errorf("unexpected name %q", name)
+	}
+	if name := importedSecretName("Mail", 3); name != "Mail (imported 3)" {
+		t.Errorf("unexpected name %q", name)
+	}
+	long := strings.Repeat("ä", maxSecretNameLength)
+	if name := importedSecretName(long, 1); len(name) > maxSecretNameLength {
+		t.Errorf("expected the name to be shortened to %d bytes, got %d", maxSecretNameLength, len(name))
+	}
+}
+
+func TestTypedSecretFields(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	created, err := manager.CreateSecret("user-1", UpsertSecretRequest{
+		SecretName: "GitHub",
+		SecretType: SecretTypeLogin,
+		Fields: []SecretField{
+			{Name: "Username", Value: "octo"},
+			{Name: "Password", Value: " hunter2 ", Concealed: true},
+			{Name: "URL", Value: ""},
+			{Name: "Recovery codes", Value: "abc def", Concealed: true},
+		},
+	}, dataProtector)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	secret, err := manager.GetSecret("user-1", created.SecretId, dataProtector)
+	if err != nil {
+		t.Fatalf("GetSecret failed: %v", err)
+	}
+	if secret.Type != SecretTypeLogin || secret.Value != "hunter2" || len(secret.Fields) != 3 || secret.Fields[2].Name != "Recovery codes" {
+		t.Fatalf("unexpected secret %+v", secret)
+	}
+
+	// Updating only the value replaces the primary field and keeps the others
+	if _, err := manager.UpdateSecret("user-1", created.SecretId, UpsertSecretRequest{SecretName: "GitHub", SecretValue: "changed"}, dataProtector); err != nil {
+		t.Fatalf("UpdateSecret failed: %v", err)
+	}
+	secret, _ = manager.GetSecret("user-1", created.SecretId, dataProtector)
+	if secret.Type != SecretTypeLogin || secret.Fields[0].Value != "octo" || secret.Fields[1].Value != "changed" || len(secret.Fields) != 3 {
+		t.Errorf("unexpected secret after update %+v", secret)
+	}
+
+	// A value without fields is stored in the first concealed field of the type
+	created, err = manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Stripe", SecretType: SecretTypeApiKey, SecretValue: "sk_live"}, dataProtector)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+	secret, _ = manager.GetSecret("user-1", created.SecretId, dataProtector)
+	if len(secret.Fields) != 1 || secret.Fields[0] != (SecretField{Name: "Secret", Value: "sk_live", Concealed: true}) {
+		t.Errorf("unexpected default fields %+v", secret.Fields)
+	}
+
+	if _, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Bad", SecretType: "passport", SecretValue: "x"}, dataProtector); err == nil {
+		t.Errorf("expected an unknown type to be rejected")
+	}
+}
+
+func TestLegacySecretsHaveSingleValueField(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	// Secrets created before secret types were introduced hold the encrypted plain value
+	name, _ := dataProtector.Protect("Legacy")
+	value, _ := dataProtector.Protect("plain value")
+	now := time.Now()
+	legacy := &Secret{Id: "legacy-1", UserId: "user-1", Name: name, Value: value, CreatedAt: now, ModifiedAt: now}
+	if _, err := manager.secretRepository.Add(legacy); err != nil {
+		t.Fatalf("Add failed: %v", err)
+	}
+
+	secret, err := manager.GetSecret("user-1", "legacy-1", dataProtector)
+	if err != nil {
+		t.Fatalf("GetSecret failed: %v", err)
+	}
+	if secret.Type != SecretTypeGeneric || secret.Value != "plain value" ||
+		len(secret.Fields) != 1 || secret.Fields[0] != (SecretField{Name: "Value", Value: "plain value", Concealed: true}) {
+		t.Fatalf("unexpected legacy secret %+v", secret)
+	}
+
+	// Updating a legacy secret stores it with a type and payload
+	if _, err := manager.UpdateSecret("user-1", "legacy-1", UpsertSecretRequest{SecretName: "Legacy", SecretValue: "new value"}, dataProtector); err != nil {
+		t.Fatalf("UpdateSecret failed: %v", err)
+	}
+	stored, _ := manager.secretRepository.FindById("legacy-1")
+	secret, _ = manager.GetSecret("user-1", "legacy-1", dataProtector)
+	if stored.Type != SecretTypeGeneric || secret.Value != "new value" {
+		t.Errorf("unexpected updated legacy secret %+v (type %q)", secret, stored.Type)
+	}
+}
+
+func TestSecretHistoryKeepsPriorVersions(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	created, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Mail", SecretValue: "v1"}, dataProtector)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+	for _, value := range []string{"v2", "v2", "v3", "v4", "v5"} {
+		if _, err := manager.UpdateSecret("user-1", created.SecretId, UpsertSecretRequest{SecretName: "Mail", SecretValue: value}, dataProtector); err != nil {
+			t.Fatalf("UpdateSecret failed: %v", err)
+		}
+	}
+
+	// Saving without changes records no version, and only the 3 newest versions are kept
+	history, err := manager.GetSecretHistory("user-1", created.SecretId, dataProtector)
+	if err != nil {
+		t.Fatalf("GetSecretHistory failed: %v", err)
+	}
+	var values []string
+	for _, version := range history {
+		values = append(values, version.Value)
+	}
+	if strings.Join(values, ",") != "v4,v3,v2" {
+		t.Fatalf("unexpected history %v", values)
+	}
+
+	if _, err := manager.RestoreSecretVersion("user-1", created.SecretId, history[2].Id, dataProtector); err != nil {
+		t.Fatalf("RestoreSecretVersion failed: %v", err)
+	}
+	secret, _ := manager.GetSecret("user-1", created.SecretId, dataProtector)
+	if secret.Value != "v2" {
+		t.Errorf("expected the restored value v2, got %s", secret.Value)
+	}
+	history, _ = manager.GetSecretHistory("user-1", created.SecretId, dataProtector)
+	if len(history) != 3 || history[0].Value != "v5" {
+		t.Errorf("expected the replaced value to be recorded, got %+v", history[0])
+	}
+
+	other, _ := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Bank", SecretValue: "x"}, dataProtector)
+	if _, err := manager.RestoreSecretVersion("user-1", other.SecretId, history[0].Id, dataProtector); !ccc.IsNotFound(err) {
+		t.Errorf("expected versions of other secrets to be rejected, got %v", err)
+	}
+
+	if _, err := manager.DeleteSecret("user-1", created.SecretId); err != nil {
+		t.Fatalf("DeleteSecret failed: %v", err)
+	}
+	if _, err := manager.PurgeSecret("user-1", created.SecretId); err != nil {
+		t.Fatalf("PurgeSecret failed: %v", err)
+	}
+	if versions, _ := manager.secretRepository.FindVersionsForUser("user-1", created.SecretId); len(versions) != 0 {
+		t.Errorf("expected the versions to be purged with the secret, got %d", len(versions))
+	}
+}
+
+func TestDeletedSecretsMoveToTrash(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	created, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Mail", SecretValue: "old"}, dataProtector)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+	if _, err := manager.DeleteSecret("user-1", created.SecretId); err != nil {
+		t.Fatalf("DeleteSecret failed: %v", err)
+	}
+
+	if _, err := manager.GetSecret("user-1", created.SecretId, dataProtector); !ccc.IsNotFound(err) {
+		t.Errorf("expected a trashed secret to be hidden, got %v", err)
+	}
+	trashed, err := manager.GetTrashedSecrets("user-1", dataProtector)
+	if err != nil {
+		t.Fatalf("GetTrashedSecrets failed: %v", err)
+	}
+	if len(trashed) != 1 || trashed[0].Name != "Mail" {
+		t.Fatalf("unexpected trash %+v", trashed)
+	}
+
+	// The name of a trashed secret can be reused, which blocks restoring the trashed secret
+	replacement, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Mail", SecretValue: "new"}, dataProtector)
+	if err != nil {
+		t.Fatalf("expected the name of a trashed secret to be reusable, got %v", err)
+	}
+	if _, err := manager.RestoreSecret("user-1", created.SecretId, dataProtector); !ccc.IsValidationError(err) {
+		t.Errorf("expected a name conflict on restore, got %v", err)
+	}
+
+	if _, err := manager.DeleteSecret("user-1", replacement.SecretId); err != nil {
+		t.Fatalf("DeleteSecret failed: %v", err)
+	}
+	if restored, err := manager.RestoreSecret("user-1", created.SecretId, dataProtector); err != nil || !restored {
+		t.Fatalf("RestoreSecret failed: %v", err)
+	}
+	secret, _ := manager.GetSecret("user-1", created.SecretId, dataProtector)
+	if secret == nil || secret.Value != "old" {
+		t.Errorf("expected the restored secret to keep its value, got %+v", secret)
+	}
+
+	purged, err := manager.PurgeTrashedSecrets(time.Now().Add(time.Minute))
+	if err != nil || purged != 1 {
+		t.Fatalf("expected 1 expired secret to be purged, got %d (%v)", purged, err)
+	}
+	if trashed, _ := manager.GetTrashedSecrets("user-1", dataProtector); len(trashed) != 0 {
+		t.Errorf("expected the trash to be empty, got %d items", len(trashed))
+	}
+}
+
+func TestExpiringSecretsAreFilteredAndReminded(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	soon := time.Now().UTC().AddDate(0, 0, 5).Truncate(24 * time.Hour)
+	later := time.Now().UTC().AddDate(0, 0, 60).Truncate(24 * time.Hour)
+	requests := []UpsertSecretRequest{
+		{SecretName: "Certificate", SecretValue: "a", ExpiresAt: &later},
+		{SecretName: "Token", SecretValue: "b", ExpiresAt: &soon},
+		{SecretName: "Password", SecretValue: "c", RotateEvery: 10},
+		{SecretName: "Note", SecretValue: "d"},
+	}
+	for _, request := range requests {
+		if _, err := manager.CreateSecret("user-1", request, dataProtector); err != nil {
+			t.Fatalf("CreateSecret failed: %v", err)
+		}
+	}
+
+	response, err := manager.GetSecrets("user-1", GetSecretsRequest{ExpiringWithinDays: 30, SortBy: "ExpiresAt", SortAsc: true}, dataProtector)
+	if err != nil {
+		t.Fatalf("GetSecrets failed: %v", err)
+	}
+	if response.TotalCount != 2 || response.Secrets[0].Name != "Token" || response.Secrets[1].Name != "Password" {
+		t.Fatalf("unexpected expiring secrets %+v", response.Secrets)
+	}
+
+	all, _ := manager.GetSecrets("user-1", GetSecretsRequest{SortBy: "ExpiresAt", SortAsc: false}, dataProtector)
+	if all.TotalCount != 4 || all.Secrets[0].Name != "Certificate" || all.Secrets[3].Name != "Note" {
+		t.Errorf("expected secrets without due date last, got %+v", all.Secrets)
+	}
+
+	// Updating only the value keeps the schedule and restarts the rotation interval
+	token, _ := manager.GetSecretByName("user-1", "Token", dataProtector)
+	if _, err := manager.UpdateSecret("user-1", token.Id, UpsertSecretRequest{SecretName: "Token", SecretValue: "new"}, dataProtector); err != nil {
+		t.Fatalf("UpdateSecret failed: %v", err)
+	}
+	token, _ = manager.GetSecretByName("user-1", "Token", dataProtector)
+	if token.ExpiresAt == nil || !token.ExpiresAt.Equal(soon) {
+		t.Errorf("expected the expiry date to be kept, got %v", token.ExpiresAt)
+	}
+
+	reminders, err := manager.GetSecretReminders(time.Now().AddDate(0, 0, 30))
+	if err != nil {
+		t.Fatalf("GetSecretReminders failed: %v", err)
+	}
+	kinds := make(map[ReminderKind]int)
+	for _, reminder := range reminders {
+		kinds[reminder.Kind]++
+	}
+	if len(reminders) != 2 || kinds[ReminderExpiry] != 1 || kinds[ReminderRotation] != 1 {
+		t.Errorf("unexpected reminders %+v", reminders)
+	}
+
+	if _, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Invalid", SecretValue: "e", RotateEvery: -1}, dataProtector); !ccc.IsValidationError(err) {
+		t.Errorf("expected a validation error for a negative rotation interval, got %v", err)
+	}
+}
+
+func TestIndexSecretNames(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	if _, err := manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Mail", SecretValue: "new"}, dataProtector); err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	// Secrets stored before blind indexing was introduced have no name index
+	addLegacySecret := func(id, encryptedName string) {
+		value, _ := dataProtector.Protect("legacy")
+		now := time.Now()
+		secret := &Secret{Id: id, UserId: "user-1", Name: encryptedName, Value: value, CreatedAt: now, ModifiedAt: now, RotatedAt: now}
+		if _, err := manager.secretRepository.Add(secret); err != nil {
+			t.Fatalf("failed to add legacy secret: %v", err)
+		}
+	}
+	mailName, _ := dataProtector.Protect("Mail")
+	bankName, _ := dataProtector.Protect("Bank")
+	addLegacySecret("legacy-mail", mailName)
+	addLegacySecret("legacy-bank", bankName)
+	addLegacySecret("legacy-broken", "not encrypted")
+
+	if _, err := manager.GetSecretByName("user-1", "Bank", dataProtector); !ccc.IsNotFound(err) {
+		t.Fatalf("expected an unindexed secret not to be found by name, got %v", err)
+	}
+
+	report, err := manager.IndexSecretNames("user-1", dataProtector)
+	if err != nil {
+		t.Fatalf("IndexSecretNames failed: %v", err)
+	}
+	if report.Indexed != 2 || len(report.Unindexed) != 1 || report.Unindexed[0] != "legacy-broken" {
+		t.Fatalf("unexpected report %+v", report)
+	}
+	if len(report.Renamed) != 1 || report.Renamed[0] != (RenamedSecret{OriginalName: "Mail", Name: "Mail (duplicate)"}) {
+		t.Fatalf("expected the duplicate to be renamed, got %+v", report.Renamed)
+	}
+
+	if secret, err := manager.GetSecretByName("user-1", "Bank", dataProtector); err != nil || secret.Id != "legacy-bank" {
+		t.Errorf("expected the indexed secret to be found by name, got %+v (%v)", secret, err)
+	}
+	if secret, err := manager.GetSecretByName("user-1", "Mail (duplicate)", dataProtector); err != nil || secret.Id != "legacy-mail" {
+		t.Errorf("expected the renamed secret to be found by name, got %+v (%v)", secret, err)
+	}
+	if secret, err := manager.GetSecretByName("user-1", "Mail", dataProtector); err != nil || secret.Value != "new" {
+		t.Errorf("expected the original secret to keep its name, got %+v (%v)", secret, err)
+	}
+
+	// Only the secret that cannot be decrypted is left, and it is reported again
+	report, err = manager.IndexSecretNames("user-1", dataProtector)
+	if err != nil || report.Indexed != 0 || len(report.Unindexed) != 1 {
+		t.Errorf("unexpected second report %+v (%v)", report, err)
+	}
+}
@@ -1,6 +1,8 @@
 package secrets
 
 import (
+	"time"
+
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
 )
 
@@ -14,10 +16,27 @@ type SecretRepository interface {
 	FindById(secretId string) (*Secret, error)
 	FindByUserId(userId string) ([]*Secret, error)
 	FindByIdForUser(userId, secretId string) (*Secret, error)
-	FindByNameForUser(userId, name string) (*Secret, error)
+	FindByNameIndexForUser(userId, nameIndex string) (*Secret, error)
+	FindWithoutNameIndexForUser(userId string) ([]*Secret, error)
 	Add(secret *Secret) (bool, error)
 	Remove(secretId string) (bool, error)
 	Update(secret *Secret) (bool, error)
+	SaveBatch(added []*Secret, updated []*Secret, versions []*SecretVersion, keepVersions int) error
+	// UpdateWithVersion updates a secret and records its prior version in one transaction,
+	// keeping only the newest keepVersions versions of the secret
+	UpdateWithVersion(secret *Secret, version *SecretVersion, keepVersions int) (bool, error)
+	// FindVersionsForUser returns the prior versions of a secret, newest first
+	FindVersionsForUser(userId, secretId string) ([]*SecretVersion, error)
+	FindVersionByIdForUser(userId, versionId string) (*SecretVersion, error)
+	// MoveToTrash marks a secret as deleted, which hides it from all other queries
+	MoveToTrash(secretId string, deletedAt time.Time) (bool, error)
+	RestoreFromTrash(secretId string) (bool, error)
+	FindTrashedByUserId(userId string) ([]*Secret, error)
+	FindTrashedByIdForUser(userId, secretId string) (*Secret, error)
+	// FindTrashedBefore returns the secrets of all users that were moved to the trash before the given time
+	FindTrashedBefore(deletedBefore time.Time) ([]*Secret, error)
+	// FindWithDueDates returns the active secrets of all users that have an expiry date or a rotation interval
+	FindWithDueDates() ([]*Secret, error)
 }
 
 // SecretManager interface for managing secrets
@@ -27,5 +46,26 @@ type SecretManager interface {
 	GetSecretByName(userId string, secretName string, dataProtector dataprotection.DataProtector) (*SecretDto, error)
 	GetSecrets(userId string, request GetSecretsRequest, dataProtector dataprotection.DataProtector) (PaginatedSecretResponse, error)
 	UpdateSecret(userId string, secretId string, request UpsertSecretRequest, dataProtector dataprotection.DataProtector) (bool, error)
+	// DeleteSecret moves a secret to the trash, from where it can be restored until it is purged
 	DeleteSecret(userId string, secretId string) (bool, error)
+	// GetTotpCode returns the current one-time password generated from the one-time password field of a secret
+	GetTotpCode(userId string, secretId string, dataProtector dataprotection.DataProtector) (*TotpCodeDto, error)
+	// GetSecretHistory returns the prior versions of a secret, newest first
+	GetSecretHistory(userId string, secretId string, dataProtector dataprotection.DataProtector) ([]*SecretVersionDto, error)
+	// RestoreSecretVersion replaces a secret with one of its prior versions
+	RestoreSecretVersion(userId string, secretId string, versionId string, dataProtector dataprotection.DataProtector) (bool, error)
+	ImportSecrets(userId string, request ImportSecretsRequest, dataProtector dataprotection.DataProtector) (ImportSecretsResponse, error)
+	// IndexSecretNames computes the name index of the secrets of a user that were stored before blind indexing was introduced.
+	// Secrets whose name is taken by another secret are renamed.
+	IndexSecretNames(userId string, dataProtector dataprotection.DataProtector) (*NameIndexReport, error)
+	// GetTrashedSecrets returns the secrets of a user that are in the trash, most recently deleted first
+	GetTrashedSecrets(userId string, dataProtector dataprotection.DataProtector) ([]*TrashedSecretDto, error)
+	// RestoreSecret moves a secret out of the trash. It fails if another secret has taken its name in the meantime.
+	RestoreSecret(userId string, secretId string, dataProtector dataprotection.DataProtector) (bool, error)
+	// PurgeSecret permanently deletes a secret in the trash together with its prior versions
+	PurgeSecret(userId string, secretId string) (bool, error)
+	// PurgeTrashedSecrets permanently deletes the secrets of all users that were moved to the trash before the given time
+	PurgeTrashedSecrets(deletedBefore time.Time) (int, error)
+	// GetSecretReminders returns the expiry and rotation dates of the secrets of all users that are due before the given time
+	GetSecretReminders(dueBefore time.Time) ([]*SecretReminder, error)
 }
@@ -1,15 +1,238 @@
 package secrets
 
 import (
+	"fmt"
+	"strings"
 	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
 // Secret describes a user's secret
 type Secret struct {
+	Id     string
+	UserId string
+	Name   string
+	// Value is the encrypted JSON payload holding the fields of the secret. Secrets created before
+	// secret types were introduced have no Type, and their Value holds the encrypted plain value.
+	Value      string
+	Type       SecretType
+	NameIndex  string // Keyed blind index of the plaintext name, used for lookups and uniqueness
+	CreatedAt  time.Time
+	ModifiedAt time.Time
+	DeletedAt  *time.Time // When the secret was moved to the trash, nil for active secrets
+	// Expiry and rotation are stored in plain text, so that reminders can be created without the key of the user
+	ExpiresAt   *time.Time // When the credential held by the secret expires, nil if it does not expire
+	RotateEvery int        // Days after which the value of the secret should be rotated, 0 = no rotation
+	RotatedAt   time.Time  // When the type or fields of the secret last changed
+}
+
+// RotationDueAt returns when the value of the secret should be rotated next, nil if it is not rotated regularly
+func (s *Secret) RotationDueAt() *time.Time {
+	if s.RotateEvery <= 0 {
+		return nil
+	}
+	dueAt := s.RotatedAt.AddDate(0, 0, s.RotateEvery)
+	return &dueAt
+}
+
+// DueAt returns the earlier of the expiry date and the rotation due date of the secret, nil if it has neither
+func (s *Secret) DueAt() *time.Time {
+	dueAt := s.ExpiresAt
+	if rotationDueAt := s.RotationDueAt(); rotationDueAt != nil && (dueAt == nil || rotationDueAt.Before(*dueAt)) {
+		dueAt = rotationDueAt
+	}
+	return dueAt
+}
+
+// ReminderKind tells why a secret needs the attention of its user
+type ReminderKind string
+
+const (
+	// ReminderExpiry is due when the credential held by a secret expires
+	ReminderExpiry ReminderKind = "expiry"
+	// ReminderRotation is due when the value of a secret should be rotated
+	ReminderRotation ReminderKind = "rotation"
+)
+
+// SecretVersion is a prior state of a secret, recorded when the secret is updated. Like the secret,
+// it holds the encrypted name and value.
+type SecretVersion struct {
 	Id         string
+	SecretId   string
 	UserId     string
 	Name       string
 	Value      string
-	CreatedAt  time.Time
-	ModifiedAt time.Time
+	Type       SecretType
+	ModifiedAt time.Time // When the version was saved
+	ReplacedAt time.Time // When the version was replaced by an update
+}
+
+// ConflictPolicy decides what happens to an imported secret whose name is already taken
+type ConflictPolicy string
+
+const (
+	// ConflictRename imports the secret under a new name, e.g. "Mail (imported)"
+	ConflictRename ConflictPolicy = "rename"
+	// ConflictSkip keeps the existing secret and drops the imported one
+	ConflictSkip ConflictPolicy = "skip"
+	// ConflictOverwrite replaces the value of the existing secret
+	ConflictOverwrite ConflictPolicy = "overwrite"
+)
+
+// ParseConflictPolicy parses the name of a conflict policy. An empty name selects ConflictRename.
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	switch policy := ConflictPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return ConflictRename, nil
+	case ConflictRename, ConflictSkip, ConflictOverwrite:
+		return policy, nil
+	default:
+		return "", ccc.NewInvalidInputErrorWithMessage(
+			"conflict policy",
+			fmt.Sprintf("unknown conflict policy %q", name),
+			"Conflicts can be handled by rename, skip or overwrite",
+		)
+	}
+}
+
+// SecretType describes what kind of credential a secret holds and which fields it has by default
+type SecretType string
+
+const (
+	SecretTypeGeneric SecretType = apicontracts.SecretTypeGeneric
+	SecretTypeLogin   SecretType = apicontracts.SecretTypeLogin
+	SecretTypeApiKey  SecretType = apicontracts.SecretTypeApiKey
+	SecretTypeNote    SecretType = apicontracts.SecretTypeNote
+	SecretTypeCard    SecretType = apicontracts.SecretTypeCard
+	SecretTypeSshKey  SecretType = apicontracts.SecretTypeSshKey
+)
+
+// SecretTypes lists all secret types in the order they are offered to users
+var SecretTypes = []SecretType{
+	SecretTypeLogin,
+	SecretTypeApiKey,
+	SecretTypeNote,
+	SecretTypeCard,
+	SecretTypeSshKey,
+	SecretTypeGeneric,
+}
+
+// DisplayName returns the name of the secret type shown to users
+func (t SecretType) DisplayName() string {
+	switch t {
+	case SecretTypeLogin:
+		return "Login"
+	case SecretTypeApiKey:
+		return "API key"
+	case SecretTypeNote:
+		return "Secure note"
+	case SecretTypeCard:
+		return "Card"
+	case SecretTypeSshKey:
+		return "SSH key"
+	default:
+		return "Generic"
+	}
+}
+
+// DefaultFields returns the fields a new secret of the type starts with, all of them empty
+func (t SecretType) DefaultFields() []SecretField {
+	switch t {
+	case SecretTypeLogin:
+		return []SecretField{{Name: "Username"}, {Name: "Password", Concealed: true}, {Name: "URL"}, {Name: "Notes"}}
+	case SecretTypeApiKey:
+		return []SecretField{{Name: "Key ID"}, {Name: "Secret", Concealed: true}}
+	case SecretTypeNote:
+		return []SecretField{{Name: "Note", Concealed: true}}
+	case SecretTypeCard:
+		return []SecretField{{Name: "Cardholder"}, {Name: "Number", Concealed: true}, {Name: "Expiry"}, {Name: "Security code", Concealed: true}}
+	case SecretTypeSshKey:
+		return []SecretField{{Name: "Private key", Concealed: true}, {Name: "Public key"}, {Name: "Passphrase", Concealed: true}}
+	default:
+		return []SecretField{{Name: "Value", Concealed: true}}
+	}
+}
+
+// ParseSecretType parses the name of a secret type. An empty name selects SecretTypeGeneric.
+func ParseSecretType(name string) (SecretType, error) {
+	secretType := SecretType(strings.ToLower(strings.TrimSpace(name)))
+	if secretType == "" {
+		return SecretTypeGeneric, nil
+	}
+	for _, known := range SecretTypes {
+		if secretType == known {
+			return secretType, nil
+		}
+	}
+	return "", ccc.NewInvalidInputErrorWithMessage(
+		"secret type",
+		fmt.Sprintf("unknown secret type %q", name),
+		"Unknown secret type. Valid types are login, api_key, note, card, ssh_key and generic.",
+	)
+}
+
+// FieldKind describes how the value of a secret field is used. Fields without a kind hold plain text.
+type FieldKind string
+
+const (
+	// FieldKindTotp marks a field holding an otpauth:// URI or base32 seed, from which one-time passwords are generated
+	FieldKindTotp FieldKind = apicontracts.FieldKindTotp
+)
+
+// TotpFieldName is the name of the field created for a one-time password seed if no other name is given
+const TotpFieldName = "One-time password"
+
+// SecretField is a named value of a secret. Concealed fields are masked until they are revealed.
+type SecretField struct {
+	Name      string    `json:"name"`
+	Value     string    `json:"value"`
+	Concealed bool      `json:"concealed"`
+	Kind      FieldKind `json:"kind,omitempty"`
+}
+
+// PrimaryValue returns the value that represents a secret where a single value is expected, e.g. when
+// copying it: the first concealed field that is not empty, or else the first field that is not empty.
+// One-time password seeds are only used if there is no other value.
+func PrimaryValue(fields []SecretField) string {
+	if i := primaryFieldIndex(fields); i >= 0 {
+		return fields[i].Value
+	}
+	return ""
+}
+
+// primaryFieldIndex returns the index of the field holding the primary value, or -1 if all fields are empty
+func primaryFieldIndex(fields []SecretField) int {
+	for i, field := range fields {
+		if field.Concealed && field.Kind != FieldKindTotp && field.Value != "" {
+			return i
+		}
+	}
+	for i, field := range fields {
+		if field.Kind != FieldKindTotp && field.Value != "" {
+			return i
+		}
+	}
+	for i, field := range fields {
+		if field.Value != "" {
+			return i
+		}
+	}
+	return -1
+}
+
+// totpField returns the one-time password field of a secret, or nil if it has none
+func totpField(fields []SecretField) *SecretField {
+	for i := range fields {
+		if fields[i].Kind == FieldKindTotp {
+			return &fields[i]
+		}
+	}
+	return nil
+}
+
+// secretPayload is the JSON document that is encrypted into the Value of a secret
+type secretPayload struct {
+	Fields []SecretField `json:"fields"`
 }
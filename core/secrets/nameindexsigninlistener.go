@@ -0,0 +1,52 @@
+package secrets
+
+import (
+	"fmt"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+// NameIndexSignInListener indexes the names of secrets stored before blind indexing was introduced
+// when their owner signs in, since the names can only be decrypted with the MEK of the user.
+// It implements auth.SignInListener.
+type NameIndexSignInListener struct {
+	secretManager     SecretManager
+	encryptionService encryption.EncryptionService
+	logger            ccc.Logger
+}
+
+// NewNameIndexSignInListener creates a new NameIndexSignInListener
+func NewNameIndexSignInListener(secretManager SecretManager, encryptionService encryption.EncryptionService, logger ccc.Logger) *NameIndexSignInListener {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &NameIndexSignInListener{
+		secretManager:     secretManager,
+		encryptionService: encryptionService,
+		logger:            logger,
+	}
+}
+
+// UserSignedIn indexes the secret names of the user and returns notices about renamed secrets
+// and secrets that cannot be found by name. Failures do not prevent the sign-in, the remaining
+// secrets are indexed at the next sign-in.
+func (l *NameIndexSignInListener) UserSignedIn(userId string, mek string) []string {
+	report, err := l.secretManager.IndexSecretNames(userId, dataprotection.NewKeyDataProtector(l.encryptionService, mek))
+	if err != nil {
+		l.logger.Error("Failed to index secret names at sign-in", "user_id", userId, "error", err)
+		return nil
+	}
+
+	var notices []string
+	for _, renamed := range report.Renamed {
+		notices = append(notices, fmt.Sprintf("The secret \"%s\" had the same name as another secret and was renamed to \"%s\".", renamed.OriginalName, renamed.Name))
+	}
+	if count := len(report.Unindexed); count == 1 {
+		notices = append(notices, "1 secret could not be indexed and cannot be looked up by name. The server log tells which one.")
+	} else if count > 1 {
+		notices = append(notices, fmt.Sprintf("%d secrets could not be indexed and cannot be looked up by name. The server log tells which ones.", count))
+	}
+	return notices
+}
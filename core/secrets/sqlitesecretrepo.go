@@ -3,6 +3,7 @@ package secrets
 import (
 	"database/sql"
 	"fmt"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	_ "github.com/mattn/go-sqlite3" // SQLite driver
@@ -19,73 +20,64 @@ type SQLiteSecretRepository struct {
 
 const (
 	// secretFieldList defines the column order for secret queries.
-	secretFieldList = `Id, UserId, Name, Value, CreatedAt, ModifiedAt`
+	secretFieldList = `Id, UserId, Name, Value, Type, NameIndex, CreatedAt, ModifiedAt, DeletedAt, ExpiresAt, RotateEvery, RotatedAt`
+
+	addSecretQuery = "INSERT INTO Secret (" + secretFieldList + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)"
+
+	updateSecretQuery = `
+	UPDATE Secret SET 
+		UserId = ?, 
+		Name = ?, 
+		Value = ?, 
+		Type = ?,
+		NameIndex = ?,
+		CreatedAt = ?, 
+		ModifiedAt = ?,
+		ExpiresAt = ?,
+		RotateEvery = ?,
+		RotatedAt = ?
+	WHERE Id = ?`
+
+	// secretVersionFieldList defines the column order for secret version queries.
+	secretVersionFieldList = `Id, SecretId, UserId, Name, Value, Type, ModifiedAt, ReplacedAt`
+
+	addSecretVersionQuery = "INSERT INTO SecretVersion (" + secretVersionFieldList + ") VALUES (?, ?, ?, ?, ?, ?, ?, ?)"
+
+	// pruneSecretVersionsQuery removes all but the newest versions of a secret
+	pruneSecretVersionsQuery = `
+	DELETE FROM SecretVersion
+	WHERE SecretId = ? AND Id NOT IN (
+		SELECT Id FROM SecretVersion WHERE SecretId = ? ORDER BY ReplacedAt DESC, rowid DESC LIMIT ?
+	)`
 )
 
 // NewSQLiteSecretRepository creates a new instance of SQLiteSecretRepository.
-// It uses an existing database connection and initializes the secrets table.
+// It uses an existing database connection; the Secret table is expected to be created by the schema migrations.
 // This repository is encryption-agnostic and will store/retrieve data exactly as provided,
 // without performing any encryption or decryption operations.
 func NewSQLiteSecretRepository(db *sql.DB) (*SQLiteSecretRepository, error) {
-	repo := &SQLiteSecretRepository{db: db}
-
-	if err := repo.initializeTable(); err != nil {
-		// Do not close db here as it's managed externally
-		return nil, fmt.Errorf("initializing secret table: %w", err)
-	}
-
-	return repo, nil
-}
-
-// initializeTable creates the Secret table if it doesn't already exist.
-func (repo *SQLiteSecretRepository) initializeTable() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS Secret (
-		Id TEXT PRIMARY KEY,
-		UserId TEXT NOT NULL,
-		Name TEXT NOT NULL,
-		Value TEXT NOT NULL,
-		CreatedAt TIMESTAMP NOT NULL,
-		ModifiedAt TIMESTAMP NOT NULL
-	);
-	CREATE INDEX IF NOT EXISTS idx_secret_userid ON Secret(UserId);
-	`
-	_, err := repo.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("executing schema creation: %w", err)
-	}
-
-	// Try to add foreign key constraint from Secret[dot]UserId to User[dot]Id
-	// If this fails, it's okay and we can proceed anyway
-	fkQuery := `
-	ALTER TABLE Secret ADD CONSTRAINT fk_secret_userid 
-	FOREIGN KEY (UserId) REFERENCES User(Id) ON DELETE CASCADE;
-	`
-	_, fkErr := repo.db.Exec(fkQuery)
-	if fkErr != nil {
-		// Log or ignore the error - foreign key constraint is optional
-		// The constraint might fail if:
-		// - User table doesn't exist yet
-		// - Constraint already exists
-		// - SQLite was compiled without foreign key support
-		// We continue anyway as this is not critical for basic functionality
-	}
-
-	return nil
+	return &SQLiteSecretRepository{db: db}, nil
 }
 
 // scanSecret scans a database row into a Secret struct.
 func scanSecret(scanner ccc.RowScanner) (*Secret, error) {
 	secret := &Secret{}
 	var createdAtStr, modifiedAtStr string
+	var secretType, nameIndex, deletedAtStr, expiresAtStr, rotatedAtStr sql.NullString
 
 	err := scanner.Scan(
 		&secret.Id,
 		&secret.UserId,
 		&secret.Name,
 		&secret.Value,
+		&secretType,
+		&nameIndex,
 		&createdAtStr,
 		&modifiedAtStr,
+		&deletedAtStr,
+		&expiresAtStr,
+		&secret.RotateEvery,
+		&rotatedAtStr,
 	)
 
 	if err == sql.ErrNoRows {
@@ -95,6 +87,9 @@ func scanSecret(scanner ccc.RowScanner) (*Secret, error) {
 		return nil, fmt.Errorf("scanning secret row: %w", err)
 	}
 
+	secret.Type = SecretType(secretType.String)
+	secret.NameIndex = nameIndex.String
+
 	secret.CreatedAt, err = ccc.ParseSQLiteTimestamp(createdAtStr)
 	if err != nil {
 		return nil, fmt.Errorf("parsing CreatedAt for secret %s: %w", secret.Id, err)
@@ -103,23 +98,76 @@ func scanSecret(scanner ccc.RowScanner) (*Secret, error) {
 	if err != nil {
 		return nil, fmt.Errorf("parsing ModifiedAt for secret %s: %w", secret.Id, err)
 	}
+	if deletedAtStr.Valid {
+		deletedAt, err := ccc.ParseSQLiteTimestamp(deletedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing DeletedAt for secret %s: %w", secret.Id, err)
+		}
+		secret.DeletedAt = &deletedAt
+	}
+	if expiresAtStr.Valid {
+		expiresAt, err := ccc.ParseSQLiteTimestamp(expiresAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing ExpiresAt for secret %s: %w", secret.Id, err)
+		}
+		secret.ExpiresAt = &expiresAt
+	}
+	// Secrets stored before rotation was tracked count as rotated when they were last modified
+	secret.RotatedAt = secret.ModifiedAt
+	if rotatedAtStr.Valid {
+		secret.RotatedAt, err = ccc.ParseSQLiteTimestamp(rotatedAtStr.String)
+		if err != nil {
+			return nil, fmt.Errorf("parsing RotatedAt for secret %s: %w", secret.Id, err)
+		}
+	}
 
 	return secret, nil
 }
 
-// FindById retrieves a secret by its ID.
+// FindById retrieves a secret by its ID. Secrets in the trash are not found.
 func (repo *SQLiteSecretRepository) FindById(secretId string) (*Secret, error) {
-	query := fmt.Sprintf("SELECT %s FROM Secret WHERE Id = ?", secretFieldList)
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE Id = ? AND DeletedAt IS NULL", secretFieldList)
 	row := repo.db.QueryRow(query, secretId)
 	return scanSecret(row)
 }
 
-// FindByUserId retrieves all secrets for a given user ID.
+// FindByUserId retrieves all secrets for a given user ID, except for those in the trash.
 func (repo *SQLiteSecretRepository) FindByUserId(userId string) ([]*Secret, error) {
-	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ?", secretFieldList)
-	rows, err := repo.db.Query(query, userId)
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND DeletedAt IS NULL", secretFieldList)
+	return repo.findSecrets(query, userId)
+}
+
+// FindTrashedByUserId retrieves all secrets of a user that are in the trash, most recently deleted first.
+func (repo *SQLiteSecretRepository) FindTrashedByUserId(userId string) ([]*Secret, error) {
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND DeletedAt IS NOT NULL ORDER BY DeletedAt DESC", secretFieldList)
+	return repo.findSecrets(query, userId)
+}
+
+// FindTrashedByIdForUser retrieves a secret in the trash by user ID and secret ID.
+func (repo *SQLiteSecretRepository) FindTrashedByIdForUser(userId, secretId string) (*Secret, error) {
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND Id = ? AND DeletedAt IS NOT NULL", secretFieldList)
+	row := repo.db.QueryRow(query, userId, secretId)
+	return scanSecret(row)
+}
+
+// FindTrashedBefore retrieves the secrets of all users that were moved to the trash before the given time.
+func (repo *SQLiteSecretRepository) FindTrashedBefore(deletedBefore time.Time) ([]*Secret, error) {
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE DeletedAt IS NOT NULL AND DeletedAt < ?", secretFieldList)
+	return repo.findSecrets(query, ccc.FormatSQLiteTimestamp(deletedBefore))
+}
+
+// FindWithDueDates retrieves the secrets of all users that have an expiry date or a rotation interval,
+// except for those in the trash.
+func (repo *SQLiteSecretRepository) FindWithDueDates() ([]*Secret, error) {
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE (ExpiresAt IS NOT NULL OR RotateEvery > 0) AND DeletedAt IS NULL", secretFieldList)
+	return repo.findSecrets(query)
+}
+
+// findSecrets retrieves all secrets matched by a query.
+func (repo *SQLiteSecretRepository) findSecrets(query string, args ...any) ([]*Secret, error) {
+	rows, err := repo.db.Query(query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("querying secrets by user ID %s: %w", userId, err)
+		return nil, fmt.Errorf("querying secrets: %w", err)
 	}
 	defer rows.Close()
 
@@ -133,7 +181,7 @@ func (repo *SQLiteSecretRepository) FindByUserId(userId string) ([]*Secret, erro
 		secrets = append(secrets, secret)
 	}
 	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterating secret rows for user ID %s: %w", userId, err)
+		return nil, fmt.Errorf("iterating secret rows: %w", err)
 	}
 	return secrets, nil
 }
@@ -141,42 +189,37 @@ func (repo *SQLiteSecretRepository) FindByUserId(userId string) ([]*Secret, erro
 // FindByIdForUser retrieves a secret by user ID and secret ID.
 func (repo *SQLiteSecretRepository) FindByIdForUser(userId, secretId string) (*Secret, error) {
 
-	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND Id = ?", secretFieldList)
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND Id = ? AND DeletedAt IS NULL", secretFieldList)
 
 	row := repo.db.QueryRow(query, userId, secretId)
 
 	return scanSecret(row)
 }
 
-// FindByNameForUser retrieves a secret by user ID and its name.
-// The 'name' parameter is expected to be the stored (potentially encrypted) name.
-func (repo *SQLiteSecretRepository) FindByNameForUser(userId, name string) (*Secret, error) {
-	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND Name = ?", secretFieldList)
-	row := repo.db.QueryRow(query, userId, name)
+// FindByNameIndexForUser retrieves a secret by user ID and the blind index of its name. Secrets in the trash are not found.
+func (repo *SQLiteSecretRepository) FindByNameIndexForUser(userId, nameIndex string) (*Secret, error) {
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND NameIndex = ? AND DeletedAt IS NULL", secretFieldList)
+	row := repo.db.QueryRow(query, userId, nameIndex)
 	return scanSecret(row)
 }
 
+// FindWithoutNameIndexForUser retrieves all secrets of a user that do not have a name index yet.
+// These are secrets created before blind indexing was introduced and need to be backfilled.
+// Secrets in the trash are included, so that a name conflict can be detected when they are restored.
+func (repo *SQLiteSecretRepository) FindWithoutNameIndexForUser(userId string) ([]*Secret, error) {
+	query := fmt.Sprintf("SELECT %s FROM Secret WHERE UserId = ? AND (NameIndex IS NULL OR NameIndex = '')", secretFieldList)
+	return repo.findSecrets(query, userId)
+}
+
 // Add adds a new secret to the database.
 func (repo *SQLiteSecretRepository) Add(secret *Secret) (bool, error) {
-	query := fmt.Sprintf("INSERT INTO Secret (%s) VALUES (?, ?, ?, ?, ?, ?)", secretFieldList)
-
-	stmt, err := repo.db.Prepare(query)
+	stmt, err := repo.db.Prepare(addSecretQuery)
 	if err != nil {
 		return false, fmt.Errorf("preparing add secret statement: %w", err)
 	}
 	defer stmt.Close()
 
-	createdAtStr := ccc.FormatSQLiteTimestamp(secret.CreatedAt)
-	modifiedAtStr := ccc.FormatSQLiteTimestamp(secret.ModifiedAt)
-
-	result, err := stmt.Exec(
-		secret.Id,
-		secret.UserId,
-		secret.Name,
-		secret.Value,
-		createdAtStr,
-		modifiedAtStr,
-	)
+	result, err := stmt.Exec(addSecretArgs(secret)...)
 	if err != nil {
 		return false, fmt.Errorf("executing add secret statement for ID %s: %w", secret.Id, err)
 	}
@@ -188,16 +231,45 @@ func (repo *SQLiteSecretRepository) Add(secret *Secret) (bool, error) {
 	return rowsAffected > 0, nil
 }
 
-// Remove deletes a secret by its ID.
+// MoveToTrash marks a secret as deleted. It returns false if the secret does not exist or is already in the trash.
+func (repo *SQLiteSecretRepository) MoveToTrash(secretId string, deletedAt time.Time) (bool, error) {
+	result, err := repo.db.Exec("UPDATE Secret SET DeletedAt = ? WHERE Id = ? AND DeletedAt IS NULL", ccc.FormatSQLiteTimestamp(deletedAt), secretId)
+	if err != nil {
+		return false, fmt.Errorf("moving secret ID %s to the trash: %w", secretId, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("getting rows affected after moving secret ID %s to the trash: %w", secretId, err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// RestoreFromTrash clears the deletion mark of a secret. It returns false if the secret is not in the trash.
+func (repo *SQLiteSecretRepository) RestoreFromTrash(secretId string) (bool, error) {
+	result, err := repo.db.Exec("UPDATE Secret SET DeletedAt = NULL WHERE Id = ? AND DeletedAt IS NOT NULL", secretId)
+	if err != nil {
+		return false, fmt.Errorf("restoring secret ID %s from the trash: %w", secretId, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("getting rows affected after restoring secret ID %s: %w", secretId, err)
+	}
+	return rowsAffected > 0, nil
+}
+
+// Remove deletes a secret by its ID together with its prior versions.
 func (repo *SQLiteSecretRepository) Remove(secretId string) (bool, error) {
-	query := "DELETE FROM Secret WHERE Id = ?"
-	stmt, err := repo.db.Prepare(query)
+	tx, err := repo.db.Begin()
 	if err != nil {
-		return false, fmt.Errorf("preparing remove secret statement: %w", err)
+		return false, fmt.Errorf("beginning transaction: %w", err)
 	}
-	defer stmt.Close()
+	defer tx.Rollback() // Will be ignored if tx.Commit() succeeds
 
-	result, err := stmt.Exec(secretId)
+	if _, err := tx.Exec("DELETE FROM SecretVersion WHERE SecretId = ?", secretId); err != nil {
+		return false, fmt.Errorf("removing versions of secret ID %s: %w", secretId, err)
+	}
+
+	result, err := tx.Exec("DELETE FROM Secret WHERE Id = ?", secretId)
 	if err != nil {
 		return false, fmt.Errorf("executing remove secret statement for ID %s: %w", secretId, err)
 	}
@@ -206,37 +278,22 @@ func (repo *SQLiteSecretRepository) Remove(secretId string) (bool, error) {
 	if err != nil {
 		return false, fmt.Errorf("getting rows affected after removing secret ID %s: %w", secretId, err)
 	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("committing transaction: %w", err)
+	}
 	return rowsAffected > 0, nil
 }
 
 // Update modifies an existing secret in the database.
 func (repo *SQLiteSecretRepository) Update(secret *Secret) (bool, error) {
-	query := `
-	UPDATE Secret SET 
-		UserId = ?, 
-		Name = ?, 
-		Value = ?, 
-		CreatedAt = ?, 
-		ModifiedAt = ?
-	WHERE Id = ?`
-
-	stmt, err := repo.db.Prepare(query)
+	stmt, err := repo.db.Prepare(updateSecretQuery)
 	if err != nil {
 		return false, fmt.Errorf("preparing update secret statement: %w", err)
 	}
 	defer stmt.Close()
 
-	createdAtStr := ccc.FormatSQLiteTimestamp(secret.CreatedAt)
-	modifiedAtStr := ccc.FormatSQLiteTimestamp(secret.ModifiedAt)
-
-	result, err := stmt.Exec(
-		secret.UserId,
-		secret.Name,
-		secret.Value,
-		createdAtStr,
-		modifiedAtStr,
-		secret.Id,
-	)
+	result, err := stmt.Exec(updateSecretArgs(secret)...)
 	if err != nil {
 		return false, fmt.Errorf("executing update secret statement for ID %s: %w", secret.Id, err)
 	}
@@ -247,3 +304,233 @@ func (repo *SQLiteSecretRepository) Update(secret *Secret) (bool, error) {
 	}
 	return rowsAffected > 0, nil
 }
+
+// SaveBatch adds and updates the given secrets and records the prior versions of updated secrets in a single
+// transaction, keeping only the newest keepVersions versions of each secret.
+// If any of the statements fails or an updated secret no longer exists, none of the changes are stored.
+func (repo *SQLiteSecretRepository) SaveBatch(added []*Secret, updated []*Secret, versions []*SecretVersion, keepVersions int) error {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() succeeds
+
+	if len(added) > 0 {
+		addStmt, err := tx.Prepare(addSecretQuery)
+		if err != nil {
+			return fmt.Errorf("preparing add secret statement: %w", err)
+		}
+		defer addStmt.Close()
+
+		for _, secret := range added {
+			if _, err := addStmt.Exec(addSecretArgs(secret)...); err != nil {
+				return fmt.Errorf("executing add secret statement for ID %s: %w", secret.Id, err)
+			}
+		}
+	}
+
+	if len(updated) > 0 {
+		updateStmt, err := tx.Prepare(updateSecretQuery)
+		if err != nil {
+			return fmt.Errorf("preparing update secret statement: %w", err)
+		}
+		defer updateStmt.Close()
+
+		for _, secret := range updated {
+			result, err := updateStmt.Exec(updateSecretArgs(secret)...)
+			if err != nil {
+				return fmt.Errorf("executing update secret statement for ID %s: %w", secret.Id, err)
+			}
+			rowsAffected, err := result.RowsAffected()
+			if err != nil {
+				return fmt.Errorf("getting rows affected after updating secret ID %s: %w", secret.Id, err)
+			}
+			if rowsAffected == 0 {
+				return fmt.Errorf("secret ID %s no longer exists", secret.Id)
+			}
+		}
+	}
+
+	for _, version := range versions {
+		if err := addSecretVersion(tx, version, keepVersions); err != nil {
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("committing transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateWithVersion updates a secret and records its prior version in a single transaction,
+// keeping only the newest keepVersions versions of the secret.
+func (repo *SQLiteSecretRepository) UpdateWithVersion(secret *Secret, version *SecretVersion, keepVersions int) (bool, error) {
+	tx, err := repo.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("beginning transaction: %w", err)
+	}
+	defer tx.Rollback() // Will be ignored if tx.Commit() succeeds
+
+	result, err := tx.Exec(updateSecretQuery, updateSecretArgs(secret)...)
+	if err != nil {
+		return false, fmt.Errorf("executing update secret statement for ID %s: %w", secret.Id, err)
+	}
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("getting rows affected after updating secret ID %s: %w", secret.Id, err)
+	}
+	if rowsAffected == 0 {
+		return false, nil
+	}
+
+	if err := addSecretVersion(tx, version, keepVersions); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("committing transaction: %w", err)
+	}
+	return true, nil
+}
+
+// addSecretVersion records a prior version of a secret and removes the versions beyond keepVersions
+func addSecretVersion(tx *sql.Tx, version *SecretVersion, keepVersions int) error {
+	_, err := tx.Exec(addSecretVersionQuery,
+		version.Id,
+		version.SecretId,
+		version.UserId,
+		version.Name,
+		version.Value,
+		nullableString(string(version.Type)),
+		ccc.FormatSQLiteTimestamp(version.ModifiedAt),
+		ccc.FormatSQLiteTimestamp(version.ReplacedAt),
+	)
+	if err != nil {
+		return fmt.Errorf("adding version of secret ID %s: %w", version.SecretId, err)
+	}
+
+	if _, err := tx.Exec(pruneSecretVersionsQuery, version.SecretId, version.SecretId, keepVersions); err != nil {
+		return fmt.Errorf("pruning versions of secret ID %s: %w", version.SecretId, err)
+	}
+	return nil
+}
+
+// scanSecretVersion scans a database row into a SecretVersion struct.
+func scanSecretVersion(scanner ccc.RowScanner) (*SecretVersion, error) {
+	version := &SecretVersion{}
+	var modifiedAtStr, replacedAtStr string
+	var secretType sql.NullString
+
+	err := scanner.Scan(
+		&version.Id,
+		&version.SecretId,
+		&version.UserId,
+		&version.Name,
+		&version.Value,
+		&secretType,
+		&modifiedAtStr,
+		&replacedAtStr,
+	)
+
+	if err == sql.ErrNoRows {
+		return nil, nil // Not found
+	}
+	if err != nil {
+		return nil, fmt.Errorf("scanning secret version row: %w", err)
+	}
+
+	version.Type = SecretType(secretType.String)
+
+	version.ModifiedAt, err = ccc.ParseSQLiteTimestamp(modifiedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ModifiedAt for secret version %s: %w", version.Id, err)
+	}
+	version.ReplacedAt, err = ccc.ParseSQLiteTimestamp(replacedAtStr)
+	if err != nil {
+		return nil, fmt.Errorf("parsing ReplacedAt for secret version %s: %w", version.Id, err)
+	}
+
+	return version, nil
+}
+
+// FindVersionsForUser retrieves the prior versions of a secret of the given user, newest first.
+func (repo *SQLiteSecretRepository) FindVersionsForUser(userId, secretId string) ([]*SecretVersion, error) {
+	query := fmt.Sprintf("SELECT %s FROM SecretVersion WHERE UserId = ? AND SecretId = ? ORDER BY ReplacedAt DESC, rowid DESC", secretVersionFieldList)
+	rows, err := repo.db.Query(query, userId, secretId)
+	if err != nil {
+		return nil, fmt.Errorf("querying versions of secret ID %s: %w", secretId, err)
+	}
+	defer rows.Close()
+
+	var versions []*SecretVersion
+	for rows.Next() {
+		version, err := scanSecretVersion(rows)
+		if err != nil {
+			return nil, err
+		}
+		versions = append(versions, version)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterating version rows of secret ID %s: %w", secretId, err)
+	}
+	return versions, nil
+}
+
+// FindVersionByIdForUser retrieves a prior version of a secret by user ID and version ID.
+func (repo *SQLiteSecretRepository) FindVersionByIdForUser(userId, versionId string) (*SecretVersion, error) {
+	query := fmt.Sprintf("SELECT %s FROM SecretVersion WHERE UserId = ? AND Id = ?", secretVersionFieldList)
+	row := repo.db.QueryRow(query, userId, versionId)
+	return scanSecretVersion(row)
+}
+
+// addSecretArgs returns the arguments of addSecretQuery for a secret
+func addSecretArgs(secret *Secret) []any {
+	return []any{
+		secret.Id,
+		secret.UserId,
+		secret.Name,
+		secret.Value,
+		nullableString(string(secret.Type)),
+		nullableString(secret.NameIndex),
+		ccc.FormatSQLiteTimestamp(secret.CreatedAt),
+		ccc.FormatSQLiteTimestamp(secret.ModifiedAt),
+		nullableTimestamp(secret.DeletedAt),
+		nullableTimestamp(secret.ExpiresAt),
+		secret.RotateEvery,
+		ccc.FormatSQLiteTimestamp(secret.RotatedAt),
+	}
+}
+
+// updateSecretArgs returns the arguments of updateSecretQuery for a secret
+func updateSecretArgs(secret *Secret) []any {
+	return []any{
+		secret.UserId,
+		secret.Name,
+		secret.Value,
+		nullableString(string(secret.Type)),
+		nullableString(secret.NameIndex),
+		ccc.FormatSQLiteTimestamp(secret.CreatedAt),
+		ccc.FormatSQLiteTimestamp(secret.ModifiedAt),
+		nullableTimestamp(secret.ExpiresAt),
+		secret.RotateEvery,
+		ccc.FormatSQLiteTimestamp(secret.RotatedAt),
+		secret.Id,
+	}
+}
+
+// nullableTimestamp maps a nil time to NULL
+func nullableTimestamp(value *time.Time) any {
+	if value == nil {
+		return nil
+	}
+	return ccc.FormatSQLiteTimestamp(*value)
+}
+
+// nullableString maps an empty string to NULL, e.g. so that unindexed rows are not covered by the unique name index.
+func nullableString(value string) any {
+	if value == "" {
+		return nil
+	}
+	return value
+}
@@ -0,0 +1,145 @@
+package secrets
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base32"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// One-time passwords of stored secrets follow RFC 6238 like the second factor of users, but the
+// parameters are chosen by the service that issued the seed. Seeds without parameters use the
+// defaults of authenticator apps.
+const (
+	defaultTotpDigits = 6
+	defaultTotpPeriod = 30
+)
+
+var totpSeedEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// totpParameters holds the key and parameters of a one-time password seed
+type totpParameters struct {
+	key       []byte
+	algorithm func() hash.Hash
+	digits    int
+	period    int
+}
+
+// ValidateTotpSeed checks that the value is an otpauth:// URI or base32 seed of a time-based one-time password
+func ValidateTotpSeed(value string) error {
+	_, err := parseTotpSeed(value)
+	return err
+}
+
+// parseTotpSeed parses an otpauth://totp/ URI, as encoded in the QR codes of services, or a base32 seed
+func parseTotpSeed(value string) (*totpParameters, error) {
+	value = strings.TrimSpace(value)
+	params := &totpParameters{algorithm: sha1.New, digits: defaultTotpDigits, period: defaultTotpPeriod}
+
+	if !strings.HasPrefix(strings.ToLower(value), "otpauth://") {
+		key, err := decodeTotpSeed(value)
+		if err != nil {
+			return nil, err
+		}
+		params.key = key
+		return params, nil
+	}
+
+	uri, err := url.Parse(value)
+	if err != nil {
+		return nil, errors.New("invalid otpauth URI")
+	}
+	if !strings.EqualFold(uri.Host, "totp") {
+		return nil, errors.New("only time-based one-time passwords are supported")
+	}
+
+	query := uri.Query()
+	if params.key, err = decodeTotpSeed(query.Get("secret")); err != nil {
+		return nil, err
+	}
+
+	switch strings.ToUpper(query.Get("algorithm")) {
+	case "", "SHA1":
+	case "SHA256":
+		params.algorithm = sha256.New
+	case "SHA512":
+		params.algorithm = sha512.New
+	default:
+		return nil, fmt.Errorf("unsupported algorithm %q", query.Get("algorithm"))
+	}
+
+	if digits := query.Get("digits"); digits != "" {
+		params.digits, err = strconv.Atoi(digits)
+		if err != nil || params.digits < 6 || params.digits > 8 {
+			return nil, fmt.Errorf("unsupported number of digits %q", digits)
+		}
+	}
+	if period := query.Get("period"); period != "" {
+		params.period, err = strconv.Atoi(period)
+		if err != nil || params.period < 1 || params.period > 300 {
+			return nil, fmt.Errorf("unsupported period %q", period)
+		}
+	}
+
+	return params, nil
+}
+
+// decodeTotpSeed decodes a base32 seed, tolerating lower case letters, spaces, dashes and padding
+func decodeTotpSeed(seed string) ([]byte, error) {
+	normalized := strings.ToUpper(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(seed)))
+	normalized = strings.TrimRight(normalized, "=")
+
+	key, err := totpSeedEncoding.DecodeString(normalized)
+	if err != nil || len(key) == 0 {
+		return nil, errors.New("invalid base32 seed")
+	}
+	return key, nil
+}
+
+// generate returns the code for the given point in time and the seconds until it expires
+func (p *totpParameters) generate(t time.Time) (string, int) {
+	unix := t.Unix()
+	counter := unix / int64(p.period)
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(counter))
+
+	mac := hmac.New(p.algorithm, p.key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+
+	modulo := uint32(1)
+	for i := 0; i < p.digits; i++ {
+		modulo *= 10
+	}
+
+	return fmt.Sprintf("%0*d", p.digits, value%modulo), p.period - int(unix%int64(p.period))
+}
+
+// generateTotpCode generates the current one-time password of a field
+func generateTotpCode(field SecretField, t time.Time) (*TotpCodeDto, error) {
+	params, err := parseTotpSeed(field.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	code, remaining := params.generate(t)
+	return &TotpCodeDto{
+		FieldName:        field.Name,
+		Code:             code,
+		Period:           params.period,
+		SecondsRemaining: remaining,
+	}, nil
+}
@@ -0,0 +1,86 @@
+package secrets
+
+import (
+	"encoding/base32"
+	"testing"
+	"time"
+)
+
+// RFC 6238 Appendix B test vectors, given as otpauth URIs with the parameters of each algorithm
+func TestTotpCodesMatchRFC6238Vectors(t *testing.T) {
+	seed := func(key string) string {
+		return base32.StdEncoding.EncodeToString([]byte(key))
+	}
+
+	vectors := []struct {
+		value string
+		unix  int64
+		code  string
+	}{
+		{seed("12345678901234567890"), 59, "287082"},
+		{"otpauth://totp/ACME:jane?secret=" + seed("12345678901234567890") + "&digits=8", 1111111109, "07081804"},
+		{"otpauth://totp/ACME:jane?secret=" + seed("12345678901234567890123456789012") + "&algorithm=SHA256&digits=8", 1234567890, "91819424"},
+		{"otpauth://totp/ACME:jane?secret=" + seed("1234567890123456789012345678901234567890123456789012345678901234") + "&algorithm=SHA512&digits=8", 2000000000, "38618901"},
+	}
+
+	for _, v := range vectors {
+		code, err := generateTotpCode(SecretField{Name: "2FA", Value: v.value}, time.Unix(v.unix, 0))
+		if err != nil {
+			t.Fatalf("generateTotpCode failed for %s: %v", v.value, err)
+		}
+		if code.Code != v.code {
+			t.Errorf("expected code %s at %d, got %s", v.code, v.unix, code.Code)
+		}
+	}
+
+	code, _ := generateTotpCode(SecretField{Value: "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&period=60"}, time.Unix(100, 0))
+	if code.Period != 60 || code.SecondsRemaining != 20 {
+		t.Errorf("unexpected period %d and remaining seconds %d", code.Period, code.SecondsRemaining)
+	}
+
+	for _, invalid := range []string{"", "not base32!", "otpauth://hotp/x?secret=JBSWY3DPEHPK3PXP&counter=1", "otpauth://totp/x?secret=JBSWY3DPEHPK3PXP&algorithm=MD5"} {
+		if err := ValidateTotpSeed(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestSecretsWithTotpFieldHaveCurrentCode(t *testing.T) {
+	manager, dataProtector := newSecretTestManager(t)
+
+	request := UpsertSecretRequest{
+		SecretName: "GitHub",
+		SecretType: SecretTypeLogin,
+		Fields: []SecretField{
+			{Name: "", Value: "jbsw y3dp ehpk 3pxp", Kind: FieldKindTotp},
+			{Name: "Password", Value: "hunter2", Concealed: true},
+		},
+	}
+	created, err := manager.CreateSecret("user-1", request, dataProtector)
+	if err != nil {
+		t.Fatalf("CreateSecret failed: %v", err)
+	}
+
+	secret, err := manager.GetSecret("user-1", created.SecretId, dataProtector)
+	if err != nil {
+		t.Fatalf("GetSecret failed: %v", err)
+	}
+	if secret.Value != "hunter2" || !secret.Fields[0].Concealed || secret.Fields[0].Name != TotpFieldName {
+		t.Errorf("unexpected secret %+v", secret)
+	}
+	code, err := manager.GetTotpCode("user-1", created.SecretId, dataProtector)
+	if err != nil || len(code.Code) != 6 || code.SecondsRemaining < 1 || code.SecondsRemaining > 30 {
+		t.Errorf("unexpected code %+v (%v)", code, err)
+	}
+
+	request.SecretName = "Invalid"
+	request.Fields[0].Value = "otpauth://totp/x?secret=!!"
+	if _, err := manager.CreateSecret("user-1", request, dataProtector); err == nil {
+		t.Errorf("expected an invalid seed to be rejected")
+	}
+
+	created, _ = manager.CreateSecret("user-1", UpsertSecretRequest{SecretName: "Plain", SecretValue: "x"}, dataProtector)
+	if _, err := manager.GetTotpCode("user-1", created.SecretId, dataProtector); err == nil {
+		t.Errorf("expected secrets without one-time password field to have no code")
+	}
+}
@@ -0,0 +1,215 @@
+package trash
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// DefaultTrashManager implements TrashManager on top of the secret, document and tag managers,
+// which soft delete their items and purge them on request
+type DefaultTrashManager struct {
+	secretManager   secrets.SecretManager
+	documentManager documents.DocumentManager
+	tagManager      documents.TagManager
+	retentionDays   int
+	logger          ccc.Logger
+}
+
+// NewDefaultTrashManager creates a trash manager that purges items after retentionDays days.
+// A retentionDays of 0 keeps items in the trash until they are purged manually.
+func NewDefaultTrashManager(
+	secretManager secrets.SecretManager,
+	documentManager documents.DocumentManager,
+	tagManager documents.TagManager,
+	retentionDays int,
+	logger ccc.Logger,
+) *DefaultTrashManager {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &DefaultTrashManager{
+		secretManager:   secretManager,
+		documentManager: documentManager,
+		tagManager:      tagManager,
+		retentionDays:   max(retentionDays, 0),
+		logger:          logger,
+	}
+}
+
+// GetTrash lists the trashed secrets, documents and tags of a user, most recently deleted first
+func (m *DefaultTrashManager) GetTrash(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TrashItemDto, error) {
+	trashedSecrets, err := m.secretManager.GetTrashedSecrets(userId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+	trashedDocuments, err := m.documentManager.GetTrashedDocuments(ctx, userId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+	trashedTags, err := m.tagManager.GetTrashedTags(ctx, userId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]*TrashItemDto, 0, len(trashedSecrets)+len(trashedDocuments)+len(trashedTags))
+	for _, secret := range trashedSecrets {
+		items = append(items, m.newTrashItem(ItemTypeSecret, secret.Id, secret.Name, secret.Type.DisplayName(), "", secret.DeletedAt))
+	}
+	for _, document := range trashedDocuments {
+		detail := fmt.Sprintf("%d files", document.FileCount)
+		if document.FileCount == 1 {
+			detail = "1 file"
+		}
+		items = append(items, m.newTrashItem(ItemTypeDocument, document.Id, document.Title, detail, "", document.DeletedAt))
+	}
+	for _, tag := range trashedTags {
+		items = append(items, m.newTrashItem(ItemTypeTag, tag.Id, tag.Name, "", tag.Color, tag.DeletedAt))
+	}
+
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].DeletedAt.After(items[j].DeletedAt)
+	})
+
+	return items, nil
+}
+
+// newTrashItem maps a trashed item to a DTO and computes when it is purged
+func (m *DefaultTrashManager) newTrashItem(itemType ItemType, id, name, detail, color string, deletedAt time.Time) *TrashItemDto {
+	item := &TrashItemDto{
+		Id:        id,
+		Type:      itemType,
+		Name:      name,
+		Detail:    detail,
+		Color:     color,
+		DeletedAt: deletedAt,
+	}
+	if m.retentionDays > 0 {
+		purgeAt := deletedAt.AddDate(0, 0, m.retentionDays)
+		item.PurgeAt = &purgeAt
+	}
+	return item
+}
+
+// RestoreItem moves a secret, document or tag of the user out of the trash
+func (m *DefaultTrashManager) RestoreItem(ctx context.Context, userId string, itemType ItemType, itemId string, dataProtector dataprotection.DataProtector) error {
+	m.logger.Info("Restoring item from the trash", "user_id", userId, "item_type", itemType, "item_id", itemId)
+
+	switch itemType {
+	case ItemTypeSecret:
+		restored, err := m.secretManager.RestoreSecret(userId, itemId, dataProtector)
+		if err != nil {
+			return err
+		}
+		if !restored {
+			return ccc.NewResourceNotFoundError(itemId, "Secret")
+		}
+		return nil
+	case ItemTypeDocument:
+		return m.documentManager.RestoreDocument(ctx, userId, itemId)
+	case ItemTypeTag:
+		return m.tagManager.RestoreTag(ctx, userId, itemId, dataProtector)
+	default:
+		_, err := ParseItemType(string(itemType))
+		return err
+	}
+}
+
+// PurgeItem permanently deletes a secret, document or tag of the user that is in the trash
+func (m *DefaultTrashManager) PurgeItem(ctx context.Context, userId string, itemType ItemType, itemId string) error {
+	m.logger.Info("Purging item from the trash", "user_id", userId, "item_type", itemType, "item_id", itemId)
+
+	switch itemType {
+	case ItemTypeSecret:
+		purged, err := m.secretManager.PurgeSecret(userId, itemId)
+		if err != nil {
+			return err
+		}
+		if !purged {
+			return ccc.NewResourceNotFoundError(itemId, "Secret")
+		}
+		return nil
+	case ItemTypeDocument:
+		return m.documentManager.PurgeDocument(ctx, userId, itemId)
+	case ItemTypeTag:
+		return m.tagManager.PurgeTag(ctx, userId, itemId)
+	default:
+		_, err := ParseItemType(string(itemType))
+		return err
+	}
+}
+
+// EmptyTrash permanently deletes all items in the trash of a user. Items purged before an error occurred
+// stay purged; the returned summary counts them.
+func (m *DefaultTrashManager) EmptyTrash(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) (*PurgeSummary, error) {
+	m.logger.Info("Emptying trash", "user_id", userId)
+
+	items, err := m.GetTrash(ctx, userId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := &PurgeSummary{}
+	for _, item := range items {
+		if err := m.PurgeItem(ctx, userId, item.Type, item.Id); err != nil {
+			m.logger.Error("Failed to empty trash", "user_id", userId, "item_type", item.Type, "item_id", item.Id, "error", err)
+			return summary, err
+		}
+		summary.count(item.Type, 1)
+	}
+
+	m.logger.Info("Trash emptied", "user_id", userId, "purged", summary.Total())
+	return summary, nil
+}
+
+// PurgeExpired permanently deletes the items of all users that were moved to the trash more than
+// the retention period before now. All item types are purged even if one of them fails; the first
+// error is returned together with the summary of the purged items.
+func (m *DefaultTrashManager) PurgeExpired(ctx context.Context, now time.Time) (*PurgeSummary, error) {
+	summary := &PurgeSummary{}
+	if m.retentionDays == 0 {
+		return summary, nil
+	}
+
+	deletedBefore := now.AddDate(0, 0, -m.retentionDays)
+	m.logger.Debug("Purging expired items from the trash", "deleted_before", deletedBefore)
+
+	var firstErr error
+	keepFirst := func(err error) {
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	purged, err := m.secretManager.PurgeTrashedSecrets(deletedBefore)
+	summary.count(ItemTypeSecret, purged)
+	keepFirst(err)
+
+	purged, err = m.documentManager.PurgeTrashedDocuments(ctx, deletedBefore)
+	summary.count(ItemTypeDocument, purged)
+	keepFirst(err)
+
+	purged, err = m.tagManager.PurgeTrashedTags(ctx, deletedBefore)
+	summary.count(ItemTypeTag, purged)
+	keepFirst(err)
+
+	return summary, firstErr
+}
+
+// count adds purged items of a type to the summary
+func (s *PurgeSummary) count(itemType ItemType, n int) {
+	switch itemType {
+	case ItemTypeSecret:
+		s.Secrets += n
+	case ItemTypeDocument:
+		s.Documents += n
+	case ItemTypeTag:
+		s.Tags += n
+	}
+}
@@ -0,0 +1,24 @@
+package trash
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+)
+
+// TrashManager manages the deleted secrets, documents and tags of users, which stay in the trash
+// until they are restored or purged
+type TrashManager interface {
+	// GetTrash lists the items in the trash of a user, most recently deleted first
+	GetTrash(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) ([]*TrashItemDto, error)
+	// RestoreItem moves an item out of the trash. Secrets and tags whose name has been taken in the meantime cannot be restored.
+	RestoreItem(ctx context.Context, userId string, itemType ItemType, itemId string, dataProtector dataprotection.DataProtector) error
+	// PurgeItem permanently deletes an item in the trash
+	PurgeItem(ctx context.Context, userId string, itemType ItemType, itemId string) error
+	// EmptyTrash permanently deletes all items in the trash of a user
+	EmptyTrash(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) (*PurgeSummary, error)
+	// PurgeExpired permanently deletes the items of all users that have been in the trash longer than the retention period.
+	// Nothing is purged if the retention period is 0.
+	PurgeExpired(ctx context.Context, now time.Time) (*PurgeSummary, error)
+}
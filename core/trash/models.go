@@ -0,0 +1,70 @@
+package trash
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// ItemType tells which kind of item is in the trash
+type ItemType string
+
+const (
+	ItemTypeSecret   ItemType = "secret"
+	ItemTypeDocument ItemType = "document"
+	ItemTypeTag      ItemType = "tag"
+)
+
+// ParseItemType parses the name of an item type
+func ParseItemType(name string) (ItemType, error) {
+	switch itemType := ItemType(strings.ToLower(strings.TrimSpace(name))); itemType {
+	case ItemTypeSecret, ItemTypeDocument, ItemTypeTag:
+		return itemType, nil
+	default:
+		return "", ccc.NewInvalidInputErrorWithMessage(
+			"item type",
+			fmt.Sprintf("unknown item type %q", name),
+			"Unknown item type. Valid types are secret, document and tag.",
+		)
+	}
+}
+
+// DisplayName returns the name of the item type shown to users
+func (t ItemType) DisplayName() string {
+	switch t {
+	case ItemTypeSecret:
+		return "Secret"
+	case ItemTypeDocument:
+		return "Document"
+	default:
+		return "Tag"
+	}
+}
+
+// TrashItemDto is a secret, document or tag in the trash with its decrypted name
+type TrashItemDto struct {
+	Id   string
+	Type ItemType
+	Name string
+	// Detail describes the item, e.g. the type of a secret or the number of files of a document
+	Detail string
+	// Color is the color of a tag
+	Color     string
+	DeletedAt time.Time
+	// PurgeAt is when the item is purged, nil if items are kept until they are purged manually
+	PurgeAt *time.Time
+}
+
+// PurgeSummary counts the items purged from the trash
+type PurgeSummary struct {
+	Secrets   int
+	Documents int
+	Tags      int
+}
+
+// Total returns the number of purged items
+func (s PurgeSummary) Total() int {
+	return s.Secrets + s.Documents + s.Tags
+}
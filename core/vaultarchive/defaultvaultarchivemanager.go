@@ -0,0 +1,500 @@
+package vaultarchive
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+const (
+	// minPassphraseLength is the minimum length of the passphrase protecting a new archive
+	minPassphraseLength = 12
+
+	// maxManifestSize bounds the memory used to read the manifest of an archive
+	maxManifestSize = 64 * 1024 * 1024
+
+	// documentPageSize is the page size used to load all documents of a user
+	documentPageSize = 100
+)
+
+// DefaultVaultArchiveManager implements VaultArchiveManager on top of the secret and document managers,
+// so imported items are validated, encrypted and queued for text extraction like new ones
+type DefaultVaultArchiveManager struct {
+	secretManager       secrets.SecretManager
+	documentManager     documents.DocumentManager
+	documentFileManager documents.DocumentFileManager
+	tagManager          documents.TagManager
+	noteManager         documents.NoteManager
+	logger              ccc.Logger
+}
+
+// NewDefaultVaultArchiveManager creates a new DefaultVaultArchiveManager
+func NewDefaultVaultArchiveManager(
+	secretManager secrets.SecretManager,
+	documentManager documents.DocumentManager,
+	documentFileManager documents.DocumentFileManager,
+	tagManager documents.TagManager,
+	noteManager documents.NoteManager,
+	logger ccc.Logger,
+) *DefaultVaultArchiveManager {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	return &DefaultVaultArchiveManager{
+		secretManager:       secretManager,
+		documentManager:     documentManager,
+		documentFileManager: documentFileManager,
+		tagManager:          tagManager,
+		noteManager:         noteManager,
+		logger:              logger,
+	}
+}
+
+// exportedFile references the document file stored at an archive path
+type exportedFile struct {
+	path       string
+	documentId string
+	fileId     string
+}
+
+// ExportVault writes all data of the user to dst. If it fails, dst contains an incomplete
+// archive that is rejected on import and should be discarded.
+func (m *DefaultVaultArchiveManager) ExportVault(
+	ctx context.Context,
+	userId, passphrase string,
+	dataProtector dataprotection.DataProtector,
+	dst io.Writer,
+) (*VaultArchiveSummary, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if len(passphrase) < minPassphraseLength {
+		return nil, ccc.NewInvalidInputErrorWithMessage(
+			"passphrase",
+			fmt.Sprintf("must be at least %d characters", minPassphraseLength),
+			fmt.Sprintf("The archive passphrase must be at least %d characters long.", minPassphraseLength),
+		)
+	}
+
+	m.logger.Info("Exporting vault", "userId", userId)
+
+	manifest, files, err := m.buildManifest(ctx, userId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+
+	writer, err := newEncryptingWriter(dst, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	tarWriter := tar.NewWriter(writer)
+
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode vault manifest: %w", err)
+	}
+	if err := writeTarEntry(tarWriter, manifestPath, manifestData, manifest.ExportedAt); err != nil {
+		return nil, err
+	}
+
+	// Files are streamed one at a time to keep the memory usage independent of the vault and file sizes
+	for _, file := range files {
+		fileDto, content, err := m.documentFileManager.OpenDocumentFile(ctx, userId, file.documentId, file.fileId, dataProtector)
+		if err != nil {
+			return nil, err
+		}
+		if err := writeTarStream(tarWriter, file.path, content, fileDto.FileSize, fileDto.CreatedAt); err != nil {
+			return nil, ccc.NewOperationFailedError("export vault", fmt.Sprintf("file %s could not be exported: %v", file.fileId, err))
+		}
+	}
+
+	if err := tarWriter.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish vault archive: %w", err)
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+
+	summary := &VaultArchiveSummary{
+		Secrets:   len(manifest.Secrets),
+		Tags:      len(manifest.Tags),
+		Documents: len(manifest.Documents),
+		Files:     len(files),
+	}
+	for _, document := range manifest.Documents {
+		summary.Notes += len(document.Notes)
+	}
+
+	m.logger.Info("Vault exported", "userId", userId, "secrets", summary.Secrets, "documents", summary.Documents, "files", summary.Files)
+	return summary, nil
+}
+
+// buildManifest collects the decrypted data of the user and the files to export
+func (m *DefaultVaultArchiveManager) buildManifest(ctx context.Context, userId string, dataProtector dataprotection.DataProtector) (*vaultManifest, []exportedFile, error) {
+	manifest := &vaultManifest{
+		FormatVersion: manifestFormatVersion,
+		AppVersion:    ccc.AppVersion,
+		ExportedAt:    time.Now().UTC(),
+		Secrets:       []archivedSecret{},
+		Tags:          []archivedTag{},
+		Documents:     []archivedDocument{},
+	}
+
+	// Without a page size all secrets are returned
+	secretResponse, err := m.secretManager.GetSecrets(userId, secrets.GetSecretsRequest{SortBy: "Name", SortAsc: true}, dataProtector)
+	if err != nil {
+		return nil, nil, err
+	}
+	// The secret manager skips secrets it cannot decrypt, which must not go unnoticed here
+	if len(secretResponse.Secrets) != secretResponse.TotalCount {
+		return nil, nil, ccc.NewOperationFailedError("export vault",
+			fmt.Sprintf("%d secrets could not be decrypted", secretResponse.TotalCount-len(secretResponse.Secrets)))
+	}
+	for _, secret := range secretResponse.Secrets {
+		manifest.Secrets = append(manifest.Secrets, archivedSecret{
+			Name:       secret.Name,
+			Value:      secret.Value,
+			Type:       secret.Type,
+			Fields:     secret.Fields,
+			CreatedAt:  secret.CreatedAt,
+			ModifiedAt: secret.ModifiedAt,
+
+			ExpiresAt:   secret.ExpiresAt,
+			RotateEvery: secret.RotateEvery,
+		})
+	}
+
+	tags, err := m.tagManager.GetUserTags(ctx, userId, dataProtector)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, tag := range tags {
+		manifest.Tags = append(manifest.Tags, archivedTag{Name: tag.Name, Color: tag.Color})
+	}
+
+	var files []exportedFile
+	for page := 1; ; page++ {
+		response, err := m.documentManager.GetDocuments(ctx, userId, documents.GetDocumentsRequest{
+			Page:     page,
+			PageSize: documentPageSize,
+			SortBy:   "created_at",
+			SortAsc:  true,
+		}, dataProtector)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for _, document := range response.Documents {
+			archived := archivedDocument{
+				Title:       document.Title,
+				Description: document.Description,
+				Issuer:      document.Issuer,
+				IssueDate:   document.IssueDate,
+				Tags:        []string{},
+				Notes:       []archivedNote{},
+				Files:       []archivedFile{},
+				CreatedAt:   document.CreatedAt.UTC(),
+				ModifiedAt:  document.ModifiedAt.UTC(),
+			}
+			for _, tag := range document.Tags {
+				archived.Tags = append(archived.Tags, tag.Name)
+			}
+
+			notes, err := m.noteManager.GetDocumentNotes(ctx, userId, document.Id, dataProtector)
+			if err != nil {
+				return nil, nil, err
+			}
+			// Notes are listed newest first, but archived in the order they have to be recreated
+			for _, note := range slices.Backward(notes) {
+				archived.Notes = append(archived.Notes, archivedNote{Content: note.Content, CreatedAt: note.CreatedAt.UTC()})
+			}
+
+			documentFiles, err := m.documentFileManager.GetDocumentFilePreviews(ctx, userId, document.Id, dataProtector)
+			if err != nil {
+				return nil, nil, err
+			}
+			for _, file := range documentFiles {
+				path := fmt.Sprintf("files/%d", len(files)+1)
+				archived.Files = append(archived.Files, archivedFile{
+					Path:        path,
+					FileName:    file.FileName,
+					ContentType: file.ContentType,
+					Size:        file.FileSize,
+				})
+				files = append(files, exportedFile{path: path, documentId: document.Id, fileId: file.Id})
+			}
+
+			manifest.Documents = append(manifest.Documents, archived)
+		}
+
+		if page >= response.TotalPages {
+			break
+		}
+	}
+
+	return manifest, files, nil
+}
+
+// importedFile is a file of the archive whose document was already created
+type importedFile struct {
+	documentId string
+	file       archivedFile
+}
+
+// ImportVault recreates the content of the archive in src for the user
+func (m *DefaultVaultArchiveManager) ImportVault(
+	ctx context.Context,
+	userId, passphrase string,
+	dataProtector dataprotection.DataProtector,
+	src io.Reader,
+) (*VaultArchiveSummary, error) {
+	if userId == "" {
+		return nil, ccc.NewInvalidInputError("userId", "cannot be empty")
+	}
+	if passphrase == "" {
+		return nil, ccc.NewInvalidInputError("passphrase", "cannot be empty")
+	}
+
+	m.logger.Info("Importing vault", "userId", userId)
+
+	reader, err := newDecryptingReader(src, passphrase)
+	if err != nil {
+		return nil, archiveError(err)
+	}
+	tarReader := tar.NewReader(reader)
+
+	manifest, err := readManifest(tarReader)
+	if err != nil {
+		return nil, archiveError(err)
+	}
+
+	summary := &VaultArchiveSummary{RenamedSecrets: map[string]string{}}
+
+	tagIds, err := m.importTags(ctx, userId, manifest.Tags, summary, dataProtector)
+	if err != nil {
+		return summary, err
+	}
+
+	if err := m.importSecrets(userId, manifest.Secrets, dataProtector, summary); err != nil {
+		return summary, err
+	}
+
+	pendingFiles := make(map[string]importedFile)
+	for _, document := range manifest.Documents {
+		request := documents.CreateDocumentRequest{
+			Title:       document.Title,
+			Description: document.Description,
+			Issuer:      document.Issuer,
+			IssueDate:   document.IssueDate,
+		}
+		for _, tagName := range document.Tags {
+			if tagId, ok := tagIds[tagName]; ok {
+				request.TagIds = append(request.TagIds, tagId)
+			}
+		}
+
+		response, err := m.documentManager.CreateDocument(ctx, userId, request, dataProtector)
+		if err != nil {
+			m.logger.Error("Failed to import document", "userId", userId, "error", err)
+			return summary, err
+		}
+		summary.Documents++
+
+		for _, note := range document.Notes {
+			_, err := m.noteManager.CreateNote(ctx, documents.CreateNoteRequest{
+				UserId:     userId,
+				DocumentId: response.DocumentId,
+				Content:    note.Content,
+			}, dataProtector)
+			if err != nil {
+				m.logger.Error("Failed to import note", "userId", userId, "documentId", response.DocumentId, "error", err)
+				return summary, err
+			}
+			summary.Notes++
+		}
+
+		for _, file := range document.Files {
+			pendingFiles[file.Path] = importedFile{documentId: response.DocumentId, file: file}
+		}
+	}
+
+	for {
+		header, err := tarReader.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return summary, archiveError(err)
+		}
+
+		pending, ok := pendingFiles[header.Name]
+		if !ok {
+			return summary, archiveError(fmt.Errorf("unexpected entry %q", header.Name))
+		}
+		delete(pendingFiles, header.Name)
+
+		// The entry is streamed into the store, which enforces the maximum file size
+		_, err = m.documentFileManager.AddDocumentFile(ctx, userId, pending.documentId, documents.AddFileRequest{
+			FileName:    pending.file.FileName,
+			ContentType: pending.file.ContentType,
+			Content:     tarReader,
+		}, dataProtector)
+		if err != nil {
+			m.logger.Error("Failed to import document file", "userId", userId, "documentId", pending.documentId, "error", err)
+			return summary, err
+		}
+		summary.Files++
+	}
+
+	// The tar reader stops at the end-of-archive marker, so the final chunk may not be verified yet
+	if _, err := io.Copy(io.Discard, reader); err != nil {
+		return summary, archiveError(err)
+	}
+	if len(pendingFiles) > 0 {
+		return summary, archiveError(fmt.Errorf("%d files are missing", len(pendingFiles)))
+	}
+
+	m.logger.Info("Vault imported", "userId", userId, "secrets", summary.Secrets, "documents", summary.Documents, "files", summary.Files)
+	return summary, nil
+}
+
+// importTags creates the tags of the archive that do not exist yet and returns the IDs of all tags by name
+func (m *DefaultVaultArchiveManager) importTags(ctx context.Context, userId string, tags []archivedTag, summary *VaultArchiveSummary, dataProtector dataprotection.DataProtector) (map[string]string, error) {
+	existingTags, err := m.tagManager.GetUserTags(ctx, userId, dataProtector)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIds := make(map[string]string, len(existingTags)+len(tags))
+	for _, tag := range existingTags {
+		tagIds[tag.Name] = tag.Id
+	}
+
+	for _, tag := range tags {
+		if _, ok := tagIds[tag.Name]; ok {
+			summary.MergedTags++
+			continue
+		}
+		created, err := m.tagManager.CreateTag(ctx, userId, documents.CreateTagRequest{Name: tag.Name, Color: tag.Color}, dataProtector)
+		if err != nil {
+			m.logger.Error("Failed to import tag", "userId", userId, "error", err)
+			return nil, err
+		}
+		tagIds[tag.Name] = created.Id
+		summary.Tags++
+	}
+
+	return tagIds, nil
+}
+
+// importSecrets creates the secrets of an archive, renaming those whose name is taken
+func (m *DefaultVaultArchiveManager) importSecrets(userId string, archived []archivedSecret, dataProtector dataprotection.DataProtector, summary *VaultArchiveSummary) error {
+	request := secrets.ImportSecretsRequest{
+		Secrets:    make([]secrets.UpsertSecretRequest, 0, len(archived)),
+		OnConflict: secrets.ConflictRename,
+	}
+	for _, secret := range archived {
+		request.Secrets = append(request.Secrets, secrets.UpsertSecretRequest{
+			SecretName:  secret.Name,
+			SecretValue: secret.Value,
+			SecretType:  secret.Type,
+			Fields:      secret.Fields,
+			ExpiresAt:   secret.ExpiresAt,
+			RotateEvery: secret.RotateEvery,
+		})
+	}
+
+	response, err := m.secretManager.ImportSecrets(userId, request, dataProtector)
+	summary.Secrets += response.Created
+	for _, renamed := range response.Renamed {
+		summary.RenamedSecrets[renamed.OriginalName] = renamed.Name
+	}
+	if err != nil {
+		m.logger.Error("Failed to import secrets", "userId", userId, "error", err)
+		return err
+	}
+
+	// Exported secrets are valid, so this only happens if no free name was found
+	for _, skipped := range response.Skipped {
+		m.logger.Warn("Secret was not imported", "userId", userId, "reason", skipped.Reason)
+	}
+	return nil
+}
+
+// readManifest reads the manifest, which has to be the first entry of an archive
+func readManifest(tarReader *tar.Reader) (*vaultManifest, error) {
+	header, err := tarReader.Next()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %w", err)
+	}
+	if header.Name != manifestPath {
+		return nil, fmt.Errorf("archive does not start with a manifest")
+	}
+	if header.Size > maxManifestSize {
+		return nil, fmt.Errorf("manifest is too large")
+	}
+
+	var manifest vaultManifest
+	if err := json.NewDecoder(tarReader).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to decode manifest: %w", err)
+	}
+	if manifest.FormatVersion != manifestFormatVersion {
+		return nil, fmt.Errorf("unsupported archive format version: %d", manifest.FormatVersion)
+	}
+	return &manifest, nil
+}
+
+// writeTarEntry writes a regular file to the archive
+func writeTarEntry(tarWriter *tar.Writer, name string, data []byte, modTime time.Time) error {
+	return writeTarStream(tarWriter, name, bytes.NewReader(data), int64(len(data)), modTime)
+}
+
+// writeTarStream writes an entry whose content is copied from a reader of the given size
+func writeTarStream(tarWriter *tar.Writer, name string, content io.Reader, size int64, modTime time.Time) error {
+	header := &tar.Header{
+		Name:     name,
+		Mode:     0600,
+		Size:     size,
+		ModTime:  modTime,
+		Typeflag: tar.TypeReg,
+		Format:   tar.FormatPAX,
+	}
+	if err := tarWriter.WriteHeader(header); err != nil {
+		return fmt.Errorf("failed to write vault archive entry %s: %w", name, err)
+	}
+	written, err := io.Copy(tarWriter, content)
+	if err != nil {
+		return fmt.Errorf("failed to write vault archive entry %s: %w", name, err)
+	}
+	if written != size {
+		return fmt.Errorf("vault archive entry %s has %d bytes instead of %d", name, written, size)
+	}
+	return nil
+}
+
+// archiveError converts an error reading an archive into an error that can be shown to the user
+func archiveError(err error) error {
+	userMessage := "The archive is damaged or incomplete."
+	switch {
+	case errors.Is(err, errNotAnArchive):
+		userMessage = "The file is not a Frozen Fortress vault archive."
+	case errors.Is(err, encryption.ErrStreamDecryptionFailed):
+		userMessage = "The archive could not be decrypted. Check the passphrase, or the archive may be damaged."
+	}
+
+	apiErr := ccc.NewInvalidInputErrorWithMessage("archive", err.Error(), userMessage)
+	apiErr.Cause = err
+	return apiErr
+}
@@ -0,0 +1,148 @@
+package vaultarchive
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"golang.org/x/crypto/argon2"
+)
+
+// Vault archives use the chunked format of the encryption package, with a key derived
+// with Argon2id from the passphrase the user chooses on export.
+//
+// Header layout (big endian):
+//
+//	magic "FFVAULT1" (8) | version (1) | salt (16) | argon2 time (4) |
+//	argon2 memory in KiB (4) | argon2 threads (1) | nonce prefix (7) | chunk size (4)
+const (
+	archiveMagic     = "FFVAULT1"
+	archiveVersion   = 1
+	archiveHeaderLen = 45
+
+	archiveSaltLen = 16
+
+	// Argon2id parameters for new archives
+	archiveArgon2Time    = 3
+	archiveArgon2Memory  = 64 * 1024
+	archiveArgon2Threads = 4
+)
+
+// errNotAnArchive is returned if the input does not start with the header of a vault archive
+var errNotAnArchive = errors.New("file is not a vault archive")
+
+// archiveHeader holds the parameters stored in the header of a vault archive
+type archiveHeader struct {
+	salt          []byte
+	argon2Time    uint32
+	argon2Memory  uint32
+	argon2Threads uint8
+	chunks        encryption.ChunkedStream
+}
+
+func (h *archiveHeader) marshal() []byte {
+	data := make([]byte, 0, archiveHeaderLen)
+	data = append(data, archiveMagic...)
+	data = append(data, archiveVersion)
+	data = append(data, h.salt...)
+	data = binary.BigEndian.AppendUint32(data, h.argon2Time)
+	data = binary.BigEndian.AppendUint32(data, h.argon2Memory)
+	data = append(data, h.argon2Threads)
+	data = append(data, h.chunks.NoncePrefix...)
+	data = binary.BigEndian.AppendUint32(data, h.chunks.ChunkSize)
+	return data
+}
+
+// readArchiveHeader reads and validates the header of a vault archive
+func readArchiveHeader(src io.Reader) (*archiveHeader, error) {
+	data := make([]byte, archiveHeaderLen)
+	if _, err := io.ReadFull(src, data); err != nil {
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			return nil, errNotAnArchive
+		}
+		return nil, fmt.Errorf("failed to read vault archive header: %w", err)
+	}
+	if !bytes.Equal(data[:8], []byte(archiveMagic)) {
+		return nil, errNotAnArchive
+	}
+	if data[8] != archiveVersion {
+		return nil, fmt.Errorf("unsupported vault archive version: %d", data[8])
+	}
+
+	header := &archiveHeader{
+		salt:          data[9:25],
+		argon2Time:    binary.BigEndian.Uint32(data[25:29]),
+		argon2Memory:  binary.BigEndian.Uint32(data[29:33]),
+		argon2Threads: data[33],
+		chunks: encryption.ChunkedStream{
+			Header:      data,
+			NoncePrefix: data[34:41],
+			ChunkSize:   binary.BigEndian.Uint32(data[41:45]),
+		},
+	}
+
+	// The header is authenticated with the first chunk, but bounds keep a forged header
+	// from making us spend minutes deriving a key
+	if header.argon2Time == 0 || header.argon2Time > 16 ||
+		header.argon2Memory < 8*1024 || header.argon2Memory > 1024*1024 || header.argon2Threads == 0 {
+		return nil, fmt.Errorf("invalid key derivation parameters in vault archive header")
+	}
+
+	return header, nil
+}
+
+// deriveKey derives the key of an archive from the passphrase
+func (h *archiveHeader) deriveKey(passphrase string) []byte {
+	return argon2.IDKey([]byte(passphrase), h.salt, h.argon2Time, h.argon2Memory, h.argon2Threads, 32)
+}
+
+// newEncryptingWriter writes the header of a new archive to dst and returns a writer for its content.
+// The final chunk is only written by Close, so an archive whose export failed half way is rejected on import.
+func newEncryptingWriter(dst io.Writer, passphrase string) (io.WriteCloser, error) {
+	header := &archiveHeader{
+		salt:          make([]byte, archiveSaltLen),
+		argon2Time:    archiveArgon2Time,
+		argon2Memory:  archiveArgon2Memory,
+		argon2Threads: archiveArgon2Threads,
+	}
+	if _, err := rand.Read(header.salt); err != nil {
+		return nil, fmt.Errorf("failed to generate salt: %w", err)
+	}
+	noncePrefix, err := encryption.NewChunkNoncePrefix()
+	if err != nil {
+		return nil, err
+	}
+	header.chunks = encryption.ChunkedStream{NoncePrefix: noncePrefix, ChunkSize: encryption.DefaultChunkSize}
+	header.chunks.Header = header.marshal()
+
+	aead, err := encryption.NewChunkAead(header.deriveKey(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	writer, err := encryption.NewChunkWriter(dst, aead, header.chunks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to write vault archive header: %w", err)
+	}
+	return writer, nil
+}
+
+// newDecryptingReader reads the header of an archive from src and returns a reader for its content
+func newDecryptingReader(src io.Reader, passphrase string) (io.Reader, error) {
+	header, err := readArchiveHeader(src)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := encryption.NewChunkAead(header.deriveKey(passphrase))
+	if err != nil {
+		return nil, err
+	}
+	reader, err := encryption.NewChunkReader(src, aead, header.chunks)
+	if err != nil {
+		return nil, fmt.Errorf("invalid vault archive header: %w", err)
+	}
+	return reader, nil
+}
@@ -0,0 +1,47 @@
+package vaultarchive
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+)
+
+func decryptArchive(archive []byte, passphrase string) ([]byte, error) {
+	reader, err := newDecryptingReader(bytes.NewReader(archive), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return io.ReadAll(reader)
+}
+
+func TestArchiveEncryptionUsesPassphrase(t *testing.T) {
+	plain := make([]byte, 100*1024)
+	rand.Read(plain)
+
+	var archive bytes.Buffer
+	writer, err := newEncryptingWriter(&archive, "correct horse battery")
+	if err != nil {
+		t.Fatalf("newEncryptingWriter failed: %v", err)
+	}
+	if _, err := writer.Write(plain); err != nil {
+		t.Fatalf("Write failed: %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	decrypted, err := decryptArchive(archive.Bytes(), "correct horse battery")
+	if err != nil || !bytes.Equal(decrypted, plain) {
+		t.Fatalf("failed to decrypt archive: %v", err)
+	}
+	if _, err := decryptArchive(archive.Bytes(), "wrong horse battery"); !errors.Is(err, encryption.ErrStreamDecryptionFailed) {
+		t.Errorf("expected a wrong passphrase to be detected, got %v", err)
+	}
+	if _, err := decryptArchive([]byte("not an archive at all, just some text that is long enough"), "x"); !errors.Is(err, errNotAnArchive) {
+		t.Errorf("expected a foreign file to be rejected, got %v", err)
+	}
+}
@@ -0,0 +1,19 @@
+package vaultarchive
+
+import (
+	"context"
+	"io"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+)
+
+// VaultArchiveManager exports the data of a user to a passphrase-protected archive and imports such archives
+type VaultArchiveManager interface {
+	// ExportVault writes the secrets, tags, documents with their files and notes of the user to dst.
+	// The data is decrypted with the data protector and encrypted again with the passphrase.
+	ExportVault(ctx context.Context, userId, passphrase string, dataProtector dataprotection.DataProtector, dst io.Writer) (*VaultArchiveSummary, error)
+	// ImportVault recreates the content of an archive for the user. Items get new IDs, tags are merged
+	// with existing tags of the same name and secrets whose name is taken are renamed. Items imported
+	// before an error occurred are kept; the returned summary counts them.
+	ImportVault(ctx context.Context, userId, passphrase string, dataProtector dataprotection.DataProtector, src io.Reader) (*VaultArchiveSummary, error)
+}
@@ -0,0 +1,80 @@
+package vaultarchive
+
+import (
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+// VaultArchiveSummary counts the items exported to or imported from a vault archive
+type VaultArchiveSummary struct {
+	Secrets   int
+	Tags      int
+	Documents int
+	Notes     int
+	Files     int
+	// MergedTags is the number of imported tags that already existed and were reused
+	MergedTags int
+	// RenamedSecrets maps the names of imported secrets that were taken to their new names
+	RenamedSecrets map[string]string
+}
+
+// The content of an archive is a tar stream. Its first entry is the manifest, followed by one entry
+// per document file referenced by its path. Values in the archive are stored decrypted.
+const (
+	manifestPath          = "vault.json"
+	manifestFormatVersion = 1
+)
+
+// vaultManifest describes all exported items except the content of files
+type vaultManifest struct {
+	FormatVersion int                `json:"formatVersion"`
+	AppVersion    string             `json:"appVersion"`
+	ExportedAt    time.Time          `json:"exportedAt"`
+	Secrets       []archivedSecret   `json:"secrets"`
+	Tags          []archivedTag      `json:"tags"`
+	Documents     []archivedDocument `json:"documents"`
+}
+
+// archivedSecret holds a secret with its fields. Value holds the primary value, so archives
+// remain readable by instances without typed secrets.
+type archivedSecret struct {
+	Name       string                `json:"name"`
+	Value      string                `json:"value"`
+	Type       secrets.SecretType    `json:"type,omitempty"`
+	Fields     []secrets.SecretField `json:"fields,omitempty"`
+	CreatedAt  string                `json:"createdAt"`
+	ModifiedAt string                `json:"modifiedAt"`
+	// Expiry and rotation interval, missing in archives of instances without reminders
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+	RotateEvery int        `json:"rotateEvery,omitempty"`
+}
+
+type archivedTag struct {
+	Name  string `json:"name"`
+	Color string `json:"color"`
+}
+
+type archivedDocument struct {
+	Title       string         `json:"title"`
+	Description string         `json:"description"`
+	Issuer      string         `json:"issuer"`
+	IssueDate   *time.Time     `json:"issueDate,omitempty"`
+	Tags        []string       `json:"tags"` // Tag names
+	Notes       []archivedNote `json:"notes"`
+	Files       []archivedFile `json:"files"`
+	CreatedAt   time.Time      `json:"createdAt"`
+	ModifiedAt  time.Time      `json:"modifiedAt"`
+}
+
+type archivedNote struct {
+	Content   string    `json:"content"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+type archivedFile struct {
+	Path        string `json:"path"`
+	FileName    string `json:"fileName"`
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
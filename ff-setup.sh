@@ -414,6 +414,10 @@ server {
         proxy_connect_timeout 60s;
         proxy_send_timeout 60s;
         proxy_read_timeout 60s;
+        
+        # Document files are streamed, buffering them would write decrypted contents to temporary files
+        proxy_request_buffering off;
+        proxy_buffering off;
     }
 }
 EOF
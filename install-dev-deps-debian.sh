@@ -201,6 +201,23 @@ install_redis() {
     log_success "Redis server installed and started successfully"
 }
 
+# Install the tools rendering PDF pages and converting HEIC images for previews and OCR
+install_document_tools() {
+    log_info "Installing Poppler utilities and ImageMagick..."
+    
+    local packages=(
+        "poppler-utils"
+        "imagemagick"
+    )
+    
+    for package in "${packages[@]}"; do
+        log_info "Installing $package..."
+        sudo apt install -y "$package"
+    done
+    
+    log_success "Document tools installed successfully"
+}
+
 # Install Tesseract and dependencies
 install_tesseract() {
     log_info "Installing Tesseract OCR and dependencies..."
@@ -318,6 +335,20 @@ verify_installations() {
         log_error "Redis verification failed"
     fi
     
+    # Check Poppler
+    if command -v pdftoppm &> /dev/null; then
+        log_success "Poppler is installed: $(pdftoppm -v 2>&1 | head -n1)"
+    else
+        log_error "Poppler verification failed"
+    fi
+    
+    # Check ImageMagick
+    if command -v convert &> /dev/null; then
+        log_success "ImageMagick is installed: $(convert -version 2>&1 | head -n1)"
+    else
+        log_error "ImageMagick verification failed"
+    fi
+    
     # Check Tesseract
     if command -v tesseract &> /dev/null; then
         local tesseract_version=$(tesseract --version 2>&1 | head -n1)
@@ -350,6 +381,7 @@ main() {
     # Install dependencies
     install_go
     install_redis
+    install_document_tools
     install_tesseract
     install_tesseract_languages
     
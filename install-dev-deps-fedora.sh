@@ -194,6 +194,24 @@ install_redis() {
     log_success "Redis (Valkey) installed and started successfully"
 }
 
+# Install the tools rendering PDF pages and converting HEIC images for previews and OCR
+install_document_tools() {
+    log_info "Installing Poppler utilities and ImageMagick..."
+    
+    local packages=(
+        "poppler-utils"
+        "ImageMagick"
+        "ImageMagick-heic"
+    )
+    
+    for package in "${packages[@]}"; do
+        log_info "Installing $package..."
+        sudo dnf install -y "$package"
+    done
+    
+    log_success "Document tools installed successfully"
+}
+
 # Install Tesseract and dependencies
 install_tesseract() {
     log_info "Installing Tesseract OCR and dependencies..."
@@ -312,6 +330,20 @@ verify_installations() {
         log_error "Redis/Valkey verification failed"
     fi
     
+    # Check Poppler
+    if command -v pdftoppm &> /dev/null; then
+        log_success "Poppler is installed: $(pdftoppm -v 2>&1 | head -n1)"
+    else
+        log_error "Poppler verification failed"
+    fi
+    
+    # Check ImageMagick
+    if command -v convert &> /dev/null; then
+        log_success "ImageMagick is installed: $(convert -version 2>&1 | head -n1)"
+    else
+        log_error "ImageMagick verification failed"
+    fi
+    
     # Check Tesseract
     if command -v tesseract &> /dev/null; then
         local tesseract_version=$(tesseract --version 2>&1 | head -n1)
@@ -343,6 +375,7 @@ main() {
     # Install dependencies
     install_go
     install_redis
+    install_document_tools
     install_tesseract
     install_tesseract_languages
     
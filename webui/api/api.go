@@ -0,0 +1,136 @@
+// Package api implements the versioned JSON REST API of Frozen Fortress.
+// Requests are authenticated with personal access tokens instead of cookie sessions.
+// Every token carries its own wrapped copy of the MEK, which is unwrapped per request.
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Services bundles the services used by the REST API
+type Services struct {
+	ApiTokenManager      auth.ApiTokenManager
+	SecretManager        secrets.SecretManager
+	DocumentManager      documents.DocumentManager
+	DocumentFileManager  documents.DocumentFileManager
+	UploadSessionManager documents.UploadSessionManager
+	DocumentListService  documents.DocumentListService
+	TagManager           documents.TagManager
+	NoteManager          documents.NoteManager
+	EncryptionService    encryption.EncryptionService
+	MaxFileSize          int64 // Maximum size of uploaded files in bytes
+	Logger               ccc.Logger
+}
+
+type handlers struct {
+	Services
+}
+
+// RegisterRoutes registers the routes of the REST API version 1 and its OpenAPI document.
+// Both are generated from the same route table, so the document always matches the registered routes.
+func RegisterRoutes(router *gin.Engine, services Services) {
+	if services.Logger == nil {
+		services.Logger = ccc.NopLogger
+	}
+	h := &handlers{Services: services}
+
+	routes := apiRoutes()
+
+	openApiDocument, err := json.Marshal(buildOpenApiDocument(routes))
+	if err != nil {
+		panic("Failed to build OpenAPI document: " + err.Error())
+	}
+
+	// The OpenAPI document describes the API only and is served without authentication
+	router.GET(apicontracts.BasePath+"/openapi.json", func(c *gin.Context) {
+		c.Data(http.StatusOK, "application/json; charset=utf-8", openApiDocument)
+	})
+
+	v1 := router.Group(apicontracts.BasePath)
+	v1.Use(middleware.ApiTokenMiddleware(services.ApiTokenManager))
+	for _, route := range routes {
+		chain := make([]gin.HandlerFunc, 0, 2)
+		if route.scope != "" {
+			chain = append(chain, middleware.RequireApiScope(route.scope))
+		}
+		handle := route.handle
+		chain = append(chain, func(c *gin.Context) { handle(h, c) })
+
+		v1.Handle(route.method, route.path, chain...)
+	}
+}
+
+// getCurrentPrincipal returns the user and scopes of the presented token
+func (h *handlers) getCurrentPrincipal(c *gin.Context) {
+	principal := h.principal(c)
+
+	scopes := make([]string, 0, len(principal.Scopes))
+	for _, scope := range principal.Scopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	c.JSON(http.StatusOK, apicontracts.PrincipalDto{
+		UserId:   principal.UserId,
+		UserName: principal.UserName,
+		TokenId:  principal.TokenId,
+		Scopes:   scopes,
+	})
+}
+
+// principal returns the principal authenticated by the token middleware
+func (h *handlers) principal(c *gin.Context) auth.ApiPrincipal {
+	principal, _ := middleware.GetApiPrincipal(c)
+	return principal
+}
+
+// dataProtector creates a data protector for the MEK unwrapped with the token of the current request
+func (h *handlers) dataProtector(c *gin.Context) dataprotection.DataProtector {
+	return dataprotection.NewKeyDataProtector(h.EncryptionService, h.principal(c).Mek)
+}
+
+// bindJson parses the JSON request body and responds with an error if it is invalid
+func bindJson(c *gin.Context, target any) bool {
+	if err := c.ShouldBindJSON(target); err != nil {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("body", err.Error(), "The request body is not valid JSON."))
+		return false
+	}
+	return true
+}
+
+// pagination reads the page and pageSize query parameters
+func pagination(c *gin.Context) (page int, pageSize int) {
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err = strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	return page, min(pageSize, maxPageSize)
+}
+
+// totalPages calculates the number of pages for a paginated response
+func totalPages(totalCount, pageSize int) int {
+	if pageSize <= 0 || totalCount == 0 {
+		return 1
+	}
+	return (totalCount + pageSize - 1) / pageSize
+}
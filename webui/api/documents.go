@@ -0,0 +1,396 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// listDocuments returns a page of the user's documents, optionally filtered by tags or a search term
+func (h *handlers) listDocuments(c *gin.Context) {
+	page, pageSize := pagination(c)
+	searchTerm := strings.TrimSpace(c.Query("search"))
+
+	defaultSort, defaultSortAsc := "title", "true"
+	if searchTerm != "" {
+		defaultSort, defaultSortAsc = "relevance", "false"
+	}
+
+	var filters documents.DocumentFilters
+	for _, tagId := range strings.Split(c.Query("tagIds"), ",") {
+		if tagId = strings.TrimSpace(tagId); tagId != "" {
+			filters.TagIds = append(filters.TagIds, tagId)
+		}
+	}
+	filters.Issuer = strings.TrimSpace(c.Query("issuer"))
+
+	// Upper date bounds include the whole day
+	endOfDay := 23*time.Hour + 59*time.Minute + 59*time.Second
+	for _, dateFilter := range []struct {
+		name   string
+		target **time.Time
+		offset time.Duration
+	}{
+		{"dateFrom", &filters.DateFrom, 0},
+		{"dateTo", &filters.DateTo, endOfDay},
+		{"issueDateFrom", &filters.IssueDateFrom, 0},
+		{"issueDateTo", &filters.IssueDateTo, endOfDay},
+	} {
+		value := strings.TrimSpace(c.Query(dateFilter.name))
+		if value == "" {
+			continue
+		}
+		date, err := time.Parse(apicontracts.IssueDateLayout, value)
+		if err != nil {
+			middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage(dateFilter.name, err.Error(), "Date filters must have the format YYYY-MM-DD."))
+			return
+		}
+		date = date.Add(dateFilter.offset)
+		*dateFilter.target = &date
+	}
+
+	response, err := h.DocumentListService.GetDocumentList(c.Request.Context(), h.principal(c).UserId, documents.DocumentListRequest{
+		SearchTerm: searchTerm,
+		DeepSearch: c.Query("deepSearch") == "true",
+		Filters:    filters,
+		Page:       page,
+		PageSize:   pageSize,
+		SortBy:     c.DefaultQuery("sortBy", defaultSort),
+		SortAsc:    c.DefaultQuery("sortAsc", defaultSortAsc) == "true",
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	result := apicontracts.DocumentListResponse{
+		PageDto: apicontracts.PageDto{
+			Page:       response.Page,
+			PageSize:   response.PageSize,
+			TotalCount: response.TotalCount,
+			TotalPages: response.TotalPages,
+		},
+		Documents: make([]apicontracts.DocumentListItemDto, 0, len(response.Items)),
+	}
+	for _, item := range response.Items {
+		result.Documents = append(result.Documents, apicontracts.DocumentListItemDto{
+			DocumentDto:     toDocumentDto(item.DocumentDto),
+			HighlightedText: item.HighlightedText,
+			MatchTypes:      item.MatchTypes,
+		})
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getDocument returns a single document by its ID
+func (h *handlers) getDocument(c *gin.Context) {
+	document, err := h.DocumentManager.GetDocument(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toDocumentDto(document))
+}
+
+// createDocument creates a new document without files. Files are uploaded separately.
+func (h *handlers) createDocument(c *gin.Context) {
+	var request apicontracts.UpsertDocumentRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	issueDate, ok := parseIssueDate(c, request.IssueDate)
+	if !ok {
+		return
+	}
+
+	response, err := h.DocumentManager.CreateDocument(c.Request.Context(), h.principal(c).UserId, documents.CreateDocumentRequest{
+		Title:       request.Title,
+		Description: request.Description,
+		Issuer:      request.Issuer,
+		IssueDate:   issueDate,
+		TagIds:      request.TagIds,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, apicontracts.IdResponse{Id: response.DocumentId})
+}
+
+// updateDocument replaces the metadata and tags of a document
+func (h *handlers) updateDocument(c *gin.Context) {
+	var request apicontracts.UpsertDocumentRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	issueDate, ok := parseIssueDate(c, request.IssueDate)
+	if !ok {
+		return
+	}
+
+	err := h.DocumentManager.UpdateDocument(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), documents.UpdateDocumentRequest{
+		Title:       request.Title,
+		Description: request.Description,
+		Issuer:      request.Issuer,
+		IssueDate:   issueDate,
+		TagIds:      request.TagIds,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// deleteDocument moves a document with all its files and notes to the trash
+func (h *handlers) deleteDocument(c *gin.Context) {
+	err := h.DocumentManager.DeleteDocument(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// listDocumentFiles returns the metadata of all files of a document
+func (h *handlers) listDocumentFiles(c *gin.Context) {
+	files, err := h.DocumentFileManager.GetDocumentFiles(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	result := make([]apicontracts.DocumentFileDto, 0, len(files))
+	for _, file := range files {
+		result = append(result, toDocumentFileDto(file))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// getDocumentFile returns the metadata of a single file
+func (h *handlers) getDocumentFile(c *gin.Context) {
+	file, err := h.DocumentFileManager.GetDocumentFile(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("fileId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toDocumentFileDto(file))
+}
+
+// getDocumentFileContent streams the decrypted content of a file
+func (h *handlers) getDocumentFileContent(c *gin.Context) {
+	file, content, err := h.DocumentFileManager.OpenDocumentFile(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("fileId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.DataFromReader(http.StatusOK, file.FileSize, file.ContentType, content, map[string]string{
+		"Content-Disposition": mime.FormatMediaType("attachment", map[string]string{"filename": file.FileName}),
+	})
+	if err := c.Errors.Last(); err != nil {
+		h.Logger.Error("Failed to stream document file", "fileId", file.Id, "error", err)
+	}
+}
+
+// addDocumentFile uploads a file to a document. The file is expected in the multipart form field "file"
+// and is encrypted and stored while it is received.
+func (h *handlers) addDocumentFile(c *gin.Context) {
+	uploadedFile, err := middleware.StreamUploadedFile(c, "file", h.MaxFileSize)
+	if errors.Is(err, middleware.ErrUploadTooLarge) {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("file", "file too large", fmt.Sprintf("The file exceeds the maximum size of %dMB.", h.MaxFileSize/(1024*1024))))
+		return
+	}
+	if err != nil {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("file", err.Error(), "No file was uploaded in the form field 'file'."))
+		return
+	}
+
+	contentType := documents.ResolveContentType(uploadedFile.FileName, uploadedFile.ContentType)
+	if !documents.IsSupportedContentType(contentType) {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("file", "unsupported content type "+contentType, "Unsupported file type. Supported formats are "+documents.SupportedFormatsDescription+"."))
+		return
+	}
+
+	addedFile, err := h.DocumentFileManager.AddDocumentFile(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), documents.AddFileRequest{
+		FileName:    uploadedFile.FileName,
+		ContentType: contentType,
+		Content:     uploadedFile.Content,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, toDocumentFileDto(addedFile))
+}
+
+// deleteDocumentFile removes a file from a document
+func (h *handlers) deleteDocumentFile(c *gin.Context) {
+	err := h.DocumentFileManager.DeleteDocumentFile(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("fileId"))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// reprocessDocumentFile queues the text extraction of a file again
+func (h *handlers) reprocessDocumentFile(c *gin.Context) {
+	err := h.DocumentFileManager.ReprocessFile(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("fileId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusAccepted)
+}
+
+// listNotes returns all notes of a document
+func (h *handlers) listNotes(c *gin.Context) {
+	notes, err := h.NoteManager.GetDocumentNotes(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	result := make([]apicontracts.NoteDto, 0, len(notes))
+	for _, note := range notes {
+		result = append(result, toNoteDto(note))
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// createNote adds a note to a document
+func (h *handlers) createNote(c *gin.Context) {
+	var request apicontracts.UpsertNoteRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	response, err := h.NoteManager.CreateNote(c.Request.Context(), documents.CreateNoteRequest{
+		UserId:     h.principal(c).UserId,
+		DocumentId: c.Param("documentId"),
+		Content:    request.Content,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, apicontracts.IdResponse{Id: response.NoteId})
+}
+
+// updateNote replaces the content of a note
+func (h *handlers) updateNote(c *gin.Context) {
+	var request apicontracts.UpsertNoteRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	err := h.NoteManager.UpdateNote(c.Request.Context(), documents.UpdateNoteRequest{
+		UserId:  h.principal(c).UserId,
+		NoteId:  c.Param("noteId"),
+		Content: request.Content,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// deleteNote deletes a note
+func (h *handlers) deleteNote(c *gin.Context) {
+	err := h.NoteManager.DeleteNote(c.Request.Context(), h.principal(c).UserId, c.Param("noteId"))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// parseIssueDate parses an optional issue date in the format YYYY-MM-DD and responds with an error if it is invalid
+func parseIssueDate(c *gin.Context, value string) (*time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, true
+	}
+
+	issueDate, err := time.Parse(apicontracts.IssueDateLayout, value)
+	if err != nil {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("issueDate", err.Error(), "The issue date must have the format YYYY-MM-DD."))
+		return nil, false
+	}
+	return &issueDate, true
+}
+
+// createUpload starts a resumable upload of a file, whose parts are sent with uploadChunk
+func (h *handlers) createUpload(c *gin.Context) {
+	var request apicontracts.CreateUploadRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	contentType := documents.ResolveContentType(request.FileName, request.ContentType)
+	if !documents.IsSupportedContentType(contentType) {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("contentType", "unsupported content type "+contentType, "Unsupported file type. Supported formats are "+documents.SupportedFormatsDescription+"."))
+		return
+	}
+
+	session, err := h.UploadSessionManager.CreateUploadSession(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), documents.CreateUploadSessionRequest{
+		FileName:    request.FileName,
+		ContentType: contentType,
+		Size:        request.Size,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, toUploadSessionDto(session))
+}
+
+// getUpload returns the state of an upload, whose offset tells where to continue after a failed part
+func (h *handlers) getUpload(c *gin.Context) {
+	session, err := h.UploadSessionManager.GetUploadSession(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("uploadId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toUploadSessionDto(session))
+}
+
+// uploadChunk stores the part of a file sent as the raw request body
+func (h *handlers) uploadChunk(c *gin.Context) {
+	offset, size, err := middleware.ParseUploadChunk(c)
+	if err != nil {
+		middleware.HandleApiError(c, ccc.NewInvalidInputErrorWithMessage("offset", err.Error(), "The query parameter offset and a Content-Length are required."))
+		return
+	}
+
+	session, err := h.UploadSessionManager.UploadChunk(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("uploadId"), documents.UploadChunkRequest{
+		Offset:  offset,
+		Size:    size,
+		Content: c.Request.Body,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toUploadSessionDto(session))
+}
+
+// completeUpload adds the completely uploaded file to its document
+func (h *handlers) completeUpload(c *gin.Context) {
+	addedFile, err := h.UploadSessionManager.CompleteUploadSession(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("uploadId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusCreated, toDocumentFileDto(addedFile))
+}
+
+// abortUpload cancels an upload
+func (h *handlers) abortUpload(c *gin.Context) {
+	err := h.UploadSessionManager.AbortUploadSession(c.Request.Context(), h.principal(c).UserId, c.Param("documentId"), c.Param("uploadId"))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
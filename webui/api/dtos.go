@@ -0,0 +1,140 @@
+package api
+
+import (
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+)
+
+func toSecretDto(secret *secrets.SecretDto) apicontracts.SecretDto {
+	dto := apicontracts.SecretDto{
+		Id:         secret.Id,
+		Name:       secret.Name,
+		Type:       string(secret.Type),
+		Value:      secret.Value,
+		Fields:     make([]apicontracts.SecretFieldDto, 0, len(secret.Fields)),
+		CreatedAt:  secret.CreatedAt,
+		ModifiedAt: secret.ModifiedAt,
+
+		ExpiresAt:     secret.ExpiresAt,
+		RotateEvery:   secret.RotateEvery,
+		RotationDueAt: secret.RotationDueAt,
+	}
+	for _, field := range secret.Fields {
+		dto.Fields = append(dto.Fields, apicontracts.SecretFieldDto{
+			Name:      field.Name,
+			Value:     field.Value,
+			Concealed: field.Concealed,
+			Kind:      string(field.Kind),
+		})
+	}
+	if secret.Totp != nil {
+		totp := toTotpCodeDto(secret.Totp)
+		dto.Totp = &totp
+	}
+	return dto
+}
+
+func toTotpCodeDto(code *secrets.TotpCodeDto) apicontracts.TotpCodeDto {
+	return apicontracts.TotpCodeDto{
+		FieldName:        code.FieldName,
+		Code:             code.Code,
+		Period:           code.Period,
+		SecondsRemaining: code.SecondsRemaining,
+	}
+}
+
+func toUpsertSecretRequest(request apicontracts.UpsertSecretRequest) secrets.UpsertSecretRequest {
+	result := secrets.UpsertSecretRequest{
+		SecretName:  request.Name,
+		SecretValue: request.Value,
+		SecretType:  secrets.SecretType(request.Type),
+		ExpiresAt:   request.ExpiresAt,
+		RotateEvery: request.RotateEvery,
+	}
+	for _, field := range request.Fields {
+		result.Fields = append(result.Fields, secrets.SecretField{
+			Name:      field.Name,
+			Value:     field.Value,
+			Concealed: field.Concealed,
+			Kind:      secrets.FieldKind(field.Kind),
+		})
+	}
+	return result
+}
+
+func toTagDto(tag *documents.TagDto) apicontracts.TagDto {
+	return apicontracts.TagDto{
+		Id:         tag.Id,
+		Name:       tag.Name,
+		Color:      tag.Color,
+		CreatedAt:  tag.CreatedAt,
+		ModifiedAt: tag.ModifiedAt,
+	}
+}
+
+func toTagDtos(tags []*documents.TagDto) []apicontracts.TagDto {
+	result := make([]apicontracts.TagDto, 0, len(tags))
+	for _, tag := range tags {
+		result = append(result, toTagDto(tag))
+	}
+	return result
+}
+
+func toDocumentDto(document *documents.DocumentDto) apicontracts.DocumentDto {
+	dto := apicontracts.DocumentDto{
+		Id:          document.Id,
+		Title:       document.Title,
+		Description: document.Description,
+		Issuer:      document.Issuer,
+		FileCount:   document.FileCount,
+		Tags:        toTagDtos(document.Tags),
+		CreatedAt:   document.CreatedAt,
+		ModifiedAt:  document.ModifiedAt,
+	}
+	if document.IssueDate != nil {
+		dto.IssueDate = document.IssueDate.Format(apicontracts.IssueDateLayout)
+	}
+	return dto
+}
+
+func toDocumentFileDto(file *documents.DocumentFileDto) apicontracts.DocumentFileDto {
+	return apicontracts.DocumentFileDto{
+		Id:            file.Id,
+		DocumentId:    file.DocumentId,
+		FileName:      file.FileName,
+		ContentType:   file.ContentType,
+		FileSize:      file.FileSize,
+		PageCount:     file.PageCount,
+		ExtractedText: file.ExtractedText,
+		Confidence:    file.Confidence,
+		OcrStatus:     file.OcrStatus,
+		OcrError:      file.OcrError,
+		CreatedAt:     file.CreatedAt,
+		ModifiedAt:    file.ModifiedAt,
+	}
+}
+
+func toUploadSessionDto(session *documents.UploadSessionDto) apicontracts.UploadSessionDto {
+	return apicontracts.UploadSessionDto{
+		Id:           session.Id,
+		DocumentId:   session.DocumentId,
+		FileName:     session.FileName,
+		ContentType:  session.ContentType,
+		Size:         session.Size,
+		Offset:       session.Offset,
+		MaxChunkSize: session.MaxChunkSize,
+		CreatedAt:    session.CreatedAt,
+		ExpiresAt:    session.ExpiresAt,
+	}
+}
+
+func toNoteDto(note *documents.NoteDto) apicontracts.NoteDto {
+	return apicontracts.NoteDto{
+		Id:         note.Id,
+		DocumentId: note.DocumentId,
+		Content:    note.Content,
+		CreatedAt:  note.CreatedAt,
+		ModifiedAt: note.ModifiedAt,
+	}
+}
@@ -0,0 +1,230 @@
+package api
+
+import (
+	"net/http"
+	"reflect"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// openApiObject is a node of the OpenAPI document. Maps keep the generator short;
+// encoding/json sorts their keys, so the document is stable.
+type openApiObject = map[string]any
+
+// buildOpenApiDocument generates an OpenAPI 3 document describing the given routes.
+// Schemas are derived from the JSON tags of the types in apicontracts.
+func buildOpenApiDocument(routes []apiRoute) openApiObject {
+	schemas := newSchemaRegistry()
+	errorResponse := openApiObject{
+		"description": "Error",
+		"content": openApiObject{
+			"application/json": openApiObject{"schema": schemas.schemaFor(reflect.TypeOf(apicontracts.ErrorResponse{}))},
+		},
+	}
+
+	paths := openApiObject{}
+	for _, route := range routes {
+		path, parameters := openApiPath(route.path)
+		for _, query := range route.query {
+			parameters = append(parameters, openApiObject{
+				"name":        query.name,
+				"in":          "query",
+				"required":    false,
+				"description": query.description,
+				"schema":      openApiObject{"type": query.kind},
+			})
+		}
+
+		operation := openApiObject{
+			"operationId": operationId(route),
+			"summary":     route.summary,
+			"tags":        []string{route.tag},
+			"responses":   openApiObject{"default": errorResponse},
+		}
+		if len(parameters) > 0 {
+			operation["parameters"] = parameters
+		}
+		if route.scope != "" {
+			operation["description"] = "Requires the token scope `" + string(route.scope) + "`."
+		}
+
+		switch {
+		case route.request != nil:
+			operation["requestBody"] = openApiObject{
+				"required": true,
+				"content": openApiObject{
+					"application/json": openApiObject{"schema": schemas.schemaFor(reflect.TypeOf(route.request))},
+				},
+			}
+		case route.upload:
+			operation["requestBody"] = openApiObject{
+				"required": true,
+				"content": openApiObject{
+					"multipart/form-data": openApiObject{"schema": openApiObject{
+						"type":       "object",
+						"required":   []string{"file"},
+						"properties": openApiObject{"file": openApiObject{"type": "string", "format": "binary"}},
+					}},
+				},
+			}
+		case route.binary:
+			operation["requestBody"] = openApiObject{
+				"required": true,
+				"content": openApiObject{
+					"application/octet-stream": openApiObject{"schema": openApiObject{"type": "string", "format": "binary"}},
+				},
+			}
+		}
+
+		success := openApiObject{"description": http.StatusText(route.status)}
+		switch {
+		case route.response != nil:
+			success["content"] = openApiObject{
+				"application/json": openApiObject{"schema": schemas.schemaFor(reflect.TypeOf(route.response))},
+			}
+		case route.download:
+			success["content"] = openApiObject{
+				"application/octet-stream": openApiObject{"schema": openApiObject{"type": "string", "format": "binary"}},
+			}
+		}
+		operation["responses"].(openApiObject)[strconv.Itoa(route.status)] = success
+
+		item, exists := paths[path].(openApiObject)
+		if !exists {
+			item = openApiObject{}
+			paths[path] = item
+		}
+		item[strings.ToLower(route.method)] = operation
+	}
+
+	scopes := make([]string, 0, len(auth.ApiTokenScopes))
+	for _, scope := range auth.ApiTokenScopes {
+		scopes = append(scopes, string(scope))
+	}
+
+	return openApiObject{
+		"openapi": "3.0.3",
+		"info": openApiObject{
+			"title":       "Frozen Fortress API",
+			"version":     ccc.AppVersion,
+			"description": "REST API for secrets and documents. Authenticate with a personal access token created in the account settings. Available scopes: " + strings.Join(scopes, ", ") + ".",
+		},
+		"servers": []openApiObject{{"url": apicontracts.BasePath}},
+		"paths":   paths,
+		"components": openApiObject{
+			"schemas": schemas.schemas,
+			"securitySchemes": openApiObject{
+				"bearerAuth": openApiObject{"type": "http", "scheme": "bearer", "bearerFormat": "ffpat"},
+			},
+		},
+		"security": []openApiObject{{"bearerAuth": []string{}}},
+	}
+}
+
+// openApiPath converts a Gin path to an OpenAPI path and returns the parameters of its segments
+func openApiPath(ginPath string) (string, []openApiObject) {
+	var parameters []openApiObject
+	segments := strings.Split(ginPath, "/")
+	for i, segment := range segments {
+		if name, found := strings.CutPrefix(segment, ":"); found {
+			segments[i] = "{" + name + "}"
+			parameters = append(parameters, openApiObject{
+				"name":     name,
+				"in":       "path",
+				"required": true,
+				"schema":   openApiObject{"type": "string"},
+			})
+		}
+	}
+	return strings.Join(segments, "/"), parameters
+}
+
+// operationId derives a stable operation ID from the name of the handler method, e.g. listSecrets
+func operationId(route apiRoute) string {
+	name := runtime.FuncForPC(reflect.ValueOf(route.handle).Pointer()).Name()
+	return name[strings.LastIndex(name, ".")+1:]
+}
+
+// schemaRegistry collects the schemas of named struct types as reusable components
+type schemaRegistry struct {
+	schemas openApiObject
+}
+
+func newSchemaRegistry() *schemaRegistry {
+	return &schemaRegistry{schemas: openApiObject{}}
+}
+
+// schemaFor returns the schema of a type. Named structs are registered as components and referenced.
+func (r *schemaRegistry) schemaFor(t reflect.Type) openApiObject {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	switch {
+	case t == reflect.TypeOf(time.Time{}):
+		return openApiObject{"type": "string", "format": "date-time"}
+	case t.Kind() == reflect.Struct:
+		if _, exists := r.schemas[t.Name()]; !exists {
+			r.schemas[t.Name()] = openApiObject{} // placeholder for recursive types
+			r.schemas[t.Name()] = r.structSchema(t)
+		}
+		return openApiObject{"$ref": "#/components/schemas/" + t.Name()}
+	case t.Kind() == reflect.Slice:
+		return openApiObject{"type": "array", "items": r.schemaFor(t.Elem())}
+	case t.Kind() == reflect.String:
+		return openApiObject{"type": "string"}
+	case t.Kind() == reflect.Bool:
+		return openApiObject{"type": "boolean"}
+	case t.Kind() >= reflect.Int && t.Kind() <= reflect.Uint64:
+		return openApiObject{"type": "integer"}
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return openApiObject{"type": "number"}
+	default:
+		return openApiObject{}
+	}
+}
+
+// structSchema builds the object schema of a struct. Embedded structs are flattened like encoding/json does.
+func (r *schemaRegistry) structSchema(t reflect.Type) openApiObject {
+	properties := openApiObject{}
+	required := []string{}
+	r.addStructProperties(t, properties, &required)
+
+	schema := openApiObject{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (r *schemaRegistry) addStructProperties(t reflect.Type, properties openApiObject, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			r.addStructProperties(field.Type, properties, required)
+			continue
+		}
+		if !field.IsExported() {
+			continue
+		}
+
+		name, options, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		properties[name] = r.schemaFor(field.Type)
+		if !strings.Contains(options, "omitempty") {
+			*required = append(*required, name)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/gin-gonic/gin"
+)
+
+func TestOpenApiDocumentMatchesRegisteredRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	RegisterRoutes(router, Services{})
+
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, apicontracts.BasePath+"/openapi.json", nil))
+	if recorder.Code != http.StatusOK {
+		t.Fatalf("expected the OpenAPI document to be served without a token, got status %d", recorder.Code)
+	}
+
+	var document struct {
+		OpenApi    string                                `json:"openapi"`
+		Paths      map[string]map[string]json.RawMessage `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &document); err != nil {
+		t.Fatalf("failed to parse OpenAPI document: %v", err)
+	}
+	if !strings.HasPrefix(document.OpenApi, "3.") {
+		t.Errorf("expected an OpenAPI 3 document, got version %q", document.OpenApi)
+	}
+
+	documented := 0
+	for _, route := range router.Routes() {
+		path, found := strings.CutPrefix(route.Path, apicontracts.BasePath)
+		if !found || path == "/openapi.json" {
+			continue
+		}
+		openApiPath, _ := openApiPath(path)
+		if _, exists := document.Paths[openApiPath][strings.ToLower(route.Method)]; !exists {
+			t.Errorf("route %s %s is missing in the OpenAPI document", route.Method, route.Path)
+		}
+		documented++
+	}
+
+	operations := 0
+	for _, item := range document.Paths {
+		operations += len(item)
+	}
+	if operations != documented {
+		t.Errorf("the OpenAPI document describes %d operations, but %d routes are registered", operations, documented)
+	}
+
+	// Every referenced schema has to be defined
+	for _, ref := range strings.Split(recorder.Body.String(), `"$ref":"#/components/schemas/`)[1:] {
+		name := ref[:strings.Index(ref, `"`)]
+		if _, exists := document.Components.Schemas[name]; !exists {
+			t.Errorf("schema %s is referenced but not defined", name)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/gin-gonic/gin"
+)
+
+// apiRoute describes a route of the REST API. The route table is used both to register the
+// handlers and to generate the OpenAPI document.
+type apiRoute struct {
+	method   string
+	path     string             // path relative to the base path in Gin syntax, e.g. /secrets/:secretId
+	scope    auth.ApiTokenScope // scope required in addition to a valid token, empty if none
+	handle   func(*handlers, *gin.Context)
+	tag      string
+	summary  string
+	query    []queryParameter
+	request  any  // type of the JSON request body, nil if none
+	status   int  // status code of a successful response
+	response any  // type of the JSON response body, nil if the response has no body
+	upload   bool // the request is a multipart form with a file in the field "file"
+	binary   bool // the request body is the raw content of a part of a file
+	download bool // the response is the raw content of a file
+}
+
+// queryParameter describes an optional query parameter of a route
+type queryParameter struct {
+	name        string
+	kind        string // OpenAPI type: string, integer or boolean
+	description string
+}
+
+var pageParameters = []queryParameter{
+	{"page", "integer", "Page number, starting at 1"},
+	{"pageSize", "integer", "Number of items per page, at most 100"},
+	{"sortAsc", "boolean", "Sort ascending instead of descending"},
+}
+
+// apiRoutes returns the routes of version 1 of the REST API
+func apiRoutes() []apiRoute {
+	return []apiRoute{
+		{method: http.MethodGet, path: "/me", handle: (*handlers).getCurrentPrincipal, tag: "Tokens",
+			summary: "Get the user and scopes of the presented token", status: http.StatusOK, response: apicontracts.PrincipalDto{}},
+
+		// Secrets
+		{method: http.MethodGet, path: "/secrets", scope: auth.ApiTokenScopeSecretsRead, handle: (*handlers).listSecrets, tag: "Secrets",
+			summary: "List secrets", status: http.StatusOK, response: apicontracts.SecretListResponse{},
+			query: append([]queryParameter{
+				{"name", "string", "Only return secrets whose name contains this value"},
+				{"sortBy", "string", "Sort field: Name, CreatedAt, ModifiedAt, Id or ExpiresAt (due date, undated secrets last)"},
+				{"expiringWithinDays", "integer", "Only return secrets that expire or are due for rotation within this many days"},
+			}, pageParameters...)},
+		{method: http.MethodGet, path: "/secrets/by-name/:name", scope: auth.ApiTokenScopeSecretsRead, handle: (*handlers).getSecretByName, tag: "Secrets",
+			summary: "Get a secret by its exact name", status: http.StatusOK, response: apicontracts.SecretDto{}},
+		{method: http.MethodGet, path: "/secrets/:secretId", scope: auth.ApiTokenScopeSecretsRead, handle: (*handlers).getSecret, tag: "Secrets",
+			summary: "Get a secret", status: http.StatusOK, response: apicontracts.SecretDto{}},
+		{method: http.MethodGet, path: "/secrets/:secretId/totp", scope: auth.ApiTokenScopeSecretsRead, handle: (*handlers).getTotpCode, tag: "Secrets",
+			summary: "Get the current one-time password of a secret", status: http.StatusOK, response: apicontracts.TotpCodeDto{}},
+		{method: http.MethodPost, path: "/secrets", scope: auth.ApiTokenScopeSecretsWrite, handle: (*handlers).createSecret, tag: "Secrets",
+			summary: "Create a secret", request: apicontracts.UpsertSecretRequest{}, status: http.StatusCreated, response: apicontracts.IdResponse{}},
+		{method: http.MethodPut, path: "/secrets/:secretId", scope: auth.ApiTokenScopeSecretsWrite, handle: (*handlers).updateSecret, tag: "Secrets",
+			summary: "Update a secret", request: apicontracts.UpsertSecretRequest{}, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/secrets/:secretId", scope: auth.ApiTokenScopeSecretsWrite, handle: (*handlers).deleteSecret, tag: "Secrets",
+			summary: "Delete a secret", status: http.StatusNoContent},
+
+		// Documents
+		{method: http.MethodGet, path: "/documents", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).listDocuments, tag: "Documents",
+			summary: "List or search documents", status: http.StatusOK, response: apicontracts.DocumentListResponse{},
+			query: append([]queryParameter{
+				{"search", "string", "Search term; results are sorted by relevance by default"},
+				{"deepSearch", "boolean", "Also search the extracted text of files"},
+				{"tagIds", "string", "Comma separated IDs of tags the documents must have"},
+				{"issuer", "string", "Only return documents of this issuer"},
+				{"dateFrom", "string", "Only return documents created on or after this date (YYYY-MM-DD)"},
+				{"dateTo", "string", "Only return documents created on or before this date (YYYY-MM-DD)"},
+				{"issueDateFrom", "string", "Only return documents issued on or after this date (YYYY-MM-DD)"},
+				{"issueDateTo", "string", "Only return documents issued on or before this date (YYYY-MM-DD)"},
+				{"sortBy", "string", "Sort field: title, created_at, modified_at or relevance"},
+			}, pageParameters...)},
+		{method: http.MethodGet, path: "/documents/:documentId", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).getDocument, tag: "Documents",
+			summary: "Get a document", status: http.StatusOK, response: apicontracts.DocumentDto{}},
+		{method: http.MethodPost, path: "/documents", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).createDocument, tag: "Documents",
+			summary: "Create a document without files", request: apicontracts.UpsertDocumentRequest{}, status: http.StatusCreated, response: apicontracts.IdResponse{}},
+		{method: http.MethodPut, path: "/documents/:documentId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).updateDocument, tag: "Documents",
+			summary: "Update a document", request: apicontracts.UpsertDocumentRequest{}, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/documents/:documentId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).deleteDocument, tag: "Documents",
+			summary: "Delete a document with its files and notes", status: http.StatusNoContent},
+
+		// Document files
+		{method: http.MethodGet, path: "/documents/:documentId/files", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).listDocumentFiles, tag: "Files",
+			summary: "List the files of a document", status: http.StatusOK, response: []apicontracts.DocumentFileDto{}},
+		{method: http.MethodGet, path: "/documents/:documentId/files/:fileId", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).getDocumentFile, tag: "Files",
+			summary: "Get the metadata of a file", status: http.StatusOK, response: apicontracts.DocumentFileDto{}},
+		{method: http.MethodGet, path: "/documents/:documentId/files/:fileId/content", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).getDocumentFileContent, tag: "Files",
+			summary: "Download the decrypted content of a file", status: http.StatusOK, download: true},
+		{method: http.MethodPost, path: "/documents/:documentId/files", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).addDocumentFile, tag: "Files",
+			summary: "Upload a file to a document", upload: true, status: http.StatusCreated, response: apicontracts.DocumentFileDto{}},
+		{method: http.MethodDelete, path: "/documents/:documentId/files/:fileId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).deleteDocumentFile, tag: "Files",
+			summary: "Delete a file", status: http.StatusNoContent},
+		{method: http.MethodPost, path: "/documents/:documentId/files/:fileId/ocr", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).reprocessDocumentFile, tag: "Files",
+			summary: "Queue the text extraction of a file again", status: http.StatusAccepted},
+
+		// Resumable uploads
+		{method: http.MethodPost, path: "/documents/:documentId/uploads", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).createUpload, tag: "Uploads",
+			summary: "Start a resumable upload of a file", request: apicontracts.CreateUploadRequest{}, status: http.StatusCreated, response: apicontracts.UploadSessionDto{}},
+		{method: http.MethodGet, path: "/documents/:documentId/uploads/:uploadId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).getUpload, tag: "Uploads",
+			summary: "Get the offset at which an upload continues", status: http.StatusOK, response: apicontracts.UploadSessionDto{}},
+		{method: http.MethodPut, path: "/documents/:documentId/uploads/:uploadId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).uploadChunk, tag: "Uploads",
+			summary: "Upload the part of a file that starts at the offset of the upload; a different offset is rejected with 409", binary: true, status: http.StatusOK, response: apicontracts.UploadSessionDto{},
+			query: []queryParameter{{"offset", "integer", "Position of the part in the file, required"}}},
+		{method: http.MethodPost, path: "/documents/:documentId/uploads/:uploadId/complete", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).completeUpload, tag: "Uploads",
+			summary: "Add the completely uploaded file to the document", status: http.StatusCreated, response: apicontracts.DocumentFileDto{}},
+		{method: http.MethodDelete, path: "/documents/:documentId/uploads/:uploadId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).abortUpload, tag: "Uploads",
+			summary: "Cancel an upload and delete the parts received so far", status: http.StatusNoContent},
+
+		// Notes
+		{method: http.MethodGet, path: "/documents/:documentId/notes", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).listNotes, tag: "Notes",
+			summary: "List the notes of a document", status: http.StatusOK, response: []apicontracts.NoteDto{}},
+		{method: http.MethodPost, path: "/documents/:documentId/notes", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).createNote, tag: "Notes",
+			summary: "Add a note to a document", request: apicontracts.UpsertNoteRequest{}, status: http.StatusCreated, response: apicontracts.IdResponse{}},
+		{method: http.MethodPut, path: "/notes/:noteId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).updateNote, tag: "Notes",
+			summary: "Update a note", request: apicontracts.UpsertNoteRequest{}, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/notes/:noteId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).deleteNote, tag: "Notes",
+			summary: "Delete a note", status: http.StatusNoContent},
+
+		// Tags
+		{method: http.MethodGet, path: "/tags", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).listTags, tag: "Tags",
+			summary: "List tags", status: http.StatusOK, response: []apicontracts.TagDto{}},
+		{method: http.MethodGet, path: "/tags/:tagId", scope: auth.ApiTokenScopeDocumentsRead, handle: (*handlers).getTag, tag: "Tags",
+			summary: "Get a tag", status: http.StatusOK, response: apicontracts.TagDto{}},
+		{method: http.MethodPost, path: "/tags", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).createTag, tag: "Tags",
+			summary: "Create a tag", request: apicontracts.UpsertTagRequest{}, status: http.StatusCreated, response: apicontracts.TagDto{}},
+		{method: http.MethodPut, path: "/tags/:tagId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).updateTag, tag: "Tags",
+			summary: "Update a tag", request: apicontracts.UpsertTagRequest{}, status: http.StatusNoContent},
+		{method: http.MethodDelete, path: "/tags/:tagId", scope: auth.ApiTokenScopeDocumentsWrite, handle: (*handlers).deleteTag, tag: "Tags",
+			summary: "Delete a tag and remove it from all documents", status: http.StatusNoContent},
+	}
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// listSecrets returns a page of the user's secrets, optionally filtered by name
+func (h *handlers) listSecrets(c *gin.Context) {
+	page, pageSize := pagination(c)
+	expiringWithinDays, _ := strconv.Atoi(c.Query("expiringWithinDays"))
+
+	response, err := h.SecretManager.GetSecrets(h.principal(c).UserId, secrets.GetSecretsRequest{
+		Name:     c.Query("name"),
+		Page:     page,
+		PageSize: pageSize,
+		SortBy:   c.DefaultQuery("sortBy", "Name"),
+		SortAsc:  c.DefaultQuery("sortAsc", "true") == "true",
+
+		ExpiringWithinDays: max(expiringWithinDays, 0),
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	result := apicontracts.SecretListResponse{
+		PageDto: apicontracts.PageDto{
+			Page:       response.Page,
+			PageSize:   response.PageSize,
+			TotalCount: response.TotalCount,
+			TotalPages: totalPages(response.TotalCount, response.PageSize),
+		},
+		Secrets: make([]apicontracts.SecretDto, 0, len(response.Secrets)),
+	}
+	for _, secret := range response.Secrets {
+		result.Secrets = append(result.Secrets, toSecretDto(secret))
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// getSecret returns a single secret by its ID
+func (h *handlers) getSecret(c *gin.Context) {
+	secret, err := h.SecretManager.GetSecret(h.principal(c).UserId, c.Param("secretId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toSecretDto(secret))
+}
+
+// getSecretByName returns a single secret by its exact name
+func (h *handlers) getSecretByName(c *gin.Context) {
+	secret, err := h.SecretManager.GetSecretByName(h.principal(c).UserId, c.Param("name"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toSecretDto(secret))
+}
+
+// getTotpCode returns the current one-time password of a secret, generated from its one-time password field
+func (h *handlers) getTotpCode(c *gin.Context) {
+	code, err := h.SecretManager.GetTotpCode(h.principal(c).UserId, c.Param("secretId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toTotpCodeDto(code))
+}
+
+// createSecret creates a new secret
+func (h *handlers) createSecret(c *gin.Context) {
+	var request apicontracts.UpsertSecretRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	response, err := h.SecretManager.CreateSecret(h.principal(c).UserId, toUpsertSecretRequest(request), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, apicontracts.IdResponse{Id: response.SecretId})
+}
+
+// updateSecret replaces the name and value or fields of a secret
+func (h *handlers) updateSecret(c *gin.Context) {
+	var request apicontracts.UpsertSecretRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	_, err := h.SecretManager.UpdateSecret(h.principal(c).UserId, c.Param("secretId"), toUpsertSecretRequest(request), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// deleteSecret moves a secret to the trash
+func (h *handlers) deleteSecret(c *gin.Context) {
+	_, err := h.SecretManager.DeleteSecret(h.principal(c).UserId, c.Param("secretId"))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
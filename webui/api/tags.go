@@ -0,0 +1,73 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// listTags returns all tags of the user
+func (h *handlers) listTags(c *gin.Context) {
+	tags, err := h.TagManager.GetUserTags(c.Request.Context(), h.principal(c).UserId, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toTagDtos(tags))
+}
+
+// getTag returns a single tag by its ID
+func (h *handlers) getTag(c *gin.Context) {
+	tag, err := h.TagManager.GetTag(c.Request.Context(), h.principal(c).UserId, c.Param("tagId"), h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.JSON(http.StatusOK, toTagDto(tag))
+}
+
+// createTag creates a new tag
+func (h *handlers) createTag(c *gin.Context) {
+	var request apicontracts.UpsertTagRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	tag, err := h.TagManager.CreateTag(c.Request.Context(), h.principal(c).UserId, documents.CreateTagRequest{
+		Name:  request.Name,
+		Color: request.Color,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, toTagDto(tag))
+}
+
+// updateTag renames or recolors a tag
+func (h *handlers) updateTag(c *gin.Context) {
+	var request apicontracts.UpsertTagRequest
+	if !bindJson(c, &request) {
+		return
+	}
+
+	err := h.TagManager.UpdateTag(c.Request.Context(), h.principal(c).UserId, c.Param("tagId"), documents.UpdateTagRequest{
+		Name:  request.Name,
+		Color: request.Color,
+	}, h.dataProtector(c))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// deleteTag moves a tag to the trash; its documents keep it until it is purged
+func (h *handlers) deleteTag(c *gin.Context) {
+	err := h.TagManager.DeleteTag(c.Request.Context(), h.principal(c).UserId, c.Param("tagId"))
+	if middleware.HandleApiError(c, err) {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,132 @@
+// Package health implements the admin health endpoint, which reports whether automatic backups
+// work as scheduled. It is meant for monitoring systems and protected by a static bearer token.
+package health
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/gin-gonic/gin"
+)
+
+// Path is the path of the health endpoint
+const Path = "/admin/health"
+
+// historyLimit is the number of backup runs included in the response
+const historyLimit = 10
+
+// Response is the JSON body of the health endpoint
+type Response struct {
+	Status    string         `json:"status"` // "ok" or "unhealthy"
+	Version   string         `json:"version"`
+	CheckedAt time.Time      `json:"checkedAt"`
+	Backup    BackupResponse `json:"backup"`
+}
+
+// BackupResponse describes the state of automatic backups
+type BackupResponse struct {
+	Enabled             bool          `json:"enabled"`
+	Schedule            string        `json:"schedule,omitempty"`
+	NextRunAt           *time.Time    `json:"nextRunAt,omitempty"`
+	ConsecutiveFailures int           `json:"consecutiveFailures"`
+	Problems            []string      `json:"problems"`
+	LastRun             *RunResponse  `json:"lastRun,omitempty"`
+	LastSuccess         *RunResponse  `json:"lastSuccess,omitempty"`
+	Runs                []RunResponse `json:"runs"`
+}
+
+// RunResponse describes a run of the backup worker
+type RunResponse struct {
+	Status          string     `json:"status"`
+	StartedAt       time.Time  `json:"startedAt"`
+	FinishedAt      *time.Time `json:"finishedAt,omitempty"`
+	DurationSeconds float64    `json:"durationSeconds"`
+	Filename        string     `json:"filename,omitempty"`
+	SizeBytes       int64      `json:"sizeBytes"`
+	Error           string     `json:"error,omitempty"`
+}
+
+// RegisterRoutes registers the health endpoint. The endpoint is only available if a token is configured.
+// It responds with 200 if backups are healthy and 503 otherwise, so it can be used by simple HTTP checks.
+func RegisterRoutes(router *gin.Engine, monitor backup.BackupMonitor, token string, logger ccc.Logger) {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+	if token == "" {
+		logger.Info("Health endpoint disabled, set " + ccc.EnvHealthToken + " to enable it")
+		return
+	}
+
+	router.GET(Path, func(c *gin.Context) {
+		c.Header("Cache-Control", "no-store")
+
+		scheme, presented, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") ||
+			subtle.ConstantTimeCompare([]byte(strings.TrimSpace(presented)), []byte(token)) != 1 {
+			c.Header("WWW-Authenticate", `Bearer realm="frozenfortress"`)
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "invalid health token"})
+			return
+		}
+
+		status, err := monitor.GetStatus(historyLimit)
+		if err != nil {
+			logger.Error("Failed to get backup status", "error", err)
+			c.JSON(http.StatusServiceUnavailable, gin.H{"status": "unhealthy", "error": "failed to get backup status"})
+			return
+		}
+
+		if status.Healthy() {
+			c.JSON(http.StatusOK, newResponse(status))
+		} else {
+			c.JSON(http.StatusServiceUnavailable, newResponse(status))
+		}
+	})
+}
+
+func newResponse(status *backup.BackupStatus) Response {
+	response := Response{
+		Status:    "ok",
+		Version:   ccc.AppVersion,
+		CheckedAt: time.Now().UTC(),
+		Backup: BackupResponse{
+			Enabled:             status.Enabled,
+			Schedule:            status.Schedule,
+			NextRunAt:           status.NextRunAt,
+			ConsecutiveFailures: status.ConsecutiveFailures,
+			Problems:            status.Problems,
+			LastRun:             newRunResponse(status.LastRun),
+			LastSuccess:         newRunResponse(status.LastSuccess),
+			Runs:                make([]RunResponse, 0, len(status.Runs)),
+		},
+	}
+	if response.Backup.Problems == nil {
+		response.Backup.Problems = []string{}
+	}
+	for _, run := range status.Runs {
+		response.Backup.Runs = append(response.Backup.Runs, *newRunResponse(run))
+	}
+
+	if !status.Healthy() {
+		response.Status = "unhealthy"
+	}
+	return response
+}
+
+func newRunResponse(run *backup.BackupRun) *RunResponse {
+	if run == nil {
+		return nil
+	}
+	return &RunResponse{
+		Status:          string(run.Status),
+		StartedAt:       run.StartedAt,
+		FinishedAt:      run.FinishedAt,
+		DurationSeconds: run.Duration().Seconds(),
+		Filename:        run.Filename,
+		SizeBytes:       run.SizeBytes,
+		Error:           run.Error,
+	}
+}
@@ -5,10 +5,16 @@ import (
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/notifications"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/schema"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secretimport"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/trash"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/vaultarchive"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/workers"
 )
 
@@ -20,16 +26,35 @@ type services struct {
 	SignInHistoryRepository auth.SignInHistoryItemRepository
 	MekStore                auth.MekStore
 	SecretManager           secrets.SecretManager
+	SecretImporter          secretimport.SecretImporter
 	UserManager             auth.UserManager
+	PasskeyManager          auth.PasskeyManager
+	ApiTokenManager         auth.ApiTokenManager
+	WebAuthnChallengeStore  auth.WebAuthnChallengeStore
+	WebAuthnConfig          ccc.WebAuthnConfig
 	BackupService           backup.BackupService
 	BackupWorker            workers.BackupWorker
+	BackupMonitor           backup.BackupMonitor
+	HealthToken             string
+	OCRWorker               workers.OCRWorker
 	Logger                  ccc.Logger
 	TagManager              documents.TagManager
 	DocumentManager         documents.DocumentManager
 	DocumentFileManager     documents.DocumentFileManager
+	UploadSessionManager    documents.UploadSessionManager
+	UploadWorker            workers.UploadWorker
 	DocumentSearchEngine    documents.DocumentSearchEngine
 	DocumentListService     documents.DocumentListService
 	NoteManager             documents.NoteManager
+	MaxUploadSize           int64
+	VaultArchiveManager     vaultarchive.VaultArchiveManager
+	TrashManager            trash.TrashManager
+	TrashWorker             workers.TrashWorker
+	TrashRetentionDays      int
+	NotificationManager     notifications.NotificationManager
+	ReminderWorker          workers.ReminderWorker
+	ReminderLeadDays        int
+	BlobWorker              workers.BlobWorker
 }
 
 // configureServices configures the services used by the web UI.
@@ -37,6 +62,12 @@ func configureServices(config ccc.AppConfig, db *sql.DB) services {
 
 	logger := ccc.CreateLogger(config)
 
+	// Bring the database schema up to date before any repository is used
+	if _, err := schema.NewMigrationRunner(db, logger).Up(); err != nil {
+		logger.Error("Failed to migrate database schema", "error", err)
+		panic("Failed to migrate database schema: " + err.Error())
+	}
+
 	userRepo, err := auth.NewSQLiteUserRepository(db)
 	if err != nil {
 		logger.Error("Failed to create user repository", "error", err)
@@ -59,11 +90,56 @@ func configureServices(config ccc.AppConfig, db *sql.DB) services {
 
 	securityService := auth.NewDefaultSecurityService(userRepo, encryptionService, logger)
 
+	totpService := auth.NewDefaultTotpService()
+
+	webAuthnCredentialRepo, err := auth.NewSQLiteWebAuthnCredentialRepository(db)
+	if err != nil {
+		logger.Error("Failed to create WebAuthn credential repository", "error", err)
+		panic("Failed to create WebAuthn credential repository: " + err.Error())
+	}
+
+	webAuthnService := auth.NewDefaultWebAuthnService(encryptionService, logger)
+
+	idGenerator := ccc.NewUuidGenerator()
+
+	secretManager := secrets.NewDefaultSecretManager(
+		secretRepo,
+		idGenerator,
+		userRepo,
+		config.SecretHistoryLimit,
+		logger,
+	)
+
+	// Create the blob store for file contents, which is nil if they are stored in the database
+	blobStore, err := blobstore.NewBlobStoreFromConfig(config.BlobStore)
+	if err != nil {
+		logger.Error("Failed to create blob store", "error", err)
+		panic("Failed to create blob store: " + err.Error())
+	}
+	if config.BlobStore.UsesDatabase() && config.MaxUploadSizeMB > ccc.MaxDatabaseUploadSizeMB {
+		logger.Warn("Uploads are limited without a blob store, set FF_BLOB_STORE to accept larger files",
+			"configured_mb", config.MaxUploadSizeMB, "limit_mb", ccc.MaxDatabaseUploadSizeMB)
+	}
+
+	// Create document unit of work factory and tag manager
+	uowFactory := documents.NewDocumentUnitOfWorkFactory(db, blobStore)
+	tagManager := documents.NewDefaultTagManager(uowFactory, idGenerator, logger)
+
+	// Secret and tag names stored before they were indexed are indexed when their owner signs in
+	signInListeners := []auth.SignInListener{
+		secrets.NewNameIndexSignInListener(secretManager, encryptionService, logger),
+		documents.NewTagNameSignInListener(tagManager, encryptionService, logger),
+	}
+
 	signInHandler := auth.NewDefaultSignInHandler(
 		userRepo,
 		signInHistoryRepo,
 		securityService,
 		encryptionService,
+		totpService,
+		webAuthnCredentialRepo,
+		webAuthnService,
+		signInListeners,
 		config,
 		logger,
 	)
@@ -78,50 +154,79 @@ func configureServices(config ccc.AppConfig, db *sql.DB) services {
 
 	mekStore := auth.NewSessionMekStore(redisStore, logger)
 
+	webAuthnChallengeStore := auth.NewSessionWebAuthnChallengeStore(redisStore, logger)
+
 	signInManager := auth.NewSessionSignInManager(
 		userRepo,
 		signInHandler,
 		redisStore,
 		mekStore,
+		webAuthnChallengeStore,
+		config.WebAuthn,
 		logger,
 	)
 
-	idGenerator := ccc.NewUuidGenerator()
-
-	secretManager := secrets.NewDefaultSecretManager(
-		secretRepo,
+	passkeyManager := auth.NewDefaultPasskeyManager(
+		userRepo,
+		webAuthnCredentialRepo,
 		idGenerator,
+		webAuthnService,
+		encryptionService,
+		logger,
+	)
+
+	apiTokenRepo, err := auth.NewSQLiteApiTokenRepository(db)
+	if err != nil {
+		logger.Error("Failed to create API token repository", "error", err)
+		panic("Failed to create API token repository: " + err.Error())
+	}
+
+	apiTokenManager := auth.NewDefaultApiTokenManager(
 		userRepo,
+		apiTokenRepo,
+		idGenerator,
+		encryptionService,
 		logger,
 	)
 
+	// Create secret importer for migrating from other password managers
+	secretImporter := secretimport.NewDefaultSecretImporter(secretManager, logger)
+
 	userManager := auth.NewDefaultUserManager(
 		userRepo,
 		idGenerator,
 		encryptionService,
 		securityService,
+		totpService,
 		logger,
 	)
 
 	// Create backup service
-	backupService := backup.NewFileBasedBackupService(config, logger)
+	backupService := backup.NewFileBasedBackupService(config, documents.NewSQLiteDocumentBlobReferenceRepositoryFactory(), logger)
 
-	// Create backup worker
-	backupWorker := workers.NewDefaultBackupWorker(backupService, config, logger)
+	backupRunRepo, err := backup.NewSQLiteBackupRunRepository(db)
+	if err != nil {
+		logger.Error("Failed to create backup run repository", "error", err)
+		panic("Failed to create backup run repository: " + err.Error())
+	}
 
-	// Create document unit of work factory and tag manager
-	uowFactory := documents.NewDocumentUnitOfWorkFactory(db)
-	tagManager := documents.NewDefaultTagManager(uowFactory, idGenerator, logger)
+	backupMonitor := backup.NewDefaultBackupMonitor(backupService, backupRunRepo, config, logger)
+
+	// Create backup worker
+	backupWorker := workers.NewDefaultBackupWorker(backupService, backupRunRepo, idGenerator, config, logger)
 
 	// Create document file processor factory
-	pdfProcessor := documents.NewPDFFileProcessor()
 	ocrService := createOCRService(config, logger)
-	imageProcessor := documents.NewImageFileProcessor(ocrService)
-	processorFactory := documents.NewDefaultDocumentFileProcessorFactory(pdfProcessor, imageProcessor)
+	processorFactory := documents.NewDocumentFileProcessorFactoryForConfig(config, ocrService, logger)
+
+	// Create OCR job processor and the worker pool processing the persisted job queue
+	ocrJobKeyProvider := auth.NewConfigOCRJobKeyProvider(config, encryptionService)
+	ocrJobProcessor := documents.NewDefaultOCRJobProcessor(uowFactory, processorFactory, encryptionService, ocrJobKeyProvider, config.OCR, logger)
+	ocrWorker := workers.NewDefaultOCRWorker(ocrJobProcessor, config, logger)
 
 	// Create document file creator and OCR dispatcher factory
-	fileCreator := documents.NewDefaultDocumentFileCreator(idGenerator, processorFactory, logger)
-	ocrDispatcherFactory := documents.NewDefaultOCRDispatcherFactory(uowFactory, config.OCR, logger)
+	fileCreator := documents.NewDefaultDocumentFileCreator(idGenerator, processorFactory, config.MaxUploadSize(), logger)
+	ocrDispatcherFactory := documents.NewDefaultOCRDispatcherFactory(idGenerator, encryptionService, ocrJobKeyProvider, ocrWorker, logger)
 
 	// Create document sorter
 	documentSorter := documents.NewDefaultDocumentSorter[*documents.DocumentDetails]()
@@ -132,6 +237,10 @@ func configureServices(config ccc.AppConfig, db *sql.DB) services {
 	// Create document file manager
 	documentFileManager := documents.NewDefaultDocumentFileManager(uowFactory, fileCreator, ocrDispatcherFactory, logger)
 
+	// Create upload session manager for resumable uploads and the worker deleting expired uploads
+	uploadSessionManager := documents.NewDefaultUploadSessionManager(uowFactory, idGenerator, fileCreator, ocrDispatcherFactory, config.MaxUploadSize(), logger)
+	uploadWorker := workers.NewDefaultUploadWorker(uploadSessionManager, logger)
+
 	// Create document search engine
 	searchSorter := documents.NewSearchDocumentSorter()
 	documentSearchEngine := documents.NewDefaultDocumentSearchEngine(uowFactory, logger, searchSorter)
@@ -142,6 +251,40 @@ func configureServices(config ccc.AppConfig, db *sql.DB) services {
 	// Create note manager
 	noteManager := documents.NewDefaultNoteManager(uowFactory, idGenerator, logger)
 
+	// Create vault archive manager for the export and import of user data
+	vaultArchiveManager := vaultarchive.NewDefaultVaultArchiveManager(secretManager, documentManager, documentFileManager, tagManager, noteManager, logger)
+
+	// Create trash manager and the worker purging expired items
+	trashManager := trash.NewDefaultTrashManager(secretManager, documentManager, tagManager, config.TrashRetentionDays, logger)
+	trashWorker := workers.NewDefaultTrashWorker(trashManager, config, logger)
+
+	// Create notification manager and the worker writing reminders of expiring secrets
+	notificationRepo, err := notifications.NewSQLiteNotificationRepository(db)
+	if err != nil {
+		logger.Error("Failed to create notification repository", "error", err)
+		panic("Failed to create notification repository: " + err.Error())
+	}
+	notificationSettingsRepo, err := notifications.NewSQLiteNotificationSettingsRepository(db)
+	if err != nil {
+		logger.Error("Failed to create notification settings repository", "error", err)
+		panic("Failed to create notification settings repository: " + err.Error())
+	}
+	var mailer notifications.Mailer
+	if config.SMTP.Host != "" {
+		mailer = notifications.NewSMTPMailer(config.SMTP, logger)
+	}
+	notificationManager := notifications.NewDefaultNotificationManager(notificationRepo, notificationSettingsRepo, secretManager, ccc.NewUuidGenerator(), mailer, config.ReminderLeadDays, logger)
+	reminderWorker := workers.NewDefaultReminderWorker(notificationManager, logger)
+
+	// Create the worker deleting blobs that are no longer referenced
+	var blobGarbageCollector blobstore.BlobGarbageCollector
+	if blobStore != nil {
+		// Blobs referenced only by backups are kept, so that restoring a backup does not leave files without content
+		blobReferenceSources := []blobstore.BlobReferenceSource{documents.NewSQLiteDocumentBlobReferenceRepository(db), backupService}
+		blobGarbageCollector = blobstore.NewDefaultBlobGarbageCollector(blobStore, blobReferenceSources, logger)
+	}
+	blobWorker := workers.NewDefaultBlobWorker(blobGarbageCollector, config, logger)
+
 	return services{
 		SignInManager:           signInManager,
 		EncryptionService:       encryptionService,
@@ -150,15 +293,34 @@ func configureServices(config ccc.AppConfig, db *sql.DB) services {
 		SignInHistoryRepository: signInHistoryRepo,
 		MekStore:                mekStore,
 		SecretManager:           secretManager,
+		SecretImporter:          secretImporter,
 		UserManager:             userManager,
+		PasskeyManager:          passkeyManager,
+		ApiTokenManager:         apiTokenManager,
+		WebAuthnChallengeStore:  webAuthnChallengeStore,
+		WebAuthnConfig:          config.WebAuthn,
 		BackupService:           backupService,
 		BackupWorker:            backupWorker,
+		BackupMonitor:           backupMonitor,
+		HealthToken:             config.HealthToken,
+		OCRWorker:               ocrWorker,
 		Logger:                  logger,
 		TagManager:              tagManager,
 		DocumentManager:         documentManager,
 		DocumentFileManager:     documentFileManager,
+		UploadSessionManager:    uploadSessionManager,
+		UploadWorker:            uploadWorker,
 		DocumentSearchEngine:    documentSearchEngine,
 		DocumentListService:     documentListService,
 		NoteManager:             noteManager,
+		MaxUploadSize:           config.MaxUploadSize(),
+		VaultArchiveManager:     vaultArchiveManager,
+		TrashManager:            trashManager,
+		TrashWorker:             trashWorker,
+		TrashRetentionDays:      config.TrashRetentionDays,
+		NotificationManager:     notificationManager,
+		ReminderWorker:          reminderWorker,
+		ReminderLeadDays:        config.ReminderLeadDays,
+		BlobWorker:              blobWorker,
 	}
 }
@@ -10,14 +10,19 @@ import (
 	"strings"
 	"syscall"
 
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/api"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/health"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/views/account"
 	documentsview "github.com/Yeti47/frozenfortress/frozenfortress/webui/views/documents"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/views/login"
+	notificationsview "github.com/Yeti47/frozenfortress/frozenfortress/webui/views/notifications"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/views/recovery"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/views/register"
 	secretsview "github.com/Yeti47/frozenfortress/frozenfortress/webui/views/secrets"
 	tagsview "github.com/Yeti47/frozenfortress/frozenfortress/webui/views/tags"
+	trashview "github.com/Yeti47/frozenfortress/frozenfortress/webui/views/trash"
 	"github.com/gin-gonic/gin"
 )
 
@@ -32,9 +37,29 @@ func main() {
 
 	svc := configureServices(config, db)
 
+	// Let ffcli backup restore know that the database is in use
+	if err := backup.WriteServerPidFile(config.DatabasePath); err != nil {
+		svc.Logger.Warn("Failed to write web UI pid file", "error", err)
+	}
+
 	// Start the backup worker
 	svc.BackupWorker.Start()
 
+	// Start the OCR worker
+	svc.OCRWorker.Start()
+
+	// Start the trash worker
+	svc.TrashWorker.Start()
+
+	// Start the reminder worker
+	svc.ReminderWorker.Start()
+
+	// Start the blob worker
+	svc.BlobWorker.Start()
+
+	// Start the upload worker
+	svc.UploadWorker.Start()
+
 	// Set up graceful shutdown
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -43,6 +68,19 @@ func main() {
 		<-c
 		svc.Logger.Info("Shutting down backup worker...")
 		svc.BackupWorker.Stop()
+		svc.Logger.Info("Shutting down OCR worker...")
+		svc.OCRWorker.Stop()
+		svc.Logger.Info("Shutting down trash worker...")
+		svc.TrashWorker.Stop()
+		svc.Logger.Info("Shutting down reminder worker...")
+		svc.ReminderWorker.Stop()
+		svc.Logger.Info("Shutting down blob worker...")
+		svc.BlobWorker.Stop()
+		svc.Logger.Info("Shutting down upload worker...")
+		svc.UploadWorker.Stop()
+		if err := backup.RemoveServerPidFile(config.DatabasePath); err != nil {
+			svc.Logger.Warn("Failed to remove web UI pid file", "error", err)
+		}
 		os.Exit(0)
 	}()
 
@@ -126,21 +164,43 @@ func registerRoutes(router *gin.Engine, svc services) {
 	router.Static("/static", "./static")
 
 	// Register routes from modules
-	secretsview.RegisterRoutes(router, svc.SignInManager, svc.SecretManager, svc.MekStore, svc.EncryptionService, svc.Logger)
-	tagsview.RegisterRoutes(router, svc.SignInManager, svc.TagManager, svc.Logger)
+	secretsview.RegisterRoutes(router, svc.SignInManager, svc.SecretManager, svc.SecretImporter, svc.NotificationManager, svc.ReminderLeadDays, svc.MekStore, svc.EncryptionService, svc.Logger)
+	tagsview.RegisterRoutes(router, svc.SignInManager, svc.TagManager, svc.MekStore, svc.EncryptionService, svc.Logger)
+	trashview.RegisterRoutes(router, svc.SignInManager, svc.TrashManager, svc.TrashRetentionDays, svc.MekStore, svc.EncryptionService, svc.Logger)
+	notificationsview.RegisterRoutes(router, svc.SignInManager, svc.NotificationManager, svc.MekStore, svc.EncryptionService, svc.Logger)
 
 	// Create document services aggregate
 	docServices := documentsview.DocumentServices{
-		DocumentManager:     svc.DocumentManager,
-		DocumentFileManager: svc.DocumentFileManager,
-		DocumentListService: svc.DocumentListService,
-		TagManager:          svc.TagManager,
-		NoteManager:         svc.NoteManager,
+		DocumentManager:      svc.DocumentManager,
+		DocumentFileManager:  svc.DocumentFileManager,
+		UploadSessionManager: svc.UploadSessionManager,
+		DocumentListService:  svc.DocumentListService,
+		TagManager:           svc.TagManager,
+		NoteManager:          svc.NoteManager,
+		MaxFileSize:          svc.MaxUploadSize,
 	}
 	documentsview.RegisterRoutes(router, svc.SignInManager, docServices, svc.MekStore, svc.EncryptionService, svc.Logger)
 
 	login.RegisterRoutes(router, svc.SignInManager)
 	register.RegisterRoutes(router, svc.UserManager)
 	recovery.RegisterRoutes(router, svc.SignInManager)
-	account.RegisterRoutes(router, svc.UserManager, svc.SignInManager)
+	account.RegisterRoutes(router, svc.UserManager, svc.SignInManager, svc.PasskeyManager, svc.ApiTokenManager, svc.WebAuthnChallengeStore, svc.MekStore, svc.EncryptionService, svc.VaultArchiveManager, svc.WebAuthnConfig)
+
+	// Register the REST API, which authenticates with personal access tokens instead of sessions
+	api.RegisterRoutes(router, api.Services{
+		ApiTokenManager:      svc.ApiTokenManager,
+		SecretManager:        svc.SecretManager,
+		DocumentManager:      svc.DocumentManager,
+		DocumentFileManager:  svc.DocumentFileManager,
+		UploadSessionManager: svc.UploadSessionManager,
+		DocumentListService:  svc.DocumentListService,
+		TagManager:           svc.TagManager,
+		NoteManager:          svc.NoteManager,
+		EncryptionService:    svc.EncryptionService,
+		MaxFileSize:          svc.MaxUploadSize,
+		Logger:               svc.Logger,
+	})
+
+	// Register the admin health endpoint for monitoring, which is protected by a static token
+	health.RegisterRoutes(router, svc.BackupMonitor, svc.HealthToken, svc.Logger)
 }
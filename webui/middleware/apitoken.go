@@ -0,0 +1,84 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/gin-gonic/gin"
+)
+
+const apiPrincipalContextKey = "ffApiPrincipal"
+
+// ApiTokenMiddleware is a Gin middleware that authenticates REST API requests with a personal access token
+// passed as bearer token. Unlike AuthMiddleware it never redirects, but responds with a JSON error.
+func ApiTokenMiddleware(tokenManager auth.ApiTokenManager) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+
+		// API responses contain decrypted data and must not be cached
+		c.Header("Cache-Control", "no-store")
+		c.Header("Pragma", "no-cache")
+		c.Header("X-Content-Type-Options", "nosniff")
+
+		scheme, token, found := strings.Cut(c.GetHeader("Authorization"), " ")
+		if !found || !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			c.Header("WWW-Authenticate", `Bearer realm="frozenfortress"`)
+			HandleApiError(c, ccc.NewUnauthorizedError("missing bearer token"))
+			c.Abort()
+			return
+		}
+
+		principal, err := tokenManager.Authenticate(token)
+		if err != nil {
+			if ccc.IsErrorCode(err, ccc.ErrCodeUnauthorized) {
+				c.Header("WWW-Authenticate", `Bearer realm="frozenfortress", error="invalid_token"`)
+			}
+			HandleApiError(c, err)
+			c.Abort()
+			return
+		}
+
+		c.Set(apiPrincipalContextKey, principal)
+		c.Next()
+	}
+}
+
+// RequireApiScope is a Gin middleware that rejects API requests whose token lacks the given scope.
+// It must be used after ApiTokenMiddleware.
+func RequireApiScope(scope auth.ApiTokenScope) gin.HandlerFunc {
+
+	return func(c *gin.Context) {
+
+		principal, ok := GetApiPrincipal(c)
+		if !ok {
+			HandleApiError(c, ccc.NewUnauthorizedError("no API principal"))
+			c.Abort()
+			return
+		}
+
+		if !principal.HasScope(scope) {
+			c.Header("WWW-Authenticate", `Bearer realm="frozenfortress", error="insufficient_scope", scope="`+string(scope)+`"`)
+			c.JSON(http.StatusForbidden, apicontracts.ErrorResponse{Error: apicontracts.ErrorDto{
+				Code:    string(ccc.ErrCodeForbidden),
+				Message: "The token lacks the scope " + string(scope),
+			}})
+			c.Abort()
+			return
+		}
+
+		c.Next()
+	}
+}
+
+// GetApiPrincipal returns the principal authenticated by ApiTokenMiddleware
+func GetApiPrincipal(c *gin.Context) (auth.ApiPrincipal, bool) {
+	value, exists := c.Get(apiPrincipalContextKey)
+	if !exists {
+		return auth.ApiPrincipal{}, false
+	}
+	principal, ok := value.(auth.ApiPrincipal)
+	return principal, ok
+}
@@ -1,6 +1,9 @@
 package middleware
 
 import (
+	"net/http"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/apicontracts"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/gin-gonic/gin"
 )
@@ -94,3 +97,46 @@ func HandleErrorWithJson(c *gin.Context, err error, defaultMessage string) bool
 	c.JSON(statusCode, gin.H{"error": errorMessage})
 	return true
 }
+
+// apiErrorStatusCodes maps the error codes of ApiErrors to the HTTP status codes of the REST API
+var apiErrorStatusCodes = map[ccc.ErrorCode]int{
+	ccc.ErrCodeNotFound:         http.StatusNotFound,
+	ccc.ErrCodeAlreadyExists:    http.StatusConflict,
+	ccc.ErrCodeInvalidInput:     http.StatusBadRequest,
+	ccc.ErrCodeValidationFailed: http.StatusBadRequest,
+	ccc.ErrCodeDatabaseError:    http.StatusInternalServerError,
+	ccc.ErrCodeUnauthorized:     http.StatusUnauthorized,
+	ccc.ErrCodeForbidden:        http.StatusForbidden,
+	ccc.ErrCodeInternalError:    http.StatusInternalServerError,
+	ccc.ErrCodeOperationFailed:  http.StatusInternalServerError,
+	ccc.ErrCodeUserNameTaken:    http.StatusConflict,
+	ccc.ErrCodeConflict:         http.StatusConflict,
+}
+
+// HandleApiError handles errors of the REST API by responding with a JSON error object holding the error code and message.
+// Returns true if an error was handled, false if there was no error.
+func HandleApiError(c *gin.Context, err error) bool {
+	if err == nil {
+		return false
+	}
+
+	statusCode := http.StatusInternalServerError
+	code := ccc.ErrCodeInternalError
+	errorMessage := "An unexpected error occurred."
+
+	if apiErr, ok := ccc.IsApiError(err); ok {
+		code = apiErr.Code
+		errorMessage = apiErr.UserMessage
+		if mapped, found := apiErrorStatusCodes[apiErr.Code]; found {
+			statusCode = mapped
+		} else if apiErr.StatusCode != 0 {
+			statusCode = apiErr.StatusCode
+		}
+	}
+
+	c.JSON(statusCode, apicontracts.ErrorResponse{Error: apicontracts.ErrorDto{
+		Code:    string(code),
+		Message: errorMessage,
+	}})
+	return true
+}
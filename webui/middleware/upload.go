@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// multipartOverhead is the room left for the headers and boundaries of a multipart request with a single file
+const multipartOverhead = 1024 * 1024
+
+var (
+	// ErrUploadTooLarge is returned if a request announces a body larger than the maximum file size allows
+	ErrUploadTooLarge = errors.New("upload exceeds the maximum file size")
+	// ErrNoFileUploaded is returned if the multipart request does not contain the file field
+	ErrNoFileUploaded = errors.New("no file uploaded")
+	// ErrInvalidUploadChunk is returned if a part of a resumable upload lacks its offset or length
+	ErrInvalidUploadChunk = errors.New("a part has to be sent with the query parameter offset and a Content-Length")
+)
+
+// UploadedFile is a file whose content is read directly from the body of a multipart request
+type UploadedFile struct {
+	FileName    string
+	ContentType string // Content type declared by the client
+	Content     io.Reader
+}
+
+// StreamUploadedFile returns the file of the given form field of a multipart request. Unlike c.FormFile, the file
+// is neither buffered in memory nor in a temporary file, its content has to be read before the request body is
+// read any further. Form fields in front of the file are skipped. The body is limited to the maximum file size,
+// the exact limit is enforced by the document file creator, which counts the bytes of the content.
+func StreamUploadedFile(c *gin.Context, fieldName string, maxFileSize int64) (*UploadedFile, error) {
+	if c.Request.ContentLength > maxFileSize+multipartOverhead {
+		return nil, ErrUploadTooLarge
+	}
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxFileSize+multipartOverhead)
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read multipart request: %w", err)
+	}
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			return nil, ErrNoFileUploaded
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read multipart request: %w", err)
+		}
+		if part.FormName() == fieldName && part.FileName() != "" {
+			return &UploadedFile{
+				FileName:    part.FileName(),
+				ContentType: part.Header.Get("Content-Type"),
+				Content:     part,
+			}, nil
+		}
+	}
+}
+
+// ParseUploadChunk returns the offset and size of a part of a resumable upload, which is sent as the raw body of the
+// request. The offset is given by the query parameter offset, the size by the Content-Length header, so chunked
+// request bodies are rejected. The body is read from c.Request.Body.
+func ParseUploadChunk(c *gin.Context) (offset int64, size int64, err error) {
+	offset, err = strconv.ParseInt(c.Query("offset"), 10, 64)
+	if err != nil || offset < 0 || c.Request.ContentLength <= 0 {
+		return 0, 0, ErrInvalidUploadChunk
+	}
+	return offset, c.Request.ContentLength, nil
+}
@@ -0,0 +1,133 @@
+/* Frozen Fortress — passkey (WebAuthn) helpers.
+ * Loaded by the login and account pages. The server exchanges all binary
+ * values as base64url strings; this file converts them for the browser API
+ * and always evaluates the PRF extension, whose output unlocks the vault.
+ */
+(function () {
+  "use strict";
+
+  function toBuffer(value) {
+    var s = String(value || "").replace(/-/g, "+").replace(/_/g, "/");
+    while (s.length % 4) s += "=";
+    var raw = atob(s);
+    var bytes = new Uint8Array(raw.length);
+    for (var i = 0; i < raw.length; i++) bytes[i] = raw.charCodeAt(i);
+    return bytes.buffer;
+  }
+
+  function toBase64Url(buffer) {
+    if (!buffer) return "";
+    var bytes = new Uint8Array(buffer);
+    var raw = "";
+    for (var i = 0; i < bytes.length; i++) raw += String.fromCharCode(bytes[i]);
+    return btoa(raw).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "");
+  }
+
+  function toDescriptors(list) {
+    return (list || []).map(function (d) {
+      var descriptor = { type: d.type, id: toBuffer(d.id) };
+      if (d.transports && d.transports.length) descriptor.transports = d.transports;
+      return descriptor;
+    });
+  }
+
+  function prfOutput(credential) {
+    var results = credential.getClientExtensionResults ? credential.getClientExtensionResults() : {};
+    var first = results && results.prf && results.prf.results && results.prf.results.first;
+    return first ? toBase64Url(first) : "";
+  }
+
+  async function postJson(url, body) {
+    var res = await fetch(url, {
+      method: "POST",
+      headers: { "Accept": "application/json", "Content-Type": "application/json" },
+      body: body === undefined ? undefined : JSON.stringify(body),
+    });
+    var data = {};
+    try { data = await res.json(); } catch (_) {}
+    if (!res.ok) throw new Error(data.error || "Request failed (" + res.status + ")");
+    return data;
+  }
+
+  // Requests an assertion and returns it in the JSON shape expected by the server.
+  async function getAssertion(options, allowCredentials) {
+    var credential = await navigator.credentials.get({
+      publicKey: {
+        challenge: toBuffer(options.challenge),
+        rpId: options.rpId,
+        timeout: options.timeout,
+        allowCredentials: allowCredentials || toDescriptors(options.allowCredentials),
+        userVerification: options.userVerification,
+        extensions: { prf: { eval: { first: toBuffer(options.prfSalt) } } },
+      },
+    });
+    return {
+      id: credential.id,
+      clientDataJSON: toBase64Url(credential.response.clientDataJSON),
+      authenticatorData: toBase64Url(credential.response.authenticatorData),
+      signature: toBase64Url(credential.response.signature),
+      userHandle: toBase64Url(credential.response.userHandle),
+      prfOutput: prfOutput(credential),
+    };
+  }
+
+  window.ffPasskeys = {
+    supported: function () {
+      return !!(window.PublicKeyCredential && navigator.credentials && window.isSecureContext);
+    },
+
+    // Signs in with a discoverable passkey and returns the server response.
+    signIn: async function () {
+      var options = await postJson("/login/passkey/options");
+      var assertion = await getAssertion(options);
+      return postJson("/login/passkey", assertion);
+    },
+
+    // Registers a new passkey for the signed in user and returns the stored passkey.
+    register: async function (name) {
+      var options = await postJson("/account/passkeys/options");
+      var salt = toBuffer(options.prfSalt);
+
+      var credential = await navigator.credentials.create({
+        publicKey: {
+          challenge: toBuffer(options.challenge),
+          rp: options.rp,
+          user: {
+            id: toBuffer(options.user.id),
+            name: options.user.name,
+            displayName: options.user.displayName,
+          },
+          pubKeyCredParams: options.pubKeyCredParams,
+          timeout: options.timeout,
+          excludeCredentials: toDescriptors(options.excludeCredentials),
+          authenticatorSelection: options.authenticatorSelection,
+          attestation: options.attestation,
+          extensions: { prf: { eval: { first: salt } } },
+        },
+      });
+
+      var response = {
+        id: credential.id,
+        clientDataJSON: toBase64Url(credential.response.clientDataJSON),
+        attestationObject: toBase64Url(credential.response.attestationObject),
+        transports: credential.response.getTransports ? credential.response.getTransports() : [],
+        prfOutput: prfOutput(credential),
+      };
+
+      // Many authenticators only evaluate the PRF during an assertion, so ask the new passkey once more.
+      if (!response.prfOutput) {
+        var results = credential.getClientExtensionResults();
+        if (!results.prf || results.prf.enabled !== false) {
+          var assertion = await getAssertion(
+            { challenge: options.challenge, rpId: options.rp.id, timeout: options.timeout,
+              userVerification: "required", prfSalt: options.prfSalt },
+            [{ type: "public-key", id: credential.rawId }]
+          );
+          response.prfOutput = assertion.prfOutput;
+        }
+      }
+
+      return postJson("/account/passkeys", { name: name || "", response: response });
+    },
+  };
+})();
@@ -1,24 +1,56 @@
 package account
 
 import (
+	"fmt"
+	"html/template"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/vaultarchive"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 type services struct {
-	UserManager   auth.UserManager
-	SignInManager auth.SignInManager
+	UserManager    auth.UserManager
+	SignInManager  auth.SignInManager
+	PasskeyManager auth.PasskeyManager
+	ApiTokens      auth.ApiTokenManager
+	ChallengeStore auth.WebAuthnChallengeStore
+	MekStore       auth.MekStore
+	Encryption     encryption.EncryptionService
+	VaultArchives  vaultarchive.VaultArchiveManager
+	WebAuthn       ccc.WebAuthnConfig
 }
 
 // RegisterRoutes registers all account-related routes
-func RegisterRoutes(router *gin.Engine, userManager auth.UserManager, signInManager auth.SignInManager) {
+func RegisterRoutes(
+	router *gin.Engine,
+	userManager auth.UserManager,
+	signInManager auth.SignInManager,
+	passkeyManager auth.PasskeyManager,
+	apiTokenManager auth.ApiTokenManager,
+	challengeStore auth.WebAuthnChallengeStore,
+	mekStore auth.MekStore,
+	encryptionService encryption.EncryptionService,
+	vaultArchiveManager vaultarchive.VaultArchiveManager,
+	webAuthnConfig ccc.WebAuthnConfig) {
+
 	s := &services{
-		UserManager:   userManager,
-		SignInManager: signInManager,
+		UserManager:    userManager,
+		SignInManager:  signInManager,
+		PasskeyManager: passkeyManager,
+		ApiTokens:      apiTokenManager,
+		ChallengeStore: challengeStore,
+		MekStore:       mekStore,
+		Encryption:     encryptionService,
+		VaultArchives:  vaultArchiveManager,
+		WebAuthn:       webAuthnConfig,
 	}
 
 	accountGroup := router.Group("/account")
@@ -27,6 +59,18 @@ func RegisterRoutes(router *gin.Engine, userManager auth.UserManager, signInMana
 		accountGroup.GET("/", s.showAccountSettings)
 		accountGroup.POST("/change-password", s.changePassword)
 		accountGroup.POST("/generate-recovery-code", s.generateRecoveryCode)
+		accountGroup.POST("/totp/begin", s.beginTotpEnrollment)
+		accountGroup.POST("/totp/confirm", s.confirmTotpEnrollment)
+		accountGroup.POST("/totp/disable", s.disableTotp)
+		accountGroup.GET("/passkeys", s.listPasskeys)
+		accountGroup.POST("/passkeys/options", s.beginPasskeyRegistration)
+		accountGroup.POST("/passkeys", s.finishPasskeyRegistration)
+		accountGroup.DELETE("/passkeys/:id", s.deletePasskey)
+		accountGroup.GET("/api-tokens", s.listApiTokens)
+		accountGroup.POST("/api-tokens", s.createApiToken)
+		accountGroup.DELETE("/api-tokens/:id", s.revokeApiToken)
+		accountGroup.POST("/vault/export", s.exportVault)
+		accountGroup.POST("/vault/import", s.importVault)
 		accountGroup.POST("/deactivate", s.deactivateAccount)
 		accountGroup.POST("/delete", s.deleteAccount)
 	}
@@ -46,9 +90,10 @@ func (s *services) showAccountSettings(c *gin.Context) {
 	}
 
 	c.HTML(http.StatusOK, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	})
 }
 
@@ -74,6 +119,7 @@ func (s *services) changePassword(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":         "Account Settings",
 			"Username":      user.UserName,
+			"TotpEnabled":   user.TotpEnabled,
 			"passwordError": "All password fields are required",
 			"Version":       ccc.AppVersion,
 		})
@@ -84,6 +130,7 @@ func (s *services) changePassword(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":         "Account Settings",
 			"Username":      user.UserName,
+			"TotpEnabled":   user.TotpEnabled,
 			"passwordError": "New passwords do not match",
 			"Version":       ccc.AppVersion,
 		})
@@ -99,9 +146,10 @@ func (s *services) changePassword(c *gin.Context) {
 
 	success, err := s.UserManager.ChangePassword(request)
 	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	}, "passwordError") {
 		return
 	}
@@ -110,6 +158,7 @@ func (s *services) changePassword(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":         "Account Settings",
 			"Username":      user.UserName,
+			"TotpEnabled":   user.TotpEnabled,
 			"passwordError": "Password change failed",
 			"Version":       ccc.AppVersion,
 		})
@@ -119,6 +168,7 @@ func (s *services) changePassword(c *gin.Context) {
 	c.HTML(http.StatusOK, "account.html", gin.H{
 		"Title":           "Account Settings",
 		"Username":        user.UserName,
+		"TotpEnabled":     user.TotpEnabled,
 		"passwordSuccess": "Password changed successfully",
 		"Version":         ccc.AppVersion,
 	})
@@ -142,6 +192,7 @@ func (s *services) generateRecoveryCode(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":         "Account Settings",
 			"Username":      user.UserName,
+			"TotpEnabled":   user.TotpEnabled,
 			"RecoveryError": "Password is required to generate recovery code",
 			"Version":       ccc.AppVersion,
 		})
@@ -156,9 +207,10 @@ func (s *services) generateRecoveryCode(c *gin.Context) {
 
 	response, err := s.UserManager.GenerateRecoveryCode(request)
 	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	}, "RecoveryError") {
 		return
 	}
@@ -166,6 +218,7 @@ func (s *services) generateRecoveryCode(c *gin.Context) {
 	c.HTML(http.StatusOK, "account.html", gin.H{
 		"Title":           "Account Settings",
 		"Username":        user.UserName,
+		"TotpEnabled":     user.TotpEnabled,
 		"RecoveryCode":    response.RecoveryCode,
 		"RecoveryContext": "account",
 		"RecoverySuccess": "Recovery code generated successfully. Please save it in a secure location.",
@@ -173,6 +226,325 @@ func (s *services) generateRecoveryCode(c *gin.Context) {
 	})
 }
 
+// beginTotpEnrollment generates a new TOTP secret and shows it for the authenticator app
+func (s *services) beginTotpEnrollment(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+
+	if user.Id == "" {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	password := c.PostForm("password")
+	if password == "" {
+		c.HTML(http.StatusBadRequest, "account.html", gin.H{
+			"Title":       "Account Settings",
+			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
+			"TotpError":   "Password is required to set up two-factor authentication",
+			"Version":     ccc.AppVersion,
+		})
+		return
+	}
+
+	response, err := s.UserManager.BeginTotpEnrollment(auth.BeginTotpEnrollmentRequest{
+		UserId:   user.Id,
+		Password: password,
+	})
+	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
+	}, "TotpError") {
+		return
+	}
+
+	c.HTML(http.StatusOK, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": false,
+		"TotpSecret":  response.Secret,
+		"TotpUri":     totpUri(response.Uri),
+		"Version":     ccc.AppVersion,
+	})
+}
+
+// confirmTotpEnrollment enables TOTP once the user has entered a valid code
+func (s *services) confirmTotpEnrollment(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+
+	if user.Id == "" {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	password := c.PostForm("password")
+	code := c.PostForm("code")
+	secret := c.PostForm("secret")
+	uri := c.PostForm("uri")
+
+	// Keep showing the pending secret, so the user can retry with another code
+	pendingData := gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"TotpSecret":  secret,
+		"TotpUri":     totpUri(uri),
+		"Version":     ccc.AppVersion,
+	}
+
+	if password == "" || code == "" {
+		pendingData["TotpError"] = "Password and code are required"
+		c.HTML(http.StatusBadRequest, "account.html", pendingData)
+		return
+	}
+
+	err = s.UserManager.ConfirmTotpEnrollment(auth.ConfirmTotpEnrollmentRequest{
+		UserId:   user.Id,
+		Password: password,
+		Code:     code,
+	})
+	if middleware.HandleErrorOnPage(c, err, "account.html", pendingData, "TotpError") {
+		return
+	}
+
+	c.HTML(http.StatusOK, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": true,
+		"TotpSuccess": "Two-factor authentication has been enabled",
+		"Version":     ccc.AppVersion,
+	})
+}
+
+// disableTotp handles requests to turn off two-factor authentication
+func (s *services) disableTotp(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+
+	if user.Id == "" {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	password := c.PostForm("password")
+	if password == "" {
+		c.HTML(http.StatusBadRequest, "account.html", gin.H{
+			"Title":       "Account Settings",
+			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
+			"TotpError":   "Password is required to disable two-factor authentication",
+			"Version":     ccc.AppVersion,
+		})
+		return
+	}
+
+	err = s.UserManager.DisableTotp(auth.DisableTotpRequest{
+		UserId:   user.Id,
+		Password: password,
+	})
+	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
+	}, "TotpError") {
+		return
+	}
+
+	c.HTML(http.StatusOK, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": false,
+		"TotpSuccess": "Two-factor authentication has been disabled",
+		"Version":     ccc.AppVersion,
+	})
+}
+
+// listPasskeys returns the passkeys of the current user as JSON
+func (s *services) listPasskeys(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	passkeys, err := s.PasskeyManager.GetPasskeys(user.Id)
+	if middleware.HandleErrorWithJson(c, err, "Failed to retrieve passkeys") {
+		return
+	}
+
+	c.JSON(http.StatusOK, passkeys)
+}
+
+// beginPasskeyRegistration creates the options for registering a passkey and remembers the challenge
+func (s *services) beginPasskeyRegistration(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	rp := auth.ResolveRelyingParty(s.WebAuthn, c.Request)
+
+	options, err := s.PasskeyManager.BeginRegistration(user.Id, rp)
+	if middleware.HandleErrorWithJson(c, err, "Failed to start passkey registration") {
+		return
+	}
+
+	err = s.ChallengeStore.Store(c.Writer, c.Request, auth.WebAuthnCeremonyRegistration, options.Challenge)
+	if middleware.HandleErrorWithJson(c, err, "Failed to start passkey registration") {
+		return
+	}
+
+	c.JSON(http.StatusOK, options)
+}
+
+// finishPasskeyRegistration verifies the new passkey and stores it with an envelope of the MEK
+func (s *services) finishPasskeyRegistration(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Name     string                           `json:"name"`
+		Response auth.PasskeyRegistrationResponse `json:"response"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	challenge, err := s.ChallengeStore.Take(c.Writer, c.Request, auth.WebAuthnCeremonyRegistration)
+	if middleware.HandleErrorWithJson(c, err, "Failed to register passkey") {
+		return
+	}
+
+	mek, err := s.MekStore.Retrieve(c.Request)
+	if middleware.HandleErrorWithJson(c, err, "Failed to register passkey") {
+		return
+	}
+
+	passkey, err := s.PasskeyManager.FinishRegistration(auth.FinishPasskeyRegistrationRequest{
+		UserId:       user.Id,
+		Mek:          mek,
+		Name:         request.Name,
+		Challenge:    challenge,
+		RelyingParty: auth.ResolveRelyingParty(s.WebAuthn, c.Request),
+		Response:     request.Response,
+	})
+	if middleware.HandleErrorWithJson(c, err, "Failed to register passkey") {
+		return
+	}
+
+	c.JSON(http.StatusCreated, passkey)
+}
+
+// deletePasskey removes a passkey of the current user
+func (s *services) deletePasskey(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err = s.PasskeyManager.DeletePasskey(user.Id, c.Param("id"))
+	if middleware.HandleErrorWithJson(c, err, "Failed to delete passkey") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Passkey deleted successfully"})
+}
+
+// listApiTokens returns the personal access tokens of the current user as JSON
+func (s *services) listApiTokens(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	tokens, err := s.ApiTokens.GetTokens(user.Id)
+	if middleware.HandleErrorWithJson(c, err, "Failed to retrieve API tokens") {
+		return
+	}
+
+	c.JSON(http.StatusOK, tokens)
+}
+
+// createApiToken creates a personal access token with its own envelope of the MEK.
+// The token is only contained in this response and cannot be retrieved later.
+func (s *services) createApiToken(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	var request struct {
+		Name          string   `json:"name"`
+		Scopes        []string `json:"scopes"`
+		ExpiresInDays int      `json:"expiresInDays"`
+	}
+	if err := c.ShouldBindJSON(&request); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+		return
+	}
+
+	mek, err := s.MekStore.Retrieve(c.Request)
+	if middleware.HandleErrorWithJson(c, err, "Failed to create API token") {
+		return
+	}
+
+	scopes := make([]auth.ApiTokenScope, 0, len(request.Scopes))
+	for _, scope := range request.Scopes {
+		scopes = append(scopes, auth.ApiTokenScope(scope))
+	}
+
+	response, err := s.ApiTokens.CreateToken(auth.CreateApiTokenRequest{
+		UserId:        user.Id,
+		Mek:           mek,
+		Name:          request.Name,
+		Scopes:        scopes,
+		ExpiresInDays: request.ExpiresInDays,
+	})
+	if middleware.HandleErrorWithJson(c, err, "Failed to create API token") {
+		return
+	}
+
+	c.JSON(http.StatusCreated, response)
+}
+
+// revokeApiToken revokes a personal access token of the current user
+func (s *services) revokeApiToken(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil || user.Id == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	err = s.ApiTokens.RevokeToken(user.Id, c.Param("id"))
+	if middleware.HandleErrorWithJson(c, err, "Failed to revoke API token") {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "API token revoked successfully"})
+}
+
 // deactivateAccount handles account deactivation requests
 func (s *services) deactivateAccount(c *gin.Context) {
 	user, err := s.SignInManager.GetCurrentUser(c.Request)
@@ -191,6 +563,7 @@ func (s *services) deactivateAccount(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":           "Account Settings",
 			"Username":        user.UserName,
+			"TotpEnabled":     user.TotpEnabled,
 			"DeactivateError": "Password is required to deactivate account",
 			"Version":         ccc.AppVersion,
 		})
@@ -200,9 +573,10 @@ func (s *services) deactivateAccount(c *gin.Context) {
 	// Verify password before deactivating
 	err = s.UserManager.VerifyPassword(user.Id, password)
 	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	}, "DeactivateError") {
 		return
 	}
@@ -210,9 +584,10 @@ func (s *services) deactivateAccount(c *gin.Context) {
 	// Deactivate account
 	success, err := s.UserManager.DeactivateUser(user.Id)
 	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	}, "DeactivateError") {
 		return
 	}
@@ -221,6 +596,7 @@ func (s *services) deactivateAccount(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":           "Account Settings",
 			"Username":        user.UserName,
+			"TotpEnabled":     user.TotpEnabled,
 			"DeactivateError": "Failed to deactivate account",
 			"Version":         ccc.AppVersion,
 		})
@@ -251,6 +627,7 @@ func (s *services) deleteAccount(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":       "Account Settings",
 			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
 			"DeleteError": "Password is required to delete account",
 			"Version":     ccc.AppVersion,
 		})
@@ -261,6 +638,7 @@ func (s *services) deleteAccount(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":       "Account Settings",
 			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
 			"DeleteError": "Please type 'DELETE' to confirm account deletion",
 			"Version":     ccc.AppVersion,
 		})
@@ -270,9 +648,10 @@ func (s *services) deleteAccount(c *gin.Context) {
 	// Verify password before deleting
 	err = s.UserManager.VerifyPassword(user.Id, password)
 	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	}, "DeleteError") {
 		return
 	}
@@ -280,9 +659,10 @@ func (s *services) deleteAccount(c *gin.Context) {
 	// Delete account
 	success, err := s.UserManager.DeleteUser(user.Id)
 	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
-		"Title":    "Account Settings",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
 	}, "DeleteError") {
 		return
 	}
@@ -291,6 +671,7 @@ func (s *services) deleteAccount(c *gin.Context) {
 		c.HTML(http.StatusBadRequest, "account.html", gin.H{
 			"Title":       "Account Settings",
 			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
 			"DeleteError": "Failed to delete account",
 			"Version":     ccc.AppVersion,
 		})
@@ -300,3 +681,187 @@ func (s *services) deleteAccount(c *gin.Context) {
 	// Redirect to login page after deletion
 	c.Redirect(http.StatusFound, "/login?message=Account deleted successfully")
 }
+
+// exportVault streams an encrypted archive of the user's data as a download
+func (s *services) exportVault(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+
+	if user.Id == "" {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	password := c.PostForm("password")
+	passphrase := c.PostForm("passphrase")
+	confirmPassphrase := c.PostForm("confirm_passphrase")
+
+	if password == "" || passphrase == "" {
+		c.HTML(http.StatusBadRequest, "account.html", gin.H{
+			"Title":       "Account Settings",
+			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
+			"VaultError":  "Password and archive passphrase are required to export your data",
+			"Version":     ccc.AppVersion,
+		})
+		return
+	}
+
+	if passphrase != confirmPassphrase {
+		c.HTML(http.StatusBadRequest, "account.html", gin.H{
+			"Title":       "Account Settings",
+			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
+			"VaultError":  "Archive passphrases do not match",
+			"Version":     ccc.AppVersion,
+		})
+		return
+	}
+
+	err = s.UserManager.VerifyPassword(user.Id, password)
+	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
+	}, "VaultError") {
+		return
+	}
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(s.MekStore, s.Encryption, c.Request)
+	download := &attachmentWriter{
+		context:  c,
+		filename: fmt.Sprintf("%s-%s.ffvault", user.UserName, time.Now().Format("20060102-150405")),
+	}
+
+	_, err = s.VaultArchives.ExportVault(c.Request.Context(), user.Id, passphrase, dataProtector, download)
+	if err != nil && !download.started {
+		middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
+			"Title":       "Account Settings",
+			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
+			"Version":     ccc.AppVersion,
+		}, "VaultError")
+		return
+	}
+	if err != nil {
+		// The download lacks the final chunk of the archive, so it is rejected on import
+		c.Error(err)
+		c.Abort()
+	}
+}
+
+// importVault recreates the content of an uploaded archive in the user's account
+func (s *services) importVault(c *gin.Context) {
+	user, err := s.SignInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+
+	if user.Id == "" {
+		c.Redirect(http.StatusSeeOther, "/login")
+		return
+	}
+
+	password := c.PostForm("password")
+	passphrase := c.PostForm("passphrase")
+	fileHeader, fileErr := c.FormFile("archive")
+
+	if password == "" || passphrase == "" || fileErr != nil {
+		c.HTML(http.StatusBadRequest, "account.html", gin.H{
+			"Title":       "Account Settings",
+			"Username":    user.UserName,
+			"TotpEnabled": user.TotpEnabled,
+			"VaultError":  "Archive, password and archive passphrase are required to import data",
+			"Version":     ccc.AppVersion,
+		})
+		return
+	}
+
+	err = s.UserManager.VerifyPassword(user.Id, password)
+	if middleware.HandleErrorOnPage(c, err, "account.html", gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
+	}, "VaultError") {
+		return
+	}
+
+	archive, err := fileHeader.Open()
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+	defer archive.Close()
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(s.MekStore, s.Encryption, c.Request)
+	summary, err := s.VaultArchives.ImportVault(c.Request.Context(), user.Id, passphrase, dataProtector, archive)
+
+	templateData := gin.H{
+		"Title":       "Account Settings",
+		"Username":    user.UserName,
+		"TotpEnabled": user.TotpEnabled,
+		"Version":     ccc.AppVersion,
+	}
+	if summary != nil {
+		templateData["VaultRenamedSecrets"] = summary.RenamedSecrets
+		if err != nil && summary.Secrets+summary.Tags+summary.Documents > 0 {
+			templateData["VaultWarning"] = fmt.Sprintf("The import stopped after %s were imported. They have been kept.", vaultSummaryText(summary))
+		}
+	}
+	if middleware.HandleErrorOnPage(c, err, "account.html", templateData, "VaultError") {
+		return
+	}
+
+	templateData["VaultSuccess"] = fmt.Sprintf("Imported %s.", vaultSummaryText(summary))
+	c.HTML(http.StatusOK, "account.html", templateData)
+}
+
+// vaultSummaryText describes the imported items, e.g. "3 secrets, 1 tag, 2 documents, 4 files and 0 notes"
+func vaultSummaryText(summary *vaultarchive.VaultArchiveSummary) string {
+	count := func(n int, noun string) string {
+		if n == 1 {
+			return fmt.Sprintf("1 %s", noun)
+		}
+		return fmt.Sprintf("%d %ss", n, noun)
+	}
+	return fmt.Sprintf("%s, %s, %s, %s and %s",
+		count(summary.Secrets, "secret"),
+		count(summary.Tags, "tag"),
+		count(summary.Documents, "document"),
+		count(summary.Files, "file"),
+		count(summary.Notes, "note"))
+}
+
+// attachmentWriter sends the headers of a file download with the first write,
+// so an error page can still be rendered if nothing was written
+type attachmentWriter struct {
+	context  *gin.Context
+	filename string
+	started  bool
+}
+
+func (w *attachmentWriter) Write(p []byte) (int, error) {
+	if !w.started {
+		w.started = true
+		w.context.Header("Content-Type", "application/octet-stream")
+		w.context.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", w.filename))
+		w.context.Header("Cache-Control", "no-store")
+		w.context.Status(http.StatusOK)
+	}
+	return w.context.Writer.Write(p)
+}
+
+// totpUri marks an otpauth:// URI as safe for use in links, which html/template would otherwise filter out.
+// The URI is posted back by the confirmation form, so anything but an otpauth URI is dropped.
+func totpUri(uri string) template.URL {
+	if !strings.HasPrefix(uri, "otpauth://totp/") {
+		return ""
+	}
+	return template.URL(uri)
+}
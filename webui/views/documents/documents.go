@@ -2,6 +2,7 @@ package documents
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"strings"
@@ -16,25 +17,20 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
-const (
-	// MaxFileSizeMB defines the maximum allowed file size in MB for document uploads
-	MaxFileSizeMB = 30
-	// MaxFileSize defines the maximum allowed file size in bytes for document uploads
-	MaxFileSize = MaxFileSizeMB * 1024 * 1024 // Convert MB to bytes
-)
-
 // getMaxFileSizeMB returns the maximum file size in MB as a string for display
-func getMaxFileSizeMB() string {
-	return fmt.Sprintf("%dMB", MaxFileSizeMB)
+func getMaxFileSizeMB(maxFileSize int64) string {
+	return fmt.Sprintf("%dMB", maxFileSize/(1024*1024))
 }
 
 // DocumentServices aggregates document-related services for cleaner function signatures
 type DocumentServices struct {
-	DocumentManager     documents.DocumentManager
-	DocumentFileManager documents.DocumentFileManager
-	DocumentListService documents.DocumentListService
-	TagManager          documents.TagManager
-	NoteManager         documents.NoteManager
+	DocumentManager      documents.DocumentManager
+	DocumentFileManager  documents.DocumentFileManager
+	UploadSessionManager documents.UploadSessionManager
+	DocumentListService  documents.DocumentListService
+	TagManager           documents.TagManager
+	NoteManager          documents.NoteManager
+	MaxFileSize          int64 // Maximum size of uploaded files in bytes
 }
 
 // RegisterRoutes registers the documents routes with the provided Gin router.
@@ -46,15 +42,15 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, docume
 
 	// Create document routes - protected by authentication
 	router.GET("/create-document", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleCreateDocumentPage(c, signInManager, logger)
+		handleCreateDocumentPage(c, signInManager, documentServices.MaxFileSize, logger)
 	})
 	router.POST("/create-document", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleCreateDocumentSubmit(c, signInManager, documentServices.DocumentManager, documentServices.TagManager, mekStore, encryptionService, logger)
+		handleCreateDocumentSubmit(c, signInManager, documentServices.DocumentManager, documentServices.TagManager, mekStore, encryptionService, documentServices.MaxFileSize, logger)
 	})
 
 	// Edit document route - protected by authentication
 	router.GET("/edit-document", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleEditDocumentPage(c, signInManager, documentServices.DocumentManager, documentServices.TagManager, mekStore, encryptionService, logger)
+		handleEditDocumentPage(c, signInManager, documentServices.DocumentManager, documentServices.TagManager, mekStore, encryptionService, documentServices.MaxFileSize, logger)
 	})
 	router.POST("/edit-document", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
 		handleEditDocumentSubmit(c, signInManager, documentServices.DocumentManager, documentServices.TagManager, mekStore, encryptionService, logger)
@@ -70,7 +66,7 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, docume
 		handleGetDocumentFiles(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, logger)
 	})
 	router.POST("/api/documents/:documentId/files", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleUploadDocumentFile(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, logger)
+		handleUploadDocumentFile(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, documentServices.MaxFileSize, logger)
 	})
 	router.DELETE("/api/documents/:documentId/files/:fileId", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
 		handleDeleteDocumentFile(c, signInManager, documentServices.DocumentFileManager, logger)
@@ -82,6 +78,34 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, docume
 		handleViewDocumentFile(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, logger)
 	})
 
+	// API routes for resumable uploads of document files - protected by authentication
+	router.POST("/api/documents/:documentId/uploads", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleCreateUploadSession(c, signInManager, documentServices.UploadSessionManager, mekStore, encryptionService, logger)
+	})
+	router.GET("/api/documents/:documentId/uploads/:uploadId", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleGetUploadSession(c, signInManager, documentServices.UploadSessionManager, mekStore, encryptionService, logger)
+	})
+	router.PUT("/api/documents/:documentId/uploads/:uploadId", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleUploadChunk(c, signInManager, documentServices.UploadSessionManager, mekStore, encryptionService, logger)
+	})
+	router.POST("/api/documents/:documentId/uploads/:uploadId/complete", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleCompleteUploadSession(c, signInManager, documentServices.UploadSessionManager, mekStore, encryptionService, logger)
+	})
+	router.DELETE("/api/documents/:documentId/uploads/:uploadId", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleAbortUploadSession(c, signInManager, documentServices.UploadSessionManager, logger)
+	})
+
+	// API routes for re-running text extraction (OCR) - protected by authentication
+	router.POST("/api/documents/:documentId/files/:fileId/ocr", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleReprocessDocumentFile(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, logger)
+	})
+	router.POST("/api/documents/:documentId/ocr", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleReprocessDocument(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, logger)
+	})
+	router.POST("/api/ocr/retry-failed", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleRetryFailedOcr(c, signInManager, documentServices.DocumentFileManager, mekStore, encryptionService, logger)
+	})
+
 	// API routes for document notes - protected by authentication
 	router.GET("/api/documents/:documentId/notes", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
 		handleGetDocumentNotes(c, signInManager, documentServices.NoteManager, mekStore, encryptionService, logger)
@@ -141,7 +165,7 @@ func handleDocumentsPage(c *gin.Context, signInManager auth.SignInManager, docum
 	} else if c.Query("updated") == "1" {
 		successMessage = "Document updated successfully!"
 	} else if c.Query("deleted") == "1" {
-		successMessage = "Document deleted successfully!"
+		successMessage = "Document moved to the trash."
 	}
 
 	// Parse page number
@@ -229,7 +253,7 @@ func handleDocumentsPage(c *gin.Context, signInManager auth.SignInManager, docum
 	}
 
 	// Get all tags for filtering dropdown
-	allTags, err := tagManager.GetUserTags(c.Request.Context(), user.Id)
+	allTags, err := tagManager.GetUserTags(c.Request.Context(), user.Id, dataProtector)
 	if err != nil {
 		logger.Error("Failed to get tags for user", "user_id", user.Id, "error", err)
 		// Don't fail the page load if tags can't be loaded
@@ -274,7 +298,7 @@ func handleDocumentsPage(c *gin.Context, signInManager auth.SignInManager, docum
 }
 
 // handleEditDocumentPage handles GET requests to the edit-document page
-func handleEditDocumentPage(c *gin.Context, signInManager auth.SignInManager, documentManager documents.DocumentManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+func handleEditDocumentPage(c *gin.Context, signInManager auth.SignInManager, documentManager documents.DocumentManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, maxFileSize int64, logger ccc.Logger) {
 	// Get current user for display
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
@@ -305,7 +329,7 @@ func handleEditDocumentPage(c *gin.Context, signInManager auth.SignInManager, do
 	}
 
 	// Get all tags for the dropdown
-	allTags, err := tagManager.GetUserTags(c.Request.Context(), user.Id)
+	allTags, err := tagManager.GetUserTags(c.Request.Context(), user.Id, dataProtector)
 	if err != nil {
 		logger.Error("Failed to get tags for user", "user_id", user.Id, "error", err)
 		// Don't fail the page load if tags can't be loaded
@@ -322,8 +346,8 @@ func handleEditDocumentPage(c *gin.Context, signInManager auth.SignInManager, do
 		"Document":        document,
 		"AllTags":         allTags,
 		"DocumentTags":    documentTags,
-		"MaxFileSize":     MaxFileSize,
-		"MaxFileSizeText": getMaxFileSizeMB(),
+		"MaxFileSize":     maxFileSize,
+		"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 	}
 
 	// Render the edit document template
@@ -378,7 +402,7 @@ func handleViewDocumentPage(c *gin.Context, signInManager auth.SignInManager, do
 }
 
 // handleCreateDocumentPage handles GET requests to the create-document page
-func handleCreateDocumentPage(c *gin.Context, signInManager auth.SignInManager, logger ccc.Logger) {
+func handleCreateDocumentPage(c *gin.Context, signInManager auth.SignInManager, maxFileSize int64, logger ccc.Logger) {
 	// Get current user for display
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
@@ -390,8 +414,8 @@ func handleCreateDocumentPage(c *gin.Context, signInManager auth.SignInManager,
 		"Title":           "Frozen Fortress - Create Document",
 		"Username":        user.UserName,
 		"Version":         ccc.AppVersion,
-		"MaxFileSize":     MaxFileSize,
-		"MaxFileSizeText": getMaxFileSizeMB(),
+		"MaxFileSize":     maxFileSize,
+		"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 	}
 
 	// Render the create document template
@@ -399,7 +423,7 @@ func handleCreateDocumentPage(c *gin.Context, signInManager auth.SignInManager,
 }
 
 // handleCreateDocumentSubmit handles POST requests to create a new document
-func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager, documentManager documents.DocumentManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager, documentManager documents.DocumentManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, maxFileSize int64, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
@@ -431,8 +455,8 @@ func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager
 			"DocumentTitle":   title,
 			"Description":     description,
 			"ErrorMessage":    "Document title is required.",
-			"MaxFileSize":     MaxFileSize,
-			"MaxFileSizeText": getMaxFileSizeMB(),
+			"MaxFileSize":     maxFileSize,
+			"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 		}
 		c.HTML(400, "create-document.html", templateData)
 		return
@@ -463,8 +487,8 @@ func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager
 			"DocumentTitle":   title,
 			"Description":     description,
 			"ErrorMessage":    "Failed to process uploaded files.",
-			"MaxFileSize":     MaxFileSize,
-			"MaxFileSizeText": getMaxFileSizeMB(),
+			"MaxFileSize":     maxFileSize,
+			"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 		}
 		c.HTML(400, "create-document.html", templateData)
 		return
@@ -473,20 +497,12 @@ func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager
 	files := form.File["files"]
 	var addFileRequests []documents.AddFileRequest
 
-	// Define allowed content types
-	allowedContentTypes := map[string]bool{
-		"image/jpeg":      true,
-		"image/jpg":       true,
-		"image/png":       true,
-		"application/pdf": true,
-	}
-
 	for _, fileHeader := range files {
-		// Get content type from header
-		contentType := fileHeader.Header.Get("Content-Type")
+		// Get content type from header, falling back to the file extension for formats browsers do not know
+		contentType := documents.ResolveContentType(fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
 
 		// Validate content type
-		if !allowedContentTypes[contentType] {
+		if !documents.IsSupportedContentType(contentType) {
 			logger.Warn("Rejected file with unsupported content type",
 				"filename", fileHeader.Filename,
 				"content_type", contentType,
@@ -495,38 +511,38 @@ func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager
 				"Title":           "Frozen Fortress - Create Document",
 				"Username":        user.UserName,
 				"Version":         ccc.AppVersion,
-				"ErrorMessage":    "File '" + fileHeader.Filename + "' has an unsupported format. Only PNG, JPG, JPEG, and PDF files are allowed.",
+				"ErrorMessage":    "File '" + fileHeader.Filename + "' has an unsupported format. Supported formats are " + documents.SupportedFormatsDescription + ".",
 				"DocumentTitle":   title,
 				"Description":     description,
-				"MaxFileSize":     MaxFileSize,
-				"MaxFileSizeText": getMaxFileSizeMB(),
+				"MaxFileSize":     maxFileSize,
+				"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 			}
 			c.HTML(400, "create-document.html", templateData)
 			return
 		}
 
 		// Check file size limit
-		if fileHeader.Size > MaxFileSize {
+		if fileHeader.Size > maxFileSize {
 			logger.Warn("Rejected file that exceeds size limit",
 				"filename", fileHeader.Filename,
 				"size", fileHeader.Size,
-				"max_size", MaxFileSize,
+				"max_size", maxFileSize,
 				"user_id", user.Id)
 			templateData := gin.H{
 				"Title":           "Frozen Fortress - Create Document",
 				"Username":        user.UserName,
 				"Version":         ccc.AppVersion,
-				"ErrorMessage":    "File '" + fileHeader.Filename + "' is too large. Maximum file size is " + getMaxFileSizeMB() + ".",
+				"ErrorMessage":    "File '" + fileHeader.Filename + "' is too large. Maximum file size is " + getMaxFileSizeMB(maxFileSize) + ".",
 				"DocumentTitle":   title,
 				"Description":     description,
-				"MaxFileSize":     MaxFileSize,
-				"MaxFileSizeText": getMaxFileSizeMB(),
+				"MaxFileSize":     maxFileSize,
+				"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 			}
 			c.HTML(400, "create-document.html", templateData)
 			return
 		}
 
-		// Read file content
+		// Open file content, which is encrypted while the document is created
 		file, err := fileHeader.Open()
 		if err != nil {
 			logger.Error("Failed to open uploaded file", "filename", fileHeader.Filename, "error", err)
@@ -534,17 +550,10 @@ func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager
 		}
 		defer file.Close()
 
-		fileData := make([]byte, fileHeader.Size)
-		_, err = file.Read(fileData)
-		if err != nil {
-			logger.Error("Failed to read uploaded file", "filename", fileHeader.Filename, "error", err)
-			continue
-		}
-
 		addFileRequests = append(addFileRequests, documents.AddFileRequest{
 			FileName:    fileHeader.Filename,
 			ContentType: contentType,
-			FileData:    fileData,
+			Content:     file,
 		})
 	}
 
@@ -575,8 +584,8 @@ func handleCreateDocumentSubmit(c *gin.Context, signInManager auth.SignInManager
 			"Version":         ccc.AppVersion,
 			"DocumentTitle":   title,
 			"Description":     description,
-			"MaxFileSize":     MaxFileSize,
-			"MaxFileSizeText": getMaxFileSizeMB(),
+			"MaxFileSize":     maxFileSize,
+			"MaxFileSizeText": getMaxFileSizeMB(maxFileSize),
 		}
 
 		if middleware.HandleErrorOnPage(c, err, "create-document.html", templateData, "ErrorMessage") {
@@ -710,10 +719,10 @@ func handleDeleteDocument(c *gin.Context, signInManager auth.SignInManager, docu
 		return
 	}
 
-	logger.Info("Document deleted successfully", "user_id", user.Id, "document_id", documentId)
+	logger.Info("Document moved to the trash", "user_id", user.Id, "document_id", documentId)
 
 	// Respond with success
-	c.JSON(200, gin.H{"success": true, "message": "Document deleted successfully"})
+	c.JSON(200, gin.H{"success": true, "message": "Document moved to the trash"})
 }
 
 // handleGetDocumentFiles handles GET requests to retrieve document file previews
@@ -774,8 +783,8 @@ func handleDownloadDocumentFile(c *gin.Context, signInManager auth.SignInManager
 		c.Request,
 	)
 
-	// Get the file
-	file, err := documentFileManager.GetDocumentFile(c.Request.Context(), user.Id, documentId, fileId, dataProtector)
+	// Open the file
+	file, content, err := documentFileManager.OpenDocumentFile(c.Request.Context(), user.Id, documentId, fileId, dataProtector)
 	if err != nil {
 		logger.Error("Failed to get document file for download", "user_id", user.Id, "document_id", documentId, "file_id", fileId, "error", err)
 		if middleware.HandleError(c, err) {
@@ -783,13 +792,13 @@ func handleDownloadDocumentFile(c *gin.Context, signInManager auth.SignInManager
 		}
 	}
 
-	// Set headers for download
-	c.Header("Content-Disposition", "attachment; filename="+file.FileName)
-	c.Header("Content-Type", file.ContentType)
-	c.Header("Content-Length", strconv.Itoa(len(file.FileData)))
-
-	// Write file data to response
-	c.Data(200, file.ContentType, file.FileData)
+	// Stream the decrypted file data to the response
+	c.DataFromReader(200, file.FileSize, file.ContentType, content, map[string]string{
+		"Content-Disposition": "attachment; filename=" + file.FileName,
+	})
+	if err := c.Errors.Last(); err != nil {
+		logger.Error("Failed to stream document file for download", "user_id", user.Id, "document_id", documentId, "file_id", fileId, "error", err)
+	}
 }
 
 // handleViewDocumentFile handles GET requests to view a document file
@@ -815,8 +824,8 @@ func handleViewDocumentFile(c *gin.Context, signInManager auth.SignInManager, do
 		c.Request,
 	)
 
-	// Get the file
-	file, err := documentFileManager.GetDocumentFile(c.Request.Context(), user.Id, documentId, fileId, dataProtector)
+	// Open the file
+	file, content, err := documentFileManager.OpenDocumentFile(c.Request.Context(), user.Id, documentId, fileId, dataProtector)
 	if err != nil {
 		logger.Error("Failed to get document file for viewing", "user_id", user.Id, "document_id", documentId, "file_id", fileId, "error", err)
 		if middleware.HandleError(c, err) {
@@ -824,16 +833,15 @@ func handleViewDocumentFile(c *gin.Context, signInManager auth.SignInManager, do
 		}
 	}
 
-	// Set headers for viewing
-	c.Header("Content-Type", file.ContentType)
-	c.Header("Content-Length", strconv.Itoa(len(file.FileData)))
-
-	// Write file data to response
-	c.Data(200, file.ContentType, file.FileData)
+	// Stream the decrypted file data to the response
+	c.DataFromReader(200, file.FileSize, file.ContentType, content, nil)
+	if err := c.Errors.Last(); err != nil {
+		logger.Error("Failed to stream document file for viewing", "user_id", user.Id, "document_id", documentId, "file_id", fileId, "error", err)
+	}
 }
 
 // handleUploadDocumentFile handles POST requests to upload a new file to a document
-func handleUploadDocumentFile(c *gin.Context, signInManager auth.SignInManager, documentFileManager documents.DocumentFileManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+func handleUploadDocumentFile(c *gin.Context, signInManager auth.SignInManager, documentFileManager documents.DocumentFileManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, maxFileSize int64, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
@@ -847,62 +855,32 @@ func handleUploadDocumentFile(c *gin.Context, signInManager auth.SignInManager,
 		return
 	}
 
-	// Parse uploaded file
-	fileHeader, err := c.FormFile("file")
+	// Stream the uploaded file, which is encrypted and stored while it is received
+	uploadedFile, err := middleware.StreamUploadedFile(c, "file", maxFileSize)
+	if errors.Is(err, middleware.ErrUploadTooLarge) {
+		logger.Warn("Rejected file that exceeds size limit",
+			"size", c.Request.ContentLength,
+			"max_size", maxFileSize,
+			"user_id", user.Id)
+		c.JSON(400, gin.H{"success": false, "error": "File is too large. Maximum file size is " + getMaxFileSizeMB(maxFileSize)})
+		return
+	}
 	if err != nil {
 		logger.Error("Failed to get uploaded file", "error", err)
 		c.JSON(400, gin.H{"success": false, "error": "No file uploaded"})
 		return
 	}
 
-	// Check file size limit
-	if fileHeader.Size > MaxFileSize {
-		logger.Warn("Rejected file that exceeds size limit",
-			"filename", fileHeader.Filename,
-			"size", fileHeader.Size,
-			"max_size", MaxFileSize,
-			"user_id", user.Id)
-		c.JSON(400, gin.H{"success": false, "error": "File is too large. Maximum file size is " + getMaxFileSizeMB()})
-		return
-	}
-
-	// Get content type
-	contentType := fileHeader.Header.Get("Content-Type")
-
-	// Define allowed content types
-	allowedContentTypes := map[string]bool{
-		"image/jpeg":      true,
-		"image/jpg":       true,
-		"image/png":       true,
-		"application/pdf": true,
-		"image/gif":       true,
-		"text/plain":      true,
-	}
+	// Get content type, falling back to the file extension for formats browsers do not know
+	contentType := documents.ResolveContentType(uploadedFile.FileName, uploadedFile.ContentType)
 
 	// Validate content type
-	if !allowedContentTypes[contentType] {
+	if !documents.IsSupportedContentType(contentType) {
 		logger.Warn("Rejected file with unsupported content type",
-			"filename", fileHeader.Filename,
+			"filename", uploadedFile.FileName,
 			"content_type", contentType,
 			"user_id", user.Id)
-		c.JSON(400, gin.H{"success": false, "error": "Unsupported file type. Only images, PDFs, and text files are allowed"})
-		return
-	}
-
-	// Read file content
-	file, err := fileHeader.Open()
-	if err != nil {
-		logger.Error("Failed to open uploaded file", "filename", fileHeader.Filename, "error", err)
-		c.JSON(500, gin.H{"success": false, "error": "Failed to read uploaded file"})
-		return
-	}
-	defer file.Close()
-
-	fileData := make([]byte, fileHeader.Size)
-	_, err = file.Read(fileData)
-	if err != nil {
-		logger.Error("Failed to read uploaded file", "filename", fileHeader.Filename, "error", err)
-		c.JSON(500, gin.H{"success": false, "error": "Failed to read uploaded file"})
+		c.JSON(400, gin.H{"success": false, "error": "Unsupported file type. Supported formats are " + documents.SupportedFormatsDescription})
 		return
 	}
 
@@ -915,21 +893,21 @@ func handleUploadDocumentFile(c *gin.Context, signInManager auth.SignInManager,
 
 	// Create add file request
 	addFileRequest := documents.AddFileRequest{
-		FileName:    fileHeader.Filename,
+		FileName:    uploadedFile.FileName,
 		ContentType: contentType,
-		FileData:    fileData,
+		Content:     uploadedFile.Content,
 	}
 
 	// Add file to document
 	addedFile, err := documentFileManager.AddDocumentFile(c.Request.Context(), user.Id, documentId, addFileRequest, dataProtector)
 	if err != nil {
-		logger.Error("Failed to add file to document", "user_id", user.Id, "document_id", documentId, "filename", fileHeader.Filename, "error", err)
+		logger.Error("Failed to add file to document", "user_id", user.Id, "document_id", documentId, "filename", uploadedFile.FileName, "error", err)
 		if middleware.HandleErrorWithJson(c, err, "Failed to add file to document") {
 			return
 		}
 	}
 
-	logger.Info("File uploaded successfully", "user_id", user.Id, "document_id", documentId, "file_id", addedFile.Id, "filename", fileHeader.Filename)
+	logger.Info("File uploaded successfully", "user_id", user.Id, "document_id", documentId, "file_id", addedFile.Id, "filename", uploadedFile.FileName)
 
 	// Return success response with file info
 	c.JSON(200, gin.H{
@@ -973,6 +951,113 @@ func handleDeleteDocumentFile(c *gin.Context, signInManager auth.SignInManager,
 	})
 }
 
+// handleReprocessDocumentFile handles POST requests to re-run the text extraction of a single file
+func handleReprocessDocumentFile(c *gin.Context, signInManager auth.SignInManager, documentFileManager documents.DocumentFileManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	documentId := c.Param("documentId")
+	fileId := c.Param("fileId")
+	if documentId == "" || fileId == "" {
+		c.JSON(400, gin.H{"success": false, "error": "Document ID and File ID are required"})
+		return
+	}
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	err = documentFileManager.ReprocessFile(c.Request.Context(), user.Id, documentId, fileId, dataProtector)
+	if err != nil {
+		logger.Error("Failed to queue text extraction", "user_id", user.Id, "document_id", documentId, "file_id", fileId, "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to queue text extraction") {
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "Text extraction queued",
+	})
+}
+
+// handleReprocessDocument handles POST requests to re-run the text extraction of all files of a document
+func handleReprocessDocument(c *gin.Context, signInManager auth.SignInManager, documentFileManager documents.DocumentFileManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	documentId := c.Param("documentId")
+	if documentId == "" {
+		c.JSON(400, gin.H{"success": false, "error": "Document ID is required"})
+		return
+	}
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	queued, err := documentFileManager.ReprocessDocument(c.Request.Context(), user.Id, documentId, dataProtector)
+	if err != nil {
+		logger.Error("Failed to queue text extraction of document", "user_id", user.Id, "document_id", documentId, "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to queue text extraction") {
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Text extraction queued for %d file(s)", queued),
+		"queued":  queued,
+	})
+}
+
+// handleRetryFailedOcr handles POST requests to re-run all failed text extractions of the current user
+func handleRetryFailedOcr(c *gin.Context, signInManager auth.SignInManager, documentFileManager documents.DocumentFileManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	queued, err := documentFileManager.ReprocessFailedFiles(c.Request.Context(), user.Id, dataProtector)
+	if err != nil {
+		logger.Error("Failed to queue failed text extractions", "user_id", user.Id, "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to queue text extraction") {
+			return
+		}
+	}
+
+	logger.Info("Queued failed text extractions", "user_id", user.Id, "count", queued)
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": fmt.Sprintf("Text extraction queued for %d file(s)", queued),
+		"queued":  queued,
+	})
+}
+
 // handleGetDocumentNotes handles GET requests to retrieve document notes
 func handleGetDocumentNotes(c *gin.Context, signInManager auth.SignInManager, noteManager documents.NoteManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
@@ -0,0 +1,192 @@
+package documents
+
+import (
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// Resumable uploads let the browser send large files in parts. A part that failed is sent again from the
+// offset the server reports, so a flaky connection does not restart the whole upload.
+
+// handleCreateUploadSession handles POST requests announcing a file that is uploaded in parts
+func handleCreateUploadSession(c *gin.Context, signInManager auth.SignInManager, uploadSessionManager documents.UploadSessionManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	documentId := c.Param("documentId")
+	if documentId == "" {
+		c.JSON(400, gin.H{"success": false, "error": "Document ID is required"})
+		return
+	}
+
+	// Parse request body
+	var requestBody struct {
+		FileName    string `json:"fileName"`
+		ContentType string `json:"contentType"`
+		Size        int64  `json:"size"`
+	}
+	if err := c.ShouldBindJSON(&requestBody); err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "Invalid request body"})
+		return
+	}
+
+	// Get content type, falling back to the file extension for formats browsers do not know
+	contentType := documents.ResolveContentType(requestBody.FileName, requestBody.ContentType)
+	if !documents.IsSupportedContentType(contentType) {
+		logger.Warn("Rejected upload with unsupported content type",
+			"filename", requestBody.FileName,
+			"content_type", contentType,
+			"user_id", user.Id)
+		c.JSON(400, gin.H{"success": false, "error": "Unsupported file type. Supported formats are " + documents.SupportedFormatsDescription})
+		return
+	}
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	session, err := uploadSessionManager.CreateUploadSession(c.Request.Context(), user.Id, documentId, documents.CreateUploadSessionRequest{
+		FileName:    requestBody.FileName,
+		ContentType: contentType,
+		Size:        requestBody.Size,
+	}, dataProtector)
+	if err != nil {
+		logger.Error("Failed to create upload session", "user_id", user.Id, "document_id", documentId, "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to start upload") {
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"success": true, "upload": session})
+}
+
+// handleGetUploadSession handles GET requests for the state of an upload, which is used to resume it
+func handleGetUploadSession(c *gin.Context, signInManager auth.SignInManager, uploadSessionManager documents.UploadSessionManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	session, err := uploadSessionManager.GetUploadSession(c.Request.Context(), user.Id, c.Param("documentId"), c.Param("uploadId"), dataProtector)
+	if err != nil {
+		logger.Error("Failed to get upload session", "user_id", user.Id, "upload_id", c.Param("uploadId"), "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to get upload") {
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"success": true, "upload": session})
+}
+
+// handleUploadChunk handles PUT requests sending a part of a file as the raw request body
+func handleUploadChunk(c *gin.Context, signInManager auth.SignInManager, uploadSessionManager documents.UploadSessionManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	offset, size, err := middleware.ParseUploadChunk(c)
+	if err != nil {
+		c.JSON(400, gin.H{"success": false, "error": "The offset and length of the part are required"})
+		return
+	}
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	session, err := uploadSessionManager.UploadChunk(c.Request.Context(), user.Id, c.Param("documentId"), c.Param("uploadId"), documents.UploadChunkRequest{
+		Offset:  offset,
+		Size:    size,
+		Content: c.Request.Body,
+	}, dataProtector)
+	if err != nil {
+		logger.Warn("Failed to store upload part", "user_id", user.Id, "upload_id", c.Param("uploadId"), "offset", offset, "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to store part of the upload") {
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"success": true, "upload": session})
+}
+
+// handleCompleteUploadSession handles POST requests creating the file of a completely received upload
+func handleCompleteUploadSession(c *gin.Context, signInManager auth.SignInManager, uploadSessionManager documents.UploadSessionManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	documentId := c.Param("documentId")
+
+	// Create data protector
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
+		mekStore,
+		encryptionService,
+		c.Request,
+	)
+
+	addedFile, err := uploadSessionManager.CompleteUploadSession(c.Request.Context(), user.Id, documentId, c.Param("uploadId"), dataProtector)
+	if err != nil {
+		logger.Error("Failed to complete upload", "user_id", user.Id, "document_id", documentId, "upload_id", c.Param("uploadId"), "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to add file to document") {
+			return
+		}
+	}
+
+	logger.Info("File uploaded successfully", "user_id", user.Id, "document_id", documentId, "file_id", addedFile.Id, "filename", addedFile.FileName)
+
+	c.JSON(200, gin.H{
+		"success": true,
+		"message": "File uploaded successfully",
+		"file":    addedFile,
+	})
+}
+
+// handleAbortUploadSession handles DELETE requests cancelling an upload
+func handleAbortUploadSession(c *gin.Context, signInManager auth.SignInManager, uploadSessionManager documents.UploadSessionManager, logger ccc.Logger) {
+	// Get current user
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"success": false, "error": "Authentication required"})
+		return
+	}
+
+	err = uploadSessionManager.AbortUploadSession(c.Request.Context(), user.Id, c.Param("documentId"), c.Param("uploadId"))
+	if err != nil {
+		logger.Error("Failed to cancel upload", "user_id", user.Id, "upload_id", c.Param("uploadId"), "error", err)
+		if middleware.HandleErrorWithJson(c, err, "Failed to cancel upload") {
+			return
+		}
+	}
+
+	c.JSON(200, gin.H{"success": true})
+}
@@ -1,6 +1,8 @@
 package login
 
 import (
+	"net/http"
+
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
@@ -35,6 +37,15 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager) {
 			return
 		}
 
+		if response.RequiresTotp {
+			// Password accepted - ask for the code of the authenticator app
+			c.HTML(200, "login.html", gin.H{
+				"TotpRequired": true,
+				"Version":      ccc.AppVersion,
+			})
+			return
+		}
+
 		if !response.Success {
 			// Authentication failed - render login page with error message
 			errorMessage := response.Error
@@ -54,6 +65,70 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager) {
 		c.Redirect(302, "/")
 	})
 
+	// POST /login/totp - Handle the TOTP code of a pending sign-in
+	router.POST("/login/totp", func(c *gin.Context) {
+		code := c.PostForm("code")
+
+		response, err := signInManager.CompleteTotpSignIn(c.Writer, c.Request, code)
+
+		if middleware.HandleError(c, err) {
+			return
+		}
+
+		if !response.Success {
+			errorMessage := response.Error
+			if errorMessage == "" {
+				errorMessage = "Invalid code"
+			}
+
+			// Without a pending sign-in the user has to start over with the password
+			c.HTML(401, "login.html", gin.H{
+				"ErrorMessage": errorMessage,
+				"TotpRequired": response.RequiresTotp,
+				"Version":      ccc.AppVersion,
+			})
+			return
+		}
+
+		// Authentication successful - redirect to home page
+		c.Redirect(302, "/")
+	})
+
+	// POST /login/passkey/options - Create the options for a passkey sign-in
+	router.POST("/login/passkey/options", func(c *gin.Context) {
+		options, err := signInManager.BeginPasskeySignIn(c.Writer, c.Request)
+		if middleware.HandleErrorWithJson(c, err, "Failed to start passkey sign-in") {
+			return
+		}
+
+		c.JSON(http.StatusOK, options)
+	})
+
+	// POST /login/passkey - Verify the passkey assertion
+	router.POST("/login/passkey", func(c *gin.Context) {
+		var assertion auth.PasskeyAssertionResponse
+		if err := c.ShouldBindJSON(&assertion); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
+			return
+		}
+
+		response, err := signInManager.CompletePasskeySignIn(c.Writer, c.Request, assertion)
+		if middleware.HandleErrorWithJson(c, err, "Failed to sign in with passkey") {
+			return
+		}
+
+		if !response.Success {
+			errorMessage := response.Error
+			if errorMessage == "" {
+				errorMessage = "The passkey could not be verified"
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": errorMessage})
+			return
+		}
+
+		c.JSON(http.StatusOK, gin.H{"success": true, "redirect": "/"})
+	})
+
 	// GET /logout - Handle logout
 	router.GET("/logout", func(c *gin.Context) {
 		// Call SignInManager to handle sign out
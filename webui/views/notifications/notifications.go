@@ -0,0 +1,183 @@
+package notifications
+
+import (
+	"net/http"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/notifications"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the notification routes with the provided Gin router.
+func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, notificationManager notifications.NotificationManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Notifications page route - protected by authentication
+	router.GET("/notifications", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleNotificationsPage(c, signInManager, notificationManager, mekStore, encryptionService, logger)
+	})
+
+	// Dismiss routes, also used by the reminders on the secrets page - protected by authentication
+	router.POST("/notifications/read", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleMarkRead(c, signInManager, notificationManager, mekStore, encryptionService, logger)
+	})
+	router.POST("/notifications/read-all", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleMarkAllRead(c, signInManager, notificationManager, mekStore, encryptionService, logger)
+	})
+
+	// Mail settings routes - protected by authentication
+	router.POST("/notifications/settings", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleSaveSettings(c, signInManager, notificationManager, mekStore, encryptionService, logger)
+	})
+	router.POST("/notifications/test-email", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleSendTestEmail(c, signInManager, notificationManager, mekStore, encryptionService, logger)
+	})
+}
+
+// handleNotificationsPage handles the page listing the reminders of the user and their mail settings
+func handleNotificationsPage(c *gin.Context, signInManager auth.SignInManager, notificationManager notifications.NotificationManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+	data := notificationsTemplateData(c, user, notificationManager, dataProtector, logger)
+
+	switch c.Query("success") {
+	case "settings":
+		data["SuccessMessage"] = "Notification settings saved."
+	case "test-email":
+		data["SuccessMessage"] = "Test mail sent. Check your inbox."
+	}
+
+	c.HTML(http.StatusOK, "notifications.html", data)
+}
+
+// handleMarkRead handles POST requests to dismiss a notification
+func handleMarkRead(c *gin.Context, signInManager auth.SignInManager, notificationManager notifications.NotificationManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	notificationId := c.PostForm("id")
+	if err := notificationManager.MarkRead(c.Request.Context(), user.Id, notificationId); err != nil {
+		logger.Error("Failed to mark notification as read", "user_id", user.Id, "notification_id", notificationId, "error", err)
+		dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+		middleware.HandleErrorOnPage(c, err, "notifications.html", notificationsTemplateData(c, user, notificationManager, dataProtector, logger), "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectTarget(c))
+}
+
+// handleMarkAllRead handles POST requests to dismiss all notifications
+func handleMarkAllRead(c *gin.Context, signInManager auth.SignInManager, notificationManager notifications.NotificationManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	if err := notificationManager.MarkAllRead(c.Request.Context(), user.Id); err != nil {
+		logger.Error("Failed to mark notifications as read", "user_id", user.Id, "error", err)
+		dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+		middleware.HandleErrorOnPage(c, err, "notifications.html", notificationsTemplateData(c, user, notificationManager, dataProtector, logger), "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, redirectTarget(c))
+}
+
+// handleSaveSettings handles POST requests to save the mail settings of the user
+func handleSaveSettings(c *gin.Context, signInManager auth.SignInManager, notificationManager notifications.NotificationManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	request := notifications.NotificationSettingsDto{
+		Email:        c.PostForm("email"),
+		EmailEnabled: c.PostForm("emailEnabled") == "true",
+	}
+
+	if err := notificationManager.SaveSettings(c.Request.Context(), user.Id, request); err != nil {
+		logger.Warn("Failed to save notification settings", "user_id", user.Id, "error", err)
+		dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+		data := notificationsTemplateData(c, user, notificationManager, dataProtector, logger)
+		// Keep the input of the user in the form
+		data["Settings"] = &request
+		middleware.HandleErrorOnPage(c, err, "notifications.html", data, "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/notifications?success=settings")
+}
+
+// handleSendTestEmail handles POST requests to send a test mail to the saved address of the user
+func handleSendTestEmail(c *gin.Context, signInManager auth.SignInManager, notificationManager notifications.NotificationManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	if err := notificationManager.SendTestEmail(c.Request.Context(), user.Id); err != nil {
+		logger.Warn("Failed to send test mail", "user_id", user.Id, "error", err)
+		dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+		middleware.HandleErrorOnPage(c, err, "notifications.html", notificationsTemplateData(c, user, notificationManager, dataProtector, logger), "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/notifications?success=test-email")
+}
+
+// redirectTarget returns the page to return to after dismissing notifications. Only the secrets page
+// and the notifications page are accepted, so the form field cannot redirect to other sites.
+func redirectTarget(c *gin.Context) string {
+	if c.PostForm("redirect") == "/" {
+		return "/"
+	}
+	return "/notifications"
+}
+
+// notificationsTemplateData loads the notifications and mail settings of the user for the notifications page.
+// If they cannot be loaded, the page shows a warning instead.
+func notificationsTemplateData(c *gin.Context, user auth.UserDto, notificationManager notifications.NotificationManager, dataProtector dataprotection.DataProtector, logger ccc.Logger) gin.H {
+	data := gin.H{
+		"Title":          "Frozen Fortress - Reminders",
+		"Username":       user.UserName,
+		"User":           user,
+		"Version":        ccc.AppVersion,
+		"EmailAvailable": notificationManager.EmailAvailable(),
+	}
+
+	settings, err := notificationManager.GetSettings(c.Request.Context(), user.Id)
+	if err != nil {
+		logger.Error("Failed to load notification settings", "user_id", user.Id, "error", err)
+		settings = &notifications.NotificationSettingsDto{}
+	}
+	data["Settings"] = settings
+
+	items, err := notificationManager.GetNotifications(c.Request.Context(), user.Id, false, dataProtector)
+	if err != nil {
+		logger.Error("Failed to load notifications", "user_id", user.Id, "error", err)
+		data["WarningMessage"] = "Your reminders could not be loaded. Please try again later."
+		return data
+	}
+	data["Notifications"] = items
+
+	hasUnread := false
+	for _, item := range items {
+		hasUnread = hasUnread || !item.Read
+	}
+	data["HasUnread"] = hasUnread
+
+	return data
+}
@@ -1,23 +1,29 @@
 package secrets
 
 import (
+	"fmt"
+	"net/http"
+	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/notifications"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/secretimport"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/secrets"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes registers the secrets routes with the provided Gin router.
-func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, secretManager secrets.SecretManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, secretManager secrets.SecretManager, secretImporter secretimport.SecretImporter, notificationManager notifications.NotificationManager, reminderLeadDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Home page route - protected by authentication - serves secrets management
 	router.GET("/", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleSecretsPage(c, signInManager, secretManager, mekStore, encryptionService, logger)
+		handleSecretsPage(c, signInManager, secretManager, notificationManager, reminderLeadDays, mekStore, encryptionService, logger)
 	})
 
 	// Edit secret routes - protected by authentication
@@ -29,6 +35,26 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, secret
 		handleEditSecretSubmit(c, signInManager, secretManager, mekStore, encryptionService, logger)
 	})
 
+	// Restore a prior version of a secret from the history on the edit page - protected by authentication
+	router.POST("/restore-secret-version", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleRestoreSecretVersion(c, signInManager, secretManager, mekStore, encryptionService, logger)
+	})
+
+	// Import secrets routes - protected by authentication
+	router.GET("/import-secrets", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleImportSecretsPage(c, signInManager)
+	})
+
+	router.POST("/import-secrets", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleImportSecretsSubmit(c, signInManager, secretImporter, mekStore, encryptionService, logger)
+	})
+
+	// One-time password route used by the secrets list to refresh expired codes - protected by authentication.
+	// Clients with an API token use GET /api/v1/secrets/:secretId/totp instead, which returns the same fields.
+	router.GET("/api/secrets/:id/totp", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleSecretTotp(c, signInManager, secretManager, mekStore, encryptionService, logger)
+	})
+
 	// Delete secret route - protected by authentication
 	router.DELETE("/delete-secret/:id", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
 		handleDeleteSecret(c, signInManager, secretManager, logger)
@@ -36,7 +62,7 @@ func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, secret
 }
 
 // handleSecretsPage handles the secrets management page with pagination, filtering, and sorting
-func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretManager secrets.SecretManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretManager secrets.SecretManager, notificationManager notifications.NotificationManager, reminderLeadDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user for display
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
@@ -49,6 +75,7 @@ func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretM
 	pageStr := c.DefaultQuery("page", "1")
 	sortBy := c.DefaultQuery("sortBy", "Name")
 	sortAsc := c.DefaultQuery("sortAsc", "true") == "true"
+	expiringWithin, _ := strconv.Atoi(c.Query("expiringWithin"))
 
 	// Check for success messages
 	var successMessage string
@@ -57,7 +84,7 @@ func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretM
 	} else if c.Query("updated") == "1" {
 		successMessage = "Secret updated successfully!"
 	} else if c.Query("deleted") == "1" {
-		successMessage = "Secret deleted successfully!"
+		successMessage = "Secret moved to the trash."
 	}
 
 	// Parse page number
@@ -80,6 +107,8 @@ func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretM
 		Page:     page,
 		SortBy:   sortBy,
 		SortAsc:  sortAsc,
+
+		ExpiringWithinDays: max(expiringWithin, 0),
 	}
 
 	// Get secrets from secret manager
@@ -97,7 +126,20 @@ func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretM
 		totalPages = (paginatedResponse.TotalCount + paginatedResponse.PageSize - 1) / paginatedResponse.PageSize
 	}
 
+	// Unread reminders are shown above the list until they are dismissed
+	unreadNotifications, err := notificationManager.GetNotifications(c.Request.Context(), user.Id, true, dataProtector)
+	if err != nil {
+		logger.Warn("Failed to get notifications for user", "user_id", user.Id, "error", err)
+	}
+
+	// Secrets renamed or left unindexed when the user signed in are reported once
+	signInNotices, err := signInManager.TakeSignInNotices(c.Writer, c.Request)
+	if err != nil {
+		logger.Warn("Failed to get sign-in notices for user", "user_id", user.Id, "error", err)
+	}
+
 	// Prepare template data
+	now := time.Now()
 	templateData := gin.H{
 		"Title":          "Frozen Fortress - Secrets",
 		"Username":       user.UserName,
@@ -113,12 +155,119 @@ func handleSecretsPage(c *gin.Context, signInManager auth.SignInManager, secretM
 		"HasPrevious":    page > 1,
 		"HasNext":        page < totalPages,
 		"SuccessMessage": successMessage,
+		"WarningMessage": strings.Join(signInNotices, " "),
+		"ExpiringWithin": expiringWithin,
+		"Notifications":  unreadNotifications,
+		// Secrets due before the reminder horizon are marked on their cards
+		"Now":             now,
+		"ReminderHorizon": now.AddDate(0, 0, reminderLeadDays),
 	}
 
 	// Render the secrets template
 	c.HTML(200, "secrets.html", templateData)
 }
 
+// secretTypeOption describes a secret type for the type picker of the edit page
+type secretTypeOption struct {
+	Value  string                `json:"value"`
+	Name   string                `json:"name"`
+	Fields []secrets.SecretField `json:"fields"`
+}
+
+// secretEditorState is the initial state of the field editor of the edit page
+type secretEditorState struct {
+	Type   string                `json:"type"`
+	Fields []secrets.SecretField `json:"fields"`
+	Types  []secretTypeOption    `json:"types"`
+}
+
+// editSecretTemplateData returns the data of the edit page for a secret with the given type and fields
+func editSecretTemplateData(username, secretId, secretName string, secretType secrets.SecretType, fields []secrets.SecretField) gin.H {
+	if secretType == "" {
+		secretType = secrets.SecretTypeGeneric
+	}
+	if len(fields) == 0 {
+		fields = secretType.DefaultFields()
+	}
+
+	editor := secretEditorState{Type: string(secretType), Fields: fields}
+	for _, option := range secrets.SecretTypes {
+		editor.Types = append(editor.Types, secretTypeOption{
+			Value:  string(option),
+			Name:   option.DisplayName(),
+			Fields: option.DefaultFields(),
+		})
+	}
+
+	return gin.H{
+		"Title":      "Frozen Fortress - Edit Secret",
+		"Username":   username,
+		"Version":    ccc.AppVersion,
+		"SecretId":   secretId,
+		"SecretName": secretName,
+		"Editor":     editor,
+	}
+}
+
+// secretFieldsFromForm reads the fields of the edit form, which are posted as parallel lists in their order
+func secretFieldsFromForm(c *gin.Context) []secrets.SecretField {
+	names := c.PostFormArray("fieldName")
+	values := c.PostFormArray("fieldValue")
+	concealed := c.PostFormArray("fieldConcealed")
+	kinds := c.PostFormArray("fieldKind")
+
+	fields := make([]secrets.SecretField, 0, len(names))
+	for i, name := range names {
+		field := secrets.SecretField{Name: strings.TrimSpace(name)}
+		if i < len(values) {
+			field.Value = strings.TrimSpace(values[i])
+		}
+		if i < len(concealed) {
+			field.Concealed = concealed[i] == "true"
+		}
+		if i < len(kinds) {
+			field.Kind = secrets.FieldKind(kinds[i])
+		}
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// secretScheduleFromForm reads the expiry date and rotation interval of the edit form. Empty inputs mean
+// that the secret does not expire or is not rotated regularly.
+func secretScheduleFromForm(c *gin.Context) (*time.Time, int, error) {
+	var expiresAt *time.Time
+	if value := strings.TrimSpace(c.PostForm("expiresAt")); value != "" {
+		parsed, err := time.Parse("2006-01-02", value)
+		if err != nil {
+			return nil, 0, ccc.NewInvalidInputErrorWithMessage("expiry date", "invalid date", "Please enter a valid expiry date")
+		}
+		expiresAt = &parsed
+	}
+
+	rotateEvery := 0
+	if value := strings.TrimSpace(c.PostForm("rotateEvery")); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, 0, ccc.NewInvalidInputErrorWithMessage("rotation interval", "not a number", "Please enter the rotation interval in days")
+		}
+		rotateEvery = parsed
+	}
+
+	return expiresAt, rotateEvery, nil
+}
+
+// setSecretScheduleData adds the expiry date and rotation interval of a secret to the data of the edit page
+func setSecretScheduleData(templateData gin.H, secretDto *secrets.SecretDto) {
+	if secretDto.ExpiresAt != nil {
+		templateData["ExpiresAt"] = secretDto.ExpiresAt.Format("2006-01-02")
+	}
+	if secretDto.RotateEvery > 0 {
+		templateData["RotateEvery"] = strconv.Itoa(secretDto.RotateEvery)
+	}
+	templateData["RotationDueAt"] = secretDto.RotationDueAt
+}
+
 // handleEditSecretPage handles GET requests to the edit-secret page
 func handleEditSecretPage(c *gin.Context, signInManager auth.SignInManager, secretManager secrets.SecretManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user for display
@@ -131,16 +280,10 @@ func handleEditSecretPage(c *gin.Context, signInManager auth.SignInManager, secr
 	// Check if we're editing an existing secret (secretId query parameter)
 	secretId := c.Query("id")
 
-	templateData := gin.H{
-		"Title":    "Frozen Fortress - Edit Secret",
-		"Username": user.UserName,
-		"Version":  ccc.AppVersion,
-	}
+	templateData := editSecretTemplateData(user.UserName, secretId, "", "", nil)
 
 	if secretId != "" {
 		// Editing existing secret - fetch its details
-		templateData["SecretId"] = secretId
-
 		// Create MekDataProtector for this request
 		dataProtector := dataprotection.CreateMekDataProtectorForRequest(
 			mekStore,
@@ -154,13 +297,24 @@ func handleEditSecretPage(c *gin.Context, signInManager auth.SignInManager, secr
 			logger.Error("Failed to get secret for editing", "user_id", user.Id, "secret_id", secretId, "error", err)
 			templateData["ErrorMessage"] = "Failed to load secret details. Please try again."
 		} else {
-			templateData["SecretName"] = secretDto.Name
-			templateData["SecretValue"] = secretDto.Value
+			templateData = editSecretTemplateData(user.UserName, secretId, secretDto.Name, secretDto.Type, secretDto.Fields)
 			templateData["CreatedAt"] = secretDto.CreatedAt
 			templateData["ModifiedAt"] = secretDto.ModifiedAt
+			setSecretScheduleData(templateData, secretDto)
+
+			history, err := secretManager.GetSecretHistory(user.Id, secretId, dataProtector)
+			if err != nil {
+				logger.Error("Failed to get secret history", "user_id", user.Id, "secret_id", secretId, "error", err)
+				templateData["WarningMessage"] = "The history of this secret could not be loaded."
+			}
+			templateData["History"] = history
 		}
 	}
 
+	if c.Query("restored") == "1" {
+		templateData["SuccessMessage"] = "Version restored. The replaced state was added to the history."
+	}
+
 	// Render the edit secret template
 	c.HTML(200, "edit-secret.html", templateData)
 }
@@ -177,35 +331,28 @@ func handleEditSecretSubmit(c *gin.Context, signInManager auth.SignInManager, se
 	// Parse form data
 	secretId := c.PostForm("secretId")
 	secretName := strings.TrimSpace(c.PostForm("secretName"))
-	secretValue := strings.TrimSpace(c.PostForm("secretValue"))
+	secretType := secrets.SecretType(c.PostForm("secretType"))
+	fields := secretFieldsFromForm(c)
+
+	templateData := editSecretTemplateData(user.UserName, secretId, secretName, secretType, fields)
+	templateData["ExpiresAt"] = c.PostForm("expiresAt")
+	templateData["RotateEvery"] = c.PostForm("rotateEvery")
 
 	// Validate input
 	if secretName == "" {
-		templateData := gin.H{
-			"Title":       "Frozen Fortress - Edit Secret",
-			"Username":    user.UserName,
-			"Version":     ccc.AppVersion,
-			"SecretId":    secretId,
-			"SecretName":  secretName,
-			"SecretValue": secretValue,
-		}
 		validationErr := ccc.NewInvalidInputErrorWithMessage("secret name", "cannot be empty", "Secret name is required")
 		middleware.HandleErrorOnPage(c, validationErr, "edit-secret.html", templateData, "ErrorMessage")
 		return
 	}
-	if secretValue == "" {
-		templateData := gin.H{
-			"Title":       "Frozen Fortress - Edit Secret",
-			"Username":    user.UserName,
-			"Version":     ccc.AppVersion,
-			"SecretId":    secretId,
-			"SecretName":  secretName,
-			"SecretValue": secretValue,
-		}
-		validationErr := ccc.NewInvalidInputErrorWithMessage("secret value", "cannot be empty", "Secret value is required")
+	if secrets.PrimaryValue(fields) == "" {
+		validationErr := ccc.NewInvalidInputErrorWithMessage("secret value", "cannot be empty", "At least one field needs a value")
 		middleware.HandleErrorOnPage(c, validationErr, "edit-secret.html", templateData, "ErrorMessage")
 		return
 	}
+	expiresAt, rotateEvery, err := secretScheduleFromForm(c)
+	if middleware.HandleErrorOnPage(c, err, "edit-secret.html", templateData, "ErrorMessage") {
+		return
+	}
 
 	// Create MekDataProtector for this request
 	dataProtector := dataprotection.CreateMekDataProtectorForRequest(
@@ -214,10 +361,14 @@ func handleEditSecretSubmit(c *gin.Context, signInManager auth.SignInManager, se
 		c.Request,
 	)
 
-	// Prepare the upsert request
+	// Prepare the upsert request, which replaces all fields of an existing secret
 	request := secrets.UpsertSecretRequest{
-		SecretName:  secretName,
-		SecretValue: secretValue,
+		SecretName: secretName,
+		SecretType: secretType,
+		Fields:     fields,
+
+		ExpiresAt:   expiresAt,
+		RotateEvery: rotateEvery,
 	}
 
 	if secretId != "" {
@@ -225,27 +376,10 @@ func handleEditSecretSubmit(c *gin.Context, signInManager auth.SignInManager, se
 		success, err := secretManager.UpdateSecret(user.Id, secretId, request, dataProtector)
 		if err != nil {
 			logger.Error("Failed to update secret", "user_id", user.Id, "secret_id", secretId, "error", err)
-
-			templateData := gin.H{
-				"Title":       "Frozen Fortress - Edit Secret",
-				"Username":    user.UserName,
-				"Version":     ccc.AppVersion,
-				"SecretId":    secretId,
-				"SecretName":  secretName,
-				"SecretValue": secretValue,
-			}
 			middleware.HandleErrorOnPage(c, err, "edit-secret.html", templateData, "ErrorMessage")
 			return
 		}
 		if !success {
-			templateData := gin.H{
-				"Title":       "Frozen Fortress - Edit Secret",
-				"Username":    user.UserName,
-				"Version":     ccc.AppVersion,
-				"SecretId":    secretId,
-				"SecretName":  secretName,
-				"SecretValue": secretValue,
-			}
 			// Create a generic error for the failure case
 			genericErr := ccc.NewInternalError("update operation did not succeed", nil)
 			middleware.HandleErrorOnPage(c, genericErr, "edit-secret.html", templateData, "ErrorMessage")
@@ -261,15 +395,6 @@ func handleEditSecretSubmit(c *gin.Context, signInManager auth.SignInManager, se
 		createResponse, err := secretManager.CreateSecret(user.Id, request, dataProtector)
 		if err != nil {
 			logger.Error("Failed to create secret", "user_id", user.Id, "secret_name", secretName, "error", err)
-
-			templateData := gin.H{
-				"Title":       "Frozen Fortress - Edit Secret",
-				"Username":    user.UserName,
-				"Version":     ccc.AppVersion,
-				"SecretId":    "",
-				"SecretName":  secretName,
-				"SecretValue": secretValue,
-			}
 			middleware.HandleErrorOnPage(c, err, "edit-secret.html", templateData, "ErrorMessage")
 			return
 		}
@@ -281,6 +406,39 @@ func handleEditSecretSubmit(c *gin.Context, signInManager auth.SignInManager, se
 	}
 }
 
+// handleRestoreSecretVersion handles POST requests to replace a secret with one of its prior versions
+func handleRestoreSecretVersion(c *gin.Context, signInManager auth.SignInManager, secretManager secrets.SecretManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(302, "/login")
+		return
+	}
+
+	secretId := c.PostForm("secretId")
+	versionId := c.PostForm("versionId")
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+
+	if _, err := secretManager.RestoreSecretVersion(user.Id, secretId, versionId, dataProtector); err != nil {
+		logger.Error("Failed to restore secret version", "user_id", user.Id, "secret_id", secretId, "version_id", versionId, "error", err)
+
+		// Show the edit page again with the error
+		templateData := editSecretTemplateData(user.UserName, secretId, "", "", nil)
+		if secretDto, getErr := secretManager.GetSecret(user.Id, secretId, dataProtector); getErr == nil {
+			templateData = editSecretTemplateData(user.UserName, secretId, secretDto.Name, secretDto.Type, secretDto.Fields)
+			templateData["CreatedAt"] = secretDto.CreatedAt
+			templateData["ModifiedAt"] = secretDto.ModifiedAt
+			setSecretScheduleData(templateData, secretDto)
+			templateData["History"], _ = secretManager.GetSecretHistory(user.Id, secretId, dataProtector)
+		}
+		middleware.HandleErrorOnPage(c, err, "edit-secret.html", templateData, "ErrorMessage")
+		return
+	}
+
+	logger.Info("Secret version restored", "user_id", user.Id, "secret_id", secretId, "version_id", versionId)
+	c.Redirect(302, "/edit-secret?id="+url.QueryEscape(secretId)+"&restored=1")
+}
+
 // handleDeleteSecret handles DELETE requests to delete a secret
 func handleDeleteSecret(c *gin.Context, signInManager auth.SignInManager, secretManager secrets.SecretManager, logger ccc.Logger) {
 	// Get current user
@@ -311,6 +469,114 @@ func handleDeleteSecret(c *gin.Context, signInManager auth.SignInManager, secret
 		return
 	}
 
-	logger.Info("Secret deleted successfully", "user_id", user.Id, "secret_id", secretId)
-	c.JSON(200, gin.H{"success": true, "message": "Secret deleted successfully"})
+	logger.Info("Secret moved to the trash", "user_id", user.Id, "secret_id", secretId)
+	c.JSON(200, gin.H{"success": true, "message": "Secret moved to the trash"})
+}
+
+// handleSecretTotp handles GET requests for the current one-time password of a secret
+func handleSecretTotp(c *gin.Context, signInManager auth.SignInManager, secretManager secrets.SecretManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.JSON(401, gin.H{"error": "Unauthorized"})
+		return
+	}
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+	code, err := secretManager.GetTotpCode(user.Id, c.Param("id"), dataProtector)
+	if err != nil {
+		logger.Warn("Failed to generate one-time password", "user_id", user.Id, "secret_id", c.Param("id"), "error", err)
+		if apiErr, ok := ccc.IsApiError(err); ok {
+			c.JSON(apiErr.StatusCode, gin.H{"error": apiErr.UserMessage})
+			return
+		}
+		c.JSON(500, gin.H{"error": "Failed to generate one-time password"})
+		return
+	}
+
+	c.JSON(200, gin.H{"fieldName": code.FieldName, "code": code.Code, "period": code.Period, "secondsRemaining": code.SecondsRemaining})
+}
+
+// maxImportUploadSize bounds the size of uploaded export files, which are parsed in memory
+const maxImportUploadSize = 16 * 1024 * 1024
+
+// importSecretsTemplateData returns the data of the import page with the given form selection
+func importSecretsTemplateData(username, format, onConflict string) gin.H {
+	return gin.H{
+		"Title":      "Frozen Fortress - Import Secrets",
+		"Username":   username,
+		"Version":    ccc.AppVersion,
+		"Formats":    secretimport.SupportedFormats,
+		"Format":     format,
+		"OnConflict": onConflict,
+	}
+}
+
+// handleImportSecretsPage handles GET requests to the import-secrets page
+func handleImportSecretsPage(c *gin.Context, signInManager auth.SignInManager) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(302, "/login")
+		return
+	}
+
+	c.HTML(200, "import-secrets.html", importSecretsTemplateData(user.UserName, string(secretimport.FormatBitwardenJson), string(secrets.ConflictRename)))
+}
+
+// handleImportSecretsSubmit handles POST requests that upload an export file of another password manager
+func handleImportSecretsSubmit(c *gin.Context, signInManager auth.SignInManager, secretImporter secretimport.SecretImporter, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(302, "/login")
+		return
+	}
+
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxImportUploadSize+1024*1024)
+
+	templateData := importSecretsTemplateData(user.UserName, c.PostForm("format"), c.PostForm("onConflict"))
+
+	format, err := secretimport.ParseFormat(c.PostForm("format"))
+	if middleware.HandleErrorOnPage(c, err, "import-secrets.html", templateData, "ErrorMessage") {
+		return
+	}
+	policy, err := secrets.ParseConflictPolicy(c.PostForm("onConflict"))
+	if middleware.HandleErrorOnPage(c, err, "import-secrets.html", templateData, "ErrorMessage") {
+		return
+	}
+
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		templateData["ErrorMessage"] = "Please choose an export file of at most 16 MB to import"
+		c.HTML(http.StatusBadRequest, "import-secrets.html", templateData)
+		return
+	}
+	file, err := fileHeader.Open()
+	if err != nil {
+		middleware.HandleError(c, err)
+		return
+	}
+	defer file.Close()
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+	report, err := secretImporter.ImportSecrets(user.Id, secretimport.ImportRequest{
+		Format:     format,
+		Source:     file,
+		OnConflict: policy,
+	}, dataProtector)
+
+	if report != nil {
+		templateData["Report"] = report
+		if err != nil && report.Created+report.Overwritten > 0 {
+			templateData["WarningMessage"] = fmt.Sprintf("The import stopped after %d secrets were created and %d overwritten. They have been kept.",
+				report.Created, report.Overwritten)
+		}
+	}
+	if err != nil {
+		logger.Error("Failed to import secrets", "user_id", user.Id, "format", format, "error", err)
+	}
+	if middleware.HandleErrorOnPage(c, err, "import-secrets.html", templateData, "ErrorMessage") {
+		return
+	}
+
+	templateData["SuccessMessage"] = fmt.Sprintf("Imported %d of %d entries from %s.", report.Created+report.Overwritten, report.Entries, format.DisplayName())
+	c.HTML(http.StatusOK, "import-secrets.html", templateData)
 }
@@ -6,48 +6,51 @@ import (
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
 	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
 	"github.com/gin-gonic/gin"
 )
 
 // RegisterRoutes registers the tags routes with the provided Gin router.
-func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Tags page route - protected by authentication
 	router.GET("/tags", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleTagsPage(c, signInManager, tagManager, logger)
+		handleTagsPage(c, signInManager, tagManager, mekStore, encryptionService, logger)
 	})
 
 	// Edit tag page routes - protected by authentication
 	router.GET("/edit-tag", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleEditTagPage(c, signInManager, tagManager, logger)
+		handleEditTagPage(c, signInManager, tagManager, mekStore, encryptionService, logger)
 	})
 	router.POST("/edit-tag", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleEditTagSubmit(c, signInManager, tagManager, logger)
+		handleEditTagSubmit(c, signInManager, tagManager, mekStore, encryptionService, logger)
 	})
 
 	// Delete tag route - protected by authentication
 	router.DELETE("/tags/:id", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleDeleteTag(c, signInManager, tagManager, logger)
+		handleDeleteTag(c, signInManager, tagManager, mekStore, encryptionService, logger)
 	})
 
 	// API routes for tag management
 	router.GET("/api/tags", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleGetTagsAPI(c, signInManager, tagManager, logger)
+		handleGetTagsAPI(c, signInManager, tagManager, mekStore, encryptionService, logger)
 	})
 	router.POST("/api/tags", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
-		handleCreateTagAPI(c, signInManager, tagManager, logger)
+		handleCreateTagAPI(c, signInManager, tagManager, mekStore, encryptionService, logger)
 	})
 }
 
 // handleTagsPage handles the tags management page
-func handleTagsPage(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func handleTagsPage(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
 		c.Redirect(http.StatusFound, "/login")
 		return
 	}
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
 
 	// Get success message from query parameters
 	var successMessage string
@@ -60,11 +63,11 @@ func handleTagsPage(c *gin.Context, signInManager auth.SignInManager, tagManager
 
 	// Handle deleted parameter for consistency with secrets page
 	if c.Query("deleted") == "1" {
-		successMessage = "Tag deleted successfully!"
+		successMessage = "Tag moved to the trash."
 	}
 
 	// Get all tags for the user
-	tags, err := tagManager.GetUserTags(context.Background(), user.Id)
+	tags, err := tagManager.GetUserTags(context.Background(), user.Id, dataProtector)
 	if middleware.HandleError(c, err) {
 		return
 	}
@@ -81,7 +84,7 @@ func handleTagsPage(c *gin.Context, signInManager auth.SignInManager, tagManager
 }
 
 // handleDeleteTag handles deleting a tag
-func handleDeleteTag(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func handleDeleteTag(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
@@ -102,17 +105,18 @@ func handleDeleteTag(c *gin.Context, signInManager auth.SignInManager, tagManage
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Tag deleted successfully"})
+	c.JSON(http.StatusOK, gin.H{"success": true, "message": "Tag moved to the trash"})
 }
 
 // handleEditTagPage handles the edit tag page (both create and edit)
-func handleEditTagPage(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func handleEditTagPage(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
 		c.Redirect(http.StatusFound, "/login")
 		return
 	}
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
 
 	tagId := c.Query("id")
 
@@ -125,7 +129,7 @@ func handleEditTagPage(c *gin.Context, signInManager auth.SignInManager, tagMana
 
 	// If we have an ID, we're editing an existing tag
 	if tagId != "" {
-		tag, err := tagManager.GetTag(context.Background(), user.Id, tagId)
+		tag, err := tagManager.GetTag(context.Background(), user.Id, tagId, dataProtector)
 		if middleware.HandleErrorOnPage(c, err, "edit-tag.html", gin.H{"Version": ccc.AppVersion}, "ErrorMessage") {
 			return
 		}
@@ -151,13 +155,14 @@ func handleEditTagPage(c *gin.Context, signInManager auth.SignInManager, tagMana
 }
 
 // handleEditTagSubmit handles the form submission for creating/editing tags
-func handleEditTagSubmit(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func handleEditTagSubmit(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
 		c.Redirect(http.StatusFound, "/login")
 		return
 	}
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
 
 	tagId := c.PostForm("tagId")
 	tagName := c.PostForm("tagName")
@@ -170,7 +175,7 @@ func handleEditTagSubmit(c *gin.Context, signInManager auth.SignInManager, tagMa
 			Color: tagColor,
 		}
 
-		err := tagManager.UpdateTag(context.Background(), user.Id, tagId, updateRequest)
+		err := tagManager.UpdateTag(context.Background(), user.Id, tagId, updateRequest, dataProtector)
 		if middleware.HandleErrorOnPage(c, err, "edit-tag.html", gin.H{
 			"TagId":    tagId,
 			"TagName":  tagName,
@@ -189,7 +194,7 @@ func handleEditTagSubmit(c *gin.Context, signInManager auth.SignInManager, tagMa
 			Color: tagColor,
 		}
 
-		_, err := tagManager.CreateTag(context.Background(), user.Id, createRequest)
+		_, err := tagManager.CreateTag(context.Background(), user.Id, createRequest, dataProtector)
 		if middleware.HandleErrorOnPage(c, err, "edit-tag.html", gin.H{
 			"TagName":  tagName,
 			"TagColor": tagColor,
@@ -206,16 +211,17 @@ func handleEditTagSubmit(c *gin.Context, signInManager auth.SignInManager, tagMa
 // API Handlers
 
 // handleGetTagsAPI returns all tags for the current user as JSON
-func handleGetTagsAPI(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func handleGetTagsAPI(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
 
 	// Get all tags for the user
-	tags, err := tagManager.GetUserTags(context.Background(), user.Id)
+	tags, err := tagManager.GetUserTags(context.Background(), user.Id, dataProtector)
 	if middleware.HandleErrorWithJson(c, err, "Failed to retrieve tags") {
 		return
 	}
@@ -234,13 +240,14 @@ func handleGetTagsAPI(c *gin.Context, signInManager auth.SignInManager, tagManag
 }
 
 // handleCreateTagAPI creates a new tag via API
-func handleCreateTagAPI(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, logger ccc.Logger) {
+func handleCreateTagAPI(c *gin.Context, signInManager auth.SignInManager, tagManager documents.TagManager, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
 	// Get current user
 	user, err := signInManager.GetCurrentUser(c.Request)
 	if err != nil {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
 		return
 	}
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
 
 	// Parse JSON request
 	var request struct {
@@ -264,7 +271,7 @@ func handleCreateTagAPI(c *gin.Context, signInManager auth.SignInManager, tagMan
 		Color: request.Color,
 	}
 
-	tag, err := tagManager.CreateTag(context.Background(), user.Id, createRequest)
+	tag, err := tagManager.CreateTag(context.Background(), user.Id, createRequest, dataProtector)
 	if middleware.HandleErrorWithJson(c, err, "Failed to create tag") {
 		return
 	}
@@ -0,0 +1,148 @@
+package trash
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/auth"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/dataprotection"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/encryption"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/trash"
+	"github.com/Yeti47/frozenfortress/frozenfortress/webui/middleware"
+	"github.com/gin-gonic/gin"
+)
+
+// RegisterRoutes registers the trash routes with the provided Gin router.
+func RegisterRoutes(router *gin.Engine, signInManager auth.SignInManager, trashManager trash.TrashManager, retentionDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	// Trash page route - protected by authentication
+	router.GET("/trash", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleTrashPage(c, signInManager, trashManager, retentionDays, mekStore, encryptionService, logger)
+	})
+
+	// Restore and purge routes - protected by authentication
+	router.POST("/trash/restore", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleRestoreItem(c, signInManager, trashManager, retentionDays, mekStore, encryptionService, logger)
+	})
+	router.POST("/trash/purge", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handlePurgeItem(c, signInManager, trashManager, retentionDays, mekStore, encryptionService, logger)
+	})
+	router.POST("/trash/empty", middleware.AuthMiddleware(signInManager), func(c *gin.Context) {
+		handleEmptyTrash(c, signInManager, trashManager, retentionDays, mekStore, encryptionService, logger)
+	})
+}
+
+// handleTrashPage handles the trash page listing deleted secrets, documents and tags
+func handleTrashPage(c *gin.Context, signInManager auth.SignInManager, trashManager trash.TrashManager, retentionDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+	data := trashTemplateData(c, user, trashManager, retentionDays, dataProtector, logger)
+
+	switch c.Query("success") {
+	case "restored":
+		data["SuccessMessage"] = "Item restored successfully!"
+	case "purged":
+		data["SuccessMessage"] = "Item deleted permanently."
+	case "emptied":
+		count, _ := strconv.Atoi(c.Query("count"))
+		data["SuccessMessage"] = fmt.Sprintf("Trash emptied, %d items deleted permanently.", count)
+	}
+
+	c.HTML(http.StatusOK, "trash.html", data)
+}
+
+// handleRestoreItem handles POST requests to restore an item from the trash
+func handleRestoreItem(c *gin.Context, signInManager auth.SignInManager, trashManager trash.TrashManager, retentionDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+	itemId := c.PostForm("id")
+
+	itemType, err := trash.ParseItemType(c.PostForm("type"))
+	if err == nil {
+		err = trashManager.RestoreItem(c.Request.Context(), user.Id, itemType, itemId, dataProtector)
+	}
+	if err != nil {
+		logger.Error("Failed to restore item from the trash", "user_id", user.Id, "item_type", itemType, "item_id", itemId, "error", err)
+		middleware.HandleErrorOnPage(c, err, "trash.html", trashTemplateData(c, user, trashManager, retentionDays, dataProtector, logger), "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/trash?success=restored")
+}
+
+// handlePurgeItem handles POST requests to permanently delete an item in the trash
+func handlePurgeItem(c *gin.Context, signInManager auth.SignInManager, trashManager trash.TrashManager, retentionDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	itemId := c.PostForm("id")
+
+	itemType, err := trash.ParseItemType(c.PostForm("type"))
+	if err == nil {
+		err = trashManager.PurgeItem(c.Request.Context(), user.Id, itemType, itemId)
+	}
+	if err != nil {
+		logger.Error("Failed to purge item from the trash", "user_id", user.Id, "item_type", itemType, "item_id", itemId, "error", err)
+		dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+		middleware.HandleErrorOnPage(c, err, "trash.html", trashTemplateData(c, user, trashManager, retentionDays, dataProtector, logger), "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, "/trash?success=purged")
+}
+
+// handleEmptyTrash handles POST requests to permanently delete all items in the trash
+func handleEmptyTrash(c *gin.Context, signInManager auth.SignInManager, trashManager trash.TrashManager, retentionDays int, mekStore auth.MekStore, encryptionService encryption.EncryptionService, logger ccc.Logger) {
+	user, err := signInManager.GetCurrentUser(c.Request)
+	if err != nil {
+		c.Redirect(http.StatusFound, "/login")
+		return
+	}
+
+	dataProtector := dataprotection.CreateMekDataProtectorForRequest(mekStore, encryptionService, c.Request)
+
+	summary, err := trashManager.EmptyTrash(c.Request.Context(), user.Id, dataProtector)
+	if err != nil {
+		logger.Error("Failed to empty trash", "user_id", user.Id, "error", err)
+		middleware.HandleErrorOnPage(c, err, "trash.html", trashTemplateData(c, user, trashManager, retentionDays, dataProtector, logger), "ErrorMessage")
+		return
+	}
+
+	c.Redirect(http.StatusFound, fmt.Sprintf("/trash?success=emptied&count=%d", summary.Total()))
+}
+
+// trashTemplateData loads the items in the trash of the user for the trash page.
+// If they cannot be loaded, the page shows a warning instead of the list.
+func trashTemplateData(c *gin.Context, user auth.UserDto, trashManager trash.TrashManager, retentionDays int, dataProtector dataprotection.DataProtector, logger ccc.Logger) gin.H {
+	data := gin.H{
+		"Title":         "Frozen Fortress - Trash",
+		"Username":      user.UserName,
+		"User":          user,
+		"Version":       ccc.AppVersion,
+		"RetentionDays": retentionDays,
+	}
+
+	items, err := trashManager.GetTrash(c.Request.Context(), user.Id, dataProtector)
+	if err != nil {
+		logger.Error("Failed to load trash", "user_id", user.Id, "error", err)
+		data["WarningMessage"] = "The trash could not be loaded. Please try again later."
+		return data
+	}
+	data["Items"] = items
+
+	return data
+}
@@ -2,23 +2,32 @@ package workers
 
 import (
 	"context"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/backup"
 	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
 )
 
+// maxScheduleCheckInterval is the longest the worker sleeps before it looks at the schedule again,
+// so that manual backups and clock changes are taken into account
+const maxScheduleCheckInterval = time.Hour
+
 // DefaultBackupWorker handles automatic backup creation in the background
 type DefaultBackupWorker struct {
 	backupService backup.BackupService
+	runRepo       backup.BackupRunRepository
+	idGenerator   backup.BackupRunIdGenerator
 	config        ccc.AppConfig
 	logger        ccc.Logger
+	schedule      backup.BackupSchedule
 	ctx           context.Context
 	cancel        context.CancelFunc
 }
 
 // NewDefaultBackupWorker creates a new backup worker instance
-func NewDefaultBackupWorker(backupService backup.BackupService, config ccc.AppConfig, logger ccc.Logger) *DefaultBackupWorker {
+func NewDefaultBackupWorker(backupService backup.BackupService, runRepo backup.BackupRunRepository, idGenerator backup.BackupRunIdGenerator, config ccc.AppConfig, logger ccc.Logger) *DefaultBackupWorker {
 	if logger == nil {
 		logger = ccc.NopLogger
 	}
@@ -27,6 +36,8 @@ func NewDefaultBackupWorker(backupService backup.BackupService, config ccc.AppCo
 
 	return &DefaultBackupWorker{
 		backupService: backupService,
+		runRepo:       runRepo,
+		idGenerator:   idGenerator,
 		config:        config,
 		logger:        logger,
 		ctx:           ctx,
@@ -44,14 +55,23 @@ func (w *DefaultBackupWorker) Start() {
 		return
 	}
 
-	if w.config.Backup.IntervalDays <= 0 {
-		w.logger.Info("Backup worker disabled: invalid interval", "interval_days", w.config.Backup.IntervalDays)
+	schedule, err := backup.NewBackupSchedule(w.config.Backup)
+	if err != nil {
+		w.logger.Error("Backup worker disabled: invalid schedule", "error", err)
 		return
 	}
+	w.schedule = schedule
+
+	// Runs that were in progress when the server stopped will never finish
+	if interrupted, err := w.runRepo.FailUnfinished(time.Now(), "interrupted by a server shutdown"); err != nil {
+		w.logger.Error("Failed to update interrupted backup runs", "error", err)
+	} else if interrupted > 0 {
+		w.logger.Warn("Marked interrupted backup runs as failed", "count", interrupted)
+	}
 
 	w.logger.Info("Backup worker started",
 		"enabled", w.config.Backup.Enabled,
-		"interval_days", w.config.Backup.IntervalDays,
+		"schedule", schedule.String(),
 		"max_generations", w.config.Backup.MaxGenerations)
 
 	// Start the worker in a goroutine
@@ -66,71 +86,179 @@ func (w *DefaultBackupWorker) Stop() {
 
 // run is the main worker loop that runs in the background
 func (w *DefaultBackupWorker) run() {
-	// Check interval - we'll check for backups every hour
-	checkInterval := time.Hour
-
-	// Create a ticker for periodic checks
-	ticker := time.NewTicker(checkInterval)
-	defer ticker.Stop()
-
-	w.logger.Info("Backup worker loop started", "check_interval", checkInterval)
+	w.logger.Info("Backup worker loop started")
 
-	// Run initial backup check immediately
-	w.performBackupCheck()
-
-	// Main worker loop
 	for {
+		nextRunAt := w.nextRunAt()
+		wait := min(max(time.Until(nextRunAt), 0), maxScheduleCheckInterval)
+		w.logger.Debug("Waiting for the next backup", "next_run_at", nextRunAt, "wait", wait)
+
+		timer := time.NewTimer(wait)
 		select {
 		case <-w.ctx.Done():
+			timer.Stop()
 			w.logger.Info("Backup worker stopped")
 			return
-		case <-ticker.C:
-			w.performBackupCheck()
+		case <-timer.C:
+			if !time.Now().Before(nextRunAt) {
+				w.performBackup()
+			}
 		}
 	}
 }
 
-// performBackupCheck checks if a backup is needed and creates one if necessary
-func (w *DefaultBackupWorker) performBackupCheck() {
-	w.logger.Debug("Performing backup check")
+// nextRunAt returns when the next automatic backup is due
+func (w *DefaultBackupWorker) nextRunAt() time.Time {
+	now := time.Now()
 
-	// Check if backup is needed
-	needed, err := w.backupService.NeedsBackup()
+	lastBackupTime, err := w.backupService.GetLastBackupTime()
 	if err != nil {
-		w.logger.Error("Failed to check if backup is needed", "error", err)
-		return
+		w.logger.Error("Failed to get the time of the last backup", "error", err)
+		return now.Add(maxScheduleCheckInterval)
 	}
 
-	if !needed {
-		w.logger.Debug("No backup needed at this time")
-		return
+	var lastRun *backup.BackupRun
+	runs, err := w.runRepo.FindRecent(1)
+	if err != nil {
+		w.logger.Error("Failed to load the last backup run", "error", err)
+		return now.Add(maxScheduleCheckInterval)
+	}
+	if len(runs) > 0 {
+		lastRun = runs[0]
 	}
 
-	w.logger.Info("Backup needed, creating automatic backup")
+	return backup.NextBackupRun(w.schedule, lastBackupTime, lastRun, now)
+}
+
+// performBackup creates, verifies, uploads and cleans up an automatic backup and records the run
+func (w *DefaultBackupWorker) performBackup() {
+	w.logger.Info("Backup due, creating automatic backup")
+
+	run := &backup.BackupRun{
+		Id:        w.idGenerator.GenerateId(),
+		Trigger:   backup.BackupTriggerAuto,
+		Status:    backup.BackupRunStatusRunning,
+		StartedAt: time.Now(),
+	}
+	if err := w.runRepo.Add(run); err != nil {
+		w.logger.Error("Failed to record backup run", "error", err)
+	}
+
+	status, problems := w.createBackup(run)
+	run.Status = status
+	run.Error = strings.Join(problems, "; ")
+
+	finishedAt := time.Now()
+	run.FinishedAt = &finishedAt
+	if err := w.runRepo.Update(run); err != nil {
+		w.logger.Error("Failed to record backup run", "error", err)
+	}
+
+	w.logger.Info("Automatic backup run finished",
+		"status", run.Status,
+		"filename", run.Filename,
+		"duration", run.Duration())
+}
+
+// createBackup runs the steps of an automatic backup and stores the created file in the run.
+// The returned status tells whether an intact backup was created, the problems what went wrong.
+func (w *DefaultBackupWorker) createBackup(run *backup.BackupRun) (backup.BackupRunStatus, []string) {
+	var problems []string
 
 	// Create the backup
 	backupInfo, err := w.backupService.CreateBackup(backup.BackupTriggerAuto)
 	if err != nil {
 		w.logger.Error("Failed to create automatic backup", "error", err)
-		return
+		return backup.BackupRunStatusFailed, []string{err.Error()}
 	}
+	run.Filename = backupInfo.Filename
+	run.SizeBytes = backupInfo.SizeBytes
 
 	w.logger.Info("Automatic backup created successfully",
 		"filename", backupInfo.Filename,
 		"size_bytes", backupInfo.SizeBytes)
 
+	status := backup.BackupRunStatusSucceeded
+
+	// Verify the new backup; the result is recorded in its manifest and shown by ffcli backup list
+	verification, err := w.backupService.VerifyBackup(backupInfo.Filename)
+	if err != nil {
+		w.logger.Error("Failed to verify automatic backup", "filename", backupInfo.Filename, "error", err)
+		problems = append(problems, fmt.Sprintf("verification failed: %v", err))
+		status = backup.BackupRunStatusFailed
+	} else if !verification.Ok() {
+		w.logger.Error("Automatic backup failed verification",
+			"filename", backupInfo.Filename,
+			"problems", strings.Join(verification.Problems, "; "))
+		problems = append(problems, "verification failed: "+strings.Join(verification.Problems, "; "))
+		status = backup.BackupRunStatusFailed
+	} else if problem := w.performUpload(backupInfo.Filename); problem != "" {
+		// Only intact backups are copied off-site
+		problems = append(problems, problem)
+		status = backup.BackupRunStatusWarning
+	}
+
 	// Cleanup old backups
-	w.performCleanup()
+	if problem := w.performCleanup(); problem != "" {
+		problems = append(problems, problem)
+		if status == backup.BackupRunStatusSucceeded {
+			status = backup.BackupRunStatusWarning
+		}
+	}
+
+	return status, problems
 }
 
-// performCleanup removes old backup files according to configuration
-func (w *DefaultBackupWorker) performCleanup() {
+// performUpload copies a backup to the configured off-site targets and reports the result of each target.
+// It returns a description of the failed uploads, or an empty string if all succeeded.
+func (w *DefaultBackupWorker) performUpload(filename string) string {
+	results, err := w.backupService.UploadBackup(filename)
+	if err != nil {
+		w.logger.Error("Failed to upload automatic backup", "filename", filename, "error", err)
+		return fmt.Sprintf("upload failed: %v", err)
+	}
+
+	var failures []string
+	for _, result := range results {
+		if result.Err != nil {
+			failures = append(failures, fmt.Sprintf("upload to %s failed: %v", result.Target, result.Err))
+		}
+	}
+	if len(results) > 0 {
+		w.logger.Info("Automatic backup uploaded to targets",
+			"filename", filename,
+			"succeeded", len(results)-len(failures),
+			"failed", len(failures))
+	}
+	return strings.Join(failures, "; ")
+}
+
+// performCleanup removes old backup files according to configuration.
+// It returns a description of the failure, or an empty string if the cleanup succeeded.
+func (w *DefaultBackupWorker) performCleanup() string {
 	w.logger.Debug("Performing backup cleanup")
 
-	if err := w.backupService.CleanupOldBackups(); err != nil {
+	decisions, err := w.backupService.CleanupOldBackups(backup.CleanupOptions{})
+	if err != nil {
 		w.logger.Error("Failed to cleanup old backups", "error", err)
-		return
+		return fmt.Sprintf("cleanup failed: %v", err)
 	}
 
-	w.logger.Debug("Backup cleanup completed successfully")
+	deleted, failed := 0, 0
+	for _, decision := range decisions {
+		if decision.Keep {
+			continue
+		}
+		if decision.Err != nil {
+			failed++
+		} else {
+			deleted++
+		}
+	}
+	w.logger.Debug("Backup cleanup completed", "deleted", deleted, "failed", failed)
+
+	if failed > 0 {
+		return fmt.Sprintf("cleanup failed to delete %d backups", failed)
+	}
+	return ""
 }
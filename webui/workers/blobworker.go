@@ -0,0 +1,80 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/blobstore"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+)
+
+// DefaultBlobWorker deletes blobs that are no longer referenced by any document file in the background
+type DefaultBlobWorker struct {
+	garbageCollector blobstore.BlobGarbageCollector
+	config           ccc.AppConfig
+	logger           ccc.Logger
+	ctx              context.Context
+	cancel           context.CancelFunc
+}
+
+// NewDefaultBlobWorker creates a new blob worker instance. The garbage collector is nil if no blob store is configured.
+func NewDefaultBlobWorker(garbageCollector blobstore.BlobGarbageCollector, config ccc.AppConfig, logger ccc.Logger) *DefaultBlobWorker {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DefaultBlobWorker{
+		garbageCollector: garbageCollector,
+		config:           config,
+		logger:           logger,
+		ctx:              ctx,
+		cancel:           cancel,
+	}
+}
+
+// Start begins the background worker loop
+func (w *DefaultBlobWorker) Start() {
+	if w.garbageCollector == nil {
+		return
+	}
+	if w.config.BlobStore.GCIntervalHours <= 0 {
+		w.logger.Info("Blob worker disabled, unreferenced blobs are kept until ffcli db blobs gc is run")
+		return
+	}
+
+	w.logger.Info("Starting blob worker", "interval_hours", w.config.BlobStore.GCIntervalHours)
+	go w.run()
+}
+
+// Stop gracefully stops the blob worker
+func (w *DefaultBlobWorker) Stop() {
+	w.logger.Info("Stopping blob worker")
+	w.cancel()
+}
+
+// run collects unreferenced blobs right away and then once per interval
+func (w *DefaultBlobWorker) run() {
+	ticker := time.NewTicker(time.Duration(w.config.BlobStore.GCIntervalHours) * time.Hour)
+	defer ticker.Stop()
+
+	for {
+		w.collectGarbage()
+
+		select {
+		case <-w.ctx.Done():
+			w.logger.Info("Blob worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// collectGarbage deletes the unreferenced blobs older than the grace period
+func (w *DefaultBlobWorker) collectGarbage() {
+	storedBefore := time.Now().Add(-blobstore.GarbageCollectionGracePeriod)
+	if _, err := w.garbageCollector.CollectGarbage(w.ctx, storedBefore, false); err != nil && w.ctx.Err() == nil {
+		w.logger.Error("Failed to collect unreferenced blobs", "error", err)
+	}
+}
@@ -8,3 +8,51 @@ type BackupWorker interface {
 	// Stop gracefully stops the backup worker
 	Stop()
 }
+
+// OCRWorker defines the interface for background text extraction of queued documents
+type OCRWorker interface {
+	// Start recovers interrupted jobs and starts the worker pool
+	Start()
+
+	// Stop gracefully stops the workers, interrupted jobs are resumed on the next start
+	Stop()
+
+	// Notify wakes up an idle worker to process newly queued jobs
+	Notify()
+}
+
+// TrashWorker defines the interface for purging expired items from the trash in the background
+type TrashWorker interface {
+	// Start begins the background worker loop
+	Start()
+
+	// Stop gracefully stops the trash worker
+	Stop()
+}
+
+// ReminderWorker defines the interface for writing reminders of expiring secrets in the background
+type ReminderWorker interface {
+	// Start begins the background worker loop
+	Start()
+
+	// Stop gracefully stops the reminder worker
+	Stop()
+}
+
+// BlobWorker defines the interface for deleting unreferenced blobs from the blob store in the background
+type BlobWorker interface {
+	// Start begins the background worker loop
+	Start()
+
+	// Stop gracefully stops the blob worker
+	Stop()
+}
+
+// UploadWorker defines the interface for deleting expired upload sessions in the background
+type UploadWorker interface {
+	// Start begins the background worker loop
+	Start()
+
+	// Stop gracefully stops the upload worker
+	Stop()
+}
@@ -0,0 +1,110 @@
+package workers
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+)
+
+// ocrPollInterval is the interval in which idle workers check for due jobs, e.g. retries with a backoff
+const ocrPollInterval = 5 * time.Second
+
+// DefaultOCRWorker processes the persisted OCR job queue with a pool of background goroutines
+type DefaultOCRWorker struct {
+	processor documents.OCRJobProcessor
+	config    ccc.AppConfig
+	logger    ccc.Logger
+	ctx       context.Context
+	cancel    context.CancelFunc
+	notify    chan struct{}
+	wg        sync.WaitGroup
+}
+
+// NewDefaultOCRWorker creates a new OCR worker instance
+func NewDefaultOCRWorker(processor documents.OCRJobProcessor, config ccc.AppConfig, logger ccc.Logger) *DefaultOCRWorker {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DefaultOCRWorker{
+		processor: processor,
+		config:    config,
+		logger:    logger,
+		ctx:       ctx,
+		cancel:    cancel,
+		notify:    make(chan struct{}, 1),
+	}
+}
+
+// Start recovers jobs interrupted by a previous shutdown and starts the worker pool
+func (w *DefaultOCRWorker) Start() {
+	workerCount := w.config.OCR.Workers
+	if workerCount <= 0 {
+		workerCount = 1
+	}
+
+	w.logger.Info("Starting OCR worker", "workers", workerCount)
+
+	if err := w.processor.RecoverOrphanedJobs(w.ctx); err != nil {
+		w.logger.Error("Failed to recover interrupted OCR jobs", "error", err)
+	}
+
+	for i := 0; i < workerCount; i++ {
+		w.wg.Add(1)
+		go w.run(i + 1)
+	}
+}
+
+// Stop cancels running extractions and waits for all workers to exit.
+// Interrupted jobs are re-queued and resumed on the next start.
+func (w *DefaultOCRWorker) Stop() {
+	w.logger.Info("Stopping OCR worker")
+	w.cancel()
+	w.wg.Wait()
+	w.logger.Info("OCR worker stopped")
+}
+
+// Notify wakes up an idle worker to process newly queued jobs
+func (w *DefaultOCRWorker) Notify() {
+	select {
+	case w.notify <- struct{}{}:
+	default:
+		// A wake-up is already pending
+	}
+}
+
+// run is the loop of a single worker goroutine
+func (w *DefaultOCRWorker) run(id int) {
+	defer w.wg.Done()
+
+	ticker := time.NewTicker(ocrPollInterval)
+	defer ticker.Stop()
+
+	w.logger.Debug("OCR worker loop started", "worker", id)
+
+	for {
+		// Drain the queue before going idle
+		for w.ctx.Err() == nil {
+			processed, err := w.processor.ProcessNext(w.ctx)
+			if err != nil {
+				w.logger.Error("Failed to process OCR job", "worker", id, "error", err)
+			}
+			if !processed {
+				break
+			}
+		}
+
+		select {
+		case <-w.ctx.Done():
+			w.logger.Debug("OCR worker loop stopped", "worker", id)
+			return
+		case <-w.notify:
+		case <-ticker.C:
+		}
+	}
+}
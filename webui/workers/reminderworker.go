@@ -0,0 +1,76 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/notifications"
+)
+
+// reminderInterval is the interval in which the worker looks for secrets that expire or are due for rotation
+const reminderInterval = time.Hour
+
+// DefaultReminderWorker writes notifications for expiring secrets and mails them in the background
+type DefaultReminderWorker struct {
+	notificationManager notifications.NotificationManager
+	logger              ccc.Logger
+	ctx                 context.Context
+	cancel              context.CancelFunc
+}
+
+// NewDefaultReminderWorker creates a new reminder worker instance
+func NewDefaultReminderWorker(notificationManager notifications.NotificationManager, logger ccc.Logger) *DefaultReminderWorker {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DefaultReminderWorker{
+		notificationManager: notificationManager,
+		logger:              logger,
+		ctx:                 ctx,
+		cancel:              cancel,
+	}
+}
+
+// Start begins the background worker loop
+func (w *DefaultReminderWorker) Start() {
+	w.logger.Info("Starting reminder worker", "email_available", w.notificationManager.EmailAvailable())
+	go w.run()
+}
+
+// Stop gracefully stops the reminder worker
+func (w *DefaultReminderWorker) Stop() {
+	w.logger.Info("Stopping reminder worker")
+	w.cancel()
+}
+
+// run writes reminders right away and then once per interval
+func (w *DefaultReminderWorker) run() {
+	ticker := time.NewTicker(reminderInterval)
+	defer ticker.Stop()
+
+	for {
+		w.createReminders()
+
+		select {
+		case <-w.ctx.Done():
+			w.logger.Info("Reminder worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// createReminders writes the reminders that are due and logs what was done
+func (w *DefaultReminderWorker) createReminders() {
+	summary, err := w.notificationManager.CreateReminders(w.ctx, time.Now())
+	if err != nil {
+		w.logger.Error("Failed to create reminders", "error", err)
+	}
+	if summary != nil && summary.Created > 0 {
+		w.logger.Info("Created reminders", "created", summary.Created, "emailed", summary.Emailed)
+	}
+}
@@ -0,0 +1,86 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/trash"
+)
+
+// trashPurgeInterval is the interval in which the worker purges expired items from the trash
+const trashPurgeInterval = time.Hour
+
+// DefaultTrashWorker purges items that have been in the trash longer than the retention period in the background
+type DefaultTrashWorker struct {
+	trashManager trash.TrashManager
+	config       ccc.AppConfig
+	logger       ccc.Logger
+	ctx          context.Context
+	cancel       context.CancelFunc
+}
+
+// NewDefaultTrashWorker creates a new trash worker instance
+func NewDefaultTrashWorker(trashManager trash.TrashManager, config ccc.AppConfig, logger ccc.Logger) *DefaultTrashWorker {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DefaultTrashWorker{
+		trashManager: trashManager,
+		config:       config,
+		logger:       logger,
+		ctx:          ctx,
+		cancel:       cancel,
+	}
+}
+
+// Start begins the background worker loop
+func (w *DefaultTrashWorker) Start() {
+	if w.config.TrashRetentionDays <= 0 {
+		w.logger.Info("Trash worker disabled, deleted items are kept until they are purged manually")
+		return
+	}
+
+	w.logger.Info("Starting trash worker", "retention_days", w.config.TrashRetentionDays)
+	go w.run()
+}
+
+// Stop gracefully stops the trash worker
+func (w *DefaultTrashWorker) Stop() {
+	w.logger.Info("Stopping trash worker")
+	w.cancel()
+}
+
+// run purges expired items right away and then once per interval
+func (w *DefaultTrashWorker) run() {
+	ticker := time.NewTicker(trashPurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		w.purgeExpired()
+
+		select {
+		case <-w.ctx.Done():
+			w.logger.Info("Trash worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purgeExpired purges the items of all users whose retention period has ended
+func (w *DefaultTrashWorker) purgeExpired() {
+	summary, err := w.trashManager.PurgeExpired(w.ctx, time.Now())
+	if err != nil {
+		w.logger.Error("Failed to purge expired items from the trash", "error", err)
+	}
+	if summary != nil && summary.Total() > 0 {
+		w.logger.Info("Purged expired items from the trash",
+			"secrets", summary.Secrets,
+			"documents", summary.Documents,
+			"tags", summary.Tags)
+	}
+}
@@ -0,0 +1,73 @@
+package workers
+
+import (
+	"context"
+	"time"
+
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/ccc"
+	"github.com/Yeti47/frozenfortress/frozenfortress/core/documents"
+)
+
+// uploadCleanupInterval is the interval in which the worker deletes expired upload sessions
+const uploadCleanupInterval = time.Hour
+
+// DefaultUploadWorker deletes upload sessions that were neither completed nor continued in time in the background.
+// The parts of the deleted sessions that are kept in the blob store are removed by the blob worker.
+type DefaultUploadWorker struct {
+	uploadSessionManager documents.UploadSessionManager
+	logger               ccc.Logger
+	ctx                  context.Context
+	cancel               context.CancelFunc
+}
+
+// NewDefaultUploadWorker creates a new upload worker instance
+func NewDefaultUploadWorker(uploadSessionManager documents.UploadSessionManager, logger ccc.Logger) *DefaultUploadWorker {
+	if logger == nil {
+		logger = ccc.NopLogger
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	return &DefaultUploadWorker{
+		uploadSessionManager: uploadSessionManager,
+		logger:               logger,
+		ctx:                  ctx,
+		cancel:               cancel,
+	}
+}
+
+// Start begins the background worker loop
+func (w *DefaultUploadWorker) Start() {
+	w.logger.Info("Starting upload worker", "session_lifetime", documents.UploadSessionLifetime)
+	go w.run()
+}
+
+// Stop gracefully stops the upload worker
+func (w *DefaultUploadWorker) Stop() {
+	w.logger.Info("Stopping upload worker")
+	w.cancel()
+}
+
+// run deletes expired sessions right away and then once per interval
+func (w *DefaultUploadWorker) run() {
+	ticker := time.NewTicker(uploadCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		w.deleteExpired()
+
+		select {
+		case <-w.ctx.Done():
+			w.logger.Info("Upload worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// deleteExpired deletes the expired upload sessions of all users
+func (w *DefaultUploadWorker) deleteExpired() {
+	if _, err := w.uploadSessionManager.DeleteExpiredUploadSessions(w.ctx, time.Now()); err != nil {
+		w.logger.Error("Failed to delete expired upload sessions", "error", err)
+	}
+}